@@ -3,6 +3,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -20,10 +21,15 @@ var (
 	taskFileFlag   string
 	kbFlag         string
 	modelFlag      string
+	personaFlag    string
 	execPaneFlag   string
 	readPanesFlag  string
 	yoloFlag       bool
+	dryRunFlag     bool
+	resumeFlag     string
 	configFileFlag string
+	messageFlag    string
+	jsonFlag       bool
 )
 
 var rootCmd = &cobra.Command{
@@ -43,10 +49,14 @@ var rootCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
 			os.Exit(1)
 		}
+		configureLogger(cfg)
 
 		if len(args) > 0 {
 			initMessage = strings.Join(args, " ")
 		}
+		if messageFlag != "" {
+			initMessage = messageFlag
+		}
 
 		if taskFileFlag != "" {
 			content, err := os.ReadFile(taskFileFlag)
@@ -59,6 +69,25 @@ var rootCmd = &cobra.Command{
 			logger.Info("Read request from file: %s", taskFileFlag)
 		}
 
+		if stdinPiped() {
+			content, total, err := readStdin(os.Stdin, maxStdinBytes)
+			if err != nil {
+				logger.Error("Error reading stdin: %v", err)
+				fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+				os.Exit(1)
+			}
+			if strings.TrimSpace(content) != "" {
+				initMessage = attachStdin(initMessage, content, total)
+				logger.Info("Read %d bytes from stdin, attached %d", total, len(content))
+				if int64(len(content)) < total {
+					fmt.Fprintf(os.Stderr, "stdin is %d bytes, only the first %d are sent\n", total, len(content))
+				}
+			}
+			if err := reopenTerminal(); err != nil {
+				logger.Error("Error reopening the terminal: %v", err)
+			}
+		}
+
 		managerOptions := internal.ManagerOptions{
 			ForcedExecPaneID: strings.TrimSpace(execPaneFlag),
 		}
@@ -89,6 +118,26 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}()
 
+		// Resume before applying flags so they take precedence over saved overrides
+		if resumeFlag != "" {
+			if err := mgr.ResumeSession(resumeFlag); err != nil {
+				logger.Error("Error resuming session: %v", err)
+				fmt.Fprintf(os.Stderr, "Error resuming session: %v\n", err)
+				os.Exit(1)
+			}
+			logger.Info("Resumed session %s", mgr.SessionID)
+		}
+
+		// Before --kb and --model, which add to or replace what it sets
+		if personaFlag != "" {
+			if err := mgr.SwitchPersona(personaFlag); err != nil {
+				logger.Error("Error switching persona: %v", err)
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			logger.Info("Set persona from CLI flag: %s", personaFlag)
+		}
+
 		// Load knowledge bases from CLI flag
 		if kbFlag != "" {
 			kbNames := strings.Split(kbFlag, ",")
@@ -106,6 +155,34 @@ var rootCmd = &cobra.Command{
 			logger.Info("Yolo mode enabled: skipping all confirmation prompts")
 		}
 
+		if dryRunFlag {
+			mgr.SessionOverrides["dry_run"] = true
+			logger.Info("Dry-run mode enabled: actions are shown but not sent to the pane")
+		}
+
+		if listenFlag != "" {
+			token, err := mgr.ServeAPI(context.Background(), listenFlag, apiTokenFlag)
+			if err != nil {
+				logger.Error("Error starting the HTTP API: %v", err)
+				fmt.Fprintf(os.Stderr, "Error starting the HTTP API: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("HTTP API listening on http://%s\n", listenFlag)
+			if apiTokenFlag == "" {
+				fmt.Printf("HTTP API token: %s\n", token)
+			}
+		}
+
+		if messageFlag != "" || jsonFlag {
+			if strings.TrimSpace(initMessage) == "" {
+				fmt.Fprintln(os.Stderr, "Error: one-shot mode needs a request, give it with -m")
+				os.Exit(1)
+			}
+			code := executeOnce(mgr, initMessage, jsonFlag)
+			mgr.Cleanup()
+			os.Exit(code)
+		}
+
 		if initMessage != "" {
 			logger.Info("Starting with initial subcommand: %s", initMessage)
 		}
@@ -121,9 +198,14 @@ func init() {
 	rootCmd.Flags().StringVarP(&taskFileFlag, "file", "f", "", "Read request from specified file")
 	rootCmd.Flags().StringVar(&kbFlag, "kb", "", "Comma-separated list of knowledge bases to load (e.g., --kb docker,git)")
 	rootCmd.Flags().StringVar(&modelFlag, "model", "", "AI model configuration to use (e.g., --model gpt4)")
-	rootCmd.Flags().StringVar(&execPaneFlag, "exec-pane", "", "Use the specified tmux pane ID as the exec pane (e.g., --exec-pane %3)")
+	rootCmd.Flags().StringVar(&personaFlag, "persona", "", "Persona from the personas config to start with (e.g., --persona sre)")
+	rootCmd.Flags().StringVar(&execPaneFlag, "exec-pane", "", "Use the specified tmux pane as the exec pane, optionally in another session (e.g., --exec-pane %3 or --exec-pane mysession:1.2)")
 	rootCmd.Flags().StringVar(&readPanesFlag, "read-panes", "", "Comma-separated tmux pane IDs to use as read context (e.g., --read-panes %1,%2)")
 	rootCmd.Flags().BoolVar(&yoloFlag, "yolo", false, "Skip all confirmation prompts and execute commands directly")
+	rootCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Show and log planned commands, keys and pastes without sending them to the pane")
+	rootCmd.Flags().StringVar(&resumeFlag, "resume", "", "Resume a saved session by ID, or 'latest' for the most recent one")
+	rootCmd.Flags().StringVarP(&messageFlag, "message", "m", "", "Process a single request and exit instead of starting the chat")
+	rootCmd.Flags().BoolVar(&jsonFlag, "json", false, "With -m, print the result as JSON on stdout; the usual output goes to stderr")
 	rootCmd.Flags().BoolP("version", "v", false, "Print version information")
 	rootCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file (overrides default ~/.config/tmuxai/config.yaml, also settable via TMUXAI_CONFIG env var)")
 }
@@ -131,3 +213,18 @@ func init() {
 func Execute() error {
 	return rootCmd.Execute()
 }
+
+// configureLogger applies the log section of the config to the logger
+func configureLogger(cfg *config.Config) {
+	err := logger.Configure(logger.Options{
+		Format:     cfg.Log.Format,
+		Level:      cfg.Log.Level,
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxFiles:   cfg.Log.MaxFiles,
+		MaxAgeDays: cfg.Log.MaxAgeDays,
+	})
+	if err != nil {
+		logger.Error("Invalid log config: %v", err)
+		fmt.Fprintf(os.Stderr, "Warning: invalid log config, keeping the defaults: %v\n", err)
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/internal"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.yaml for unknown keys and invalid settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadDiagnosticsConfig()
+		if !internal.ValidateConfig(cfg, config.ConfigFileUsed(), os.Stdout) {
+			os.Exit(1)
+		}
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the config, tmux and the connection to the AI provider",
+	Long: `Validate config.yaml, check that tmux is installed and recent enough, and
+send a short request to the configured model. Every problem found comes
+with a suggested fix.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadDiagnosticsConfig()
+		if modelFlag != "" {
+			cfg.DefaultModel = modelFlag
+		}
+		if !internal.RunDoctor(cfg, config.ConfigFileUsed(), os.Stdout) {
+			os.Exit(1)
+		}
+	},
+}
+
+// loadDiagnosticsConfig loads the config, exiting when the file can't be read
+func loadDiagnosticsConfig() *config.Config {
+	cfg, err := config.Load(configFileFlag)
+	if err != nil {
+		logger.Error("Error loading configuration: %v", err)
+		fmt.Fprintf(os.Stderr, "✗ %v\n  → fix the YAML syntax of %s\n", err, config.ConfigFileUsed())
+		os.Exit(1)
+	}
+	configureLogger(cfg)
+	return cfg
+}
+
+func init() {
+	configValidateCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file")
+	doctorCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file")
+	doctorCmd.Flags().StringVar(&modelFlag, "model", "", "AI model configuration to test (e.g., --model gpt4)")
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd, doctorCmd)
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/internal"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/spf13/cobra"
+)
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "mcp-serve",
+	Short: "Expose tmux panes as MCP tools over stdio",
+	Long: `Run TmuxAI as an MCP server over stdio, exposing capture_pane, exec_command
+and send_keys tools. Commands and keys go through the same whitelist and
+confirmation settings as the interactive agent; confirmations are requested
+from the MCP client.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(configFileFlag)
+		if err != nil {
+			logger.Error("Error loading configuration: %v", err)
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			os.Exit(1)
+		}
+		configureLogger(cfg)
+		if yoloFlag {
+			cfg.Yolo = true
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		logger.Info("Starting MCP server over stdio")
+		if err := internal.ServeMCP(ctx, cfg); err != nil && ctx.Err() == nil {
+			logger.Error("MCP server failed: %v", err)
+			fmt.Fprintf(os.Stderr, "MCP server failed: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().BoolVar(&yoloFlag, "yolo", false, "Skip all confirmation prompts and execute commands directly")
+	mcpServeCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file")
+	rootCmd.AddCommand(mcpServeCmd)
+}
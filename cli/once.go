@@ -0,0 +1,50 @@
+// once.go: One-shot mode, tmuxai -m "..." processes a single request and exits
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alvinunreal/tmuxai/internal"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/fatih/color"
+)
+
+// executeOnce runs the request and returns the process exit code. With
+// asJSON, everything TmuxAI prints while processing goes to stderr so stdout
+// carries only the JSON result.
+func executeOnce(mgr *internal.Manager, message string, asJSON bool) int {
+	logger.Info("One-shot request: %s", message)
+	stdout := os.Stdout
+	if asJSON {
+		os.Stdout = os.Stderr
+		color.Output = os.Stderr
+		defer func() {
+			os.Stdout = stdout
+			color.Output = stdout
+		}()
+	}
+
+	result := mgr.ExecuteOnce(message)
+	if !asJSON {
+		return 0
+	}
+	if err := writeOnceResult(stdout, result); err != nil {
+		logger.Error("Error writing the result: %v", err)
+		fmt.Fprintf(os.Stderr, "Error writing the result: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeOnceResult(w io.Writer, result internal.OnceResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode the result: %w", err)
+	}
+	return nil
+}
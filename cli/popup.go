@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/internal"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/spf13/cobra"
+)
+
+var popupPaneFlag string
+
+var popupCmd = &cobra.Command{
+	Use:   "popup [question]",
+	Short: "Ask one question about a pane, for use inside tmux display-popup",
+	Long: `Capture a pane, ask the model one question about it and show the answer.
+If the answer suggests a command, it can be typed into the pane, where
+Enter is left to you. Bind it to a key with:
+
+  bind-key a display-popup -E -w 80% -h 60% "tmuxai popup"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(configFileFlag)
+		if err != nil {
+			logger.Error("Error loading configuration: %v", err)
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			os.Exit(1)
+		}
+		configureLogger(cfg)
+		if modelFlag != "" {
+			cfg.DefaultModel = modelFlag
+		}
+
+		if err := internal.RunPopup(cfg, strings.TrimSpace(popupPaneFlag), strings.Join(args, " ")); err != nil {
+			logger.Error("Popup failed: %v", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\nPress Enter to close", err)
+			_, _ = fmt.Fscanln(os.Stdin)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	popupCmd.Flags().StringVar(&popupPaneFlag, "pane", "", "Pane to ask about (defaults to the active pane)")
+	popupCmd.Flags().StringVar(&modelFlag, "model", "", "AI model configuration to use (e.g., --model gpt4)")
+	popupCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file")
+	rootCmd.AddCommand(popupCmd)
+}
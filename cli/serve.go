@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listenFlag   string
+	apiTokenFlag string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [request message]",
+	Short: "Start TmuxAI with a local HTTP API for editor plugins and scripts",
+	Long: `Start TmuxAI as usual, with an HTTP API on the side that drives the same
+agent: POST /messages submits a request, GET /status, GET /panes and
+GET /transcript report on the session. Requests sent to the API are
+processed one at a time, like the ones typed in the chat. Without --token,
+a token is generated and printed at startup.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rootCmd.Run(cmd, args)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&listenFlag, "listen", "127.0.0.1:8787", "Address the HTTP API listens on")
+	serveCmd.Flags().StringVar(&apiTokenFlag, "token", os.Getenv("TMUXAI_API_TOKEN"), "Bearer token the HTTP API requires (defaults to TMUXAI_API_TOKEN)")
+	serveCmd.Flags().StringVar(&kbFlag, "kb", "", "Comma-separated list of knowledge bases to load (e.g., --kb docker,git)")
+	serveCmd.Flags().StringVar(&modelFlag, "model", "", "AI model configuration to use (e.g., --model gpt4)")
+	serveCmd.Flags().StringVar(&execPaneFlag, "exec-pane", "", "Use the specified tmux pane as the exec pane")
+	serveCmd.Flags().BoolVar(&yoloFlag, "yolo", false, "Skip all confirmation prompts and execute commands directly")
+	serveCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Show and log planned commands, keys and pastes without sending them to the pane")
+	serveCmd.Flags().StringVar(&resumeFlag, "resume", "", "Resume a saved session by ID, or 'latest' for the most recent one")
+	serveCmd.Flags().StringVar(&configFileFlag, "config", "", "Path to config file")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,77 @@
+// stdin.go: Piped input attached to the initial request, e.g. git diff | tmuxai "review this diff"
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStdinBytes is how much piped input is attached to the request; the
+// rest is cut off with a notice
+const maxStdinBytes = 100 * 1024
+
+// stdinPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// readStdin reads up to limit bytes of piped input. The rest is drained to
+// count it; total is the whole input's size.
+func readStdin(r io.Reader, limit int) (content string, total int64, err error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	total = int64(len(data))
+	if len(data) <= limit {
+		return string(data), total, nil
+	}
+	rest, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return truncateStdin(data[:limit]), total + rest, nil
+}
+
+// truncateStdin cuts input at its last line break, or else at a whole
+// character
+func truncateStdin(data []byte) string {
+	text := string(data)
+	if i := strings.LastIndexByte(text, '\n'); i > 0 {
+		return text[:i+1]
+	}
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// attachStdin adds piped input to the request as context, noting when it
+// was cut off. Without a request, the input is the request.
+func attachStdin(message, content string, total int64) string {
+	notice := ""
+	if int64(len(content)) < total {
+		notice = fmt.Sprintf("\n[stdin truncated: showing the first %d of %d bytes]", len(content), total)
+	}
+	content = strings.TrimRight(content, "\n")
+	if strings.TrimSpace(message) == "" {
+		return content + notice
+	}
+	return message + "\n\nInput piped to tmuxai:\n<stdin>\n" + content + "\n</stdin>" + notice
+}
+
+// reopenTerminal points stdin back at the terminal once the piped input is
+// read, so confirmations and the chat can read keys again
+func reopenTerminal() error {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return fmt.Errorf("failed to open the terminal: %w", err)
+	}
+	os.Stdin = tty
+	return nil
+}
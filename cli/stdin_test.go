@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadStdin(t *testing.T) {
+	content, total, err := readStdin(strings.NewReader("diff --git a/x b/x\n+new\n"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "diff --git a/x b/x\n+new\n" || total != 24 {
+		t.Errorf("read %q (%d bytes), want the whole input", content, total)
+	}
+
+	content, total, err = readStdin(strings.NewReader("line one\nline two\nline three\n"), 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "line one\n" || total != 29 {
+		t.Errorf("read %q (%d bytes), want the first whole line of 29 bytes", content, total)
+	}
+
+	// cut inside a multi-byte character
+	content, _, _ = readStdin(strings.NewReader("héllo"), 2)
+	if content != "h" {
+		t.Errorf("read %q, want the whole characters only", content)
+	}
+}
+
+func TestAttachStdin(t *testing.T) {
+	got := attachStdin("review this diff", "+new\n", 5)
+	want := "review this diff\n\nInput piped to tmuxai:\n<stdin>\n+new\n</stdin>"
+	if got != want {
+		t.Errorf("attached %q, want %q", got, want)
+	}
+
+	got = attachStdin("", "line one\n", 29)
+	if want := "line one\n[stdin truncated: showing the first 9 of 29 bytes]"; got != want {
+		t.Errorf("attached %q, want %q", got, want)
+	}
+}
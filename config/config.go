@@ -12,28 +12,67 @@ import (
 
 // Config holds the application configuration
 type Config struct {
-	Debug                 bool                   `mapstructure:"debug"`
-	Yolo                  bool                   `mapstructure:"yolo"`
-	MaxCaptureLines       int                    `mapstructure:"max_capture_lines"`
-	MaxContextSize        int                    `mapstructure:"max_context_size"`
-	StatusLine            string                 `mapstructure:"status_line"`
-	WaitInterval          int                    `mapstructure:"wait_interval"`
-	SendKeysConfirm       bool                   `mapstructure:"send_keys_confirm"`
-	PasteMultilineConfirm bool                   `mapstructure:"paste_multiline_confirm"`
-	ExecConfirm           bool                   `mapstructure:"exec_confirm"`
-	WhitelistPatterns     []string               `mapstructure:"whitelist_patterns"`
-	BlacklistPatterns     []string               `mapstructure:"blacklist_patterns"`
-	Tmux                  TmuxConfig             `mapstructure:"tmux"`
-	OpenRouter            OpenRouterConfig       `mapstructure:"openrouter"`
-	Requesty              RequestyConfig         `mapstructure:"requesty"`
-	OpenAI                OpenAIConfig           `mapstructure:"openai"`
-	AzureOpenAI           AzureOpenAIConfig      `mapstructure:"azure_openai"`
-	DefaultModel          string                 `mapstructure:"default_model"`
-	Models                map[string]ModelConfig `mapstructure:"models"`
-	Prompts               PromptsConfig          `mapstructure:"prompts"`
-	KnowledgeBase         KnowledgeBaseConfig    `mapstructure:"knowledge_base"`
-	WebSearch             WebSearchConfig        `mapstructure:"web_search"`
-	WebFetch              WebFetchConfig         `mapstructure:"web_fetch"`
+	Debug                 bool                     `mapstructure:"debug"`
+	Log                   LogConfig                `mapstructure:"log"`
+	Telemetry             TelemetryConfig          `mapstructure:"telemetry"`
+	Yolo                  bool                     `mapstructure:"yolo"`
+	DryRun                bool                     `mapstructure:"dry_run"`
+	PlanMode              bool                     `mapstructure:"plan_mode"`
+	AuditLog              bool                     `mapstructure:"audit_log"`
+	SaveSessions          bool                     `mapstructure:"save_sessions"`
+	SessionSummaries      bool                     `mapstructure:"session_summaries"`
+	SessionSummaryModel   string                   `mapstructure:"session_summary_model"`
+	MaxCaptureLines       int                      `mapstructure:"max_capture_lines"`
+	PaneDiff              bool                     `mapstructure:"pane_diff"`
+	Capture               CaptureConfig            `mapstructure:"capture"`
+	PaneImageCommand      string                   `mapstructure:"pane_image_command"`
+	GitContext            GitContextConfig         `mapstructure:"git_context"`
+	ShellContext          ShellContextConfig       `mapstructure:"shell_context"`
+	Redaction             RedactionConfig          `mapstructure:"redaction"`
+	MaxContextSize        int                      `mapstructure:"max_context_size"`
+	Compaction            CompactionConfig         `mapstructure:"compaction"`
+	Watch                 WatchConfig              `mapstructure:"watch"`
+	StatusLine            string                   `mapstructure:"status_line"`
+	RenderMarkdown        bool                     `mapstructure:"render_markdown"`
+	ReloadConfig          bool                     `mapstructure:"reload_config"`
+	Theme                 ThemeConfig              `mapstructure:"theme"`
+	CLI                   CLIConfig                `mapstructure:"cli"`
+	WaitInterval          int                      `mapstructure:"wait_interval"`
+	ExecTimeout           int                      `mapstructure:"exec_timeout"`
+	ExecTimeoutInterrupt  bool                     `mapstructure:"exec_timeout_interrupt"`
+	ExecStreamInterval    int                      `mapstructure:"exec_stream_interval"`
+	MaxSubagents          int                      `mapstructure:"max_subagents"`
+	MaxIterations         int                      `mapstructure:"max_iterations"`
+	SendKeysConfirm       bool                     `mapstructure:"send_keys_confirm"`
+	Typing                TypingConfig             `mapstructure:"typing"`
+	PasteMultilineConfirm bool                     `mapstructure:"paste_multiline_confirm"`
+	ExecConfirm           bool                     `mapstructure:"exec_confirm"`
+	McpConfirm            bool                     `mapstructure:"mcp_confirm"`
+	ContextWindows        string                   `mapstructure:"context_windows"`
+	ExcludePanes          []string                 `mapstructure:"exclude_panes"`
+	ReadOnlyPanes         []string                 `mapstructure:"readonly_panes"`
+	FixOnFailure          string                   `mapstructure:"fix_on_failure"`
+	WhitelistPatterns     []string                 `mapstructure:"whitelist_patterns"`
+	BlacklistPatterns     []string                 `mapstructure:"blacklist_patterns"`
+	Safety                SafetyConfig             `mapstructure:"safety"`
+	Sandbox               SandboxConfig            `mapstructure:"sandbox"`
+	Files                 FilesConfig              `mapstructure:"files"`
+	Hooks                 HooksConfig              `mapstructure:"hooks"`
+	Tmux                  TmuxConfig               `mapstructure:"tmux"`
+	OpenRouter            OpenRouterConfig         `mapstructure:"openrouter"`
+	Requesty              RequestyConfig           `mapstructure:"requesty"`
+	OpenAI                OpenAIConfig             `mapstructure:"openai"`
+	AzureOpenAI           AzureOpenAIConfig        `mapstructure:"azure_openai"`
+	DefaultModel          string                   `mapstructure:"default_model"`
+	Roles                 RolesConfig              `mapstructure:"roles"`
+	Models                map[string]ModelConfig   `mapstructure:"models"`
+	Persona               string                   `mapstructure:"persona"`
+	Personas              map[string]PersonaConfig `mapstructure:"personas"`
+	Prompts               PromptsConfig            `mapstructure:"prompts"`
+	KnowledgeBase         KnowledgeBaseConfig      `mapstructure:"knowledge_base"`
+	WebSearch             WebSearchConfig          `mapstructure:"web_search"`
+	WebFetch              WebFetchConfig           `mapstructure:"web_fetch"`
+	Retry                 RetryConfig              `mapstructure:"retry"`
 }
 
 // OpenRouterConfig holds OpenRouter API configuration
@@ -72,6 +111,12 @@ type ModelConfig struct {
 	APIKey   string `mapstructure:"api_key"`
 	BaseURL  string `mapstructure:"base_url"`
 
+	// Without api_key, the key is read from the output of APIKeyCmd (run
+	// with sh -c) or from the APIKeyKeychain service in the macOS Keychain
+	// or libsecret, when the first request needs it
+	APIKeyCmd      string `mapstructure:"api_key_cmd"`
+	APIKeyKeychain string `mapstructure:"api_key_keychain"`
+
 	// Azure-specific fields
 	APIBase        string `mapstructure:"api_base"`
 	APIVersion     string `mapstructure:"api_version"`
@@ -86,11 +131,127 @@ type ModelConfig struct {
 	Region     string `mapstructure:"region"`
 	AWSProfile string `mapstructure:"aws_profile"`
 
+	// GitHub Copilot-specific fields
+	// For github-copilot, BaseURL is the GitHub Enterprise host the copilot
+	// CLI signs in to and lists models from (e.g. https://acme.ghe.com) and
+	// Proxy the HTTP(S) proxy it connects through. CopilotCLIURL connects to
+	// a copilot CLI server that is already running (copilot --headless
+	// --port N) instead of starting one; it brings its own host, proxy and
+	// credentials.
+	Proxy         string `mapstructure:"proxy"`
+	CopilotCLIURL string `mapstructure:"copilot_cli_url"`
+
 	// Inference parameters (used by Bedrock today; other providers may adopt
 	// them later). Zero values mean "unset"; the provider layer supplies a
 	// safe default where one is required.
 	MaxTokens   int32   `mapstructure:"max_tokens"`
 	Temperature float32 `mapstructure:"temperature"`
+
+	// Stream renders the response as it is generated (chat completion
+	// compatible providers and ollama). Action tags still run once the
+	// full response has arrived.
+	Stream bool `mapstructure:"stream"`
+
+	// PromptCache adds cache breakpoints to Anthropic requests and a
+	// per-session prompt_cache_key to OpenAI requests, so the system prompt
+	// and history repeated on every agent loop iteration are cached.
+	PromptCache bool `mapstructure:"prompt_cache"`
+
+	// ToolCalling declares the response actions as native function tools
+	// (openai, anthropic and chat completion compatible providers) instead
+	// of relying on the model to write XML tags.
+	ToolCalling bool `mapstructure:"tool_calling"`
+
+	// StructuredOutput requests a JSON object matching the response schema
+	// (openai and chat completion compatible providers). Responses that
+	// aren't valid JSON are still parsed as XML tags.
+	StructuredOutput bool `mapstructure:"structured_output"`
+
+	// Vision marks a model that accepts images: with pane_image_command set,
+	// a picture of the exec pane is attached to each request.
+	Vision bool `mapstructure:"vision"`
+
+	// FallbackModels are the model configurations tried in order when a
+	// request to this one fails, e.g. on rate limits, server errors or a
+	// too large context
+	FallbackModels []string `mapstructure:"fallback_models"`
+
+	// Pricing in USD per million tokens, used for /usage cost estimates
+	InputCostPerMillion  float64 `mapstructure:"input_cost_per_million"`
+	OutputCostPerMillion float64 `mapstructure:"output_cost_per_million"`
+}
+
+// RolesConfig picks a model configuration, by name under models, for each
+// kind of request. Chat answers the conversation and runs its tasks, in
+// place of default_model until /model switches the session's model; Watch
+// handles watch mode observations and Summarize squashing, session
+// summaries and knowledge base notes. Empty roles use the current model.
+type RolesConfig struct {
+	Chat      string `mapstructure:"chat"`
+	Watch     string `mapstructure:"watch"`
+	Summarize string `mapstructure:"summarize"`
+}
+
+// PersonaConfig is a named profile switched with /persona or --persona.
+// Prompt is added to the system prompt, Model (a name under models) becomes
+// the session's model and KBs are loaded while the persona is active.
+type PersonaConfig struct {
+	Prompt string   `mapstructure:"prompt"`
+	Model  string   `mapstructure:"model"`
+	KBs    []string `mapstructure:"kbs"`
+}
+
+// LogConfig configures ~/.config/tmuxai/tmuxai.log. Format is "text" or
+// "json" (one object per line with time, level, session and msg), Level the
+// lowest level written (debug, info or error). The file is rotated past
+// MaxSizeMB (0 = never), keeping MaxFiles rotated files, none older than
+// MaxAgeDays (0 = no age limit).
+type LogConfig struct {
+	Format     string `mapstructure:"format"`
+	Level      string `mapstructure:"level"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`
+	MaxFiles   int    `mapstructure:"max_files"`
+	MaxAgeDays int    `mapstructure:"max_age_days"`
+}
+
+// TelemetryConfig exports OpenTelemetry traces and metrics of the agent
+// loop over OTLP/HTTP. Endpoint is the collector's base URL (e.g.
+// http://localhost:4318); when empty the standard OTEL_EXPORTER_OTLP_*
+// environment variables apply. Headers are sent with every export, with
+// environment variables expanded.
+type TelemetryConfig struct {
+	Enabled  bool              `mapstructure:"enabled"`
+	Endpoint string            `mapstructure:"endpoint"`
+	Headers  map[string]string `mapstructure:"headers"`
+}
+
+// WatchConfig tunes watch mode. Interval is the seconds between checks of
+// the panes (0 = wait_interval). With OnlyOnChange the AI is only called when
+// the pane content changed since the last check, and with Triggers only when
+// new lines match one of the regular expressions. Notify also shows comments
+// and trigger matches as desktop notifications, and Webhook POSTs them as
+// JSON with WebhookHeaders, whose environment variables are expanded.
+type WatchConfig struct {
+	Interval       int               `mapstructure:"interval"`
+	OnlyOnChange   bool              `mapstructure:"only_on_change"`
+	Triggers       []string          `mapstructure:"triggers"`
+	Notify         bool              `mapstructure:"notify"`
+	Webhook        string            `mapstructure:"webhook"`
+	WebhookHeaders map[string]string `mapstructure:"webhook_headers"`
+}
+
+// ThemeConfig picks the CLI colors: a preset (default, light or none) and
+// overrides of single colors, e.g. prompt: "#ff8800 bold"
+type ThemeConfig struct {
+	Preset string            `mapstructure:"preset"`
+	Colors map[string]string `mapstructure:"colors"`
+}
+
+// CLIConfig configures the input line: emacs or vi editing, and key
+// bindings from key names (e.g. C-o, M-e, F2) to readline functions
+type CLIConfig struct {
+	EditingMode string            `mapstructure:"editing_mode"`
+	KeyBindings map[string]string `mapstructure:"key_bindings"`
 }
 
 // PromptsConfig holds customizable prompt templates
@@ -113,11 +274,39 @@ type SkillsConfig struct {
 	TruncateDescAt     int     `mapstructure:"truncate_desc_at"`
 }
 
-// KnowledgeBaseConfig holds knowledge base configuration
+// KnowledgeBaseConfig holds knowledge base configuration. Remote lists
+// URLs loaded as KBs named after their last path segment (runbook.md is
+// "runbook"); RemoteHeaders are sent when fetching them, with environment
+// variables expanded so tokens can stay out of the config file.
+// ProjectDiscovery loads .tmuxai/context.md and .tmuxai/kb/*.md from the exec
+// pane's working directory and its parents at startup. Watch reloads loaded
+// local KBs when their files change on disk. MaxTokens caps the tokens of
+// the KBs sent whole (0 = no limit); OverBudget is "truncate" to cut every
+// KB proportionally to fit, or "refuse" to reject loads that don't fit.
 type KnowledgeBaseConfig struct {
-	AutoLoad []string     `mapstructure:"auto_load"`
-	Path     string       `mapstructure:"path"`
-	Skills   SkillsConfig `mapstructure:"skills"`
+	AutoLoad         []string          `mapstructure:"auto_load"`
+	MaxTokens        int               `mapstructure:"max_tokens"`
+	OverBudget       string            `mapstructure:"over_budget"`
+	ProjectDiscovery bool              `mapstructure:"project_discovery"`
+	Path             string            `mapstructure:"path"`
+	Remote           []string          `mapstructure:"remote"`
+	RemoteHeaders    map[string]string `mapstructure:"remote_headers"`
+	Search           KBSearchConfig    `mapstructure:"search"`
+	Skills           SkillsConfig      `mapstructure:"skills"`
+	Watch            bool              `mapstructure:"watch"`
+}
+
+// KBSearchConfig switches loaded knowledge bases from being sent whole to
+// retrieval: they are split into chunks of about ChunkChars and only the
+// TopK chunks most similar to the request are sent. Model names an entry in
+// models whose provider serves an OpenAI-compatible embeddings API, used
+// with EmbeddingModel; without it a local keyword embedding is used.
+type KBSearchConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	TopK           int    `mapstructure:"top_k"`
+	ChunkChars     int    `mapstructure:"chunk_chars"`
+	Model          string `mapstructure:"model"`
+	EmbeddingModel string `mapstructure:"embedding_model"`
 }
 
 // WebSearchConfig holds web search configuration.
@@ -145,28 +334,224 @@ type WebFetchConfig struct {
 	AllowedRedirects bool `mapstructure:"allowed_redirects"`
 }
 
+// CompactionConfig controls automatic squashing of the chat history. Once
+// the estimated context passes Threshold (a fraction of max_context_size),
+// everything but the last KeepMessages messages is replaced by a summary.
+type CompactionConfig struct {
+	Threshold    float64 `mapstructure:"threshold"`
+	KeepMessages int     `mapstructure:"keep_messages"`
+}
+
+// RetryConfig controls retries of AI requests that fail with rate limits,
+// transient server errors or network failures. MaxAttempts counts the first
+// request; a Retry-After header from the provider takes precedence over the
+// computed backoff (capped at MaxBackoffMs).
+type RetryConfig struct {
+	MaxAttempts      int  `mapstructure:"max_attempts"`
+	InitialBackoffMs int  `mapstructure:"initial_backoff_ms"`
+	MaxBackoffMs     int  `mapstructure:"max_backoff_ms"`
+	Jitter           bool `mapstructure:"jitter"`
+}
+
+// SafetyConfig holds risk-based confirmation rules for executed commands.
+// AutoApproveSafe skips confirmation for commands the risk scorer rates safe;
+// AlwaysConfirmDanger asks for dangerous commands even with exec_confirm off
+// or in yolo mode. AllowPatterns and DenyPatterns are regexes that extend the
+// risk scorer's built-in safe and dangerous patterns. AlwaysAllowPatterns are
+// the rules saved by answering [a] at a confirmation; unlike
+// whitelist_patterns they never cover dangerous commands.
+type SafetyConfig struct {
+	AutoApproveSafe     bool     `mapstructure:"auto_approve_safe"`
+	AlwaysConfirmDanger bool     `mapstructure:"always_confirm_danger"`
+	AllowPatterns       []string `mapstructure:"allow_patterns"`
+	DenyPatterns        []string `mapstructure:"deny_patterns"`
+	AlwaysAllowPatterns []string `mapstructure:"always_allow_patterns"`
+}
+
+// FilesConfig governs the ReadFile action, which gives the AI a file's
+// contents without running cat in the exec pane, and the WriteFile and
+// ApplyPatch actions, which change files after showing their diff. Files
+// must be under one of AllowedPaths (~ expanded), or under the exec pane's
+// working directory when it's empty; MaxReadBytes bounds what is returned
+// of each. WriteConfirm asks before each change (not with yolo), and Backup
+// keeps the previous version of changed files in the config directory.
+type FilesConfig struct {
+	Read         bool     `mapstructure:"read"`
+	Write        bool     `mapstructure:"write"`
+	WriteConfirm bool     `mapstructure:"write_confirm"`
+	Backup       bool     `mapstructure:"backup"`
+	AllowedPaths []string `mapstructure:"allowed_paths"`
+	MaxReadBytes int      `mapstructure:"max_read_bytes"`
+}
+
+// GitContextConfig adds the branch, short status and diff of the git repo
+// the exec pane is in to the context of every request. The diff is cut
+// after MaxDiffLines lines.
+type GitContextConfig struct {
+	Enabled      bool `mapstructure:"enabled"`
+	MaxDiffLines int  `mapstructure:"max_diff_lines"`
+}
+
+// CaptureConfig shapes the pane content sent as context. ANSI captures with
+// escape sequences (capture-pane -e) so the model sees colors, e.g. which
+// test lines are red. Normalize strips other escape sequences and
+// carriage-return redraws, trims trailing whitespace, collapses runs of blank
+// lines and squeezes repeated spinner and progress bar lines.
+type CaptureConfig struct {
+	ANSI      bool `mapstructure:"ansi"`
+	Normalize bool `mapstructure:"normalize"`
+}
+
+// TypingConfig slows down send_keys for programs that drop keys sent all at
+// once, like vim over mosh. KeyDelay and LineDelay are milliseconds between
+// keys and after each Enter. Human varies the key delay like a person typing,
+// for demos.
+type TypingConfig struct {
+	KeyDelay  int  `mapstructure:"key_delay"`
+	LineDelay int  `mapstructure:"line_delay"`
+	Human     bool `mapstructure:"human"`
+}
+
+// ShellContextConfig adds a summary of the user's shell history and alias
+// definitions (bash, zsh or fish) to the system prompt, so suggested commands
+// use the tools they already use. The last HistoryLines commands of the
+// history file are read, and MaxCommands bounds each list of the summary.
+type ShellContextConfig struct {
+	Enabled      bool `mapstructure:"enabled"`
+	HistoryLines int  `mapstructure:"history_lines"`
+	MaxCommands  int  `mapstructure:"max_commands"`
+}
+
+// RedactionConfig masks secrets in pane content and command output before
+// they are sent to the model: common credential shapes (API keys, bearer
+// tokens, private key blocks, password and token assignments) plus Patterns.
+type RedactionConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Patterns []string `mapstructure:"patterns"`
+}
+
+// SandboxConfig runs exec_command in a throwaway Docker container instead of
+// the exec pane. Workdir is mounted at /workspace (defaults to the current
+// directory); Network is passed to `docker run --network`, and TimeoutSeconds
+// bounds each command.
+type SandboxConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Image          string   `mapstructure:"image"`
+	Workdir        string   `mapstructure:"workdir"`
+	Network        string   `mapstructure:"network"`
+	TimeoutSeconds int      `mapstructure:"timeout_seconds"`
+	DockerArgs     []string `mapstructure:"docker_args"`
+}
+
+// HooksConfig holds shell commands run on agent events with the event as
+// JSON on stdin. A pre_exec hook exiting non-zero blocks the command; the
+// others are only informed. TimeoutSeconds bounds each run.
+type HooksConfig struct {
+	PreExec           string `mapstructure:"pre_exec"`
+	PostExec          string `mapstructure:"post_exec"`
+	OnConfirmRequired string `mapstructure:"on_confirm_required"`
+	OnTaskDone        string `mapstructure:"on_task_done"`
+	TimeoutSeconds    int    `mapstructure:"timeout_seconds"`
+}
+
 // TmuxConfig holds tmux-specific behavior settings.
 // ExecSplitArgs are raw args passed to `tmux split-window` before target/format flags.
+// SSHHost, when set, makes TmuxAI observe and drive a tmux server on that host
+// via `ssh <ssh_args> <ssh_host> tmux ...`; RemoteTarget selects the remote
+// session or window (defaults to the remote server's current window).
+// PromptDetection is how /prepare finds command boundaries in the exec pane:
+// "ps1" rewrites the shell prompt, "osc133" reads the OSC 133 markers of the
+// shell's own integration instead, and "hook" installs a pre-prompt hook that
+// reports exit codes to a tmux pane option.
 type TmuxConfig struct {
-	ExecSplitArgs []string `mapstructure:"exec_split_args"`
+	ExecSplitArgs   []string `mapstructure:"exec_split_args"`
+	SSHHost         string   `mapstructure:"ssh_host"`
+	SSHArgs         []string `mapstructure:"ssh_args"`
+	RemoteTarget    string   `mapstructure:"remote_target"`
+	PromptDetection string   `mapstructure:"prompt_detection"`
+	ControlMode     bool     `mapstructure:"control_mode"`
 }
 
 // DefaultConfig returns a configuration with default values
 func DefaultConfig() *Config {
 	return &Config{
-		Debug:                 false,
+		Debug: false,
+		Log: LogConfig{
+			Format:    "text",
+			Level:     "debug",
+			MaxSizeMB: 10,
+			MaxFiles:  3,
+		},
 		Yolo:                  false,
+		DryRun:                false,
+		AuditLog:              true,
+		SaveSessions:          true,
+		SessionSummaries:      true,
 		MaxCaptureLines:       200,
+		PaneDiff:              true,
 		MaxContextSize:        100000,
 		StatusLine:            ``,
+		RenderMarkdown:        true,
+		ReloadConfig:          true,
 		WaitInterval:          5,
+		ExecTimeout:           0,
+		ExecTimeoutInterrupt:  true,
+		ExecStreamInterval:    0,
+		MaxSubagents:          2,
+		MaxIterations:         25,
 		SendKeysConfirm:       true,
 		PasteMultilineConfirm: true,
 		ExecConfirm:           true,
+		McpConfirm:            true,
+		ContextWindows:        "current",
+		FixOnFailure:          "offer",
 		WhitelistPatterns:     []string{},
 		BlacklistPatterns:     []string{},
+		Compaction: CompactionConfig{
+			Threshold:    0.8,
+			KeepMessages: 6,
+		},
+		Watch: WatchConfig{
+			Triggers: []string{},
+		},
+		Theme: ThemeConfig{
+			Preset: "default",
+		},
+		CLI: CLIConfig{
+			EditingMode: "emacs",
+		},
+		Sandbox: SandboxConfig{
+			Image:          "alpine:latest",
+			Network:        "none",
+			TimeoutSeconds: 120,
+		},
+		GitContext: GitContextConfig{
+			MaxDiffLines: 100,
+		},
+		ShellContext: ShellContextConfig{
+			HistoryLines: 2000,
+			MaxCommands:  25,
+		},
+		Redaction: RedactionConfig{
+			Enabled:  true,
+			Patterns: []string{},
+		},
+		Files: FilesConfig{
+			Read:         true,
+			Write:        true,
+			WriteConfirm: true,
+			Backup:       true,
+			AllowedPaths: []string{},
+			MaxReadBytes: 65536,
+		},
+		Hooks: HooksConfig{
+			TimeoutSeconds: 10,
+		},
 		Tmux: TmuxConfig{
 			ExecSplitArgs: []string{"-d", "-h"},
+			// Reuse one SSH connection and never prompt for a password mid-session
+			SSHArgs:         []string{"-o", "BatchMode=yes", "-o", "ControlMaster=auto", "-o", "ControlPath=~/.ssh/tmuxai-%C", "-o", "ControlPersist=10m"},
+			PromptDetection: "ps1",
 		},
 		OpenRouter: OpenRouterConfig{
 			BaseURL: "https://openrouter.ai/api/v1",
@@ -187,8 +572,15 @@ func DefaultConfig() *Config {
 			ChatAssistant: ``,
 		},
 		KnowledgeBase: KnowledgeBaseConfig{
-			AutoLoad: []string{},
-			Path:     "",
+			AutoLoad:         []string{},
+			OverBudget:       "truncate",
+			ProjectDiscovery: true,
+			Path:             "",
+			Watch:            true,
+			Search: KBSearchConfig{
+				TopK:       5,
+				ChunkChars: 1500,
+			},
 			Skills: SkillsConfig{
 				Enabled:            false,
 				AutoScan:           true,
@@ -215,6 +607,12 @@ func DefaultConfig() *Config {
 			TimeoutSeconds:   8,
 			AllowedRedirects: true,
 		},
+		Retry: RetryConfig{
+			MaxAttempts:      3,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     30000,
+			Jitter:           true,
+		},
 	}
 }
 
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileUsed returns the config file Load read from, or the default
+// ~/.config/tmuxai/config.yaml when no file was found.
+func ConfigFileUsed() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return GetConfigFilePath("config.yaml")
+}
+
+// AppendConfigListValue appends value to the list at a dot-notation key in a
+// YAML config file, creating the file, parent mappings and list as needed.
+// The file is edited as a YAML node tree so comments and ordering survive.
+func AppendConfigListValue(path, key, value string) error {
+	return updateConfigFile(path, func(root *yaml.Node) error {
+		list, err := lookupNode(root, strings.Split(key, "."), yaml.SequenceNode)
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Content {
+			if item.Value == value {
+				return nil
+			}
+		}
+		list.Style = 0 // switch flow lists like [] to block style
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		return nil
+	})
+}
+
+// updateConfigFile loads a YAML file as a node tree, applies fn and writes it back
+func updateConfigFile(path string, fn func(root *yaml.Node) error) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	if err := fn(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// lookupNode walks a mapping node along keys, creating missing entries. The
+// final node is created with the given kind if it doesn't exist.
+func lookupNode(node *yaml.Node, keys []string, kind yaml.Kind) (*yaml.Node, error) {
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("config key %s is not a mapping", strings.Join(keys[:i], "."))
+		}
+
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child = node.Content[j+1]
+				break
+			}
+		}
+
+		wantKind := yaml.MappingNode
+		if i == len(keys)-1 {
+			wantKind = kind
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: wantKind}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		} else if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			// an empty key like "safety:" parses as null
+			child.Kind, child.Tag, child.Value = wantKind, "", ""
+		}
+		if child.Kind != wantKind {
+			return nil, fmt.Errorf("config key %s has an unexpected type", strings.Join(keys[:i+1], "."))
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// SetConfigValue sets the scalar at a dot-notation key in a YAML config file,
+// creating the file and parent mappings as needed. Only the value changes,
+// so comments on the key survive.
+func SetConfigValue(path, key string, value any) error {
+	return updateConfigFile(path, func(root *yaml.Node) error {
+		node, err := lookupNode(root, strings.Split(key, "."), yaml.ScalarNode)
+		if err != nil {
+			return err
+		}
+		var encoded yaml.Node
+		if err := encoded.Encode(value); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		if encoded.Kind != yaml.ScalarNode {
+			return fmt.Errorf("config key %s only takes a single value", key)
+		}
+		node.Tag, node.Value, node.Style = encoded.Tag, encoded.Value, encoded.Style
+		return nil
+	})
+}
+
+// Value returns the value of a dot-notation key in cfg
+func Value(cfg *Config, key string) (any, bool) {
+	field, ok := lookupField(reflect.ValueOf(cfg).Elem(), strings.Split(key, "."))
+	if !ok {
+		return nil, false
+	}
+	return field.Interface(), true
+}
+
+// SetValue sets a dot-notation key in cfg. The value must have the key's kind.
+func SetValue(cfg *Config, key string, value any) error {
+	field, ok := lookupField(reflect.ValueOf(cfg).Elem(), strings.Split(key, "."))
+	if !ok {
+		return fmt.Errorf("unknown config key %s", key)
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Kind() != field.Kind() {
+		return fmt.Errorf("config key %s takes a %s value, got %v", key, field.Kind(), value)
+	}
+	field.Set(v.Convert(field.Type()))
+	return nil
+}
+
+// lookupField walks struct fields along keys by their mapstructure tags
+func lookupField(val reflect.Value, keys []string) (reflect.Value, bool) {
+	for _, key := range keys {
+		if val.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		found := false
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			tag := field.Tag.Get("mapstructure")
+			if tag == "" {
+				tag = strings.ToLower(field.Name)
+			}
+			if tag == key {
+				val, found = val.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return val, val.Kind() != reflect.Struct
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendConfigListValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# my settings
+exec_confirm: true # keep asking
+safety:
+  deny_patterns: []
+`), 0o644))
+
+	require.NoError(t, AppendConfigListValue(path, "safety.deny_patterns", `\bterraform\s+destroy\b`))
+	require.NoError(t, AppendConfigListValue(path, "safety.allow_patterns", `^make\s+test$`))
+	require.NoError(t, AppendConfigListValue(path, "safety.deny_patterns", `\bterraform\s+destroy\b`))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# my settings
+exec_confirm: true # keep asking
+safety:
+  deny_patterns:
+    - \bterraform\s+destroy\b
+  allow_patterns:
+    - ^make\s+test$
+`, string(data))
+}
+
+func TestAppendConfigListValueCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, AppendConfigListValue(path, "safety.allow_patterns", "^ls"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "safety:\n  allow_patterns:\n    - ^ls\n", string(data))
+}
+
+func TestAppendConfigListValueTypeMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("safety: strict\n"), 0o644))
+	assert.Error(t, AppendConfigListValue(path, "safety.allow_patterns", "^ls"))
+}
+
+func TestSetConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# my settings
+max_capture_lines: 200 # lines per pane
+watch:
+`), 0o644))
+
+	require.NoError(t, SetConfigValue(path, "max_capture_lines", 500))
+	require.NoError(t, SetConfigValue(path, "watch.notify", true))
+	require.NoError(t, SetConfigValue(path, "status_line", "{model} >"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# my settings
+max_capture_lines: 500 # lines per pane
+watch:
+  notify: true
+status_line: '{model} >'
+`, string(data))
+
+	require.NoError(t, os.WriteFile(path, []byte("watch: 5\n"), 0o644))
+	assert.Error(t, SetConfigValue(path, "watch.interval", 10))
+}
+
+func TestValueAndSetValue(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, SetValue(cfg, "watch.interval", 12))
+	require.NoError(t, SetValue(cfg, "sandbox.enabled", true))
+
+	value, ok := Value(cfg, "watch.interval")
+	require.True(t, ok)
+	assert.Equal(t, 12, value)
+	assert.True(t, cfg.Sandbox.Enabled)
+
+	_, ok = Value(cfg, "watch")
+	assert.False(t, ok)
+	assert.Error(t, SetValue(cfg, "watch.interval", "soon"))
+	assert.Error(t, SetValue(cfg, "no_such_key", 1))
+}
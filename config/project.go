@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// ProjectConfigFile is the per-project override file looked up from the exec
+// pane's working directory
+const ProjectConfigFile = ".tmuxai.yaml"
+
+// ApplyProjectFile layers a project's .tmuxai.yaml over cfg. A project file
+// may come with a repository someone else wrote, so it can only pick one of
+// the user's models, change capture and context limits, auto-load KBs and
+// tighten the safety rules; list values are added to the user's. The keys it
+// may not set are returned as ignored.
+func ApplyProjectFile(cfg *Config, path string) (ignored []string, err error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read project config %s: %w", path, err)
+	}
+
+	keys := v.AllKeys()
+	slices.Sort(keys)
+	for _, key := range keys {
+		switch key {
+		case "default_model":
+			cfg.DefaultModel = v.GetString(key)
+		case "max_capture_lines":
+			cfg.MaxCaptureLines = v.GetInt(key)
+		case "max_context_size":
+			cfg.MaxContextSize = v.GetInt(key)
+		case "knowledge_base.auto_load":
+			cfg.KnowledgeBase.AutoLoad = appendMissing(cfg.KnowledgeBase.AutoLoad, v.GetStringSlice(key))
+		case "blacklist_patterns":
+			cfg.BlacklistPatterns = appendMissing(cfg.BlacklistPatterns, v.GetStringSlice(key))
+		case "safety.deny_patterns":
+			cfg.Safety.DenyPatterns = appendMissing(cfg.Safety.DenyPatterns, v.GetStringSlice(key))
+		case "exec_confirm":
+			cfg.ExecConfirm = cfg.ExecConfirm || v.GetBool(key)
+		case "safety.always_confirm_danger":
+			cfg.Safety.AlwaysConfirmDanger = cfg.Safety.AlwaysConfirmDanger || v.GetBool(key)
+		default:
+			ignored = append(ignored, key)
+		}
+	}
+	return ignored, nil
+}
+
+// appendMissing returns list with the values it doesn't have yet added, in
+// a new slice
+func appendMissing(list, values []string) []string {
+	merged := slices.Clone(list)
+	for _, value := range values {
+		if !slices.Contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
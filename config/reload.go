@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Reload reads the config file Load found again, with the same environment
+// overrides, into a fresh Config
+func Reload() (*Config, error) {
+	config := DefaultConfig()
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	if err := viper.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	ResolveEnvKeyInConfig(config)
+	return config, nil
+}
+
+// ChangedKeys returns the dot-notation keys whose values differ between two
+// configs, in struct order. Maps and lists count as a single key.
+func ChangedKeys(a, b *Config) []string {
+	return changedKeys(reflect.ValueOf(*a), reflect.ValueOf(*b), "")
+}
+
+func changedKeys(a, b reflect.Value, prefix string) []string {
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, changedKeys(a.Field(i), b.Field(i), key)...)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
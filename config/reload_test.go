@@ -0,0 +1,19 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedKeys(t *testing.T) {
+	a := DefaultConfig()
+	b := DefaultConfig()
+	assert.Empty(t, ChangedKeys(a, b))
+
+	b.MaxCaptureLines = 500
+	b.Tmux.SSHHost = "devbox"
+	b.Safety.DenyPatterns = []string{"rm"}
+	b.Models["fast"] = ModelConfig{Provider: "openai", Model: "gpt-4o-mini"}
+	assert.Equal(t, []string{"max_capture_lines", "safety.deny_patterns", "tmux.ssh_host", "models"}, ChangedKeys(a, b))
+}
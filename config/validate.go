@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Providers are the values models.<name>.provider accepts
+var Providers = []string{"openai", "azure", "openrouter", "requesty", "gemini", "github-copilot", "bedrock", "anthropic", "ollama"}
+
+// keylessProviders authenticate without an api_key
+var keylessProviders = []string{"github-copilot", "bedrock", "ollama"}
+
+// Issue is a problem in a config, with the key it concerns and how to fix it
+type Issue struct {
+	Key     string
+	Problem string
+	Fix     string
+}
+
+// UnknownKeys returns the keys of a YAML config file that don't match any
+// config field, usually typos, sorted
+func UnknownKeys(path string) ([]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if !knownKey(reflect.TypeOf(Config{}), strings.Split(key, ".")) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// knownKey walks a type along keys: struct fields by mapstructure tag, and
+// any name for a map entry
+func knownKey(typ reflect.Type, keys []string) bool {
+	for i, key := range keys {
+		switch typ.Kind() {
+		case reflect.Struct:
+			found := false
+			for j := 0; j < typ.NumField(); j++ {
+				field := typ.Field(j)
+				tag := field.Tag.Get("mapstructure")
+				if tag == "" {
+					tag = strings.ToLower(field.Name)
+				}
+				if tag == key {
+					typ, found = field.Type, true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case reflect.Map:
+			typ = typ.Elem()
+		default:
+			// a scalar or list can't have sub keys
+			return i == len(keys)
+		}
+	}
+	return true
+}
+
+// Validate checks a loaded config for settings that would fail at runtime:
+// model profiles without a provider, model or credentials, references to
+// models that don't exist, invalid regexes and unknown enum values.
+func Validate(cfg *Config) []Issue {
+	var issues []Issue
+	add := func(key, problem, fix string) {
+		issues = append(issues, Issue{Key: key, Problem: problem, Fix: fix})
+	}
+
+	names := make([]string, 0, len(cfg.Models))
+	for name := range cfg.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mc := cfg.Models[name]
+		key := "models." + name
+		switch {
+		case mc.Provider == "":
+			add(key+".provider", "missing", "set it to one of: "+strings.Join(Providers, ", "))
+			continue
+		case !slices.Contains(Providers, mc.Provider):
+			add(key+".provider", fmt.Sprintf("unknown provider %q", mc.Provider), "use one of: "+strings.Join(Providers, ", "))
+			continue
+		}
+		if mc.Provider == "azure" {
+			if mc.APIBase == "" {
+				add(key+".api_base", "missing for the azure provider", "set it to your resource URL, e.g. https://<resource>.openai.azure.com")
+			}
+			if mc.DeploymentName == "" {
+				add(key+".deployment_name", "missing for the azure provider", "set it to the name of your Azure OpenAI deployment")
+			}
+		} else if mc.Model == "" {
+			add(key+".model", "missing", "set the model name the provider expects, e.g. gpt-4o")
+		}
+		for _, fallback := range mc.FallbackModels {
+			if _, ok := cfg.Models[fallback]; !ok {
+				add(key+".fallback_models", fmt.Sprintf("model %q is not defined in models", fallback), "use one of the names under models")
+			}
+		}
+		if mc.Provider == "github-copilot" && mc.CopilotCLIURL != "" {
+			for field, value := range map[string]string{"api_key": mc.APIKey + mc.APIKeyCmd + mc.APIKeyKeychain, "base_url": mc.BaseURL, "proxy": mc.Proxy} {
+				if value != "" {
+					add(key+"."+field, "ignored with copilot_cli_url", "set it on the copilot CLI server instead, or drop copilot_cli_url")
+				}
+			}
+		}
+		if mc.APIKey == "" && mc.APIKeyCmd == "" && mc.APIKeyKeychain == "" && !slices.Contains(keylessProviders, mc.Provider) {
+			add(key+".api_key", "missing", fmt.Sprintf("set it, reference an environment variable with api_key: \"${%s_API_KEY}\", or read it with api_key_cmd or api_key_keychain", strings.ToUpper(strings.ReplaceAll(mc.Provider, "-", "_"))))
+		}
+	}
+
+	if len(cfg.Models) == 0 && cfg.OpenRouter.APIKey == "" && cfg.Requesty.APIKey == "" && cfg.OpenAI.APIKey == "" && cfg.AzureOpenAI.APIKey == "" {
+		add("models", "no AI provider is configured", "add an entry under models with a provider, model and api_key")
+	}
+	for key, name := range map[string]string{
+		"default_model":               cfg.DefaultModel,
+		"session_summary_model":       cfg.SessionSummaryModel,
+		"knowledge_base.search.model": cfg.KnowledgeBase.Search.Model,
+		"roles.chat":                  cfg.Roles.Chat,
+		"roles.watch":                 cfg.Roles.Watch,
+		"roles.summarize":             cfg.Roles.Summarize,
+	} {
+		if name != "" {
+			if _, ok := cfg.Models[name]; !ok {
+				add(key, fmt.Sprintf("model %q is not defined in models", name), "use one of the names under models")
+			}
+		}
+	}
+
+	personas := make([]string, 0, len(cfg.Personas))
+	for name := range cfg.Personas {
+		personas = append(personas, name)
+	}
+	sort.Strings(personas)
+	for _, name := range personas {
+		if model := cfg.Personas[name].Model; model != "" {
+			if _, ok := cfg.Models[model]; !ok {
+				add("personas."+name+".model", fmt.Sprintf("model %q is not defined in models", model), "use one of the names under models")
+			}
+		}
+	}
+	if cfg.Persona != "" {
+		if _, ok := cfg.Personas[cfg.Persona]; !ok {
+			add("persona", fmt.Sprintf("persona %q is not defined in personas", cfg.Persona), "use one of the names under personas")
+		}
+	}
+
+	for key, patterns := range map[string][]string{
+		"whitelist_patterns":           cfg.WhitelistPatterns,
+		"blacklist_patterns":           cfg.BlacklistPatterns,
+		"safety.allow_patterns":        cfg.Safety.AllowPatterns,
+		"safety.deny_patterns":         cfg.Safety.DenyPatterns,
+		"safety.always_allow_patterns": cfg.Safety.AlwaysAllowPatterns,
+		"watch.triggers":               cfg.Watch.Triggers,
+		"redaction.patterns":           cfg.Redaction.Patterns,
+	} {
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				add(key, fmt.Sprintf("invalid regex %q: %v", pattern, err), "fix the pattern; backslashes need no escaping in single-quoted YAML")
+			}
+		}
+	}
+
+	for _, enum := range []struct {
+		key, value string
+		allowed    []string
+	}{
+		{"context_windows", cfg.ContextWindows, []string{"current", "all"}},
+		{"fix_on_failure", cfg.FixOnFailure, []string{"off", "offer", "auto"}},
+		{"tmux.prompt_detection", cfg.Tmux.PromptDetection, []string{"ps1", "osc133", "hook"}},
+		{"knowledge_base.over_budget", cfg.KnowledgeBase.OverBudget, []string{"truncate", "refuse"}},
+		{"cli.editing_mode", cfg.CLI.EditingMode, []string{"emacs", "vi"}},
+		{"theme.preset", cfg.Theme.Preset, []string{"default", "light", "none"}},
+		{"log.format", cfg.Log.Format, []string{"text", "json"}},
+		{"log.level", cfg.Log.Level, []string{"debug", "info", "error"}},
+	} {
+		if enum.value != "" && !slices.Contains(enum.allowed, enum.value) {
+			add(enum.key, fmt.Sprintf("unknown value %q", enum.value), "use one of: "+strings.Join(enum.allowed, ", "))
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Key < issues[j].Key })
+	return issues
+}
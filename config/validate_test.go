@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`max_captre_lines: 100
+watch:
+  interval: 5
+  webhook_headers:
+    Authorization: token
+models:
+  fast:
+    provider: openai
+    temprature: 0.2
+exec_confirm:
+  always: true
+`), 0o644))
+
+	unknown, err := UnknownKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec_confirm.always", "max_captre_lines", "models.fast.temprature"}, unknown)
+}
+
+func TestUnknownKeysExampleConfig(t *testing.T) {
+	unknown, err := UnknownKeys(filepath.Join("..", "config.example.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, unknown, "config.example.yaml should only use known keys")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, []Issue{{Key: "models", Problem: "no AI provider is configured", Fix: "add an entry under models with a provider, model and api_key"}}, Validate(cfg))
+
+	cfg.DefaultModel = "gpt"
+	cfg.SessionSummaryModel = "local"
+	cfg.Models = map[string]ModelConfig{
+		"local": {Provider: "ollama", Model: "llama3", FallbackModels: []string{"gpt", "nope"}},
+		"gpt":   {Provider: "openai", Model: "gpt-4o"},
+		"az":    {Provider: "azure", APIKey: "k", APIBase: "https://x.openai.azure.com"},
+		"typo":  {Provider: "open-ai", Model: "gpt-4o", APIKey: "k"},
+		"gh":    {Provider: "github-copilot", Model: "gpt-4o", Proxy: "http://proxy:3128", CopilotCLIURL: "localhost:3000"},
+	}
+	cfg.Safety.DenyPatterns = []string{`\bterraform\s+destroy\b`, `(unclosed`}
+	cfg.Redaction.Patterns = []string{`[`}
+	cfg.ContextWindows = "everything"
+	cfg.Roles = RolesConfig{Chat: "gpt", Watch: "nope"}
+	cfg.Personas = map[string]PersonaConfig{"sre": {Model: "gpt"}, "proto": {Model: "fast"}}
+	cfg.Persona = "ops"
+
+	var keys []string
+	for _, issue := range Validate(cfg) {
+		keys = append(keys, issue.Key)
+	}
+	assert.Equal(t, []string{
+		"context_windows",
+		"models.az.deployment_name",
+		"models.gh.proxy",
+		"models.gpt.api_key",
+		"models.local.fallback_models",
+		"models.typo.provider",
+		"persona",
+		"personas.proto.model",
+		"redaction.patterns",
+		"roles.watch",
+		"safety.deny_patterns",
+	}, keys)
+}
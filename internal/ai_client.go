@@ -3,6 +3,7 @@ package internal
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,6 +17,9 @@ import (
 
 	"github.com/alvinunreal/tmuxai/config"
 	"github.com/alvinunreal/tmuxai/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/genai"
 
 	bedrockruntime "github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -27,35 +31,80 @@ type AiClient struct {
 	configMgr    *Manager // To access model configuration methods
 	client       *http.Client
 	geminiClient *genai.Client
+	geminiKey    string // cache key: api_key|base_url
 	geminiMu     sync.Mutex
 
 	// GitHub Copilot SDK client (wraps the copilot CLI subprocess)
 	copilotClient *copilot.Client
-	copilotToken  string
+	copilotKey    string // cache key: api_key|base_url|proxy|copilot_cli_url
 	copilotMu     sync.Mutex
 
 	// AWS Bedrock runtime client
 	bedrockClient *bedrockruntime.Client
 	bedrockKey    string // cache key: region|profile
 	bedrockMu     sync.Mutex
+
+	// API keys read with api_key_cmd or from the keychain, by secret source
+	secrets   map[string]string
+	secretsMu sync.Mutex
 }
 
-// Message represents a chat message
+// Message represents a chat message. Images are base64 PNGs attached to it.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"-"`
+}
+
+// MarshalJSON writes a message with images as chat completion content parts
+func (msg Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	if len(msg.Images) == 0 {
+		return json.Marshal(plain(msg))
+	}
+	parts := []map[string]interface{}{{"type": "text", "text": msg.Content}}
+	for _, image := range msg.Images {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": pngDataURL(image)},
+		})
+	}
+	return json.Marshal(map[string]interface{}{"role": msg.Role, "content": parts})
 }
 
 // ChatCompletionRequest represents a request to the chat completion API
 type ChatCompletionRequest struct {
-	Model    string    `json:"model,omitempty"`
-	Messages []Message `json:"messages"`
+	Model          string              `json:"model,omitempty"`
+	Messages       []Message           `json:"messages"`
+	Stream         bool                `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions      `json:"stream_options,omitempty"`
+	Tools          []ChatTool          `json:"tools,omitempty"`
+	ResponseFormat *ChatResponseFormat `json:"response_format,omitempty"`
+}
+
+// StreamOptions asks for a final usage chunk on streamed chat completions
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatCompletionUsage represents token usage in the chat completion API
+type ChatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // ChatCompletionChoice represents a choice in the chat completion response
 type ChatCompletionChoice struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
+	Index   int                   `json:"index"`
+	Message ChatCompletionMessage `json:"message"`
+}
+
+// ChatCompletionMessage represents the assistant message in a chat completion choice
+type ChatCompletionMessage struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []ChatToolCall `json:"tool_calls,omitempty"`
 }
 
 // ChatCompletionResponse represents a response from the chat completion API
@@ -64,6 +113,7 @@ type ChatCompletionResponse struct {
 	Object  string                 `json:"object"`
 	Created int64                  `json:"created"`
 	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
 }
 
 // Responses API Types
@@ -86,6 +136,10 @@ type ResponseOutputItem struct {
 	Content []ResponseContent `json:"content,omitempty"`
 	Role    string            `json:"role,omitempty"` // "assistant", "user", etc.
 	Summary []interface{}     `json:"summary,omitempty"`
+
+	// Set on "function_call" items
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 // ResponseRequest represents a request to the Responses API
@@ -98,6 +152,7 @@ type ResponseRequest struct {
 	Store              bool                   `json:"store,omitempty"`
 	Include            []string               `json:"include,omitempty"`
 	Text               map[string]interface{} `json:"text,omitempty"` // for structured outputs
+	PromptCacheKey     string                 `json:"prompt_cache_key,omitempty"`
 }
 
 // Response represents a response from the Responses API
@@ -121,10 +176,16 @@ type ResponseError struct {
 
 // ResponseUsage represents token usage in the Responses API
 type ResponseUsage struct {
-	InputTokens     int `json:"input_tokens"`
-	OutputTokens    int `json:"output_tokens"`
-	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
-	TotalTokens     int `json:"total_tokens"`
+	InputTokens        int                 `json:"input_tokens"`
+	InputTokensDetails *InputTokensDetails `json:"input_tokens_details,omitempty"`
+	OutputTokens       int                 `json:"output_tokens"`
+	ReasoningTokens    int                 `json:"reasoning_tokens,omitempty"`
+	TotalTokens        int                 `json:"total_tokens"`
+}
+
+// InputTokensDetails breaks down input tokens in the Responses API
+type InputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
 }
 
 func NewAiClient(cfg *config.Config) *AiClient {
@@ -139,27 +200,62 @@ func (c *AiClient) SetConfigManager(mgr *Manager) {
 	c.configMgr = mgr
 }
 
+// recordUsage forwards provider-reported token usage to the manager
+func (c *AiClient) recordUsage(ctx context.Context, usage TokenUsage) {
+	if c.configMgr == nil || (usage.PromptTokens == 0 && usage.CompletionTokens == 0) {
+		return
+	}
+	logger.Debug("Token usage: prompt=%d completion=%d", usage.PromptTokens, usage.CompletionTokens)
+	recordTokens(ctx, usage)
+	c.configMgr.recordUsage(usage)
+}
+
 // getOrCreateCopilotClient returns the cached Copilot SDK client, creating it if necessary.
-func (c *AiClient) getOrCreateCopilotClient(githubToken string) (*copilot.Client, error) {
+func (c *AiClient) getOrCreateCopilotClient(mc config.ModelConfig) (*copilot.Client, error) {
 	c.copilotMu.Lock()
 	defer c.copilotMu.Unlock()
 
-	// Reuse the cached client only if it was created with the same GitHub token.
-	if c.copilotClient != nil && c.copilotToken == githubToken {
+	// Reuse the cached client only if it was created with the same token and endpoints.
+	key := mc.APIKey + "|" + mc.BaseURL + "|" + mc.Proxy + "|" + mc.CopilotCLIURL
+	if c.copilotClient != nil && c.copilotKey == key {
 		return c.copilotClient, nil
 	}
 
+	cl := copilot.NewClient(copilotClientOptions(mc))
+	c.copilotClient = cl
+	c.copilotKey = key
+	return cl, nil
+}
+
+// copilotClientOptions configures the copilot CLI for a model: a GitHub
+// Enterprise host is passed as GH_HOST and a proxy as HTTPS_PROXY and
+// HTTP_PROXY. With copilot_cli_url an existing server is used, which
+// manages its own host, proxy and auth.
+func copilotClientOptions(mc config.ModelConfig) *copilot.ClientOptions {
 	opts := &copilot.ClientOptions{
 		LogLevel: "warning", // copilot CLI 1.x uses "warning" not "warn"
 	}
-	if githubToken != "" {
-		opts.GitHubToken = githubToken
+	if mc.CopilotCLIURL != "" {
+		opts.CLIUrl = mc.CopilotCLIURL
+		return opts
+	}
+	if mc.APIKey != "" {
+		opts.GitHubToken = mc.APIKey
 	}
 
-	cl := copilot.NewClient(opts)
-	c.copilotClient = cl
-	c.copilotToken = githubToken
-	return cl, nil
+	var env []string
+	if mc.BaseURL != "" {
+		host := strings.TrimPrefix(strings.TrimPrefix(mc.BaseURL, "https://"), "http://")
+		env = append(env, "GH_HOST="+strings.TrimSuffix(host, "/"))
+	}
+	if mc.Proxy != "" {
+		env = append(env, "HTTPS_PROXY="+mc.Proxy, "HTTP_PROXY="+mc.Proxy)
+	}
+	if len(env) > 0 {
+		// later entries win, so these override the inherited environment
+		opts.Env = append(os.Environ(), env...)
+	}
+	return opts
 }
 
 // buildCopilotPrompt converts a slice of Messages into copilot-sdk/go required prompt format
@@ -189,15 +285,7 @@ func buildCopilotPrompt(messages []Message) (prompt string, systemInstruction st
 
 // CopilotGenerateContent sends via the copilot-sdk/go, converts the conversation history into a single-turn prompt
 func (c *AiClient) CopilotGenerateContent(ctx context.Context, messages []Message, model string) (string, error) {
-	// Resolve auth token and model from config
-	var githubToken string
-	if c.configMgr != nil {
-		if mc, exists := c.configMgr.GetCurrentModelConfig(); exists && mc.Provider == "github-copilot" {
-			githubToken = mc.APIKey
-		}
-	}
-
-	cl, err := c.getOrCreateCopilotClient(githubToken)
+	cl, err := c.getOrCreateCopilotClient(c.providerModelConfig("github-copilot"))
 	if err != nil {
 		return "", fmt.Errorf("failed to create Copilot client: %w", err)
 	}
@@ -272,6 +360,10 @@ func (c *AiClient) determineAPIType(model string) string {
 				return "github-copilot"
 			case "bedrock":
 				return "bedrock"
+			case "anthropic":
+				return "anthropic"
+			case "ollama":
+				return "ollama"
 			default:
 				return "openrouter"
 			}
@@ -293,8 +385,55 @@ func (c *AiClient) determineAPIType(model string) string {
 	return "openrouter"
 }
 
-// GetResponseFromChatMessages gets a response from the AI based on chat messages
+// providerModelConfig returns the current model configuration when it
+// belongs to the given provider, or a zero value otherwise.
+func (c *AiClient) providerModelConfig(provider string) config.ModelConfig {
+	if c.configMgr != nil {
+		if mc, exists := c.configMgr.GetCurrentModelConfig(); exists && mc.Provider == provider {
+			return mc
+		}
+	}
+	return config.ModelConfig{}
+}
+
+// GetResponseFromChatMessages gets a response from the AI based on chat
+// messages. When the request fails and the current model configuration has
+// fallback_models, they are tried in turn.
 func (c *AiClient) GetResponseFromChatMessages(ctx context.Context, chatMessages []ChatMessage, model string) (string, error) {
+	response, err := c.requestChatMessages(ctx, chatMessages, model)
+	if err == nil || ctx.Err() != nil || c.configMgr == nil {
+		return response, err
+	}
+
+	m := c.configMgr
+	failed := m.GetModelsDefault()
+	mc, exists := m.GetModelConfig(failed)
+	if !exists {
+		return response, err
+	}
+	for _, fallback := range mc.FallbackModels {
+		if fallback == failed {
+			continue
+		}
+		if _, exists := m.GetModelConfig(fallback); !exists {
+			logger.Error("Fallback model configuration %q not found", fallback)
+			continue
+		}
+		logger.Info("Model %s failed: %v, falling back to %s", failed, err, fallback)
+		m.Println(fmt.Sprintf("%s failed, retrying with %s", failed, fallback))
+		response, err = m.withModel(fallback, func() (string, error) {
+			return c.requestChatMessages(ctx, chatMessages, m.GetModel())
+		})
+		if err == nil || ctx.Err() != nil {
+			return response, err
+		}
+		failed = fallback
+	}
+	return response, err
+}
+
+// requestChatMessages sends chat messages to the current model configuration
+func (c *AiClient) requestChatMessages(ctx context.Context, chatMessages []ChatMessage, model string) (string, error) {
 	// Convert chat messages to AI client format
 	aiMessages := []Message{}
 
@@ -312,15 +451,31 @@ func (c *AiClient) GetResponseFromChatMessages(ctx context.Context, chatMessages
 		aiMessages = append(aiMessages, Message{
 			Role:    role,
 			Content: msg.Content,
+			Images:  msg.Images,
 		})
 	}
 
 	logger.Info("Sending %d messages to AI using model: %s", len(aiMessages), model)
 
+	if c.configMgr != nil {
+		if mc, exists := c.configMgr.GetCurrentModelConfig(); exists {
+			if _, err := c.resolveAPIKey(ctx, mc); err != nil {
+				return "", err
+			}
+		}
+	}
+
 	// Determine which API to use
 	apiType := c.determineAPIType(model)
 	logger.Debug("Using API type: %s for model: %s", apiType, model)
 
+	ctx, span := tracer.Start(ctx, "tmuxai.ai_request", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", model),
+		attribute.String("tmuxai.api_type", apiType),
+		attribute.Int("tmuxai.messages", len(aiMessages)),
+	))
+	start := time.Now()
+
 	// Route to appropriate API
 	var response string
 	var err error
@@ -339,17 +494,20 @@ func (c *AiClient) GetResponseFromChatMessages(ctx context.Context, chatMessages
 	case "gemini":
 		response, err = c.GeminiGenerateContent(ctx, aiMessages, model)
 	case "bedrock":
-		var bedrockCfg config.ModelConfig
-		if c.configMgr != nil {
-			if mc, exists := c.configMgr.GetCurrentModelConfig(); exists && mc.Provider == "bedrock" {
-				bedrockCfg = mc
-			}
-		}
-		response, err = c.BedrockConverse(ctx, aiMessages, model, bedrockCfg)
+		response, err = c.BedrockConverse(ctx, aiMessages, model, c.providerModelConfig("bedrock"))
+	case "anthropic":
+		response, err = c.AnthropicMessages(ctx, aiMessages, model, c.providerModelConfig("anthropic"))
+	case "ollama":
+		response, err = c.OllamaChat(ctx, aiMessages, model, c.providerModelConfig("ollama"))
 	default:
-		return "", fmt.Errorf("unknown API type: %s", apiType)
+		err = fmt.Errorf("unknown API type: %s", apiType)
 	}
 
+	aiRequestDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("gen_ai.request.model", model),
+		attribute.Bool("error", err != nil),
+	))
+	endSpan(span, err)
 	if err != nil {
 		return "", err
 	}
@@ -375,6 +533,16 @@ func (c *AiClient) ChatCompletion(ctx context.Context, messages []Message, model
 	// Try to get model configuration
 	if c.configMgr != nil {
 		if modelConfig, exists := c.configMgr.GetCurrentModelConfig(); exists {
+			if modelConfig.ToolCalling {
+				// Tool call deltas aren't assembled from streams, so tool mode wins
+				reqBody.Tools = chatCompletionTools()
+			} else if modelConfig.StructuredOutput {
+				// Raw JSON isn't worth streaming to the user either
+				reqBody.ResponseFormat = chatCompletionResponseFormat()
+			} else if modelConfig.Stream {
+				reqBody.Stream = true
+				reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+			}
 			provider = modelConfig.Provider
 			apiKey = modelConfig.APIKey
 			baseURL = modelConfig.BaseURL
@@ -456,7 +624,7 @@ func (c *AiClient) ChatCompletion(ctx context.Context, messages []Message, model
 	logger.Debug("Sending API request to: %s with model: %s", url, model)
 
 	// Send the request
-	resp, err := c.client.Do(req)
+	resp, err := c.doWithRetry(ctx, req)
 	if err != nil {
 		if ctx.Err() == context.Canceled {
 			return "", fmt.Errorf("request canceled: %w", ctx.Err())
@@ -466,6 +634,24 @@ func (c *AiClient) ChatCompletion(ctx context.Context, messages []Message, model
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	// Streamed responses arrive as server-sent events; errors still come back as a plain body
+	if reqBody.Stream && resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		responseContent, usage, err := readChatCompletionStream(resp.Body, streamHandlerFromCtx(ctx))
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return "", fmt.Errorf("request canceled: %w", ctx.Err())
+			}
+			logger.Error("Failed to read stream: %v", err)
+			return "", err
+		}
+		if responseContent == "" {
+			return "", fmt.Errorf("no content returned in stream (model: %s)", model)
+		}
+		c.recordUsage(ctx, usage)
+		logger.Debug("Received streamed AI response (%d characters): %s", len(responseContent), responseContent)
+		return responseContent, nil
+	}
+
 	// Read the response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -489,9 +675,24 @@ func (c *AiClient) ChatCompletion(ctx context.Context, messages []Message, model
 		return "", fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	if completionResp.Usage != nil {
+		c.recordUsage(ctx, TokenUsage{
+			PromptTokens:     completionResp.Usage.PromptTokens,
+			CompletionTokens: completionResp.Usage.CompletionTokens,
+		})
+	}
+
 	// Return the response content
 	if len(completionResp.Choices) > 0 {
-		responseContent := completionResp.Choices[0].Message.Content
+		message := completionResp.Choices[0].Message
+		responseContent := message.Content
+		if len(message.ToolCalls) > 0 {
+			calls := make([]toolCall, 0, len(message.ToolCalls))
+			for _, call := range message.ToolCalls {
+				calls = append(calls, toolCall{Name: call.Function.Name, Arguments: json.RawMessage(call.Function.Arguments)})
+			}
+			responseContent = toolCallResponse(responseContent, calls)
+		}
 		logger.Debug("Received AI response (%d characters): %s", len(responseContent), responseContent)
 		return responseContent, nil
 	}
@@ -501,6 +702,24 @@ func (c *AiClient) ChatCompletion(ctx context.Context, messages []Message, model
 	return "", fmt.Errorf("no completion choices returned (model: %s, status: %d)", model, resp.StatusCode)
 }
 
+// responsesInput converts messages for the Responses API, where images are
+// input_image content parts
+func responsesInput(messages []Message) []interface{} {
+	input := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.Images) == 0 {
+			input = append(input, msg)
+			continue
+		}
+		content := []map[string]string{{"type": "input_text", "text": msg.Content}}
+		for _, image := range msg.Images {
+			content = append(content, map[string]string{"type": "input_image", "image_url": pngDataURL(image)})
+		}
+		input = append(input, map[string]interface{}{"role": msg.Role, "content": content})
+	}
+	return input
+}
+
 // Response sends a request to the OpenAI Responses API
 func (c *AiClient) Response(ctx context.Context, messages []Message, model string) (string, error) {
 	// Convert messages to Responses API format
@@ -515,13 +734,13 @@ func (c *AiClient) Response(ctx context.Context, messages []Message, model strin
 	if messages[0].Role == "system" {
 		instructions = messages[0].Content
 		if len(messages) > 1 {
-			input = messages[1:]
+			input = responsesInput(messages[1:])
 		} else {
 			// Only system message provided, no user input
 			return "", fmt.Errorf("only system message provided, no user message to process")
 		}
 	} else {
-		input = messages
+		input = responsesInput(messages)
 	}
 
 	reqBody := ResponseRequest{
@@ -540,6 +759,16 @@ func (c *AiClient) Response(ctx context.Context, messages []Message, model strin
 		if modelConfig, exists := c.configMgr.GetCurrentModelConfig(); exists && modelConfig.Provider == "openai" {
 			apiKey = modelConfig.APIKey
 			baseURL = modelConfig.BaseURL
+			// OpenAI caches long prefixes automatically; a stable key per
+			// session keeps agent loop iterations on the same cache
+			if modelConfig.PromptCache {
+				reqBody.PromptCacheKey = "tmuxai-" + c.configMgr.PaneId
+			}
+			if modelConfig.ToolCalling {
+				reqBody.Tools = responsesTools()
+			} else if modelConfig.StructuredOutput {
+				reqBody.Text = responsesTextFormat()
+			}
 		}
 	}
 
@@ -581,7 +810,7 @@ func (c *AiClient) Response(ctx context.Context, messages []Message, model strin
 	logger.Debug("Sending Responses API request to: %s with model: %s", url, model)
 
 	// Send the request
-	resp, err := c.client.Do(req)
+	resp, err := c.doWithRetry(ctx, req)
 	if err != nil {
 		if ctx.Err() == context.Canceled {
 			return "", fmt.Errorf("request canceled: %w", ctx.Err())
@@ -620,10 +849,28 @@ func (c *AiClient) Response(ctx context.Context, messages []Message, model strin
 		return "", fmt.Errorf("API error: %s", response.Error.Message)
 	}
 
+	if response.Usage != nil {
+		if details := response.Usage.InputTokensDetails; details != nil && details.CachedTokens > 0 {
+			logger.Debug("OpenAI prompt cache: %d cached input tokens", details.CachedTokens)
+		}
+		c.recordUsage(ctx, TokenUsage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+		})
+	}
+
+	// Collect function calls made in tool calling mode
+	var toolCalls []toolCall
+	for _, item := range response.Output {
+		if item.Type == "function_call" {
+			toolCalls = append(toolCalls, toolCall{Name: item.Name, Arguments: json.RawMessage(item.Arguments)})
+		}
+	}
+
 	// Return the response content
 	if response.OutputText != "" {
 		logger.Debug("Received Responses API response (%d characters): %s", len(response.OutputText), response.OutputText)
-		return response.OutputText, nil
+		return toolCallResponse(response.OutputText, toolCalls), nil
 	}
 
 	// If no output_text, extract from message items
@@ -632,35 +879,47 @@ func (c *AiClient) Response(ctx context.Context, messages []Message, model strin
 			for _, content := range item.Content {
 				if (content.Type == "output_text" || content.Type == "text") && content.Text != "" {
 					logger.Debug("Received Responses API response from output items (%d characters): %s", len(content.Text), content.Text)
-					return content.Text, nil
+					return toolCallResponse(content.Text, toolCalls), nil
 				}
 			}
 		}
 	}
 
+	if len(toolCalls) > 0 {
+		return toolCallResponse("", toolCalls), nil
+	}
+
 	// Enhanced error for no response content
 	logger.Error("No response content returned. Raw response: %s", string(body))
 	return "", fmt.Errorf("no response content returned (model: %s, status: %d)", model, resp.StatusCode)
 }
 
-// getOrCreateGeminiClient creates or returns the cached Gemini client
-func (c *AiClient) getOrCreateGeminiClient(ctx context.Context, apiKey string) (*genai.Client, error) {
+// getOrCreateGeminiClient returns a cached Gemini client, creating a new one
+// when the API key or base URL changes (e.g. after /model switches profiles).
+func (c *AiClient) getOrCreateGeminiClient(ctx context.Context, apiKey, baseURL string) (*genai.Client, error) {
 	c.geminiMu.Lock()
 	defer c.geminiMu.Unlock()
 
-	if c.geminiClient != nil {
+	cacheKey := apiKey + "|" + baseURL
+	if c.geminiClient != nil && c.geminiKey == cacheKey {
 		return c.geminiClient, nil
 	}
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+	clientConfig := &genai.ClientConfig{
 		APIKey:  apiKey,
 		Backend: genai.BackendGeminiAPI,
-	})
+	}
+	if baseURL != "" {
+		clientConfig.HTTPOptions.BaseURL = baseURL
+	}
+
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
 	c.geminiClient = client
+	c.geminiKey = cacheKey
 	return client, nil
 }
 
@@ -670,46 +929,38 @@ func (c *AiClient) GeminiGenerateContent(ctx context.Context, messages []Message
 		return "", fmt.Errorf("no messages provided")
 	}
 
-	// Get API key from model configuration
-	var apiKey string
-	if c.configMgr != nil {
-		if modelConfig, exists := c.configMgr.GetCurrentModelConfig(); exists && modelConfig.Provider == "gemini" {
-			apiKey = modelConfig.APIKey
-		}
-	}
-
-	if apiKey == "" {
+	modelCfg := c.providerModelConfig("gemini")
+	if modelCfg.APIKey == "" {
 		return "", fmt.Errorf("gemini API key not configured")
 	}
 
 	// Get or create Gemini client
-	client, err := c.getOrCreateGeminiClient(ctx, apiKey)
+	client, err := c.getOrCreateGeminiClient(ctx, modelCfg.APIKey, modelCfg.BaseURL)
 	if err != nil {
 		return "", err
 	}
 
-	// Convert messages to Gemini format
-	var systemInstruction *genai.Content
-	var contents []*genai.Content
-
-	for _, msg := range messages {
-		if msg.Role == "system" {
-			// System instruction is handled separately in Gemini
-			systemInstruction = &genai.Content{
-				Parts: []*genai.Part{{Text: msg.Content}},
-			}
-			continue
-		}
+	// Gemini expects alternating user/model turns with the system prompt
+	// passed separately as a system instruction
+	system, conv := splitSystemAndConversation(messages)
 
+	var contents []*genai.Content
+	for _, msg := range conv {
 		// Map roles: user -> user, assistant -> model
-		role := msg.Role
-		if role == "assistant" {
-			role = "model"
+		role := genai.RoleUser
+		if msg.Role == "assistant" {
+			role = genai.RoleModel
 		}
 
+		parts := []*genai.Part{{Text: msg.Content}}
+		for _, image := range msg.Images {
+			if data, err := base64.StdEncoding.DecodeString(image); err == nil {
+				parts = append(parts, &genai.Part{InlineData: &genai.Blob{MIMEType: "image/png", Data: data}})
+			}
+		}
 		contents = append(contents, &genai.Content{
 			Role:  role,
-			Parts: []*genai.Part{{Text: msg.Content}},
+			Parts: parts,
 		})
 	}
 
@@ -719,8 +970,16 @@ func (c *AiClient) GeminiGenerateContent(ctx context.Context, messages []Message
 
 	// Build generation config
 	config := &genai.GenerateContentConfig{}
-	if systemInstruction != nil {
-		config.SystemInstruction = systemInstruction
+	if system != "" {
+		config.SystemInstruction = &genai.Content{
+			Parts: []*genai.Part{{Text: system}},
+		}
+	}
+	if modelCfg.MaxTokens > 0 {
+		config.MaxOutputTokens = modelCfg.MaxTokens
+	}
+	if modelCfg.Temperature > 0 {
+		config.Temperature = genai.Ptr(modelCfg.Temperature)
 	}
 
 	logger.Debug("Sending Gemini API request with model: %s, %d messages", model, len(contents))
@@ -735,6 +994,13 @@ func (c *AiClient) GeminiGenerateContent(ctx context.Context, messages []Message
 		return "", fmt.Errorf("gemini API error: %w", err)
 	}
 
+	if result.UsageMetadata != nil {
+		c.recordUsage(ctx, TokenUsage{
+			PromptTokens:     int(result.UsageMetadata.PromptTokenCount),
+			CompletionTokens: int(result.UsageMetadata.CandidatesTokenCount),
+		})
+	}
+
 	// Extract text from response
 	responseText := result.Text()
 	if responseText == "" {
@@ -2,12 +2,15 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/alvinunreal/tmuxai/config"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	brtypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 )
 
 func TestAzureOpenAIEndpoint(t *testing.T) {
@@ -438,6 +441,32 @@ func TestBuildBedrockInferenceConfig(t *testing.T) {
 	}
 }
 
+func TestBuildBedrockMessages(t *testing.T) {
+	system, conv := buildBedrockMessages([]Message{
+		{Role: "system", Content: "base prompt"},
+		{Role: "assistant", Content: "loaded kb"},
+		{Role: "user", Content: "pane state"},
+		{Role: "user", Content: "question"},
+		{Role: "assistant", Content: "answer"},
+	})
+
+	if len(system) != 1 {
+		t.Fatalf("expected 1 system block, got %d", len(system))
+	}
+	if text := system[0].(*brtypes.SystemContentBlockMemberText).Value; text != "base prompt\n\nloaded kb" {
+		t.Errorf("unexpected system text: %q", text)
+	}
+	if len(conv) != 2 {
+		t.Fatalf("expected 2 conversation turns, got %d", len(conv))
+	}
+	if conv[0].Role != brtypes.ConversationRoleUser || conv[1].Role != brtypes.ConversationRoleAssistant {
+		t.Errorf("unexpected roles: %s, %s", conv[0].Role, conv[1].Role)
+	}
+	if text := conv[0].Content[0].(*brtypes.ContentBlockMemberText).Value; text != "pane state\n\nquestion" {
+		t.Errorf("consecutive user turns not merged: %q", text)
+	}
+}
+
 func TestGeminiProviderSelection(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -506,3 +535,110 @@ func TestGeminiProviderSelection(t *testing.T) {
 		})
 	}
 }
+
+func TestGeminiGenerateContentRequest(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/gemini-2.5-flash:generateContent") {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing x-goog-api-key header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok from gemini"}]}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "flash",
+		Models: map[string]config.ModelConfig{
+			"flash": {Provider: "gemini", Model: "gemini-2.5-flash", APIKey: "test-key", BaseURL: server.URL, MaxTokens: 512},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	resp, err := client.GeminiGenerateContent(context.Background(), []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+		{Role: "user", Content: "again"},
+	}, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatalf("GeminiGenerateContent error: %v", err)
+	}
+	if resp != "ok from gemini" {
+		t.Errorf("unexpected response: %s", resp)
+	}
+
+	contents, _ := got["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d", len(contents))
+	}
+	if role := contents[1].(map[string]interface{})["role"]; role != "model" {
+		t.Errorf("expected assistant mapped to model, got %v", role)
+	}
+	if _, ok := got["systemInstruction"]; !ok {
+		t.Errorf("missing systemInstruction")
+	}
+	genCfg, _ := got["generationConfig"].(map[string]interface{})
+	if genCfg["maxOutputTokens"] != float64(512) {
+		t.Errorf("unexpected maxOutputTokens: %v", genCfg["maxOutputTokens"])
+	}
+}
+
+func TestGeminiClientCacheKey(t *testing.T) {
+	client := NewAiClient(&config.Config{})
+	first, err := client.getOrCreateGeminiClient(context.Background(), "key-a", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := client.getOrCreateGeminiClient(context.Background(), "key-a", "")
+	if first != again {
+		t.Errorf("expected cached client for same key")
+	}
+	other, _ := client.getOrCreateGeminiClient(context.Background(), "key-b", "")
+	if first == other {
+		t.Errorf("expected new client after api key change")
+	}
+}
+
+func TestOpenAIResponsesPromptCacheKey(t *testing.T) {
+	var got ResponseRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"output_text":"ok","usage":{"input_tokens":2000,"input_tokens_details":{"cached_tokens":1024},"output_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "codex",
+		Models: map[string]config.ModelConfig{
+			"codex": {Provider: "openai", Model: "gpt-5", APIKey: "k", BaseURL: server.URL, PromptCache: true},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		PaneId:           "%3",
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	if _, err := client.Response(context.Background(), []Message{{Role: "user", Content: "hi"}}, "gpt-5"); err != nil {
+		t.Fatalf("Response error: %v", err)
+	}
+	if got.PromptCacheKey != "tmuxai-%3" {
+		t.Errorf("unexpected prompt_cache_key: %q", got.PromptCacheKey)
+	}
+}
@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+
+	// defaultAnthropicMaxTokens is used when max_tokens isn't configured.
+	// The Messages API rejects requests without it.
+	defaultAnthropicMaxTokens int32 = 4096
+)
+
+// AnthropicRequest represents a request to the Anthropic Messages API.
+// System and message content are either plain strings or, when prompt caching
+// is enabled, lists of text blocks carrying cache_control breakpoints.
+type AnthropicRequest struct {
+	Model       string             `json:"model"`
+	System      interface{}        `json:"system,omitempty"`
+	Messages    []AnthropicMessage `json:"messages"`
+	MaxTokens   int32              `json:"max_tokens"`
+	Temperature float32            `json:"temperature,omitempty"`
+	Tools       []AnthropicTool    `json:"tools,omitempty"`
+}
+
+// AnthropicMessage represents a conversation turn in a Messages API request
+type AnthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// AnthropicTextBlock represents a text content block in a Messages API request
+type AnthropicTextBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// AnthropicCacheControl marks the end of a cacheable prompt prefix
+type AnthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// AnthropicContentBlock represents a content block in a Messages API response
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// Set on "tool_use" blocks
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// AnthropicResponse represents a response from the Anthropic Messages API
+type AnthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      *AnthropicUsage         `json:"usage,omitempty"`
+	Error      *AnthropicError         `json:"error,omitempty"`
+}
+
+// AnthropicUsage represents token usage in a Messages API response.
+// InputTokens excludes tokens written to or read from the prompt cache.
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// AnthropicError represents an error returned by the Anthropic Messages API
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// splitSystemAndConversation separates system content from the conversation
+// and reshapes the rest for APIs that require a strictly alternating
+// user/assistant exchange starting with a user turn (Anthropic, Bedrock).
+//
+// Assistant messages that appear before the first user message (loaded
+// knowledge bases and skills) carry context rather than replies, so they are
+// folded into the system prompt. Consecutive messages with the same role are
+// merged into one.
+func splitSystemAndConversation(messages []Message) (string, []Message) {
+	var systemParts []string
+	var conv []Message
+
+	for _, msg := range messages {
+		if msg.Role == "system" || (len(conv) == 0 && msg.Role != "user") {
+			if msg.Content != "" {
+				systemParts = append(systemParts, msg.Content)
+			}
+			continue
+		}
+
+		if n := len(conv); n > 0 && conv[n-1].Role == msg.Role {
+			conv[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		conv = append(conv, Message{Role: msg.Role, Content: msg.Content})
+	}
+
+	return strings.Join(systemParts, "\n\n"), conv
+}
+
+// buildAnthropicPrompt shapes the system prompt and conversation for the
+// Messages API. With caching enabled, breakpoints are placed after the system
+// prompt and after the last turn before the newest message, so the system
+// prompt, knowledge bases and earlier history are served from the cache while
+// only the fresh pane context is billed at the full rate.
+func buildAnthropicPrompt(system string, conv []Message, cache bool) (interface{}, []AnthropicMessage) {
+	messages := make([]AnthropicMessage, 0, len(conv))
+	for _, msg := range conv {
+		messages = append(messages, AnthropicMessage{Role: msg.Role, Content: anthropicContent(msg)})
+	}
+
+	if !cache {
+		if system == "" {
+			return nil, messages
+		}
+		return system, messages
+	}
+
+	ephemeral := &AnthropicCacheControl{Type: "ephemeral"}
+
+	var systemField interface{}
+	if system != "" {
+		systemField = []AnthropicTextBlock{{Type: "text", Text: system, CacheControl: ephemeral}}
+	}
+
+	if n := len(conv); n >= 2 && len(conv[n-2].Images) == 0 {
+		messages[n-2].Content = []AnthropicTextBlock{{Type: "text", Text: conv[n-2].Content, CacheControl: ephemeral}}
+	}
+
+	return systemField, messages
+}
+
+// anthropicContent is a message's text, followed by image blocks when it
+// has images
+func anthropicContent(msg Message) interface{} {
+	if len(msg.Images) == 0 {
+		return msg.Content
+	}
+	blocks := []interface{}{AnthropicTextBlock{Type: "text", Text: msg.Content}}
+	for _, image := range msg.Images {
+		blocks = append(blocks, map[string]interface{}{
+			"type":   "image",
+			"source": map[string]string{"type": "base64", "media_type": "image/png", "data": image},
+		})
+	}
+	return blocks
+}
+
+// anthropicMessagesURL builds the Messages API endpoint, accepting base URLs
+// with or without the trailing /v1.
+func anthropicMessagesURL(baseURL string) string {
+	base := strings.TrimSuffix(baseURL, "/")
+	if base == "" {
+		base = defaultAnthropicBaseURL
+	}
+	if strings.HasSuffix(base, "/v1") {
+		return base + "/messages"
+	}
+	return base + "/v1/messages"
+}
+
+// AnthropicMessages sends a request to the Anthropic Messages API
+func (c *AiClient) AnthropicMessages(ctx context.Context, messages []Message, model string, modelCfg config.ModelConfig) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no messages provided")
+	}
+
+	system, conv := splitSystemAndConversation(messages)
+	if len(conv) == 0 {
+		return "", fmt.Errorf("no user messages to send")
+	}
+
+	maxTokens := modelCfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+
+	systemField, anthropicMessages := buildAnthropicPrompt(system, conv, modelCfg.PromptCache)
+
+	reqBody := AnthropicRequest{
+		Model:       model,
+		System:      systemField,
+		Messages:    anthropicMessages,
+		MaxTokens:   maxTokens,
+		Temperature: modelCfg.Temperature,
+	}
+	if modelCfg.ToolCalling {
+		reqBody.Tools = anthropicTools()
+	}
+
+	reqJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		logger.Error("Failed to marshal Anthropic request: %v", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := anthropicMessagesURL(modelCfg.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqJSON))
+	if err != nil {
+		logger.Error("Failed to create Anthropic request: %v", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", modelCfg.APIKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	logger.Debug("Sending Anthropic Messages request to: %s with model: %s (%d messages, max_tokens=%d)", url, model, len(conv), maxTokens)
+
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return "", fmt.Errorf("request canceled: %w", ctx.Err())
+		}
+		logger.Error("Failed to send Anthropic request: %v", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error("Failed to read Anthropic response: %v", err)
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	logger.Debug("Anthropic API response status: %d, response size: %d bytes", resp.StatusCode, len(body))
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Anthropic API returned error: %s", body)
+		return "", fmt.Errorf("API returned error: %s", body)
+	}
+
+	var messagesResp AnthropicResponse
+	if err := json.Unmarshal(body, &messagesResp); err != nil {
+		logger.Error("Failed to unmarshal Anthropic response: %v, body: %s", err, body)
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if messagesResp.Error != nil {
+		return "", fmt.Errorf("API error: %s", messagesResp.Error.Message)
+	}
+
+	if u := messagesResp.Usage; u != nil {
+		if u.CacheReadInputTokens > 0 || u.CacheCreationInputTokens > 0 {
+			logger.Debug("Anthropic prompt cache: read=%d written=%d", u.CacheReadInputTokens, u.CacheCreationInputTokens)
+		}
+		c.recordUsage(ctx, TokenUsage{
+			PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+			CompletionTokens: u.OutputTokens,
+		})
+	}
+
+	var sb strings.Builder
+	var toolCalls []toolCall
+	for _, block := range messagesResp.Content {
+		switch block.Type {
+		case "text":
+			sb.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, toolCall{Name: block.Name, Arguments: block.Input})
+		}
+	}
+
+	response := sb.String()
+	if len(toolCalls) > 0 {
+		response = toolCallResponse(response, toolCalls)
+	}
+	if response == "" {
+		logger.Error("No text content returned. Raw response: %s", string(body))
+		return "", fmt.Errorf("no content returned (model: %s, stop_reason: %s)", model, messagesResp.StopReason)
+	}
+
+	logger.Debug("Received Anthropic response (%d characters): %s", len(response), response)
+	return response, nil
+}
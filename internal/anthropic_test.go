@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicMessagesEndpoint(t *testing.T) {
+	var got AnthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("missing x-api-key header")
+		}
+		if r.Header.Get("anthropic-version") != anthropicAPIVersion {
+			t.Errorf("missing anthropic-version header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok from claude"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	client := NewAiClient(&config.Config{})
+	msgs := []Message{
+		{Role: "system", Content: "be helpful"},
+		{Role: "user", Content: "hi"},
+	}
+	resp, err := client.AnthropicMessages(context.Background(), msgs, "claude-sonnet-4-5", config.ModelConfig{
+		Provider: "anthropic",
+		APIKey:   "test-key",
+		BaseURL:  server.URL,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok from claude", resp)
+
+	assert.Equal(t, "claude-sonnet-4-5", got.Model)
+	assert.Equal(t, "be helpful", got.System)
+	assert.Equal(t, defaultAnthropicMaxTokens, got.MaxTokens)
+	assert.Equal(t, []AnthropicMessage{{Role: "user", Content: "hi"}}, got.Messages)
+}
+
+func TestBuildAnthropicPromptCaching(t *testing.T) {
+	conv := []Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "user", Content: "latest pane state"},
+	}
+
+	system, messages := buildAnthropicPrompt("base prompt", conv, false)
+	assert.Equal(t, "base prompt", system)
+	assert.Equal(t, "reply", messages[1].Content)
+
+	system, messages = buildAnthropicPrompt("base prompt", conv, true)
+	ephemeral := &AnthropicCacheControl{Type: "ephemeral"}
+	assert.Equal(t, []AnthropicTextBlock{{Type: "text", Text: "base prompt", CacheControl: ephemeral}}, system)
+	assert.Equal(t, "first", messages[0].Content)
+	assert.Equal(t, []AnthropicTextBlock{{Type: "text", Text: "reply", CacheControl: ephemeral}}, messages[1].Content)
+	assert.Equal(t, "latest pane state", messages[2].Content, "newest message stays outside the cached prefix")
+
+	raw, err := json.Marshal(AnthropicRequest{System: system, Messages: messages})
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"cache_control":{"type":"ephemeral"}`)
+}
+
+func TestAnthropicMessagesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	client := NewAiClient(&config.Config{})
+	_, err := client.AnthropicMessages(context.Background(), []Message{{Role: "user", Content: "hi"}}, "claude", config.ModelConfig{
+		APIKey:  "bad",
+		BaseURL: server.URL,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid x-api-key")
+}
+
+func TestSplitSystemAndConversation(t *testing.T) {
+	system, conv := splitSystemAndConversation([]Message{
+		{Role: "system", Content: "base prompt"},
+		{Role: "assistant", Content: "knowledge base"},
+		{Role: "user", Content: "first"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "assistant", Content: "more"},
+		{Role: "user", Content: "third"},
+	})
+
+	assert.Equal(t, "base prompt\n\nknowledge base", system)
+	assert.Equal(t, []Message{
+		{Role: "user", Content: "first\n\nsecond"},
+		{Role: "assistant", Content: "reply\n\nmore"},
+		{Role: "user", Content: "third"},
+	}, conv)
+}
+
+func TestAnthropicMessagesURL(t *testing.T) {
+	assert.Equal(t, "https://api.anthropic.com/v1/messages", anthropicMessagesURL(""))
+	assert.Equal(t, "https://proxy.local/v1/messages", anthropicMessagesURL("https://proxy.local/"))
+	assert.Equal(t, "https://proxy.local/v1/messages", anthropicMessagesURL("https://proxy.local/v1"))
+}
+
+func TestDetermineAPITypeAnthropic(t *testing.T) {
+	cfg := &config.Config{
+		DefaultModel: "claude",
+		Models: map[string]config.ModelConfig{
+			"claude": {Provider: "anthropic", Model: "claude-sonnet-4-5", APIKey: "key"},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	assert.Equal(t, "anthropic", client.determineAPIType("claude-sonnet-4-5"))
+}
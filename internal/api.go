@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// apiMaxMessageBytes caps the body of a POST /messages request
+const apiMaxMessageBytes = 1 << 20
+
+// apiServer exposes the running manager over HTTP for `tmuxai serve`, so
+// editor plugins and scripts can drive the agent the user is chatting with
+type apiServer struct {
+	m       *Manager
+	token   string // required as a bearer token, an empty one refuses every request
+	anyHost bool   // accept any Host header, for APIs listening beyond loopback
+}
+
+type apiMessageRequest struct {
+	Message string `json:"message"`
+}
+
+type apiStatus struct {
+	Status    string `json:"status"` // running, waiting, done or "" when idle
+	Busy      bool   `json:"busy"`   // a request is being processed
+	WatchMode bool   `json:"watch_mode"`
+	Model     string `json:"model"`
+	ExecPane  string `json:"exec_pane"`
+	SessionID string `json:"session_id"`
+	Messages  int    `json:"messages"`
+}
+
+type apiPane struct {
+	ID             string `json:"id"`
+	CurrentCommand string `json:"current_command"`
+	CurrentPath    string `json:"current_path,omitempty"`
+	Active         bool   `json:"active"`
+	TmuxAIPane     bool   `json:"tmuxai_pane"`
+	ExecPane       bool   `json:"exec_pane"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// NewAPIHandler returns the HTTP API of the manager. Requests need the token
+// as a bearer token and a loopback Host header, which keeps web pages the
+// user visits from reaching the API through DNS rebinding.
+func NewAPIHandler(m *Manager, token string) http.Handler {
+	return newAPIHandler(m, token, false)
+}
+
+func newAPIHandler(m *Manager, token string, anyHost bool) http.Handler {
+	s := &apiServer{m: m, token: token, anyHost: anyHost}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /messages", s.postMessage)
+	mux.HandleFunc("GET /status", s.getStatus)
+	mux.HandleFunc("GET /panes", s.getPanes)
+	mux.HandleFunc("GET /transcript", s.getTranscript)
+	return s.authorize(mux)
+}
+
+// ServeAPI listens on addr and serves the HTTP API in the background until
+// ctx is done. Without a token it generates one, which is returned so the
+// caller can show it. Listening errors are returned right away.
+func (m *Manager) ServeAPI(ctx context.Context, addr, token string) (string, error) {
+	if token == "" {
+		var err error
+		if token, err = generateAPIToken(); err != nil {
+			return "", err
+		}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	// an address beyond loopback is reached under names we can't know
+	anyHost := false
+	if host, _, err := net.SplitHostPort(listener.Addr().String()); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() {
+			anyHost = true
+			logger.Info("HTTP API on %s is reachable from other hosts", addr)
+		}
+	}
+
+	server := &http.Server{Handler: newAPIHandler(m, token, anyHost), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP API failed: %v", err)
+		}
+	}()
+	logger.Info("HTTP API listening on %s", listener.Addr())
+	return token, nil
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate an API token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.anyHost && !isLoopbackHost(r.Host) {
+			writeAPIJSON(w, http.StatusForbidden, apiError{Error: "Host must be a loopback address"})
+			return
+		}
+		given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+			writeAPIJSON(w, http.StatusUnauthorized, apiError{Error: "missing or wrong bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether a Host header names this machine
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// postMessage processes a request like one typed in the chat and answers
+// with what it did, in the shape of tmuxai --json. A request already being
+// processed gets 409 rather than a queue, so callers know to retry.
+func (s *apiServer) postMessage(w http.ResponseWriter, r *http.Request) {
+	// browsers send text/plain and form posts cross-origin without a preflight
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeAPIJSON(w, http.StatusUnsupportedMediaType, apiError{Error: "Content-Type must be application/json"})
+		return
+	}
+	var req apiMessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxMessageBytes)).Decode(&req); err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, apiError{Error: "invalid request body: " + err.Error()})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		writeAPIJSON(w, http.StatusBadRequest, apiError{Error: "message is required"})
+		return
+	}
+	if !s.m.busy.TryLock() {
+		writeAPIJSON(w, http.StatusConflict, apiError{Error: "a request is already being processed"})
+		return
+	}
+	defer s.m.busy.Unlock()
+	defer s.m.saveSession()
+	// the chat owns the terminal, so confirmations fail closed rather than
+	// race it for stdin
+	s.m.apiRequest = true
+	defer func() { s.m.apiRequest = false }()
+
+	logger.Info("HTTP API request: %s", message)
+	s.m.Println("Request from the HTTP API: " + message)
+	// a client that goes away stops the request, like Ctrl+C in the chat
+	result := s.m.processOnce(r.Context(), message)
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) getStatus(w http.ResponseWriter, r *http.Request) {
+	busy := !s.m.busy.TryLock()
+	if !busy {
+		s.m.busy.Unlock()
+	}
+	// a request may be running on another goroutine, so the fields are
+	// snapshotted under the lock its writes take
+	s.m.stateMu.Lock()
+	status := apiStatus{
+		Status:    s.m.Status,
+		Busy:      busy,
+		WatchMode: s.m.WatchMode,
+		Model:     s.m.getPromptModelName(),
+		SessionID: s.m.SessionID,
+		Messages:  len(s.m.Messages),
+	}
+	if s.m.ExecPane != nil {
+		status.ExecPane = s.m.ExecPane.Id
+	}
+	s.m.stateMu.Unlock()
+	writeAPIJSON(w, http.StatusOK, status)
+}
+
+func (s *apiServer) getPanes(w http.ResponseWriter, r *http.Request) {
+	panes, err := s.m.GetTmuxPanes()
+	if err != nil {
+		writeAPIJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	result := make([]apiPane, 0, len(panes))
+	for _, pane := range panes {
+		result = append(result, apiPane{
+			ID:             pane.Id,
+			CurrentCommand: pane.CurrentCommand,
+			CurrentPath:    pane.CurrentPath,
+			Active:         pane.IsActive == 1,
+			TmuxAIPane:     pane.IsTmuxAiPane,
+			ExecPane:       pane.IsTmuxAiExecPane,
+		})
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+// getTranscript lists the session history as /history shows it
+func (s *apiServer) getTranscript(w http.ResponseWriter, r *http.Request) {
+	// appends by a running request leave the snapshotted elements alone
+	s.m.stateMu.Lock()
+	messages, execHistory := s.m.Messages, s.m.ExecHistory
+	s.m.stateMu.Unlock()
+	entries := s.m.historyOf(messages, execHistory)
+	if entries == nil {
+		entries = []historyEntry{}
+	}
+	writeAPIJSON(w, http.StatusOK, entries)
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Debug("Failed to write HTTP API response: %v", err)
+	}
+}
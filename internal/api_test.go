@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiRequest(t *testing.T, handler http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "127.0.0.1:8787"
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIStatusAndTranscript(t *testing.T) {
+	m := historyTestManager()
+	m.ExecPane = &system.TmuxPaneDetails{Id: "%2"}
+	m.SessionID = "abc"
+	handler := NewAPIHandler(m, "secret")
+
+	rec := apiRequest(t, handler, "GET", "/status", "", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status apiStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, apiStatus{ExecPane: "%2", SessionID: "abc", Messages: 4}, status)
+
+	rec = apiRequest(t, handler, "GET", "/transcript", "", "secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 5)
+	assert.Equal(t, "user", entries[0]["kind"])
+	assert.Equal(t, "make test", entries[3]["text"])
+	assert.Equal(t, float64(2), entries[3]["exit_code"])
+	assert.NotContains(t, entries[4], "exit_code", "make lint never ran")
+
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, "GET", "/nope", "", "secret").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, apiRequest(t, handler, "GET", "/messages", "", "secret").Code)
+}
+
+func TestAPIToken(t *testing.T) {
+	handler := NewAPIHandler(historyTestManager(), "secret")
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(t, handler, "GET", "/status", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(t, handler, "GET", "/status", "", "guess").Code)
+	assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/status", "", "secret").Code)
+
+	open := NewAPIHandler(historyTestManager(), "")
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(t, open, "GET", "/status", "", "").Code, "no token refuses everything")
+}
+
+func TestAPIHostAndContentType(t *testing.T) {
+	handler := NewAPIHandler(historyTestManager(), "secret")
+	for host, want := range map[string]int{
+		"localhost:8787":   http.StatusOK,
+		"127.0.0.1":        http.StatusOK,
+		"[::1]:8787":       http.StatusOK,
+		"evil.example":     http.StatusForbidden,
+		"192.168.1.5:8787": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/status", nil)
+		req.Host = host
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, host)
+	}
+
+	req := httptest.NewRequest("POST", "/messages", strings.NewReader(`{"message": "build it"}`))
+	req.Host = "localhost:8787"
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestGenerateAPIToken(t *testing.T) {
+	a, err := generateAPIToken()
+	require.NoError(t, err)
+	b, err := generateAPIToken()
+	require.NoError(t, err)
+	assert.Len(t, a, 32)
+	assert.NotEqual(t, a, b)
+}
+
+func TestAPIPostMessage(t *testing.T) {
+	m := historyTestManager()
+	handler := NewAPIHandler(m, "secret")
+
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, "POST", "/messages", "not json", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, "POST", "/messages", `{"message": "  "}`, "secret").Code)
+
+	m.busy.Lock()
+	defer m.busy.Unlock()
+	rec := apiRequest(t, handler, "POST", "/messages", `{"message": "build it"}`, "secret")
+	assert.Equal(t, http.StatusConflict, rec.Code, "requests aren't queued behind a running one")
+
+	rec = apiRequest(t, handler, "GET", "/status", "", "secret")
+	var status apiStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.True(t, status.Busy)
+}
+
+func TestAPIRequestConfirmationsFailClosed(t *testing.T) {
+	m := historyTestManager()
+	m.apiRequest = true
+
+	_, _, err := m.readConfirmation("Execute? [Y/n]: ")
+	assert.ErrorIs(t, err, errAPIConfirmation)
+	assert.False(t, m.confirmedYesNoFn("Continue?"))
+	ok, _ := m.confirmedToExecFn("rm -rf build", "Execute this command?", true)
+	assert.False(t, ok)
+	ok, _ = m.confirmedPlanFn("1. build")
+	assert.False(t, ok)
+	assert.Nil(t, m.confirmedBatchFn([]batchCommand{{Command: "make"}}))
+}
+
+func TestAPIStatusWhileProcessing(t *testing.T) {
+	m := historyTestManager()
+	handler := NewAPIHandler(m, "secret")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.setStatus("running")
+			m.appendMessages(ChatMessage{Content: "<ExecCommand>ls</ExecCommand>"})
+			m.setExecPane(&system.TmuxPaneDetails{Id: "%2"})
+			m.setOverride("default_model", "fast")
+			m.setStatus("")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/status", "", "secret").Code)
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/transcript", "", "secret").Code)
+	}
+	<-done
+}
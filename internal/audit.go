@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// auditLogFile is the append-only action log in the config directory
+const auditLogFile = "audit.jsonl"
+
+// Confirmation decisions recorded in the audit log
+const (
+	auditAuto        = "auto"        // no confirmation was required
+	auditWhitelisted = "whitelisted" // matched whitelist_patterns
+	auditApproved    = "approved"
+	auditEdited      = "edited" // approved after editing the command
+	auditDeclined    = "declined"
+	auditDryRun      = "dry_run"      // shown but not sent
+	auditHookBlocked = "hook_blocked" // refused by the pre_exec hook
+	auditReadOnly    = "read_only"    // aimed at a read-only pane
+)
+
+// AuditEntry is one line of the audit log
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	PaneID   string    `json:"pane_id"`
+	Action   string    `json:"action"` // exec_command, send_keys or paste_multiline_content
+	Command  string    `json:"command"`
+	Risk     string    `json:"risk,omitempty"`
+	Flags    []string  `json:"risk_flags,omitempty"`
+	Decision string    `json:"decision"`
+	ExitCode *int      `json:"exit_code,omitempty"` // only known for prepared panes
+}
+
+// audit records an action sent to (or withheld from) a pane and runs the
+// post_exec hook for commands that ran. Failing to write the log is
+// reported but never blocks the action itself.
+func (m *Manager) audit(action, paneID, command, decision string, exitCode *int) {
+	entry := AuditEntry{
+		Time:     time.Now(),
+		PaneID:   paneID,
+		Action:   action,
+		Command:  command,
+		Decision: decision,
+		ExitCode: exitCode,
+	}
+	if action != "send_keys" {
+		assessment := m.assessCommand(command)
+		entry.Risk = string(assessment.Level)
+		entry.Flags = assessment.Flags
+	}
+	if m.actionLog != nil {
+		*m.actionLog = append(*m.actionLog, entry)
+	}
+	// watch mode and mcp-serve act outside typed requests, which would grow
+	// taskActions for as long as they run
+	if m.inTask {
+		m.taskActions = append(m.taskActions, entry)
+	}
+	if action == "exec_command" && decision != auditDeclined && decision != auditDryRun && decision != auditHookBlocked {
+		event := hookEvent{Event: hookPostExec, PaneID: paneID, Command: command, Risk: entry.Risk, Flags: entry.Flags, Decision: decision, ExitCode: exitCode}
+		if exitCode != nil && !strings.HasPrefix(paneID, "sandbox:") {
+			event.Output = m.execOutput(command)
+		}
+		m.notifyHook(event)
+	}
+	if !m.GetAuditLog() {
+		return
+	}
+
+	if err := appendAuditEntry(config.GetConfigFilePath(auditLogFile), entry); err != nil {
+		logger.Error("Failed to write audit log: %v", err)
+	}
+}
+
+// auditDecision classifies the outcome of a confirmation prompt
+func (m *Manager) auditDecision(original, final string, approved bool) string {
+	if !approved {
+		return auditDeclined
+	}
+	if whitelisted, _ := m.whitelistCheck(original); whitelisted {
+		return auditWhitelisted
+	}
+	if final != original {
+		return auditEdited
+	}
+	return auditApproved
+}
+
+func appendAuditEntry(path string, entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return nil
+}
+
+// readAuditLog returns the last n entries of the audit log, oldest first
+func readAuditLog(path string, n int) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Debug("Skipping malformed audit log line: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// showAudit prints the most recent audit log entries
+func (m *Manager) showAudit(n int) {
+	path := config.GetConfigFilePath(auditLogFile)
+	entries, err := readAuditLog(path, n)
+	if err != nil {
+		m.Println(fmt.Sprintf("Error reading audit log: %v", err))
+		return
+	}
+	if len(entries) == 0 {
+		m.Println("No actions recorded in " + path)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Last %d action(s) from %s:\n", len(entries), path)
+	for _, e := range entries {
+		exit := ""
+		if e.ExitCode != nil {
+			exit = fmt.Sprintf(" exit=%d", *e.ExitCode)
+		}
+		risk := ""
+		if e.Risk != "" {
+			risk = " [" + e.Risk + "]"
+		}
+		fmt.Fprintf(&b, "%s %s %s %s%s%s: %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.PaneID, e.Action, e.Decision, risk, exit,
+			strings.ReplaceAll(e.Command, "\n", "⏎"))
+	}
+	m.Println(strings.TrimRight(b.String(), "\n"))
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	manager := &Manager{
+		Config:           &config.Config{AuditLog: true},
+		SessionOverrides: make(map[string]interface{}),
+	}
+
+	code := 2
+	manager.audit("exec_command", "%1", "rm -rf build", auditApproved, &code)
+	manager.audit("send_keys", "%1", "C-c", auditAuto, nil)
+	manager.audit("exec_command", "%1", "ls", auditDeclined, nil)
+
+	path := filepath.Join(home, ".config", "tmuxai", auditLogFile)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "\n"), "one JSON line per action")
+
+	entries, err := readAuditLog(path, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "only the last n entries")
+	assert.Equal(t, "send_keys", entries[0].Action)
+	assert.Empty(t, entries[0].Risk, "keys aren't risk scored")
+	assert.Equal(t, auditDeclined, entries[1].Decision)
+
+	entries, err = readAuditLog(path, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "danger", entries[0].Risk)
+	require.NotNil(t, entries[0].ExitCode)
+	assert.Equal(t, 2, *entries[0].ExitCode)
+
+	manager.SessionOverrides["audit_log"] = false
+	manager.audit("exec_command", "%1", "pwd", auditAuto, nil)
+	entries, err = readAuditLog(path, 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "disabled audit log records nothing")
+}
+
+func TestAuditTaskActions(t *testing.T) {
+	manager := &Manager{
+		Config:           &config.Config{},
+		SessionOverrides: make(map[string]interface{}),
+	}
+
+	manager.audit("exec_command", "%1", "tail -f app.log", auditAuto, nil)
+	assert.Empty(t, manager.taskActions, "actions outside a typed request, as in watch mode or mcp-serve, aren't kept")
+
+	manager.inTask = true
+	manager.audit("exec_command", "%1", "make", auditApproved, nil)
+	require.Len(t, manager.taskActions, 1)
+	assert.Equal(t, "make", manager.taskActions[0].Command)
+}
+
+func TestReadAuditLogMissingFile(t *testing.T) {
+	entries, err := readAuditLog(filepath.Join(t.TempDir(), auditLogFile), 5)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/fatih/color"
+)
+
+// batchCommand is a command of a multi-command response, listed for
+// confirmation with a note on where it runs
+type batchCommand struct {
+	Command string
+	Note    string // e.g. "in pane tests, background", "" for the exec pane
+}
+
+// execBatch returns the commands of r for one batch confirmation, or nil
+// when fewer than two of them need confirming and they're asked one by one.
+// SendKeys stay out of the list and are confirmed together afterwards: key
+// sequences build on each other, like ":wq" and "Enter", so sending only a
+// selection of them could leave the pane in a state nobody approved.
+func (m *Manager) execBatch(r AIResponse) []batchCommand {
+	if len(r.ExecCommand) < 2 || m.GetDryRun() {
+		return nil
+	}
+	pending := 0
+	batch := make([]batchCommand, len(r.ExecCommand))
+	for i, command := range r.ExecCommand {
+		if whitelisted, _ := m.whitelistCheck(command); !whitelisted && m.needsExecConfirm(command) {
+			pending++
+		}
+		var notes []string
+		if name := r.execCommandPane(i); name != "" {
+			notes = append(notes, "in pane "+name)
+		}
+		if r.execCommandBackground(i) {
+			notes = append(notes, "background")
+		}
+		batch[i] = batchCommand{Command: command, Note: strings.Join(notes, ", ")}
+	}
+	if pending < 2 {
+		return nil
+	}
+	return batch
+}
+
+// confirmedBatchFn lists the commands numbered and asks which to run: all,
+// none, a selection like "1,3" or "2-4", or "e N" to edit one and ask again.
+// It returns the command to run for each entry, "" for the ones left out,
+// and nil when all are declined.
+func (m *Manager) confirmedBatchFn(commands []batchCommand) []string {
+	approved := make([]string, len(commands))
+	for i, c := range commands {
+		approved[i] = c.Command
+		if whitelisted, _ := m.whitelistCheck(c.Command); whitelisted || !m.needsExecConfirm(c.Command) {
+			continue
+		}
+		assessment := m.assessCommand(c.Command)
+		event := hookEvent{Event: hookOnConfirmRequired, Command: c.Command, Risk: string(assessment.Level), Flags: assessment.Flags, Prompt: "Execute these commands?"}
+		if m.ExecPane != nil {
+			event.PaneID = m.ExecPane.Id
+		}
+		m.notifyHook(event)
+	}
+
+	for {
+		m.printBatch(commands, approved)
+		promptStr := system.CurrentTheme().Confirm.Sprint("Execute these commands? [Y/n/e N/1,3]: ")
+		input, cancelled, err := m.readConfirmation(promptStr)
+		if err != nil {
+			fmt.Printf("Error reading confirmation: %v\n", err)
+			return nil
+		}
+		if cancelled {
+			m.setStatus("")
+			return nil
+		}
+
+		input = strings.TrimSpace(strings.ToLower(input))
+		switch input {
+		case "", "y", "yes", "ok", "sure":
+			return approved
+		case "n", "no", "cancel":
+			return nil
+		}
+
+		if rest, ok := strings.CutPrefix(input, "e"); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(rest))
+			if err != nil || n < 1 || n > len(approved) {
+				fmt.Printf("Pick a command to edit between 1 and %d\n", len(approved))
+				continue
+			}
+			source := approved[n-1]
+			if source == "" {
+				source = commands[n-1].Command
+			}
+			edited, err := startEditor(source)
+			if err != nil {
+				fmt.Printf("Error running editor: %v\n", err)
+				continue
+			}
+			// an emptied command is left out
+			approved[n-1] = strings.TrimSpace(edited)
+			continue
+		}
+
+		selected, ok := parseSelection(input, len(approved))
+		if !ok {
+			continue
+		}
+		picked := make([]string, len(approved))
+		for _, n := range selected {
+			picked[n-1] = approved[n-1]
+		}
+		return picked
+	}
+}
+
+// printBatch prints the numbered commands with their risk, marking edited
+// and emptied ones
+func (m *Manager) printBatch(commands []batchCommand, approved []string) {
+	fmt.Println("Commands to execute:")
+	for i, c := range commands {
+		command := approved[i]
+		if command == "" {
+			fmt.Println(color.New(color.Faint).Sprintf("%d. (left out) %s", i+1, c.Command))
+			continue
+		}
+		level := m.assessCommand(command).Level
+		riskColor, _ := riskStyle(level)
+		line := fmt.Sprintf("%d. %s %s", i+1, riskColor.Sprintf("[%s]", level), command)
+		if command != c.Command {
+			line += " (edited)"
+		}
+		if c.Note != "" {
+			line += color.New(color.Faint).Sprintf(" (%s)", c.Note)
+		}
+		fmt.Println(line)
+	}
+}
+
+// parseSelection parses a selection of 1-based entries like "1,3", "1 3" or
+// "2-4", each between 1 and n, returned in order without duplicates
+func parseSelection(input string, n int) ([]int, bool) {
+	picked := make([]bool, n+1)
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, false
+	}
+	for _, field := range fields {
+		from, to, isRange := strings.Cut(field, "-")
+		if !isRange {
+			to = from
+		}
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || lo < 1 || hi > n || lo > hi {
+			return nil, false
+		}
+		for i := lo; i <= hi; i++ {
+			picked[i] = true
+		}
+	}
+	var selected []int
+	for i := 1; i <= n; i++ {
+		if picked[i] {
+			selected = append(selected, i)
+		}
+	}
+	return selected, true
+}
+
+// skippedCommandsObservation tells the model which of its commands the user
+// left out, so it doesn't expect their output
+func skippedCommandsObservation(skipped []string) string {
+	return "I chose not to run these commands:\n- " + strings.Join(skipped, "\n- ") + "\n"
+}
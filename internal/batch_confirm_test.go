@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelection(t *testing.T) {
+	selected, ok := parseSelection("3,1", 3)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 3}, selected)
+
+	selected, ok = parseSelection("2-4 1 2", 5)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2, 3, 4}, selected)
+
+	for _, input := range []string{"", "0", "4", "2-1", "1-9", "x", "1,,e"} {
+		_, ok := parseSelection(input, 3)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestExecBatch(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.WhitelistPatterns = []string{`^(ls|pwd)$`}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+
+	r := AIResponse{
+		ExecCommand:           []string{"ls", "make build", "make test"},
+		ExecCommandPanes:      []string{"", "", "tests"},
+		ExecCommandBackground: []bool{false, false, true},
+	}
+	batch := manager.execBatch(r)
+	require.Len(t, batch, 3, "whitelisted commands are listed too")
+	assert.Equal(t, batchCommand{Command: "ls"}, batch[0])
+	assert.Equal(t, "in pane tests, background", batch[2].Note)
+
+	r.ExecCommand = []string{"ls", "make build", "pwd"}
+	assert.Nil(t, manager.execBatch(r), "a single command to confirm is asked as before")
+
+	r.ExecCommand = []string{"make build", "make test"}
+	manager.SessionOverrides["exec_confirm"] = false
+	assert.Nil(t, manager.execBatch(r))
+
+	manager.SessionOverrides["exec_confirm"] = true
+	manager.SessionOverrides["dry_run"] = true
+	assert.Nil(t, manager.execBatch(r), "nothing runs in dry-run mode")
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strings"
 
@@ -36,6 +37,44 @@ func buildBedrockInferenceConfig(modelCfg config.ModelConfig) *brtypes.Inference
 	return inference
 }
 
+// buildBedrockMessages converts chat messages into Converse system blocks and
+// conversation turns. Converse rejects conversations that don't start with a
+// user turn or that repeat a role back to back (Claude-on-Bedrock is strict
+// about both), so the conversation is normalized first.
+func buildBedrockMessages(messages []Message) ([]brtypes.SystemContentBlock, []brtypes.Message) {
+	system, conv := splitSystemAndConversation(messages)
+
+	var systemBlocks []brtypes.SystemContentBlock
+	if system != "" {
+		systemBlocks = append(systemBlocks, &brtypes.SystemContentBlockMemberText{Value: system})
+	}
+
+	convMessages := make([]brtypes.Message, 0, len(conv))
+	for _, msg := range conv {
+		role := brtypes.ConversationRoleUser
+		if msg.Role == "assistant" {
+			role = brtypes.ConversationRoleAssistant
+		}
+		content := []brtypes.ContentBlock{
+			&brtypes.ContentBlockMemberText{Value: msg.Content},
+		}
+		for _, image := range msg.Images {
+			if data, err := base64.StdEncoding.DecodeString(image); err == nil {
+				content = append(content, &brtypes.ContentBlockMemberImage{Value: brtypes.ImageBlock{
+					Format: brtypes.ImageFormatPng,
+					Source: &brtypes.ImageSourceMemberBytes{Value: data},
+				}})
+			}
+		}
+		convMessages = append(convMessages, brtypes.Message{
+			Role:    role,
+			Content: content,
+		})
+	}
+
+	return systemBlocks, convMessages
+}
+
 // getOrCreateBedrockClient returns a cached Bedrock runtime client, creating
 // one when the region/profile tuple changes. Credentials flow through the
 // default AWS credential chain (env, shared config, SSO, IAM role, etc.).
@@ -88,31 +127,7 @@ func (c *AiClient) BedrockConverse(ctx context.Context, messages []Message, mode
 		return "", err
 	}
 
-	// Split out system messages; Bedrock Converse accepts them separately.
-	var systemBlocks []brtypes.SystemContentBlock
-	var convMessages []brtypes.Message
-	for _, msg := range messages {
-		switch msg.Role {
-		case "system":
-			systemBlocks = append(systemBlocks, &brtypes.SystemContentBlockMemberText{
-				Value: msg.Content,
-			})
-		case "user":
-			convMessages = append(convMessages, brtypes.Message{
-				Role: brtypes.ConversationRoleUser,
-				Content: []brtypes.ContentBlock{
-					&brtypes.ContentBlockMemberText{Value: msg.Content},
-				},
-			})
-		case "assistant":
-			convMessages = append(convMessages, brtypes.Message{
-				Role: brtypes.ConversationRoleAssistant,
-				Content: []brtypes.ContentBlock{
-					&brtypes.ContentBlockMemberText{Value: msg.Content},
-				},
-			})
-		}
-	}
+	systemBlocks, convMessages := buildBedrockMessages(messages)
 
 	if len(convMessages) == 0 {
 		return "", fmt.Errorf("no user/assistant messages to send")
@@ -142,6 +157,13 @@ func (c *AiClient) BedrockConverse(ctx context.Context, messages []Message, mode
 		return "", fmt.Errorf("bedrock API error: %w", err)
 	}
 
+	if out.Usage != nil {
+		c.recordUsage(ctx, TokenUsage{
+			PromptTokens:     int(aws.ToInt32(out.Usage.InputTokens)),
+			CompletionTokens: int(aws.ToInt32(out.Usage.OutputTokens)),
+		})
+	}
+
 	msgOut, ok := out.Output.(*brtypes.ConverseOutputMemberMessage)
 	if !ok || msgOut == nil {
 		return "", fmt.Errorf("bedrock returned unexpected output type (model: %s)", modelID)
@@ -8,21 +8,25 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
 	"github.com/nyaosorg/go-readline-ny"
 	"github.com/nyaosorg/go-readline-ny/completion"
-	"github.com/nyaosorg/go-readline-ny/keys"
 	"github.com/nyaosorg/go-readline-ny/simplehistory"
 )
 
 // Message represents a chat message
 type ChatMessage struct {
 	Content   string
+	Request   string // user message without the pane context, empty for AI messages
 	FromUser  bool
+	Typed     bool // typed by the user rather than a follow-up sent by TmuxAI
 	Timestamp time.Time
+	Images    []string `json:"-"` // base64 PNGs sent with the message, never saved
 }
 
 type CLIInterface struct {
@@ -63,16 +67,17 @@ func (c *CLIInterface) Start(initMessage string) error {
 		HistoryCycling: true,
 	}
 
-	// Bind TAB key to completion
-	editor.BindKey(keys.CtrlI, c.newCompleter())
-
-	// Bind Ctrl+O and Alt+E to open current prompt in external editor
-	editorCmd := &readline.GoCommand{
-		Name: "EDIT_IN_EDITOR",
-		Func: cmdEditInEditor,
+	// TAB completes, Ctrl+O and Alt+E open the current prompt in an external
+	// editor, unless cli.key_bindings moves them
+	commands := map[string]readline.Command{
+		"COMPLETE":       c.newCompleter(),
+		"EDIT_IN_EDITOR": &readline.GoCommand{Name: "EDIT_IN_EDITOR", Func: cmdEditInEditor},
+	}
+	vi, err := bindKeys(editor, c.manager.Config.CLI, commands)
+	if err != nil {
+		logger.Error("Failed to set up key bindings: %v", err)
+		fmt.Printf("Invalid cli config: %v\n", err)
 	}
-	editor.BindKey(keys.CtrlO, editorCmd)
-	editor.BindKey(keys.AltE, editorCmd)
 
 	if initMessage != "" {
 		fmt.Printf("%s%s\n", c.manager.GetPrompt(), initMessage)
@@ -82,6 +87,8 @@ func (c *CLIInterface) Start(initMessage string) error {
 	ctx := context.Background()
 
 	for {
+		editor.Default = c.manager.takePendingInput()
+		vi.reset()
 		line, err := editor.ReadLine(ctx)
 
 		if err == readline.CtrlC {
@@ -183,11 +190,25 @@ func cmdEditInEditor(ctx context.Context, B *readline.Buffer) readline.Result {
 }
 
 func (c *CLIInterface) processInput(input string) {
-	if c.manager.IsMessageSubcommand(input) {
+	// requests sent to the API wait until this one is done
+	c.manager.busy.Lock()
+	defer c.manager.busy.Unlock()
+	defer c.manager.saveSession()
+
+	task, planned := c.manager.plannedTask(input)
+	note, fixing := c.manager.fixRequest(input)
+	if !planned && !fixing && c.manager.IsMessageSubcommand(input) {
 		c.manager.ProcessSubCommand(input)
 		return
 	}
 
+	if c.manager.sessionTitle == "" {
+		c.manager.sessionTitle = input
+		if planned {
+			c.manager.sessionTitle = task
+		}
+	}
+
 	// Set up signal handling for Ctrl+C
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
@@ -212,7 +233,15 @@ func (c *CLIInterface) processInput(input string) {
 
 	// Run the message processing in the main thread
 	c.manager.Status = "running"
-	c.manager.ProcessUserMessage(ctx, input)
+	if planned {
+		c.manager.runPlanned(ctx, task)
+		c.manager.offerFix(ctx)
+	} else if fixing {
+		c.manager.runFix(ctx, note)
+	} else {
+		c.manager.ProcessUserMessage(ctxWithTypedInput(ctx), input)
+		c.manager.offerFix(ctx)
+	}
 	c.manager.Status = ""
 
 	close(done)
@@ -234,7 +263,7 @@ func (c *CLIInterface) newCompleter() *completion.CmdCompletionOrList2 {
 			// Handle /config subcommands
 			if len(field) > 0 && field[0] == "/config" {
 				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
-					return []string{"set", "get"}, []string{"set", "get"}
+					return []string{"set", "get", "save", "diff"}, []string{"set", "get", "save", "diff"}
 				} else if len(field) == 2 || (len(field) == 3 && !strings.HasSuffix(field[2], " ")) {
 					return AllowedConfigKeys, AllowedConfigKeys
 				}
@@ -243,15 +272,52 @@ func (c *CLIInterface) newCompleter() *completion.CmdCompletionOrList2 {
 			// Handle /prepare subcommands
 			if len(field) > 0 && field[0] == "/prepare" {
 				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
-					return []string{"bash", "zsh", "fish"}, []string{"bash", "zsh", "fish"}
+					return prepareShells, prepareShells
+				}
+			}
+
+			// Handle /exec-pane subcommands
+			if len(field) > 0 && field[0] == "/exec-pane" {
+				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
+					return []string{"set", "add", "remove", "release"}, []string{"set", "add", "remove", "release"}
+				} else if len(field) >= 2 && field[1] == "remove" {
+					names := c.manager.namedExecPaneNames()
+					return names, names
+				}
+			}
+
+			// Handle /history subcommands
+			if len(field) > 0 && field[0] == "/history" {
+				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
+					return []string{"exec", "failed", "copy"}, []string{"exec", "failed", "copy"}
+				}
+			}
+
+			// Handle /copy subcommands
+			if len(field) > 0 && field[0] == "/copy" {
+				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
+					return []string{"last-command", "last-output", "message"}, []string{"last-command", "last-output", "message"}
+				}
+			}
+
+			// Handle /jobs subcommands
+			if len(field) > 0 && field[0] == "/jobs" {
+				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
+					return []string{"list", "stop"}, []string{"list", "stop"}
+				} else if len(field) >= 2 && field[1] == "stop" {
+					ids := []string{"all"}
+					for _, job := range c.manager.backgroundJobs() {
+						ids = append(ids, strconv.Itoa(job.ID))
+					}
+					return ids, ids
 				}
 			}
 
 			// Handle /kb subcommands
 			if len(field) > 0 && field[0] == "/kb" {
 				if len(field) == 1 || (len(field) == 2 && !strings.HasSuffix(field[1], " ")) {
-					return []string{"list", "load", "unload"}, []string{"list", "load", "unload"}
-				} else if (len(field) == 2 && field[1] == "load") || (len(field) >= 3 && field[1] == "load") {
+					return []string{"list", "load", "unload", "save"}, []string{"list", "load", "unload", "save"}
+				} else if (len(field) == 2 && (field[1] == "load" || field[1] == "save")) || (len(field) >= 3 && (field[1] == "load" || field[1] == "save")) {
 					// Get available knowledge bases for completion
 					kbs, err := c.manager.listKBs()
 					if err != nil {
@@ -3,10 +3,12 @@ package internal
 import (
 	"context"
 	"fmt"
+	"os/exec"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/alvinunreal/tmuxai/config"
@@ -20,14 +22,50 @@ const helpMessage = `Available commands:
 - /clear: Clear the chat history
 - /reset: Reset the chat history
 - /prepare: Prepare the pane for TmuxAI automation
-- /watch <prompt>: Start watch mode
-- /squash: Summarize the chat history
+- /exec-pane: Show the current exec pane
+- /exec-pane set <target>: Use another pane as exec pane (e.g. %3 or mysession:1.2)
+- /exec-pane add <name> <target>: Add a named exec pane the AI can route commands to
+- /exec-pane remove <name>: Remove a named exec pane
+- /exec-pane release: Restore the exec pane's prompt and stop tracking its commands
+- /jobs: List background jobs and subagents started by the AI
+- /jobs stop <id|all>: Stop a background job or subagent and close its panes
+- /plan <task>: Have the AI plan the task, approve or edit the plan, then let it carry it out
+- /fix [note]: Have the AI explain why the exec pane's last command failed and correct it
+- /explain [pane] [N-M|N]: Explain the exec pane's last command, or a pane's content (lines N-M, or the last N)
+- /watch [pane ids...] <prompt>: Start watch mode, optionally on specific panes
+- /watch until <condition>: Watch until the condition is met, then stop and notify
+- /history [exec|failed] [page]: Browse this session's messages and commands, newest first
+- /history copy <n>: Put history entry n into the input line
+- /copy <last-command|last-output>: Copy the AI's last command or its output to the clipboard
+- /copy message <n>: Copy history entry n to the clipboard
+- /squash [N]: Summarize the chat history, keeping the last N messages
+- /sessions [list]: List saved sessions
+- /sessions resume <id|latest>: Resume a saved session
+- /config save [key|--all]: Save session overrides from /config set to config.yaml
+- /config diff: Show session overrides that differ from config.yaml
+- /config add <safety.allow_patterns|safety.deny_patterns|safety.always_allow_patterns|whitelist_patterns> <regex>: Add a safety pattern and save it to config.yaml
+- /usage: Show token usage and estimated cost for this session
+- /stats: Show what the next request's context is made of and how much is left
+- /export md [file]: Export this session to a Markdown file
+- /prompt reload: Re-read prompts/system.tmpl from the config directory
+- /debug dump [file]: Write prompts, pane content and AI responses to a zip for bug reports, secrets redacted
+- /audit [n]: Show the last n executed actions from the audit log (default 20)
+- /context windows <current|all>: Include panes from the current window or all session windows
+- /context exclude <pane>: Leave a pane out of the context entirely
+- /context readonly <pane>: Let the AI read a pane but never send it commands or keys
+- /context allow <pane>: Undo /context exclude and readonly for a pane
+- /context lines <pane> <n|off>: Capture n lines from a pane instead of max_capture_lines
+- /dryrun <on|off>: Show planned actions without sending them to the pane
+- /sandbox <on|off>: Run commands in a throwaway Docker container instead of the exec pane
 - /model: List available models and show current model
 - /model <name>: Switch to a different model
+- /persona: List personas and show the active one
+- /persona <name|off>: Switch persona (prompt, model and KBs), or drop it
 - /kb: List available knowledge bases
 - /kb load <name>: Load a knowledge base
 - /kb unload <name>: Unload a knowledge base
 - /kb unload --all: Unload all knowledge bases
+- /kb save <name>: Append notes distilled from this session to a knowledge base
 - /skill: List available skills
 - /skill load <name>: Load a skill
 - /skill unload <name>: Unload a skill
@@ -51,9 +89,27 @@ var commands = []string{
 	"/info",
 	"/watch",
 	"/prepare",
+	"/exec-pane",
+	"/jobs",
+	"/history",
+	"/copy",
 	"/config",
 	"/squash",
+	"/sessions",
+	"/usage",
+	"/stats",
+	"/audit",
+	"/export",
+	"/debug",
+	"/prompt",
+	"/plan",
+	"/fix",
+	"/explain",
+	"/context",
+	"/dryrun",
+	"/sandbox",
 	"/model",
+	"/persona",
 	"/kb",
 	"/skill",
 	"/websearch",
@@ -94,7 +150,7 @@ func (m *Manager) ProcessSubCommand(command string) {
 		return
 
 	case prefixMatch(commandPrefix, "/prepare"):
-		supportedShells := []string{"bash", "zsh", "fish"}
+		supportedShells := prepareShells
 		if err := m.InitExecPane(); err != nil {
 			m.Println(fmt.Sprintf("Error preparing exec pane: %v", err))
 			return
@@ -118,7 +174,7 @@ func (m *Manager) ProcessSubCommand(command string) {
 				m.PrepareExecPaneWithShell(shell)
 			} else {
 				m.Println("Shell detection is not supported on subshells.")
-				m.Println("Please specify the shell manually: /prepare bash, /prepare zsh, or /prepare fish")
+				m.Println("Please specify the shell manually: /prepare " + strings.Join(supportedShells, ", /prepare "))
 				return
 			}
 		} else {
@@ -145,28 +201,33 @@ func (m *Manager) ProcessSubCommand(command string) {
 		// for latency over ssh connections
 		time.Sleep(500 * time.Millisecond)
 		m.ExecPane.Refresh(m.GetMaxCaptureLines())
-		m.Messages = []ChatMessage{}
+		m.ExecPane.IsPrepared = m.ExecPane.IsPrepared || m.execPaneTracked(m.ExecPane.Id)
+		m.setMessages([]ChatMessage{})
 
 		fmt.Println(m.ExecPane.String())
 		m.parseExecPaneCommandHistory()
 
 		logger.Debug("Parsed exec history:")
 		for _, history := range m.ExecHistory {
-			logger.Debug(fmt.Sprintf("Command: %s\nOutput: %s\nCode: %d\n", history.Command, history.Output, history.Code))
+			logger.Debug("Command: %s\nOutput: %s\nCode: %d\n", history.Command, history.Output, history.Code)
 		}
 
 		return
 
 	case prefixMatch(commandPrefix, "/clear"):
-		m.Messages = []ChatMessage{}
+		m.setMessages([]ChatMessage{})
+		m.newSession()
 		_ = system.TmuxClearPane(m.PaneId)
 		return
 
 	case prefixMatch(commandPrefix, "/reset"):
-		m.Status = ""
-		m.Messages = []ChatMessage{}
+		m.setStatus("")
+		m.setMessages([]ChatMessage{})
+		m.newSession()
 		_ = system.TmuxClearPane(m.PaneId)
-		_ = system.TmuxClearPane(m.ExecPane.Id)
+		if !system.TmuxIsRemote() {
+			_ = system.TmuxClearPane(m.ExecPane.Id)
+		}
 		return
 
 	case prefixMatch(commandPrefix, "/exit"):
@@ -175,25 +236,222 @@ func (m *Manager) ProcessSubCommand(command string) {
 		logger.Info("Exit command received.")
 		return
 
+	case prefixMatch(commandPrefix, "/exec-pane"):
+		if len(parts) == 1 {
+			fmt.Println(m.ExecPane.String())
+			for _, name := range m.namedExecPaneNames() {
+				m.Println(fmt.Sprintf("%s: %s", name, m.namedExecPanes[name]))
+			}
+			return
+		}
+		if len(parts) == 4 && parts[1] == "add" {
+			// targets may contain case-sensitive session names
+			paneID, err := m.AddNamedExecPane(parts[2], strings.Fields(command)[3])
+			if err != nil {
+				m.Println(fmt.Sprintf("Error adding exec pane: %v", err))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Exec pane %s set to %s", parts[2], paneID))
+			return
+		}
+		if len(parts) == 3 && parts[1] == "remove" {
+			if !m.RemoveNamedExecPane(parts[2]) {
+				m.Println(fmt.Sprintf("No exec pane named %s", parts[2]))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Removed exec pane %s", parts[2]))
+			return
+		}
+		if len(parts) == 2 && parts[1] == "release" {
+			if err := m.ReleaseExecPane(); err != nil {
+				m.Println(fmt.Sprintf("Error releasing exec pane: %v", err))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Released exec pane %s", m.ExecPane.Id))
+			return
+		}
+		if len(parts) == 3 && parts[1] == "set" {
+			// targets may contain case-sensitive session names
+			target := strings.Fields(command)[2]
+			if err := m.SetExecPaneTarget(target); err != nil {
+				m.Println(fmt.Sprintf("Error setting exec pane: %v", err))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Exec pane set to %s", m.ExecPane.Id))
+			return
+		}
+		m.Println("Usage: /exec-pane [set <target> | add <name> <target> | remove <name> | release]")
+		return
+
+	case prefixMatch(commandPrefix, "/jobs"):
+		if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+			m.showBackgroundJobs()
+			return
+		}
+		if len(parts) == 3 && parts[1] == "stop" {
+			var ids []int
+			if parts[2] == "all" {
+				for _, job := range m.backgroundJobs() {
+					ids = append(ids, job.ID)
+				}
+			} else if id, err := strconv.Atoi(parts[2]); err == nil {
+				ids = []int{id}
+			} else {
+				m.Println("Usage: /jobs stop <id|all>")
+				return
+			}
+			for _, id := range ids {
+				if err := m.stopBackgroundJob(id); err != nil {
+					m.Println(fmt.Sprintf("Error stopping job: %v", err))
+					continue
+				}
+				m.Println(fmt.Sprintf("✓ Stopped background job %d", id))
+			}
+			return
+		}
+		m.Println("Usage: /jobs [list | stop <id|all>]")
+		return
+
+	case prefixMatch(commandPrefix, "/history"):
+		if len(parts) == 3 && parts[1] == "copy" {
+			n, err := strconv.Atoi(parts[2])
+			if err == nil {
+				err = m.copyHistoryEntry(n)
+			}
+			if err != nil {
+				m.Println(fmt.Sprintf("Error copying history entry: %v", err))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Entry %d is in the input line", n))
+			return
+		}
+		filter, page := "", 1
+		args := parts[1:]
+		if len(args) > 0 && (args[0] == "exec" || args[0] == "failed") {
+			filter, args = args[0], args[1:]
+		}
+		if len(args) == 1 {
+			if v, err := strconv.Atoi(args[0]); err == nil && v > 0 {
+				page, args = v, nil
+			}
+		}
+		if len(args) > 0 {
+			m.Println("Usage: /history [exec|failed] [page] | /history copy <n>")
+			return
+		}
+		m.showHistory(filter, page)
+		return
+
+	case prefixMatch(commandPrefix, "/copy"):
+		n := 0
+		valid := len(parts) == 2 && (parts[1] == "last-command" || parts[1] == "last-output")
+		if len(parts) == 3 && parts[1] == "message" {
+			var err error
+			n, err = strconv.Atoi(parts[2])
+			valid = err == nil
+		}
+		if !valid {
+			m.Println("Usage: /copy <last-command | last-output | message <n>>")
+			return
+		}
+		text, err := m.clipboardText(parts[1], n)
+		if err != nil {
+			m.Println(fmt.Sprintf("Error copying: %v", err))
+			return
+		}
+		method, err := system.CopyToClipboard(text)
+		if err != nil {
+			m.Println(fmt.Sprintf("Error copying: %v", err))
+			return
+		}
+		m.Println(fmt.Sprintf("✓ Copied %d characters with %s", utf8.RuneCountInString(text), method))
+		return
+
 	case prefixMatch(commandPrefix, "/squash"):
-		m.squashHistory()
+		m.squashCommand(parts[1:])
+		return
+
+	case prefixMatch(commandPrefix, "/sessions"):
+		if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+			m.showSessions()
+			return
+		}
+		if len(parts) == 3 && parts[1] == "resume" {
+			if err := m.ResumeSession(parts[2]); err != nil {
+				m.Println(fmt.Sprintf("Error resuming session: %v", err))
+				return
+			}
+			m.Println(fmt.Sprintf("✓ Resumed session %s (%d messages)", m.SessionID, len(m.Messages)))
+			return
+		}
+		m.Println("Usage: /sessions [list|resume <id|latest>]")
+		return
+
+	case prefixMatch(commandPrefix, "/usage"):
+		m.showUsage()
+		return
+
+	case prefixMatch(commandPrefix, "/stats"):
+		m.showStats()
+		return
+
+	case prefixMatch(commandPrefix, "/export"):
+		if len(parts) >= 2 && parts[1] == "md" {
+			// file names are case-sensitive, so take them from the raw command
+			path, err := m.exportMarkdown(argsAfter(command, 2))
+			if err != nil {
+				m.Println(fmt.Sprintf("Error exporting session: %v", err))
+				return
+			}
+			m.Println("✓ Session exported to " + path)
+			return
+		}
+		m.Println("Usage: /export md [file]")
+		return
+
+	case prefixMatch(commandPrefix, "/audit"):
+		n := 20
+		if len(parts) == 2 {
+			if v, err := strconv.Atoi(parts[1]); err == nil && v > 0 {
+				n = v
+			} else {
+				m.Println("Usage: /audit [n]")
+				return
+			}
+		}
+		m.showAudit(n)
 		return
 
 	case prefixMatch(commandPrefix, "/watch") || commandPrefix == "/w":
-		parts := strings.Fields(command)
-		if len(parts) > 1 {
-			watchDesc := strings.Join(parts[1:], " ")
+		panes, watchDesc, until := parseWatchArgs(strings.Fields(command)[1:])
+		for _, id := range panes {
+			if _, found := m.findPane(id); !found {
+				m.Println(fmt.Sprintf("Pane %s not found", id))
+				return
+			}
+		}
+		if watchDesc != "" {
 			startWatch := `
 1. Find out if there is new content in the pane based on chat history.
 2. Comment only considering the new content in this pane output.
 
 Watch for: ` + watchDesc
-			m.Status = "running"
-			m.WatchMode = true
+			m.watchGoal = ""
+			if until {
+				startWatch = `
+1. Find out if there is new content in the pane based on chat history.
+2. Decide from the new content whether this has happened, and say so once it has: ` + watchDesc
+				m.watchGoal = watchDesc
+			}
+			m.setStatus("running")
+			m.setWatchMode(true)
+			m.watchLastCapture = ""
+			m.watchPanes = panes
+			m.watchChanges = nil
 			m.startWatchMode(startWatch)
 			return
 		}
-		m.Println("Usage: /watch <description>")
+		m.Println("Usage: /watch [pane ids...] [until] <description>")
 		return
 
 	case prefixMatch(commandPrefix, "/config"):
@@ -215,17 +473,106 @@ Watch for: ` + watchDesc
 				return
 			}
 			value := strings.Join(parts[3:], " ")
-			m.SessionOverrides[key] = config.TryInferType(key, value)
+			m.setOverride(key, config.TryInferType(key, value))
 			m.Println(fmt.Sprintf("Set %s = %v", key, m.SessionOverrides[key]))
 			return
+		} else if (len(parts) == 2 || len(parts) == 3) && parts[1] == "save" {
+			key := ""
+			if len(parts) == 3 && parts[2] != "--all" {
+				key = parts[2]
+			}
+			m.saveOverrides(key)
+			return
+		} else if len(parts) == 2 && parts[1] == "diff" {
+			diff := m.configDiff()
+			if len(diff) == 0 {
+				m.Println("No session overrides differ from " + config.ConfigFileUsed())
+				return
+			}
+			m.Println("Session overrides not in " + config.ConfigFileUsed() + ":")
+			for _, line := range diff {
+				fmt.Println("  " + line)
+			}
+			return
+		} else if len(parts) >= 4 && parts[1] == "add" {
+			// patterns are case-sensitive, so take them from the raw command
+			m.addSafetyPattern(parts[2], argsAfter(command, 3))
+			return
 		} else {
 			code, _ := system.HighlightCode("yaml", m.FormatConfig())
 			fmt.Println(code)
 			return
 		}
 
+	case prefixMatch(commandPrefix, "/context"):
+		if len(parts) == 3 && parts[1] == "windows" && (parts[2] == "current" || parts[2] == "all") {
+			m.setOverride("context_windows", parts[2])
+			m.Println(fmt.Sprintf("Pane context: %s window(s)", parts[2]))
+			return
+		}
+		if len(parts) == 3 && (parts[1] == "exclude" || parts[1] == "readonly" || parts[1] == "allow") {
+			// targets may contain case-sensitive session names
+			if err := m.setPaneAccess(parts[1], strings.Fields(command)[2]); err != nil {
+				m.Println(fmt.Sprintf("Error: %v", err))
+			}
+			return
+		}
+		if len(parts) == 4 && parts[1] == "lines" {
+			if err := m.setPaneCaptureLines(strings.Fields(command)[2], parts[3]); err != nil {
+				m.Println(fmt.Sprintf("Error: %v", err))
+			}
+			return
+		}
+		if len(parts) == 1 {
+			m.Println(fmt.Sprintf("Pane context: %s window(s)", m.GetContextWindows()))
+			for _, line := range m.paneAccessSummary() {
+				m.Println(line)
+			}
+			return
+		}
+		m.Println("Usage: /context [windows <current|all> | exclude <pane> | readonly <pane> | allow <pane> | lines <pane> <n|off>]")
+		return
+
+	case prefixMatch(commandPrefix, "/dryrun"):
+		if len(parts) == 2 && (parts[1] == "on" || parts[1] == "off") {
+			m.setOverride("dry_run", parts[1] == "on")
+		} else if len(parts) != 1 {
+			m.Println("Usage: /dryrun <on|off>")
+			return
+		}
+		if m.GetDryRun() {
+			m.Println("Dry-run: on (actions are shown but not sent to the pane)")
+		} else {
+			m.Println("Dry-run: off")
+		}
+		return
+
+	case prefixMatch(commandPrefix, "/sandbox"):
+		if len(parts) == 2 && (parts[1] == "on" || parts[1] == "off") {
+			if parts[1] == "on" {
+				if _, err := exec.LookPath("docker"); err != nil {
+					m.Println("Cannot enable sandbox: docker was not found in PATH")
+					return
+				}
+			}
+			m.setOverride("sandbox", parts[1] == "on")
+		} else if len(parts) != 1 {
+			m.Println("Usage: /sandbox <on|off>")
+			return
+		}
+		if !m.GetSandbox() {
+			m.Println("Sandbox: off")
+			return
+		}
+		workdir, err := m.sandboxWorkdir()
+		if err != nil {
+			workdir = err.Error()
+		}
+		m.Println(fmt.Sprintf("Sandbox: on (image %s, %s mounted at %s)", m.Config.Sandbox.Image, workdir, sandboxMount))
+		return
+
 	case prefixMatch(commandPrefix, "/kb"):
-		// Handle KB commands: /kb, /kb list, /kb load <name>, /kb unload <name>
+		// Handle KB commands: /kb, /kb list, /kb load <name>, /kb unload <name>, /kb save <name>
 		if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
 			// List all available knowledge bases
 			kbs, err := m.listKBs()
@@ -242,15 +589,24 @@ Watch for: ` + watchDesc
 			m.Println("Available knowledge bases:")
 			totalTokens := 0
 			loadedCount := 0
+			remote := m.remoteKBs()
+			sent := m.kbPromptContents()
 
 			for _, name := range kbs {
 				_, loaded := m.LoadedKBs[name]
 				status := "[ ]"
 				tokens := ""
+				if rawURL, ok := remote[name]; ok {
+					tokens = " <" + rawURL + ">"
+				}
 				if loaded {
 					status = "[✓]"
-					tokenCount := system.EstimateTokenCount(m.LoadedKBs[name])
-					tokens = fmt.Sprintf(" (%d tokens)", tokenCount)
+					tokenCount := m.countTokens(m.LoadedKBs[name])
+					if sent[name] != m.LoadedKBs[name] {
+						tokens += fmt.Sprintf(" (%d tokens, truncated to %d)", tokenCount, m.countTokens(sent[name]))
+					} else {
+						tokens += fmt.Sprintf(" (%d tokens)", tokenCount)
+					}
 					totalTokens += tokenCount
 					loadedCount++
 				}
@@ -259,7 +615,15 @@ Watch for: ` + watchDesc
 
 			if loadedCount > 0 {
 				m.Println("")
-				m.Println(fmt.Sprintf("Loaded: %d KB(s), %d tokens", loadedCount, totalTokens))
+				summary := fmt.Sprintf("Loaded: %d KB(s), %d tokens", loadedCount, totalTokens)
+				if budget := m.kbBudget(); budget > 0 {
+					summary += fmt.Sprintf(" (%d%% of the %d token budget", totalTokens*100/budget, budget)
+					if totalTokens > budget {
+						summary += ", truncated to fit"
+					}
+					summary += ")"
+				}
+				m.Println(summary)
 			}
 			return
 
@@ -280,7 +644,7 @@ Watch for: ` + watchDesc
 				return
 			}
 
-			tokenCount := system.EstimateTokenCount(m.LoadedKBs[name])
+			tokenCount := m.countTokens(m.LoadedKBs[name])
 			m.Println(fmt.Sprintf("✓ Loaded knowledge base: %s (%d tokens)", name, tokenCount))
 			return
 
@@ -312,8 +676,28 @@ Watch for: ` + watchDesc
 			m.Println(fmt.Sprintf("✓ Unloaded knowledge base: %s", name))
 			return
 
+		} else if len(parts) >= 2 && parts[1] == "save" {
+			if len(parts) < 3 {
+				m.Println("Usage: /kb save <name>")
+				return
+			}
+
+			name := parts[2]
+			path, notes, err := m.saveKBNotes(name)
+			if err != nil {
+				m.Println(fmt.Sprintf("Error saving notes to KB '%s': %v", name, err))
+				return
+			}
+			if notes == "" {
+				m.Println("Nothing from this session worth saving")
+				return
+			}
+
+			m.Println(fmt.Sprintf("✓ Saved notes to %s:\n%s", path, notes))
+			return
+
 		} else {
-			m.Println("Usage: /kb [list|load <name>|unload <name>|unload --all]")
+			m.Println("Usage: /kb [list|load <name>|unload <name>|unload --all|save <name>]")
 			return
 		}
 
@@ -329,6 +713,22 @@ Watch for: ` + watchDesc
 			return
 		}
 
+	case prefixMatch(commandPrefix, "/persona"):
+		if len(parts) == 1 {
+			m.listPersonas()
+			return
+		}
+		if err := m.SwitchPersona(parts[1]); err != nil {
+			m.Println(err.Error())
+			return
+		}
+		if parts[1] == "off" {
+			m.Println("✓ Persona off")
+			return
+		}
+		m.Println(fmt.Sprintf("✓ Switched to persona %s (model: %s)", parts[1], m.GetModelsDefault()))
+		return
+
 	case prefixMatch(commandPrefix, "/skill"):
 		// Feature gate
 		if m.Skills == nil || !m.Config.KnowledgeBase.Skills.Enabled {
@@ -572,7 +972,7 @@ Watch for: ` + watchDesc
 		}
 		formatted := FormatSearchResultsBlock(query, searchResp.Provider, searchResp.Results)
 		fmt.Println(formatted)
-		m.Messages = append(m.Messages, ChatMessage{
+		m.appendMessages(ChatMessage{
 			Content:   formatted,
 			FromUser:  false,
 			Timestamp: time.Now(),
@@ -610,7 +1010,7 @@ Watch for: ` + watchDesc
 				}
 				fmt.Printf("...fetched: %s (%d chars)%s\n", urlStr, chrs, sourceLabel)
 				formatted := FormatFetchResultsBlock(urlStr, fetchResp.Content)
-				m.Messages = append(m.Messages, ChatMessage{
+				m.appendMessages(ChatMessage{
 					Content:   formatted,
 					FromUser:  false,
 					Timestamp: time.Now(),
@@ -664,6 +1064,47 @@ Watch for: ` + watchDesc
 		}
 		m.Println("Usage: /mcp [list|tools <server>|load|reload|unload]")
 
+	case prefixMatch(commandPrefix, "/plan"):
+		// tasks are taken by plannedTask before commands are processed
+		m.Println("Usage: /plan <task>")
+		return
+
+	case prefixMatch(commandPrefix, "/fix"):
+		// /fix is taken by fixRequest before commands are processed
+		m.Println("Usage: /fix [note]")
+		return
+
+	case prefixMatch(commandPrefix, "/explain"):
+		explanation, err := m.explain(parts[1:])
+		if err != nil {
+			m.Println(fmt.Sprintf("Failed to explain: %v", err))
+			return
+		}
+		fmt.Println(system.FormatMessage(explanation, m.GetRenderMarkdown()))
+		return
+
+	case prefixMatch(commandPrefix, "/prompt"):
+		if len(parts) == 2 && parts[1] == "reload" {
+			m.reloadSystemTemplate()
+			return
+		}
+		m.Println("Usage: /prompt reload")
+		return
+
+	case prefixMatch(commandPrefix, "/debug"):
+		if len(parts) >= 2 && parts[1] == "dump" {
+			path, err := m.writeDebugBundle(argsAfter(command, 2))
+			if err != nil {
+				m.Println(fmt.Sprintf("Error writing debug bundle: %v", err))
+				return
+			}
+			m.Println("✓ Debug bundle written to " + path)
+			m.Println("Secrets matching known patterns are redacted, but check it before sharing")
+			return
+		}
+		m.Println("Usage: /debug dump [file]")
+		return
+
 	default:
 		m.Println(fmt.Sprintf("Unknown command: %s. Type '/help' to see available commands.", command))
 		return
@@ -671,6 +1112,19 @@ Watch for: ` + watchDesc
 }
 
 // Helper function to check if a command matches a prefix
+// argsAfter returns the raw text following the first n fields of a command
+func argsAfter(command string, n int) string {
+	rest := strings.TrimSpace(command)
+	for i := 0; i < n; i++ {
+		idx := strings.IndexFunc(rest, unicode.IsSpace)
+		if idx == -1 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[idx:])
+	}
+	return rest
+}
+
 func prefixMatch(command, target string) bool {
 	return strings.HasPrefix(target, command)
 }
@@ -716,25 +1170,40 @@ func (m *Manager) formatInfo() {
 	// Display context information section
 	fmt.Println(formatter.FormatSection("\nContext"))
 	formatLine("Messages", len(m.Messages))
-	var totalTokens int
+	tokenizer := m.tokenizer()
+	var messageTokens int
 	for _, msg := range m.Messages {
-		totalTokens += system.EstimateTokenCount(msg.Content)
+		messageTokens += tokenizer.Count(msg.Content)
 	}
+	// what the next request carries: system prompt, KBs, skills and messages
+	totalTokens := m.contextTokens()
 
 	usagePercent := 0.0
 	if m.GetMaxContextSize() > 0 {
 		usagePercent = float64(totalTokens) / float64(m.GetMaxContextSize()) * 100
 	}
-	fmt.Print(formatter.LabelColor.Sprintf("%-*s", labelWidth, "Context Size~"))
+	formatLine("Tokenizer", tokenizer.Description())
+	sizeLabel := "Context Size"
+	if !tokenizer.Exact {
+		sizeLabel += "~"
+	}
+	fmt.Print(formatter.LabelColor.Sprintf("%-*s", labelWidth, sizeLabel))
 	fmt.Print("  ") // Two spaces for separation
-	fmt.Printf("%s\n", fmt.Sprintf("%d tokens", totalTokens))
+	fmt.Printf("%s\n", fmt.Sprintf("%d tokens (messages %d, prompt and KBs %d)", totalTokens, messageTokens, totalTokens-messageTokens))
 	fmt.Printf("%-*s  %s\n", labelWidth, "", formatter.FormatProgressBar(usagePercent, 10))
 	formatLine("Max Size", fmt.Sprintf("%d tokens", m.GetMaxContextSize()))
+	formatLine("Squash At", fmt.Sprintf("%d tokens", m.squashBudget()))
+	formatLine("Redacted", m.redactionSummary())
 
 	// Display knowledge base information
 	if len(m.LoadedKBs) > 0 {
 		kbTokens := m.getTotalLoadedKBTokens()
 		formatLine("Loaded KBs", fmt.Sprintf("%d (%d tokens)", len(m.LoadedKBs), kbTokens))
+		if m.Config.KnowledgeBase.Search.Enabled {
+			embedderID, _ := m.kbEmbedder()
+			formatLine("KB Search", fmt.Sprintf("top %d chunks, %s embeddings (%d tokens last sent)",
+				m.kbSearchTopK(), embedderID, m.countTokens(m.kbExcerptsSent)))
+		}
 	}
 
 	// Display loaded skills information
@@ -764,7 +1233,7 @@ func (m *Manager) formatInfo() {
 				}
 			}
 			// Estimate tokens from the actual tool definitions text
-			mcpTokens := system.EstimateTokenCount(m.ensureMcpToolDefs())
+			mcpTokens := m.countTokens(m.ensureMcpToolDefs())
 			fmt.Println(formatter.FormatSection("\nMCP"))
 			formatLine("Active", fmt.Sprintf("%d (total tools: %d, ~%d tokens)", active, totalTools, mcpTokens))
 			if unhealthy > 0 {
@@ -782,7 +1251,7 @@ func (m *Manager) formatInfo() {
 
 	panes, _ := m.GetTmuxPanes()
 	for _, pane := range panes {
-		pane.Refresh(m.GetMaxCaptureLines())
+		pane.Refresh(m.GetPaneCaptureLines(pane.Id))
 		fmt.Println(pane.FormatInfo(formatter))
 	}
 }
@@ -872,7 +1341,7 @@ func (m *Manager) handleWebSearch(query string) {
 	fmt.Println(formatted)
 
 	// Inject into chat history so the LLM can see results on the next interaction
-	m.Messages = append(m.Messages, ChatMessage{
+	m.appendMessages(ChatMessage{
 		Content:   formatted,
 		FromUser:  false,
 		Timestamp: time.Now(),
@@ -911,7 +1380,7 @@ func (m *Manager) handleWebFetch(rawURL string) {
 
 	// Inject FULL content into chat history so the LLM can see it
 	formatted := FormatFetchResultsBlock(rawURL, resp.Content)
-	m.Messages = append(m.Messages, ChatMessage{
+	m.appendMessages(ChatMessage{
 		Content:   formatted,
 		FromUser:  false,
 		Timestamp: time.Now(),
@@ -178,3 +178,26 @@ func TestIsMessageSubcommand(t *testing.T) {
 		assert.Equal(t, tc.expected, result, tc.desc)
 	}
 }
+
+func TestArgsAfter(t *testing.T) {
+	assert.Equal(t, `\bTerraform\s+destroy`, argsAfter(`/config add safety.deny_patterns  \bTerraform\s+destroy`, 3))
+	assert.Equal(t, "", argsAfter("/config add", 3))
+}
+
+func TestProcessSubCommand_DryRun(t *testing.T) {
+	manager := &Manager{
+		Config:           &config.Config{},
+		SessionOverrides: make(map[string]any),
+	}
+	assert.False(t, manager.GetDryRun())
+
+	manager.ProcessSubCommand("/dryrun on")
+	assert.True(t, manager.GetDryRun())
+
+	manager.ProcessSubCommand("/dryrun off")
+	assert.False(t, manager.GetDryRun())
+
+	manager.Config.DryRun = true
+	manager.ProcessSubCommand("/dryrun maybe")
+	assert.False(t, manager.GetDryRun(), "invalid argument leaves the session override alone")
+}
@@ -3,20 +3,52 @@ package internal
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
 )
 
 // AllowedConfigKeys defines the list of configuration keys that users are allowed to modify
 var AllowedConfigKeys = []string{
 	"max_capture_lines",
+	"pane_diff",
+	"capture.ansi",
+	"capture.normalize",
+	"git_context.enabled",
+	"shell_context.enabled",
+	"redaction.enabled",
 	"max_context_size",
 	"wait_interval",
+	"exec_timeout",
+	"exec_timeout_interrupt",
+	"exec_stream_interval",
+	"watch.interval",
+	"watch.only_on_change",
+	"watch.notify",
 	"send_keys_confirm",
+	"typing.key_delay",
+	"typing.line_delay",
+	"typing.human",
 	"paste_multiline_confirm",
 	"exec_confirm",
+	"mcp_confirm",
+	"files.write_confirm",
+	"context_windows",
+	"fix_on_failure",
+	"render_markdown",
+	"tmux.prompt_detection",
 	"yolo",
+	"dry_run",
+	"plan_mode",
+	"max_subagents",
+	"max_iterations",
+	"audit_log",
 	"openrouter.model",
 	"requesty.model",
 	"openai.api_key",
@@ -40,6 +72,87 @@ func (m *Manager) GetMaxCaptureLines() int {
 	return m.Config.MaxCaptureLines
 }
 
+// paneCaptureLinesPrefix starts the session override keys /context lines
+// sets, followed by a pane ID
+const paneCaptureLinesPrefix = "pane_capture_lines."
+
+// GetPaneCaptureLines returns how many lines to capture from a pane: its
+// /context lines override, or max_capture_lines
+func (m *Manager) GetPaneCaptureLines(paneID string) int {
+	if override, exists := m.SessionOverrides[paneCaptureLinesPrefix+paneID]; exists {
+		if val, ok := override.(int); ok {
+			return val
+		}
+	}
+	return m.GetMaxCaptureLines()
+}
+
+// GetCaptureANSI reports whether panes are captured with their color
+// escape sequences for the context
+func (m *Manager) GetCaptureANSI() bool {
+	if override, exists := m.SessionOverrides["capture.ansi"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Capture.ANSI
+}
+
+// GetCaptureNormalize reports whether captured pane content is normalized
+// before it's sent
+func (m *Manager) GetCaptureNormalize() bool {
+	if override, exists := m.SessionOverrides["capture.normalize"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Capture.Normalize
+}
+
+// GetPaneDiff reports whether pane content already sent in a task is left
+// out of its follow-up requests
+func (m *Manager) GetPaneDiff() bool {
+	if override, exists := m.SessionOverrides["pane_diff"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.PaneDiff
+}
+
+// GetPlanMode reports whether every typed task is planned and approved
+// before it runs, as with /plan
+func (m *Manager) GetPlanMode() bool {
+	if override, exists := m.SessionOverrides["plan_mode"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.PlanMode
+}
+
+// GetMaxSubagents returns how many subagents may run at once, 0 turning
+// SpawnAgent off
+func (m *Manager) GetMaxSubagents() int {
+	if override, exists := m.SessionOverrides["max_subagents"]; exists {
+		if val, ok := override.(int); ok {
+			return val
+		}
+	}
+	return m.Config.MaxSubagents
+}
+
+// GetMaxIterations returns how many AI requests one typed request may lead
+// to before the task pauses, 0 for no limit
+func (m *Manager) GetMaxIterations() int {
+	if override, exists := m.SessionOverrides["max_iterations"]; exists {
+		if val, ok := override.(int); ok {
+			return val
+		}
+	}
+	return m.Config.MaxIterations
+}
+
 // GetMaxContextSize returns the max context size value with session override if present
 func (m *Manager) GetMaxContextSize() int {
 	if override, exists := m.SessionOverrides["max_context_size"]; exists {
@@ -60,6 +173,40 @@ func (m *Manager) GetWaitInterval() int {
 	return m.Config.WaitInterval
 }
 
+// GetWatchInterval returns the seconds between watch mode checks, falling
+// back to the wait interval
+func (m *Manager) GetWatchInterval() int {
+	if override, exists := m.SessionOverrides["watch.interval"]; exists {
+		if val, ok := override.(int); ok && val > 0 {
+			return val
+		}
+	}
+	if m.Config.Watch.Interval > 0 {
+		return m.Config.Watch.Interval
+	}
+	return m.GetWaitInterval()
+}
+
+// GetWatchOnlyOnChange returns whether watch mode skips unchanged panes
+func (m *Manager) GetWatchOnlyOnChange() bool {
+	if override, exists := m.SessionOverrides["watch.only_on_change"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Watch.OnlyOnChange
+}
+
+// GetWatchNotify returns whether watch mode sends desktop notifications
+func (m *Manager) GetWatchNotify() bool {
+	if override, exists := m.SessionOverrides["watch.notify"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Watch.Notify
+}
+
 func (m *Manager) GetSendKeysConfirm() bool {
 	if m.GetYolo() {
 		return false
@@ -72,6 +219,32 @@ func (m *Manager) GetSendKeysConfirm() bool {
 	return m.Config.SendKeysConfirm
 }
 
+// GetTypingDelays returns how send_keys types into a pane, from typing with
+// session overrides applied
+func (m *Manager) GetTypingDelays() system.TypingDelays {
+	typing := m.Config.Typing
+	if override, exists := m.SessionOverrides["typing.key_delay"]; exists {
+		if val, ok := override.(int); ok {
+			typing.KeyDelay = val
+		}
+	}
+	if override, exists := m.SessionOverrides["typing.line_delay"]; exists {
+		if val, ok := override.(int); ok {
+			typing.LineDelay = val
+		}
+	}
+	if override, exists := m.SessionOverrides["typing.human"]; exists {
+		if val, ok := override.(bool); ok {
+			typing.Human = val
+		}
+	}
+	return system.TypingDelays{
+		Key:   time.Duration(typing.KeyDelay) * time.Millisecond,
+		Line:  time.Duration(typing.LineDelay) * time.Millisecond,
+		Human: typing.Human,
+	}
+}
+
 func (m *Manager) GetPasteMultilineConfirm() bool {
 	if m.GetYolo() {
 		return false
@@ -84,6 +257,105 @@ func (m *Manager) GetPasteMultilineConfirm() bool {
 	return m.Config.PasteMultilineConfirm
 }
 
+// GetGitContext reports whether the git state of the exec pane's directory
+// is sent as context
+func (m *Manager) GetGitContext() bool {
+	if override, exists := m.SessionOverrides["git_context.enabled"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.GitContext.Enabled
+}
+
+// GetRedaction reports whether secrets are masked in pane content and
+// command output before they are sent
+func (m *Manager) GetRedaction() bool {
+	if override, exists := m.SessionOverrides["redaction.enabled"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Redaction.Enabled
+}
+
+// GetShellContext reports whether the summary of the user's shell history
+// and aliases is sent in the system prompt
+func (m *Manager) GetShellContext() bool {
+	if override, exists := m.SessionOverrides["shell_context.enabled"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.ShellContext.Enabled
+}
+
+// GetPersona returns the name of the active persona, "" for none
+func (m *Manager) GetPersona() string {
+	if override, exists := m.SessionOverrides["persona"]; exists {
+		if val, ok := override.(string); ok {
+			return val
+		}
+	}
+	return m.Config.Persona
+}
+
+// GetWriteFileConfirm reports whether WriteFile and ApplyPatch changes are
+// confirmed before they're written
+func (m *Manager) GetWriteFileConfirm() bool {
+	if m.GetYolo() {
+		return false
+	}
+	if override, exists := m.SessionOverrides["files.write_confirm"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Files.WriteConfirm
+}
+
+// GetExecTimeout returns how many seconds a command in a prepared exec pane
+// may run, 0 for no limit
+func (m *Manager) GetExecTimeout() int {
+	if override, exists := m.SessionOverrides["exec_timeout"]; exists {
+		if val, ok := override.(int); ok {
+			return val
+		}
+	}
+	return m.Config.ExecTimeout
+}
+
+// GetExecTimeoutInterrupt reports whether timed out commands get a C-c
+func (m *Manager) GetExecTimeoutInterrupt() bool {
+	if override, exists := m.SessionOverrides["exec_timeout_interrupt"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.ExecTimeoutInterrupt
+}
+
+// GetExecStreamInterval returns how many seconds apart the output of a
+// running command is shown to the AI, or 0 if it waits for the command
+func (m *Manager) GetExecStreamInterval() int {
+	if override, exists := m.SessionOverrides["exec_stream_interval"]; exists {
+		if val, ok := override.(int); ok {
+			return val
+		}
+	}
+	return m.Config.ExecStreamInterval
+}
+
+// GetRenderMarkdown reports whether AI messages are rendered as Markdown
+func (m *Manager) GetRenderMarkdown() bool {
+	if override, exists := m.SessionOverrides["render_markdown"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.RenderMarkdown
+}
+
 func (m *Manager) GetExecConfirm() bool {
 	if m.GetYolo() {
 		return false
@@ -96,6 +368,49 @@ func (m *Manager) GetExecConfirm() bool {
 	return m.Config.ExecConfirm
 }
 
+func (m *Manager) GetMcpConfirm() bool {
+	if m.GetYolo() {
+		return false
+	}
+	if override, exists := m.SessionOverrides["mcp_confirm"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.McpConfirm
+}
+
+// GetContextWindows returns which windows' panes are sent as context: "current" or "all"
+func (m *Manager) GetContextWindows() string {
+	if override, exists := m.SessionOverrides["context_windows"]; exists {
+		if val, ok := override.(string); ok {
+			return val
+		}
+	}
+	return m.Config.ContextWindows
+}
+
+// GetFixOnFailure returns what happens when the exec pane's last command
+// failed after a task: "off", "offer" or "auto"
+func (m *Manager) GetFixOnFailure() string {
+	if override, exists := m.SessionOverrides["fix_on_failure"]; exists {
+		if val, ok := override.(string); ok {
+			return val
+		}
+	}
+	return m.Config.FixOnFailure
+}
+
+// GetPromptDetection returns how the exec pane's command boundaries are found: "ps1", "osc133" or "hook"
+func (m *Manager) GetPromptDetection() string {
+	if override, exists := m.SessionOverrides["tmux.prompt_detection"]; exists {
+		if val, ok := override.(string); ok {
+			return val
+		}
+	}
+	return m.Config.Tmux.PromptDetection
+}
+
 func (m *Manager) GetYolo() bool {
 	if override, exists := m.SessionOverrides["yolo"]; exists {
 		if val, ok := override.(bool); ok {
@@ -105,6 +420,36 @@ func (m *Manager) GetYolo() bool {
 	return m.Config.Yolo
 }
 
+// GetDryRun reports whether exec, send-keys and paste actions are only shown, never sent
+func (m *Manager) GetDryRun() bool {
+	if override, exists := m.SessionOverrides["dry_run"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.DryRun
+}
+
+// GetAuditLog reports whether actions are recorded in the audit log
+func (m *Manager) GetAuditLog() bool {
+	if override, exists := m.SessionOverrides["audit_log"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.AuditLog
+}
+
+// GetSandbox reports whether exec_command runs in a Docker sandbox
+func (m *Manager) GetSandbox() bool {
+	if override, exists := m.SessionOverrides["sandbox"]; exists {
+		if val, ok := override.(bool); ok {
+			return val
+		}
+	}
+	return m.Config.Sandbox.Enabled
+}
+
 func (m *Manager) GetPromptTemplate() string {
 	if override, exists := m.SessionOverrides["status_line"]; exists {
 		if val, ok := override.(string); ok {
@@ -192,6 +537,18 @@ func (m *Manager) GetModelsDefault() string {
 		}
 	}
 
+	return m.configuredModel()
+}
+
+// configuredModel returns the model configuration the config selects: the
+// chat role, default_model or the first available model
+func (m *Manager) configuredModel() string {
+	if name := m.Config.Roles.Chat; name != "" {
+		if _, exists := m.Config.Models[name]; exists {
+			return name
+		}
+	}
+
 	// If default model is configured, use it
 	if m.Config.DefaultModel != "" {
 		return m.Config.DefaultModel
@@ -209,7 +566,7 @@ func (m *Manager) GetModelsDefault() string {
 
 // SetModelsDefault sets the default model configuration for the current session
 func (m *Manager) SetModelsDefault(modelName string) {
-	m.SessionOverrides["default_model"] = modelName
+	m.setOverride("default_model", modelName)
 }
 
 // GetAvailableModels returns a list of available model configuration names
@@ -232,6 +589,9 @@ func (m *Manager) GetAvailableModels() []string {
 // GetModelConfig returns the model configuration for the given name
 func (m *Manager) GetModelConfig(name string) (config.ModelConfig, bool) {
 	config, exists := m.Config.Models[name]
+	if exists && m.AiClient != nil {
+		config.APIKey = m.AiClient.cachedAPIKey(config)
+	}
 	return config, exists
 }
 
@@ -249,6 +609,41 @@ func (m *Manager) GetCurrentModelConfig() (config.ModelConfig, bool) {
 	return m.getLegacyModelConfig(), true
 }
 
+// toolCallingEnabled reports whether the current model uses native tool calls
+func (m *Manager) toolCallingEnabled() bool {
+	mc, exists := m.GetCurrentModelConfig()
+	if !exists || !mc.ToolCalling {
+		return false
+	}
+	switch mc.Provider {
+	case "openai", "anthropic", "openrouter", "azure", "requesty":
+		return true
+	}
+	return false
+}
+
+// structuredOutputEnabled reports whether the current model answers with JSON
+func (m *Manager) structuredOutputEnabled() bool {
+	mc, exists := m.GetCurrentModelConfig()
+	if !exists || !mc.StructuredOutput || mc.ToolCalling {
+		return false
+	}
+	switch mc.Provider {
+	case "openai", "openrouter", "azure", "requesty":
+		return true
+	}
+	return false
+}
+
+// isKeylessProvider reports whether a provider authenticates without an api_key
+func isKeylessProvider(provider string) bool {
+	switch provider {
+	case "github-copilot", "bedrock", "ollama":
+		return true
+	}
+	return false
+}
+
 // hasValidAIConfiguration checks if there's a valid AI configuration available
 func (m *Manager) hasValidAIConfiguration() bool {
 	// Check new model configurations first
@@ -257,14 +652,14 @@ func (m *Manager) hasValidAIConfiguration() bool {
 		// Check if any model has an API key or is a keyless provider
 		for _, modelName := range availableModels {
 			if modelConfig, exists := m.GetModelConfig(modelName); exists {
-				if modelConfig.APIKey != "" || modelConfig.Provider == "github-copilot" || modelConfig.Provider == "bedrock" {
+				if hasAPIKey(modelConfig) || isKeylessProvider(modelConfig.Provider) {
 					return true
 				}
 			}
 		}
 		// Also check if current model has API key or is a keyless provider
 		if currentModelConfig, exists := m.GetCurrentModelConfig(); exists {
-			if currentModelConfig.APIKey != "" || currentModelConfig.Provider == "github-copilot" || currentModelConfig.Provider == "bedrock" {
+			if hasAPIKey(currentModelConfig) || isKeylessProvider(currentModelConfig.Provider) {
 				return true
 			}
 		}
@@ -359,6 +754,126 @@ func (m *Manager) GetModel() string {
 	return m.GetOpenRouterModel()
 }
 
+// safetyPatternKeys are the list keys `/config add` and always-allow
+// confirmations can extend and persist
+var safetyPatternKeys = []string{"safety.allow_patterns", "safety.deny_patterns", "safety.always_allow_patterns", "whitelist_patterns"}
+
+// addSafetyPattern adds a risk scorer or whitelist pattern for this session
+// and saves it to the config file so it persists.
+func (m *Manager) addSafetyPattern(key, pattern string) {
+	if !slices.Contains(safetyPatternKeys, key) {
+		m.Println(fmt.Sprintf("Cannot add to '%s'. Only these keys are allowed: %s", key, strings.Join(safetyPatternKeys, ", ")))
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		m.Println(fmt.Sprintf("Invalid pattern '%s': %v", pattern, err))
+		return
+	}
+
+	switch key {
+	case "safety.allow_patterns":
+		m.Config.Safety.AllowPatterns = append(m.Config.Safety.AllowPatterns, pattern)
+	case "safety.deny_patterns":
+		m.Config.Safety.DenyPatterns = append(m.Config.Safety.DenyPatterns, pattern)
+	case "safety.always_allow_patterns":
+		m.Config.Safety.AlwaysAllowPatterns = append(m.Config.Safety.AlwaysAllowPatterns, pattern)
+	default:
+		m.Config.WhitelistPatterns = append(m.Config.WhitelistPatterns, pattern)
+	}
+
+	path := config.ConfigFileUsed()
+	if err := config.AppendConfigListValue(path, key, pattern); err != nil {
+		logger.Error("Failed to persist %s: %v", key, err)
+		m.Println(fmt.Sprintf("Added %s pattern for this session, but saving failed: %v", key, err))
+		return
+	}
+	m.Println(fmt.Sprintf("✓ Added %s pattern %s (saved to %s)", key, pattern, path))
+}
+
+// overrideConfigKey returns the config key a session override stands for
+func overrideConfigKey(key string) string {
+	if key == "sandbox" {
+		return "sandbox.enabled"
+	}
+	return key
+}
+
+// sortedOverrideKeys returns the session override keys in order
+func (m *Manager) sortedOverrideKeys() []string {
+	keys := make([]string, 0, len(m.SessionOverrides))
+	for key := range m.SessionOverrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// saveOverrides writes session overrides to config.yaml, one key or all of
+// them when key is empty. Saved overrides are applied to the config and
+// dropped, so later edits to the file take effect again.
+func (m *Manager) saveOverrides(key string) {
+	keys := m.sortedOverrideKeys()
+	if key != "" {
+		if _, exists := m.SessionOverrides[key]; !exists {
+			m.Println(fmt.Sprintf("No session override for '%s'", key))
+			return
+		}
+		keys = []string{key}
+	}
+	if len(keys) == 0 {
+		m.Println("No session overrides to save")
+		return
+	}
+
+	path := config.ConfigFileUsed()
+	var saved []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, paneCaptureLinesPrefix) {
+			m.Println(fmt.Sprintf("Not saving %s: pane IDs change between tmux sessions", key))
+			continue
+		}
+		configKey := overrideConfigKey(key)
+		value := m.SessionOverrides[key]
+		if err := config.SetValue(m.Config, configKey, value); err != nil {
+			m.Println(fmt.Sprintf("Not saving %s: %v", key, err))
+			continue
+		}
+		if err := config.SetConfigValue(path, configKey, value); err != nil {
+			logger.Error("Failed to persist %s: %v", configKey, err)
+			m.Println(fmt.Sprintf("Failed to save %s: %v", configKey, err))
+			continue
+		}
+		m.deleteOverride(key)
+		saved = append(saved, configKey)
+	}
+	if len(saved) > 0 {
+		m.Println(fmt.Sprintf("✓ Saved %s to %s", strings.Join(saved, ", "), path))
+	}
+}
+
+// configDiff lists the session overrides that differ from the config file,
+// as "key: file value → session value"
+func (m *Manager) configDiff() []string {
+	var lines []string
+	for _, key := range m.sortedOverrideKeys() {
+		configKey := overrideConfigKey(key)
+		current, ok := config.Value(m.Config, configKey)
+		if strings.HasPrefix(key, paneCaptureLinesPrefix) {
+			current, ok = m.Config.MaxCaptureLines, true
+		}
+		override := m.SessionOverrides[key]
+		if ok && reflect.DeepEqual(current, override) {
+			continue
+		}
+		from, to := fmt.Sprintf("%v", current), fmt.Sprintf("%v", override)
+		if strings.Contains(configKey, "api_key") {
+			from, to = maskAPIKey(from), maskAPIKey(to)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", configKey, from, to))
+	}
+	return lines
+}
+
 // FormatConfig returns a nicely formatted string of all config values with session overrides applied
 func (m *Manager) FormatConfig() string {
 	var result strings.Builder
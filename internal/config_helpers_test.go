@@ -1,10 +1,14 @@
 package internal
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/alvinunreal/tmuxai/config"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetYolo(t *testing.T) {
@@ -192,3 +196,55 @@ func TestYoloSessionOverrideTakesPrecedence(t *testing.T) {
 	assert.False(t, manager.GetPasteMultilineConfirm())
 	assert.False(t, manager.GetExecConfirm())
 }
+
+func TestGetMcpConfirm(t *testing.T) {
+	manager := &Manager{
+		Config:           &config.Config{McpConfirm: true},
+		SessionOverrides: make(map[string]interface{}),
+	}
+	assert.True(t, manager.GetMcpConfirm())
+
+	manager.SessionOverrides["mcp_confirm"] = false
+	assert.False(t, manager.GetMcpConfirm())
+
+	manager.SessionOverrides["mcp_confirm"] = true
+	manager.SessionOverrides["yolo"] = true
+	assert.False(t, manager.GetMcpConfirm(), "yolo skips MCP confirmation")
+}
+
+func TestConfigDiffAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("max_capture_lines: 200 # lines per pane\n"), 0o644))
+	viper.SetConfigFile(path)
+	t.Cleanup(func() { viper.SetConfigFile("") })
+
+	cfg := config.DefaultConfig()
+	cfg.MaxCaptureLines = 200
+	m := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{
+		"max_capture_lines": 500,
+		"sandbox":           true,
+		"exec_confirm":      cfg.ExecConfirm,
+		"openai.api_key":    "sk-1234567890abcdef",
+	}}
+
+	assert.Equal(t, []string{
+		"max_capture_lines: 200 → 500",
+		"openai.api_key: **** → sk-1...cdef",
+		"sandbox.enabled: false → true",
+	}, m.configDiff())
+
+	captureStdout(t, func() { m.saveOverrides("max_capture_lines") })
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "max_capture_lines: 500 # lines per pane\n", string(data))
+	assert.Equal(t, 500, m.Config.MaxCaptureLines)
+	assert.NotContains(t, m.SessionOverrides, "max_capture_lines")
+
+	captureStdout(t, func() { m.saveOverrides("") })
+	assert.Empty(t, m.SessionOverrides)
+	assert.Empty(t, m.configDiff())
+	saved, err := config.Reload()
+	require.NoError(t, err)
+	assert.True(t, saved.Sandbox.Enabled)
+	assert.Equal(t, "sk-1234567890abcdef", saved.OpenAI.APIKey)
+}
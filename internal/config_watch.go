@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/fsnotify/fsnotify"
+)
+
+// configRestartKeys are the config keys read once at startup. Reloaded
+// values for them are kept aside by keepStartupConfig until a restart.
+var configRestartKeys = []string{"log.", "telemetry.", "tmux.", "cli.", "web_search.", "web_fetch.", "knowledge_base.skills.", "save_sessions", "reload_config"}
+
+// keepStartupConfig carries the configRestartKeys values over from the
+// running config into a reloaded one
+func keepStartupConfig(next, current *config.Config) {
+	next.Log = current.Log
+	next.Telemetry = current.Telemetry
+	next.Tmux = current.Tmux
+	next.CLI = current.CLI
+	next.WebSearch = current.WebSearch
+	next.WebFetch = current.WebFetch
+	next.KnowledgeBase.Skills = current.KnowledgeBase.Skills
+	next.SaveSessions = current.SaveSessions
+	next.ReloadConfig = current.ReloadConfig
+}
+
+// configWatcher records that the config file changed on disk. Like KB
+// reloads, the change is applied on the main goroutine before the next
+// request.
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+	path    string
+	changed atomic.Bool
+	load    func() (*config.Config, error)
+}
+
+// watchConfig starts watching the config file when reload_config is on. The
+// file is watched through its directory, since editors often save by
+// replacing it.
+func (m *Manager) watchConfig() {
+	if !m.Config.ReloadConfig {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Error("Config hot reload disabled: failed to create file watcher: %v", err)
+		return
+	}
+	path := config.ConfigFileUsed()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		logger.Error("Config hot reload disabled: failed to watch %s: %v", path, err)
+		_ = watcher.Close()
+		return
+	}
+
+	w := &configWatcher{watcher: watcher, path: path, load: config.Reload}
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Name == path && event.Op != fsnotify.Chmod {
+					w.changed.Store(true)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Config watcher: %v", err)
+			}
+		}
+	}()
+	m.configWatcher = w
+}
+
+// reloadConfig applies a changed config file to the running session and
+// says in the chat which keys changed, and which of them wait for a restart.
+// Session overrides from /config set still take precedence. A file that
+// fails to load leaves the settings as they were.
+func (m *Manager) reloadConfig() {
+	if m.configWatcher == nil || !m.configWatcher.changed.Swap(false) {
+		return
+	}
+	next, err := m.configWatcher.load()
+	if err != nil {
+		logger.Error("Failed to reload config: %v", err)
+		m.Println(fmt.Sprintf("Warning: Failed to reload %s, keeping the previous settings: %v", m.configWatcher.path, err))
+		return
+	}
+	for _, path := range m.projectConfigs {
+		if _, err := config.ApplyProjectFile(next, path); err != nil {
+			logger.Error("Failed to apply project config: %v", err)
+		}
+	}
+
+	var applied, restart []string
+	themeChanged := false
+	for _, key := range config.ChangedKeys(m.Config, next) {
+		if configNeedsRestart(key) {
+			restart = append(restart, key)
+			continue
+		}
+		applied = append(applied, key)
+		themeChanged = themeChanged || strings.HasPrefix(key, "theme.")
+	}
+	if len(applied) == 0 && len(restart) == 0 {
+		return
+	}
+
+	keepStartupConfig(next, m.Config)
+	m.stateMu.Lock()
+	*m.Config = *next
+	m.stateMu.Unlock()
+	if themeChanged {
+		if err := system.SetTheme(m.Config.Theme.Preset, m.Config.Theme.Colors); err != nil {
+			logger.Error("Failed to set theme, using the default one: %v", err)
+		}
+	}
+	logger.Info("Reloaded config %s: applied %v, needs restart %v", m.configWatcher.path, applied, restart)
+
+	if len(applied) > 0 {
+		m.Println("Reloaded config: " + strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		m.Println("Changed but only applied after a restart: " + strings.Join(restart, ", "))
+	}
+}
+
+// configNeedsRestart reports whether a changed key is one of configRestartKeys
+func configNeedsRestart(key string) bool {
+	for _, restartKey := range configRestartKeys {
+		if key == restartKey || (strings.HasSuffix(restartKey, ".") && strings.HasPrefix(key, restartKey)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *configWatcher) close() {
+	_ = w.watcher.Close()
+}
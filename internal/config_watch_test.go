@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = stdout
+	_ = w.Close()
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestReloadConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	m := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{"exec_confirm": true}}
+	next := config.DefaultConfig()
+	next.MaxCaptureLines = 50
+	next.ExecConfirm = false
+	next.Tmux.SSHHost = "devbox"
+	m.configWatcher = &configWatcher{path: "config.yaml", load: func() (*config.Config, error) { return next, nil }}
+
+	out := captureStdout(t, m.reloadConfig)
+	assert.Empty(t, out, "nothing happens until the file changes")
+
+	m.configWatcher.changed.Store(true)
+	out = captureStdout(t, m.reloadConfig)
+	assert.Contains(t, out, "Reloaded config: max_capture_lines, exec_confirm")
+	assert.Contains(t, out, "only applied after a restart: tmux.ssh_host")
+
+	assert.Same(t, cfg, m.Config, "the config is updated in place")
+	assert.Equal(t, 50, m.GetMaxCaptureLines())
+	assert.True(t, m.GetExecConfirm(), "session overrides still win")
+	assert.Empty(t, m.Config.Tmux.SSHHost, "startup-only keys wait for a restart")
+}
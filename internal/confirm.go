@@ -12,39 +12,48 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/alvinunreal/tmuxai/system"
 	"github.com/fatih/color"
 	"golang.org/x/sys/unix"
 	"golang.org/x/term"
 )
 
+// execConfirmPrompt is the confirmation of AI commands, which offers to
+// always allow commands like the one shown
+const execConfirmPrompt = "Execute this command?"
+
+// allowPrefixWords is how many leading words of a command allowPrefix keeps
+const allowPrefixWords = 2
+
 func (m *Manager) confirmedToExecFn(command string, prompt string, edit bool) (bool, string) {
 	isSafe, _ := m.whitelistCheck(command)
 	if isSafe {
 		return true, command
 	}
 
-	promptColor := color.New(color.FgCyan, color.Bold)
+	theme := system.CurrentTheme()
+	promptColor := theme.Confirm
 
 	// Score the command for risk assessment
-	assessment := ScoreCommand(command)
+	assessment := m.assessCommand(command)
+	event := hookEvent{Event: hookOnConfirmRequired, Command: command, Risk: string(assessment.Level), Flags: assessment.Flags, Prompt: prompt}
+	if m.ExecPane != nil {
+		event.PaneID = m.ExecPane.Id
+	}
+	m.notifyHook(event)
 
-	// Determine color and icon based on risk level
-	var riskColor *color.Color
-	var riskIcon string
-	switch assessment.Level {
-	case RiskDanger:
-		riskColor = color.New(color.FgRed, color.Bold)
-		riskIcon = "!"
-	case RiskUnknown:
-		riskColor = color.New(color.FgYellow, color.Bold)
-		riskIcon = "?"
-	default: // RiskSafe
-		riskColor = color.New(color.FgGreen, color.Bold)
-		riskIcon = "✓"
+	riskColor, riskIcon := riskStyle(assessment.Level)
+
+	fmt.Println(riskColor.Sprintf("[%s]", assessment.Level))
+	for _, flag := range assessment.Flags {
+		fmt.Println(color.New(color.Faint).Sprint("  matched: " + flag))
 	}
 
+	always := offersAlwaysAllow(prompt, edit, assessment.Level)
 	var promptText string
-	if edit {
+	if always {
+		promptText = fmt.Sprintf("%s %s [Y/n/e/a]: ", riskColor.Sprint(riskIcon), prompt)
+	} else if edit {
 		promptText = fmt.Sprintf("%s %s [Y/n/e]: ", riskColor.Sprint(riskIcon), prompt)
 	} else {
 		promptText = fmt.Sprintf("%s %s [Y/n]: ", riskColor.Sprint(riskIcon), prompt)
@@ -52,13 +61,13 @@ func (m *Manager) confirmedToExecFn(command string, prompt string, edit bool) (b
 
 	promptStr := promptColor.Sprint(promptText)
 
-	confirmInput, cancelled, err := readConfirmationInput(promptStr)
+	confirmInput, cancelled, err := m.readConfirmation(promptStr)
 	if err != nil {
 		fmt.Printf("Error reading confirmation: %v\n", err)
 		return false, ""
 	}
 	if cancelled {
-		m.Status = ""
+		m.setStatus("")
 		return false, ""
 	}
 
@@ -68,6 +77,13 @@ func (m *Manager) confirmedToExecFn(command string, prompt string, edit bool) (b
 		confirmInput = "y"
 	}
 
+	if always && (confirmInput == "a" || confirmInput == "always") {
+		if !m.allowAlways(command) {
+			return false, ""
+		}
+		return true, command
+	}
+
 	switch confirmInput {
 	case "y", "yes", "ok", "sure":
 		return true, command
@@ -145,7 +161,107 @@ func (m *Manager) confirmedToExecFn(command string, prompt string, edit bool) (b
 	}
 }
 
+// riskStyle returns the color and icon a risk level is shown with
+func riskStyle(level RiskLevel) (*color.Color, string) {
+	theme := system.CurrentTheme()
+	switch level {
+	case RiskDanger:
+		return theme.RiskDanger, "!"
+	case RiskUnknown:
+		return theme.RiskUnknown, "?"
+	default: // RiskSafe
+		return theme.RiskSafe, "✓"
+	}
+}
+
+// offersAlwaysAllow reports whether a confirmation offers [a]: commands can
+// be allowed for good, keys, MCP calls and dangerous commands can't
+func offersAlwaysAllow(prompt string, edit bool, level RiskLevel) bool {
+	return edit && prompt == execConfirmPrompt && level != RiskDanger
+}
+
+// allowAlways asks which commands to stop confirming, suggesting a prefix of
+// command, and adds a safety.always_allow_patterns rule for them to the
+// config file. It returns false when cancelled with Ctrl+C or the answer
+// can't be read.
+func (m *Manager) allowAlways(command string) bool {
+	suggested := allowPrefix(command)
+	promptStr := system.CurrentTheme().Confirm.Sprintf("Always allow commands starting with [%s]: ", suggested)
+	input, cancelled, err := m.readConfirmation(promptStr)
+	if err != nil {
+		fmt.Printf("Error reading confirmation: %v\n", err)
+		return false
+	}
+	if cancelled {
+		m.setStatus("")
+		return false
+	}
+	prefix := strings.TrimSpace(input)
+	if prefix == "" {
+		prefix = suggested
+	}
+	m.addSafetyPattern("safety.always_allow_patterns", prefixPattern(prefix))
+	return true
+}
+
+// allowPrefix suggests the prefix of command an always-allow rule covers: the
+// program and what follows it up to the first flag, path or argument, at most
+// allowPrefixWords words, e.g. "go test" for "go test ./..."
+func allowPrefix(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	prefix := fields[:1]
+	for _, field := range fields[1:min(len(fields), allowPrefixWords)] {
+		if strings.ContainsAny(field, "-./~=$'\"*:") {
+			break
+		}
+		prefix = append(prefix, field)
+	}
+	return strings.Join(prefix, " ")
+}
+
+// allowRuleSuffix ends the patterns always-allow adds: arguments without
+// chaining, pipes, redirects or substitutions
+const allowRuleSuffix = `(\s[^;&|<>$` + "`" + `()\n]*)?$`
+
+// prefixPattern returns an always-allow regex for commands starting with prefix
+// as a whole word. Chaining, pipes, redirects and substitutions after it
+// don't match, so the rule can't let other commands through.
+func prefixPattern(prefix string) string {
+	return "^" + regexp.QuoteMeta(prefix) + allowRuleSuffix
+}
+
+// assessCommand scores a command with the user's safety patterns applied
+func (m *Manager) assessCommand(command string) RiskAssessment {
+	return ScoreCommandWithPatterns(command, m.Config.Safety.AllowPatterns, m.Config.Safety.DenyPatterns)
+}
+
+// needsExecConfirm decides whether a command must be confirmed before it
+// runs, applying the safety rules on top of exec_confirm and yolo.
+func (m *Manager) needsExecConfirm(command string) bool {
+	level := m.assessCommand(command).Level
+	if level == RiskDanger && m.Config.Safety.AlwaysConfirmDanger {
+		return true
+	}
+	if !m.GetExecConfirm() {
+		return false
+	}
+	if level == RiskSafe && m.Config.Safety.AutoApproveSafe {
+		return false
+	}
+	return true
+}
+
+// whitelistCheck reports whether a command runs without confirmation by
+// whitelist_patterns or safety.always_allow_patterns. Dangerous commands
+// never do with safety.always_confirm_danger, whatever the whitelist says.
 func (m *Manager) whitelistCheck(command string) (bool, error) {
+	danger := m.assessCommand(command).Level == RiskDanger
+	if m.Config.Safety.AlwaysConfirmDanger && danger {
+		return false, nil
+	}
 	isWhitelisted := false
 	for _, pattern := range m.Config.WhitelistPatterns {
 		if pattern == "" {
@@ -160,6 +276,23 @@ func (m *Manager) whitelistCheck(command string) (bool, error) {
 			break
 		}
 	}
+	// an always-allow rule like ^rm for "rm -rf build" mustn't let
+	// "rm -rf /" through
+	if !isWhitelisted && !danger {
+		for _, pattern := range m.Config.Safety.AlwaysAllowPatterns {
+			if pattern == "" {
+				continue
+			}
+			match, err := regexp.MatchString(pattern, command)
+			if err != nil {
+				return false, fmt.Errorf("invalid always-allow regex pattern '%s': %w", pattern, err)
+			}
+			if match {
+				isWhitelisted = true
+				break
+			}
+		}
+	}
 
 	if !isWhitelisted {
 		return false, nil
@@ -181,6 +314,43 @@ func (m *Manager) whitelistCheck(command string) (bool, error) {
 	return true, nil
 }
 
+// confirmedYesNoFn asks a yes or no question, yes by default. Ctrl+C stops
+// the task.
+func (m *Manager) confirmedYesNoFn(prompt string) bool {
+	promptStr := system.CurrentTheme().Confirm.Sprint(prompt + " [Y/n]: ")
+	input, cancelled, err := m.readConfirmation(promptStr)
+	if err != nil {
+		fmt.Printf("Error reading confirmation: %v\n", err)
+		return false
+	}
+	if cancelled {
+		m.setStatus("")
+		return false
+	}
+
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "", "y", "yes", "ok", "sure":
+		return true
+	case "n", "no", "cancel":
+		return false
+	default:
+		return m.confirmedYesNoFn(prompt)
+	}
+}
+
+// errAPIConfirmation refuses confirmations for requests sent to the HTTP
+// API, which has nobody to answer them while the chat reads the terminal
+var errAPIConfirmation = errors.New("requests from the HTTP API can't be confirmed, allow the command with whitelist_patterns or use --yolo")
+
+// readConfirmation reads the answer to a confirmation prompt from the
+// terminal, failing for requests from the HTTP API
+func (m *Manager) readConfirmation(prompt string) (string, bool, error) {
+	if m.apiRequest {
+		return "", false, errAPIConfirmation
+	}
+	return readConfirmationInput(prompt)
+}
+
 func readConfirmationInput(prompt string) (string, bool, error) {
 	fd := int(os.Stdin.Fd())
 
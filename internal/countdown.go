@@ -9,6 +9,9 @@ import (
 	"github.com/fatih/color"
 )
 
+// maxCountdownDots is the longest countdown drawn as one dot per second
+const maxCountdownDots = 30
+
 func (m *Manager) Countdown(seconds int) {
 	highlightColor := color.New(color.FgYellow, color.Bold).SprintFunc()
 	dimColor := color.New(color.FgBlue).SprintFunc()
@@ -53,8 +56,8 @@ func (m *Manager) Countdown(seconds int) {
 				remaining = 0 // Set remaining to 0 to end the countdown loop
 				renderCountdown(remaining, seconds, paused, highlightColor, dimColor, pauseColor)
 			case keyboard.KeyCtrlC: // Ctrl+C
-				m.Status = ""
-				m.WatchMode = false
+				m.setStatus("")
+				m.setWatchMode(false)
 				return
 			}
 		case <-ticker.C:
@@ -73,14 +76,19 @@ func renderCountdown(remaining, total int, paused bool, highlightColor, dimColor
 	// \033[K clears from cursor to end of line
 	fmt.Print("\033[0G\033[K")
 
-	// Build the dot display with consistent spacing
-	dots := make([]string, total)
-	for j := 0; j < total; j++ {
-		if j >= total-remaining {
-			dots[j] = dimColor("○")
-		} else {
-			dots[j] = highlightColor("●")
+	// Build the dot display with consistent spacing, or a plain seconds
+	// counter for long watch intervals that wouldn't fit on a line
+	progress := fmt.Sprintf("%s %s", highlightColor(remaining), dimColor("s"))
+	if total <= maxCountdownDots {
+		dots := make([]string, total)
+		for j := 0; j < total; j++ {
+			if j >= total-remaining {
+				dots[j] = dimColor("○")
+			} else {
+				dots[j] = highlightColor("●")
+			}
 		}
+		progress = strings.Join(dots, " ")
 	}
 
 	// Use simple fixed-width characters for status indicators
@@ -94,5 +102,5 @@ func renderCountdown(remaining, total int, paused bool, highlightColor, dimColor
 	// Ensure exact character count and consistent spacing with printf
 	// %2s gives a fixed width for the status indicator
 	fmt.Printf("%s %s [Space: Pause/Resume | Enter: To continue]",
-		statusIndicator, strings.Join(dots, " "))
+		statusIndicator, progress)
 }
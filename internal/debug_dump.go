@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// maxDebugExchanges bounds the AI exchanges kept for /debug dump
+const maxDebugExchanges = 20
+
+// debugExchange is one request sent to the AI and what came back
+type debugExchange struct {
+	Time     time.Time     `json:"time"`
+	Model    string        `json:"model"`
+	Request  []debugMsg    `json:"request"`
+	Response string        `json:"response,omitempty"`
+	Parsed   *AIResponse   `json:"parsed,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// debugMsg is a chat message as it appears in a debug bundle
+type debugMsg struct {
+	Role      string      `json:"role"`
+	Content   string      `json:"content"`
+	Typed     bool        `json:"typed,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Parsed    *AIResponse `json:"parsed,omitempty"`
+}
+
+// recordExchange keeps a request and its outcome for /debug dump
+func (m *Manager) recordExchange(sending []ChatMessage, model, response string, parsed *AIResponse, err error, started time.Time) {
+	ex := debugExchange{
+		Time:     started,
+		Model:    model,
+		Request:  debugMessages(sending, false, nil),
+		Response: response,
+		Parsed:   parsed,
+		Duration: time.Since(started),
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	m.debugExchanges = append(m.debugExchanges, ex)
+	if len(m.debugExchanges) > maxDebugExchanges {
+		m.debugExchanges = m.debugExchanges[len(m.debugExchanges)-maxDebugExchanges:]
+	}
+}
+
+// debugMessages converts chat messages for a debug bundle, parsing AI
+// replies when parse is set. The first message of a request is the system
+// prompt.
+func debugMessages(msgs []ChatMessage, parse bool, m *Manager) []debugMsg {
+	out := make([]debugMsg, 0, len(msgs))
+	for _, msg := range msgs {
+		d := debugMsg{Role: "assistant", Content: msg.Content, Typed: msg.Typed, Timestamp: msg.Timestamp}
+		if msg.FromUser {
+			d.Role = "user"
+		} else if parse && m != nil {
+			if r, err := m.parseAIResponse(msg.Content); err == nil {
+				d.Parsed = &r
+			}
+		}
+		out = append(out, d)
+	}
+	if !parse && len(out) > 0 {
+		out[0].Role = "system"
+	}
+	return out
+}
+
+// configSecrets returns the API keys and header values set in cfg and the
+// keys read with api_key_cmd or the keychain, longest first
+func (m *Manager) configSecrets() []string {
+	seen := map[string]bool{}
+	collectSecrets(reflect.ValueOf(m.Config), "", seen)
+	if m.AiClient != nil {
+		m.AiClient.secretsMu.Lock()
+		for _, key := range m.AiClient.secrets {
+			seen[key] = true
+		}
+		m.AiClient.secretsMu.Unlock()
+	}
+	secrets := make([]string, 0, len(seen))
+	for s := range seen {
+		// too short to replace without mangling unrelated text
+		if len(s) >= 6 {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// collectSecrets walks a config value, adding string fields whose
+// mapstructure tag names a key or token and every value of header maps
+func collectSecrets(v reflect.Value, tag string, seen map[string]bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSecrets(v.Elem(), tag, seen)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("mapstructure"), ",")
+			collectSecrets(v.Field(i), name, seen)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if strings.HasSuffix(tag, "headers") && iter.Value().Kind() == reflect.String {
+				seen[iter.Value().String()] = true
+				continue
+			}
+			collectSecrets(iter.Value(), tag, seen)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			collectSecrets(v.Index(i), tag, seen)
+		}
+	case reflect.String:
+		if v.String() != "" && (tag == "api_key" || strings.HasSuffix(tag, "_token") || strings.HasSuffix(tag, "_secret") || tag == "password") {
+			seen[v.String()] = true
+		}
+	}
+}
+
+// writeDebugBundle writes a zip with everything needed to reproduce the
+// session's AI exchanges, secrets redacted, to path, defaulting to a file
+// named after the session in the current directory
+func (m *Manager) writeDebugBundle(path string) (string, error) {
+	if path == "" {
+		id := m.SessionID
+		if id == "" {
+			id = time.Now().Format("20060102-150405")
+		}
+		path = "tmuxai-debug-" + id + ".zip"
+	}
+
+	prepared := m.ExecPane != nil && m.ExecPane.IsPrepared
+	info := map[string]any{
+		"version":    Version,
+		"commit":     Commit,
+		"os":         runtime.GOOS + "/" + runtime.GOARCH,
+		"session_id": m.SessionID,
+		"model":      m.GetModel(),
+		"created":    time.Now().Format(time.RFC3339),
+		"status":     m.Status,
+		"overrides":  m.SessionOverrides,
+		"loaded_kbs": sortedKeys(m.LoadedKBs),
+		"skills":     sortedKeys(m.LoadedSkills),
+	}
+	if m.ExecPane != nil {
+		info["exec_pane"] = map[string]any{
+			"id": m.ExecPane.Id, "shell": m.ExecPane.Shell, "os": m.ExecPane.OS, "prepared": prepared,
+		}
+	}
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"info.json", info},
+		{"config.json", m.Config},
+		{"system_prompt.txt", m.chatAssistantPrompt(prepared).Content},
+		{"panes.xml", m.getTmuxPanesInXml(m.Config)},
+		{"messages.json", debugMessages(m.Messages, true, m)},
+		{"exchanges.json", m.debugExchanges},
+		{"exec_history.json", m.ExecHistory},
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	secrets := m.configSecrets()
+	for _, file := range files {
+		var data []byte
+		if s, ok := file.content.(string); ok {
+			data = []byte(s)
+		} else if data, err = json.MarshalIndent(file.content, "", "  "); err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if _, err := w.Write([]byte(redactSecrets(string(data), secrets))); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	logger.Info("Wrote debug bundle to %s", path)
+	return path, nil
+}
+
+// sortedKeys returns the keys of a name -> content map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
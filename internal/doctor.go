@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// doctorTimeout bounds the provider connectivity check
+const doctorTimeout = 30 * time.Second
+
+// minTmuxVersion is the oldest tmux with display-popup and the control mode
+// flags TmuxAI uses
+const minTmuxVersion = 3.2
+
+// doctorReport prints check results and counts the failures
+type doctorReport struct {
+	out      io.Writer
+	failures int
+}
+
+func (r *doctorReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.out, "✓ %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) warn(msg, fix string) {
+	fmt.Fprintf(r.out, "! %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(r.out, "  → %s\n", fix)
+	}
+}
+
+func (r *doctorReport) fail(msg, fix string) {
+	r.failures++
+	fmt.Fprintf(r.out, "✗ %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(r.out, "  → %s\n", fix)
+	}
+}
+
+// ValidateConfig prints the problems of the config file at path and of the
+// loaded cfg, for `tmuxai config validate`. It reports whether there were none.
+func ValidateConfig(cfg *config.Config, path string, out io.Writer) bool {
+	r := &doctorReport{out: out}
+	r.validateConfig(cfg, path)
+	return r.failures == 0
+}
+
+// RunDoctor checks the config, the tmux installation and the connection to
+// the configured provider, printing a fix for each problem, for
+// `tmuxai doctor`. It reports whether everything passed.
+func RunDoctor(cfg *config.Config, path string, out io.Writer) bool {
+	r := &doctorReport{out: out}
+	r.validateConfig(cfg, path)
+
+	if cfg.Tmux.SSHHost != "" {
+		system.SetTmuxRemote(cfg.Tmux.SSHHost, cfg.Tmux.SSHArgs, cfg.Tmux.RemoteTarget)
+	}
+	r.checkTmux()
+
+	m := newStandaloneManager(cfg)
+	r.checkProvider(m)
+	return r.failures == 0
+}
+
+// validateConfig reports unknown keys in the file and invalid settings
+func (r *doctorReport) validateConfig(cfg *config.Config, path string) {
+	if _, err := os.Stat(path); err != nil {
+		r.warn(fmt.Sprintf("No config file at %s, using the defaults", path), "create it with a models entry, see config.example.yaml")
+	} else {
+		unknown, err := config.UnknownKeys(path)
+		if err != nil {
+			r.fail(err.Error(), "fix the YAML syntax of "+path)
+			return
+		}
+		for _, key := range unknown {
+			r.fail(fmt.Sprintf("Unknown config key %s", key), "check it for typos, or remove it")
+		}
+	}
+
+	issues := config.Validate(cfg)
+	for _, issue := range issues {
+		r.fail(fmt.Sprintf("%s: %s", issue.Key, issue.Problem), issue.Fix)
+	}
+	if r.failures == 0 {
+		r.ok("Config %s is valid", path)
+	}
+}
+
+// checkTmux reports whether tmux runs and is recent enough
+func (r *doctorReport) checkTmux() {
+	version, err := system.TmuxVersion()
+	if err != nil {
+		fix := "install tmux 3.2 or newer"
+		if system.TmuxIsRemote() {
+			fix = "check that `ssh " + system.TmuxRemoteHost() + " tmux -V` works without a password prompt"
+		}
+		r.fail(fmt.Sprintf("tmux is not available: %v", err), fix)
+		return
+	}
+	if parsed, ok := parseTmuxVersion(version); ok && parsed < minTmuxVersion {
+		r.warn(fmt.Sprintf("tmux %s is older than %.1f", version, minTmuxVersion), "upgrade tmux for `tmuxai popup` and tmux.control_mode")
+	} else {
+		r.ok("tmux %s", version)
+	}
+	if !system.TmuxIsRemote() && os.Getenv("TMUX") == "" {
+		r.warn("Not running inside tmux", "start tmux first, TmuxAI works with the panes of its own tmux window")
+	}
+}
+
+// parseTmuxVersion turns "3.3a" or "next-3.4" into 3.3 or 3.4
+func parseTmuxVersion(version string) (float64, bool) {
+	version = strings.TrimPrefix(version, "next-")
+	end := 0
+	for end < len(version) && (version[end] == '.' || (version[end] >= '0' && version[end] <= '9')) {
+		end++
+	}
+	parsed, err := strconv.ParseFloat(version[:end], 64)
+	return parsed, err == nil
+}
+
+// checkProvider sends a tiny request to the current model
+func (r *doctorReport) checkProvider(m *Manager) {
+	if !m.hasValidAIConfiguration() {
+		r.fail("No model with credentials to test", "add an api_key to a models entry, or use a keyless provider like ollama")
+		return
+	}
+	model := m.GetModel()
+	provider := "openrouter"
+	if mc, ok := m.GetCurrentModelConfig(); ok {
+		provider = mc.Provider
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	start := time.Now()
+	messages := []ChatMessage{{Content: "Reply with OK.", FromUser: true, Timestamp: start}}
+	if _, err := m.AiClient.GetResponseFromChatMessages(ctx, messages, model); err != nil {
+		r.fail(fmt.Sprintf("%s (%s) did not answer: %v", model, provider, err), "check the api_key, base_url and model name of the model, and your network")
+		return
+	}
+	r.ok("%s (%s) answered in %s", model, provider, time.Since(start).Round(time.Millisecond))
+}
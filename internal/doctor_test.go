@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTmuxVersion(t *testing.T) {
+	for version, want := range map[string]float64{"3.3a": 3.3, "3.2": 3.2, "next-3.5": 3.5, "2.9a": 2.9} {
+		parsed, ok := parseTmuxVersion(version)
+		assert.True(t, ok, version)
+		assert.Equal(t, want, parsed, version)
+	}
+	_, ok := parseTmuxVersion("master")
+	assert.False(t, ok)
+}
+
+func TestCheckTmux(t *testing.T) {
+	original := system.TmuxVersion
+	t.Cleanup(func() { system.TmuxVersion = original })
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+
+	var out bytes.Buffer
+	r := &doctorReport{out: &out}
+	system.TmuxVersion = func() (string, error) { return "3.1c", nil }
+	r.checkTmux()
+	assert.Contains(t, out.String(), "! tmux 3.1c is older than 3.2")
+	assert.Zero(t, r.failures, "an old tmux only warns")
+
+	out.Reset()
+	system.TmuxVersion = func() (string, error) { return "", errors.New("executable file not found in $PATH") }
+	r.checkTmux()
+	assert.Contains(t, out.String(), "✗ tmux is not available")
+	assert.Equal(t, 1, r.failures)
+}
+
+func TestRunDoctorChecksProvider(t *testing.T) {
+	original := system.TmuxVersion
+	t.Cleanup(func() { system.TmuxVersion = original })
+	system.TmuxVersion = func() (string, error) { return "3.4", nil }
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Retry.MaxAttempts = 1
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "some/model", APIKey: "k", BaseURL: server.URL}}
+
+	var out bytes.Buffer
+	ok := RunDoctor(cfg, filepath.Join(t.TempDir(), "config.yaml"), &out)
+	assert.True(t, ok, out.String())
+	assert.Contains(t, out.String(), "! No config file at")
+	assert.Contains(t, out.String(), "✓ tmux 3.4")
+	assert.Contains(t, out.String(), "✓ some/model (openrouter) answered in")
+
+	out.Reset()
+	server.Close()
+	assert.False(t, RunDoctor(cfg, filepath.Join(t.TempDir(), "config.yaml"), &out))
+	assert.Contains(t, out.String(), "✗ some/model (openrouter) did not answer")
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// embeddingBatchSize is how many texts are sent per embeddings request
+const embeddingBatchSize = 64
+
+// EmbeddingRequest is an OpenAI-compatible embeddings request
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse is an OpenAI-compatible embeddings response
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embeddingsURL returns the embeddings endpoint for a model configuration.
+// OpenAI, OpenRouter, Requesty and Ollama all serve the OpenAI format.
+func (c *AiClient) embeddingsURL(mc config.ModelConfig) (string, error) {
+	base := mc.BaseURL
+	switch mc.Provider {
+	case "openai":
+		if base == "" {
+			base = c.config.OpenAI.BaseURL
+		}
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+	case "openrouter":
+		if base == "" {
+			base = c.config.OpenRouter.BaseURL
+		}
+	case "requesty":
+		if base == "" {
+			base = c.config.Requesty.BaseURL
+		}
+	case "ollama":
+		base = strings.TrimSuffix(strings.TrimSuffix(base, "/"), "/v1")
+		if base == "" {
+			base = defaultOllamaBaseURL
+		}
+		base += "/v1"
+	default:
+		if base == "" {
+			return "", fmt.Errorf("provider %s has no OpenAI-compatible embeddings endpoint, set base_url", mc.Provider)
+		}
+	}
+	return strings.TrimSuffix(base, "/") + "/embeddings", nil
+}
+
+// Embeddings returns a vector for each text from the provider of mc
+func (c *AiClient) Embeddings(ctx context.Context, texts []string, model string, mc config.ModelConfig) ([][]float32, error) {
+	mc, err := c.resolveAPIKey(ctx, mc)
+	if err != nil {
+		return nil, err
+	}
+	url, err := c.embeddingsURL(mc)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(texts))
+		batch, err := c.embeddingsBatch(ctx, url, texts[start:end], model, mc.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (c *AiClient) embeddingsBatch(ctx context.Context, url string, texts []string, model, apiKey string) ([][]float32, error) {
+	reqJSON, err := json.Marshal(EmbeddingRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	req.Header.Set("HTTP-Referer", "https://github.com/alvinunreal/tmuxai")
+	req.Header.Set("X-Title", "TmuxAI")
+
+	logger.Debug("Sending embeddings request to: %s with model: %s (%d inputs)", url, model, len(texts))
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned error: %s", body)
+	}
+
+	var embResp EmbeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embResp.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
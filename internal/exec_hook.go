@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+const (
+	promptDetectionHook    = "hook"
+	execStatusOption       = "@tmuxai_status"
+	execHookPrepareTimeout = 3 * time.Second
+)
+
+// execHookCommand installs a hook that runs before each prompt and stores a
+// counter and the last exit code in the pane's execStatusOption. The hook
+// goes first so it sees the command's exit code.
+func execHookCommand(shell string) (string, error) {
+	set := `tmux set-option -p -t "$TMUX_PANE" ` + execStatusOption + ` "$__tmuxai_n:$s" 2>/dev/null`
+	switch shell {
+	case "bash":
+		return `__tmuxai_status() { local s=$?; __tmuxai_n=$((__tmuxai_n+1)); ` + set + `; return $s; }; PROMPT_COMMAND="__tmuxai_status${PROMPT_COMMAND:+;$PROMPT_COMMAND}"`, nil
+	case "zsh":
+		return `__tmuxai_status() { local s=$?; (( __tmuxai_n++ )); ` + set + `; return $s; }; precmd_functions=(__tmuxai_status ${precmd_functions:#__tmuxai_status})`, nil
+	case "fish":
+		return `function __tmuxai_status --on-event fish_postexec; set -l s $status; set -g __tmuxai_n (math 0$__tmuxai_n + 1); ` + set + `; end`, nil
+	}
+	return "", fmt.Errorf("shell '%s' is not supported, use bash, zsh or fish", shell)
+}
+
+// execHookPrepared reports whether a pane's shell reports exit codes through
+// the execStatusOption hook
+func (m *Manager) execHookPrepared(paneID string) bool {
+	return paneID != "" && m.execHookPane == paneID
+}
+
+// execPaneTracked reports whether a pane's commands are tracked without the
+// rewritten prompt, which capture-pane refreshes can't tell
+func (m *Manager) execPaneTracked(paneID string) bool {
+	return m.osc133Prepared(paneID) || m.execHookPrepared(paneID)
+}
+
+// prepareExecHook installs the exit code hook in the exec pane's shell and
+// waits for its first report
+func (m *Manager) prepareExecHook(shell string) error {
+	paneID := m.ExecPane.Id
+	hook, err := execHookCommand(shell)
+	if err != nil {
+		return err
+	}
+	m.stopOSC133()
+
+	before, err := system.TmuxPaneOption(paneID, execStatusOption)
+	if err != nil {
+		return err
+	}
+	_ = system.TmuxSendCommandToPane(paneID, hook, true)
+	_ = system.TmuxSendCommandToPane(paneID, "C-l", false)
+
+	deadline := time.Now().Add(execHookPrepareTimeout)
+	for {
+		if status, _ := system.TmuxPaneOption(paneID, execStatusOption); status != "" && status != before {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pane %s did not report an exit code; its shell may not be able to run tmux", paneID)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	m.execHookPane = paneID
+	m.rememberPreparedShell(shell)
+	m.ExecPane.IsPrepared = true
+	logger.Info("Exec pane %s prepared with an exit code hook", paneID)
+	return nil
+}
+
+// commandEchoLines is how many pane lines a command takes when typed at
+// column x, wrapping at width
+func commandEchoLines(command string, x, width int) int {
+	if width <= 0 {
+		return 1
+	}
+	lines := 0
+	for i, line := range strings.Split(command, "\n") {
+		n := utf8.RuneCountInString(line)
+		if i == 0 {
+			n += x
+		}
+		lines += n/width + 1
+	}
+	return lines
+}
+
+// execWaitCaptureHook runs a command in a hooked exec pane. Completion and
+// the exit code come from the pane option; the output is the pane lines
+// between the typed command and the next prompt.
+func (m *Manager) execWaitCaptureHook(command string, timeout time.Duration) (CommandExecHistory, error) {
+	paneID := m.ExecPane.Id
+	before, err := system.TmuxPaneOption(paneID, execStatusOption)
+	if err != nil {
+		return CommandExecHistory{}, err
+	}
+	start, err := system.TmuxCursorPosition(paneID)
+	if err != nil {
+		return CommandExecHistory{}, err
+	}
+
+	_ = system.TmuxSendCommandToPane(paneID, command, true)
+	// wait for keys to be sent, duo to sometimes ssh latency
+	time.Sleep(500 * time.Millisecond)
+
+	status := before
+	finished := func() bool {
+		if current, err := system.TmuxPaneOption(paneID, execStatusOption); err == nil {
+			status = current
+		}
+		return status != before
+	}
+	review := m.newExecReview(command)
+	timedOut := m.waitForExec(review.until(finished), timeout)
+	if timedOut {
+		m.interruptTimedOut(command, timeout, finished)
+	} else if reason := review.aborted(); reason != "" {
+		m.abortExec(command, reason, finished)
+	}
+	m.ExecPane.IsPrepared = true
+
+	history := CommandExecHistory{Command: command, Code: -1, TimedOut: timedOut, AbortReason: review.aborted()}
+	if status != before {
+		_, codeText, _ := strings.Cut(status, ":")
+		if history.Code, err = strconv.Atoi(codeText); err != nil {
+			return CommandExecHistory{}, fmt.Errorf("invalid exit code %q reported by exec pane %s", status, paneID)
+		}
+	} else if !timedOut && history.AbortReason == "" {
+		return CommandExecHistory{}, fmt.Errorf("no exit code reported by exec pane %s", paneID)
+	}
+
+	end, err := system.TmuxCursorPosition(paneID)
+	if err == nil {
+		// a running command's output goes up to the cursor, a finished one's up to the prompt
+		last := end.Line
+		if status != before {
+			last--
+		}
+		from := start.Line + commandEchoLines(command, start.X, start.Width)
+		history.Output, err = system.TmuxCaptureLines(paneID, from, last)
+	}
+	if err != nil {
+		logger.Error("Failed to capture output of '%s': %v", command, err)
+	}
+	history.Output = strings.TrimSpace(history.Output)
+
+	m.stateMu.Lock()
+	m.ExecHistory = append(m.ExecHistory, history)
+	m.stateMu.Unlock()
+	logger.Debug("Command: %s\nOutput: %s\nCode: %d\n", history.Command, history.Output, history.Code)
+	return history, nil
+}
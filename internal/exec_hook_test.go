@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecHookCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		hook, err := execHookCommand(shell)
+		require.NoError(t, err)
+		assert.Contains(t, hook, `tmux set-option -p -t "$TMUX_PANE" @tmuxai_status "$__tmuxai_n:$s"`, shell)
+		assert.False(t, strings.HasSuffix(hook, ";"), "trailing semicolons are escaped by send-keys")
+	}
+	_, err := execHookCommand("tcsh")
+	assert.Error(t, err)
+}
+
+func TestCommandEchoLines(t *testing.T) {
+	assert.Equal(t, 1, commandEchoLines("ls", 10, 80))
+	assert.Equal(t, 2, commandEchoLines(strings.Repeat("x", 75), 10, 80), "long commands wrap")
+	assert.Equal(t, 3, commandEchoLines("cat <<EOF\nhi\nEOF", 10, 80))
+}
+
+// mockHookedPane fakes a zsh whose hook bumps @tmuxai_status after every
+// command; the pane holds lines counted from the top of its history
+func mockHookedPane(t *testing.T) (*Manager, *[]string) {
+	t.Helper()
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: map[string]interface{}{"tmux.prompt_detection": promptDetectionHook},
+		Status:           "running",
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentCommand: "zsh"},
+	}
+
+	originalSend := system.TmuxSendCommandToPane
+	originalCapture := system.TmuxCapturePane
+	originalOption := system.TmuxPaneOption
+	originalCursor := system.TmuxCursorPosition
+	originalLines := system.TmuxCaptureLines
+	t.Cleanup(func() {
+		system.TmuxSendCommandToPane = originalSend
+		system.TmuxCapturePane = originalCapture
+		system.TmuxPaneOption = originalOption
+		system.TmuxCursorPosition = originalCursor
+		system.TmuxCaptureLines = originalLines
+	})
+
+	status := ""
+	runs := 0
+	lines := []string{"~/src ❯ "}
+	var sent []string
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		assert.Equal(t, "%2", paneId)
+		sent = append(sent, command)
+		if command == "C-l" {
+			return nil
+		}
+		last := len(lines) - 1
+		lines[last] += command
+		code := 0
+		if command == "make test" {
+			lines = append(lines, "ok  pkg/a", "FAIL pkg/b")
+			code = 2
+		}
+		lines = append(lines, "~/src ❯ ")
+		runs++
+		status = fmt.Sprintf("%d:%d", runs, code)
+		return nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return strings.Join(lines, "\n"), nil
+	}
+	system.TmuxPaneOption = func(paneId string, name string) (string, error) {
+		assert.Equal(t, execStatusOption, name)
+		return status, nil
+	}
+	system.TmuxCursorPosition = func(paneId string) (system.TmuxCursor, error) {
+		return system.TmuxCursor{Line: len(lines) - 1, X: len([]rune(lines[len(lines)-1])), Width: 80}, nil
+	}
+	system.TmuxCaptureLines = func(paneId string, from, to int) (string, error) {
+		if to < from {
+			return "", nil
+		}
+		return strings.Join(lines[from:to+1], "\n"), nil
+	}
+	return manager, &sent
+}
+
+func TestExecWaitCaptureHook(t *testing.T) {
+	manager, sent := mockHookedPane(t)
+
+	manager.PrepareExecPane()
+	require.True(t, manager.execHookPrepared("%2"))
+	assert.True(t, manager.ExecPane.IsPrepared)
+	assert.Contains(t, (*sent)[0], "precmd_functions=(__tmuxai_status", "zsh hook installed")
+	for _, command := range *sent {
+		assert.NotContains(t, command, "PROMPT=", "the prompt is never rewritten")
+	}
+
+	history, err := manager.ExecWaitCapture("make test")
+	require.NoError(t, err)
+	assert.Equal(t, CommandExecHistory{Command: "make test", Output: "ok  pkg/a\nFAIL pkg/b", Code: 2}, history)
+	assert.Equal(t, []CommandExecHistory{history}, manager.ExecHistory)
+
+	history, err = manager.ExecWaitCapture("true")
+	require.NoError(t, err)
+	assert.Equal(t, CommandExecHistory{Command: "true", Code: 0}, history)
+
+	// /prepare re-parses the history, which hooked panes keep
+	manager.parseExecPaneCommandHistory()
+	assert.Len(t, manager.ExecHistory, 2)
+	assert.True(t, manager.ExecPane.IsPrepared)
+}
+
+func TestPrepareExecHookWithoutReport(t *testing.T) {
+	manager, _ := mockHookedPane(t)
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		return nil
+	}
+
+	err := manager.prepareExecHook("zsh")
+	assert.ErrorContains(t, err, "did not report an exit code")
+	assert.False(t, manager.execHookPrepared("%2"))
+}
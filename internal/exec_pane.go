@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,7 +17,7 @@ import (
 func (m *Manager) GetAvailablePane() system.TmuxPaneDetails {
 	panes, _ := m.GetTmuxPanes()
 	for _, pane := range panes {
-		if !pane.IsTmuxAiPane && !m.ForcedReadPaneIDs[pane.Id] {
+		if !pane.IsTmuxAiPane && !m.ForcedReadPaneIDs[pane.Id] && !m.paneReadOnly(pane) {
 			logger.Info("Found available pane: %s", pane.Id)
 			return pane
 		}
@@ -42,16 +43,18 @@ func (m *Manager) resolvePaneSelection() ([]system.TmuxPaneDetails, error) {
 	}
 
 	if m.ForcedExecPaneID != "" {
-		if m.ForcedExecPaneID == m.PaneId {
+		if m.ForcedExecPaneID == m.PaneId && !system.TmuxIsRemote() {
 			return nil, fmt.Errorf("exec pane cannot be the TmuxAI chat pane (%s)", m.PaneId)
 		}
 		if _, ok := available[m.ForcedExecPaneID]; !ok {
-			return nil, fmt.Errorf("exec pane %s was not found in the current tmux window", m.ForcedExecPaneID)
+			if _, found := m.findPane(m.ForcedExecPaneID); !found {
+				return nil, fmt.Errorf("exec pane %s was not found in any tmux session", m.ForcedExecPaneID)
+			}
 		}
 	}
 
 	for paneID := range m.ForcedReadPaneIDs {
-		if paneID == m.PaneId {
+		if paneID == m.PaneId && !system.TmuxIsRemote() {
 			return nil, fmt.Errorf("read pane %s cannot be the TmuxAI chat pane", paneID)
 		}
 		if _, ok := available[paneID]; !ok {
@@ -62,7 +65,28 @@ func (m *Manager) resolvePaneSelection() ([]system.TmuxPaneDetails, error) {
 	return panes, nil
 }
 
+// findPane looks up a pane by ID in any window or session
+func (m *Manager) findPane(paneID string) (system.TmuxPaneDetails, bool) {
+	panes, _ := system.TmuxPanesDetails(paneID)
+	for _, pane := range panes {
+		if pane.Id == paneID {
+			pane.OS = m.paneOS(pane)
+			return pane, true
+		}
+	}
+	return system.TmuxPaneDetails{}, false
+}
+
 func (m *Manager) InitExecPane() error {
+	// Session-qualified targets like "mysession:1.2" are resolved to a pane ID
+	if m.ForcedExecPaneID != "" && !strings.HasPrefix(m.ForcedExecPaneID, "%") {
+		paneID, err := system.TmuxResolvePaneId(m.ForcedExecPaneID)
+		if err != nil {
+			return err
+		}
+		m.ForcedExecPaneID = paneID
+	}
+
 	if _, err := m.resolvePaneSelection(); err != nil {
 		return err
 	}
@@ -74,16 +98,26 @@ func (m *Manager) InitExecPane() error {
 		}
 		for i := range panes {
 			if panes[i].Id == m.ForcedExecPaneID {
-				m.ExecPane = &panes[i]
+				m.setExecPane(&panes[i])
 				return nil
 			}
 		}
+		if pane, found := m.findPane(m.ForcedExecPaneID); found {
+			pane.IsTmuxAiExecPane = true
+			pane.IsPrepared = true
+			m.setExecPane(&pane)
+			return nil
+		}
 		return fmt.Errorf("exec pane %s could not be initialized", m.ForcedExecPaneID)
 	}
 
 	availablePane := m.GetAvailablePane()
 	if availablePane.Id == "" {
-		paneID, err := system.TmuxCreateNewPane(m.PaneId, m.Config.Tmux.ExecSplitArgs)
+		splitTarget := m.PaneId
+		if system.TmuxIsRemote() {
+			splitTarget, _ = system.TmuxCurrentWindowTarget()
+		}
+		paneID, err := system.TmuxCreateNewPane(splitTarget, m.Config.Tmux.ExecSplitArgs)
 		if err != nil {
 			return fmt.Errorf("failed to create exec pane: %w", err)
 		}
@@ -92,35 +126,94 @@ func (m *Manager) InitExecPane() error {
 			availablePane = system.TmuxPaneDetails{Id: paneID}
 		}
 	}
-	m.ExecPane = &availablePane
+	m.setExecPane(&availablePane)
+	return nil
+}
+
+// SetExecPaneTarget switches the exec pane to a tmux target, which may be a
+// pane ID or a session-qualified target in another session.
+func (m *Manager) SetExecPaneTarget(target string) error {
+	previous := m.ForcedExecPaneID
+	m.ForcedExecPaneID = target
+	if err := m.InitExecPane(); err != nil {
+		m.ForcedExecPaneID = previous
+		return err
+	}
+	m.ExecPane.Refresh(m.GetMaxCaptureLines())
 	return nil
 }
 
 func (m *Manager) PrepareExecPaneWithShell(shell string) {
 	m.ExecPane.Refresh(m.GetMaxCaptureLines())
+	// exit code hooks and shell integration markers leave the user's prompt alone
+	if m.GetPromptDetection() == promptDetectionHook {
+		if err := m.prepareExecHook(shell); err != nil {
+			m.Println(fmt.Sprintf("Exit code hook unavailable: %v", err))
+		}
+		return
+	}
+	if m.GetPromptDetection() == promptDetectionOSC133 {
+		if err := m.prepareOSC133(); err != nil {
+			m.Println(fmt.Sprintf("OSC 133 prompt detection unavailable: %v", err))
+		}
+		return
+	}
+	m.stopOSC133()
+	m.execHookPane = ""
 	if m.ExecPane.IsPrepared && m.ExecPane.Shell != "" {
 		return
 	}
 
+	// each prompt command first saves the user's prompt, unless an earlier
+	// /prepare already did, so releasing the pane can restore it
 	var ps1Command string
-	switch shell {
+	clear := true
+	kind := shellKind(shell)
+	switch kind {
 	case "zsh":
-		// Only set PROMPT for zsh; avoid unsetting precmd hooks to respect user's zsh configuration
-		ps1Command = `export PROMPT='%n@%m:%~[%T][%?]» '`
+		// Set PROMPT from a precmd hook that runs last, so themes redrawing
+		// their prompt in precmd don't replace it; other hooks are kept
+		ps1Command = `(( ${+__tmuxai_prompt} )) || __tmuxai_prompt=$PROMPT; __tmuxai_set_prompt() { PROMPT='%n@%m:%~[%T][%?]» ' }; precmd_functions=(${precmd_functions:#__tmuxai_set_prompt} __tmuxai_set_prompt); export PROMPT='%n@%m:%~[%T][%?]» '`
 	case "bash":
 		// Unset PROMPT_COMMAND for bash (can interfere with prompts), then set PS1
-		ps1Command = `unset PROMPT_COMMAND; export PS1='\u@\h:\w[\A][$?]» '`
+		ps1Command = `[ -n "${__tmuxai_ps1+x}" ] || { __tmuxai_ps1=$PS1; __tmuxai_pc=$PROMPT_COMMAND; }; unset PROMPT_COMMAND; export PS1='\u@\h:\w[\A][$?]» '`
 	case "fish":
 		// Redefine fish_prompt only (do not remove other functions)
-		ps1Command = `function fish_prompt; set -l s $status; printf '%s@%s:%s[%s][%d]» ' $USER (hostname -s) (prompt_pwd) (date +"%H:%M") $s; end`
+		ps1Command = `functions -q __tmuxai_fish_prompt; or functions -c fish_prompt __tmuxai_fish_prompt; function fish_prompt; set -l s $status; printf '%s@%s:%s[%s][%d]» ' $USER (hostname -s) (prompt_pwd) (date +"%H:%M") $s; end`
+	case "pwsh":
+		// $? is false for failed cmdlets too, which leave $LASTEXITCODE alone
+		ps1Command = `if (-not (Test-Path function:__tmuxai_prompt)) { Set-Item function:global:__tmuxai_prompt (Get-Item function:prompt).ScriptBlock }; function global:prompt { $s = if ($?) { 0 } elseif ($LASTEXITCODE) { $LASTEXITCODE } else { 1 }; "$([Environment]::UserName)@$([Environment]::MachineName):$($PWD.Path)[$(Get-Date -Format HH:mm)][$s]» " }`
+	case "nu":
+		ps1Command = `$env.__TMUXAI_PROMPT = ($env.__TMUXAI_PROMPT? | default ($env | select -o PROMPT_COMMAND PROMPT_COMMAND_RIGHT PROMPT_INDICATOR PROMPT_INDICATOR_VI_INSERT PROMPT_INDICATOR_VI_NORMAL)); $env.PROMPT_COMMAND = {|| $"(whoami | str trim)@(hostname | str trim):($env.PWD)[(date now | format date '%H:%M')][($env.LAST_EXIT_CODE)]" }; $env.PROMPT_COMMAND_RIGHT = ""; $env.PROMPT_INDICATOR = "» "; $env.PROMPT_INDICATOR_VI_INSERT = "» "; $env.PROMPT_INDICATOR_VI_NORMAL = "» "`
+	case "cmd":
+		// cmd can't show the exit code in its prompt, so it is unknown (?); it
+		// ignores C-l, so cls clears instead
+		ps1Command = `(if not defined TMUXAI_PROMPT set "TMUXAI_PROMPT=%PROMPT%") & chcp 65001 >nul & prompt %USERNAME%@%COMPUTERNAME%:$P[$T][?]»$S & cls`
+		clear = false
 	default:
 		errMsg := fmt.Sprintf("Shell '%s' in pane %s is recognized but not yet supported for PS1 modification.", shell, m.ExecPane.Id)
-		logger.Info(errMsg)
+		logger.Info("%s", errMsg)
 		return
 	}
 
 	_ = system.TmuxSendCommandToPane(m.ExecPane.Id, ps1Command, true)
-	_ = system.TmuxSendCommandToPane(m.ExecPane.Id, "C-l", false)
+	if clear {
+		_ = system.TmuxSendCommandToPane(m.ExecPane.Id, "C-l", false)
+	}
+	m.rememberPreparedShell(kind)
+}
+
+// prepareShells are the shells whose prompt /prepare can rewrite
+var prepareShells = []string{"bash", "zsh", "fish", "pwsh", "nu", "cmd"}
+
+// shellKind maps a shell's process name to the shell /prepare knows it as,
+// e.g. powershell.exe to pwsh
+func shellKind(shell string) string {
+	kind := strings.TrimSuffix(strings.ToLower(shell), ".exe")
+	if kind == "powershell" {
+		return "pwsh"
+	}
+	return kind
 }
 
 func (m *Manager) PrepareExecPane() {
@@ -128,22 +221,29 @@ func (m *Manager) PrepareExecPane() {
 }
 
 func (m *Manager) ExecWaitCapture(command string) (CommandExecHistory, error) {
+	return m.ExecWaitCaptureTimeout(command, time.Duration(m.GetExecTimeout())*time.Second)
+}
+
+// ExecWaitCaptureTimeout is ExecWaitCapture for a command that may run at
+// most timeout, or without limit when it's 0
+func (m *Manager) ExecWaitCaptureTimeout(command string, timeout time.Duration) (CommandExecHistory, error) {
+	if m.execHookPrepared(m.ExecPane.Id) {
+		return m.execWaitCaptureHook(command, timeout)
+	}
+
+	finished := m.execFinished()
+	review := m.newExecReview(command)
 	_ = system.TmuxSendCommandToPane(m.ExecPane.Id, command, true)
 
 	// wait for keys to be sent, duo to sometimes ssh latency
 	time.Sleep(500 * time.Millisecond)
 
-	m.ExecPane.Refresh(m.GetMaxCaptureLines())
-
-	animChars := []string{"⋯", "⋱", "⋮", "⋰"}
-	animIndex := 0
-	for !strings.HasSuffix(m.ExecPane.LastLine, "]»") && m.Status != "" {
-		fmt.Printf("\r%s%s ", m.GetPrompt(), animChars[animIndex])
-		animIndex = (animIndex + 1) % len(animChars)
-		time.Sleep(500 * time.Millisecond)
-		m.ExecPane.Refresh(m.GetMaxCaptureLines())
+	if m.waitForExec(review.until(finished), timeout) {
+		return m.execTimedOut(command, timeout, finished), nil
+	}
+	if reason := review.aborted(); reason != "" {
+		return m.execAborted(command, reason, finished), nil
 	}
-	fmt.Print("\r\033[K")
 
 	m.parseExecPaneCommandHistory()
 	if len(m.ExecHistory) == 0 {
@@ -151,15 +251,64 @@ func (m *Manager) ExecWaitCapture(command string) (CommandExecHistory, error) {
 		return CommandExecHistory{}, fmt.Errorf("failed to parse command history from exec pane")
 	}
 	cmd := m.ExecHistory[len(m.ExecHistory)-1]
+	if m.osc133Prepared(m.ExecPane.Id) {
+		// the echoed command line carries the shell's redraws, the sent one doesn't
+		cmd.Command = command
+	}
 	logger.Debug("Command: %s\nOutput: %s\nCode: %d\n", cmd.Command, cmd.Output, cmd.Code)
 	return cmd, nil
 }
 
+// waitForExec animates the prompt until finished reports the command done
+// or the request is cancelled. It reports whether timeout, if set, ran out
+// first.
+func (m *Manager) waitForExec(finished func() bool, timeout time.Duration) bool {
+	changed := m.paneChanges(m.ExecPane.Id)
+	m.ExecPane.Refresh(m.GetMaxCaptureLines())
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	animChars := []string{"⋯", "⋱", "⋮", "⋰"}
+	animIndex := 0
+	timedOut := false
+	done := finished()
+	for !done && m.Status != "" {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			timedOut = true
+			break
+		}
+		fmt.Printf("\r%s%s ", m.GetPrompt(), animChars[animIndex])
+		animIndex = (animIndex + 1) % len(animChars)
+		time.Sleep(500 * time.Millisecond)
+		// in control mode, a pane that printed nothing hasn't finished either
+		if changed() {
+			m.ExecPane.Refresh(m.GetMaxCaptureLines())
+			done = finished()
+		}
+	}
+	fmt.Print("\r\033[K")
+	return timedOut
+}
+
 func (m *Manager) parseExecPaneCommandHistory() {
 	m.parseExecPaneCommandHistoryWithContent("")
 }
 
 func (m *Manager) parseExecPaneCommandHistoryWithContent(testContent string) {
+	if testContent == "" && m.execHookPrepared(m.ExecPane.Id) {
+		// hooked panes record history as commands finish
+		m.ExecPane.Refresh(m.GetMaxCaptureLines())
+		m.ExecPane.IsPrepared = true
+		return
+	}
+	if testContent == "" && m.osc133Prepared(m.ExecPane.Id) {
+		m.ExecPane.Refresh(m.GetMaxCaptureLines())
+		m.ExecPane.IsPrepared = true
+		m.ExecHistory, _ = parseOSC133(m.osc133.read())
+		return
+	}
 	if testContent == "" {
 		m.ExecPane.Refresh(m.GetMaxCaptureLines())
 	} else {
@@ -173,8 +322,9 @@ func (m *Manager) parseExecPaneCommandHistoryWithContent(testContent string) {
 
 	// Regex: Capture status code (group 1), optionally capture command (group 2)
 	// Making the command part optional handles prompts that only show status (like the last line).
+	// Codes may be negative (Windows' NTSTATUS codes in pwsh) or ? where the shell can't tell (cmd).
 	// ` ?` allows zero or one space after »
-	promptRegex := regexp.MustCompile(`.*\[(\d+)\]» ?(.*)$`)
+	promptRegex := regexp.MustCompile(`.*\[(-?\d+|\?)\]» ?(.*)$`)
 
 	scanner := bufio.NewScanner(strings.NewReader(m.ExecPane.Content))
 
@@ -197,7 +347,9 @@ func (m *Manager) parseExecPaneCommandHistoryWithContent(testContent string) {
 			if currentCommand != nil {
 				// Parse the status code found on *this* line - it belongs to the *previous* command
 				statusCode, err := strconv.Atoi(statusCodeStr)
-				if err != nil {
+				if statusCodeStr == "?" {
+					currentCommand.Code = -1 // the shell doesn't report exit codes
+				} else if err != nil {
 					// This shouldn't happen with \d+ regex but check anyway
 					fmt.Printf("Warning: Could not parse status code '%s' for previous command on line: %s\n", statusCodeStr, line)
 					currentCommand.Code = -1 // Indicate parsing error
@@ -252,5 +404,93 @@ func (m *Manager) parseExecPaneCommandHistoryWithContent(testContent string) {
 	}
 
 	// Update the manager's command history
+	m.stateMu.Lock()
 	m.ExecHistory = history
+	m.stateMu.Unlock()
+}
+
+// execPaneNameRe limits pane names to what reads well in an XML attribute
+var execPaneNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// AddNamedExecPane registers a pane the model can route commands to with
+// <ExecCommand pane="name">. The target may be a pane ID or a
+// session-qualified target.
+func (m *Manager) AddNamedExecPane(name, target string) (string, error) {
+	if !execPaneNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid pane name %q: use lowercase letters, digits, - and _", name)
+	}
+	paneID := target
+	if !strings.HasPrefix(target, "%") {
+		resolved, err := system.TmuxResolvePaneId(target)
+		if err != nil {
+			return "", err
+		}
+		paneID = resolved
+	}
+	if paneID == m.PaneId && !system.TmuxIsRemote() {
+		return "", fmt.Errorf("exec pane cannot be the TmuxAI chat pane (%s)", m.PaneId)
+	}
+	if _, found := m.findPane(paneID); !found {
+		return "", fmt.Errorf("pane %s was not found in any tmux session", paneID)
+	}
+	if m.namedExecPanes == nil {
+		m.namedExecPanes = make(map[string]string)
+	}
+	m.namedExecPanes[name] = paneID
+	return paneID, nil
+}
+
+// RemoveNamedExecPane forgets a named exec pane
+func (m *Manager) RemoveNamedExecPane(name string) bool {
+	if _, ok := m.namedExecPanes[name]; !ok {
+		return false
+	}
+	delete(m.namedExecPanes, name)
+	return true
+}
+
+// namedExecPaneNames returns the named exec panes in sorted order
+func (m *Manager) namedExecPaneNames() []string {
+	names := make([]string, 0, len(m.namedExecPanes))
+	for name := range m.namedExecPanes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// execPaneName returns the name a pane was added under, if any
+func (m *Manager) execPaneName(paneID string) string {
+	for name, id := range m.namedExecPanes {
+		if id == paneID {
+			return name
+		}
+	}
+	return ""
+}
+
+// namedExecPane looks up a named exec pane. Prepared means its prompt is
+// the one PrepareExecPane sets, so output and exit codes can be captured.
+func (m *Manager) namedExecPane(name string) (*system.TmuxPaneDetails, error) {
+	paneID, ok := m.namedExecPanes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exec pane %q", name)
+	}
+	pane, found := m.findPane(paneID)
+	if !found {
+		return nil, fmt.Errorf("exec pane %s (%s) no longer exists", name, paneID)
+	}
+	pane.Refresh(m.GetMaxCaptureLines())
+	pane.IsTmuxAiExecPane = true
+	pane.IsPrepared = strings.HasSuffix(pane.LastLine, "]»")
+	return &pane, nil
+}
+
+// pasteToPane sends PasteMultilineContent to a pane: multiline content as
+// one tmux paste so heredocs and indentation survive, a single line as keys
+func pasteToPane(paneID, content string) error {
+	if strings.Contains(strings.TrimSuffix(content, "\n"), "\n") {
+		return system.TmuxPasteToPane(paneID, content)
+	}
+	return system.TmuxSendCommandToPane(paneID, content, true)
 }
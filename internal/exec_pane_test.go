@@ -8,6 +8,7 @@ import (
 	"github.com/alvinunreal/tmuxai/config"
 	"github.com/alvinunreal/tmuxai/system"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test regex matching for bash shell prompts
@@ -160,6 +161,28 @@ func TestPrepareExecPaneWithShell(t *testing.T) {
 	assert.Contains(t, commandsSent[0], "function fish_prompt", "Should set fish_prompt for fish")
 	assert.Equal(t, "C-l", commandsSent[1], "Should clear screen")
 
+	// PowerShell, by any of its process names
+	for _, shell := range []string{"pwsh", "powershell.exe"} {
+		commandsSent = []string{}
+		manager.PrepareExecPaneWithShell(shell)
+		assert.Len(t, commandsSent, 2, "Should send 2 commands for %s", shell)
+		assert.Contains(t, commandsSent[0], "function global:prompt {", "Should define prompt for %s", shell)
+		assert.Contains(t, commandsSent[0], "[$s]» ", "Should show the exit code for %s", shell)
+		assert.Equal(t, "C-l", commandsSent[1], "Should clear screen")
+	}
+
+	commandsSent = []string{}
+	manager.PrepareExecPaneWithShell("nu")
+	assert.Len(t, commandsSent, 2, "Should send 2 commands for nu")
+	assert.Contains(t, commandsSent[0], "$env.PROMPT_COMMAND = {||", "Should set PROMPT_COMMAND for nu")
+	assert.Contains(t, commandsSent[0], "[($env.LAST_EXIT_CODE)]", "Should show the exit code for nu")
+	assert.Contains(t, commandsSent[0], `$env.PROMPT_INDICATOR = "» "`, "Should end the prompt with » for nu")
+
+	commandsSent = []string{}
+	manager.PrepareExecPaneWithShell("cmd.exe")
+	assert.Len(t, commandsSent, 1, "cmd clears with cls, it ignores C-l")
+	assert.Contains(t, commandsSent[0], "prompt %USERNAME%@%COMPUTERNAME%:$P[$T][?]»$S")
+
 	// Reset and test unsupported shell
 	commandsSent = []string{}
 	manager.PrepareExecPaneWithShell("tcsh")
@@ -459,3 +482,187 @@ func TestGetAvailablePane_SkipsForcedReadPanes(t *testing.T) {
 	pane := manager.GetAvailablePane()
 	assert.Equal(t, "%3", pane.Id)
 }
+
+func TestInitExecPane_SessionQualifiedTarget(t *testing.T) {
+	manager := &Manager{
+		Config:            &config.Config{MaxCaptureLines: 1000},
+		PaneId:            "%1",
+		ExecPane:          &system.TmuxPaneDetails{},
+		ForcedExecPaneID:  "servers:1.2",
+		ForcedReadPaneIDs: map[string]bool{},
+	}
+
+	originalWindowTarget := system.TmuxCurrentWindowTarget
+	originalCurrentPaneID := system.TmuxCurrentPaneId
+	originalPanesDetails := system.TmuxPanesDetails
+	originalResolvePaneID := system.TmuxResolvePaneId
+	defer func() {
+		system.TmuxCurrentWindowTarget = originalWindowTarget
+		system.TmuxCurrentPaneId = originalCurrentPaneID
+		system.TmuxPanesDetails = originalPanesDetails
+		system.TmuxResolvePaneId = originalResolvePaneID
+	}()
+
+	system.TmuxCurrentWindowTarget = func() (string, error) {
+		return "$1:1", nil
+	}
+	system.TmuxCurrentPaneId = func() (string, error) {
+		return "%1", nil
+	}
+	system.TmuxResolvePaneId = func(target string) (string, error) {
+		assert.Equal(t, "servers:1.2", target)
+		return "%7", nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		if target == "%7" {
+			return []system.TmuxPaneDetails{{Id: "%7", CurrentCommand: "bash"}}, nil
+		}
+		return []system.TmuxPaneDetails{
+			{Id: "%1", CurrentCommand: "tmuxai"},
+			{Id: "%2", CurrentCommand: "zsh"},
+		}, nil
+	}
+
+	err := manager.InitExecPane()
+	assert.NoError(t, err)
+	assert.Equal(t, "%7", manager.ForcedExecPaneID)
+	assert.Equal(t, "%7", manager.ExecPane.Id)
+	assert.True(t, manager.ExecPane.IsTmuxAiExecPane)
+
+	panes, _ := manager.GetTmuxPanes()
+	assert.Len(t, panes, 3, "exec pane from another session is part of the context")
+	assert.Equal(t, "%7", panes[2].Id)
+	assert.True(t, panes[2].IsTmuxAiExecPane)
+}
+
+func TestNamedExecPanes(t *testing.T) {
+	manager := &Manager{
+		Config:   &config.Config{MaxCaptureLines: 1000},
+		PaneId:   "%1",
+		ExecPane: &system.TmuxPaneDetails{Id: "%2"},
+	}
+
+	originalWindowTarget := system.TmuxCurrentWindowTarget
+	originalCurrentPaneID := system.TmuxCurrentPaneId
+	originalPanesDetails := system.TmuxPanesDetails
+	originalCapturePane := system.TmuxCapturePane
+	originalResolvePaneID := system.TmuxResolvePaneId
+	defer func() {
+		system.TmuxCurrentWindowTarget = originalWindowTarget
+		system.TmuxCurrentPaneId = originalCurrentPaneID
+		system.TmuxPanesDetails = originalPanesDetails
+		system.TmuxCapturePane = originalCapturePane
+		system.TmuxResolvePaneId = originalResolvePaneID
+	}()
+
+	system.TmuxCurrentWindowTarget = func() (string, error) {
+		return "@1:1", nil
+	}
+	system.TmuxCurrentPaneId = func() (string, error) {
+		return "%1", nil
+	}
+	system.TmuxResolvePaneId = func(target string) (string, error) {
+		assert.Equal(t, "db:1.0", target)
+		return "%9", nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		switch target {
+		case "%9":
+			return []system.TmuxPaneDetails{{Id: "%9", CurrentCommand: "psql"}}, nil
+		case "%3":
+			return []system.TmuxPaneDetails{{Id: "%3", CurrentCommand: "bash"}}, nil
+		}
+		return []system.TmuxPaneDetails{
+			{Id: "%1", CurrentCommand: "tmuxai"},
+			{Id: "%2", CurrentCommand: "bash"},
+			{Id: "%3", CurrentCommand: "bash"},
+		}, nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		if paneId == "%3" {
+			return "user@host:~/api[10:00][0]»", nil
+		}
+		return "captured from " + paneId, nil
+	}
+
+	_, err := manager.AddNamedExecPane("Backend", "%3")
+	assert.Error(t, err, "names are lowercase")
+	_, err = manager.AddNamedExecPane("chat", "%1")
+	assert.Error(t, err, "the chat pane can't run commands")
+	_, err = manager.AddNamedExecPane("gone", "%5")
+	assert.Error(t, err)
+
+	paneID, err := manager.AddNamedExecPane("backend", "%3")
+	assert.NoError(t, err)
+	assert.Equal(t, "%3", paneID)
+	paneID, err = manager.AddNamedExecPane("db", "db:1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "%9", paneID)
+	assert.Equal(t, []string{"backend", "db"}, manager.namedExecPaneNames())
+
+	xml := manager.getTmuxPanesInXmlFn(manager.Config)
+	assert.Contains(t, xml, "<named_exec_pane>\n - Id: %3\n - ExecPaneName: backend\n")
+	assert.Contains(t, xml, " - Id: %9\n - ExecPaneName: db\n", "named panes in other sessions are part of the context")
+	assert.Contains(t, manager.namedExecPanesPrompt(), "backend (%3), db (%9)")
+
+	pane, err := manager.namedExecPane("backend")
+	assert.NoError(t, err)
+	assert.True(t, pane.IsPrepared, "a pane showing the prepared prompt captures output")
+	pane, err = manager.namedExecPane("db")
+	assert.NoError(t, err)
+	assert.False(t, pane.IsPrepared)
+
+	assert.True(t, manager.RemoveNamedExecPane("db"))
+	assert.False(t, manager.RemoveNamedExecPane("db"))
+	_, err = manager.namedExecPane("db")
+	assert.Error(t, err)
+}
+
+// Test the exit code tokens of the pwsh, nu and cmd prompts
+func TestParseExecPaneCommandHistory_WindowsShells(t *testing.T) {
+	manager := &Manager{
+		Config:           &config.Config{MaxCaptureLines: 1000},
+		SessionOverrides: make(map[string]interface{}),
+		ExecPane:         &system.TmuxPaneDetails{},
+	}
+
+	manager.parseExecPaneCommandHistoryWithContent(`alice@DESKTOP:C:\src[09:41][0]» .\crash.exe
+alice@DESKTOP:C:\src[09:41][-1073741819]» Get-Item missing
+Get-Item: Cannot find path 'C:\src\missing' because it does not exist.
+alice@DESKTOP:C:\src[09:42][1]» `)
+	if !assert.Len(t, manager.ExecHistory, 2) {
+		return
+	}
+	assert.Equal(t, -1073741819, manager.ExecHistory[0].Code, "negative NTSTATUS codes")
+	assert.Equal(t, "Get-Item missing", manager.ExecHistory[1].Command)
+	assert.Equal(t, 1, manager.ExecHistory[1].Code)
+
+	manager.parseExecPaneCommandHistoryWithContent(`alice@DESKTOP:C:\src[ 9:42:07.15][?]» dir /b
+go.mod
+main.go
+alice@DESKTOP:C:\src[ 9:42:09.80][?]» `)
+	if !assert.Len(t, manager.ExecHistory, 1) {
+		return
+	}
+	assert.Equal(t, CommandExecHistory{Command: "dir /b", Output: "go.mod\nmain.go", Code: -1}, manager.ExecHistory[0], "cmd prompts have no exit code")
+}
+
+func TestPasteToPane(t *testing.T) {
+	originalPaste, originalSend := system.TmuxPasteToPane, system.TmuxSendCommandToPane
+	t.Cleanup(func() { system.TmuxPasteToPane, system.TmuxSendCommandToPane = originalPaste, originalSend })
+	var pasted, sent []string
+	system.TmuxPasteToPane = func(paneId string, content string) error {
+		pasted = append(pasted, content)
+		return nil
+	}
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		sent = append(sent, command)
+		return nil
+	}
+
+	heredoc := "cat <<EOF > notes.txt\n  indented\nEOF\n"
+	require.NoError(t, pasteToPane("%1", heredoc))
+	require.NoError(t, pasteToPane("%1", "print('hi')\n"))
+	assert.Equal(t, []string{heredoc}, pasted, "multiline content is pasted in one go")
+	assert.Equal(t, []string{"print('hi')\n"}, sent)
+}
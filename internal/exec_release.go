@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// preparedShell is an exec pane whose shell /prepare changed, by rewriting
+// its prompt or adding the exit code hook
+type preparedShell struct {
+	shell   string // as shellKind names it
+	command string // the pane's foreground process at the time, e.g. ssh for a remote shell
+}
+
+// restorePromptCommand undoes a shell's /prepare prompt command, bringing
+// back the prompt it saved
+func restorePromptCommand(shell string) (string, error) {
+	switch shell {
+	case "zsh":
+		return `precmd_functions=(${precmd_functions:#__tmuxai_set_prompt}); unfunction __tmuxai_set_prompt 2>/dev/null; (( ${+__tmuxai_prompt} )) && PROMPT=$__tmuxai_prompt; unset __tmuxai_prompt`, nil
+	case "bash":
+		return `[ -z "${__tmuxai_ps1+x}" ] || { PS1=$__tmuxai_ps1; PROMPT_COMMAND=$__tmuxai_pc; }; unset __tmuxai_ps1 __tmuxai_pc`, nil
+	case "fish":
+		return `functions -q __tmuxai_fish_prompt; and functions -e fish_prompt; and functions -c __tmuxai_fish_prompt fish_prompt; and functions -e __tmuxai_fish_prompt`, nil
+	case "pwsh":
+		return `if (Test-Path function:__tmuxai_prompt) { Set-Item function:global:prompt (Get-Item function:__tmuxai_prompt).ScriptBlock; Remove-Item function:__tmuxai_prompt }`, nil
+	case "nu":
+		return `load-env ($env.__TMUXAI_PROMPT? | default {}); hide-env -i __TMUXAI_PROMPT`, nil
+	case "cmd":
+		return `(if defined TMUXAI_PROMPT prompt %TMUXAI_PROMPT%) & set TMUXAI_PROMPT=& cls`, nil
+	}
+	return "", fmt.Errorf("shell '%s' is not supported", shell)
+}
+
+// unhookCommand removes the exit code hook of prepareExecHook
+func unhookCommand(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return `PROMPT_COMMAND=${PROMPT_COMMAND#__tmuxai_status}; PROMPT_COMMAND=${PROMPT_COMMAND#;}; unset -f __tmuxai_status`, nil
+	case "zsh":
+		return `precmd_functions=(${precmd_functions:#__tmuxai_status}); unfunction __tmuxai_status 2>/dev/null`, nil
+	case "fish":
+		return `functions -e __tmuxai_status`, nil
+	}
+	return "", fmt.Errorf("shell '%s' is not supported", shell)
+}
+
+// rememberPreparedShell records that /prepare changed the exec pane's shell
+func (m *Manager) rememberPreparedShell(shell string) {
+	if m.preparedShells == nil {
+		m.preparedShells = make(map[string]preparedShell)
+	}
+	m.preparedShells[m.ExecPane.Id] = preparedShell{shell: shellKind(shell), command: m.ExecPane.CurrentCommand}
+}
+
+// restoreShell undoes what /prepare changed in a pane's shell: the prompt
+// is restored or the exit code hook removed. The command is only typed if
+// the prepared shell still runs there rather than, say, an editor.
+func (m *Manager) restoreShell(paneID string) error {
+	prepared, ok := m.preparedShells[paneID]
+	if !ok {
+		return nil
+	}
+	// the prompt commands only restore a prompt they saved, so a hooked
+	// pane gets both in case it was prepared with its prompt before
+	restore, err := restorePromptCommand(prepared.shell)
+	if err != nil {
+		return err
+	}
+	commands := []string{restore}
+	if m.execHookPrepared(paneID) {
+		if unhook, err := unhookCommand(prepared.shell); err == nil {
+			commands = append(commands, unhook)
+		}
+	}
+
+	panes, err := system.TmuxPanesDetails(paneID)
+	if err != nil || len(panes) == 0 {
+		delete(m.preparedShells, paneID)
+		return fmt.Errorf("pane %s is gone", paneID)
+	}
+	if panes[0].CurrentCommand != prepared.command {
+		return fmt.Errorf("pane %s runs %s now, not %s", paneID, panes[0].CurrentCommand, prepared.command)
+	}
+	for _, command := range commands {
+		_ = system.TmuxSendCommandToPane(paneID, command, true)
+	}
+	if prepared.shell != "cmd" {
+		_ = system.TmuxSendCommandToPane(paneID, "C-l", false)
+	}
+	delete(m.preparedShells, paneID)
+	logger.Info("Restored the shell of pane %s", paneID)
+	return nil
+}
+
+// ReleaseExecPane hands the exec pane back to the user: its prompt is
+// restored, or the exit code hook or OSC 133 pipe removed, and its commands
+// are no longer tracked
+func (m *Manager) ReleaseExecPane() error {
+	paneID := m.ExecPane.Id
+	_, changed := m.preparedShells[paneID]
+	if !changed && !m.execPaneTracked(paneID) {
+		return fmt.Errorf("exec pane %s is not prepared", paneID)
+	}
+
+	if err := m.restoreShell(paneID); err != nil {
+		return fmt.Errorf("failed to restore the shell: %w", err)
+	}
+	if m.execHookPrepared(paneID) {
+		m.execHookPane = ""
+	}
+	if m.osc133Prepared(paneID) {
+		m.stopOSC133()
+	}
+	m.ExecPane.IsPrepared = false
+	m.stateMu.Lock()
+	m.ExecHistory = nil
+	m.stateMu.Unlock()
+	return nil
+}
+
+// restoreShells undoes /prepare in every pane it changed, when the session
+// exits
+func (m *Manager) restoreShells() {
+	for paneID := range m.preparedShells {
+		if err := m.restoreShell(paneID); err != nil {
+			logger.Error("Failed to restore the shell of pane %s: %v", paneID, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockReleasePane(t *testing.T, foreground *string) (*Manager, *[]string) {
+	t.Helper()
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: map[string]interface{}{},
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentCommand: "zsh"},
+	}
+
+	originalSend := system.TmuxSendCommandToPane
+	originalCapture := system.TmuxCapturePane
+	originalDetails := system.TmuxPanesDetails
+	t.Cleanup(func() {
+		system.TmuxSendCommandToPane = originalSend
+		system.TmuxCapturePane = originalCapture
+		system.TmuxPanesDetails = originalDetails
+	})
+
+	var sent []string
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		sent = append(sent, command)
+		return nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return "user@host:~$ ", nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		return []system.TmuxPaneDetails{{Id: target, CurrentCommand: *foreground}}, nil
+	}
+	return manager, &sent
+}
+
+func TestReleaseExecPaneRestoresPrompt(t *testing.T) {
+	foreground := "zsh"
+	manager, sent := mockReleasePane(t, &foreground)
+
+	manager.PrepareExecPaneWithShell("zsh")
+	require.Len(t, *sent, 2)
+	assert.Contains(t, (*sent)[0], "__tmuxai_prompt=$PROMPT", "the user's prompt is saved")
+	assert.Contains(t, (*sent)[0], "precmd_functions=(${precmd_functions:#__tmuxai_set_prompt} __tmuxai_set_prompt)", "the prompt is set from the last precmd hook")
+	assert.Equal(t, preparedShell{shell: "zsh", command: "zsh"}, manager.preparedShells["%2"])
+
+	*sent = nil
+	manager.ExecPane.IsPrepared = true
+	manager.ExecHistory = []CommandExecHistory{{Command: "ls"}}
+	require.NoError(t, manager.ReleaseExecPane())
+	assert.Equal(t, []string{
+		`precmd_functions=(${precmd_functions:#__tmuxai_set_prompt}); unfunction __tmuxai_set_prompt 2>/dev/null; (( ${+__tmuxai_prompt} )) && PROMPT=$__tmuxai_prompt; unset __tmuxai_prompt`,
+		"C-l",
+	}, *sent)
+	assert.False(t, manager.ExecPane.IsPrepared)
+	assert.Empty(t, manager.ExecHistory)
+	assert.Empty(t, manager.preparedShells)
+
+	assert.ErrorContains(t, manager.ReleaseExecPane(), "not prepared")
+}
+
+func TestRestoreShellsSkipsBusyPanes(t *testing.T) {
+	foreground := "bash"
+	manager, sent := mockReleasePane(t, &foreground)
+	manager.ExecPane.CurrentCommand = "bash"
+	manager.PrepareExecPaneWithShell("bash")
+	assert.Contains(t, (*sent)[0], `__tmuxai_ps1=$PS1; __tmuxai_pc=$PROMPT_COMMAND;`)
+
+	// vim has the pane, typing the restore there would edit the file
+	*sent = nil
+	foreground = "vim"
+	manager.restoreShells()
+	assert.Empty(t, *sent)
+	assert.Contains(t, manager.preparedShells, "%2", "restored once the shell is back")
+
+	foreground = "bash"
+	manager.Cleanup()
+	require.Len(t, *sent, 2)
+	assert.Contains(t, (*sent)[0], "PS1=$__tmuxai_ps1")
+	assert.Empty(t, manager.preparedShells)
+}
+
+func TestReleaseHookedExecPane(t *testing.T) {
+	manager, sent := mockHookedPane(t)
+	originalDetails := system.TmuxPanesDetails
+	t.Cleanup(func() { system.TmuxPanesDetails = originalDetails })
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		return []system.TmuxPaneDetails{{Id: target, CurrentCommand: "zsh"}}, nil
+	}
+
+	manager.PrepareExecPane()
+	require.True(t, manager.execHookPrepared("%2"))
+	*sent = nil
+	require.NoError(t, manager.ReleaseExecPane())
+	assert.Contains(t, *sent, `precmd_functions=(${precmd_functions:#__tmuxai_status}); unfunction __tmuxai_status 2>/dev/null`)
+	assert.False(t, manager.execHookPrepared("%2"))
+	assert.False(t, manager.ExecPane.IsPrepared)
+}
+
+func TestRestoreCommandsCoverPreparedShells(t *testing.T) {
+	for _, shell := range prepareShells {
+		_, err := restorePromptCommand(shell)
+		assert.NoError(t, err, shell)
+	}
+}
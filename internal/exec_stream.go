@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+const (
+	// lines of a running command's pane shown to the model at each review
+	execReviewLines = 50
+	// how much of the session is sent along for context
+	execReviewTranscriptChars = 4000
+	execReviewAbort           = "ABORT"
+)
+
+// execReview shows the output of a running command to the model every
+// exec_stream_interval seconds, so it can stop a command whose failure is
+// already obvious instead of waiting for it to finish
+type execReview struct {
+	m          *Manager
+	command    string
+	interval   time.Duration
+	last       time.Time
+	lastOutput string
+	reason     string // why the model stopped the command, "" while it runs
+}
+
+// newExecReview returns the review of a command about to run, or nil if
+// exec_stream_interval is off
+func (m *Manager) newExecReview(command string) *execReview {
+	interval := time.Duration(m.GetExecStreamInterval()) * time.Second
+	if interval <= 0 {
+		return nil
+	}
+	return &execReview{m: m, command: command, interval: interval, last: time.Now()}
+}
+
+// until wraps a command's finished check to also end the wait once the
+// model asks to stop the command
+func (r *execReview) until(finished func() bool) func() bool {
+	if r == nil {
+		return finished
+	}
+	return func() bool {
+		if finished() {
+			return true
+		}
+		if time.Since(r.last) < r.interval {
+			return false
+		}
+		r.last = time.Now()
+		output := lastLines(strings.TrimSpace(r.m.ExecPane.Content), execReviewLines)
+		if output == r.lastOutput {
+			return false
+		}
+		r.lastOutput = output
+		r.reason = r.m.reviewPartialOutput(r.command, output)
+		// the model may take a while to answer
+		r.last = time.Now()
+		return r.reason != ""
+	}
+}
+
+// aborted returns why the model stopped the command, or "" if it didn't
+func (r *execReview) aborted() string {
+	if r == nil {
+		return ""
+	}
+	return r.reason
+}
+
+// reviewPartialOutput asks the current model whether a running command
+// should be stopped, returning its reason if so. Errors keep it running.
+func (m *Manager) reviewPartialOutput(command, output string) string {
+	prompt := "You are a terminal assistant waiting for this command to finish:\n$ " + command + "\n\n" +
+		"Its output so far:\n" + m.redactContext(output) + "\n\n" +
+		"If the output already shows the command failing, hanging, or not doing what the user needs, reply with " +
+		execReviewAbort + ": <one-line reason>. Otherwise reply with exactly CONTINUE.\n\n" +
+		"The session so far:\n" + m.sessionTranscript(execReviewTranscriptChars)
+	messages := []ChatMessage{{Content: prompt, FromUser: true, Timestamp: time.Now()}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	response, err := m.AiClient.GetResponseFromChatMessages(ctx, messages, m.GetModel())
+	if err != nil {
+		logger.Error("Failed to review output of '%s': %v", command, err)
+		return ""
+	}
+	if m.Config.Debug {
+		debugChatMessages(messages, response)
+	}
+	return parseExecReview(response)
+}
+
+// parseExecReview returns the reason of an ABORT reply, or "" for anything
+// else
+func parseExecReview(response string) string {
+	response = strings.TrimSpace(response)
+	if !strings.HasPrefix(strings.ToUpper(response), execReviewAbort) {
+		return ""
+	}
+	reason := strings.TrimSpace(strings.TrimLeft(response[len(execReviewAbort):], ":-— "))
+	if reason == "" {
+		reason = "the output showed it going wrong"
+	}
+	return reason
+}
+
+// abortExec interrupts a command the model stopped mid-run
+func (m *Manager) abortExec(command, reason string, finished func() bool) {
+	logger.Info("Command '%s' stopped after reviewing its output: %s", command, reason)
+	m.Println(fmt.Sprintf("Stopping command, sending C-c: %s", reason))
+	m.interruptExec(finished)
+}
+
+// execAborted handles a command the model stopped mid-run
+func (m *Manager) execAborted(command, reason string, finished func() bool) CommandExecHistory {
+	m.abortExec(command, reason, finished)
+	history := m.unfinishedHistory(command, finished)
+	history.AbortReason = reason
+	return history
+}
+
+// abortedObservation tells the model which commands it stopped mid-run
+func abortedObservation(results []CommandExecHistory) string {
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "<aborted_command exit_code=\"%d\">\n$ %s\n%s\n</aborted_command>\n", r.Code, sanitizeXML(r.Command), sanitizeXML(r.Output))
+		fmt.Fprintf(&b, "The command above was interrupted with C-c while running, because: %s\n", sanitizeXML(r.AbortReason))
+	}
+	b.WriteString("Fix the problem its output shows before running it again.")
+	return b.String()
+}
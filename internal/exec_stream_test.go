@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExecReview(t *testing.T) {
+	assert.Empty(t, parseExecReview("CONTINUE"))
+	assert.Empty(t, parseExecReview("The build looks fine so far."))
+	assert.Equal(t, "pkg/b fails to compile", parseExecReview("ABORT: pkg/b fails to compile"))
+	assert.Equal(t, "wrong branch", parseExecReview(" abort - wrong branch\n"))
+	assert.NotEmpty(t, parseExecReview("ABORT"))
+}
+
+func TestExecWaitCaptureAbortsAfterReview(t *testing.T) {
+	manager, sent := mockHangingPane(t)
+	var reviewed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reviewed = string(body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ABORT: the log only repeats ticks"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	manager.Config.DefaultModel = "m"
+	manager.Config.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}}
+	manager.AiClient = NewAiClient(manager.Config)
+	manager.AiClient.SetConfigManager(manager)
+	manager.SessionOverrides["exec_stream_interval"] = 1
+
+	history, err := manager.ExecWaitCaptureTimeout("tail -f app.log", time.Minute)
+	require.NoError(t, err)
+	assert.Contains(t, reviewed, "tick 2", "the output so far is reviewed")
+	assert.Equal(t, []string{"tail -f app.log", "C-c"}, *sent)
+	assert.Equal(t, "the log only repeats ticks", history.AbortReason)
+	assert.False(t, history.TimedOut)
+	assert.Equal(t, 130, history.Code)
+
+	observation := abortedObservation([]CommandExecHistory{history})
+	assert.Contains(t, observation, `<aborted_command exit_code="130">`)
+	assert.Contains(t, observation, "because: the log only repeats ticks")
+}
+
+func TestExecReviewOff(t *testing.T) {
+	manager, _ := mockHangingPane(t)
+	assert.Nil(t, manager.newExecReview("make"))
+	finished := func() bool { return false }
+	assert.False(t, manager.newExecReview("make").until(finished)())
+	assert.Empty(t, manager.newExecReview("make").aborted())
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+const (
+	// how long an interrupted command gets to return to the prompt
+	execInterruptWait = 3 * time.Second
+	// lines of pane content kept as the output of a command still running
+	execTimeoutTailLines = 20
+)
+
+// interruptTimedOut sends C-c to the exec pane if timed out commands are
+// interrupted, and waits for the prompt to come back
+func (m *Manager) interruptTimedOut(command string, timeout time.Duration, finished func() bool) {
+	logger.Info("Command '%s' timed out after %s", command, timeout)
+	if !m.GetExecTimeoutInterrupt() {
+		m.Println(fmt.Sprintf("Command timed out after %s, leaving it running", timeout))
+		return
+	}
+	m.Println(fmt.Sprintf("Command timed out after %s, sending C-c", timeout))
+	m.interruptExec(finished)
+}
+
+// interruptExec sends C-c to the exec pane and waits for the prompt to come
+// back
+func (m *Manager) interruptExec(finished func() bool) {
+	_ = system.TmuxSendCommandToPane(m.ExecPane.Id, "C-c", false)
+	m.waitForExec(finished, execInterruptWait)
+}
+
+// execTimedOut handles a command that outran its timeout
+func (m *Manager) execTimedOut(command string, timeout time.Duration, finished func() bool) CommandExecHistory {
+	m.interruptTimedOut(command, timeout, finished)
+	history := m.unfinishedHistory(command, finished)
+	history.TimedOut = true
+	return history
+}
+
+// unfinishedHistory records a command that was cut short. What it printed
+// so far comes from the command history once it's back at the prompt, or
+// from the end of the pane while it still runs.
+func (m *Manager) unfinishedHistory(command string, finished func() bool) CommandExecHistory {
+	history := CommandExecHistory{Command: command, Code: -1}
+	if finished() {
+		m.parseExecPaneCommandHistory()
+		if n := len(m.ExecHistory); n > 0 {
+			history.Output = m.ExecHistory[n-1].Output
+			history.Code = m.ExecHistory[n-1].Code
+		}
+	} else {
+		m.ExecPane.Refresh(m.GetMaxCaptureLines())
+		history.Output = lastLines(m.ExecPane.Content, execTimeoutTailLines)
+	}
+	m.ExecPane.IsPrepared = true
+	return history
+}
+
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// timeoutObservation tells the model which commands timed out, so it can
+// wait, run them differently or give them a longer timeout
+func (m *Manager) timeoutObservation(results []CommandExecHistory) string {
+	var b strings.Builder
+	for _, r := range results {
+		state := "was interrupted with C-c"
+		switch {
+		case !m.GetExecTimeoutInterrupt():
+			state = "is still running in the exec pane"
+		case r.Code < 0:
+			state = "did not stop after C-c and is still running in the exec pane"
+		}
+		fmt.Fprintf(&b, "<timed_out_command exit_code=\"%d\">\n$ %s\n%s\n</timed_out_command>\n", r.Code, sanitizeXML(r.Command), sanitizeXML(r.Output))
+		fmt.Fprintf(&b, "The command above did not finish within its timeout and %s.\n", state)
+	}
+	b.WriteString(`Adapt to this: e.g. wait for it, limit its output or runtime, run it in the background, or give it a longer timeout with <ExecCommand timeout="seconds">.`)
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockHangingPane fakes a prepared exec pane whose commands never finish on
+// their own; C-c brings the prompt back with exit code 130
+func mockHangingPane(t *testing.T) (*Manager, *[]string) {
+	t.Helper()
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: map[string]interface{}{},
+		Status:           "running",
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentCommand: "bash", IsPrepared: true},
+	}
+
+	originalSend := system.TmuxSendCommandToPane
+	originalCapture := system.TmuxCapturePane
+	t.Cleanup(func() {
+		system.TmuxSendCommandToPane = originalSend
+		system.TmuxCapturePane = originalCapture
+	})
+
+	content := "user@host:~[10:00][0]» "
+	var sent []string
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		sent = append(sent, command)
+		if command == "C-c" {
+			content += "^C\nuser@host:~[10:01][130]» "
+			return nil
+		}
+		content += command + "\ntick 1\ntick 2"
+		return nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return content, nil
+	}
+	return manager, &sent
+}
+
+func TestExecWaitCaptureTimeoutInterrupts(t *testing.T) {
+	manager, sent := mockHangingPane(t)
+
+	history, err := manager.ExecWaitCaptureTimeout("tail -f app.log", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tail -f app.log", "C-c"}, *sent)
+	assert.True(t, history.TimedOut)
+	assert.Equal(t, "tail -f app.log", history.Command)
+	assert.Equal(t, 130, history.Code)
+	assert.Contains(t, history.Output, "tick 2")
+	assert.True(t, manager.ExecPane.IsPrepared)
+}
+
+func TestExecWaitCaptureTimeoutLeavesRunning(t *testing.T) {
+	manager, sent := mockHangingPane(t)
+	manager.SessionOverrides["exec_timeout_interrupt"] = false
+
+	history, err := manager.ExecWaitCaptureTimeout("tail -f app.log", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tail -f app.log"}, *sent, "no C-c is sent")
+	assert.Equal(t, CommandExecHistory{
+		Command:  "tail -f app.log",
+		Output:   "user@host:~[10:00][0]» tail -f app.log\ntick 1\ntick 2",
+		Code:     -1,
+		TimedOut: true,
+	}, history)
+
+	observation := manager.timeoutObservation([]CommandExecHistory{history})
+	assert.Contains(t, observation, `<timed_out_command exit_code="-1">`)
+	assert.Contains(t, observation, "is still running in the exec pane")
+	assert.Contains(t, observation, `<ExecCommand timeout="seconds">`)
+}
+
+func TestLastLines(t *testing.T) {
+	assert.Equal(t, "b\nc", lastLines("a\nb\nc\n", 2))
+	assert.Equal(t, "a", lastLines("a", 5))
+	assert.Equal(t, 20, strings.Count(lastLines(strings.Repeat("x\n", 50), execTimeoutTailLines), "x"))
+}
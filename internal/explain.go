@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/briandowns/spinner"
+)
+
+// explainPrompt asks for an explanation of content, without acting on it
+func explainPrompt(subject, content, shell, osName string) string {
+	return "Explain the following " + subject + " from a terminal (" + shell + " on " + osName + "): what it does or shows, " +
+		"and the cause of any error or warning in it. Be concise and don't suggest running anything unless I need to act. " +
+		"Don't use any tags.\n\n<content>\n" + content + "\n</content>"
+}
+
+// parseLineRange reads "N-M" (lines N to M of a capture, from 1) or "N"
+// (the last N lines, returned as start -N)
+func parseLineRange(s string) (int, int, error) {
+	if from, to, found := strings.Cut(s, "-"); found {
+		start, err1 := strconv.Atoi(from)
+		end, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || start < 1 || end < start {
+			return 0, 0, fmt.Errorf("invalid line range %q, use N-M", s)
+		}
+		return start, end, nil
+	}
+	last, err := strconv.Atoi(s)
+	if err != nil || last < 1 {
+		return 0, 0, fmt.Errorf("invalid line count %q", s)
+	}
+	return -last, 0, nil
+}
+
+// selectLines returns the lines of content parseLineRange selected
+func selectLines(content string, start, end int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if start < 0 {
+		return strings.Join(lines[max(len(lines)+start, 0):], "\n")
+	}
+	if start > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start-1:min(end, len(lines))], "\n")
+}
+
+// explainSubject returns what /explain explains: the exec pane's last
+// command with its output, or the content of a pane (an ID like %3 or a
+// named exec pane), optionally limited to a line range
+func (m *Manager) explainSubject(args []string) (subject, content string, pane *system.TmuxPaneDetails, err error) {
+	pane = m.ExecPane
+	rangeArg := ""
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "%"):
+			found, ok := m.findPane(arg)
+			if !ok {
+				return "", "", nil, fmt.Errorf("pane %s not found", arg)
+			}
+			pane = &found
+		case arg[0] >= '0' && arg[0] <= '9':
+			rangeArg = arg
+		default:
+			if pane, err = m.namedExecPane(arg); err != nil {
+				return "", "", nil, err
+			}
+		}
+	}
+
+	if len(args) == 0 {
+		if pane != nil && pane.IsPrepared {
+			m.parseExecPaneCommandHistory()
+		}
+		if n := len(m.ExecHistory); n > 0 {
+			last := m.ExecHistory[n-1]
+			content = fmt.Sprintf("$ %s\n%s\n[exit code %d]", last.Command, strings.TrimRight(last.Output, "\n"), last.Code)
+			return "command and its output", content, pane, nil
+		}
+	}
+	if pane == nil {
+		return "", "", nil, errors.New("no exec pane")
+	}
+
+	captured := *pane
+	captured.Refresh(m.GetPaneCaptureLines(captured.Id))
+	content = strings.TrimRight(captured.Content, "\n")
+	if rangeArg != "" {
+		start, end, err := parseLineRange(rangeArg)
+		if err != nil {
+			return "", "", nil, err
+		}
+		content = selectLines(content, start, end)
+	}
+	if strings.TrimSpace(content) == "" {
+		return "", "", nil, fmt.Errorf("nothing to explain in pane %s", pane.Id)
+	}
+	return "pane content", content, pane, nil
+}
+
+// explain asks the model about the exec pane's last command or a pane's
+// content and returns the answer. It's a single request outside the agent
+// loop: nothing is run and the chat history is left as it was.
+func (m *Manager) explain(args []string) (string, error) {
+	subject, content, pane, err := m.explainSubject(args)
+	if err != nil {
+		return "", err
+	}
+	shell, osName := "a shell", m.OS
+	if pane != nil {
+		if pane.Shell != "" {
+			shell = pane.Shell
+		}
+		osName = m.paneOS(*pane)
+	}
+	messages := []ChatMessage{{Content: explainPrompt(subject, m.redactContext(content), shell, osName), FromUser: true, Timestamp: time.Now()}}
+
+	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
+	s.Start()
+	defer s.Stop()
+
+	response, err := m.AiClient.GetResponseFromChatMessages(context.Background(), messages, m.GetModel())
+	if err != nil {
+		return "", err
+	}
+	if m.Config.Debug {
+		debugChatMessages(messages, response)
+	}
+	if r, err := m.parseAIResponse(response); err == nil && r.Message != "" {
+		response = r.Message
+	}
+	return strings.TrimSpace(response), nil
+}
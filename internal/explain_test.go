@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineRange(t *testing.T) {
+	start, end, err := parseLineRange("10-20")
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 20}, []int{start, end})
+
+	start, _, err = parseLineRange("5")
+	require.NoError(t, err)
+	assert.Equal(t, -5, start)
+
+	for _, bad := range []string{"0-3", "4-2", "a-b", "0", "x"} {
+		_, _, err := parseLineRange(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSelectLines(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\n"
+	assert.Equal(t, "two\nthree", selectLines(content, 2, 3))
+	assert.Equal(t, "three\nfour", selectLines(content, 3, 10))
+	assert.Equal(t, "four", selectLines(content, -1, 0))
+	assert.Equal(t, content[:len(content)-1], selectLines(content, -10, 0))
+	assert.Empty(t, selectLines(content, 9, 10))
+}
+
+func TestExplainLastCommand(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"The file doesn't exist."}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "m",
+		Models:       map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}},
+	}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}, OS: "linux"}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.ExecHistory = []CommandExecHistory{{Command: "cat nope", Output: "cat: nope: No such file or directory\n", Code: 1}}
+
+	explanation, err := manager.explain(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "The file doesn't exist.", explanation)
+	assert.Contains(t, prompt, "$ cat nope\ncat: nope: No such file or directory\n[exit code 1]")
+	assert.Empty(t, manager.Messages, "the chat history is left alone")
+}
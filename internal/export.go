@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// renderSessionMarkdown renders the conversation as Markdown: user requests,
+// AI replies and the actions they took. Commands are matched against the
+// prepared exec pane's history for output and exit code; follow-ups TmuxAI
+// sends on its own (pane updates, tool results) are left out.
+func (m *Manager) renderSessionMarkdown() string {
+	var b strings.Builder
+	b.WriteString("# TmuxAI session")
+	if m.sessionTitle != "" {
+		b.WriteString(": " + strings.Join(strings.Fields(m.sessionTitle), " "))
+	}
+	b.WriteString("\n\n")
+	if m.SessionID != "" {
+		fmt.Fprintf(&b, "- Session: `%s`\n", m.SessionID)
+	}
+	fmt.Fprintf(&b, "- Exported: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	if m.ExecPane != nil && m.ExecPane.Id != "" {
+		fmt.Fprintf(&b, "- Exec pane: `%s` (%s)\n", m.ExecPane.Id, m.ExecPane.Shell)
+	}
+
+	historyIdx := 0
+	for _, msg := range m.Messages {
+		if msg.FromUser {
+			if !msg.Typed {
+				continue
+			}
+			fmt.Fprintf(&b, "\n## User · %s\n\n%s\n", msg.Timestamp.Format("15:04:05"), strings.TrimSpace(msg.Request))
+			continue
+		}
+
+		r, err := m.parseAIResponse(msg.Content)
+		if err != nil {
+			r = AIResponse{Message: msg.Content}
+		}
+		fmt.Fprintf(&b, "\n## TmuxAI · %s\n", msg.Timestamp.Format("15:04:05"))
+		if text := strings.TrimSpace(r.Message); text != "" {
+			b.WriteString("\n" + text + "\n")
+		}
+
+		for _, command := range r.ExecCommand {
+			b.WriteString("\n" + markdownFence("$ "+command, "sh") + "\n")
+			// find the command's run in the exec history, keeping order
+			for i := historyIdx; i < len(m.ExecHistory); i++ {
+				if m.ExecHistory[i].Command != command {
+					continue
+				}
+				h := m.ExecHistory[i]
+				historyIdx = i + 1
+				fmt.Fprintf(&b, "\nExit code: %d\n", h.Code)
+				if out := strings.TrimRight(h.Output, "\n"); out != "" {
+					b.WriteString("\n" + markdownFence(out, "text") + "\n")
+				}
+				break
+			}
+		}
+		if len(r.SendKeys) > 0 {
+			keys := make([]string, len(r.SendKeys))
+			for i, k := range r.SendKeys {
+				keys[i] = "`" + k + "`"
+			}
+			b.WriteString("\nKeys sent: " + strings.Join(keys, " ") + "\n")
+		}
+		if r.PasteMultilineContent != "" {
+			b.WriteString("\nPasted:\n\n" + markdownFence(r.PasteMultilineContent, "text") + "\n")
+		}
+		for _, call := range r.MCPToolCalls {
+			fmt.Fprintf(&b, "\nMCP tool: `%s` `%s`\n", call.Name, string(call.Arguments))
+		}
+	}
+	return b.String()
+}
+
+// markdownFence wraps content in a code fence longer than any backtick run
+// inside it
+func markdownFence(content, lang string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + content + "\n" + fence
+}
+
+// exportMarkdown writes the session to path, defaulting to a file named
+// after the session in the current directory
+func (m *Manager) exportMarkdown(path string) (string, error) {
+	if path == "" {
+		id := m.SessionID
+		if id == "" {
+			id = time.Now().Format("20060102-150405")
+		}
+		path = "tmuxai-session-" + id + ".md"
+	}
+	if err := os.WriteFile(path, []byte(m.renderSessionMarkdown()), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	logger.Info("Exported session to %s", path)
+	return path, nil
+}
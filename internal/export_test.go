@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSessionMarkdown(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 10, 30, 0, 0, time.Local)
+	manager := &Manager{
+		Config:           &config.Config{},
+		SessionOverrides: make(map[string]interface{}),
+		SessionID:        "1700000000000",
+		sessionTitle:     "why is the build failing?",
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", Shell: "bash"},
+		Messages: []ChatMessage{
+			{Content: "<panes/>\n\nwhy is the build failing?", Request: "why is the build failing?", FromUser: true, Typed: true, Timestamp: ts},
+			{Content: "Let me run it.\n<ExecCommand>make build</ExecCommand>", Timestamp: ts},
+			{Content: "<panes/>\n\nsending updated pane(s) content", Request: "sending updated pane(s) content", FromUser: true, Timestamp: ts},
+			{Content: "A `go` file has a typo.\n<RequestAccomplished>1</RequestAccomplished>", Timestamp: ts},
+		},
+		ExecHistory: []CommandExecHistory{{Command: "make build", Output: "main.go:3: syntax error", Code: 2}},
+	}
+
+	md := manager.renderSessionMarkdown()
+	assert.Contains(t, md, "# TmuxAI session: why is the build failing?")
+	assert.Contains(t, md, "## User · 10:30:00\n\nwhy is the build failing?\n")
+	assert.NotContains(t, md, "<panes/>", "pane context isn't exported")
+	assert.NotContains(t, md, "sending updated pane(s) content", "follow-ups are skipped")
+	assert.Contains(t, md, "Let me run it.\n\n```sh\n$ make build\n```\n\nExit code: 2\n\n```text\nmain.go:3: syntax error\n```")
+	assert.Contains(t, md, "A `go` file has a typo.")
+}
+
+func TestExportMarkdownWritesFile(t *testing.T) {
+	manager := &Manager{Config: &config.Config{}, SessionOverrides: make(map[string]interface{}), SessionID: "42"}
+	path := filepath.Join(t.TempDir(), "out.md")
+
+	written, err := manager.exportMarkdown(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, written)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "- Session: `42`")
+}
+
+func TestMarkdownFence(t *testing.T) {
+	assert.Equal(t, "````sh\necho ```\n````", markdownFence("echo ```", "sh"))
+}
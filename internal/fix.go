@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxFixOutputLines is how much of a failed command's output /fix sends,
+// from the end, where errors usually are
+const maxFixOutputLines = 100
+
+// fixMessage asks the model to find why a command failed and correct it
+func fixMessage(failed CommandExecHistory, note string) string {
+	lines := strings.Split(strings.TrimRight(failed.Output, "\n"), "\n")
+	output := strings.Join(lines[max(len(lines)-maxFixOutputLines, 0):], "\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This command failed with exit code %d:\n<command>%s</command>\n", failed.Code, failed.Command)
+	fmt.Fprintf(&b, "<command_output>\n%s\n</command_output>\n", output)
+	b.WriteString("Explain the cause of the error in a sentence or two, then propose a corrected command with ExecCommand. If it can't be fixed with a command, say what I need to do instead.")
+	if note != "" {
+		b.WriteString("\n\n" + note)
+	}
+	return b.String()
+}
+
+// fixRequest returns the note of a "/fix [note]" input
+func (m *Manager) fixRequest(input string) (string, bool) {
+	trimmed := strings.TrimSpace(input)
+	fields := strings.Fields(trimmed)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "/fix") {
+		return strings.TrimSpace(trimmed[len(fields[0]):]), true
+	}
+	return "", false
+}
+
+// lastFailedCommand returns the last command of the exec pane's history if
+// it exited non-zero
+func (m *Manager) lastFailedCommand() (CommandExecHistory, bool) {
+	if n := len(m.ExecHistory); n > 0 && m.ExecHistory[n-1].Code > 0 {
+		return m.ExecHistory[n-1], true
+	}
+	return CommandExecHistory{}, false
+}
+
+// runFix has the model correct the last command of the exec pane if it
+// failed, the corrected command going through the usual confirmation. It
+// returns whether the task was accomplished.
+func (m *Manager) runFix(ctx context.Context, note string) bool {
+	if m.ExecPane == nil || !m.ExecPane.IsPrepared {
+		m.Println("Exit codes are only known in a prepared exec pane, run /prepare first")
+		return false
+	}
+	m.parseExecPaneCommandHistory()
+	failed, ok := m.lastFailedCommand()
+	if !ok {
+		m.Println("The last command in the exec pane didn't fail, nothing to fix")
+		return false
+	}
+	m.fixOffered = fixKey(failed)
+	m.Println(fmt.Sprintf("Fixing %s (exit code %d)", failed.Command, failed.Code))
+	return m.ProcessUserMessage(ctxWithTypedInput(ctx), fixMessage(failed, note))
+}
+
+// offerFix runs after a task ends: if the exec pane's last command failed,
+// it suggests /fix or, with fix_on_failure set to auto, runs it
+func (m *Manager) offerFix(ctx context.Context) {
+	mode := m.GetFixOnFailure()
+	if mode == "off" || ctx.Err() != nil || m.ExecPane == nil || !m.ExecPane.IsPrepared {
+		return
+	}
+	m.parseExecPaneCommandHistory()
+	failed, ok := m.failureToOffer()
+	if !ok {
+		return
+	}
+	if mode == "auto" {
+		m.runFix(ctx, "")
+		return
+	}
+	m.Println(fmt.Sprintf("%s exited with code %d, type /fix for a corrected command", failed.Command, failed.Code))
+}
+
+// failureToOffer returns the exec pane's last command if it failed and
+// wasn't offered to /fix before, marking it offered
+func (m *Manager) failureToOffer() (CommandExecHistory, bool) {
+	failed, ok := m.lastFailedCommand()
+	if !ok || fixKey(failed) == m.fixOffered {
+		return CommandExecHistory{}, false
+	}
+	m.fixOffered = fixKey(failed)
+	return failed, true
+}
+
+// fixKey identifies a failed command, so the same failure is offered once
+func fixKey(h CommandExecHistory) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", h.Code, h.Command, h.Output)
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixRequest(t *testing.T) {
+	manager := &Manager{}
+
+	note, ok := manager.fixRequest("/fix")
+	assert.True(t, ok)
+	assert.Empty(t, note)
+
+	note, ok = manager.fixRequest("  /FIX use sudo  ")
+	assert.True(t, ok)
+	assert.Equal(t, "use sudo", note)
+
+	_, ok = manager.fixRequest("/fixme")
+	assert.False(t, ok)
+	_, ok = manager.fixRequest("fix the build")
+	assert.False(t, ok)
+}
+
+func TestFixMessage(t *testing.T) {
+	var output []string
+	for i := 1; i <= maxFixOutputLines+5; i++ {
+		output = append(output, fmt.Sprintf("line %d", i))
+	}
+	message := fixMessage(CommandExecHistory{Command: "make test", Output: strings.Join(output, "\n"), Code: 2}, "only touch the Makefile")
+
+	assert.Contains(t, message, "exit code 2")
+	assert.Contains(t, message, "<command>make test</command>")
+	assert.Contains(t, message, "ExecCommand")
+	assert.Contains(t, message, fmt.Sprintf("line %d\n</command_output>", maxFixOutputLines+5))
+	assert.NotContains(t, message, "line 5\n", "only the end of the output is sent")
+	assert.True(t, strings.HasSuffix(message, "only touch the Makefile"))
+}
+
+func TestFailureToOffer(t *testing.T) {
+	manager := &Manager{ExecHistory: []CommandExecHistory{{Command: "ls", Code: 0}}}
+	_, ok := manager.failureToOffer()
+	assert.False(t, ok, "the last command succeeded")
+
+	manager.ExecHistory = append(manager.ExecHistory, CommandExecHistory{Command: "cat nope", Output: "No such file", Code: 1})
+	failed, ok := manager.failureToOffer()
+	assert.True(t, ok)
+	assert.Equal(t, "cat nope", failed.Command)
+
+	_, ok = manager.failureToOffer()
+	assert.False(t, ok, "a failure is offered once")
+
+	manager.ExecHistory = append(manager.ExecHistory, CommandExecHistory{Command: "cat nope", Output: "No such file", Code: -1})
+	_, ok = manager.failureToOffer()
+	assert.False(t, ok, "unknown exit codes aren't failures")
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// gitContextTimeout bounds each git command run for the context
+const gitContextTimeout = 2 * time.Second
+
+// maxGitStatusLines caps the short status, which lists every changed file
+const maxGitStatusLines = 50
+
+// runGit runs git in dir and returns its output. It's a variable so tests
+// can stub it.
+var runGit = func(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// gitContextXml renders the branch, short status and diff of the git repo
+// the exec pane is in, or "" when git_context is off or it isn't in one.
+// It's built anew for every request, so it follows the pane's cd and edits.
+func (m *Manager) gitContextXml() string {
+	if !m.GetGitContext() || system.TmuxIsRemote() || m.ExecPane == nil || m.ExecPane.CurrentPath == "" {
+		return ""
+	}
+	dir := m.ExecPane.CurrentPath
+	git := func(args ...string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), gitContextTimeout)
+		defer cancel()
+		return runGit(ctx, dir, args...)
+	}
+
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		// not a repo, or git isn't installed
+		return ""
+	}
+	status, err := git("status", "--short", "--branch")
+	if err != nil {
+		logger.Error("Failed to get git status of %s: %v", dir, err)
+		return ""
+	}
+	branch, status, _ := strings.Cut(status, "\n")
+	diff, err := git("diff", "HEAD")
+	if err != nil {
+		// a repo without commits has no HEAD to compare with
+		diff, _ = git("diff")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<git_context repo=\"%s\">\n", sanitizeXML(strings.TrimSpace(root)))
+	fmt.Fprintf(&b, " - Branch: %s\n", strings.TrimPrefix(branch, "## "))
+	if status = strings.TrimRight(status, "\n"); status != "" {
+		fmt.Fprintf(&b, "<git_status>\n%s\n</git_status>\n", truncateLines(status, maxGitStatusLines))
+	}
+	if diff = strings.TrimRight(diff, "\n"); diff != "" {
+		fmt.Fprintf(&b, "<git_diff>\n%s\n</git_diff>\n", truncateLines(diff, m.Config.GitContext.MaxDiffLines))
+	}
+	b.WriteString("</git_context>\n")
+	return b.String()
+}
+
+// truncateLines keeps the first limit lines of s, noting how many were cut;
+// limit <= 0 keeps them all
+func truncateLines(s string, limit int) string {
+	lines := strings.Split(s, "\n")
+	if limit <= 0 || len(lines) <= limit {
+		return s
+	}
+	return strings.Join(lines[:limit], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-limit)
+}
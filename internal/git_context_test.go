@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitContextXml(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		_, err := runGit(context.Background(), dir, append([]string{"-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		require.NoError(t, err, strings.Join(args, " "))
+	}
+	git("init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644))
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "init")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0o644))
+
+	manager := &Manager{
+		Config:   &config.Config{GitContext: config.GitContextConfig{Enabled: true, MaxDiffLines: 6}},
+		ExecPane: &system.TmuxPaneDetails{Id: "%2", CurrentPath: dir},
+	}
+	xml := manager.gitContextXml()
+	assert.Contains(t, xml, " - Branch: main\n")
+	assert.Contains(t, xml, "<git_status>\n M a.txt\n?? new.txt\n</git_status>")
+	assert.Contains(t, xml, "+++ b/a.txt")
+	assert.Contains(t, xml, "... (", "the diff is cut after max_diff_lines")
+	assert.NotContains(t, xml, "+four")
+
+	manager.SessionOverrides = map[string]interface{}{"git_context.enabled": false}
+	assert.Empty(t, manager.gitContextXml())
+
+	manager.SessionOverrides = nil
+	manager.ExecPane.CurrentPath = t.TempDir()
+	assert.Empty(t, manager.gitContextXml(), "not a repo")
+}
+
+func TestTruncateLines(t *testing.T) {
+	assert.Equal(t, "a\nb", truncateLines("a\nb", 2))
+	assert.Equal(t, "a\n... (2 more lines)", truncateLines("a\nb\nc", 1))
+	assert.Equal(t, "a\nb\nc", truncateLines("a\nb\nc", 0))
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// entries shown per /history page
+	historyPageSize = 20
+	// characters of an entry shown on its line
+	historyTextWidth = 100
+)
+
+// historyEntry is a line of /history: a typed user message, an AI reply, or
+// a command the AI ran
+type historyEntry struct {
+	Kind string    `json:"kind"` // user, ai or exec
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+	Code *int      `json:"exit_code,omitempty"` // exit code of an exec entry, nil if the run wasn't found
+	// output of an exec entry's run, if found
+	Output string `json:"output,omitempty"`
+}
+
+// failed reports whether a command entry exited non-zero
+func (e historyEntry) failed() bool {
+	return e.Kind == "exec" && e.Code != nil && *e.Code != 0
+}
+
+// sessionHistory lists the session's typed messages, AI replies and the
+// commands they ran, oldest first. Commands are matched against the exec
+// pane's history for their exit code, as the Markdown export does.
+func (m *Manager) sessionHistory() []historyEntry {
+	return m.historyOf(m.Messages, m.ExecHistory)
+}
+
+func (m *Manager) historyOf(messages []ChatMessage, execHistory []CommandExecHistory) []historyEntry {
+	var entries []historyEntry
+	historyIdx := 0
+	for _, msg := range messages {
+		if msg.FromUser {
+			if msg.Typed {
+				entries = append(entries, historyEntry{Kind: "user", Time: msg.Timestamp, Text: strings.TrimSpace(msg.Request)})
+			}
+			continue
+		}
+
+		r, err := m.parseAIResponse(msg.Content)
+		if err != nil {
+			r = AIResponse{Message: msg.Content}
+		}
+		if text := strings.TrimSpace(r.Message); text != "" {
+			entries = append(entries, historyEntry{Kind: "ai", Time: msg.Timestamp, Text: text})
+		}
+		for _, command := range r.ExecCommand {
+			entry := historyEntry{Kind: "exec", Time: msg.Timestamp, Text: command}
+			for i := historyIdx; i < len(execHistory); i++ {
+				if execHistory[i].Command == command {
+					code := execHistory[i].Code
+					entry.Code = &code
+					entry.Output = strings.TrimRight(execHistory[i].Output, "\n")
+					historyIdx = i + 1
+					break
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// showHistory prints a page of the session history, newest page first.
+// Entries keep their number in the full history so /history copy works
+// from any filtered view. filter is "", "exec" or "failed".
+func (m *Manager) showHistory(filter string, page int) {
+	entries := m.sessionHistory()
+	var numbers []int
+	for i, e := range entries {
+		if (filter == "exec" && e.Kind != "exec") || (filter == "failed" && !e.failed()) {
+			continue
+		}
+		numbers = append(numbers, i+1)
+	}
+	if len(numbers) == 0 {
+		m.Println("No history entries")
+		return
+	}
+
+	pages := (len(numbers) + historyPageSize - 1) / historyPageSize
+	if page < 1 || page > pages {
+		m.Println(fmt.Sprintf("No page %d, the history has %d page(s)", page, pages))
+		return
+	}
+	end := len(numbers) - (page-1)*historyPageSize
+	start := max(end-historyPageSize, 0)
+
+	var b strings.Builder
+	for _, n := range numbers[start:end] {
+		e := entries[n-1]
+		status := ""
+		switch {
+		case e.Kind != "exec":
+		case e.Code == nil:
+			status = " [exit ?]"
+		default:
+			status = fmt.Sprintf(" [exit %d]", *e.Code)
+		}
+		text := strings.ReplaceAll(e.Text, "\n", "⏎")
+		if runes := []rune(text); len(runes) > historyTextWidth {
+			text = string(runes[:historyTextWidth-1]) + "…"
+		}
+		fmt.Fprintf(&b, "%3d %s %-4s%s %s\n", n, e.Time.Format("15:04:05"), e.Kind, status, text)
+	}
+	if pages > 1 {
+		fmt.Fprintf(&b, "Page %d/%d", page, pages)
+		if page < pages {
+			args := strings.TrimSpace(filter + " " + fmt.Sprint(page+1))
+			fmt.Fprintf(&b, ", /history %s for older entries", args)
+		}
+	}
+	m.Println(strings.TrimRight(b.String(), "\n"))
+}
+
+// copyHistoryEntry puts the text of history entry n into the next input line
+func (m *Manager) copyHistoryEntry(n int) error {
+	entries := m.sessionHistory()
+	if n < 1 || n > len(entries) {
+		return fmt.Errorf("no history entry %d", n)
+	}
+	m.pendingInput = entries[n-1].Text
+	return nil
+}
+
+// takePendingInput returns the text /history copy left for the input line,
+// clearing it
+func (m *Manager) takePendingInput() string {
+	input := m.pendingInput
+	m.pendingInput = ""
+	return input
+}
+
+// clipboardText returns what /copy puts on the clipboard: the last command
+// the AI ran, its output, or history entry n
+func (m *Manager) clipboardText(what string, n int) (string, error) {
+	entries := m.sessionHistory()
+	switch what {
+	case "last-command", "last-output":
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Kind != "exec" {
+				continue
+			}
+			if what == "last-command" {
+				return entries[i].Text, nil
+			}
+			if entries[i].Code == nil {
+				return "", fmt.Errorf("no output recorded for '%s', the exec pane may not be prepared", entries[i].Text)
+			}
+			return entries[i].Output, nil
+		}
+		return "", fmt.Errorf("no command was run in this session")
+	case "message":
+		if n < 1 || n > len(entries) {
+			return "", fmt.Errorf("no history entry %d", n)
+		}
+		return entries[n-1].Text, nil
+	}
+	return "", fmt.Errorf("unknown /copy target '%s'", what)
+}
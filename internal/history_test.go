@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTestManager() *Manager {
+	ts := time.Date(2024, 1, 2, 10, 30, 0, 0, time.Local)
+	return &Manager{
+		Config:           &config.Config{},
+		SessionOverrides: make(map[string]interface{}),
+		Messages: []ChatMessage{
+			{Content: "<panes/>\n\nbuild it", Request: "build it", FromUser: true, Typed: true, Timestamp: ts},
+			{Content: "Building.\n<ExecCommand>make build</ExecCommand>\n<ExecCommand>make test</ExecCommand>", Timestamp: ts},
+			{Content: "<panes/>\n\nsending updated pane(s) content", Request: "sending updated pane(s) content", FromUser: true, Timestamp: ts},
+			{Content: "<ExecCommand>make lint</ExecCommand>", Timestamp: ts},
+		},
+		ExecHistory: []CommandExecHistory{
+			{Command: "make build", Code: 0},
+			{Command: "make test", Code: 2},
+		},
+	}
+}
+
+func TestSessionHistory(t *testing.T) {
+	entries := historyTestManager().sessionHistory()
+	require.Len(t, entries, 5)
+
+	assert.Equal(t, historyEntry{Kind: "user", Time: entries[0].Time, Text: "build it"}, entries[0], "follow-ups are skipped")
+	assert.Equal(t, "ai", entries[1].Kind)
+	assert.Equal(t, "Building.", entries[1].Text)
+
+	assert.Equal(t, "make build", entries[2].Text)
+	require.NotNil(t, entries[2].Code)
+	assert.False(t, entries[2].failed())
+	assert.Equal(t, "make test", entries[3].Text)
+	assert.True(t, entries[3].failed())
+	assert.Equal(t, "make lint", entries[4].Text)
+	assert.Nil(t, entries[4].Code, "a command missing from the exec history has no exit code")
+	assert.False(t, entries[4].failed())
+}
+
+func TestCopyHistoryEntry(t *testing.T) {
+	manager := historyTestManager()
+
+	require.NoError(t, manager.copyHistoryEntry(4))
+	assert.Equal(t, "make test", manager.takePendingInput())
+	assert.Empty(t, manager.takePendingInput(), "the copied entry fills one input line")
+
+	assert.Error(t, manager.copyHistoryEntry(0))
+	assert.Error(t, manager.copyHistoryEntry(6))
+}
+
+func TestClipboardText(t *testing.T) {
+	manager := historyTestManager()
+	manager.ExecHistory[1].Output = "FAIL: TestParse\n"
+
+	text, err := manager.clipboardText("last-command", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "make lint", text)
+
+	_, err = manager.clipboardText("last-output", 0)
+	assert.Error(t, err, "the last command's run wasn't recorded")
+
+	manager.Messages = manager.Messages[:2]
+	text, err = manager.clipboardText("last-output", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "FAIL: TestParse", text)
+
+	text, err = manager.clipboardText("message", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Building.", text)
+	_, err = manager.clipboardText("message", 9)
+	assert.Error(t, err)
+}
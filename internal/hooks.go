@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// Hook events, named as in the hooks config
+const (
+	hookPreExec           = "pre_exec"
+	hookPostExec          = "post_exec"
+	hookOnConfirmRequired = "on_confirm_required"
+	hookOnTaskDone        = "on_task_done"
+)
+
+// hookEvent is the JSON a hook reads on stdin
+type hookEvent struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id,omitempty"`
+	PaneID    string    `json:"pane_id,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	Risk      string    `json:"risk,omitempty"`
+	Flags     []string  `json:"risk_flags,omitempty"`
+	Decision  string    `json:"decision,omitempty"`
+	ExitCode  *int      `json:"exit_code,omitempty"`
+	Output    string    `json:"output,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`  // on_confirm_required: the question asked
+	Request   string    `json:"request,omitempty"` // on_task_done: the typed request
+	Message   string    `json:"message,omitempty"` // on_task_done: the AI's final reply
+}
+
+// runHookScript runs a hook through the shell with the event on stdin and
+// returns its combined output. It's a variable so tests can stub it.
+var runHookScript = func(ctx context.Context, script string, event string, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Env = append(os.Environ(), "TMUXAI_HOOK_EVENT="+event)
+	// don't wait on children of a killed hook that keep its output open
+	cmd.WaitDelay = time.Second
+	return cmd.CombinedOutput()
+}
+
+// hookScript returns the command configured for an event, "" if none is
+func (m *Manager) hookScript(event string) string {
+	hooks := m.Config.Hooks
+	switch event {
+	case hookPreExec:
+		return hooks.PreExec
+	case hookPostExec:
+		return hooks.PostExec
+	case hookOnConfirmRequired:
+		return hooks.OnConfirmRequired
+	case hookOnTaskDone:
+		return hooks.OnTaskDone
+	}
+	return ""
+}
+
+// runHook runs the event's hook, if one is configured, and returns what it
+// printed. ran is false when there is no hook for the event.
+func (m *Manager) runHook(e hookEvent) (output string, ran bool, err error) {
+	script := m.hookScript(e.Event)
+	if strings.TrimSpace(script) == "" {
+		return "", false, nil
+	}
+	e.Time = time.Now()
+	e.SessionID = m.SessionID
+	if e.Command != "" && e.Risk == "" {
+		assessment := m.assessCommand(e.Command)
+		e.Risk = string(assessment.Level)
+		e.Flags = assessment.Flags
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to encode %s hook event: %w", e.Event, err)
+	}
+
+	timeout := time.Duration(m.Config.Hooks.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := runHookScript(ctx, script, e.Event, data)
+	output = strings.TrimSpace(string(out))
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, true, fmt.Errorf("%s hook timed out after %s", e.Event, timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return output, true, fmt.Errorf("%s hook exited with status %d", e.Event, exitErr.ExitCode())
+	}
+	if err != nil {
+		return output, true, fmt.Errorf("failed to run %s hook: %w", e.Event, err)
+	}
+	logger.Debug("%s hook output: %s", e.Event, output)
+	return output, true, nil
+}
+
+// notifyHook runs an informational hook; its failures are logged and
+// otherwise ignored
+func (m *Manager) notifyHook(e hookEvent) {
+	if _, _, err := m.runHook(e); err != nil {
+		logger.Error("Hook failed: %v", err)
+	}
+}
+
+// preExecAllowed runs the pre_exec hook for a command about to run. A hook
+// that exits non-zero, times out or can't run blocks the command; the reason
+// is what it printed, or the failure.
+func (m *Manager) preExecAllowed(paneID, command, decision string) (bool, string) {
+	output, ran, err := m.runHook(hookEvent{Event: hookPreExec, PaneID: paneID, Command: command, Decision: decision})
+	if !ran || err == nil {
+		return true, ""
+	}
+	logger.Info("pre_exec hook blocked %q: %v", command, err)
+	if output != "" {
+		return false, output
+	}
+	return false, err.Error()
+}
+
+// lastTypedRequest returns the last message the user typed
+func (m *Manager) lastTypedRequest() string {
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].FromUser && m.Messages[i].Typed {
+			return m.Messages[i].Request
+		}
+	}
+	return ""
+}
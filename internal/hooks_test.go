@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreExecHook(t *testing.T) {
+	m := &Manager{Config: &config.Config{}, SessionOverrides: map[string]interface{}{}}
+	allowed, _ := m.preExecAllowed("%1", "rm -rf build", auditAuto)
+	assert.True(t, allowed, "without a hook every command runs")
+
+	m.Config.Hooks.PreExec = `grep -q '"command":"rm ' && { echo "no rm in this repo"; exit 1; }; exit 0`
+	allowed, reason := m.preExecAllowed("%1", "rm -rf build", auditAuto)
+	assert.False(t, allowed)
+	assert.Equal(t, "no rm in this repo", reason)
+	allowed, _ = m.preExecAllowed("%1", "make build", auditAuto)
+	assert.True(t, allowed)
+
+	m.Config.Hooks.PreExec = "sleep 5"
+	m.Config.Hooks.TimeoutSeconds = 1
+	allowed, reason = m.preExecAllowed("%1", "make build", auditAuto)
+	assert.False(t, allowed, "a hook that hangs blocks the command")
+	assert.Contains(t, reason, "timed out")
+}
+
+func TestPostExecHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event.json")
+	m := historyTestManager()
+	m.Config.Hooks.PostExec = `echo "$TMUXAI_HOOK_EVENT" > ` + out + `.name; cat > ` + out
+	m.SessionOverrides["audit_log"] = false
+
+	m.audit("exec_command", "%1", "make lint", auditDeclined, nil)
+	_, err := os.Stat(out)
+	assert.True(t, os.IsNotExist(err), "declined commands don't run the hook")
+
+	code := 2
+	m.audit("exec_command", "%1", "make test", auditApproved, &code)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var event hookEvent
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, hookPostExec, event.Event)
+	assert.Equal(t, "make test", event.Command)
+	assert.Equal(t, auditApproved, event.Decision)
+	require.NotNil(t, event.ExitCode)
+	assert.Equal(t, 2, *event.ExitCode)
+	assert.NotEmpty(t, event.Risk)
+
+	name, err := os.ReadFile(out + ".name")
+	require.NoError(t, err)
+	assert.Equal(t, "post_exec\n", string(name))
+}
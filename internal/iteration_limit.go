@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSummaryCommandLen cuts long commands in the iteration limit summary
+const maxSummaryCommandLen = 100
+
+// continuePastIterationLimit is called for every AI request of a task. Once
+// the task has made max_iterations requests for one typed request, it shows
+// the actions taken so far and asks whether to continue, which allows
+// max_iterations more. In yolo mode nobody is asked, so the task stops.
+func (m *Manager) continuePastIterationLimit() bool {
+	limit := m.GetMaxIterations()
+	if limit <= 0 || m.taskIterations <= limit {
+		return true
+	}
+
+	m.Println(fmt.Sprintf("Paused after %d AI requests for this task", limit))
+	fmt.Println(taskSummary(m.taskActions))
+	if m.GetYolo() {
+		m.Println("Stopping the task, max_iterations can't be confirmed in yolo mode")
+		return false
+	}
+	if !m.confirmedContinue("Continue the task?") {
+		return false
+	}
+	m.taskIterations = 1
+	return true
+}
+
+// taskSummary lists the actions taken for the current request, with their
+// exit code or how they were withheld
+func taskSummary(actions []AuditEntry) string {
+	if len(actions) == 0 {
+		return "No actions taken so far."
+	}
+	var b strings.Builder
+	b.WriteString("Actions so far:")
+	for _, a := range actions {
+		command := strings.ReplaceAll(a.Command, "\n", " ⏎ ")
+		if runes := []rune(command); len(runes) > maxSummaryCommandLen {
+			command = string(runes[:maxSummaryCommandLen]) + "…"
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", a.Action, command)
+		switch {
+		case a.ExitCode != nil:
+			fmt.Fprintf(&b, " (exit code %d)", *a.ExitCode)
+		case a.Decision == auditDeclined, a.Decision == auditDryRun, a.Decision == auditHookBlocked, a.Decision == auditReadOnly:
+			fmt.Fprintf(&b, " (%s)", strings.ReplaceAll(a.Decision, "_", " "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContinuePastIterationLimit(t *testing.T) {
+	asked := 0
+	answer := true
+	manager := &Manager{
+		Config:           &config.Config{MaxIterations: 3},
+		SessionOverrides: map[string]interface{}{},
+		confirmedContinue: func(prompt string) bool {
+			asked++
+			return answer
+		},
+	}
+
+	manager.taskIterations = 3
+	assert.True(t, manager.continuePastIterationLimit())
+	assert.Zero(t, asked)
+
+	manager.taskIterations = 4
+	assert.True(t, manager.continuePastIterationLimit())
+	assert.Equal(t, 1, asked)
+	assert.Equal(t, 1, manager.taskIterations, "continuing allows max_iterations more")
+
+	answer = false
+	manager.taskIterations = 4
+	assert.False(t, manager.continuePastIterationLimit())
+
+	manager.SessionOverrides["yolo"] = true
+	assert.False(t, manager.continuePastIterationLimit(), "yolo stops without asking")
+	assert.Equal(t, 2, asked)
+
+	manager.SessionOverrides["max_iterations"] = 0
+	manager.taskIterations = 100
+	assert.True(t, manager.continuePastIterationLimit(), "0 is no limit")
+}
+
+func TestProcessUserMessage_IterationLimit(t *testing.T) {
+	manager := &Manager{
+		Config:            &config.Config{MaxIterations: 2},
+		SessionOverrides:  map[string]interface{}{},
+		Status:            "running",
+		taskIterations:    2,
+		confirmedContinue: func(string) bool { return false },
+	}
+
+	assert.False(t, manager.ProcessUserMessage(context.Background(), "follow-up"))
+	assert.Empty(t, manager.Status, "declining stops the task")
+	assert.Equal(t, 3, manager.taskIterations)
+}
+
+func TestTaskSummary(t *testing.T) {
+	assert.Equal(t, "No actions taken so far.", taskSummary(nil))
+
+	code := 2
+	summary := taskSummary([]AuditEntry{
+		{Action: "exec_command", Command: "make test", ExitCode: &code},
+		{Action: "exec_command", Command: "rm -rf build", Decision: auditDeclined},
+		{Action: "paste_multiline_content", Command: "line 1\nline 2", Decision: auditDryRun},
+		{Action: "exec_command", Command: strings.Repeat("x", maxSummaryCommandLen+10), Decision: auditAuto},
+	})
+	assert.Contains(t, summary, "exec_command: make test (exit code 2)")
+	assert.Contains(t, summary, "exec_command: rm -rf build (declined)")
+	assert.Contains(t, summary, "paste_multiline_content: line 1 ⏎ line 2 (dry run)")
+	assert.Contains(t, summary, strings.Repeat("x", maxSummaryCommandLen)+"…")
+}
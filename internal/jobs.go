@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+const (
+	jobPollInterval = 5 * time.Second
+	// lines of a job's pane kept as its latest output
+	jobOutputLines = 10
+)
+
+// backgroundJob is a long-running command, like a dev server or a build,
+// started in a pane of its own so the agent loop doesn't wait for it
+type backgroundJob struct {
+	ID       int
+	Command  string
+	PaneID   string
+	Started  time.Time
+	Finished time.Time // zero while the command runs
+	Output   string    // last lines of the pane at the last poll
+	reported bool      // whether finishing was shown in the chat
+
+	// subagents are jobs running tmuxai -m on Task with an exec pane of
+	// their own, writing their OnceResult to resultPath
+	Task       string
+	ExecPaneID string
+	Result     *OnceResult
+	resultPath string
+}
+
+// isAgent reports whether the job is a subagent started with SpawnAgent
+func (j *backgroundJob) isAgent() bool {
+	return j.Task != ""
+}
+
+// name describes the job in the chat
+func (j *backgroundJob) name() string {
+	if j.isAgent() {
+		return fmt.Sprintf("agent %d", j.ID)
+	}
+	return fmt.Sprintf("background job %d", j.ID)
+}
+
+// description is the job's command, or a subagent's task
+func (j *backgroundJob) description() string {
+	if j.isAgent() {
+		return j.Task
+	}
+	return j.Command
+}
+
+func (j *backgroundJob) running() bool {
+	return j.Finished.IsZero()
+}
+
+// status describes the job's state, e.g. "running for 2m0s"
+func (j *backgroundJob) status() string {
+	if j.running() {
+		return "running for " + time.Since(j.Started).Round(time.Second).String()
+	}
+	return "finished after " + j.Finished.Sub(j.Started).Round(time.Second).String()
+}
+
+// startBackgroundJob runs a command in a new pane split from the exec pane,
+// in the exec pane's directory, and starts polling it
+func (m *Manager) startBackgroundJob(command string) (*backgroundJob, error) {
+	splitArgs := []string{"-d"}
+	if m.ExecPane.CurrentPath != "" {
+		splitArgs = append(splitArgs, "-c", m.ExecPane.CurrentPath)
+	}
+	paneID, err := system.TmuxCreateNewPane(m.ExecPane.Id, splitArgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job pane: %w", err)
+	}
+	if err := system.TmuxSendCommandToPane(paneID, command, true); err != nil {
+		return nil, fmt.Errorf("failed to start job in pane %s: %w", paneID, err)
+	}
+
+	job := &backgroundJob{Command: command, PaneID: paneID, Started: time.Now()}
+	m.addJob(job)
+	logger.Info("Started background job %d in pane %s: %s", job.ID, paneID, command)
+	return job, nil
+}
+
+// addJob numbers a started job and polls it until it finishes
+func (m *Manager) addJob(job *backgroundJob) {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	m.nextJobID++
+	job.ID = m.nextJobID
+	m.jobs = append(m.jobs, job)
+	if !m.jobsPolling {
+		m.jobsPolling = true
+		go m.pollJobs()
+	}
+}
+
+// pollJobs checks the running jobs until none are left
+func (m *Manager) pollJobs() {
+	for {
+		time.Sleep(jobPollInterval)
+		if !m.pollJobsOnce() {
+			return
+		}
+	}
+}
+
+// pollJobsOnce refreshes the output of the running jobs and marks the ones
+// whose pane is back at a shell, or gone, as finished. It reports whether
+// any job is still running; if none is, polling stops.
+func (m *Manager) pollJobsOnce() bool {
+	m.jobsMu.Lock()
+	var running []backgroundJob
+	for _, job := range m.jobs {
+		if job.running() {
+			running = append(running, *job)
+		}
+	}
+	m.jobsMu.Unlock()
+
+	type update struct {
+		output   string
+		finished bool
+	}
+	updates := make(map[int]update, len(running))
+	for _, job := range running {
+		panes, err := system.TmuxPanesDetails(job.PaneID)
+		if err != nil || len(panes) == 0 {
+			updates[job.ID] = update{output: job.Output, finished: true}
+			continue
+		}
+		output := job.Output
+		if content, err := system.TmuxCapturePane(job.PaneID, jobOutputLines); err == nil {
+			output = lastLines(strings.TrimSpace(content), jobOutputLines)
+		}
+		updates[job.ID] = update{output: output, finished: system.IsShellCommand(panes[0].CurrentCommand)}
+	}
+
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	stillRunning := false
+	for _, job := range m.jobs {
+		if u, ok := updates[job.ID]; ok {
+			job.Output = u.output
+			if u.finished && job.running() {
+				job.Finished = time.Now()
+				if job.isAgent() {
+					job.Result = readAgentResult(job.resultPath)
+				}
+				logger.Info("%s finished: %s", job.name(), job.description())
+			}
+		}
+		stillRunning = stillRunning || job.running()
+	}
+	if !stillRunning {
+		m.jobsPolling = false
+	}
+	return stillRunning
+}
+
+// backgroundJobs returns copies of the jobs, safe to read without the lock
+func (m *Manager) backgroundJobs() []backgroundJob {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	jobs := make([]backgroundJob, len(m.jobs))
+	for i, job := range m.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// reportFinishedJobs says in the chat which jobs finished since the last
+// request
+func (m *Manager) reportFinishedJobs() {
+	m.jobsMu.Lock()
+	var finished []backgroundJob
+	for _, job := range m.jobs {
+		if !job.running() && !job.reported {
+			job.reported = true
+			finished = append(finished, *job)
+		}
+	}
+	m.jobsMu.Unlock()
+
+	for _, job := range finished {
+		line := fmt.Sprintf("%s finished: %s", job.name(), job.description())
+		if job.isAgent() {
+			line += " (" + job.agentOutcome() + ")"
+		}
+		m.Println(strings.ToUpper(line[:1]) + line[1:])
+	}
+}
+
+// stopBackgroundJob interrupts a job and closes its pane
+func (m *Manager) stopBackgroundJob(id int) error {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	for i, job := range m.jobs {
+		if job.ID != id {
+			continue
+		}
+		if job.running() {
+			_ = system.TmuxSendCommandToPane(job.PaneID, "C-c", false)
+		}
+		if err := system.TmuxKillPane(job.PaneID); err != nil {
+			logger.Error("Failed to close pane of job %d: %v", id, err)
+		}
+		if job.ExecPaneID != "" {
+			if err := system.TmuxKillPane(job.ExecPaneID); err != nil {
+				logger.Error("Failed to close exec pane of agent %d: %v", id, err)
+			}
+		}
+		if job.resultPath != "" {
+			_ = os.Remove(job.resultPath)
+		}
+		m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+		logger.Info("Stopped %s: %s", job.name(), job.description())
+		return nil
+	}
+	return fmt.Errorf("no background job %d", id)
+}
+
+// showBackgroundJobs prints the jobs with their latest output line
+func (m *Manager) showBackgroundJobs() {
+	jobs := m.backgroundJobs()
+	if len(jobs) == 0 {
+		m.Println("No background jobs")
+		return
+	}
+	for _, job := range jobs {
+		if job.isAgent() {
+			m.Println(fmt.Sprintf("%d: agent: %s (pane %s, exec pane %s, %s)", job.ID, job.Task, job.PaneID, job.ExecPaneID, job.status()))
+			if job.Result != nil && job.Result.Message != "" {
+				fmt.Println("   " + lastLines(job.Result.Message, 1))
+			}
+			continue
+		}
+		m.Println(fmt.Sprintf("%d: %s (pane %s, %s)", job.ID, job.Command, job.PaneID, job.status()))
+		if job.Output != "" {
+			fmt.Println("   " + lastLines(job.Output, 1))
+		}
+	}
+}
+
+// backgroundJobsPrompt lists the jobs and their latest output for the
+// model, or returns "" when there are none
+func (m *Manager) backgroundJobsPrompt() string {
+	jobs := m.backgroundJobs()
+	if len(jobs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nBackground jobs and agents you started, each in a pane of its own. Don't start a job or agent again while it runs; the user stops them with /jobs stop.\n")
+	for _, job := range jobs {
+		if job.isAgent() {
+			b.WriteString(job.agentPrompt())
+			continue
+		}
+		fmt.Fprintf(&b, "<background_job id=\"%d\" pane=\"%s\" status=\"%s\">\n$ %s\n%s\n</background_job>\n", job.ID, job.PaneID, job.status(), sanitizeXML(job.Command), sanitizeXML(job.Output))
+	}
+	return b.String()
+}
+
+// jobsObservation tells the model which jobs were started, instead of
+// waiting for them
+func jobsObservation(jobs []*backgroundJob) string {
+	var b strings.Builder
+	for _, job := range jobs {
+		if job.isAgent() {
+			fmt.Fprintf(&b, "Started agent %d in pane %s, with exec pane %s: %s\n", job.ID, job.PaneID, job.ExecPaneID, job.Task)
+			continue
+		}
+		fmt.Fprintf(&b, "Started background job %d in pane %s: %s\n", job.ID, job.PaneID, job.Command)
+	}
+	b.WriteString("Jobs and agents keep running while you continue; their status, latest output and results are listed under background jobs.")
+	return b.String()
+}
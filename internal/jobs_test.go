@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockJobPanes(t *testing.T) (*Manager, map[string]string, *[]string) {
+	t.Helper()
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: map[string]interface{}{},
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentPath: "/src/app"},
+		// keeps pollJobs from starting, the tests poll themselves
+		jobsPolling: true,
+	}
+
+	originalCreate := system.TmuxCreateNewPane
+	originalSend := system.TmuxSendCommandToPane
+	originalDetails := system.TmuxPanesDetails
+	originalCapture := system.TmuxCapturePane
+	originalKill := system.TmuxKillPane
+	t.Cleanup(func() {
+		system.TmuxCreateNewPane = originalCreate
+		system.TmuxSendCommandToPane = originalSend
+		system.TmuxPanesDetails = originalDetails
+		system.TmuxCapturePane = originalCapture
+		system.TmuxKillPane = originalKill
+	})
+
+	// pane ID -> its foreground command
+	panes := map[string]string{}
+	var sent []string
+	system.TmuxCreateNewPane = func(target string, splitArgs []string) (string, error) {
+		assert.Equal(t, "%2", target)
+		assert.Equal(t, []string{"-d", "-c", "/src/app"}, splitArgs)
+		id := "%" + string(rune('5'+len(panes)))
+		panes[id] = "zsh"
+		return id, nil
+	}
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		sent = append(sent, paneId+" "+command)
+		if command != "C-c" {
+			panes[paneId] = "node"
+		}
+		return nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		command, ok := panes[target]
+		if !ok {
+			return nil, assert.AnError
+		}
+		return []system.TmuxPaneDetails{{Id: target, CurrentCommand: command}}, nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return "$ npm run dev\nready on http://localhost:3000\n\n", nil
+	}
+	system.TmuxKillPane = func(paneId string) error {
+		delete(panes, paneId)
+		return nil
+	}
+	return manager, panes, &sent
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	manager, panes, sent := mockJobPanes(t)
+
+	job, err := manager.startBackgroundJob("npm run dev")
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.ID)
+	assert.Equal(t, "%5", job.PaneID)
+	assert.Equal(t, []string{"%5 npm run dev"}, *sent)
+	_, err = manager.startBackgroundJob("make watch")
+	require.NoError(t, err)
+
+	assert.True(t, manager.pollJobsOnce())
+	jobs := manager.backgroundJobs()
+	require.Len(t, jobs, 2)
+	assert.True(t, jobs[0].running())
+	assert.Equal(t, "$ npm run dev\nready on http://localhost:3000", jobs[0].Output)
+	assert.Contains(t, manager.backgroundJobsPrompt(), `<background_job id="1" pane="%5" status="running for 0s">`)
+
+	// make watch exits back to the shell, npm's pane is closed by hand
+	panes["%6"] = "zsh"
+	delete(panes, "%5")
+	assert.False(t, manager.pollJobsOnce(), "polling stops without running jobs")
+	assert.False(t, manager.jobsPolling)
+	for _, job := range manager.backgroundJobs() {
+		assert.False(t, job.running(), job.Command)
+	}
+	manager.reportFinishedJobs()
+	assert.True(t, manager.jobs[0].reported)
+
+	require.NoError(t, manager.stopBackgroundJob(2))
+	assert.NotContains(t, panes, "%6")
+	assert.Len(t, manager.backgroundJobs(), 1)
+	assert.Error(t, manager.stopBackgroundJob(2))
+}
+
+func TestStopRunningBackgroundJob(t *testing.T) {
+	manager, panes, sent := mockJobPanes(t)
+
+	job, err := manager.startBackgroundJob("npm run dev")
+	require.NoError(t, err)
+	require.NoError(t, manager.stopBackgroundJob(job.ID))
+	assert.Equal(t, []string{"%5 npm run dev", "%5 C-c"}, *sent)
+	assert.Empty(t, panes)
+	assert.Empty(t, manager.backgroundJobsPrompt())
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const kbTruncatedMarker = "\n\n...[knowledge base truncated to fit knowledge_base.max_tokens]"
+
+// kbBudget is knowledge_base.max_tokens, or 0 when there is no limit. It
+// doesn't apply in search mode, where only excerpts are sent.
+func (m *Manager) kbBudget() int {
+	if m.Config.KnowledgeBase.Search.Enabled || m.Config.KnowledgeBase.MaxTokens <= 0 {
+		return 0
+	}
+	return m.Config.KnowledgeBase.MaxTokens
+}
+
+// checkKBBudget rejects loading content as KB name when over_budget is
+// "refuse" and it would take the loaded KBs past the budget
+func (m *Manager) checkKBBudget(name, content string) error {
+	budget := m.kbBudget()
+	if budget == 0 || m.Config.KnowledgeBase.OverBudget != "refuse" {
+		return nil
+	}
+	used := 0
+	for other, otherContent := range m.LoadedKBs {
+		if other != name {
+			used += m.countTokens(otherContent)
+		}
+	}
+	if tokens := m.countTokens(content); used+tokens > budget {
+		return fmt.Errorf("knowledge base '%s' (%d tokens) would exceed knowledge_base.max_tokens (%d/%d)",
+			name, tokens, used, budget)
+	}
+	return nil
+}
+
+// kbPromptContents returns the loaded KBs as they are sent: whole, or when
+// they exceed the budget, each truncated to its share of it
+func (m *Manager) kbPromptContents() map[string]string {
+	budget := m.kbBudget()
+	if budget == 0 {
+		return m.LoadedKBs
+	}
+
+	tokens := make(map[string]int, len(m.LoadedKBs))
+	total := 0
+	for name, content := range m.LoadedKBs {
+		tokens[name] = m.countTokens(content)
+		total += tokens[name]
+	}
+	if total <= budget {
+		return m.LoadedKBs
+	}
+
+	contents := make(map[string]string, len(m.LoadedKBs))
+	for name, content := range m.LoadedKBs {
+		contents[name] = truncateKB(content, tokens[name], budget*tokens[name]/total)
+	}
+	return contents
+}
+
+// kbPromptTokens counts the tokens of the KBs as they are sent
+func (m *Manager) kbPromptTokens() int {
+	total := 0
+	tokenizer := m.tokenizer()
+	for _, content := range m.kbPromptContents() {
+		total += tokenizer.Count(content)
+	}
+	return total
+}
+
+// truncateKB cuts content of the given token count down to about budget
+// tokens, at a line break where possible
+func truncateKB(content string, tokens, budget int) string {
+	if tokens <= budget {
+		return content
+	}
+	cut := len(content) * budget / tokens
+	if nl := strings.LastIndex(content[:cut], "\n"); nl > 0 {
+		cut = nl
+	}
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return strings.TrimRight(content[:cut], "\n") + kbTruncatedMarker
+}
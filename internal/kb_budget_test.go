@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKBPromptContentsTruncatesProportionally(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.MaxTokens = 300
+	big := strings.Repeat("deploy the service with make deploy\n", 60)
+	small := strings.Repeat("backups run nightly\n", 20)
+	manager := &Manager{Config: cfg, LoadedKBs: map[string]string{"big": big, "small": small}}
+
+	bigTokens, smallTokens := manager.countTokens(big), manager.countTokens(small)
+	require.Greater(t, bigTokens+smallTokens, 300)
+
+	contents := manager.kbPromptContents()
+	for name, content := range contents {
+		assert.True(t, strings.HasSuffix(content, kbTruncatedMarker), name)
+		assert.True(t, strings.HasPrefix(manager.LoadedKBs[name], strings.TrimSuffix(content, kbTruncatedMarker)), name)
+	}
+	assert.InDelta(t, 300*bigTokens/(bigTokens+smallTokens), manager.countTokens(contents["big"]), 25)
+	assert.InDelta(t, 300*smallTokens/(bigTokens+smallTokens), manager.countTokens(contents["small"]), 25)
+	assert.Equal(t, big, manager.LoadedKBs["big"], "loaded content is kept whole")
+
+	cfg.KnowledgeBase.MaxTokens = 0
+	assert.Equal(t, manager.LoadedKBs, manager.kbPromptContents())
+}
+
+func TestLoadKBRefusesOverBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(kbDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(kbDir, "big"), []byte(strings.Repeat("word ", 500)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(kbDir, "small"), []byte("backups run nightly"), 0o644))
+
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Watch = false
+	cfg.KnowledgeBase.MaxTokens = 100
+	cfg.KnowledgeBase.OverBudget = "refuse"
+	manager := &Manager{Config: cfg, LoadedKBs: map[string]string{}}
+
+	require.NoError(t, manager.loadKB("small"))
+	err := manager.loadKB("big")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "would exceed knowledge_base.max_tokens")
+	assert.NotContains(t, manager.LoadedKBs, "big")
+}
@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	htmltomd "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/ledongthuc/pdf"
+)
+
+// kbExtensions are the file types loadKB normalizes. Files with other
+// extensions, or none, are loaded as plain text if they are text.
+var kbExtensions = []string{".md", ".markdown", ".txt", ".org", ".rst", ".html", ".htm", ".pdf"}
+
+var (
+	blankLinesRe = regexp.MustCompile(`\n{3,}`)
+	orgHeadingRe = regexp.MustCompile(`^(\*+)\s+(.*)$`)
+	orgLinkRe    = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]+)\])?\]`)
+	orgKeywordRe = regexp.MustCompile(`(?i)^#\+(\w+):\s*(.*)$`)
+	rstDirective = regexp.MustCompile(`^\.\.\s+([\w-]+)::\s*(.*)$`)
+)
+
+// normalizeKB turns a KB file's bytes into the text sent to the model,
+// converting by extension: PDF and HTML are extracted, Org and
+// reStructuredText are rewritten to Markdown, everything else must be text
+func normalizeKB(name string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		text, err = pdfText(data)
+	case ".html", ".htm":
+		text, err = htmltomd.ConvertString(string(data))
+	case ".org":
+		text, err = orgToMarkdown(string(data)), nil
+	case ".rst":
+		text, err = rstToMarkdown(string(data)), nil
+	default:
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", fmt.Errorf("%s is not a text file", filepath.Base(name))
+		}
+		text = string(data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s: %w", filepath.Base(name), err)
+	}
+	return cleanKBText(text), nil
+}
+
+// cleanKBText strips a BOM, Windows line endings, trailing spaces and runs
+// of blank lines, none of which carry meaning but all of which cost tokens
+func cleanKBText(text string) string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	text = blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// pdfText extracts the plain text of a PDF. The parser panics on some
+// malformed files, which is reported as an error instead.
+func pdfText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(plain)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// orgToMarkdown rewrites Org headings, links and blocks as Markdown and drops
+// property drawers and export settings
+func orgToMarkdown(text string) string {
+	var b strings.Builder
+	inBlock, inDrawer := false, false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		upper := strings.ToUpper(trimmed)
+
+		switch {
+		case inBlock:
+			if strings.HasPrefix(upper, "#+END_") {
+				b.WriteString("```\n")
+				inBlock = false
+			} else {
+				b.WriteString(line + "\n")
+			}
+			continue
+		case inDrawer:
+			inDrawer = upper != ":END:"
+			continue
+		case upper == ":PROPERTIES:" || upper == ":LOGBOOK:":
+			inDrawer = true
+			continue
+		case strings.HasPrefix(upper, "#+BEGIN_SRC") || strings.HasPrefix(upper, "#+BEGIN_EXAMPLE"):
+			lang := ""
+			if fields := strings.Fields(trimmed); len(fields) > 1 && strings.HasPrefix(upper, "#+BEGIN_SRC") {
+				lang = fields[1]
+			}
+			b.WriteString("```" + lang + "\n")
+			inBlock = true
+			continue
+		case strings.HasPrefix(upper, "#+BEGIN_") || strings.HasPrefix(upper, "#+END_"):
+			continue
+		}
+
+		if m := orgKeywordRe.FindStringSubmatch(trimmed); m != nil {
+			if strings.EqualFold(m[1], "title") {
+				b.WriteString("# " + m[2] + "\n")
+			}
+			continue
+		}
+		if m := orgHeadingRe.FindStringSubmatch(line); m != nil {
+			line = strings.Repeat("#", len(m[1])) + " " + m[2]
+		}
+		line = orgLinkRe.ReplaceAllStringFunc(line, func(link string) string {
+			m := orgLinkRe.FindStringSubmatch(link)
+			if m[2] == "" {
+				return "<" + m[1] + ">"
+			}
+			return "[" + m[2] + "](" + m[1] + ")"
+		})
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// isRstAdornment reports whether line is a section underline or overline
+func isRstAdornment(line string) bool {
+	if len(line) < 2 || !strings.ContainsRune("=-~^\"'`#*+_:.", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// rstToMarkdown rewrites reStructuredText section titles, code directives and
+// literal blocks as Markdown, and drops comments and link targets
+func rstToMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	levels := map[string]int{} // adornment style -> heading level, by first use
+	var out []string
+	heading := func(style, title string) {
+		if _, ok := levels[style]; !ok {
+			levels[style] = len(levels) + 1
+		}
+		out = append(out, strings.Repeat("#", levels[style])+" "+title)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		// titles: text above an adornment at least as long, optionally also below one
+		if isRstAdornment(line) && i+2 < len(lines) {
+			if title := strings.TrimSpace(lines[i+1]); title != "" && strings.TrimRight(lines[i+2], " \t") == line {
+				heading("over"+line[:1], title)
+				i += 2
+				continue
+			}
+		}
+		if title := strings.TrimSpace(line); title != "" && !isRstAdornment(line) && i+1 < len(lines) {
+			if under := strings.TrimRight(lines[i+1], " \t"); isRstAdornment(under) && len(under) >= utf8.RuneCountInString(title) {
+				heading(under[:1], title)
+				i++
+				continue
+			}
+		}
+
+		// everything below either passes the line through or starts a literal block
+		var lang string
+		if m := rstDirective.FindStringSubmatch(line); m != nil {
+			switch m[1] {
+			case "code", "code-block", "sourcecode":
+				lang = m[2]
+			default:
+				// admonitions keep their content under a bold label
+				out = append(out, "**"+strings.ToUpper(m[1][:1])+m[1][1:]+":** "+m[2])
+				continue
+			}
+		} else if strings.HasPrefix(line, "..") {
+			// comments and link targets, with their indented continuation
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], " ") {
+				i++
+			}
+			continue
+		} else if strings.HasSuffix(line, "::") {
+			if line = strings.TrimSuffix(line, ":"); strings.TrimSpace(line) == ":" {
+				line = ""
+			}
+			if line != "" {
+				out = append(out, line)
+			}
+		} else {
+			out = append(out, line)
+			continue
+		}
+
+		// the literal block is the following indented lines
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		var block []string
+		indent := ""
+		for ; j < len(lines); j++ {
+			l := lines[j]
+			if strings.TrimSpace(l) == "" {
+				block = append(block, "")
+				continue
+			}
+			if !strings.HasPrefix(l, " ") && !strings.HasPrefix(l, "\t") {
+				break
+			}
+			if indent == "" {
+				indent = l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+			}
+			block = append(block, strings.TrimPrefix(l, indent))
+		}
+		for len(block) > 0 && block[len(block)-1] == "" {
+			block = block[:len(block)-1]
+		}
+		if len(block) > 0 {
+			out = append(out, "", "```"+lang)
+			out = append(out, block...)
+			out = append(out, "```", "")
+		}
+		i = j - 1
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanKBText(t *testing.T) {
+	assert.Equal(t, "# Title\n\nbody", cleanKBText("\ufeff# Title  \r\n\r\n\r\n\r\nbody\r\n"))
+}
+
+func TestNormalizeKBRejectsBinary(t *testing.T) {
+	_, err := normalizeKB("logo.png", []byte{0x89, 'P', 'N', 'G', 0, 0})
+	assert.Error(t, err)
+}
+
+func TestNormalizeKBHTML(t *testing.T) {
+	text, err := normalizeKB("page.html", []byte("<html><body><h1>Deploy</h1><p>Run <code>make deploy</code>.</p></body></html>"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Deploy\n\nRun `make deploy`.", text)
+}
+
+func TestOrgToMarkdown(t *testing.T) {
+	org := `#+TITLE: Runbook
+#+OPTIONS: toc:nil
+* Deploy
+:PROPERTIES:
+:ID: 1234
+:END:
+See [[https://example.com/ci][the CI page]] or [[https://example.com]].
+** Steps
+#+BEGIN_SRC bash
+make deploy
+#+END_SRC
+`
+	want := "# Runbook\n# Deploy\nSee [the CI page](https://example.com/ci) or <https://example.com>.\n## Steps\n```bash\nmake deploy\n```"
+	assert.Equal(t, want, cleanKBText(orgToMarkdown(org)))
+}
+
+func TestRstToMarkdown(t *testing.T) {
+	rst := `=======
+Runbook
+=======
+
+Deploy
+======
+
+.. note:: Deploys are frozen on Fridays.
+
+.. code-block:: bash
+
+   make deploy
+
+Rollback
+--------
+
+Run this::
+
+    kubectl rollout undo deploy/web
+
+.. _internal-target:
+`
+	want := "# Runbook\n\n## Deploy\n\n**Note:** Deploys are frozen on Fridays.\n\n```bash\nmake deploy\n```\n\n### Rollback\n\nRun this:\n\n```\nkubectl rollout undo deploy/web\n```"
+	assert.Equal(t, want, cleanKBText(rstToMarkdown(rst)))
+}
+
+// minimalPDF builds a one-page PDF showing text, with a valid xref table
+func minimalPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return []byte(b.String())
+}
+
+func TestNormalizeKBPDF(t *testing.T) {
+	text, err := normalizeKB("runbook.pdf", minimalPDF("Restart nginx first"))
+	require.NoError(t, err)
+	assert.Contains(t, text, "Restart nginx first")
+
+	_, err = normalizeKB("broken.pdf", []byte("%PDF-1.4\nnot really"))
+	assert.Error(t, err)
+}
+
+func TestLoadKBWithoutExtension(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(kbDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(kbDir, "runbook.pdf"), minimalPDF("Restart nginx first"), 0o644))
+
+	manager := &Manager{Config: config.DefaultConfig(), LoadedKBs: map[string]string{}}
+	require.NoError(t, manager.loadKB("runbook"))
+	assert.Contains(t, manager.LoadedKBs["runbook"], "Restart nginx first")
+}
@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+const (
+	defaultKBSearchTopK   = 5
+	defaultKBChunkChars   = 1500
+	localEmbeddingDims    = 1024
+	localEmbedderID       = "local"
+	kbFileMarkerPrefix    = "=== File: "
+	kbFileMarkerSuffix    = " ==="
+	kbSearchTimeout       = 30 * time.Second
+	kbIndexCacheDirectory = "kb-index"
+)
+
+// kbChunk is a retrievable piece of a knowledge base
+type kbChunk struct {
+	KB     string
+	Source string // file within a directory KB, if any
+	Text   string
+}
+
+// kbIndex holds the chunks of one knowledge base and their embeddings. Key
+// identifies the content, chunk size and embedder it was built from.
+type kbIndex struct {
+	Key     string      `json:"key"`
+	Chunks  []kbChunk   `json:"-"`
+	Vectors [][]float32 `json:"vectors"`
+}
+
+// embedFunc turns texts into vectors
+type embedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+func (m *Manager) kbSearchTopK() int {
+	if k := m.Config.KnowledgeBase.Search.TopK; k > 0 {
+		return k
+	}
+	return defaultKBSearchTopK
+}
+
+func (m *Manager) kbChunkChars() int {
+	if n := m.Config.KnowledgeBase.Search.ChunkChars; n > 0 {
+		return n
+	}
+	return defaultKBChunkChars
+}
+
+// chunkKB splits a knowledge base into chunks of about maxChars, breaking at
+// blank lines. File markers from directory KBs always start a new chunk.
+func chunkKB(name, content string, maxChars int) []kbChunk {
+	var chunks []kbChunk
+	var current strings.Builder
+	source := ""
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			chunks = append(chunks, kbChunk{KB: name, Source: source, Text: text})
+		}
+		current.Reset()
+	}
+
+	for _, para := range strings.Split(content, "\n\n") {
+		if strings.HasPrefix(para, kbFileMarkerPrefix) {
+			flush()
+			line, rest, _ := strings.Cut(para, "\n")
+			source = strings.TrimSuffix(strings.TrimPrefix(line, kbFileMarkerPrefix), kbFileMarkerSuffix)
+			para = rest
+		}
+		if current.Len() > 0 && current.Len()+len(para) > maxChars {
+			flush()
+		}
+		// paragraphs longer than a chunk are cut at line breaks where possible
+		for len(para) > maxChars {
+			cut := strings.LastIndex(para[:maxChars], "\n")
+			if cut <= 0 {
+				cut = maxChars
+			}
+			current.WriteString(para[:cut])
+			flush()
+			para = strings.TrimLeft(para[cut:], "\n")
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+	}
+	flush()
+	return chunks
+}
+
+// localEmbedding hashes keywords into a fixed-size vector, so similar texts
+// share dimensions without calling an embeddings API
+func localEmbedding(text string) []float32 {
+	counts := make(map[uint32]float64)
+	for _, w := range tokenize(text) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(w))
+		counts[h.Sum32()%localEmbeddingDims]++
+	}
+	vec := make([]float32, localEmbeddingDims)
+	var norm float64
+	for dim, tf := range counts {
+		weight := 1 + math.Log(tf)
+		vec[dim] = float32(weight)
+		norm += weight * weight
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] = float32(float64(vec[i]) / norm)
+		}
+	}
+	return vec
+}
+
+func localEmbed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = localEmbedding(text)
+	}
+	return vectors, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// kbEmbedder returns the configured embedder and an ID for cache keys. It
+// falls back to local embeddings when the search model isn't usable.
+func (m *Manager) kbEmbedder() (string, embedFunc) {
+	cfg := m.Config.KnowledgeBase.Search
+	if cfg.Model == "" {
+		return localEmbedderID, localEmbed
+	}
+	mc, exists := m.GetModelConfig(cfg.Model)
+	if !exists || cfg.EmbeddingModel == "" || m.AiClient == nil {
+		logger.Error("KB search: model %q or embedding_model not configured, using local embeddings", cfg.Model)
+		return localEmbedderID, localEmbed
+	}
+	embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+		return m.AiClient.Embeddings(ctx, texts, cfg.EmbeddingModel, mc)
+	}
+	return mc.Provider + ":" + cfg.EmbeddingModel, embed
+}
+
+// kbIndexFor returns the index of a loaded KB, building it if the content,
+// chunk size or embedder changed. Provider embeddings are cached on disk.
+func (m *Manager) kbIndexFor(ctx context.Context, name, embedderID string, embed embedFunc) (*kbIndex, error) {
+	content := m.LoadedKBs[name]
+	chunkChars := m.kbChunkChars()
+	sum := sha256.Sum256([]byte(content))
+	key := fmt.Sprintf("%s:%d:%s", embedderID, chunkChars, hex.EncodeToString(sum[:]))
+
+	if idx, ok := m.kbIndexes[name]; ok && idx.Key == key {
+		return idx, nil
+	}
+
+	chunks := chunkKB(name, content, chunkChars)
+	cachePath := filepath.Join(config.GetConfigFilePath(kbIndexCacheDirectory), name+".json")
+	idx := &kbIndex{Key: key, Chunks: chunks}
+
+	if embedderID != localEmbedderID {
+		var cached kbIndex
+		if data, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(data, &cached) == nil &&
+			cached.Key == key && len(cached.Vectors) == len(chunks) {
+			idx.Vectors = cached.Vectors
+		}
+	}
+
+	if idx.Vectors == nil {
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Text
+		}
+		vectors, err := embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed KB '%s': %w", name, err)
+		}
+		idx.Vectors = vectors
+		logger.Info("KB search: indexed %s (%d chunks, %s)", name, len(chunks), embedderID)
+
+		if embedderID != localEmbedderID {
+			if err := writeKBIndexCache(cachePath, idx); err != nil {
+				logger.Error("Failed to cache KB index for %s: %v", name, err)
+			}
+		}
+	}
+
+	if m.kbIndexes == nil {
+		m.kbIndexes = make(map[string]*kbIndex)
+	}
+	m.kbIndexes[name] = idx
+	return idx, nil
+}
+
+func writeKBIndexCache(path string, idx *kbIndex) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+type scoredChunk struct {
+	chunk kbChunk
+	score float64
+}
+
+// searchKBs returns the chunks of the loaded KBs most similar to query
+func (m *Manager) searchKBs(ctx context.Context, query string, k int, embedderID string, embed embedFunc) ([]kbChunk, error) {
+	queryVec, err := embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	names := make([]string, 0, len(m.LoadedKBs))
+	for name := range m.LoadedKBs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var scored []scoredChunk
+	for _, name := range names {
+		idx, err := m.kbIndexFor(ctx, name, embedderID, embed)
+		if err != nil {
+			return nil, err
+		}
+		for i, vec := range idx.Vectors {
+			scored = append(scored, scoredChunk{idx.Chunks[i], cosineSimilarity(queryVec[0], vec)})
+		}
+	}
+
+	// stable so ties keep KB and document order
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	var results []kbChunk
+	for _, s := range scored {
+		if len(results) == k || s.score <= 0 {
+			break
+		}
+		results = append(results, s.chunk)
+	}
+	return results, nil
+}
+
+// kbExcerpts renders the KB chunks relevant to query as a context message.
+// Provider failures fall back to local embeddings rather than dropping the
+// knowledge bases.
+func (m *Manager) kbExcerpts(query string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), kbSearchTimeout)
+	defer cancel()
+
+	embedderID, embed := m.kbEmbedder()
+	chunks, err := m.searchKBs(ctx, query, m.kbSearchTopK(), embedderID, embed)
+	if err != nil && embedderID != localEmbedderID {
+		logger.Error("KB search failed, falling back to local embeddings: %v", err)
+		chunks, err = m.searchKBs(ctx, query, m.kbSearchTopK(), localEmbedderID, localEmbed)
+	}
+	if err != nil {
+		logger.Error("KB search failed: %v", err)
+		return ""
+	}
+	if len(chunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("=== Knowledge Base excerpts relevant to the request ===")
+	for _, c := range chunks {
+		source := c.KB
+		if c.Source != "" {
+			source += " (" + c.Source + ")"
+		}
+		fmt.Fprintf(&b, "\n\n--- %s ---\n%s", source, c.Text)
+	}
+	return b.String()
+}
+
+// kbSearchQuery is the text KB chunks are matched against: the request for
+// typed messages, or the latest typed request for TmuxAI's own follow-ups
+func (m *Manager) kbSearchQuery(message string, typed bool) string {
+	if typed {
+		return message
+	}
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].Typed && m.Messages[i].Request != "" {
+			return m.Messages[i].Request
+		}
+	}
+	return message
+}
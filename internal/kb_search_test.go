@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkKB(t *testing.T) {
+	content := "=== File: deploy.md ===\n# Deploy\n\nRun make deploy.\n\n=== File: db/backup.md ===\nBackups run nightly.\n\n" +
+		strings.Repeat("a long line of backup notes\n", 10)
+
+	chunks := chunkKB("ops", content, 120)
+	require.NotEmpty(t, chunks)
+	assert.Equal(t, kbChunk{KB: "ops", Source: "deploy.md", Text: "# Deploy\n\nRun make deploy."}, chunks[0])
+	for _, c := range chunks[1:] {
+		assert.Equal(t, "db/backup.md", c.Source)
+		assert.LessOrEqual(t, len(c.Text), 120)
+	}
+	assert.Equal(t, "Backups run nightly.", chunks[1].Text, "a paragraph that doesn't fit starts a new chunk")
+}
+
+func TestSearchKBsLocal(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Search.Enabled = true
+	cfg.KnowledgeBase.Search.ChunkChars = 60
+	manager := &Manager{Config: cfg, LoadedKBs: map[string]string{
+		"runbook": "Restart nginx with systemctl restart nginx.\n\nRotate postgres credentials every quarter.\n\nKubernetes pods are drained before node upgrades.",
+	}}
+
+	excerpts := manager.kbExcerpts("how do I restart nginx")
+	assert.Contains(t, excerpts, "--- runbook ---\nRestart nginx")
+	assert.NotContains(t, excerpts, "Rotate postgres", "chunks sharing no keywords aren't sent")
+
+	assert.Empty(t, manager.kbExcerpts("zzz qqq"), "nothing relevant, nothing sent")
+}
+
+func TestKBSearchQueryUsesLatestTypedRequest(t *testing.T) {
+	manager := &Manager{Messages: []ChatMessage{
+		{Request: "restart nginx", FromUser: true, Typed: true},
+		{Content: "<ExecCommand>systemctl restart nginx</ExecCommand>"},
+	}}
+	assert.Equal(t, "check the logs", manager.kbSearchQuery("check the logs", true))
+	assert.Equal(t, "restart nginx", manager.kbSearchQuery("sending updated pane(s) content", false))
+}
+
+func TestKBIndexCachesProviderEmbeddings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		var req EmbeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var resp EmbeddingResponse
+		for i, text := range req.Input {
+			vec := []float32{0, 1}
+			if strings.Contains(text, "nginx") {
+				vec = []float32{1, 0}
+			}
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, vec})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	newManager := func() *Manager {
+		cfg := config.DefaultConfig()
+		cfg.Models = map[string]config.ModelConfig{"embed": {Provider: "ollama", Model: "llama3", BaseURL: server.URL}}
+		cfg.KnowledgeBase.Search = config.KBSearchConfig{Enabled: true, TopK: 1, Model: "embed", EmbeddingModel: "nomic-embed-text"}
+		manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}, LoadedKBs: map[string]string{
+			"runbook": "Restart nginx with systemctl.\n\n" + strings.Repeat("Unrelated backup notes. ", 100),
+		}}
+		manager.AiClient = NewAiClient(cfg)
+		manager.AiClient.SetConfigManager(manager)
+		return manager
+	}
+
+	excerpts := newManager().kbExcerpts("nginx is down")
+	assert.Contains(t, excerpts, "Restart nginx")
+	assert.NotContains(t, excerpts, "backup notes")
+	assert.Equal(t, 2, requests, "query plus one batch of chunks")
+	_, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".config", "tmuxai", kbIndexCacheDirectory, "runbook.json"))
+	require.NoError(t, err)
+
+	// a new session reuses the cached chunk embeddings and only embeds the query
+	newManager().kbExcerpts("nginx is down")
+	assert.Equal(t, 3, requests)
+}
+
+func TestReadKBDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "db"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "deploy.md"), []byte("Run make deploy."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db", "backup.md"), []byte("Backups run nightly."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".secret"), []byte("hidden"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.png"), []byte{0x89, 'P', 'N', 'G', 0, 0}, 0o644))
+
+	content, err := readKBDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "=== File: db/backup.md ===\nBackups run nightly.\n\n=== File: deploy.md ===\nRun make deploy.", string(content))
+}
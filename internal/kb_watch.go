@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// kbWatcher records which loaded knowledge bases changed on disk. It never
+// touches LoadedKBs itself: the changes are reloaded on the main goroutine
+// before the next request.
+type kbWatcher struct {
+	watcher *fsnotify.Watcher
+	mu      sync.Mutex
+	paths   map[string]string // KB name -> file or directory it was loaded from
+	changed map[string]bool
+}
+
+func newKBWatcher() (*kbWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	w := &kbWatcher{
+		watcher: watcher,
+		paths:   make(map[string]string),
+		changed: make(map[string]bool),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *kbWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			w.mu.Lock()
+			var inDirKB bool
+			for name, path := range w.paths {
+				switch {
+				case event.Name == path:
+					w.changed[name] = true
+				case strings.HasPrefix(event.Name, path+string(filepath.Separator)):
+					w.changed[name] = true
+					inDirKB = true
+				}
+			}
+			w.mu.Unlock()
+
+			// new subdirectories of directory KBs need watches of their own
+			if inDirKB && event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						logger.Error("KB watcher: failed to watch %s: %v", event.Name, err)
+					}
+				}
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("KB watcher: %v", err)
+		}
+	}
+}
+
+// add watches the file or directory a KB was loaded from. Files are watched
+// through their directory, since editors often save by replacing the file.
+func (w *kbWatcher) add(name, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.paths[name] = path
+	w.mu.Unlock()
+
+	if !info.IsDir() {
+		return w.watcher.Add(filepath.Dir(path))
+	}
+	return w.addTree(path)
+}
+
+// addTree watches dir and its subdirectories, skipping hidden ones like loadKB
+func (w *kbWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+// remove stops reporting changes for a KB. The directory watch stays, as
+// other KBs may share it; events for it are simply ignored.
+func (w *kbWatcher) remove(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.paths, name)
+	delete(w.changed, name)
+}
+
+// takeChanged returns the KBs changed since the last call, sorted
+func (w *kbWatcher) takeChanged() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	names := make([]string, 0, len(w.changed))
+	for name := range w.changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.changed = make(map[string]bool)
+	return names
+}
+
+func (w *kbWatcher) close() {
+	_ = w.watcher.Close()
+}
+
+// watchKB watches the path a KB was loaded from, or stops watching it when
+// path is empty, as for remote KBs and unloads
+func (m *Manager) watchKB(name, path string) {
+	if path == "" {
+		if m.kbWatcher != nil {
+			m.kbWatcher.remove(name)
+		}
+		return
+	}
+	if !m.Config.KnowledgeBase.Watch {
+		return
+	}
+	if m.kbWatcher == nil {
+		w, err := newKBWatcher()
+		if err != nil {
+			logger.Error("KB hot reload disabled: %v", err)
+			return
+		}
+		m.kbWatcher = w
+	}
+	if err := m.kbWatcher.add(name, path); err != nil {
+		logger.Error("Failed to watch KB '%s': %v", name, err)
+	}
+}
+
+// reloadChangedKBs reloads the loaded KBs whose files changed on disk and
+// says so in the chat. A KB that fails to reload keeps its old content.
+func (m *Manager) reloadChangedKBs() {
+	if m.kbWatcher == nil {
+		return
+	}
+	for _, name := range m.kbWatcher.takeChanged() {
+		old, loaded := m.LoadedKBs[name]
+		if !loaded {
+			continue
+		}
+		if err := m.loadKB(name); err != nil {
+			logger.Error("Failed to reload KB '%s': %v", name, err)
+			m.Println(fmt.Sprintf("Warning: Failed to reload KB '%s', keeping the previous version: %v", name, err))
+			continue
+		}
+		if m.LoadedKBs[name] != old {
+			m.Println(fmt.Sprintf("Reloaded KB '%s' (changed on disk)", name))
+		}
+	}
+}
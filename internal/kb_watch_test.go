@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadChangedKBs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(kbDir, 0o755))
+	kbFile := filepath.Join(kbDir, "notes.md")
+	require.NoError(t, os.WriteFile(kbFile, []byte("first version"), 0o644))
+
+	manager := &Manager{Config: config.DefaultConfig(), LoadedKBs: map[string]string{}}
+	defer manager.Cleanup()
+	require.NoError(t, manager.loadKB("notes"))
+	require.NotNil(t, manager.kbWatcher)
+
+	// editors often save by writing a new file and renaming it over the old one
+	tmp := filepath.Join(kbDir, ".notes.md.swp")
+	require.NoError(t, os.WriteFile(tmp, []byte("second version"), 0o644))
+	require.NoError(t, os.Rename(tmp, kbFile))
+
+	assert.Eventually(t, func() bool {
+		manager.reloadChangedKBs()
+		return manager.LoadedKBs["notes"] == "second version"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// unloaded KBs are no longer reloaded
+	require.NoError(t, manager.unloadKB("notes"))
+	require.NoError(t, os.WriteFile(kbFile, []byte("third version"), 0o644))
+	time.Sleep(100 * time.Millisecond)
+	manager.reloadChangedKBs()
+	assert.NotContains(t, manager.LoadedKBs, "notes")
+}
+
+func TestWatchKBDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Watch = false
+	manager := &Manager{Config: cfg, LoadedKBs: map[string]string{}}
+	manager.watchKB("notes", t.TempDir())
+	assert.Nil(t, manager.kbWatcher)
+}
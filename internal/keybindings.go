@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+)
+
+// bindKeys sets up the editor's keys: completion, the external editor, vi
+// mode if cli.editing_mode asks for it, and cli.key_bindings on top. The vi
+// mode is returned so each new line can start in insert mode; it is nil in
+// emacs mode.
+func bindKeys(editor *readline.Editor, cfg config.CLIConfig, commands map[string]readline.Command) (*viMode, error) {
+	editor.BindKey(keys.CtrlI, commands["COMPLETE"])
+	editor.BindKey(keys.CtrlO, commands["EDIT_IN_EDITOR"])
+	editor.BindKey(keys.AltE, commands["EDIT_IN_EDITOR"])
+
+	var vi *viMode
+	switch cfg.EditingMode {
+	case "", "emacs":
+	case "vi":
+		vi = &viMode{}
+		vi.bind(editor)
+	default:
+		return nil, fmt.Errorf("unknown editing mode '%s', use emacs or vi", cfg.EditingMode)
+	}
+
+	for key, name := range cfg.KeyBindings {
+		code, ok := keys.NameToCode[keys.NormalizeName(key)]
+		if !ok {
+			return vi, fmt.Errorf("unknown key '%s'", key)
+		}
+		command, ok := commands[keys.NormalizeName(name)]
+		if !ok {
+			command, ok = readline.NameToFunc[keys.NormalizeName(name)]
+		}
+		if !ok {
+			return vi, fmt.Errorf("unknown function '%s' for key '%s'", name, key)
+		}
+		editor.BindKey(code, command)
+	}
+	return vi, nil
+}
+
+// viMode is a vi-like modal editing on top of the emacs-style readline:
+// Escape switches to normal mode, where the printable keys are commands
+// instead of being typed
+type viMode struct {
+	normal bool
+}
+
+// viNormalKeys maps the normal mode keys to the readline commands they run
+var viNormalKeys = map[string]readline.Command{
+	"h": readline.CmdBackwardChar,
+	"l": readline.CmdForwardChar,
+	" ": readline.CmdForwardChar,
+	"0": readline.CmdBeginningOfLine,
+	"^": readline.CmdBeginningOfLine,
+	"$": readline.CmdEndOfLine,
+	"w": readline.CmdForwardWord,
+	"b": readline.CmdBackwardWord,
+	"x": readline.CmdDeleteChar,
+	"X": readline.CmdBackwardDeleteChar,
+	"D": readline.CmdKillLine,
+	"k": readline.CmdPreviousHistory,
+	"j": readline.CmdNextHistory,
+	"u": readline.CmdUndo,
+	"p": readline.CmdYank,
+}
+
+// bind routes Escape and every printable ASCII key through the mode
+func (v *viMode) bind(editor *readline.Editor) {
+	editor.BindKey(keys.Escape, &readline.GoCommand{Name: "VI_NORMAL_MODE", Func: v.enterNormal})
+	for c := ' '; c <= '~'; c++ {
+		key := string(c)
+		editor.BindKey(keys.Code(key), &readline.GoCommand{
+			Name: "VI_KEY",
+			Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+				return v.key(ctx, B, key)
+			},
+		})
+	}
+}
+
+// reset starts a new line in insert mode
+func (v *viMode) reset() {
+	if v != nil {
+		v.normal = false
+	}
+}
+
+func (v *viMode) enterNormal(ctx context.Context, B *readline.Buffer) readline.Result {
+	if !v.normal {
+		v.normal = true
+		// like vi, the cursor goes back onto the last typed character
+		return readline.CmdBackwardChar.Call(ctx, B)
+	}
+	return readline.CONTINUE
+}
+
+func (v *viMode) key(ctx context.Context, B *readline.Buffer, key string) readline.Result {
+	if !v.normal {
+		return readline.SelfInserter(key).Call(ctx, B)
+	}
+	if command, ok := viNormalKeys[key]; ok {
+		return command.Call(ctx, B)
+	}
+	switch key {
+	case "i":
+		v.normal = false
+	case "a":
+		v.normal = false
+		return readline.CmdForwardChar.Call(ctx, B)
+	case "A":
+		v.normal = false
+		return readline.CmdEndOfLine.Call(ctx, B)
+	case "I":
+		v.normal = false
+		return readline.CmdBeginningOfLine.Call(ctx, B)
+	case "C":
+		v.normal = false
+		return readline.CmdKillLine.Call(ctx, B)
+	case "d", "c":
+		// dd/cc take the whole line, dw/cw a word
+		next, err := B.GetKey()
+		if err != nil {
+			return readline.CONTINUE
+		}
+		v.normal = key == "d"
+		switch next {
+		case key:
+			return readline.CmdKillWholeLine.Call(ctx, B)
+		case "w":
+			return readline.CmdKillWord.Call(ctx, B)
+		}
+		v.normal = true
+	}
+	// other keys do nothing in normal mode
+	return readline.CONTINUE
+}
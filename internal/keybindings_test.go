@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEditorCommands() map[string]readline.Command {
+	return map[string]readline.Command{
+		"COMPLETE":       &readline.GoCommand{Name: "COMPLETE"},
+		"EDIT_IN_EDITOR": &readline.GoCommand{Name: "EDIT_IN_EDITOR"},
+	}
+}
+
+func lookupKey(t *testing.T, editor *readline.Editor, code keys.Code) string {
+	t.Helper()
+	command, ok := editor.KeyMap.Lookup(code)
+	require.True(t, ok, "key %q is not bound", code)
+	return command.String()
+}
+
+func TestBindKeysDefaults(t *testing.T) {
+	editor := &readline.Editor{}
+	vi, err := bindKeys(editor, config.CLIConfig{EditingMode: "emacs"}, testEditorCommands())
+	require.NoError(t, err)
+	assert.Nil(t, vi)
+
+	assert.Equal(t, "COMPLETE", lookupKey(t, editor, keys.CtrlI))
+	assert.Equal(t, "EDIT_IN_EDITOR", lookupKey(t, editor, keys.CtrlO))
+	assert.Equal(t, "EDIT_IN_EDITOR", lookupKey(t, editor, keys.AltE))
+	_, bound := editor.KeyMap.Lookup("h")
+	assert.False(t, bound, "printable keys are typed in emacs mode")
+}
+
+func TestBindKeysConfig(t *testing.T) {
+	editor := &readline.Editor{}
+	cfg := config.CLIConfig{KeyBindings: map[string]string{
+		"f2":  "edit_in_editor",
+		"c-o": "complete",
+		"c-b": "backward-word",
+	}}
+	_, err := bindKeys(editor, cfg, testEditorCommands())
+	require.NoError(t, err)
+
+	assert.Equal(t, "EDIT_IN_EDITOR", lookupKey(t, editor, keys.F2))
+	assert.Equal(t, "COMPLETE", lookupKey(t, editor, keys.CtrlO))
+	assert.Equal(t, "BACKWARD_WORD", lookupKey(t, editor, keys.CtrlB))
+
+	_, err = bindKeys(&readline.Editor{}, config.CLIConfig{KeyBindings: map[string]string{"c-o": "launch_rockets"}}, testEditorCommands())
+	assert.Error(t, err)
+	_, err = bindKeys(&readline.Editor{}, config.CLIConfig{KeyBindings: map[string]string{"hyper-o": "complete"}}, testEditorCommands())
+	assert.Error(t, err)
+	_, err = bindKeys(&readline.Editor{}, config.CLIConfig{EditingMode: "ed"}, testEditorCommands())
+	assert.Error(t, err)
+}
+
+func TestViMode(t *testing.T) {
+	editor := &readline.Editor{}
+	vi, err := bindKeys(editor, config.CLIConfig{EditingMode: "vi"}, testEditorCommands())
+	require.NoError(t, err)
+	require.NotNil(t, vi)
+
+	assert.Equal(t, "VI_NORMAL_MODE", lookupKey(t, editor, keys.Escape))
+	assert.Equal(t, "VI_KEY", lookupKey(t, editor, "h"))
+	assert.Equal(t, "COMPLETE", lookupKey(t, editor, keys.CtrlI), "control keys keep their bindings")
+
+	vi.normal = true
+	assert.Equal(t, readline.CONTINUE, vi.key(context.Background(), nil, "z"), "unknown keys do nothing")
+	assert.True(t, vi.normal)
+	vi.key(context.Background(), nil, "i")
+	assert.False(t, vi.normal, "i goes back to insert mode")
+
+	vi.normal = true
+	vi.reset()
+	assert.False(t, vi.normal, "a new line starts in insert mode")
+}
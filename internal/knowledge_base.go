@@ -1,31 +1,118 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/alvinunreal/tmuxai/config"
 	"github.com/alvinunreal/tmuxai/logger"
-	"github.com/alvinunreal/tmuxai/system"
 )
 
-// loadKB loads a knowledge base file by name
+// loadKB loads a knowledge base file by name, or a remote one from
+// knowledge_base.remote when no local file has that name. Files are
+// normalized by format (see normalizeKB) and may be named without their
+// extension. A directory is loaded as one KB made of all the files inside
+// it. Absolute paths, used for project KBs, are read as they are.
 func (m *Manager) loadKB(name string) error {
-	kbDir := config.GetKBDir()
-	kbPath := filepath.Join(kbDir, name)
-
-	content, err := os.ReadFile(kbPath)
+	kbPath := name
+	if !filepath.IsAbs(name) {
+		kbPath = filepath.Join(config.GetKBDir(), name)
+	}
+	kbPath = resolveKBPath(kbPath)
+
+	var content string
+	var err error
+	if info, statErr := os.Stat(kbPath); statErr == nil && info.IsDir() {
+		content, err = readKBDir(kbPath)
+	} else {
+		content, err = readKBFile(kbPath)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read KB file '%s': %w", name, err)
+		rawURL, remote := m.remoteKBs()[name]
+		if !remote || !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read KB file '%s': %w", name, err)
+		}
+		content, err = m.fetchRemoteKB(name, rawURL)
+		if err != nil {
+			return fmt.Errorf("failed to load remote KB '%s': %w", name, err)
+		}
+		kbPath = ""
+	}
+	if err := m.checkKBBudget(name, content); err != nil {
+		return err
 	}
 
-	m.LoadedKBs[name] = string(content)
+	m.LoadedKBs[name] = content
+	m.watchKB(name, kbPath)
 	logger.Info("Loaded knowledge base: %s", name)
 	return nil
 }
 
+// resolveKBPath returns path, or path with the first supported extension
+// that exists when path itself doesn't
+func resolveKBPath(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	for _, ext := range kbExtensions {
+		if _, err := os.Stat(path + ext); err == nil {
+			return path + ext
+		}
+	}
+	return path
+}
+
+// readKBFile reads and normalizes a single KB file
+func readKBFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return normalizeKB(path, data)
+}
+
+// readKBDir joins the files under dir, each introduced by a file marker
+// that KB search uses to label its chunks. Hidden files and files that
+// can't be read as text are skipped.
+func readKBDir(dir string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		text, err := readKBFile(path)
+		if err != nil {
+			logger.Debug("Skipping KB file %s: %v", path, err)
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(kbFileMarkerPrefix + filepath.ToSlash(rel) + kbFileMarkerSuffix + "\n")
+		b.WriteString(text)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
 // unloadKB removes a knowledge base from memory
 func (m *Manager) unloadKB(name string) error {
 	if _, exists := m.LoadedKBs[name]; !exists {
@@ -33,6 +120,8 @@ func (m *Manager) unloadKB(name string) error {
 	}
 
 	delete(m.LoadedKBs, name)
+	delete(m.kbIndexes, name)
+	m.watchKB(name, "")
 	logger.Info("Unloaded knowledge base: %s", name)
 	return nil
 }
@@ -42,22 +131,39 @@ func (m *Manager) listKBs() ([]string, error) {
 	kbDir := config.GetKBDir()
 
 	entries, err := os.ReadDir(kbDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// KB directory doesn't exist yet
-			return []string{}, nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read KB directory: %w", err)
 	}
 
-	var kbs []string
+	kbs := []string{}
+	local := make(map[string]bool, len(entries))
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !strings.HasPrefix(entry.Name(), ".") {
 			kbs = append(kbs, entry.Name())
+			local[entry.Name()] = true
+		}
+	}
+
+	// remote KBs follow the local ones; a local file with the same name wins
+	var remote []string
+	for name := range m.remoteKBs() {
+		if !local[name] {
+			remote = append(remote, name)
+			local[name] = true
+		}
+	}
+	sort.Strings(remote)
+
+	// then anything loaded from elsewhere, such as project KBs
+	var other []string
+	for name := range m.LoadedKBs {
+		if !local[name] {
+			other = append(other, name)
 		}
 	}
+	sort.Strings(other)
 
-	return kbs, nil
+	return append(append(kbs, remote...), other...), nil
 }
 
 // autoLoadKBs loads knowledge bases specified in the config
@@ -100,8 +206,9 @@ func (m *Manager) LoadKBsFromCLI(kbNames []string) {
 // getTotalLoadedKBTokens calculates the total token count of all loaded KBs
 func (m *Manager) getTotalLoadedKBTokens() int {
 	total := 0
+	tokenizer := m.tokenizer()
 	for _, content := range m.LoadedKBs {
-		total += system.EstimateTokenCount(content)
+		total += tokenizer.Count(content)
 	}
 	return total
 }
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/briandowns/spinner"
+)
+
+// kbNotesTranscriptChars caps how much of the session is sent for distilling
+const kbNotesTranscriptChars = 20000
+
+// kbNothingToSave is the reply asked for when a session taught nothing lasting
+const kbNothingToSave = "NOTHING"
+
+// distillSessionNotes asks the current model for the lasting lessons of the
+// session, as Markdown bullets. It returns "" when there are none.
+func (m *Manager) distillSessionNotes() (string, error) {
+	transcript := m.sessionTranscript(kbNotesTranscriptChars)
+	if strings.TrimSpace(transcript) == "" {
+		return "", nil
+	}
+
+	prompt := "Below is a terminal assistant session. Write down what is worth remembering for future sessions on this project: " +
+		"fixed configurations, commands that worked, quirks and gotchas discovered, and their causes. " +
+		"Skip anything only relevant to this session. Reply with concise Markdown bullet points and nothing else, " +
+		"or with exactly " + kbNothingToSave + " if nothing is worth keeping.\n\n" + transcript
+	messages := []ChatMessage{{Content: prompt, FromUser: true, Timestamp: time.Now()}}
+
+	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
+	s.Start()
+	defer s.Stop()
+
+	notes, err := m.withModel(m.Config.Roles.Summarize, func() (string, error) {
+		return m.AiClient.GetResponseFromChatMessages(context.Background(), messages, m.GetModel())
+	})
+	if err != nil {
+		return "", err
+	}
+	if m.Config.Debug {
+		debugChatMessages(messages, notes)
+	}
+
+	notes = strings.TrimSpace(notes)
+	if strings.EqualFold(strings.Trim(notes, ".`* "), kbNothingToSave) {
+		return "", nil
+	}
+	return notes, nil
+}
+
+// kbNotesPath is the file /kb save appends to: an existing KB file of that
+// name, with or without extension, or a new Markdown file
+func kbNotesPath(name string) (string, error) {
+	path := name
+	if !filepath.IsAbs(name) {
+		path = filepath.Join(config.GetKBDir(), name)
+	}
+	path = resolveKBPath(path)
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if filepath.Ext(path) == "" {
+			path += ".md"
+		}
+		return path, nil
+	case err != nil:
+		return "", err
+	case info.IsDir():
+		return "", fmt.Errorf("'%s' is a directory knowledge base, name a file inside it", name)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf", ".html", ".htm":
+		return "", fmt.Errorf("can't add notes to %s, it is converted when loaded", filepath.Base(path))
+	}
+	return path, nil
+}
+
+// appendKBNotes appends notes under a dated heading
+func appendKBNotes(path, title, notes string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create KB directory: %w", err)
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read KB file: %w", err)
+	}
+
+	var b strings.Builder
+	if len(existing) > 0 {
+		if !strings.HasSuffix(string(existing), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	heading := "## " + time.Now().Format("2006-01-02 15:04")
+	if title != "" {
+		heading += " " + title
+	}
+	b.WriteString(heading + "\n\n" + notes + "\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open KB file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write KB file: %w", err)
+	}
+	return nil
+}
+
+// saveKBNotes distills the session into notes appended to KB name, and
+// reloads the KB if it is loaded. It returns the path written and the notes,
+// which are empty when there was nothing worth saving.
+func (m *Manager) saveKBNotes(name string) (string, string, error) {
+	path, err := kbNotesPath(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	notes, err := m.distillSessionNotes()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to distill session notes: %w", err)
+	}
+	if notes == "" {
+		return path, "", nil
+	}
+
+	title := ""
+	if m.sessionSummary != nil {
+		title = m.sessionSummary.Name
+	}
+	if err := appendKBNotes(path, title, notes); err != nil {
+		return "", "", err
+	}
+
+	if _, loaded := m.LoadedKBs[name]; loaded {
+		if err := m.loadKB(name); err != nil {
+			return path, notes, fmt.Errorf("saved notes but failed to reload KB '%s': %w", name, err)
+		}
+	}
+	return path, notes, nil
+}
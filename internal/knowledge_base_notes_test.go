@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notesManager(t *testing.T, reply string) *Manager {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + reply + `"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}}
+	cfg.KnowledgeBase.Watch = false
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}, LoadedKBs: map[string]string{}}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.Messages = []ChatMessage{
+		{Request: "why does nginx fail to start", FromUser: true, Typed: true},
+		{Content: "Port 80 was taken by apache, which I stopped."},
+	}
+	return manager
+}
+
+func TestSaveKBNotesAppends(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(kbDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(kbDir, "ops.md"), []byte("# Ops notes"), 0o644))
+
+	manager := notesManager(t, `- apache grabs port 80 on boot, stop it before starting nginx`)
+	manager.sessionSummary = &session.SessionSummary{Name: "Fix nginx startup"}
+	require.NoError(t, manager.loadKB("ops"))
+
+	path, notes, err := manager.saveKBNotes("ops")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(kbDir, "ops.md"), path)
+	assert.Equal(t, "- apache grabs port 80 on boot, stop it before starting nginx", notes)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Regexp(t, `^# Ops notes\n\n## \d{4}-\d{2}-\d{2} \d{2}:\d{2} Fix nginx startup\n\n- apache grabs port 80`, string(data))
+	assert.Contains(t, manager.LoadedKBs["ops"], "apache grabs port 80", "loaded KBs are reloaded")
+}
+
+func TestSaveKBNotesNewFileAndNothingToSave(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	path, notes, err := notesManager(t, "NOTHING").saveKBNotes("lessons")
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "nothing is written when there is nothing to save")
+
+	path, _, err = notesManager(t, "- keep backups").saveKBNotes("lessons")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(config.GetKBDir(), "lessons.md"), path)
+}
+
+func TestKBNotesPathRejectsConvertedFormats(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(kbDir, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(kbDir, "manual.pdf"), []byte("%PDF"), 0o644))
+
+	_, err := kbNotesPath("manual")
+	assert.Error(t, err)
+	_, err = kbNotesPath("docs")
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// projectKBDir is the per-project directory searched for context files
+const projectKBDir = ".tmuxai"
+
+// findProjectKBs walks up from dir collecting .tmuxai/context.md and
+// .tmuxai/kb/*.md, outermost directory first so nearer files come last
+func findProjectKBs(dir string) []string {
+	var levels [][]string
+	for {
+		var files []string
+		base := filepath.Join(dir, projectKBDir)
+		if info, err := os.Stat(filepath.Join(base, "context.md")); err == nil && !info.IsDir() {
+			files = append(files, filepath.Join(base, "context.md"))
+		}
+		if matches, err := filepath.Glob(filepath.Join(base, "kb", "*.md")); err == nil {
+			sort.Strings(matches)
+			files = append(files, matches...)
+		}
+		if len(files) > 0 {
+			levels = append(levels, files)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	var found []string
+	for i := len(levels) - 1; i >= 0; i-- {
+		found = append(found, levels[i]...)
+	}
+	return found
+}
+
+// projectKBStartDir is the exec pane's working directory, or ours when tmux
+// didn't report one
+func (m *Manager) projectKBStartDir() (string, error) {
+	if m.ExecPane != nil && m.ExecPane.CurrentPath != "" {
+		return m.ExecPane.CurrentPath, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return wd, nil
+}
+
+// autoLoadProjectKBs loads project context files found above the exec
+// pane's working directory. They're named by absolute path, which loadKB
+// reads directly.
+func (m *Manager) autoLoadProjectKBs() {
+	if !m.Config.KnowledgeBase.ProjectDiscovery {
+		return
+	}
+	// the exec pane's directory is on another host
+	if system.TmuxIsRemote() {
+		return
+	}
+
+	dir, err := m.projectKBStartDir()
+	if err != nil {
+		logger.Error("Project KB discovery: %v", err)
+		return
+	}
+
+	for _, path := range findProjectKBs(dir) {
+		if _, loaded := m.LoadedKBs[path]; loaded {
+			continue
+		}
+		if err := m.loadKB(path); err != nil {
+			logger.Error("Failed to load project KB '%s': %v", path, err)
+			m.Println(fmt.Sprintf("Warning: Failed to load project KB '%s': %v", path, err))
+			continue
+		}
+		// project files come with whatever repo was cloned, so say what was loaded
+		m.Println("Loaded project KB: " + path)
+	}
+}
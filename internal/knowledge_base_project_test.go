@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestFindProjectKBs(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "services", "api")
+	writeFile(t, filepath.Join(root, ".tmuxai", "context.md"), "org conventions")
+	writeFile(t, filepath.Join(repo, ".tmuxai", "context.md"), "repo context")
+	writeFile(t, filepath.Join(repo, ".tmuxai", "kb", "deploy.md"), "deploy steps")
+	writeFile(t, filepath.Join(repo, ".tmuxai", "kb", "notes.txt"), "not markdown")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findProjectKBs(sub)
+	want := []string{
+		filepath.Join(root, ".tmuxai", "context.md"),
+		filepath.Join(repo, ".tmuxai", "context.md"),
+		filepath.Join(repo, ".tmuxai", "kb", "deploy.md"),
+	}
+	// temp dirs may sit under other directories with their own .tmuxai
+	if len(got) < len(want) {
+		t.Fatalf("findProjectKBs() = %v, want suffix %v", got, want)
+	}
+	got = got[len(got)-len(want):]
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("findProjectKBs()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAutoLoadProjectKBs(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".tmuxai", "context.md"), "Use make for builds.")
+
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Path = t.TempDir()
+	mgr := &Manager{
+		Config:    cfg,
+		LoadedKBs: make(map[string]string),
+		ExecPane:  &system.TmuxPaneDetails{CurrentPath: repo},
+	}
+
+	mgr.autoLoadProjectKBs()
+	path := filepath.Join(repo, ".tmuxai", "context.md")
+	if mgr.LoadedKBs[path] != "Use make for builds." {
+		t.Fatalf("project context not loaded, LoadedKBs = %v", mgr.LoadedKBs)
+	}
+
+	kbs, err := mgr.listKBs()
+	if err != nil || len(kbs) == 0 || kbs[len(kbs)-1] != path {
+		t.Errorf("listKBs() = %v, %v; want loaded project KB listed", kbs, err)
+	}
+
+	cfg.KnowledgeBase.ProjectDiscovery = false
+	mgr.LoadedKBs = make(map[string]string)
+	mgr.autoLoadProjectKBs()
+	if len(mgr.LoadedKBs) != 0 {
+		t.Errorf("project_discovery: false should load nothing, got %v", mgr.LoadedKBs)
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// remoteKBMaxBytes caps the size of a fetched knowledge base
+const remoteKBMaxBytes = 5 << 20
+
+// remoteKBClient fetches remote knowledge bases. Unlike web_fetch it may
+// reach private addresses, since the URLs come from the user's own config.
+var remoteKBClient = &http.Client{Timeout: 30 * time.Second}
+
+// remoteKBName derives a KB name from the last path segment of a URL,
+// without its extension
+func remoteKBName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid knowledge base URL %q", rawURL)
+	}
+	base := path.Base(strings.TrimRight(u.Path, "/"))
+	name := strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("cannot derive a knowledge base name from %q", rawURL)
+	}
+	return name, nil
+}
+
+// remoteKBs maps the names of configured remote knowledge bases to their URLs
+func (m *Manager) remoteKBs() map[string]string {
+	kbs := make(map[string]string, len(m.Config.KnowledgeBase.Remote))
+	for _, rawURL := range m.Config.KnowledgeBase.Remote {
+		name, err := remoteKBName(rawURL)
+		if err != nil {
+			logger.Error("Skipping remote knowledge base: %v", err)
+			continue
+		}
+		if existing, ok := kbs[name]; ok {
+			logger.Error("Skipping remote knowledge base %s: name %q is already used by %s", rawURL, name, existing)
+			continue
+		}
+		kbs[name] = rawURL
+	}
+	return kbs
+}
+
+// remoteKBCachePaths returns where a remote KB's content and ETag are cached
+func remoteKBCachePaths(name string) (string, string) {
+	dir := config.GetConfigFilePath("kb-cache")
+	return filepath.Join(dir, name), filepath.Join(dir, name+".etag")
+}
+
+// fetchRemoteKB returns a remote knowledge base, revalidating the cached copy
+// with its ETag. If the server can't be reached the cached copy is used.
+func (m *Manager) fetchRemoteKB(name, rawURL string) (string, error) {
+	contentPath, etagPath := remoteKBCachePaths(name)
+	cached, cacheErr := os.ReadFile(contentPath)
+	etag, _ := os.ReadFile(etagPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteKBClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for key, value := range m.Config.KnowledgeBase.RemoteHeaders {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+	if cacheErr == nil && len(etag) > 0 {
+		req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+	}
+
+	resp, err := remoteKBClient.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			logger.Error("Failed to fetch KB %s, using cached copy: %v", rawURL, err)
+			return string(cached), nil
+		}
+		return "", fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cacheErr == nil:
+		logger.Debug("KB %s not modified, using cached copy", rawURL)
+		return string(cached), nil
+	case resp.StatusCode != http.StatusOK:
+		if cacheErr == nil {
+			logger.Error("Fetching KB %s returned %s, using cached copy", rawURL, resp.Status)
+			return string(cached), nil
+		}
+		return "", fmt.Errorf("fetching %s returned %s", rawURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, remoteKBMaxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if len(body) > remoteKBMaxBytes {
+		return "", fmt.Errorf("%s is larger than %d bytes", rawURL, remoteKBMaxBytes)
+	}
+
+	// the content type wins over the URL's extension, which wikis often lack
+	format := path.Ext(req.URL.Path)
+	switch contentType := strings.ToLower(resp.Header.Get("Content-Type")); {
+	case strings.Contains(contentType, "text/html"):
+		format = ".html"
+	case strings.Contains(contentType, "application/pdf"):
+		format = ".pdf"
+	}
+	content, err := normalizeKB(name+format, body)
+	if err != nil {
+		return "", err
+	}
+
+	// caching only speeds up later loads, so failures are just logged
+	if err := os.MkdirAll(filepath.Dir(contentPath), 0o700); err != nil {
+		logger.Error("Failed to create KB cache directory: %v", err)
+		return content, nil
+	}
+	if err := os.WriteFile(contentPath, []byte(content), 0o600); err != nil {
+		logger.Error("Failed to cache KB %s: %v", name, err)
+		return content, nil
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		err = os.WriteFile(etagPath, []byte(etag), 0o600)
+	} else {
+		err = os.Remove(etagPath)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		logger.Error("Failed to cache ETag for KB %s: %v", name, err)
+	}
+	return content, nil
+}
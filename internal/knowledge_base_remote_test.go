@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+)
+
+func TestRemoteKBName(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/runbook.md":          "runbook",
+		"https://wiki.example.com/ops/Deploy/":    "deploy",
+		"http://example.com/kb/on-call.txt?raw=1": "on-call",
+	}
+	for rawURL, want := range tests {
+		got, err := remoteKBName(rawURL)
+		if err != nil || got != want {
+			t.Errorf("remoteKBName(%q) = %q, %v; want %q", rawURL, got, err, want)
+		}
+	}
+	for _, rawURL := range []string{"https://example.com/", "ftp://example.com/a.md", "runbook.md"} {
+		if _, err := remoteKBName(rawURL); err == nil {
+			t.Errorf("remoteKBName(%q) should fail", rawURL)
+		}
+	}
+}
+
+func TestLoadRemoteKBRevalidatesWithETag(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("WIKI_TOKEN", "secret")
+
+	requests := 0
+	var gotIfNoneMatch, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		gotIfNoneMatch = r.Header.Get("If-None-Match")
+		gotAuth = r.Header.Get("Authorization")
+		if gotIfNoneMatch == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("# Runbook\nRestart the service."))
+	}))
+
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Path = t.TempDir()
+	cfg.KnowledgeBase.Remote = []string{server.URL + "/runbook.md"}
+	cfg.KnowledgeBase.RemoteHeaders = map[string]string{"Authorization": "Bearer ${WIKI_TOKEN}"}
+	mgr := &Manager{Config: cfg, LoadedKBs: make(map[string]string)}
+
+	kbs, err := mgr.listKBs()
+	if err != nil || len(kbs) != 1 || kbs[0] != "runbook" {
+		t.Fatalf("listKBs() = %v, %v; want [runbook]", kbs, err)
+	}
+
+	if err := mgr.loadKB("runbook"); err != nil {
+		t.Fatalf("loadKB() failed: %v", err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want expanded header", gotAuth)
+	}
+
+	delete(mgr.LoadedKBs, "runbook")
+	if err := mgr.loadKB("runbook"); err != nil {
+		t.Fatalf("second loadKB() failed: %v", err)
+	}
+	if gotIfNoneMatch != `"v1"` {
+		t.Errorf("If-None-Match = %q, want cached ETag", gotIfNoneMatch)
+	}
+	if !strings.Contains(mgr.LoadedKBs["runbook"], "Restart the service.") {
+		t.Errorf("304 should serve the cached content, got %q", mgr.LoadedKBs["runbook"])
+	}
+
+	// an unreachable server falls back to the cache
+	server.Close()
+	delete(mgr.LoadedKBs, "runbook")
+	if err := mgr.loadKB("runbook"); err != nil {
+		t.Fatalf("loadKB() with server down failed: %v", err)
+	}
+	if requests != 2 || !strings.Contains(mgr.LoadedKBs["runbook"], "Restart the service.") {
+		t.Errorf("expected cached content after 2 requests, got %d requests and %q", requests, mgr.LoadedKBs["runbook"])
+	}
+}
+
+func TestLoadRemoteKBConvertsHTML(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><body><h1>Deploy</h1><p>Run <code>make deploy</code>.</p></body></html>"))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.KnowledgeBase.Path = t.TempDir()
+	cfg.KnowledgeBase.Remote = []string{server.URL + "/wiki/Deploy"}
+	mgr := &Manager{Config: cfg, LoadedKBs: make(map[string]string)}
+
+	if err := mgr.loadKB("deploy"); err != nil {
+		t.Fatalf("loadKB() failed: %v", err)
+	}
+	if got := mgr.LoadedKBs["deploy"]; !strings.Contains(got, "# Deploy") || strings.Contains(got, "<p>") {
+		t.Errorf("HTML should be converted to markdown, got %q", got)
+	}
+}
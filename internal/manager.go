@@ -4,25 +4,36 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/alvinunreal/tmuxai/config"
 	"github.com/alvinunreal/tmuxai/internal/mcp"
 	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/session"
 	"github.com/alvinunreal/tmuxai/system"
-	"github.com/fatih/color"
 )
 
 type AIResponse struct {
-	Message                string
-	SendKeys               []string
-	ExecCommand            []string
-	PasteMultilineContent  string
-	RequestAccomplished    bool
-	ExecPaneSeemsBusy      bool
-	WaitingForUserResponse bool
-	NoComment              bool
-	MCPToolCalls           []mcp.MCPToolCall
+	Message                string            `json:"message"`
+	SendKeys               []string          `json:"send_keys"`
+	ExecCommand            []string          `json:"exec_command"`
+	ExecCommandPanes       []string          `json:"exec_command_panes"`      // named exec pane per ExecCommand, "" for the exec pane
+	ExecCommandTimeouts    []int             `json:"exec_command_timeouts"`   // timeout in seconds per ExecCommand, 0 for exec_timeout
+	ExecCommandBackground  []bool            `json:"exec_command_background"` // whether each ExecCommand starts a background job
+	PasteMultilineContent  string            `json:"paste_multiline_content"`
+	SpawnAgent             []string          `json:"spawn_agent"` // tasks handed to subagents
+	ReadFile               []string          `json:"read_file"`   // paths of files to send back
+	WriteFile              []string          `json:"write_file"`
+	WriteFilePaths         []string          `json:"write_file_paths"` // path per WriteFile
+	ApplyPatch             []string          `json:"apply_patch"`      // unified diffs
+	RequestAccomplished    bool              `json:"request_accomplished"`
+	ExecPaneSeemsBusy      bool              `json:"exec_pane_seems_busy"`
+	WaitingForUserResponse bool              `json:"waiting_for_user_response"`
+	NoComment              bool              `json:"no_comment"`
+	WatchGoalMet           bool              `json:"watch_goal_met"`
+	MCPToolCalls           []mcp.MCPToolCall `json:"-"`
 }
 
 type ManagerOptions struct {
@@ -32,9 +43,12 @@ type ManagerOptions struct {
 
 // Parsed only when pane is prepared
 type CommandExecHistory struct {
-	Command string
-	Output  string
-	Code    int
+	Command  string
+	Output   string
+	Code     int
+	TimedOut bool // ran past its timeout; Output is what it printed by then
+	// why the model stopped it after seeing its output mid-run, "" if it didn't
+	AbortReason string
 }
 
 // Manager represents the TmuxAI manager agent
@@ -47,13 +61,42 @@ type Manager struct {
 	Messages          []ChatMessage
 	ExecHistory       []CommandExecHistory
 	WatchMode         bool
+	watchLastCapture  string            // pane content at the last watch mode check
+	watchPanes        []string          // panes given to /watch; empty watches the whole window
+	watchTrigger      string            // new pane line that matched a watch trigger at the last check
+	watchGoal         string            // condition given to /watch until, which ends watch mode once met
+	watchChanges      func() bool       // whether the watched panes printed since the last check, set by the first one
+	paneSnapshots     map[string]string // pane content sent earlier in the current task, set while one runs with pane_diff
 	OS                string
 	SessionOverrides  map[string]interface{} // session-only config overrides
 	LoadedKBs         map[string]string      // Loaded knowledge bases (name -> content)
+	kbIndexes         map[string]*kbIndex    // search indexes of loaded KBs
+	kbExcerptsSent    string                 // KB excerpts sent with the last request in search mode
+	kbWatcher         *kbWatcher             // watches loaded KB files, started on first load
+	configWatcher     *configWatcher         // watches the config file, with reload_config
+	projectConfigs    []string               // .tmuxai.yaml files applied over the config
+	osc133            *osc133Pipe            // exec pane output read for OSC 133 markers, set by /prepare
+	execHookPane      string                 // exec pane whose shell reports exit codes to execStatusOption
+	control           *system.TmuxControl    // tmux control mode client, with tmux.control_mode
+	stopTelemetry     func()                 // flushes and stops the OTLP exporters, with telemetry.enabled
 	LoadedSkills      map[string]string      // Loaded skill bodies + manifests (name -> content)
 	Skills            *SkillRegistry         // Skill registry (discovery, L1, budget)
 	ForcedExecPaneID  string
 	ForcedReadPaneIDs map[string]bool
+	namedExecPanes    map[string]string        // panes added with /exec-pane add (name -> pane ID)
+	excludedPanes     map[string]bool          // panes left out of the context with /context exclude
+	readOnlyPanes     map[string]bool          // panes marked with /context readonly
+	preparedShells    map[string]preparedShell // exec panes whose shell /prepare changed, undone on release
+	pendingInput      string                   // text /history copy puts into the next input line
+	fixOffered        string                   // failed command last offered to /fix, see fixKey
+	shellContextCache map[string]string        // shell -> summary of its history and aliases, see shellContextPrompt
+	redactionCounts   map[string]int           // secrets masked in context sent this session, per kind
+	redactionPatterns *compiledShapes          // redaction.patterns, compiled
+	systemTemplate    *template.Template       // prompts/system.tmpl in the config dir, nil without one
+	personaKBs        []string                 // KBs the active persona loaded, unloaded when it's switched
+	taskIterations    int                      // AI requests made for the current typed request
+	taskActions       []AuditEntry             // actions taken for the current typed request
+	inTask            bool                     // a typed request is processed, so taskActions collects actions
 
 	SearchEngine *SearchEngine
 
@@ -62,9 +105,39 @@ type Manager struct {
 	McpToolDefCached string
 	mcpDirty         bool
 
+	usage   map[string]*ModelUsage // token usage per model configuration
+	usageMu sync.Mutex
+
+	jobs        []*backgroundJob // commands started with <ExecCommand background="1">
+	nextJobID   int
+	jobsPolling bool // whether pollJobs is running
+	jobsMu      sync.Mutex
+
+	SessionID        string // ID the session is saved under
+	sessionStore     *session.FileStore
+	sessionCreatedAt time.Time
+	sessionTitle     string
+	sessionSummary   *session.SessionSummary
+
+	actionLog      *[]AuditEntry   // actions taken during ExecuteOnce, nil otherwise
+	debugExchanges []debugExchange // last AI requests and responses, for /debug dump
+	busy           sync.Mutex      // held while a request is processed, typed or sent to the API
+	apiRequest     bool            // the request being processed came from the HTTP API
+
+	// stateMu guards writes to the fields GET /status and /transcript read while another
+	// goroutine processes a request: Status, WatchMode, Messages,
+	// ExecHistory, ExecPane, SessionID, SessionOverrides and *Config. Reads
+	// on the processing goroutine don't need it.
+	stateMu sync.Mutex
+
 	// Functions for mocking
 	confirmedToExec   func(command string, prompt string, edit bool) (bool, string)
+	confirmedPlan     func(plan string) (bool, string)
+	confirmedBatch    func(commands []batchCommand) []string
+	confirmedWrite    func(prompt string) bool
+	confirmedContinue func(prompt string) bool
 	getTmuxPanesInXml func(config *config.Config) string
+	notify            func(title, message string)
 }
 
 // NewManager creates a new manager agent
@@ -90,6 +163,15 @@ func NewManager(cfg *config.Config, options ManagerOptions) (*Manager, error) {
 		os.Exit(0)
 	}
 
+	if cfg.Tmux.SSHHost != "" {
+		system.SetTmuxRemote(cfg.Tmux.SSHHost, cfg.Tmux.SSHArgs, cfg.Tmux.RemoteTarget)
+		logger.Info("Driving remote tmux on %s", cfg.Tmux.SSHHost)
+	}
+
+	if err := system.SetTheme(cfg.Theme.Preset, cfg.Theme.Colors); err != nil {
+		logger.Error("Failed to set theme, using the default one: %v", err)
+	}
+
 	aiClient := NewAiClient(cfg)
 	os := system.GetOSDetails()
 
@@ -115,14 +197,32 @@ func NewManager(cfg *config.Config, options ManagerOptions) (*Manager, error) {
 	aiClient.SetConfigManager(manager)
 
 	manager.confirmedToExec = manager.confirmedToExecFn
+	manager.confirmedPlan = manager.confirmedPlanFn
+	manager.confirmedBatch = manager.confirmedBatchFn
+	manager.confirmedWrite = manager.confirmedYesNoFn
+	manager.confirmedContinue = manager.confirmedYesNoFn
 	manager.getTmuxPanesInXml = manager.getTmuxPanesInXmlFn
+	manager.notify = system.Notify
 
 	if err := manager.InitExecPane(); err != nil {
 		return nil, err
 	}
 
-	// Auto-load knowledge bases from config
+	manager.startControlMode()
+	manager.stopTelemetry = startTelemetry(cfg.Telemetry)
+
+	// Project settings come first, they may add KBs to auto-load
+	manager.applyProjectConfig()
+
+	// Auto-load knowledge bases from config and the project
 	manager.autoLoadKBs()
+	manager.autoLoadProjectKBs()
+
+	if name := manager.Config.Persona; name != "" {
+		if err := manager.SwitchPersona(name); err != nil {
+			manager.Println(fmt.Sprintf("Warning: %v", err))
+		}
+	}
 
 	// Initialize skill registry if enabled
 	if manager.Config.KnowledgeBase.Skills.Enabled {
@@ -139,6 +239,14 @@ func NewManager(cfg *config.Config, options ManagerOptions) (*Manager, error) {
 
 	manager.initMCP()
 
+	manager.initSessions()
+
+	if _, err := manager.loadSystemTemplate(); err != nil {
+		manager.Println(fmt.Sprintf("Failed to load %s, using the built-in system prompt: %v", systemTemplateFile, err))
+	}
+
+	manager.watchConfig()
+
 	return manager, nil
 }
 
@@ -171,10 +279,8 @@ func (m *Manager) GetPrompt() string {
 		return m.renderPromptTemplate(template)
 	}
 
-	tmuxaiColor := color.New(color.FgGreen, color.Bold)
-	arrowColor := color.New(color.FgYellow, color.Bold)
-	stateColor := color.New(color.FgMagenta, color.Bold)
-	modelColor := color.New(color.FgCyan, color.Bold)
+	theme := system.CurrentTheme()
+	tmuxaiColor, arrowColor, stateColor, modelColor := theme.Prompt, theme.Arrow, theme.State, theme.Model
 
 	var stateSymbol string
 	switch m.Status {
@@ -197,11 +303,8 @@ func (m *Manager) GetPrompt() string {
 	currentModel := m.GetModelsDefault()
 	availableModels := m.GetAvailableModels()
 	if len(availableModels) > 0 {
-		// Get the "expected" model (configured default or first available)
-		expectedModel := m.Config.DefaultModel
-		if expectedModel == "" && len(availableModels) > 0 {
-			expectedModel = availableModels[0] // First model as default
-		}
+		// Get the "expected" model (chat role, configured default or first available)
+		expectedModel := m.configuredModel()
 
 		// Show model if current is different from expected
 		if currentModel != "" && currentModel != expectedModel {
@@ -217,9 +320,8 @@ func (m *Manager) GetPrompt() string {
 }
 
 func (m *Manager) renderPromptTemplate(template string) string {
-	tmuxaiColor := color.New(color.FgGreen, color.Bold)
-	stateColor := color.New(color.FgMagenta, color.Bold)
-	modelColor := color.New(color.FgCyan, color.Bold)
+	theme := system.CurrentTheme()
+	tmuxaiColor, stateColor, modelColor := theme.Prompt, theme.State, theme.Model
 
 	replacements := map[string]string{}
 
@@ -295,12 +397,66 @@ func (m *Manager) getPromptStateSymbol() string {
 
 func (m *Manager) getPromptContextTokens() (int, int) {
 	totalTokens := 0
+	tokenizer := m.tokenizer()
 	for _, msg := range m.Messages {
-		totalTokens += system.EstimateTokenCount(msg.Content)
+		totalTokens += tokenizer.Count(msg.Content)
 	}
 	return totalTokens, m.GetMaxContextSize()
 }
 
+// tokenizer returns the tokenizer matching the current model
+func (m *Manager) tokenizer() system.Tokenizer {
+	modelConfig, _ := m.GetCurrentModelConfig()
+	return system.TokenizerFor(modelConfig.Provider, modelConfig.Model)
+}
+
+// countTokens counts text with the current model's tokenizer
+func (m *Manager) countTokens(text string) int {
+	return m.tokenizer().Count(text)
+}
+
+func (m *Manager) setStatus(status string) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.Status = status
+}
+
+func (m *Manager) setWatchMode(on bool) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.WatchMode = on
+}
+
+func (m *Manager) setExecPane(pane *system.TmuxPaneDetails) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.ExecPane = pane
+}
+
+func (m *Manager) appendMessages(messages ...ChatMessage) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.Messages = append(m.Messages, messages...)
+}
+
+func (m *Manager) setMessages(messages []ChatMessage) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.Messages = messages
+}
+
+func (m *Manager) setOverride(key string, value interface{}) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.SessionOverrides[key] = value
+}
+
+func (m *Manager) deleteOverride(key string) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	delete(m.SessionOverrides, key)
+}
+
 func (m *Manager) getPromptModelName() string {
 	availableModels := m.GetAvailableModels()
 	if len(availableModels) > 0 {
@@ -318,10 +474,7 @@ func (m *Manager) getPromptChangedModelName() string {
 		return ""
 	}
 
-	expectedModel := m.Config.DefaultModel
-	if expectedModel == "" {
-		expectedModel = availableModels[0]
-	}
+	expectedModel := m.configuredModel()
 	if currentModel != "" && currentModel != expectedModel {
 		return currentModel
 	}
@@ -335,26 +488,66 @@ func formatPromptTokenCount(tokens int) string {
 	return fmt.Sprintf("%d", tokens)
 }
 
+// execCommandPane returns the named exec pane the i-th ExecCommand runs in,
+// or "" for the exec pane
+func (ai *AIResponse) execCommandPane(i int) string {
+	if i < len(ai.ExecCommandPanes) {
+		return ai.ExecCommandPanes[i]
+	}
+	return ""
+}
+
+// execCommandTimeout returns the timeout the model gave the i-th
+// ExecCommand, or 0 if it didn't
+func (ai *AIResponse) execCommandTimeout(i int) time.Duration {
+	if i < len(ai.ExecCommandTimeouts) && ai.ExecCommandTimeouts[i] > 0 {
+		return time.Duration(ai.ExecCommandTimeouts[i]) * time.Second
+	}
+	return 0
+}
+
+// writeFilePath returns the path of the i-th WriteFile, "" if it has none
+func (ai *AIResponse) writeFilePath(i int) string {
+	if i < len(ai.WriteFilePaths) {
+		return ai.WriteFilePaths[i]
+	}
+	return ""
+}
+
+// execCommandBackground reports whether the i-th ExecCommand starts a
+// background job
+func (ai *AIResponse) execCommandBackground(i int) bool {
+	return i < len(ai.ExecCommandBackground) && ai.ExecCommandBackground[i]
+}
+
 func (ai *AIResponse) String() string {
 	return fmt.Sprintf(`
 	Message: %s
 	SendKeys: %v
 	ExecCommand: %v
+	ExecCommandPanes: %v
+	ExecCommandTimeouts: %v
+	ExecCommandBackground: %v
 	PasteMultilineContent: %s
 	RequestAccomplished: %v
 	ExecPaneSeemsBusy: %v
 	WaitingForUserResponse: %v
 	NoComment: %v
+	WatchGoalMet: %v
 	MCPToolCalls: %d
 `,
 		ai.Message,
 		ai.SendKeys,
 		ai.ExecCommand,
+		ai.ExecCommandPanes,
+		ai.ExecCommandTimeouts,
+		ai.ExecCommandBackground,
 		ai.PasteMultilineContent,
 		ai.RequestAccomplished,
 		ai.ExecPaneSeemsBusy,
 		ai.WaitingForUserResponse,
 		ai.NoComment,
+		ai.WatchGoalMet,
 		len(ai.MCPToolCalls),
 	)
 }
@@ -454,6 +647,21 @@ func (m *Manager) Cleanup() {
 		m.McpManager = nil
 		m.McpRegistry = nil
 	}
+	if m.kbWatcher != nil {
+		m.kbWatcher.close()
+		m.kbWatcher = nil
+	}
+	if m.configWatcher != nil {
+		m.configWatcher.close()
+		m.configWatcher = nil
+	}
+	m.stopOSC133()
+	m.restoreShells()
+	m.stopControlMode()
+	if m.stopTelemetry != nil {
+		m.stopTelemetry()
+		m.stopTelemetry = nil
+	}
 }
 
 func (m *Manager) ensureMcpToolDefs() string {
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// paneServer exposes tmux panes as MCP tools. It reuses the manager's
+// config getters and whitelist so MCP clients go through the same safety
+// rules as the interactive agent. Since stdio is owned by the MCP
+// transport, confirmations are requested from the client via elicitation.
+type paneServer struct {
+	m *Manager
+}
+
+type capturePaneInput struct {
+	PaneID string `json:"pane_id" jsonschema:"tmux pane ID, e.g. %3"`
+	Lines  int    `json:"lines,omitempty" jsonschema:"number of history lines to capture (defaults to max_capture_lines)"`
+}
+
+type execCommandInput struct {
+	PaneID  string `json:"pane_id" jsonschema:"tmux pane ID, e.g. %3"`
+	Command string `json:"command" jsonschema:"shell command to run in the pane"`
+}
+
+type sendKeysInput struct {
+	PaneID string `json:"pane_id" jsonschema:"tmux pane ID, e.g. %3"`
+	Keys   string `json:"keys" jsonschema:"keys to send; supports tmux key names such as Enter, C-c, Escape"`
+}
+
+type paneOutput struct {
+	Content string `json:"content"`
+}
+
+// NewPaneMCPServer builds the MCP server used by `tmuxai mcp-serve`
+func NewPaneMCPServer(cfg *config.Config) *mcpsdk.Server {
+	ps := &paneServer{m: &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}}
+
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "tmuxai", Version: Version}, nil)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "capture_pane",
+		Description: "Capture the visible content and recent history of a tmux pane.",
+	}, ps.capturePane)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "exec_command",
+		Description: "Run a shell command in a tmux pane and return the pane content afterwards. Commands may require user confirmation.",
+	}, ps.execCommand)
+	mcpsdk.AddTool(server, &mcpsdk.Tool{
+		Name:        "send_keys",
+		Description: "Send keystrokes to a tmux pane without pressing Enter. Keys may require user confirmation.",
+	}, ps.sendKeys)
+	return server
+}
+
+// ServeMCP runs the pane MCP server over stdio until the client disconnects
+func ServeMCP(ctx context.Context, cfg *config.Config) error {
+	return NewPaneMCPServer(cfg).Run(ctx, &mcpsdk.StdioTransport{})
+}
+
+func (ps *paneServer) capturePane(ctx context.Context, req *mcpsdk.CallToolRequest, in capturePaneInput) (*mcpsdk.CallToolResult, paneOutput, error) {
+	if in.PaneID == "" {
+		return nil, paneOutput{}, fmt.Errorf("pane_id is required")
+	}
+	if ps.m.paneIDExcluded(in.PaneID) {
+		return nil, paneOutput{}, fmt.Errorf("pane %s is excluded from the context", in.PaneID)
+	}
+	lines := in.Lines
+	if lines <= 0 {
+		lines = ps.m.GetPaneCaptureLines(in.PaneID)
+	}
+	content, err := system.TmuxCapturePane(in.PaneID, lines)
+	if err != nil {
+		return nil, paneOutput{}, fmt.Errorf("failed to capture pane %s: %w", in.PaneID, err)
+	}
+	// the client's model gets the same redacted content as ours
+	return nil, paneOutput{Content: ps.m.redactContext(content)}, nil
+}
+
+func (ps *paneServer) execCommand(ctx context.Context, req *mcpsdk.CallToolRequest, in execCommandInput) (*mcpsdk.CallToolResult, paneOutput, error) {
+	if in.PaneID == "" || in.Command == "" {
+		return nil, paneOutput{}, fmt.Errorf("pane_id and command are required")
+	}
+	if ps.m.paneIDReadOnly(in.PaneID) {
+		ps.m.audit("exec_command", in.PaneID, in.Command, auditReadOnly, nil)
+		return nil, paneOutput{}, fmt.Errorf("pane %s is read-only", in.PaneID)
+	}
+
+	decision := auditAuto
+	if ps.m.needsExecConfirm(in.Command) {
+		whitelisted, _ := ps.m.whitelistCheck(in.Command)
+		decision = auditWhitelisted
+		if !whitelisted {
+			assessment := ps.m.assessCommand(in.Command)
+			msg := fmt.Sprintf("Execute this command in pane %s?\n\n%s\n\nRisk: %s", in.PaneID, in.Command, assessment.Level)
+			if err := ps.confirm(ctx, req, msg); err != nil {
+				ps.m.audit("exec_command", in.PaneID, in.Command, auditDeclined, nil)
+				return nil, paneOutput{}, err
+			}
+			decision = auditApproved
+		}
+	}
+
+	if allowed, reason := ps.m.preExecAllowed(in.PaneID, in.Command, decision); !allowed {
+		ps.m.audit("exec_command", in.PaneID, in.Command, auditHookBlocked, nil)
+		return nil, paneOutput{}, fmt.Errorf("blocked by the pre_exec hook: %s", reason)
+	}
+
+	logger.Info("mcp-serve: executing command in pane %s: %s", in.PaneID, in.Command)
+	if err := system.TmuxSendCommandToPane(in.PaneID, in.Command, true); err != nil {
+		return nil, paneOutput{}, fmt.Errorf("failed to send command to pane %s: %w", in.PaneID, err)
+	}
+	ps.m.audit("exec_command", in.PaneID, in.Command, decision, nil)
+	time.Sleep(1 * time.Second)
+
+	return ps.capturePane(ctx, req, capturePaneInput{PaneID: in.PaneID})
+}
+
+func (ps *paneServer) sendKeys(ctx context.Context, req *mcpsdk.CallToolRequest, in sendKeysInput) (*mcpsdk.CallToolResult, paneOutput, error) {
+	if in.PaneID == "" || in.Keys == "" {
+		return nil, paneOutput{}, fmt.Errorf("pane_id and keys are required")
+	}
+	if ps.m.paneIDReadOnly(in.PaneID) {
+		ps.m.audit("send_keys", in.PaneID, in.Keys, auditReadOnly, nil)
+		return nil, paneOutput{}, fmt.Errorf("pane %s is read-only", in.PaneID)
+	}
+
+	decision := auditAuto
+	if ps.m.GetSendKeysConfirm() {
+		msg := fmt.Sprintf("Send these keys to pane %s?\n\n%s", in.PaneID, in.Keys)
+		if err := ps.confirm(ctx, req, msg); err != nil {
+			ps.m.audit("send_keys", in.PaneID, in.Keys, auditDeclined, nil)
+			return nil, paneOutput{}, err
+		}
+		decision = auditApproved
+	}
+
+	logger.Info("mcp-serve: sending keys to pane %s: %s", in.PaneID, in.Keys)
+	if err := system.TmuxTypeToPane(in.PaneID, in.Keys, ps.m.GetTypingDelays()); err != nil {
+		return nil, paneOutput{}, fmt.Errorf("failed to send keys to pane %s: %w", in.PaneID, err)
+	}
+	ps.m.audit("send_keys", in.PaneID, in.Keys, decision, nil)
+	time.Sleep(1 * time.Second)
+
+	return ps.capturePane(ctx, req, capturePaneInput{PaneID: in.PaneID})
+}
+
+// confirm asks the MCP client's user to approve an action. Clients without
+// elicitation support can't confirm, so the action is refused; users can
+// whitelist commands or disable confirmations in config.yaml instead.
+func (ps *paneServer) confirm(ctx context.Context, req *mcpsdk.CallToolRequest, message string) error {
+	res, err := req.Session.Elicit(ctx, &mcpsdk.ElicitParams{
+		Message:         message,
+		RequestedSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+	})
+	if err != nil {
+		logger.Info("mcp-serve: confirmation unavailable: %v", err)
+		return fmt.Errorf("action requires confirmation but the client could not ask the user (%v); whitelist it or disable confirmation in tmuxai config", err)
+	}
+	if res.Action != "accept" {
+		return fmt.Errorf("user declined the action")
+	}
+	return nil
+}
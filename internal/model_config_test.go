@@ -177,6 +177,31 @@ func TestModelConfigurationWithSessionOverrides(t *testing.T) {
 	assert.Equal(t, "claude-3.5-sonnet", modelConfig.Model)
 }
 
+func TestChatRoleSelectsModel(t *testing.T) {
+	manager := &Manager{
+		Config: &config.Config{
+			DefaultModel: "fast",
+			Roles:        config.RolesConfig{Chat: "smart"},
+			Models: map[string]config.ModelConfig{
+				"fast":  {Provider: "openai", Model: "gpt-5-mini"},
+				"smart": {Provider: "openai", Model: "gpt-5"},
+			},
+		},
+		SessionOverrides: make(map[string]interface{}),
+	}
+
+	assert.Equal(t, "smart", manager.GetModelsDefault(), "the chat role takes precedence over default_model")
+	assert.Empty(t, manager.getPromptChangedModelName(), "the chat role counts as the configured model")
+
+	manager.switchModel("fast")
+	assert.Equal(t, "fast", manager.GetModelsDefault(), "/model still switches")
+	assert.Equal(t, "fast", manager.getPromptChangedModelName())
+
+	delete(manager.SessionOverrides, "default_model")
+	manager.Config.Roles.Chat = "missing"
+	assert.Equal(t, "fast", manager.GetModelsDefault(), "an unknown chat role is ignored")
+}
+
 func TestGetModel(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -338,6 +363,33 @@ func TestGitHubCopilotProvider(t *testing.T) {
 		apiType := aiClient.determineAPIType("gpt-4o")
 		assert.Equal(t, "github-copilot", apiType)
 	})
+
+	t.Run("enterprise host and proxy reach the copilot CLI", func(t *testing.T) {
+		opts := copilotClientOptions(config.ModelConfig{
+			Provider: "github-copilot",
+			APIKey:   "ghu_sometoken",
+			BaseURL:  "https://acme.ghe.com/",
+			Proxy:    "http://proxy.acme.com:3128",
+		})
+		assert.Equal(t, "ghu_sometoken", opts.GitHubToken)
+		assert.Empty(t, opts.CLIUrl)
+		assert.Subset(t, opts.Env, []string{"GH_HOST=acme.ghe.com", "HTTPS_PROXY=http://proxy.acme.com:3128", "HTTP_PROXY=http://proxy.acme.com:3128"})
+
+		assert.Nil(t, copilotClientOptions(config.ModelConfig{Provider: "github-copilot"}).Env, "the environment is inherited as is")
+	})
+
+	t.Run("copilot_cli_url connects to a running server", func(t *testing.T) {
+		opts := copilotClientOptions(config.ModelConfig{
+			Provider:      "github-copilot",
+			APIKey:        "ghu_sometoken",
+			Proxy:         "http://proxy.acme.com:3128",
+			CopilotCLIURL: "localhost:3000",
+		})
+		assert.Equal(t, "localhost:3000", opts.CLIUrl)
+		// the SDK rejects a token together with CLIUrl
+		assert.Empty(t, opts.GitHubToken)
+		assert.Nil(t, opts.Env)
+	})
 }
 
 func TestLegacyModelConfig(t *testing.T) {
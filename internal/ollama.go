@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaChatRequest represents a request to the Ollama /api/chat endpoint
+type OllamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []OllamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// OllamaMessage is a chat message as /api/chat takes it, images as a list
+// of base64 strings beside the text
+type OllamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// OllamaChatChunk represents one line of the Ollama /api/chat NDJSON stream.
+// Non-streaming responses are a single chunk with Done set.
+type OllamaChatChunk struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error,omitempty"`
+
+	// Token counts, only present on the final chunk
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// ollamaChatURL builds the native chat endpoint. Base URLs copied from an
+// OpenAI-compatible setup (ending in /v1) are accepted as well.
+func ollamaChatURL(baseURL string) string {
+	base := strings.TrimSuffix(baseURL, "/")
+	base = strings.TrimSuffix(base, "/v1")
+	base = strings.TrimSuffix(base, "/api")
+	if base == "" {
+		base = defaultOllamaBaseURL
+	}
+	return base + "/api/chat"
+}
+
+// OllamaChat sends messages to a local Ollama server and assembles the
+// streamed reply.
+func (c *AiClient) OllamaChat(ctx context.Context, messages []Message, model string, modelCfg config.ModelConfig) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no messages provided")
+	}
+
+	reqBody := OllamaChatRequest{
+		Model:    model,
+		Messages: make([]OllamaMessage, 0, len(messages)),
+		Stream:   true,
+	}
+	for _, msg := range messages {
+		reqBody.Messages = append(reqBody.Messages, OllamaMessage{Role: msg.Role, Content: msg.Content, Images: msg.Images})
+	}
+	options := map[string]interface{}{}
+	if modelCfg.MaxTokens > 0 {
+		options["num_predict"] = modelCfg.MaxTokens
+	}
+	if modelCfg.Temperature > 0 {
+		options["temperature"] = modelCfg.Temperature
+	}
+	if len(options) > 0 {
+		reqBody.Options = options
+	}
+
+	reqJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		logger.Error("Failed to marshal Ollama request: %v", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := ollamaChatURL(modelCfg.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqJSON))
+	if err != nil {
+		logger.Error("Failed to create Ollama request: %v", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	// Ollama itself needs no key, but it is often fronted by an auth proxy
+	if modelCfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+modelCfg.APIKey)
+	}
+
+	logger.Debug("Sending Ollama chat request to: %s with model: %s", url, model)
+
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return "", fmt.Errorf("request canceled: %w", ctx.Err())
+		}
+		logger.Error("Failed to send Ollama request: %v", err)
+		return "", fmt.Errorf("failed to send request (is ollama running at %s?): %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		logger.Error("Ollama API returned error: %s", body)
+		return "", fmt.Errorf("API returned error: %s", body)
+	}
+
+	response, usage, err := readOllamaStream(resp.Body, streamHandlerFromCtx(ctx))
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return "", fmt.Errorf("request canceled: %w", ctx.Err())
+		}
+		logger.Error("Failed to read Ollama stream: %v", err)
+		return "", err
+	}
+
+	if response == "" {
+		return "", fmt.Errorf("ollama returned empty response (model: %s)", model)
+	}
+	c.recordUsage(ctx, usage)
+
+	logger.Debug("Received Ollama response (%d characters): %s", len(response), response)
+	return response, nil
+}
+
+// readOllamaStream concatenates message content from an NDJSON stream until
+// a chunk reports done, forwarding each piece to onDelta when set.
+func readOllamaStream(r io.Reader, onDelta StreamHandler) (string, TokenUsage, error) {
+	var sb strings.Builder
+	var usage TokenUsage
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk OllamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return "", usage, fmt.Errorf("failed to unmarshal response chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", usage, fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+
+		sb.WriteString(chunk.Message.Content)
+		if onDelta != nil && chunk.Message.Content != "" {
+			onDelta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			usage = TokenUsage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", usage, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return sb.String(), usage, nil
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaChatStreaming(t *testing.T) {
+	var got OllamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"model":"gemma3:1b","message":{"role":"assistant","content":"Hello"},"done":false}
+{"model":"gemma3:1b","message":{"role":"assistant","content":" world"},"done":false}
+{"model":"gemma3:1b","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}
+`))
+	}))
+	defer server.Close()
+
+	client := NewAiClient(&config.Config{})
+	resp, err := client.OllamaChat(context.Background(), []Message{{Role: "user", Content: "hi"}}, "gemma3:1b", config.ModelConfig{
+		Provider:    "ollama",
+		BaseURL:     server.URL + "/v1",
+		Temperature: 0.2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", resp)
+	assert.True(t, got.Stream)
+	assert.Equal(t, "gemma3:1b", got.Model)
+	assert.InDelta(t, 0.2, got.Options["temperature"], 0.001)
+}
+
+func TestReadOllamaStreamError(t *testing.T) {
+	_, _, err := readOllamaStream(strings.NewReader(`{"error":"model 'missing' not found"}`), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestOllamaChatURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:11434/api/chat", ollamaChatURL(""))
+	assert.Equal(t, "http://gpu-box:11434/api/chat", ollamaChatURL("http://gpu-box:11434/"))
+	assert.Equal(t, "http://gpu-box:11434/api/chat", ollamaChatURL("http://gpu-box:11434/v1"))
+}
+
+func TestOllamaProviderIsKeyless(t *testing.T) {
+	cfg := &config.Config{
+		DefaultModel: "local",
+		Models: map[string]config.ModelConfig{
+			"local": {Provider: "ollama", Model: "llama3.2"},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	assert.True(t, manager.hasValidAIConfiguration())
+
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+	assert.Equal(t, "ollama", client.determineAPIType("llama3.2"))
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// OnceResult is what a one-shot request did, as printed by tmuxai --json
+type OnceResult struct {
+	Message          string                    `json:"message"`
+	Accomplished     bool                      `json:"request_accomplished"`
+	ProposedCommands []string                  `json:"proposed_commands"`
+	ExecutedCommands []OnceCommand             `json:"executed_commands"`
+	Usage            map[string]OnceModelUsage `json:"usage"`
+}
+
+// OnceCommand is a command the AI proposed and TmuxAI acted on
+type OnceCommand struct {
+	Command  string `json:"command"`
+	PaneID   string `json:"pane_id"`
+	Decision string `json:"decision"`  // as in the audit log: auto, approved, declined, dry_run...
+	ExitCode *int   `json:"exit_code"` // only known for prepared panes and the sandbox
+	Output   string `json:"output,omitempty"`
+}
+
+// OnceModelUsage is the token usage of one model configuration
+type OnceModelUsage struct {
+	Requests         int      `json:"requests"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Cost             *float64 `json:"cost,omitempty"` // only when the model has pricing configured
+}
+
+// ExecuteOnce processes a single request, follow-ups included, and reports
+// what was proposed and run instead of starting the chat. Ctrl+C stops the
+// request the way it does in the chat.
+func (m *Manager) ExecuteOnce(message string) OnceResult {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-sigChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	m.busy.Lock()
+	defer m.busy.Unlock()
+	return m.processOnce(ctx, message)
+}
+
+// processOnce processes a request and records what it did. The caller holds
+// m.busy.
+func (m *Manager) processOnce(ctx context.Context, message string) OnceResult {
+	var actions []AuditEntry
+	m.actionLog = &actions
+	defer func() { m.actionLog = nil }()
+	start := len(m.Messages)
+	if m.sessionTitle == "" {
+		m.sessionTitle = message
+	}
+
+	m.setStatus("running")
+	accomplished := m.ProcessUserMessage(ctxWithTypedInput(ctx), message)
+	m.setStatus("")
+
+	return m.onceResult(m.Messages[start:], actions, accomplished)
+}
+
+// onceResult sums up the AI replies and actions of a one-shot request
+func (m *Manager) onceResult(messages []ChatMessage, actions []AuditEntry, accomplished bool) OnceResult {
+	result := OnceResult{
+		Accomplished:     accomplished,
+		ProposedCommands: []string{},
+		ExecutedCommands: []OnceCommand{},
+		Usage:            map[string]OnceModelUsage{},
+	}
+
+	var replies []string
+	for _, msg := range messages {
+		if msg.FromUser {
+			continue
+		}
+		r, err := m.parseAIResponse(msg.Content)
+		if err != nil {
+			r = AIResponse{Message: msg.Content}
+		}
+		if text := strings.TrimSpace(r.Message); text != "" {
+			replies = append(replies, text)
+		}
+		result.ProposedCommands = append(result.ProposedCommands, r.ExecCommand...)
+	}
+	result.Message = strings.Join(replies, "\n\n")
+
+	for _, action := range actions {
+		if action.Action != "exec_command" {
+			continue
+		}
+		command := OnceCommand{
+			Command:  action.Command,
+			PaneID:   action.PaneID,
+			Decision: action.Decision,
+			ExitCode: action.ExitCode,
+		}
+		if action.ExitCode != nil && !strings.HasPrefix(action.PaneID, "sandbox:") {
+			command.Output = m.execOutput(action.Command)
+		}
+		result.ExecutedCommands = append(result.ExecutedCommands, command)
+	}
+
+	for name, u := range m.GetUsage() {
+		usage := OnceModelUsage{Requests: u.Requests, PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+		if u.Priced {
+			cost := u.Cost
+			usage.Cost = &cost
+		}
+		result.Usage[name] = usage
+	}
+	return result
+}
+
+// execOutput returns the output of the latest run of command in the exec
+// pane history, "" if it isn't there
+func (m *Manager) execOutput(command string) string {
+	for i := len(m.ExecHistory) - 1; i >= 0; i-- {
+		if m.ExecHistory[i].Command == command {
+			return strings.TrimRight(m.ExecHistory[i].Output, "\n")
+		}
+	}
+	return ""
+}
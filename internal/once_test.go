@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnceResult(t *testing.T) {
+	m := historyTestManager()
+	m.recordUsage(TokenUsage{PromptTokens: 120, CompletionTokens: 30})
+
+	var actions []AuditEntry
+	m.actionLog = &actions
+	code := 2
+	m.audit("exec_command", "%1", "make test", auditApproved, &code)
+	m.audit("send_keys", "%1", "q", auditAuto, nil)
+	m.audit("exec_command", "%1", "make lint", auditDeclined, nil)
+	require.Len(t, actions, 3, "actions are recorded with the audit log off")
+
+	result := m.onceResult(m.Messages[1:], actions, false)
+	assert.Equal(t, "Building.", result.Message)
+	assert.Equal(t, []string{"make build", "make test", "make lint"}, result.ProposedCommands)
+	require.Len(t, result.ExecutedCommands, 2, "only commands are listed")
+	assert.Equal(t, OnceCommand{Command: "make test", PaneID: "%1", Decision: auditApproved, ExitCode: &code}, result.ExecutedCommands[0])
+	assert.Equal(t, auditDeclined, result.ExecutedCommands[1].Decision)
+	assert.Nil(t, result.ExecutedCommands[1].ExitCode)
+
+	require.Len(t, result.Usage, 1)
+	for _, usage := range result.Usage {
+		assert.Equal(t, OnceModelUsage{Requests: 1, PromptTokens: 120, CompletionTokens: 30}, usage)
+	}
+}
@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+const (
+	promptDetectionOSC133 = "osc133"
+	osc133PrepareTimeout  = 2 * time.Second
+)
+
+var (
+	// OSC 133 shell integration markers: A prompt start, B command start,
+	// C output start, D command finished with an optional exit code
+	osc133MarkerRe = regexp.MustCompile(`\x1b\]133;([ABCD])(?:;([^\x07\x1b]*))?(?:\x07|\x1b\\)`)
+	// OSC strings, CSI sequences and other two-byte escapes
+	terminalEscapeRe = regexp.MustCompile(`\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b\[[0-?]*[ -/]*[@-~]|\x1b[@-Z\\-_]`)
+)
+
+// osc133Pipe is the exec pane's raw output, piped to a file so the OSC 133
+// markers tmux keeps out of capture-pane can be read
+type osc133Pipe struct {
+	paneID string
+	path   string
+}
+
+func (p *osc133Pipe) read() string {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		logger.Error("Failed to read OSC 133 log %s: %v", p.path, err)
+		return ""
+	}
+	return string(data)
+}
+
+// parseOSC133 returns the commands that finished in a pane's raw output and
+// whether the shell printed any prompt markers at all
+func parseOSC133(raw string) (history []CommandExecHistory, promptSeen bool) {
+	var current *CommandExecHistory
+	cmdStart, outStart := -1, -1
+
+	for _, match := range osc133MarkerRe.FindAllStringSubmatchIndex(raw, -1) {
+		start, end := match[0], match[1]
+		switch raw[match[2]:match[3]] {
+		case "A":
+			promptSeen = true
+		case "B":
+			current = &CommandExecHistory{Code: -1}
+			cmdStart, outStart = end, -1
+		case "C":
+			if current != nil {
+				current.Command = cleanTerminalText(raw[cmdStart:start])
+				outStart = end
+			}
+		case "D":
+			promptSeen = true
+			if current == nil {
+				continue
+			}
+			if outStart < 0 {
+				// without a C marker the command line is the first line of the output
+				text := cleanTerminalText(raw[cmdStart:start])
+				current.Command, current.Output, _ = strings.Cut(text, "\n")
+				current.Output = strings.TrimSpace(current.Output)
+			} else {
+				current.Output = cleanTerminalText(raw[outStart:start])
+			}
+			if match[4] >= 0 {
+				if code, err := strconv.Atoi(strings.TrimSpace(raw[match[4]:match[5]])); err == nil {
+					current.Code = code
+				}
+			}
+			// an empty Enter finishes without a command
+			if current.Command != "" {
+				history = append(history, *current)
+			}
+			current = nil
+		}
+	}
+	return history, promptSeen
+}
+
+// cleanTerminalText renders raw terminal output as plain text: escape
+// sequences are dropped, carriage returns and backspaces overwrite
+func cleanTerminalText(raw string) string {
+	raw = terminalEscapeRe.ReplaceAllString(raw, "")
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndex(line, "\r"); j >= 0 {
+			line = line[j+1:]
+		}
+		var out []rune
+		for _, r := range line {
+			switch {
+			case r == '\b':
+				if len(out) > 0 {
+					out = out[:len(out)-1]
+				}
+			case r == '\t' || r >= ' ':
+				out = append(out, r)
+			}
+		}
+		lines[i] = strings.TrimRight(string(out), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// osc133Prepared reports whether a pane's commands are tracked by OSC 133
+// markers instead of the rewritten prompt
+func (m *Manager) osc133Prepared(paneID string) bool {
+	return m.osc133 != nil && m.osc133.paneID == paneID
+}
+
+// prepareOSC133 starts piping the exec pane's output and checks that its
+// shell emits OSC 133 markers, leaving the prompt untouched
+func (m *Manager) prepareOSC133() error {
+	// the pipe's file would be written on the remote host
+	if system.TmuxIsRemote() {
+		return fmt.Errorf("not supported with tmux.ssh_host")
+	}
+	paneID := m.ExecPane.Id
+	if m.osc133Prepared(paneID) {
+		return nil
+	}
+	m.stopOSC133()
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("tmuxai-osc133-%d-%s.log", os.Getpid(), strings.TrimPrefix(paneID, "%")))
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		return fmt.Errorf("failed to create OSC 133 log: %w", err)
+	}
+	if err := system.TmuxPipePane(paneID, path); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	pipe := &osc133Pipe{paneID: paneID, path: path}
+
+	// a fresh prompt makes the shell print its markers
+	_ = system.TmuxSendCommandToPane(paneID, "Enter", false)
+	deadline := time.Now().Add(osc133PrepareTimeout)
+	for {
+		if _, promptSeen := parseOSC133(pipe.read()); promptSeen {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = system.TmuxPipePane(paneID, "")
+			_ = os.Remove(path)
+			return fmt.Errorf("no OSC 133 markers from pane %s; enable your shell's integration or set tmux.prompt_detection to ps1", paneID)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	m.osc133 = pipe
+	m.execHookPane = ""
+	m.ExecPane.IsPrepared = true
+	logger.Info("Exec pane %s prepared with OSC 133 markers, log %s", paneID, path)
+	return nil
+}
+
+// stopOSC133 closes the exec pane's pipe and removes its log
+func (m *Manager) stopOSC133() {
+	if m.osc133 == nil {
+		return
+	}
+	if err := system.TmuxPipePane(m.osc133.paneID, ""); err != nil {
+		logger.Error("Failed to close OSC 133 pipe: %v", err)
+	}
+	_ = os.Remove(m.osc133.path)
+	m.osc133 = nil
+}
+
+// execFinished returns a check for the command about to be sent to the exec
+// pane having finished: a new OSC 133 D marker, or the prepared prompt
+func (m *Manager) execFinished() func() bool {
+	if m.osc133Prepared(m.ExecPane.Id) {
+		pipe := m.osc133
+		before, _ := parseOSC133(pipe.read())
+		return func() bool {
+			history, _ := parseOSC133(pipe.read())
+			return len(history) > len(before)
+		}
+	}
+	return func() bool { return strings.HasSuffix(m.ExecPane.LastLine, "]»") }
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oscPrompt = "\x1b]133;A\x07\x1b[1;34m~/src\x1b[0m ❯ \x1b]133;B\x07"
+	oscOutput = "\x1b]133;C\x07"
+)
+
+func oscFinished(code string) string {
+	return "\x1b]133;D;" + code + "\x1b\\"
+}
+
+func TestParseOSC133(t *testing.T) {
+	raw := "\x1b]133;D\x07" + oscPrompt + // first prompt, nothing ran yet
+		"ls\r\n" + oscOutput + "go.mod\r\nmain.go\r\n" + oscFinished("0") +
+		oscPrompt + "\r\n" + oscFinished("0") + // empty Enter
+		oscPrompt + "gti\b\bit status\r\n" + oscOutput + "fatal: not a git repository\r\n" + oscFinished("128") +
+		oscPrompt + "sleep 100"
+
+	history, promptSeen := parseOSC133(raw)
+	assert.True(t, promptSeen)
+	assert.Equal(t, []CommandExecHistory{
+		{Command: "ls", Output: "go.mod\nmain.go", Code: 0},
+		{Command: "git status", Output: "fatal: not a git repository", Code: 128},
+	}, history, "running commands aren't finished yet")
+
+	// shells without preexec hooks emit no C marker
+	history, _ = parseOSC133(oscPrompt + "echo hi\r\nhi\r\n" + oscFinished("0"))
+	assert.Equal(t, []CommandExecHistory{{Command: "echo hi", Output: "hi", Code: 0}}, history)
+
+	_, promptSeen = parseOSC133("user@host:~$ ls\r\ngo.mod\r\n")
+	assert.False(t, promptSeen, "a plain prompt has no markers")
+}
+
+func TestCleanTerminalText(t *testing.T) {
+	assert.Equal(t, "100% done\nok", cleanTerminalText("\x1b[?25l 10% \r 50% \r100% done\r\n\x1b[32mok\x1b[0m\x1b]0;title\x07\r\n"))
+}
+
+func mockOSC133Pane(t *testing.T) (*Manager, *string) {
+	t.Helper()
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: map[string]interface{}{"tmux.prompt_detection": promptDetectionOSC133},
+		Status:           "running",
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentCommand: "zsh"},
+	}
+
+	originalPipePane := system.TmuxPipePane
+	originalSend := system.TmuxSendCommandToPane
+	originalCapture := system.TmuxCapturePane
+	t.Cleanup(func() {
+		system.TmuxPipePane = originalPipePane
+		system.TmuxSendCommandToPane = originalSend
+		system.TmuxCapturePane = originalCapture
+	})
+
+	// the "pane" appends what a zsh with shell integration prints
+	var logPath string
+	appendLog := func(s string) {
+		f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, _ = f.WriteString(s)
+		_ = f.Close()
+	}
+	system.TmuxPipePane = func(paneId string, path string) error {
+		assert.Equal(t, "%2", paneId)
+		if path != "" {
+			logPath = path
+		}
+		return nil
+	}
+	var sent []string
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		sent = append(sent, command)
+		if command == "Enter" {
+			appendLog("\r\n" + oscFinished("0") + oscPrompt)
+			return nil
+		}
+		appendLog(command + "\r\n" + oscOutput + "hello\r\n" + oscFinished("3") + oscPrompt)
+		return nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return "~/src ❯", nil
+	}
+	t.Cleanup(func() {
+		assert.NotContains(t, sent, "export PROMPT='%n@%m:%~[%T][%?]» '", "the prompt is never rewritten")
+	})
+	return manager, &logPath
+}
+
+func TestExecWaitCaptureOSC133(t *testing.T) {
+	manager, logPath := mockOSC133Pane(t)
+
+	manager.PrepareExecPane()
+	require.True(t, manager.osc133Prepared("%2"))
+	assert.True(t, manager.ExecPane.IsPrepared)
+	assert.Equal(t, os.TempDir(), filepath.Dir(*logPath))
+
+	history, err := manager.ExecWaitCapture("echo hello; exit 3")
+	require.NoError(t, err)
+	assert.Equal(t, CommandExecHistory{Command: "echo hello; exit 3", Output: "hello", Code: 3}, history)
+	assert.True(t, manager.ExecPane.IsPrepared)
+
+	manager.Cleanup()
+	assert.Nil(t, manager.osc133)
+	_, err = os.Stat(*logPath)
+	assert.True(t, os.IsNotExist(err), "the log is removed")
+}
+
+func TestPrepareOSC133WithoutMarkers(t *testing.T) {
+	manager, logPath := mockOSC133Pane(t)
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		return nil
+	}
+
+	err := manager.prepareOSC133()
+	assert.ErrorContains(t, err, "no OSC 133 markers")
+	assert.False(t, manager.osc133Prepared("%2"))
+	_, statErr := os.Stat(*logPath)
+	assert.True(t, os.IsNotExist(statErr))
+}
@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// paneListed reports whether a pane matches an exclude_panes or
+// readonly_panes entry: a pane ID like %5, or the program running in it
+func paneListed(entries []string, pane system.TmuxPaneDetails) bool {
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "%") {
+			if entry == pane.Id {
+				return true
+			}
+		} else if entry == pane.CurrentCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// paneExcluded reports whether a pane is left out of the context entirely,
+// by /context exclude or exclude_panes
+func (m *Manager) paneExcluded(pane system.TmuxPaneDetails) bool {
+	if m.excludedPanes[pane.Id] {
+		return true
+	}
+	return m.Config != nil && paneListed(m.Config.ExcludePanes, pane)
+}
+
+// paneReadOnly reports whether the AI may only read a pane, never send it
+// commands or keys. Excluded panes are read-only too.
+func (m *Manager) paneReadOnly(pane system.TmuxPaneDetails) bool {
+	if m.readOnlyPanes[pane.Id] || m.paneExcluded(pane) {
+		return true
+	}
+	return m.Config != nil && paneListed(m.Config.ReadOnlyPanes, pane)
+}
+
+// paneIDReadOnly is paneReadOnly for a pane known only by its ID
+func (m *Manager) paneIDReadOnly(paneID string) bool {
+	pane, found := m.findPane(paneID)
+	if !found {
+		pane = system.TmuxPaneDetails{Id: paneID}
+	}
+	return m.paneReadOnly(pane)
+}
+
+// paneIDExcluded is paneExcluded for a pane known only by its ID
+func (m *Manager) paneIDExcluded(paneID string) bool {
+	pane, found := m.findPane(paneID)
+	if !found {
+		pane = system.TmuxPaneDetails{Id: paneID}
+	}
+	return m.paneExcluded(pane)
+}
+
+// setPaneAccess handles /context exclude, readonly and allow for a pane ID
+// or target. allow clears what this session set; exclude_panes and
+// readonly_panes from config.yaml still apply.
+func (m *Manager) setPaneAccess(action, target string) error {
+	paneID, err := m.resolveContextPane(target)
+	if err != nil {
+		return err
+	}
+	if m.excludedPanes == nil {
+		m.excludedPanes = make(map[string]bool)
+	}
+	if m.readOnlyPanes == nil {
+		m.readOnlyPanes = make(map[string]bool)
+	}
+
+	switch action {
+	case "exclude":
+		m.excludedPanes[paneID] = true
+		m.Println(fmt.Sprintf("✓ Pane %s is excluded from the context", paneID))
+	case "readonly":
+		m.readOnlyPanes[paneID] = true
+		m.Println(fmt.Sprintf("✓ Pane %s is read-only, the AI can't send it commands or keys", paneID))
+	default:
+		delete(m.excludedPanes, paneID)
+		delete(m.readOnlyPanes, paneID)
+		if m.paneIDReadOnly(paneID) {
+			m.Println(fmt.Sprintf("Pane %s still matches exclude_panes or readonly_panes in config.yaml", paneID))
+			return nil
+		}
+		m.Println(fmt.Sprintf("✓ Pane %s is in the context and can be targeted", paneID))
+	}
+	return nil
+}
+
+// setPaneCaptureLines handles /context lines: how many lines to capture from
+// one pane, instead of max_capture_lines. "off" drops the override. The
+// limit lasts for the session and is kept when it's resumed.
+func (m *Manager) setPaneCaptureLines(target, value string) error {
+	paneID, err := m.resolveContextPane(target)
+	if err != nil {
+		return err
+	}
+	key := paneCaptureLinesPrefix + paneID
+	if value == "off" {
+		m.deleteOverride(key)
+		m.Println(fmt.Sprintf("✓ Pane %s is captured up to max_capture_lines (%d lines)", paneID, m.GetMaxCaptureLines()))
+		return nil
+	}
+	lines, err := strconv.Atoi(value)
+	if err != nil || lines <= 0 {
+		return fmt.Errorf("lines must be a positive number or off, got %s", value)
+	}
+	m.setOverride(key, lines)
+	m.Println(fmt.Sprintf("✓ Pane %s is captured up to %d lines", paneID, lines))
+	return nil
+}
+
+// resolveContextPane returns the ID of a pane given by ID or tmux target,
+// failing when no such pane exists
+func (m *Manager) resolveContextPane(target string) (string, error) {
+	paneID := target
+	if !strings.HasPrefix(target, "%") {
+		resolved, err := system.TmuxResolvePaneId(target)
+		if err != nil {
+			return "", err
+		}
+		paneID = resolved
+	}
+	if _, found := m.findPane(paneID); !found {
+		return "", fmt.Errorf("pane %s was not found in any tmux session", paneID)
+	}
+	return paneID, nil
+}
+
+// paneAccessSummary lists the panes excluded or marked read-only, in this
+// session or in config.yaml, and the panes with their own capture lines
+func (m *Manager) paneAccessSummary() []string {
+	var lines []string
+	excluded := append(slices.Collect(maps.Keys(m.excludedPanes)), m.Config.ExcludePanes...)
+	if len(excluded) > 0 {
+		lines = append(lines, "Excluded panes: "+strings.Join(uniqueSorted(excluded), ", "))
+	}
+	readOnly := append(slices.Collect(maps.Keys(m.readOnlyPanes)), m.Config.ReadOnlyPanes...)
+	if len(readOnly) > 0 {
+		lines = append(lines, "Read-only panes: "+strings.Join(uniqueSorted(readOnly), ", "))
+	}
+	var captureLines []string
+	for _, key := range m.sortedOverrideKeys() {
+		if paneID, ok := strings.CutPrefix(key, paneCaptureLinesPrefix); ok {
+			captureLines = append(captureLines, fmt.Sprintf("%s %d", paneID, m.GetPaneCaptureLines(paneID)))
+		}
+	}
+	if len(captureLines) > 0 {
+		lines = append(lines, "Capture lines: "+strings.Join(captureLines, ", "))
+	}
+	return lines
+}
+
+// readOnlyObservation tells the model its actions on a read-only pane
+// weren't sent
+func readOnlyObservation(actions []string) string {
+	return "These actions target a read-only pane and were NOT sent:\n- " + strings.Join(actions, "\n- ") +
+		"\nRead-only panes are context only, don't send them commands or keys.\n"
+}
+
+// uniqueSorted returns values sorted, without duplicates
+func uniqueSorted(values []string) []string {
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
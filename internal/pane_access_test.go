@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaneExcludedAndReadOnly(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ExcludePanes = []string{"weechat"}
+	cfg.ReadOnlyPanes = []string{"%7"}
+	manager := &Manager{Config: cfg}
+
+	chat := system.TmuxPaneDetails{Id: "%3", CurrentCommand: "weechat"}
+	logs := system.TmuxPaneDetails{Id: "%7", CurrentCommand: "tail"}
+	shell := system.TmuxPaneDetails{Id: "%5", CurrentCommand: "zsh"}
+
+	assert.True(t, manager.paneExcluded(chat), "matched by program")
+	assert.True(t, manager.paneReadOnly(chat), "excluded panes are read-only too")
+	assert.False(t, manager.paneExcluded(logs))
+	assert.True(t, manager.paneReadOnly(logs), "matched by pane ID")
+	assert.False(t, manager.paneReadOnly(shell))
+
+	assert.False(t, manager.shouldIncludeReadPane(chat))
+	assert.True(t, manager.shouldIncludeReadPane(logs))
+
+	manager.excludedPanes = map[string]bool{"%5": true}
+	assert.False(t, manager.shouldIncludeReadPane(shell))
+	assert.Equal(t, []string{"Excluded panes: %5, weechat", "Read-only panes: %7"}, manager.paneAccessSummary())
+}
+
+func TestSetPaneAccess(t *testing.T) {
+	original := system.TmuxPanesDetails
+	t.Cleanup(func() { system.TmuxPanesDetails = original })
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		return []system.TmuxPaneDetails{{Id: "%5", CurrentCommand: "zsh"}}, nil
+	}
+	manager := &Manager{Config: config.DefaultConfig()}
+
+	require.NoError(t, manager.setPaneAccess("readonly", "%5"))
+	assert.True(t, manager.paneIDReadOnly("%5"))
+	assert.False(t, manager.paneIDExcluded("%5"))
+
+	require.NoError(t, manager.setPaneAccess("exclude", "%5"))
+	assert.True(t, manager.paneIDExcluded("%5"))
+
+	require.NoError(t, manager.setPaneAccess("allow", "%5"))
+	assert.False(t, manager.paneIDReadOnly("%5"))
+
+	assert.Error(t, manager.setPaneAccess("exclude", "%9"), "unknown panes are refused")
+}
+
+func TestPaneMCPServerReadOnlyPane(t *testing.T) {
+	sent := stubTmux(t)
+	cfg := config.DefaultConfig()
+	cfg.WhitelistPatterns = []string{`^ls$`}
+	cfg.ReadOnlyPanes = []string{"%1"}
+	cfg.ExcludePanes = []string{"%2"}
+	session := connectPaneServer(t, cfg, nil)
+
+	res, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "exec_command",
+		Arguments: map[string]any{"pane_id": "%1", "command": "ls"},
+	})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Empty(t, *sent)
+
+	res, err = session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "capture_pane",
+		Arguments: map[string]any{"pane_id": "%1"},
+	})
+	require.NoError(t, err)
+	assert.False(t, res.IsError, "read-only panes can still be read")
+
+	res, err = session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "capture_pane",
+		Arguments: map[string]any{"pane_id": "%2"},
+	})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestSetPaneCaptureLines(t *testing.T) {
+	original := system.TmuxPanesDetails
+	t.Cleanup(func() { system.TmuxPanesDetails = original })
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		return []system.TmuxPaneDetails{{Id: "%3"}, {Id: "%4"}}, nil
+	}
+	manager := &Manager{Config: config.DefaultConfig(), SessionOverrides: map[string]interface{}{}}
+
+	require.NoError(t, manager.setPaneCaptureLines("%3", "500"))
+	assert.Equal(t, 500, manager.GetPaneCaptureLines("%3"))
+	assert.Equal(t, 200, manager.GetPaneCaptureLines("%4"), "other panes keep max_capture_lines")
+	assert.Equal(t, []string{"Capture lines: %3 500"}, manager.paneAccessSummary())
+	assert.Equal(t, []string{"pane_capture_lines.%3: 200 → 500"}, manager.configDiff())
+
+	assert.Error(t, manager.setPaneCaptureLines("%4", "0"))
+	assert.Error(t, manager.setPaneCaptureLines("%9", "30"), "unknown panes are refused")
+
+	require.NoError(t, manager.setPaneCaptureLines("%3", "off"))
+	assert.Equal(t, 200, manager.GetPaneCaptureLines("%3"))
+	assert.Empty(t, manager.paneAccessSummary())
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// minSqueezedRun is the shortest run of similar progress lines normalization
+// replaces with its last line and a count
+const minSqueezedRun = 3
+
+// ansiSequence matches terminal escape sequences: CSI (colors, cursor
+// movement), OSC (titles, hyperlinks) and two-byte escapes
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// sgrSequence matches the CSI sequences that set colors and text attributes
+var sgrSequence = regexp.MustCompile(`^\x1b\[[0-9;:]*m$`)
+
+// progressMarks are what spinner and progress bar lines look like: a
+// percentage, a bar, a spinner glyph or a byte counter
+var progressMarks = regexp.MustCompile(`\d+(?:\.\d+)?\s?%|[█▉▊▋▌▍▎▏░▒▓■□━─=#>-]{4,}|[⠀-⣿◐◓◑◒◴◷◶◵⣾⣽⣻⢿⡿⣟⣯⣷]|\d+(?:\.\d+)?\s?[KMG]i?B\b`)
+
+// progressNoise is what changes between redraws of a progress line
+var progressNoise = regexp.MustCompile(`\d+(?:\.\d+)?|[█▉▊▋▌▍▎▏░▒▓■□━─=#>\-|/\\⠀-⣿◐◓◑◒◴◷◶◵]+|\s+`)
+
+// capturedContent returns a pane's content as sent in the context: captured
+// again with escape sequences when capture.ansi is on, and normalized when
+// capture.normalize is on
+func (m *Manager) capturedContent(pane system.TmuxPaneDetails) string {
+	content := pane.Content
+	ansi := m.GetCaptureANSI()
+	if ansi {
+		if escaped, err := system.TmuxCapturePaneEscapes(pane.Id, m.GetPaneCaptureLines(pane.Id)); err == nil {
+			content = escaped
+		} else {
+			logger.Error("Failed to capture pane %s with escape sequences: %v", pane.Id, err)
+		}
+	}
+	if m.GetCaptureNormalize() {
+		content = normalizePaneContent(content, ansi)
+	}
+	return content
+}
+
+// normalizePaneContent shrinks noisy captures: escape sequences are removed
+// (colors are kept when keepColors is set), carriage-return redraws keep
+// their last state, trailing whitespace is trimmed, runs of blank lines
+// collapse into one, and runs of similar progress lines into their last line
+func normalizePaneContent(content string, keepColors bool) string {
+	content = ansiSequence.ReplaceAllStringFunc(content, func(seq string) string {
+		if keepColors && sgrSequence.MatchString(seq) {
+			return seq
+		}
+		return ""
+	})
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(content, "\n") {
+		// a progress bar redrawn in place leaves "\r"-separated states
+		if i := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.TrimRight(strings.Join(squeezeProgressLines(lines), "\n"), "\n")
+}
+
+// squeezeProgressLines replaces runs of repeated lines, and of progress
+// lines differing only in their numbers, bars or spinner glyphs, with a
+// count and the last line of the run
+func squeezeProgressLines(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && similarLines(lines[i], lines[j]) {
+			j++
+		}
+		if j-i >= minSqueezedRun {
+			out = append(out, fmt.Sprintf("[%d similar lines]", j-i-1), lines[j-1])
+		} else {
+			out = append(out, lines[i:j]...)
+		}
+		i = j
+	}
+	return out
+}
+
+// similarLines reports whether b repeats a, or redraws the same progress line
+func similarLines(a, b string) bool {
+	if a == b {
+		return a != ""
+	}
+	if !progressMarks.MatchString(a) || !progressMarks.MatchString(b) {
+		return false
+	}
+	plain := func(s string) string { return progressNoise.ReplaceAllString(sgrStrip(s), "") }
+	return plain(a) == plain(b)
+}
+
+// sgrStrip removes the color sequences normalization may have kept
+func sgrStrip(s string) string {
+	return ansiSequence.ReplaceAllString(s, "")
+}
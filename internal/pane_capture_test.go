@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePaneContent(t *testing.T) {
+	content := "\x1b]0;title\x07$ npm install   \n" +
+		"\x1b[32madded\x1b[0m 10 packages\n\n\n\n" +
+		"⠋ fetching 1/40\n⠙ fetching 2/40\n⠹ fetching 3/40\n⠸ fetching 40/40\n" +
+		"[=====>    ] 50%\r[==========] 100%\n" +
+		"GET /api 200\nGET /api 200\n" +
+		"$ "
+
+	assert.Equal(t, "$ npm install\n"+
+		"added 10 packages\n\n"+
+		"[3 similar lines]\n⠸ fetching 40/40\n"+
+		"[==========] 100%\n"+
+		"GET /api 200\nGET /api 200\n"+
+		"$", normalizePaneContent(content, false))
+
+	assert.Contains(t, normalizePaneContent(content, true), "\x1b[32madded\x1b[0m 10 packages", "colors are kept with capture.ansi")
+	assert.NotContains(t, normalizePaneContent(content, true), "title")
+}
+
+func TestSimilarLines(t *testing.T) {
+	assert.True(t, similarLines("Downloading 10%", "Downloading 85%"))
+	assert.True(t, similarLines("waiting...", "waiting..."))
+	assert.False(t, similarLines("test 1 passed", "test 2 passed"), "numbers alone don't make progress")
+	assert.False(t, similarLines("", ""))
+}
+
+func TestCapturedContent(t *testing.T) {
+	original := system.TmuxCapturePaneEscapes
+	t.Cleanup(func() { system.TmuxCapturePaneEscapes = original })
+	system.TmuxCapturePaneEscapes = func(paneId string, maxLines int) (string, error) {
+		return "\x1b[31mFAIL\x1b[0m pkg/a   ", nil
+	}
+
+	manager := &Manager{Config: config.DefaultConfig(), SessionOverrides: map[string]interface{}{}}
+	pane := system.TmuxPaneDetails{Id: "%1", Content: "FAIL pkg/a   "}
+	assert.Equal(t, "FAIL pkg/a   ", manager.capturedContent(pane))
+
+	manager.SessionOverrides["capture.ansi"] = true
+	assert.Equal(t, "\x1b[31mFAIL\x1b[0m pkg/a   ", manager.capturedContent(pane))
+
+	manager.SessionOverrides["capture.normalize"] = true
+	assert.Equal(t, "\x1b[31mFAIL\x1b[0m pkg/a", manager.capturedContent(pane))
+}
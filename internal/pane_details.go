@@ -2,39 +2,94 @@ package internal
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
 	"github.com/alvinunreal/tmuxai/system"
 )
 
+// maxParallelCaptures bounds the capture-pane calls running at once
+const maxParallelCaptures = 4
+
 func (m *Manager) GetTmuxPanes() ([]system.TmuxPaneDetails, error) {
-	currentPaneId, _ := system.TmuxCurrentPaneId()
+	if m.watchingPanes() {
+		return m.watchedPanes(), nil
+	}
+
 	windowTarget, _ := system.TmuxCurrentWindowTarget()
+	panes, err := m.getWindowPanes(windowTarget)
+
+	// The exec pane may live in another window or session
+	if m.ExecPane.Id != "" && !containsPane(panes, m.ExecPane.Id) {
+		if pane, found := m.findPane(m.ExecPane.Id); found {
+			pane.IsTmuxAiExecPane = true
+			pane.IsPrepared = true
+			panes = append(panes, pane)
+		}
+	}
+	// and so may named exec panes
+	for _, name := range m.namedExecPaneNames() {
+		paneID := m.namedExecPanes[name]
+		if containsPane(panes, paneID) {
+			continue
+		}
+		if pane, found := m.findPane(paneID); found {
+			panes = append(panes, pane)
+		}
+	}
+	return panes, err
+}
+
+func containsPane(panes []system.TmuxPaneDetails, paneID string) bool {
+	for _, pane := range panes {
+		if pane.Id == paneID {
+			return true
+		}
+	}
+	return false
+}
+
+// getWindowPanes lists the panes of a window target and marks TmuxAI's own panes
+func (m *Manager) getWindowPanes(windowTarget string) ([]system.TmuxPaneDetails, error) {
+	currentPaneId, _ := system.TmuxCurrentPaneId()
 	currentPanes, _ := system.TmuxPanesDetails(windowTarget)
 
 	for i := range currentPanes {
-		currentPanes[i].IsTmuxAiPane = currentPanes[i].Id == currentPaneId
+		// The chat pane is on the local tmux server, so remote IDs never match it
+		currentPanes[i].IsTmuxAiPane = !system.TmuxIsRemote() && currentPanes[i].Id == currentPaneId
 		currentPanes[i].IsTmuxAiExecPane = currentPanes[i].Id == m.ExecPane.Id
 		currentPanes[i].IsPrepared = currentPanes[i].Id == m.ExecPane.Id
-		if currentPanes[i].IsSubShell {
-			currentPanes[i].OS = "OS Unknown (subshell)"
-		} else {
-			currentPanes[i].OS = m.OS
-		}
+		currentPanes[i].OS = m.paneOS(currentPanes[i])
 
 	}
 	return currentPanes, nil
 }
 
+// paneOS describes the OS a pane runs on for the context
+func (m *Manager) paneOS(pane system.TmuxPaneDetails) string {
+	if pane.IsSubShell {
+		return "OS Unknown (subshell)"
+	}
+	if system.TmuxIsRemote() {
+		return "Remote host " + system.TmuxRemoteHost()
+	}
+	return m.OS
+}
+
 func (m *Manager) shouldIncludeReadPane(pane system.TmuxPaneDetails) bool {
-	if pane.IsTmuxAiPane {
+	if pane.IsTmuxAiPane || m.paneExcluded(pane) {
 		return false
 	}
+	if m.watchingPanes() {
+		return slices.Contains(m.watchPanes, pane.Id)
+	}
 	if len(m.ForcedReadPaneIDs) == 0 {
 		return true
 	}
-	if pane.IsTmuxAiExecPane {
+	if pane.IsTmuxAiExecPane || m.execPaneName(pane.Id) != "" {
 		return true
 	}
 	return m.ForcedReadPaneIDs[pane.Id]
@@ -44,7 +99,43 @@ func (m *Manager) getTmuxPanesInXmlFn(config *config.Config) string {
 	currentTmuxWindow := strings.Builder{}
 	currentTmuxWindow.WriteString("<current_tmux_window_state>\n")
 	panes, _ := m.GetTmuxPanes()
+	m.writePanesXml(&currentTmuxWindow, panes)
+	currentTmuxWindow.WriteString("</current_tmux_window_state>\n")
+	currentTmuxWindow.WriteString(m.gitContextXml())
+
+	if m.GetContextWindows() == "all" && !m.watchingPanes() {
+		currentTmuxWindow.WriteString(m.otherWindowsXml())
+	}
+	return currentTmuxWindow.String()
+}
+
+// otherWindowsXml renders the panes of every other window in the session,
+// grouped per window, for the "all" context_windows setting.
+func (m *Manager) otherWindowsXml() string {
+	windows, err := system.TmuxSessionWindows()
+	if err != nil {
+		logger.Error("Failed to list session windows: %v", err)
+		return ""
+	}
+	currentWindow, _ := system.TmuxCurrentWindowTarget()
+
+	var b strings.Builder
+	for _, w := range windows {
+		if w.Target == currentWindow {
+			continue
+		}
+		panes, _ := m.getWindowPanes(w.Target)
+		fmt.Fprintf(&b, "<tmux_window index=\"%d\" name=\"%s\">\n", w.Index, sanitizeXML(w.Name))
+		m.writePanesXml(&b, panes)
+		b.WriteString("</tmux_window>\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<other_tmux_windows>\n" + b.String() + "</other_tmux_windows>\n"
+}
 
+func (m *Manager) writePanesXml(currentTmuxWindow *strings.Builder, panes []system.TmuxPaneDetails) {
 	// Filter out tmuxai_pane
 	var filteredPanes []system.TmuxPaneDetails
 	for _, p := range panes {
@@ -52,46 +143,71 @@ func (m *Manager) getTmuxPanesInXmlFn(config *config.Config) string {
 			filteredPanes = append(filteredPanes, p)
 		}
 	}
+	refreshPanes(filteredPanes, m.GetPaneCaptureLines)
 	for _, pane := range filteredPanes {
-		if !pane.IsTmuxAiPane {
-			pane.Refresh(m.GetMaxCaptureLines())
-		}
 		if pane.IsTmuxAiExecPane {
-			m.ExecPane = &pane
+			pane.IsPrepared = pane.IsPrepared || m.execPaneTracked(pane.Id)
+			m.setExecPane(&pane)
 		}
 
+		name := m.execPaneName(pane.Id)
 		var title string
-		if pane.IsTmuxAiExecPane {
+		if m.paneReadOnly(pane) {
+			title = "read_only_pane"
+		} else if pane.IsTmuxAiExecPane {
 			title = "tmuxai_exec_pane"
+		} else if name != "" {
+			title = "named_exec_pane"
 		} else {
 			title = "read_only_pane"
 		}
 
-		fmt.Fprintf(&currentTmuxWindow, "<%s>\n", title)
-		fmt.Fprintf(&currentTmuxWindow, " - Id: %s\n", pane.Id)
-		fmt.Fprintf(&currentTmuxWindow, " - CurrentPid: %d\n", pane.CurrentPid)
-		fmt.Fprintf(&currentTmuxWindow, " - CurrentCommand: %s\n", pane.CurrentCommand)
-		fmt.Fprintf(&currentTmuxWindow, " - CurrentCommandArgs: %s\n", pane.CurrentCommandArgs)
-		fmt.Fprintf(&currentTmuxWindow, " - Shell: %s\n", pane.Shell)
-		fmt.Fprintf(&currentTmuxWindow, " - OS: %s\n", pane.OS)
-		fmt.Fprintf(&currentTmuxWindow, " - LastLine: %s\n", pane.LastLine)
-		fmt.Fprintf(&currentTmuxWindow, " - IsActive: %d\n", pane.IsActive)
-		fmt.Fprintf(&currentTmuxWindow, " - IsTmuxAiPane: %t\n", pane.IsTmuxAiPane)
-		fmt.Fprintf(&currentTmuxWindow, " - IsTmuxAiExecPane: %t\n", pane.IsTmuxAiExecPane)
-		fmt.Fprintf(&currentTmuxWindow, " - IsPrepared: %t\n", pane.IsPrepared)
-		fmt.Fprintf(&currentTmuxWindow, " - IsSubShell: %t\n", pane.IsSubShell)
-		fmt.Fprintf(&currentTmuxWindow, " - HistorySize: %d\n", pane.HistorySize)
-		fmt.Fprintf(&currentTmuxWindow, " - HistoryLimit: %d\n", pane.HistoryLimit)
+		fmt.Fprintf(currentTmuxWindow, "<%s>\n", title)
+		fmt.Fprintf(currentTmuxWindow, " - Id: %s\n", pane.Id)
+		if name != "" {
+			fmt.Fprintf(currentTmuxWindow, " - ExecPaneName: %s\n", name)
+		}
+		fmt.Fprintf(currentTmuxWindow, " - CurrentPid: %d\n", pane.CurrentPid)
+		fmt.Fprintf(currentTmuxWindow, " - CurrentCommand: %s\n", pane.CurrentCommand)
+		fmt.Fprintf(currentTmuxWindow, " - CurrentCommandArgs: %s\n", pane.CurrentCommandArgs)
+		fmt.Fprintf(currentTmuxWindow, " - Shell: %s\n", pane.Shell)
+		fmt.Fprintf(currentTmuxWindow, " - OS: %s\n", pane.OS)
+		fmt.Fprintf(currentTmuxWindow, " - LastLine: %s\n", pane.LastLine)
+		fmt.Fprintf(currentTmuxWindow, " - IsActive: %d\n", pane.IsActive)
+		fmt.Fprintf(currentTmuxWindow, " - IsTmuxAiPane: %t\n", pane.IsTmuxAiPane)
+		fmt.Fprintf(currentTmuxWindow, " - IsTmuxAiExecPane: %t\n", pane.IsTmuxAiExecPane)
+		fmt.Fprintf(currentTmuxWindow, " - IsPrepared: %t\n", pane.IsPrepared)
+		fmt.Fprintf(currentTmuxWindow, " - IsSubShell: %t\n", pane.IsSubShell)
+		fmt.Fprintf(currentTmuxWindow, " - HistorySize: %d\n", pane.HistorySize)
+		fmt.Fprintf(currentTmuxWindow, " - HistoryLimit: %d\n", pane.HistoryLimit)
 
 		if !pane.IsTmuxAiPane && pane.Content != "" {
 			currentTmuxWindow.WriteString("<pane_content>\n")
-			currentTmuxWindow.WriteString(pane.Content)
+			currentTmuxWindow.WriteString(m.paneContextContent(pane.Id, m.redactContext(m.capturedContent(pane))))
 			currentTmuxWindow.WriteString("\n</pane_content>\n")
 		}
 
-		fmt.Fprintf(&currentTmuxWindow, "</%s>\n\n", title)
+		fmt.Fprintf(currentTmuxWindow, "</%s>\n\n", title)
 	}
+}
 
-	currentTmuxWindow.WriteString("</current_tmux_window_state>\n")
-	return currentTmuxWindow.String()
+// refreshPanes captures the content of panes concurrently, at most
+// maxParallelCaptures at a time, keeping their order. maxLines gives the
+// lines to capture from each pane.
+func refreshPanes(panes []system.TmuxPaneDetails, maxLines func(paneID string) int) {
+	sem := make(chan struct{}, maxParallelCaptures)
+	var wg sync.WaitGroup
+	for i := range panes {
+		if panes[i].IsTmuxAiPane {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p *system.TmuxPaneDetails) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.Refresh(maxLines(p.Id))
+		}(&panes[i])
+	}
+	wg.Wait()
 }
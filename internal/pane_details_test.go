@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubSessionWindows(t *testing.T) {
+	t.Helper()
+	origPaneId, origWindow := system.TmuxCurrentPaneId, system.TmuxCurrentWindowTarget
+	origWindows, origPanes, origCapture := system.TmuxSessionWindows, system.TmuxPanesDetails, system.TmuxCapturePane
+	t.Cleanup(func() {
+		system.TmuxCurrentPaneId, system.TmuxCurrentWindowTarget = origPaneId, origWindow
+		system.TmuxSessionWindows, system.TmuxPanesDetails, system.TmuxCapturePane = origWindows, origPanes, origCapture
+	})
+
+	system.TmuxCurrentPaneId = func() (string, error) { return "%0", nil }
+	system.TmuxCurrentWindowTarget = func() (string, error) { return "$1:1", nil }
+	system.TmuxSessionWindows = func() ([]system.TmuxWindowDetails, error) {
+		return []system.TmuxWindowDetails{
+			{Target: "$1:1", Index: 1, Name: "main", IsActive: true},
+			{Target: "$1:2", Index: 2, Name: "logs"},
+		}, nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		if target == "$1:2" {
+			return []system.TmuxPaneDetails{{Id: "%5", CurrentCommand: "tail"}}, nil
+		}
+		return []system.TmuxPaneDetails{{Id: "%0"}, {Id: "%1", CurrentCommand: "bash"}}, nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return "content of " + paneId, nil
+	}
+}
+
+func TestGetTmuxPanesInXmlContextWindows(t *testing.T) {
+	stubSessionWindows(t)
+	manager := &Manager{
+		Config:           &config.Config{ContextWindows: "current", MaxCaptureLines: 10},
+		SessionOverrides: make(map[string]interface{}),
+		ExecPane:         &system.TmuxPaneDetails{Id: "%1"},
+	}
+
+	xml := manager.getTmuxPanesInXmlFn(manager.Config)
+	assert.Contains(t, xml, "content of %1")
+	assert.NotContains(t, xml, "other_tmux_windows")
+	assert.NotContains(t, xml, "content of %5")
+
+	manager.SessionOverrides["context_windows"] = "all"
+	xml = manager.getTmuxPanesInXmlFn(manager.Config)
+	assert.Contains(t, xml, "<other_tmux_windows>\n<tmux_window index=\"2\" name=\"logs\">\n<read_only_pane>\n - Id: %5")
+	assert.Contains(t, xml, "content of %5")
+	assert.Equal(t, 1, strings.Count(xml, "<tmux_window "), "current window isn't repeated")
+}
+
+func TestRefreshPanesCapturesConcurrently(t *testing.T) {
+	origCapture := system.TmuxCapturePane
+	t.Cleanup(func() { system.TmuxCapturePane = origCapture })
+
+	var running, peak atomic.Int32
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return "$ echo " + paneId, nil
+	}
+
+	panes := []system.TmuxPaneDetails{{Id: "%0", IsTmuxAiPane: true}}
+	for i := 1; i <= 8; i++ {
+		panes = append(panes, system.TmuxPaneDetails{Id: fmt.Sprintf("%%%d", i)})
+	}
+	refreshPanes(panes, func(string) int { return 10 })
+
+	assert.Empty(t, panes[0].Content, "the chat pane isn't captured")
+	for i := 1; i <= 8; i++ {
+		assert.Equal(t, fmt.Sprintf("$ echo %%%d", i), panes[i].Content)
+	}
+	assert.Greater(t, peak.Load(), int32(1))
+	assert.LessOrEqual(t, peak.Load(), int32(maxParallelCaptures))
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// paneDiffOverlap is the number of already sent lines repeated before the
+// new ones, so the model can tell where they continue
+const paneDiffOverlap = 3
+
+// paneDiffMinMatch is the number of lines a capture must share with the
+// previous one to be sent as a diff. Fewer could match by chance, e.g.
+// repeated blank lines or prompts.
+const paneDiffMinMatch = 3
+
+// paneContentDiff returns content with the lines already sent in previous
+// left out, and whether it could do so. It can when content continues
+// previous: its top lines, after any that scrolled out of the capture, are
+// the previous capture but for the last line, the prompt, which may have
+// been typed on since. Redrawn or cleared panes are sent whole.
+func paneContentDiff(previous, content string) (string, bool) {
+	old := strings.Split(previous, "\n")
+	lines := strings.Split(content, "\n")
+	anchor := old[:len(old)-1]
+
+	for s := 0; s < len(anchor); s++ {
+		tail := anchor[s:]
+		if len(tail) < paneDiffMinMatch && s > 0 {
+			break
+		}
+		if len(tail) > len(lines) || !slices.Equal(tail, lines[:len(tail)]) {
+			continue
+		}
+		skipped := max(len(tail)-paneDiffOverlap, 0)
+		if skipped == 0 {
+			return content, false
+		}
+		return fmt.Sprintf("[%d earlier lines unchanged since the previous capture]\n%s", skipped, strings.Join(lines[skipped:], "\n")), true
+	}
+	return content, false
+}
+
+// paneContextContent returns the pane content to send, diffed against what
+// was sent for the pane earlier in the current task
+func (m *Manager) paneContextContent(paneID, content string) string {
+	if m.paneSnapshots == nil {
+		return content
+	}
+	previous, sent := m.paneSnapshots[paneID]
+	m.paneSnapshots[paneID] = content
+	if !sent {
+		return content
+	}
+	diff, _ := paneContentDiff(previous, content)
+	return diff
+}
+
+// resetPaneSnapshots makes the next request of the current task send full
+// pane captures, e.g. after the history holding earlier ones was squashed
+func (m *Manager) resetPaneSnapshots() {
+	if m.paneSnapshots != nil {
+		m.paneSnapshots = make(map[string]string)
+	}
+}
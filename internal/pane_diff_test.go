@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func numberedLines(from, to int) []string {
+	var lines []string
+	for i := from; i <= to; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return lines
+}
+
+func TestPaneContentDiff(t *testing.T) {
+	previous := strings.Join(append(numberedLines(1, 10), "$"), "\n")
+
+	t.Run("appended output", func(t *testing.T) {
+		content := strings.Join(append(numberedLines(1, 10), "$ make", "ok", "$"), "\n")
+		diff, ok := paneContentDiff(previous, content)
+		assert.True(t, ok)
+		assert.Equal(t, "[7 earlier lines unchanged since the previous capture]\nline 8\nline 9\nline 10\n$ make\nok\n$", diff)
+	})
+
+	t.Run("scrolled out of the capture", func(t *testing.T) {
+		content := strings.Join(append(numberedLines(4, 10), "$ make", "ok", "$"), "\n")
+		diff, ok := paneContentDiff(previous, content)
+		assert.True(t, ok)
+		assert.Equal(t, "[4 earlier lines unchanged since the previous capture]\nline 8\nline 9\nline 10\n$ make\nok\n$", diff)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		diff, ok := paneContentDiff(previous, previous)
+		assert.True(t, ok)
+		assert.Equal(t, "[7 earlier lines unchanged since the previous capture]\nline 8\nline 9\nline 10\n$", diff)
+	})
+
+	t.Run("redrawn", func(t *testing.T) {
+		content := "top - 10:00:00 up 3 days\nTasks: 120 total\n$"
+		diff, ok := paneContentDiff(previous, content)
+		assert.False(t, ok)
+		assert.Equal(t, content, diff)
+	})
+
+	t.Run("too short to diff", func(t *testing.T) {
+		diff, ok := paneContentDiff("$", "$ ls\nfile\n$")
+		assert.False(t, ok)
+		assert.Equal(t, "$ ls\nfile\n$", diff)
+	})
+}
+
+func TestPaneContextContentOnlyDiffsWithinATask(t *testing.T) {
+	manager := &Manager{Config: &config.Config{}, SessionOverrides: make(map[string]interface{})}
+	first := strings.Join(append(numberedLines(1, 10), "$"), "\n")
+	second := strings.Join(append(numberedLines(1, 10), "$ ls", "$"), "\n")
+
+	assert.Equal(t, second, manager.paneContextContent("%1", second), "no task running")
+
+	manager.paneSnapshots = make(map[string]string)
+	assert.Equal(t, first, manager.paneContextContent("%1", first), "first capture of a task is sent whole")
+	assert.True(t, strings.HasPrefix(manager.paneContextContent("%1", second), "[7 earlier lines unchanged"))
+	assert.Equal(t, first, manager.paneContextContent("%2", first), "each pane has its own snapshot")
+
+	manager.resetPaneSnapshots()
+	assert.Equal(t, second, manager.paneContextContent("%1", second), "sent whole again after a squash")
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// paneImageTimeout bounds a run of pane_image_command
+const paneImageTimeout = 10 * time.Second
+
+// maxPaneImageBytes is the largest picture attached, below the 5 MB most
+// vision APIs accept per image
+const maxPaneImageBytes = 4 << 20
+
+// runPaneImageCommand runs pane_image_command through the shell. It's a
+// variable so tests can stub it.
+var runPaneImageCommand = func(ctx context.Context, command string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.WaitDelay = time.Second
+	return cmd.CombinedOutput()
+}
+
+// paneImageEnabled reports whether requests to the current model get a
+// picture of the exec pane
+func (m *Manager) paneImageEnabled() bool {
+	if m.Config.PaneImageCommand == "" || m.ExecPane == nil || m.ExecPane.Id == "" {
+		return false
+	}
+	mc, exists := m.GetCurrentModelConfig()
+	// the copilot SDK only takes attachments from files it reads itself
+	return exists && mc.Vision && mc.Provider != "github-copilot"
+}
+
+// paneImage renders the exec pane to a PNG with pane_image_command and
+// returns it base64 encoded, or "" when it isn't enabled or fails
+func (m *Manager) paneImage() string {
+	if !m.paneImageEnabled() {
+		return ""
+	}
+	image, err := m.capturePaneImage(m.ExecPane.Id)
+	if err != nil {
+		logger.Error("Failed to capture an image of pane %s: %v", m.ExecPane.Id, err)
+		return ""
+	}
+	return image
+}
+
+// capturePaneImage runs pane_image_command with {pane} replaced by paneID
+// and {file} by the PNG it should write, and returns the PNG base64 encoded
+func (m *Manager) capturePaneImage(paneID string) (string, error) {
+	f, err := os.CreateTemp("", "tmuxai-pane-*.png")
+	if err != nil {
+		return "", err
+	}
+	_ = f.Close()
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	command := strings.NewReplacer(
+		"{pane}", system.ShellQuote(paneID),
+		"{file}", system.ShellQuote(f.Name()),
+	).Replace(m.Config.PaneImageCommand)
+
+	ctx, cancel := context.WithTimeout(context.Background(), paneImageTimeout)
+	defer cancel()
+	if output, err := runPaneImageCommand(ctx, command); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(data) == 0:
+		return "", fmt.Errorf("pane_image_command wrote no image to {file}")
+	case len(data) > maxPaneImageBytes:
+		return "", fmt.Errorf("the image is %d bytes, more than the %d allowed", len(data), maxPaneImageBytes)
+	case !strings.HasPrefix(string(data), "\x89PNG"):
+		return "", fmt.Errorf("pane_image_command didn't write a PNG")
+	}
+	logger.Debug("Captured a %d byte image of pane %s", len(data), paneID)
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// pngDataURL wraps a base64 PNG in a data URL
+func pngDataURL(image string) string {
+	return "data:image/png;base64," + image
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paneImageManager(command string, vision bool) *Manager {
+	return &Manager{
+		Config: &config.Config{
+			PaneImageCommand: command,
+			DefaultModel:     "eyes",
+			Models:           map[string]config.ModelConfig{"eyes": {Provider: "openrouter", Model: "m", Vision: vision}},
+		},
+		SessionOverrides: map[string]interface{}{},
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2"},
+	}
+}
+
+func TestPaneImageEnabled(t *testing.T) {
+	assert.True(t, paneImageManager("freeze -o {file}", true).paneImageEnabled())
+	assert.False(t, paneImageManager("", true).paneImageEnabled(), "no command")
+	assert.False(t, paneImageManager("freeze -o {file}", false).paneImageEnabled(), "model without vision")
+
+	manager := paneImageManager("freeze -o {file}", true)
+	manager.Config.Models["eyes"] = config.ModelConfig{Provider: "github-copilot", Model: "m", Vision: true}
+	assert.False(t, manager.paneImageEnabled(), "copilot takes no inline images")
+}
+
+func TestCapturePaneImage(t *testing.T) {
+	manager := paneImageManager(`printf '\211PNG pane %s' {pane} > {file}`, true)
+
+	image := manager.paneImage()
+	data, err := base64.StdEncoding.DecodeString(image)
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG pane %2", string(data))
+
+	manager.Config.PaneImageCommand = "echo not an image > {file}"
+	_, err = manager.capturePaneImage("%2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PNG")
+
+	manager.Config.PaneImageCommand = "echo missing tool >&2; exit 127"
+	_, err = manager.capturePaneImage("%2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing tool")
+	assert.Empty(t, manager.paneImage(), "failures send the request without a picture")
+}
+
+func TestMessageImagesPerProvider(t *testing.T) {
+	msg := Message{Role: "user", Content: "what is in htop?", Images: []string{"aW1n"}}
+
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role":"user","content":[{"type":"text","text":"what is in htop?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aW1n"}}]}`, string(data))
+
+	data, err = json.Marshal(Message{Role: "user", Content: "plain"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role":"user","content":"plain"}`, string(data))
+
+	data, err = json.Marshal(responsesInput([]Message{msg}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"role":"user","content":[{"type":"input_text","text":"what is in htop?"},{"type":"input_image","image_url":"data:image/png;base64,aW1n"}]}]`, string(data))
+
+	data, err = json.Marshal(anthropicContent(msg))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"type":"text","text":"what is in htop?"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"aW1n"}}]`, string(data))
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// personaPrompt returns the active persona's prompt, added after the base
+// system prompt
+func (m *Manager) personaPrompt() string {
+	persona, ok := m.Config.Personas[m.GetPersona()]
+	if !ok || strings.TrimSpace(persona.Prompt) == "" {
+		return ""
+	}
+	return "\n" + strings.TrimSpace(persona.Prompt) + "\n"
+}
+
+// SwitchPersona makes the named persona active: its model becomes the
+// session's model and its KBs are loaded, and the KBs the previous persona
+// loaded are unloaded. "off" drops the persona and its KBs, keeping the model.
+func (m *Manager) SwitchPersona(name string) error {
+	var persona config.PersonaConfig
+	if name != "off" {
+		var ok bool
+		if persona, ok = m.Config.Personas[name]; !ok {
+			return fmt.Errorf("persona '%s' not found, available personas: %s", name, strings.Join(m.personaNames(), ", "))
+		}
+		if persona.Model != "" {
+			if _, exists := m.GetModelConfig(persona.Model); !exists {
+				return fmt.Errorf("model '%s' of persona '%s' not found", persona.Model, name)
+			}
+		}
+	}
+
+	for _, kb := range m.personaKBs {
+		if err := m.unloadKB(kb); err != nil {
+			logger.Debug("persona KB %s was already unloaded: %v", kb, err)
+		}
+	}
+	m.personaKBs = nil
+
+	if name == "off" {
+		// an empty override, so the config's persona doesn't come back
+		m.setOverride("persona", "")
+		return nil
+	}
+	m.setOverride("persona", name)
+	if persona.Model != "" {
+		m.SetModelsDefault(persona.Model)
+	}
+	for _, kb := range persona.KBs {
+		if _, loaded := m.LoadedKBs[kb]; loaded {
+			// loaded before, so it stays when the persona is switched off
+			continue
+		}
+		if err := m.loadKB(kb); err != nil {
+			logger.Error("Failed to load KB '%s' of persona %s: %v", kb, name, err)
+			m.Println(fmt.Sprintf("Warning: Failed to load KB '%s': %v", kb, err))
+			continue
+		}
+		m.personaKBs = append(m.personaKBs, kb)
+	}
+	logger.Info("Switched to persona %s", name)
+	return nil
+}
+
+// personaNames returns the configured persona names, sorted
+func (m *Manager) personaNames() []string {
+	names := make([]string, 0, len(m.Config.Personas))
+	for name := range m.Config.Personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listPersonas prints the configured personas, marking the active one
+func (m *Manager) listPersonas() {
+	names := m.personaNames()
+	if len(names) == 0 {
+		m.Println("No personas configured, add them under personas in config.yaml")
+		return
+	}
+	active := m.GetPersona()
+	m.Println("Available personas:")
+	for _, name := range names {
+		persona := m.Config.Personas[name]
+		status := "[ ]"
+		if name == active {
+			status = "[✓]"
+		}
+		var details []string
+		if persona.Model != "" {
+			details = append(details, "model: "+persona.Model)
+		}
+		if len(persona.KBs) > 0 {
+			details = append(details, "KBs: "+strings.Join(persona.KBs, ", "))
+		}
+		line := fmt.Sprintf("  %s %s", status, name)
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, "; ") + ")"
+		}
+		m.Println(line)
+	}
+}
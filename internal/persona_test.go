@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchPersona(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	kbDir := config.GetKBDir()
+	require.NoError(t, os.MkdirAll(kbDir, 0o755))
+	for _, name := range []string{"k8s", "docker"} {
+		require.NoError(t, os.WriteFile(filepath.Join(kbDir, name+".md"), []byte(name+" notes"), 0o644))
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Models = map[string]config.ModelConfig{
+		"careful": {Provider: "openai", Model: "gpt-4o", APIKey: "k"},
+		"fast":    {Provider: "openai", Model: "gpt-4o-mini", APIKey: "k"},
+	}
+	cfg.DefaultModel = "fast"
+	cfg.Personas = map[string]config.PersonaConfig{
+		"sre":   {Prompt: "Production: double-check before changing anything.", Model: "careful", KBs: []string{"k8s", "docker"}},
+		"proto": {Prompt: "Move fast.", Model: "fast"},
+		"bad":   {Model: "nope"},
+	}
+	manager := &Manager{Config: cfg, LoadedKBs: map[string]string{"docker": "docker notes"}, SessionOverrides: map[string]interface{}{}}
+	defer manager.Cleanup()
+
+	assert.Empty(t, manager.personaPrompt())
+	assert.Error(t, manager.SwitchPersona("missing"))
+	assert.Error(t, manager.SwitchPersona("bad"), "its model isn't configured")
+	assert.Empty(t, manager.GetPersona())
+
+	require.NoError(t, manager.SwitchPersona("sre"))
+	assert.Equal(t, "sre", manager.GetPersona())
+	assert.Equal(t, "careful", manager.GetModelsDefault())
+	assert.Contains(t, manager.LoadedKBs, "k8s")
+	assert.Equal(t, "\nProduction: double-check before changing anything.\n", manager.personaPrompt())
+	assert.Contains(t, manager.chatAssistantPrompt(false).Content, "Production: double-check")
+
+	require.NoError(t, manager.SwitchPersona("proto"))
+	assert.Equal(t, "fast", manager.GetModelsDefault())
+	assert.NotContains(t, manager.LoadedKBs, "k8s", "the previous persona's KBs are unloaded")
+	assert.Contains(t, manager.LoadedKBs, "docker", "KBs loaded before the persona stay")
+	assert.Equal(t, "\nMove fast.\n", manager.personaPrompt())
+
+	cfg.Persona = "sre"
+	require.NoError(t, manager.SwitchPersona("off"))
+	assert.Empty(t, manager.GetPersona(), "off hides the config's persona too")
+	assert.Empty(t, manager.personaPrompt())
+	assert.Equal(t, "fast", manager.GetModelsDefault())
+}
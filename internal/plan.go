@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/briandowns/spinner"
+)
+
+// planPrompt asks for a plan of task without acting on it yet
+func planPrompt(task string) string {
+	return "Before doing anything, write a short plan for this task as a numbered list, one step per line, " +
+		"each a command to run or a thing to check. Don't run commands, send keys, paste content or call tools yet, " +
+		"and don't use any tags: the plan will be shown to me to approve or edit first.\n\nTask: " + task
+}
+
+// executePlanMessage starts work on an approved plan
+func executePlanMessage(task, plan string) string {
+	return "Task: " + task + "\n\nI approved this plan:\n\n" + plan +
+		"\n\nCarry it out one step at a time, starting each reply with the number of the step you're on. " +
+		"If a step turns out to be wrong or impossible, stop and explain instead of improvising."
+}
+
+// plannedTask returns the task of a "/plan <task>" input, or of any message
+// while plan_mode is on
+func (m *Manager) plannedTask(input string) (string, bool) {
+	trimmed := strings.TrimSpace(input)
+	fields := strings.Fields(trimmed)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "/plan") {
+		task := strings.TrimSpace(trimmed[len(fields[0]):])
+		return task, task != ""
+	}
+	if m.GetPlanMode() && !m.IsMessageSubcommand(input) {
+		return input, true
+	}
+	return "", false
+}
+
+// requestPlan asks the model for a numbered plan of task, with the current
+// panes and chat history as context but without running anything
+func (m *Manager) requestPlan(ctx context.Context, task string) (string, error) {
+	prepared := m.ExecPane != nil && m.ExecPane.IsPrepared
+	messages := []ChatMessage{m.chatAssistantPrompt(prepared)}
+	messages = append(messages, m.Messages...)
+	messages = append(messages, ChatMessage{
+		Content:   m.getTmuxPanesInXml(m.Config) + "\n\n" + planPrompt(task),
+		FromUser:  true,
+		Timestamp: time.Now(),
+	})
+
+	response, err := m.AiClient.GetResponseFromChatMessages(ctx, messages, m.GetModel())
+	if err != nil {
+		return "", err
+	}
+	if m.Config.Debug {
+		debugChatMessages(messages, response)
+	}
+
+	plan := response
+	if r, err := m.parseAIResponse(response); err == nil {
+		plan = r.Message
+	}
+	plan = strings.TrimSpace(plan)
+	if plan == "" {
+		return "", fmt.Errorf("the model replied without a plan")
+	}
+	return plan, nil
+}
+
+// runPlanned has the model plan task, lets the user approve or edit the
+// plan, and only then starts the agent loop on it. It returns whether the
+// task was accomplished.
+func (m *Manager) runPlanned(ctx context.Context, task string) bool {
+	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
+	s.Start()
+	plan, err := m.requestPlan(ctx, task)
+	s.Stop()
+	if err != nil {
+		if ctx.Err() == nil {
+			m.Println("Failed to get a plan: " + err.Error())
+		}
+		return false
+	}
+	logger.Debug("Plan for %q:\n%s", task, plan)
+
+	fmt.Println(system.FormatMessage(plan, m.GetRenderMarkdown()))
+	approved, plan := m.confirmedPlan(plan)
+	if !approved || m.Status == "" {
+		m.Println("Plan discarded")
+		return false
+	}
+	return m.ProcessUserMessage(ctxWithTypedInput(ctx), executePlanMessage(task, plan))
+}
+
+// confirmedPlanFn asks whether to carry out a plan, offering to edit it in
+// $EDITOR first
+func (m *Manager) confirmedPlanFn(plan string) (bool, string) {
+	promptStr := system.CurrentTheme().Confirm.Sprint("Execute this plan? [Y/n/e]: ")
+	input, cancelled, err := m.readConfirmation(promptStr)
+	if err != nil {
+		fmt.Printf("Error reading confirmation: %v\n", err)
+		return false, ""
+	}
+	if cancelled {
+		m.setStatus("")
+		return false, ""
+	}
+
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "", "y", "yes", "ok", "sure":
+		return true, plan
+	case "e", "edit":
+		edited, err := startEditor(plan)
+		if err != nil {
+			fmt.Printf("Error running editor: %v\n", err)
+			return false, ""
+		}
+		edited = strings.TrimSpace(edited)
+		return edited != "", edited
+	case "n", "no", "cancel":
+		return false, ""
+	default:
+		return m.confirmedPlanFn(plan)
+	}
+}
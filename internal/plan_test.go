@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planManager answers the first request with a plan and the next ones as
+// done, recording the last message of each request
+func planManager(t *testing.T, lastMessages *[]string) *Manager {
+	t.Helper()
+	replies := []string{"1. Run make build\n2. Fix the errors", "Step 1: built fine.<RequestAccomplished>1</RequestAccomplished>"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		*lastMessages = append(*lastMessages, body.Messages[len(body.Messages)-1].Content)
+		reply := replies[min(len(*lastMessages), len(replies))-1]
+		content, _ := json.Marshal(reply)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":` + string(content) + `}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}}
+	cfg.SaveSessions = false
+	cfg.RenderMarkdown = false
+	manager := &Manager{
+		Config:           cfg,
+		Status:           "running",
+		SessionOverrides: map[string]interface{}{},
+		LoadedKBs:        map[string]string{},
+		LoadedSkills:     map[string]string{},
+		ExecPane:         &system.TmuxPaneDetails{Id: "%1", Shell: "bash"},
+	}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.getTmuxPanesInXml = func(*config.Config) string { return "<panes/>" }
+	manager.confirmedToExec = func(command, prompt string, edit bool) (bool, string) { return false, "" }
+	return manager
+}
+
+func TestPlannedTask(t *testing.T) {
+	manager := &Manager{Config: &config.Config{}, SessionOverrides: map[string]interface{}{}}
+
+	task, ok := manager.plannedTask("/plan  fix the build ")
+	assert.True(t, ok)
+	assert.Equal(t, "fix the build", task)
+	_, ok = manager.plannedTask("/plan")
+	assert.False(t, ok, "without a task /plan prints its usage")
+	_, ok = manager.plannedTask("fix the build")
+	assert.False(t, ok)
+
+	manager.SessionOverrides["plan_mode"] = true
+	task, ok = manager.plannedTask("fix the build")
+	assert.True(t, ok)
+	assert.Equal(t, "fix the build", task)
+	_, ok = manager.plannedTask("/model")
+	assert.False(t, ok, "commands aren't planned")
+}
+
+func TestRunPlannedExecutesTheEditedPlan(t *testing.T) {
+	var sent []string
+	manager := planManager(t, &sent)
+	var shown string
+	manager.confirmedPlan = func(plan string) (bool, string) {
+		shown = plan
+		return true, "1. Run make build"
+	}
+
+	assert.True(t, manager.runPlanned(context.Background(), "fix the build"))
+	assert.Equal(t, "1. Run make build\n2. Fix the errors", shown)
+	require.Len(t, sent, 2)
+	assert.Contains(t, sent[0], "Task: fix the build")
+	assert.Contains(t, sent[1], "I approved this plan:\n\n1. Run make build\n\nCarry it out")
+	assert.NotContains(t, sent[1], "Fix the errors", "the edited plan is the one carried out")
+}
+
+func TestRunPlannedStopsWhenDeclined(t *testing.T) {
+	var sent []string
+	manager := planManager(t, &sent)
+	manager.confirmedPlan = func(plan string) (bool, string) { return false, "" }
+
+	assert.False(t, manager.runPlanned(context.Background(), "fix the build"))
+	assert.Len(t, sent, 1, "nothing runs without an approved plan")
+	assert.Empty(t, manager.Messages)
+}
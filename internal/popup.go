@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// popupTimeout bounds the wait for the answer to a popup question
+const popupTimeout = 2 * time.Minute
+
+// popupCommandRe finds the command a popup answer suggests
+var popupCommandRe = regexp.MustCompile(`(?s)<Command>(.*?)</Command>`)
+
+// RunPopup asks one question about a pane and shows the answer, for
+// `tmuxai popup` inside tmux display-popup. An empty paneID asks about the
+// active pane, an empty question is read from stdin. A suggested command
+// can be typed into the pane, leaving Enter to the user.
+func RunPopup(cfg *config.Config, paneID, question string) error {
+	if err := system.SetTheme(cfg.Theme.Preset, cfg.Theme.Colors); err != nil {
+		logger.Error("Failed to set theme, using the default one: %v", err)
+	}
+	m := newStandaloneManager(cfg)
+	if paneID == "" {
+		var err error
+		if paneID, err = system.TmuxActivePaneId(); err != nil {
+			return err
+		}
+	}
+	return m.popup(bufio.NewReader(os.Stdin), os.Stdout, paneID, question)
+}
+
+// newStandaloneManager returns a Manager with just the config and an AI
+// client, for commands that ask the model without a chat session
+func newStandaloneManager(cfg *config.Config) *Manager {
+	m := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	m.AiClient = NewAiClient(cfg)
+	m.AiClient.SetConfigManager(m)
+	return m
+}
+
+// popup runs the popup's question and answer on in and out
+func (m *Manager) popup(in *bufio.Reader, out io.Writer, paneID, question string) error {
+	content, err := system.TmuxCapturePane(paneID, m.GetPaneCaptureLines(paneID))
+	if err != nil {
+		return fmt.Errorf("failed to capture pane %s: %w", paneID, err)
+	}
+	pane := system.TmuxPaneDetails{Id: paneID}
+	if panes, err := system.TmuxPanesDetails(paneID); err == nil && len(panes) > 0 {
+		pane = panes[0]
+	}
+
+	if question == "" {
+		fmt.Fprintf(out, "Ask about pane %s (%s in %s):\n» ", paneID, pane.CurrentCommand, pane.CurrentPath)
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return nil
+		}
+		question = strings.TrimSpace(line)
+		if question == "" {
+			return nil
+		}
+	}
+
+	fmt.Fprintln(out, "Thinking...")
+	ctx, cancel := context.WithTimeout(context.Background(), popupTimeout)
+	defer cancel()
+	messages := []ChatMessage{{Content: popupPrompt(pane, m.redactContext(content), question), FromUser: true, Timestamp: time.Now()}}
+	response, err := m.AiClient.GetResponseFromChatMessages(ctx, messages, m.GetModel())
+	if err != nil {
+		return fmt.Errorf("failed to get an answer: %w", err)
+	}
+	if m.Config.Debug {
+		debugChatMessages(messages, response)
+	}
+
+	answer, command := parsePopupAnswer(response)
+	if answer != "" {
+		fmt.Fprintln(out, system.FormatMessage(answer, m.GetRenderMarkdown()))
+	}
+	if command == "" {
+		fmt.Fprint(out, "\nPress Enter to close")
+		_, _ = in.ReadString('\n')
+		return nil
+	}
+
+	fmt.Fprintf(out, "\n$ %s\n", command)
+	if assessment := m.assessCommand(command); assessment.Level == RiskDanger {
+		fmt.Fprintf(out, "Flagged as dangerous: %s\n", strings.Join(assessment.Flags, ", "))
+	}
+	fmt.Fprintf(out, "Type it into pane %s? [y/N] ", paneID)
+	reply, _ := in.ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(reply), "y") {
+		return nil
+	}
+	if err := system.TmuxSendCommandToPane(paneID, command, false); err != nil {
+		return fmt.Errorf("failed to type the command into pane %s: %w", paneID, err)
+	}
+	m.audit("send_keys", paneID, command, auditApproved, nil)
+	return nil
+}
+
+// popupPrompt asks about a pane's content for a short answer, with at most
+// one command to type into it
+func popupPrompt(pane system.TmuxPaneDetails, content, question string) string {
+	return "You are a terminal assistant answering a quick question about a tmux pane " +
+		"running " + pane.CurrentCommand + " in " + pane.CurrentPath + ".\n\n" +
+		"The pane shows:\n" + content + "\n\n" +
+		"Question: " + question + "\n\n" +
+		"Answer in a few sentences. If a single shell command would help, put it on its own line as " +
+		"<Command>the command</Command>; it may be typed into the pane for the user to run."
+}
+
+// parsePopupAnswer splits a popup answer into its text and the command it
+// suggests, if any
+func parsePopupAnswer(response string) (answer, command string) {
+	if match := popupCommandRe.FindStringSubmatch(response); match != nil {
+		command = strings.TrimSpace(match[1])
+	}
+	answer = strings.TrimSpace(popupCommandRe.ReplaceAllString(response, ""))
+	return answer, command
+}
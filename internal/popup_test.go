@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePopupAnswer(t *testing.T) {
+	answer, command := parsePopupAnswer("The port is taken by another server.\n<Command>lsof -i :8080</Command>\n")
+	assert.Equal(t, "The port is taken by another server.", answer)
+	assert.Equal(t, "lsof -i :8080", command)
+
+	answer, command = parsePopupAnswer("Nothing to run here.")
+	assert.Equal(t, "Nothing to run here.", answer)
+	assert.Empty(t, command)
+}
+
+func TestPopupTypesSuggestedCommand(t *testing.T) {
+	originalCapture, originalDetails, originalSend := system.TmuxCapturePane, system.TmuxPanesDetails, system.TmuxSendCommandToPane
+	t.Cleanup(func() {
+		system.TmuxCapturePane, system.TmuxPanesDetails, system.TmuxSendCommandToPane = originalCapture, originalDetails, originalSend
+	})
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
+		return "$ npm start\nError: listen EADDRINUSE :::8080", nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		return []system.TmuxPaneDetails{{Id: target, CurrentCommand: "bash", CurrentPath: "/src/app"}}, nil
+	}
+	var sent []string
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		assert.Equal(t, "%3", paneId)
+		assert.False(t, autoenter, "Enter is left to the user")
+		sent = append(sent, command)
+		return nil
+	}
+
+	var asked string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		asked = string(body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Port 8080 is in use.\n<Command>lsof -i :8080</Command>"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.AuditLog = false
+	cfg.RenderMarkdown = false
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}}
+	m := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	m.AiClient = NewAiClient(cfg)
+	m.AiClient.SetConfigManager(m)
+
+	var out strings.Builder
+	in := bufio.NewReader(strings.NewReader("why did it fail?\ny\n"))
+	require.NoError(t, m.popup(in, &out, "%3", ""))
+
+	assert.Contains(t, asked, "EADDRINUSE", "the pane content is sent")
+	assert.Contains(t, asked, "why did it fail?")
+	assert.Contains(t, out.String(), "Port 8080 is in use.")
+	assert.Contains(t, out.String(), "$ lsof -i :8080")
+	assert.Equal(t, []string{"lsof -i :8080"}, sent)
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -11,6 +12,8 @@ import (
 	"github.com/alvinunreal/tmuxai/logger"
 	"github.com/alvinunreal/tmuxai/system"
 	"github.com/briandowns/spinner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type mcpDepthKey struct{}
@@ -28,6 +31,18 @@ func ctxWithMcpDepth(ctx context.Context, depth int) context.Context {
 	return context.WithValue(ctx, mcpDepthKey{}, depth)
 }
 
+// typedInputKey marks a context whose message was typed by the user, as
+// opposed to follow-ups TmuxAI sends on its own
+type typedInputKey struct{}
+
+func ctxWithTypedInput(ctx context.Context) context.Context {
+	return context.WithValue(ctx, typedInputKey{}, true)
+}
+
+// squashRetryKey marks a request resent after a context length error, so a
+// model that still rejects it isn't retried forever
+type squashRetryKey struct{}
+
 // Main function to process regular user messages
 // Returns true if the request was accomplished and no further processing should happen
 func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
@@ -36,10 +51,50 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 		return false
 	}
 
+	typed, _ := ctx.Value(typedInputKey{}).(bool)
+	if typed {
+		// follow-ups reuse ctx but weren't typed by the user
+		ctx = context.WithValue(ctx, typedInputKey{}, false)
+		if retried, _ := ctx.Value(squashRetryKey{}).(bool); !retried {
+			m.taskIterations, m.taskActions = 0, nil
+		}
+		m.inTask = true
+		defer func() { m.inTask = false }()
+	}
+	if !m.WatchMode {
+		m.taskIterations++
+		if !m.continuePastIterationLimit() {
+			m.setStatus("")
+			return false
+		}
+	}
+
+	// follow-ups run inside the span of the iteration that asked for them
+	ctx, span := tracer.Start(ctx, "tmuxai.iteration", trace.WithAttributes(attribute.Bool("tmuxai.typed", typed)))
+	defer span.End()
+
+	m.reloadConfig()
+	m.reloadChangedKBs()
+	m.reportFinishedJobs()
+
+	// watch mode observations go to the watch role's model, prompt included
+	if m.WatchMode && m.Config.Roles.Watch != "" {
+		if _, exists := m.GetModelConfig(m.Config.Roles.Watch); exists {
+			defer m.selectModel(m.Config.Roles.Watch)()
+		}
+	}
+
+	// a task sends full pane captures first, then only what changed
+	if typed && m.GetPaneDiff() && !m.WatchMode {
+		m.paneSnapshots = make(map[string]string)
+		defer func() { m.paneSnapshots = nil }()
+	}
+
 	// Check if context management is needed before sending
 	if m.needSquash() {
 		m.Println("Exceeded context size, squashing history...")
 		m.squashHistory()
+		m.resetPaneSnapshots()
 	}
 
 	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
@@ -52,13 +107,19 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	}
 
 	currentTmuxWindow := m.getTmuxPanesInXml(m.Config)
+	// follow-ups carry command output, the user's own words are sent as typed
+	if !typed {
+		message = m.redactContext(message)
+	}
 	execPaneEnv := ""
 	if !m.ExecPane.IsSubShell {
 		execPaneEnv = fmt.Sprintf("Keep in mind, you are working within the shell: %s and OS: %s", m.ExecPane.Shell, m.ExecPane.OS)
 	}
 	currentMessage := ChatMessage{
 		Content:   currentTmuxWindow + "\n\n" + execPaneEnv + "\n\n" + message,
+		Request:   message,
 		FromUser:  true,
+		Typed:     typed,
 		Timestamp: time.Now(),
 	}
 
@@ -92,12 +153,34 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	}
 
 	// Inject loaded knowledge bases after system prompt
-	for kbName, kbContent := range m.LoadedKBs {
-		history = append(history, ChatMessage{
-			Content:   fmt.Sprintf("=== Knowledge Base: %s ===\n%s", kbName, kbContent),
-			FromUser:  false,
-			Timestamp: time.Now(),
-		})
+	if m.Config.KnowledgeBase.Search.Enabled {
+		// search mode: only the chunks relevant to the request
+		m.kbExcerptsSent = ""
+		if len(m.LoadedKBs) > 0 {
+			m.kbExcerptsSent = m.kbExcerpts(m.kbSearchQuery(message, typed))
+		}
+		if m.kbExcerptsSent != "" {
+			history = append(history, ChatMessage{
+				Content:   m.kbExcerptsSent,
+				FromUser:  false,
+				Timestamp: time.Now(),
+			})
+		}
+	} else {
+		// Sorted so the prompt prefix stays identical between requests
+		kbContents := m.kbPromptContents()
+		kbNames := make([]string, 0, len(kbContents))
+		for kbName := range kbContents {
+			kbNames = append(kbNames, kbName)
+		}
+		sort.Strings(kbNames)
+		for _, kbName := range kbNames {
+			history = append(history, ChatMessage{
+				Content:   fmt.Sprintf("=== Knowledge Base: %s ===\n%s", kbName, kbContents[kbName]),
+				FromUser:  false,
+				Timestamp: time.Now(),
+			})
+		}
 	}
 
 	// Inject loaded skill bodies (m.LoadedSkills map)
@@ -121,10 +204,17 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 
 	sending := append(history, currentMessage)
 
+	// vision models also see the exec pane as a picture, only in this request
+	if image := m.paneImage(); image != "" {
+		withImage := currentMessage
+		withImage.Images = []string{image}
+		sending = append(history, withImage)
+	}
+
 	// Check if AI configuration is available before making the API call
 	if !m.hasValidAIConfiguration() {
 		s.Stop()
-		m.Status = ""
+		m.setStatus("")
 		fmt.Println("⚠️  No AI configuration found.")
 		fmt.Println("Please configure your AI settings:")
 		fmt.Println("  • Add model configurations to ~/.config/tmuxai/config.yaml")
@@ -141,10 +231,35 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 		return false
 	}
 
-	response, err := m.AiClient.GetResponseFromChatMessages(ctx, sending, m.GetModel())
+	var printer *streamPrinter
+	aiCtx := ctx
+	if modelConfig, exists := m.GetCurrentModelConfig(); exists && modelConfig.Stream {
+		printer = newStreamPrinter(os.Stdout, s.Stop)
+		aiCtx = ctxWithStreamHandler(ctx, printer.Write)
+	}
+
+	requestStart := time.Now()
+	response, err := m.AiClient.GetResponseFromChatMessages(aiCtx, sending, m.GetModel())
+	streamed := printer != nil && printer.Finish()
 	if err != nil {
+		m.recordExchange(sending, m.GetModel(), response, nil, err, requestStart)
 		s.Stop()
-		m.Status = ""
+
+		// the estimate in needSquash can be off, or max_context_size larger
+		// than the model allows: squash harder and send the request again
+		if retried, _ := ctx.Value(squashRetryKey{}).(bool); !retried && isContextLengthError(err) {
+			m.Println("Context too large for the model, squashing history and retrying...")
+			if m.squashHistory() || m.compactHistory(0) {
+				m.resetPaneSnapshots()
+				retryCtx := context.WithValue(ctx, squashRetryKey{}, true)
+				if typed {
+					retryCtx = ctxWithTypedInput(retryCtx)
+				}
+				return m.ProcessUserMessage(retryCtx, message)
+			}
+		}
+
+		m.setStatus("")
 
 		if ctx.Err() == context.Canceled {
 			return false
@@ -170,8 +285,9 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 
 	r, err := m.parseAIResponse(response)
 	if err != nil {
+		m.recordExchange(sending, m.GetModel(), response, nil, err, requestStart)
 		s.Stop()
-		m.Status = ""
+		m.setStatus("")
 
 		// Log both to console and debug file
 		errMsg := "Failed to parse AI response: " + err.Error()
@@ -185,6 +301,7 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 		return false
 	}
 
+	m.recordExchange(sending, m.GetModel(), response, &r, nil, requestStart)
 	if m.Config.Debug {
 		debugChatMessages(append(history, currentMessage), response)
 	}
@@ -203,48 +320,228 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	guidelineError, validResponse := m.aiFollowedGuidelines(r)
 	if !validResponse {
 		m.Println("AI didn't follow guidelines, trying again...")
-		m.Messages = append(m.Messages, currentMessage, responseMsg)
+		m.appendMessages(currentMessage, responseMsg)
 		return m.ProcessUserMessage(ctx, guidelineError)
 
 	}
 
-	// colorize code blocks in the response
-	if r.Message != "" {
-		fmt.Println(system.Cosmetics(r.Message))
+	// render the response as Markdown, unless it was already streamed
+	if r.Message != "" && !streamed {
+		fmt.Println(system.FormatMessage(r.Message, m.GetRenderMarkdown()))
+	}
+	if m.WatchMode {
+		m.reportWatch(r)
 	}
 
 	// Don't append to history if AI is waiting for the pane or is watch mode no comment
 	// Also defer appending when MCP tool calls are present — the MCP block handles it
 	if r.ExecPaneSeemsBusy || r.NoComment || (len(r.MCPToolCalls) > 0 && m.McpManager != nil) {
 	} else {
-		m.Messages = append(m.Messages, currentMessage, responseMsg)
+		m.appendMessages(currentMessage, responseMsg)
+	}
+
+	// actions skipped in dry-run mode, reported back instead of pane changes
+	var dryRunActions []string
+	// commands run in the Docker sandbox, whose output isn't in any pane
+	var sandboxResults []CommandExecHistory
+	// commands that ran past their timeout in a prepared pane
+	var timedOut []CommandExecHistory
+	// commands stopped mid-run after the model reviewed their output
+	var aborted []CommandExecHistory
+	// commands started as background jobs, and subagents
+	var startedJobs []*backgroundJob
+	// subagents that couldn't be started, and why
+	var spawnFailures []string
+	// files read for the model, sent back to it
+	var fileReads []fileRead
+	// outcomes of WriteFile and ApplyPatch changes
+	var fileWrites []fileWrite
+
+	// commands the user left out of a batch confirmation
+	var skipped []string
+	// actions aimed at a read-only pane, not sent
+	var readOnlyActions []string
+
+	// several commands to confirm are listed and confirmed up front
+	var batch []string
+	if commands := m.execBatch(r); commands != nil {
+		if batch = m.confirmedBatch(commands); batch == nil {
+			for _, execCommand := range r.ExecCommand {
+				m.audit("exec_command", m.ExecPane.Id, execCommand, auditDeclined, nil)
+			}
+			m.setStatus("")
+			return false
+		}
 	}
 
 	// observe/prepared mode
-	for _, execCommand := range r.ExecCommand {
+	// commands for a named exec pane run with ExecPane switched to it
+	defaultExecPane := m.ExecPane
+	for i, execCommand := range r.ExecCommand {
+		m.setExecPane(defaultExecPane)
+		if name := r.execCommandPane(i); name != "" {
+			pane, err := m.namedExecPane(name)
+			if err != nil {
+				m.Println(fmt.Sprintf("Skipping command: %v", err))
+				continue
+			}
+			m.setExecPane(pane)
+			m.Println(fmt.Sprintf("In exec pane %s (%s):", name, pane.Id))
+		}
 		code, _ := system.HighlightCode("sh", execCommand)
 		m.Println(code)
+		if m.paneReadOnly(*m.ExecPane) {
+			m.Println(fmt.Sprintf("Skipping command: pane %s is read-only", m.ExecPane.Id))
+			m.audit("exec_command", m.ExecPane.Id, execCommand, auditReadOnly, nil)
+			readOnlyActions = append(readOnlyActions, "exec_command: "+execCommand)
+			continue
+		}
+
+		if m.GetDryRun() {
+			dryRunActions = append(dryRunActions, m.skipDryRunAction("exec_command", execCommand))
+			continue
+		}
 
 		isSafe := false
 		command := execCommand
-		if m.GetExecConfirm() {
-			isSafe, command = m.confirmedToExec(execCommand, "Execute this command?", true)
+		decision := auditAuto
+		if batch != nil {
+			if command = batch[i]; command == "" {
+				m.Println("Skipping command, left out")
+				m.audit("exec_command", m.ExecPane.Id, execCommand, auditDeclined, nil)
+				skipped = append(skipped, execCommand)
+				continue
+			}
+			isSafe = true
+			if whitelisted, _ := m.whitelistCheck(execCommand); whitelisted || m.needsExecConfirm(execCommand) {
+				decision = m.auditDecision(execCommand, command, true)
+			}
+		} else if m.needsExecConfirm(execCommand) {
+			isSafe, command = m.confirmedToExec(execCommand, execConfirmPrompt, true)
+			decision = m.auditDecision(execCommand, command, isSafe)
 		} else {
 			isSafe = true
 		}
 		if isSafe {
+			if allowed, reason := m.preExecAllowed(m.ExecPane.Id, command, decision); !allowed {
+				m.Println("Blocked by the pre_exec hook: " + reason)
+				m.audit("exec_command", m.ExecPane.Id, command, auditHookBlocked, nil)
+				m.setExecPane(defaultExecPane)
+				m.setStatus("")
+				return false
+			}
+		}
+		if isSafe && m.GetSandbox() {
+			m.Println("Executing in sandbox: " + command)
+			history, err := m.ExecInSandbox(command)
+			if err != nil {
+				m.Println(fmt.Sprintf("Sandbox error: %v", err))
+				history.Output = strings.TrimSpace(history.Output + "\n" + err.Error())
+				history.Code = -1
+			} else if history.Output != "" {
+				m.Println(history.Output)
+			}
+			m.audit("exec_command", "sandbox:"+m.Config.Sandbox.Image, command, decision, &history.Code)
+			sandboxResults = append(sandboxResults, history)
+		} else if isSafe && r.execCommandBackground(i) {
+			job, err := m.startBackgroundJob(command)
+			if err != nil {
+				m.Println(fmt.Sprintf("Failed to start background job: %v", err))
+				continue
+			}
+			m.Println(fmt.Sprintf("Started background job %d in pane %s: %s", job.ID, job.PaneID, command))
+			m.audit("exec_command", job.PaneID, command, decision, nil)
+			startedJobs = append(startedJobs, job)
+		} else if isSafe {
 			m.Println("Executing command: " + command)
 			if m.ExecPane.IsPrepared {
-				_, _ = m.ExecWaitCapture(command)
+				timeout := r.execCommandTimeout(i)
+				if timeout == 0 {
+					timeout = time.Duration(m.GetExecTimeout()) * time.Second
+				}
+				history, err := m.tracedExecWaitCapture(ctx, command, timeout)
+				var exitCode *int
+				if err == nil && history.Code >= 0 {
+					exitCode = &history.Code
+				}
+				m.audit("exec_command", m.ExecPane.Id, command, decision, exitCode)
+				// later commands could land in the still running one or depend on it
+				if history.TimedOut {
+					timedOut = append(timedOut, history)
+					break
+				}
+				if history.AbortReason != "" {
+					aborted = append(aborted, history)
+					break
+				}
 			} else {
 				_ = system.TmuxSendCommandToPane(m.ExecPane.Id, command, true)
+				m.audit("exec_command", m.ExecPane.Id, command, decision, nil)
 				time.Sleep(1 * time.Second)
 			}
 		} else {
-			m.Status = ""
+			m.audit("exec_command", m.ExecPane.Id, execCommand, decision, nil)
+			m.setExecPane(defaultExecPane)
+			m.setStatus("")
 			return false
 		}
 	}
+	m.setExecPane(defaultExecPane)
+
+	for _, task := range r.SpawnAgent {
+		if m.GetDryRun() {
+			dryRunActions = append(dryRunActions, m.skipDryRunAction("spawn_agent", task))
+			continue
+		}
+		job, err := m.startSubagent(task)
+		if err != nil {
+			m.Println(fmt.Sprintf("Failed to start agent: %v", err))
+			spawnFailures = append(spawnFailures, fmt.Sprintf("%s: %v", task, err))
+			continue
+		}
+		m.Println(fmt.Sprintf("Started agent %d in pane %s: %s", job.ID, job.PaneID, task))
+		m.audit("spawn_agent", job.PaneID, task, auditAuto, nil)
+		startedJobs = append(startedJobs, job)
+	}
+
+	var changes []fileChange
+	for i, content := range r.WriteFile {
+		change, err := m.proposeWrite(r.writeFilePath(i), content)
+		if err != nil {
+			m.Println(fmt.Sprintf("Can't write %s: %v", r.writeFilePath(i), err))
+			fileWrites = append(fileWrites, fileWrite{Path: r.writeFilePath(i), Status: "failed", Err: err})
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for _, patch := range r.ApplyPatch {
+		patchChanges, err := m.proposePatch(patch)
+		if err != nil {
+			m.Println(fmt.Sprintf("Can't apply patch: %v", err))
+			fileWrites = append(fileWrites, fileWrite{Path: "patch", Status: "failed", Err: err})
+			continue
+		}
+		changes = append(changes, patchChanges...)
+	}
+	if len(changes) > 0 {
+		writes, ok := m.writeFiles(changes)
+		fileWrites = append(fileWrites, writes...)
+		if !ok {
+			m.setStatus("")
+			return false
+		}
+	}
+
+	for _, path := range r.ReadFile {
+		read := m.readFile(path)
+		if read.Err != nil {
+			m.Println(fmt.Sprintf("Failed to read %s: %v", path, read.Err))
+		} else {
+			m.Println(fmt.Sprintf("Read %s (%d bytes)", path, read.Size))
+		}
+		m.audit("read_file", "", path, auditAuto, nil)
+		fileReads = append(fileReads, read)
+	}
 
 	// Process SendKeys
 	if len(r.SendKeys) > 0 {
@@ -264,27 +561,40 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 
 		m.Println(keysPreview)
 
-		// Determine confirmation message based on number of keys
-		confirmMessage := "Send this key?"
-		if len(r.SendKeys) > 1 {
-			confirmMessage = "Send all these keys?"
-		}
+		if m.GetDryRun() {
+			dryRunActions = append(dryRunActions, m.skipDryRunAction("send_keys", strings.Join(r.SendKeys, " ")))
+		} else if m.paneReadOnly(*m.ExecPane) {
+			m.Println(fmt.Sprintf("Not sending keys: pane %s is read-only", m.ExecPane.Id))
+			m.audit("send_keys", m.ExecPane.Id, strings.Join(r.SendKeys, " "), auditReadOnly, nil)
+			readOnlyActions = append(readOnlyActions, "send_keys: "+strings.Join(r.SendKeys, " "))
+		} else {
+			// Determine confirmation message based on number of keys
+			confirmMessage := "Send this key?"
+			if len(r.SendKeys) > 1 {
+				confirmMessage = "Send all these keys?"
+			}
 
-		// Get confirmation if required
-		var allConfirmed bool
-		if m.GetSendKeysConfirm() {
-			allConfirmed, _ = m.confirmedToExec("keys shown above", confirmMessage, true)
-			if !allConfirmed {
-				m.Status = ""
-				return false
+			// Get confirmation if required
+			var allConfirmed bool
+			decision := auditAuto
+			if m.GetSendKeysConfirm() {
+				allConfirmed, _ = m.confirmedToExec("keys shown above", confirmMessage, true)
+				decision = auditApproved
+				if !allConfirmed {
+					decision = auditDeclined
+					m.audit("send_keys", m.ExecPane.Id, strings.Join(r.SendKeys, " "), decision, nil)
+					m.setStatus("")
+					return false
+				}
 			}
-		}
 
-		// Send each key with delay
-		for _, sendKey := range r.SendKeys {
-			m.Println("Sending keys: " + sendKey)
-			_ = system.TmuxSendCommandToPane(m.ExecPane.Id, sendKey, false)
-			time.Sleep(1 * time.Second)
+			// Send each key with delay
+			for _, sendKey := range r.SendKeys {
+				m.Println("Sending keys: " + sendKey)
+				_ = system.TmuxTypeToPane(m.ExecPane.Id, sendKey, m.GetTypingDelays())
+				m.audit("send_keys", m.ExecPane.Id, sendKey, decision, nil)
+				time.Sleep(1 * time.Second)
+			}
 		}
 	}
 
@@ -300,23 +610,35 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	}
 
 	// observe or prepared mode
-	if r.PasteMultilineContent != "" {
+	if r.PasteMultilineContent != "" && m.paneReadOnly(*m.ExecPane) && !m.GetDryRun() {
+		m.Println(fmt.Sprintf("Not pasting: pane %s is read-only", m.ExecPane.Id))
+		m.audit("paste_multiline_content", m.ExecPane.Id, r.PasteMultilineContent, auditReadOnly, nil)
+		readOnlyActions = append(readOnlyActions, "paste_multiline_content: "+r.PasteMultilineContent)
+	} else if r.PasteMultilineContent != "" {
 		code, _ := system.HighlightCode("txt", r.PasteMultilineContent)
 		fmt.Println(code)
 
 		isSafe := false
-		if m.GetPasteMultilineConfirm() {
+		decision := auditAuto
+		if m.GetPasteMultilineConfirm() && !m.GetDryRun() {
 			isSafe, _ = m.confirmedToExec(r.PasteMultilineContent, "Paste multiline content?", false)
+			decision = m.auditDecision(r.PasteMultilineContent, r.PasteMultilineContent, isSafe)
 		} else {
 			isSafe = true
 		}
 
-		if isSafe {
+		if m.GetDryRun() {
+			dryRunActions = append(dryRunActions, m.skipDryRunAction("paste_multiline_content", r.PasteMultilineContent))
+		} else if isSafe {
 			m.Println("Pasting...")
-			_ = system.TmuxSendCommandToPane(m.ExecPane.Id, r.PasteMultilineContent, true)
+			if err := pasteToPane(m.ExecPane.Id, r.PasteMultilineContent); err != nil {
+				m.Println(fmt.Sprintf("Error pasting: %v", err))
+			}
+			m.audit("paste_multiline_content", m.ExecPane.Id, r.PasteMultilineContent, decision, nil)
 			time.Sleep(1 * time.Second)
 		} else {
-			m.Status = ""
+			m.audit("paste_multiline_content", m.ExecPane.Id, r.PasteMultilineContent, decision, nil)
+			m.setStatus("")
 			return false
 		}
 	}
@@ -327,13 +649,24 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 		s.Restart()
 
 		// Append user message and AI response first
-		m.Messages = append(m.Messages, currentMessage, responseMsg)
+		m.appendMessages(currentMessage, responseMsg)
 
 		for _, call := range r.MCPToolCalls {
 			displayName := strings.TrimPrefix(call.Name, "mcp__")
 			displayName = strings.ReplaceAll(displayName, "__", ".")
 			m.Println("MCP tool call: " + displayName)
 
+			if m.GetMcpConfirm() {
+				s.Stop()
+				args, _ := system.HighlightCode("json", string(call.Arguments))
+				m.Println(args)
+				if ok, _ := m.confirmedToExec(displayName+" "+string(call.Arguments), "Run this MCP tool?", false); !ok {
+					m.setStatus("")
+					return false
+				}
+				s.Restart()
+			}
+
 			result, isErr := mcp.ExecuteToolCall(ctx, m.McpManager, m.McpRegistry, call.Name, call.Arguments)
 			if isErr {
 				logger.Info("MCP tool %s returned error result", call.Name)
@@ -341,7 +674,7 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 			safeResult := sanitizeXML(result)
 
 			// Each tool result as separate message
-			m.Messages = append(m.Messages, ChatMessage{
+			m.appendMessages(ChatMessage{
 				Content:   fmt.Sprintf("<ToolResult name=\"%s\">%s</ToolResult>", call.Name, safeResult),
 				FromUser:  false,
 				Timestamp: time.Now(),
@@ -357,12 +690,19 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	}
 
 	if r.RequestAccomplished {
-		m.Status = ""
+		m.setStatus("")
+		m.notifyHook(hookEvent{Event: hookOnTaskDone, Request: m.lastTypedRequest(), Message: r.Message})
+		return true
+	}
+
+	// reportWatch has already sent the notification
+	if r.WatchGoalMet && m.WatchMode && m.watchGoal != "" {
+		m.Println("✓ Watch goal met, stopping watch mode: " + m.watchGoal)
 		return true
 	}
 
 	if r.WaitingForUserResponse {
-		m.Status = "waiting"
+		m.setStatus("waiting")
 		return false
 	}
 
@@ -372,7 +712,42 @@ func (m *Manager) ProcessUserMessage(ctx context.Context, message string) bool {
 	}
 
 	if !m.WatchMode {
-		accomplished := m.ProcessUserMessage(ctx, "sending updated pane(s) content")
+		// every action that reported something is passed on, a response can
+		// both run commands and read files
+		var observations []string
+		if len(dryRunActions) > 0 {
+			observations = append(observations, dryRunObservation(dryRunActions))
+		}
+		if len(timedOut) > 0 {
+			observations = append(observations, m.timeoutObservation(timedOut))
+		}
+		if len(aborted) > 0 {
+			observations = append(observations, abortedObservation(aborted))
+		}
+		if len(sandboxResults) > 0 {
+			observations = append(observations, sandboxObservation(sandboxResults, m.GetMaxCaptureLines()))
+		}
+		if len(spawnFailures) > 0 {
+			observations = append(observations, spawnFailuresObservation(spawnFailures))
+		}
+		if len(startedJobs) > 0 {
+			observations = append(observations, jobsObservation(startedJobs))
+		}
+		// writes are applied before reads, so contents read may include them
+		if len(fileWrites) > 0 {
+			observations = append(observations, writeFilesObservation(fileWrites))
+		}
+		if len(fileReads) > 0 {
+			observations = append(observations, readFilesObservation(fileReads))
+		}
+		followUp := followUpMessage(observations)
+		if len(skipped) > 0 {
+			followUp = skippedCommandsObservation(skipped) + followUp
+		}
+		if len(readOnlyActions) > 0 {
+			followUp = readOnlyObservation(readOnlyActions) + followUp
+		}
+		accomplished := m.ProcessUserMessage(ctx, followUp)
 		if accomplished {
 			return true
 		}
@@ -387,21 +762,26 @@ func (m *Manager) startWatchMode(desc string) {
 		return
 	}
 
-	m.Countdown(m.GetWaitInterval())
+	m.Countdown(m.GetWatchInterval())
 
-	// Create a new background context since this is a separate process
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// a skipped check keeps desc for the next one, so it still gets sent
+	next := desc
+	if m.watchTriggered() {
+		// Create a new background context since this is a separate process
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-	accomplished := m.ProcessUserMessage(ctx, desc)
-	if accomplished {
-		m.WatchMode = false
-		m.Status = ""
+		accomplished := m.ProcessUserMessage(ctx, desc)
+		if accomplished {
+			m.setWatchMode(false)
+			m.setStatus("")
+		}
+		next = ""
 	}
 
 	// we continue running if status is still set
 	if m.Status != "" && m.WatchMode {
-		m.startWatchMode("")
+		m.startWatchMode(next)
 	}
 }
 
@@ -419,6 +799,9 @@ func (m *Manager) aiFollowedGuidelines(r AIResponse) (string, bool) {
 	if r.NoComment {
 		boolCount++
 	}
+	if r.WatchGoalMet {
+		boolCount++
+	}
 
 	if boolCount > 1 {
 		return "You didn't follow the guidelines. Only one boolean flag should be set to true in your response. Pay attention!", false
@@ -434,6 +817,18 @@ func (m *Manager) aiFollowedGuidelines(r AIResponse) (string, bool) {
 	if r.PasteMultilineContent != "" {
 		nonMcpTags++
 	}
+	if len(r.SpawnAgent) > 0 {
+		nonMcpTags++
+	}
+	if len(r.ReadFile) > 0 {
+		nonMcpTags++
+	}
+	if len(r.WriteFile) > 0 {
+		nonMcpTags++
+	}
+	if len(r.ApplyPatch) > 0 {
+		nonMcpTags++
+	}
 
 	if nonMcpTags > 1 {
 		return "You didn't follow the guidelines. You can only use one type of XML tag in your response. Pay attention!", false
@@ -455,3 +850,27 @@ func sanitizeXML(s string) string {
 	s = strings.ReplaceAll(s, ">", "&gt;")
 	return s
 }
+
+// skipDryRunAction reports and logs an action that dry-run mode won't send,
+// returning its description for the observation fed back to the model
+func (m *Manager) skipDryRunAction(kind, content string) string {
+	m.Println(fmt.Sprintf("[dry-run] Not sent to pane %s (%s)", m.ExecPane.Id, kind))
+	logger.Info("dry-run: skipped %s in pane %s: %s", kind, m.ExecPane.Id, content)
+	m.audit(kind, m.ExecPane.Id, content, auditDryRun, nil)
+	return kind + ": " + content
+}
+
+// followUpMessage joins the observations of a response's actions into the
+// message that continues the task, a plain pane update when there are none
+func followUpMessage(observations []string) string {
+	if len(observations) == 0 {
+		return "sending updated pane(s) content"
+	}
+	return strings.Join(observations, "\n\n")
+}
+
+func dryRunObservation(actions []string) string {
+	return "Dry-run mode is on: these actions were NOT executed and the pane(s) are unchanged:\n- " +
+		strings.Join(actions, "\n- ") +
+		"\nContinue with the next step you would take, without assuming any of their output."
+}
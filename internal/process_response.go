@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alvinunreal/tmuxai/internal/mcp"
 )
@@ -19,17 +21,17 @@ type tagRegexes struct {
 }
 
 var tagNames = []string{
-	"TmuxSendKeys", "ExecCommand", "PasteMultilineContent",
-	"RequestAccomplished", "ExecPaneSeemsBusy", "WaitingForUserResponse", "NoComment",
+	"TmuxSendKeys", "ExecCommand", "PasteMultilineContent", "SpawnAgent", "ReadFile", "WriteFile", "ApplyPatch",
+	"RequestAccomplished", "ExecPaneSeemsBusy", "WaitingForUserResponse", "NoComment", "WatchGoalMet",
 }
 
 var tagPatterns = func() map[string]*tagRegexes {
 	patterns := make(map[string]*tagRegexes, len(tagNames))
 	for _, name := range tagNames {
 		patterns[name] = &tagRegexes{
-			tag:       regexp.MustCompile(fmt.Sprintf(`(?s)<%s>(.*?)</%s>`, name, name)),
-			codeBlock: regexp.MustCompile(fmt.Sprintf("(?s)```(?:xml)?\\s*<%s>.*?</%s>\\s*```", name, name)),
-			backtick:  regexp.MustCompile(fmt.Sprintf("`<%s>.*?</%s>`", name, name)),
+			tag:       regexp.MustCompile(fmt.Sprintf(`(?s)<%s(\s[^>]*)?>(.*?)</%s>`, name, name)),
+			codeBlock: regexp.MustCompile(fmt.Sprintf("(?s)```(?:xml)?\\s*<%s(?:\\s[^>]*)?>.*?</%s>\\s*```", name, name)),
+			backtick:  regexp.MustCompile(fmt.Sprintf("`<%s(?:\\s[^>]*)?>.*?</%s>`", name, name)),
 			boolPat:   regexp.MustCompile(fmt.Sprintf(`(?s)(<%s>\s*</%s>|<%s>\s*|`+"```<%s>```"+`|<%s/>)`, name, name, name, name, name)),
 			leftover:  regexp.MustCompile(fmt.Sprintf(`(?m)^\s*(<%s>\s*|`+"```<%s>```"+`)?\s*$`, name, name)),
 		}
@@ -43,9 +45,15 @@ var (
 	mcpBacktickRe  = regexp.MustCompile("`<MCPToolCall>.*?</MCPToolCall>`")
 	mcpTagRe       = regexp.MustCompile(`(?s)<MCPToolCall>.*?</MCPToolCall>`)
 	multiNewlineRe = regexp.MustCompile(`\n{2,}`)
+	tagAttrRe      = regexp.MustCompile(`\b(\w+)\s*=\s*"([^"]*)"`)
 )
 
 func (m *Manager) parseAIResponse(response string) (AIResponse, error) {
+	// Structured output mode answers with JSON; anything else is XML tags
+	if r, ok := parseStructuredResponse(response); ok {
+		return r, nil
+	}
+
 	// Tag mapping: tag name -> field
 	type tagInfo struct {
 		name     string
@@ -57,27 +65,52 @@ func (m *Manager) parseAIResponse(response string) (AIResponse, error) {
 		{"TmuxSendKeys", true, false, func(r *AIResponse, v string) { r.SendKeys = append(r.SendKeys, v) }},
 		{"ExecCommand", true, false, func(r *AIResponse, v string) { r.ExecCommand = append(r.ExecCommand, v) }},
 		{"PasteMultilineContent", false, false, func(r *AIResponse, v string) { r.PasteMultilineContent = v }},
+		{"SpawnAgent", true, false, func(r *AIResponse, v string) { r.SpawnAgent = append(r.SpawnAgent, v) }},
+		{"ReadFile", true, false, func(r *AIResponse, v string) { r.ReadFile = append(r.ReadFile, v) }},
+		{"WriteFile", true, false, func(r *AIResponse, v string) { r.WriteFile = append(r.WriteFile, v) }},
+		{"ApplyPatch", true, false, func(r *AIResponse, v string) { r.ApplyPatch = append(r.ApplyPatch, v) }},
 		{"RequestAccomplished", false, true, func(r *AIResponse, v string) { r.RequestAccomplished = isTrue(v) }},
 		{"ExecPaneSeemsBusy", false, true, func(r *AIResponse, v string) { r.ExecPaneSeemsBusy = isTrue(v) }},
 		{"WaitingForUserResponse", false, true, func(r *AIResponse, v string) { r.WaitingForUserResponse = isTrue(v) }},
 		{"NoComment", false, true, func(r *AIResponse, v string) { r.NoComment = isTrue(v) }},
+		{"WatchGoalMet", false, true, func(r *AIResponse, v string) { r.WatchGoalMet = isTrue(v) }},
 	}
 
 	clean := response
 	r := AIResponse{}
 	cleanForMsg := clean
+	// attributes of ExecCommand tags, kept only if any command sets them
+	var execPanes []string
+	var execTimeouts []int
+	var execBackground []bool
+	namedPane, timeout, background := false, false, false
 	for _, t := range tags {
 		pats := tagPatterns[t.name]
 		tagMatches := pats.tag.FindAllStringSubmatch(clean, -1)
 		for _, m := range tagMatches {
-			if len(m) < 2 {
+			if len(m) < 3 {
 				continue
 			}
-			val := strings.TrimSpace(m[1])
+			val := strings.TrimSpace(m[2])
 			if !t.isBool {
 				val = html.UnescapeString(val)
 			}
 			t.setField(&r, val)
+			if t.name == "ExecCommand" {
+				attrs := tagAttrs(m[1])
+				pane := strings.ToLower(attrs["pane"])
+				seconds := parseTimeoutSeconds(attrs["timeout"])
+				bg := isTrue(attrs["background"])
+				execPanes = append(execPanes, pane)
+				execTimeouts = append(execTimeouts, seconds)
+				execBackground = append(execBackground, bg)
+				namedPane = namedPane || pane != ""
+				timeout = timeout || seconds > 0
+				background = background || bg
+			}
+			if t.name == "WriteFile" {
+				r.WriteFilePaths = append(r.WriteFilePaths, tagAttrs(m[1])["path"])
+			}
 		}
 		// For message: remove all tag blocks, including code/backtick wrappers
 		cleanForMsg = pats.codeBlock.ReplaceAllString(cleanForMsg, "")
@@ -85,6 +118,16 @@ func (m *Manager) parseAIResponse(response string) (AIResponse, error) {
 		cleanForMsg = pats.tag.ReplaceAllString(cleanForMsg, "")
 	}
 
+	if namedPane {
+		r.ExecCommandPanes = execPanes
+	}
+	if timeout {
+		r.ExecCommandTimeouts = execTimeouts
+	}
+	if background {
+		r.ExecCommandBackground = execBackground
+	}
+
 	// Special handling: tags that may appear as <TagName> or ```<TagName>``` (no value)
 	for _, t := range tags {
 		if !t.isBool {
@@ -123,6 +166,30 @@ func (m *Manager) parseAIResponse(response string) (AIResponse, error) {
 	return r, nil
 }
 
+// tagAttrs parses the name="value" attributes of a tag
+func tagAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range tagAttrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = strings.TrimSpace(html.UnescapeString(m[2]))
+	}
+	return attrs
+}
+
+// parseTimeoutSeconds reads a timeout given as seconds ("30") or a duration
+// ("2m"), returning 0 if it's neither
+func parseTimeoutSeconds(s string) int {
+	if s == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(s); err == nil && seconds > 0 {
+		return seconds
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= time.Second {
+		return int(d / time.Second)
+	}
+	return 0
+}
+
 // Helper: check if string is "1" or "true" (case-insensitive)
 func isTrue(s string) bool {
 	s = strings.TrimSpace(strings.ToLower(s))
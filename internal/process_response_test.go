@@ -4,6 +4,7 @@ package internal
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Test: Single tag, inline
@@ -253,3 +254,64 @@ func TestParseAIResponse_MixedAIAndNonAIXMLTags(t *testing.T) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 }
+
+// Test: ExecCommand pane attributes route commands to named exec panes
+func TestParseAIResponse_ExecCommandPanes(t *testing.T) {
+	m := &Manager{}
+	input := "Restarting both.\n<ExecCommand pane=\"Backend\">make run</ExecCommand>\n<ExecCommand>ls</ExecCommand>\n`<ExecCommand pane=\"db\">psql</ExecCommand>`"
+	want := AIResponse{
+		Message:          "Restarting both.",
+		ExecCommand:      []string{"make run", "ls", "psql"},
+		ExecCommandPanes: []string{"backend", "", "db"},
+	}
+	got, err := m.parseAIResponse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if pane := got.execCommandPane(1); pane != "" {
+		t.Errorf("command without a pane attribute got pane %q", pane)
+	}
+}
+
+// Test: ExecCommand timeout attributes set per-command timeouts
+func TestParseAIResponse_ExecCommandTimeouts(t *testing.T) {
+	m := &Manager{}
+	input := "<ExecCommand timeout=\"90\">make test</ExecCommand>\n<ExecCommand pane=\"db\" timeout=\"2m\">psql -f load.sql</ExecCommand>\n<ExecCommand timeout=\"soon\">ls</ExecCommand>"
+	want := AIResponse{
+		ExecCommand:         []string{"make test", "psql -f load.sql", "ls"},
+		ExecCommandPanes:    []string{"", "db", ""},
+		ExecCommandTimeouts: []int{90, 120, 0},
+	}
+	got, err := m.parseAIResponse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if timeout := got.execCommandTimeout(1); timeout != 2*time.Minute {
+		t.Errorf("got timeout %s, want 2m", timeout)
+	}
+	if timeout := got.execCommandTimeout(2); timeout != 0 {
+		t.Errorf("invalid timeout parsed as %s", timeout)
+	}
+}
+
+// Test: ExecCommand background attributes start background jobs
+func TestParseAIResponse_ExecCommandBackground(t *testing.T) {
+	m := &Manager{}
+	input := "Starting the server.\n<ExecCommand background=\"1\">npm run dev</ExecCommand>\n<ExecCommand>curl localhost:3000</ExecCommand>"
+	got, err := m.parseAIResponse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []bool{true, false}; !reflect.DeepEqual(got.ExecCommandBackground, want) {
+		t.Errorf("got %v, want %v", got.ExecCommandBackground, want)
+	}
+	if !got.execCommandBackground(0) || got.execCommandBackground(1) || got.execCommandBackground(2) {
+		t.Errorf("execCommandBackground doesn't match %v", got.ExecCommandBackground)
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// findProjectConfigs walks up from dir to the repository root, the first
+// directory with a .git, collecting .tmuxai.yaml files outermost first so
+// nearer files are applied last
+func findProjectConfigs(dir string) []string {
+	var found []string
+	for {
+		path := filepath.Join(dir, config.ProjectConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			found = append([]string{path}, found...)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return found
+}
+
+// applyProjectConfig layers the .tmuxai.yaml files of the exec pane's
+// project over the config. It shares knowledge_base.project_discovery with
+// project KBs, since both come with whatever repo was cloned.
+func (m *Manager) applyProjectConfig() {
+	if !m.Config.KnowledgeBase.ProjectDiscovery {
+		return
+	}
+	// the exec pane's directory is on another host
+	if system.TmuxIsRemote() {
+		return
+	}
+
+	dir, err := m.projectKBStartDir()
+	if err != nil {
+		logger.Error("Project config discovery: %v", err)
+		return
+	}
+
+	for _, path := range findProjectConfigs(dir) {
+		ignored, err := config.ApplyProjectFile(m.Config, path)
+		if err != nil {
+			logger.Error("Failed to apply project config: %v", err)
+			m.Println(fmt.Sprintf("Warning: %v", err))
+			continue
+		}
+		m.projectConfigs = append(m.projectConfigs, path)
+		m.Println("Applied project config: " + path)
+		if len(ignored) > 0 {
+			m.Println(fmt.Sprintf("Ignored keys a project config can't set: %s", strings.Join(ignored, ", ")))
+		}
+	}
+}
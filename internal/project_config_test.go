@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProjectConfigsStopsAtRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "services", "api")
+	writeFile(t, filepath.Join(root, ".tmuxai.yaml"), "max_capture_lines: 10")
+	writeFile(t, filepath.Join(repo, ".tmuxai.yaml"), "max_capture_lines: 300")
+	writeFile(t, filepath.Join(sub, ".tmuxai.yaml"), "default_model: api")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+
+	assert.Equal(t, []string{
+		filepath.Join(repo, ".tmuxai.yaml"),
+		filepath.Join(sub, ".tmuxai.yaml"),
+	}, findProjectConfigs(sub), "files above the repo root are left out")
+}
+
+func TestApplyProjectConfig(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0o755))
+	writeFile(t, filepath.Join(repo, ".tmuxai.yaml"), "max_capture_lines: 42\nyolo: true\n")
+
+	cfg := config.DefaultConfig()
+	m := &Manager{Config: cfg, ExecPane: &system.TmuxPaneDetails{CurrentPath: repo}}
+	m.applyProjectConfig()
+	assert.Equal(t, 42, m.GetMaxCaptureLines())
+	assert.False(t, m.GetYolo())
+
+	cfg = config.DefaultConfig()
+	cfg.KnowledgeBase.ProjectDiscovery = false
+	m = &Manager{Config: cfg, ExecPane: &system.TmuxPaneDetails{CurrentPath: repo}}
+	m.applyProjectConfig()
+	assert.Equal(t, 200, m.GetMaxCaptureLines(), "project_discovery: false turns project configs off")
+}
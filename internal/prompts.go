@@ -16,7 +16,17 @@ var (
 	longB64Rx    = regexp.MustCompile(`(?i)base64[,:=]\s*[A-Za-z0-9+/]{256,}={0,2}`)
 )
 
+// baseSystemPrompt returns prompts/system.tmpl rendered when there is one,
+// else the built-in system prompt
 func (m *Manager) baseSystemPrompt() string {
+	if m.systemTemplate != nil {
+		return m.templatedSystemPrompt()
+	}
+	return m.defaultSystemPrompt()
+}
+
+// defaultSystemPrompt returns prompts.base_system or the built-in prompt
+func (m *Manager) defaultSystemPrompt() string {
 	basePrompt := `You are TmuxAI assistant. You are AI agent and live inside user's Tmux's window and can see all panes in that window.
 Think of TmuxAI as a pair programmer that sits beside user, watching users terminal window exactly as user see it.
 TmuxAI's design philosophy mirrors the way humans collaborate at the terminal. Just as a colleague sitting next to the user would observe users screen, understand context from what's visible, and help accordingly,
@@ -54,6 +64,7 @@ DO NOT WRITE MORE TEXT AFTER THE TOOL CALLS IN A RESPONSE. You can wait until th
 func (m *Manager) chatAssistantPrompt(prepared bool) ChatMessage {
 	var builder strings.Builder
 	builder.WriteString(m.baseSystemPrompt())
+	builder.WriteString(m.personaPrompt())
 
 	// Inject L1 skill registry (after base system, before XML tool docs)
 	if m.Skills != nil && m.Skills.L1Block != "" {
@@ -66,7 +77,7 @@ Your primary function is to assist users by interpreting their requests and exec
 You have access to the following XML tags to control the tmux pane:
 
 <TmuxSendKeys>: Use this to send keystrokes to the tmux pane. Supported keys include standard characters, function keys (F1-F12), navigation keys (Up,Down,Left,Right,BSpace,BTab,DC,End,Enter,Escape,Home,IC,NPage,PageDown,PgDn,PPage,PageUp,PgUp,Space,Tab), and modifier keys (C-, M-).
-<ExecCommand>: Use this to execute shell commands in the tmux pane.
+<ExecCommand>: Use this to execute shell commands in the tmux pane. For long-running commands that don't exit on their own, like dev servers and file watchers, add background="1" to start them as a background job in a pane of their own: <ExecCommand background="1">npm run dev</ExecCommand>.
 <PasteMultilineContent>: Use this to send multiline content into the tmux pane. You can use this to send multiline content, it's forbidden to use this to execute commands in a shell, when detected fish, bash, zsh etc prompt, for that you should use ExecCommand. Main use for this is when it's vim open and you need to type multiline text, etc.
 <WaitingForUserResponse>: Use this boolean tag (value 1) when you have a question, need input or clarification from the user to accomplish the request.
 <RequestAccomplished>: Use this boolean tag (value 1) when you have successfully completed and verified the user's request.
@@ -74,8 +85,17 @@ You have access to the following XML tags to control the tmux pane:
 
 	if !prepared {
 		builder.WriteString(`<ExecPaneSeemsBusy>: Use this boolean tag (value 1) when you need to wait for the exec pane to finish before proceeding.`)
+	} else {
+		builder.WriteString(`For commands that may hang or run long, give ExecCommand a timeout in seconds: <ExecCommand timeout="120">make test</ExecCommand>. You will be told if the command runs out of time.`)
 	}
 
+	builder.WriteString(m.namedExecPanesPrompt())
+	builder.WriteString(m.subagentsPrompt())
+	builder.WriteString(m.readFilePrompt())
+	builder.WriteString(m.writeFilePrompt())
+	builder.WriteString(m.backgroundJobsPrompt())
+	builder.WriteString(m.shellContextPrompt())
+
 	if toolDefs := m.ensureMcpToolDefs(); toolDefs != "" {
 		builder.WriteString(`
 
@@ -171,6 +191,14 @@ I'll wait for it to complete before proceeding.
 
 	builder.WriteString(`</examples_of_responses>`)
 
+	if m.toolCallingEnabled() {
+		builder.WriteString(`
+
+The actions above are also available as function tools (exec_command, send_keys, paste_multiline_content, spawn_agent, read_file, write_file, apply_patch, request_accomplished, waiting_for_user_response, exec_pane_seems_busy). Call these tools instead of writing the XML tags.`)
+	} else if m.structuredOutputEnabled() {
+		builder.WriteString(structuredOutputHint)
+	}
+
 	// Custom additional prompt
 	if m.Config.Prompts.ChatAssistant != "" {
 		builder.WriteString(m.Config.Prompts.ChatAssistant)
@@ -183,10 +211,15 @@ I'll wait for it to complete before proceeding.
 	}
 }
 
+const structuredOutputHint = `
+
+Respond with a single JSON object instead of XML tags. Put your text for the user in "message"; each XML tag maps to a field: TmuxSendKeys -> "send_keys" (list), ExecCommand -> "exec_command" (list) with its pane and timeout attributes in "exec_command_panes" (list, "" for the exec pane) and "exec_command_timeouts" (list of seconds, 0 for none) and "exec_command_background" (list of booleans), PasteMultilineContent -> "paste_multiline_content", SpawnAgent -> "spawn_agent" (list), ReadFile -> "read_file" (list), WriteFile -> "write_file" (list of contents) with each path in "write_file_paths" (list), ApplyPatch -> "apply_patch" (list), and the boolean tags to "request_accomplished", "exec_pane_seems_busy", "waiting_for_user_response", "no_comment", "watch_goal_met". Leave unused fields empty or false.`
+
 func (m *Manager) watchPrompt() ChatMessage {
 	var builder strings.Builder
 	builder.WriteString("\n")
 	builder.WriteString(m.baseSystemPrompt())
+	builder.WriteString(m.personaPrompt())
 
 	// Inject L1 skill registry (after base system, before watch-mode instructions)
 	if m.Skills != nil && m.Skills.L1Block != "" {
@@ -207,6 +240,19 @@ If no response is needed, output:
 
 `)
 
+	if m.watchGoal != "" {
+		fmt.Fprintf(&builder, "The user is waiting for this to happen: %s\nOnce the pane content shows it has happened, say so briefly and output:\n<WatchGoalMet>1</WatchGoalMet>\nUntil then, only comment on problems that would keep it from happening.\n\n", m.watchGoal)
+	}
+
+	if len(m.watchPanes) > 0 {
+		fmt.Fprintf(&builder, "You are watching the panes with Id %s. Start every observation with the Id of the pane it is about in brackets, for example [%s], so the user knows which pane it refers to.\n\n",
+			strings.Join(m.watchPanes, ", "), m.watchPanes[0])
+	}
+
+	if m.structuredOutputEnabled() {
+		builder.WriteString(structuredOutputHint)
+	}
+
 	if m.Config.Prompts.Watch != "" {
 		builder.WriteString(m.Config.Prompts.Watch)
 	}
@@ -302,3 +348,25 @@ func sanitizeFetchContent(content string) string {
 
 	return cleaned
 }
+
+// namedExecPanesPrompt explains how to route commands to the panes added
+// with /exec-pane add, or returns "" when there are none
+func (m *Manager) namedExecPanesPrompt() string {
+	names := m.namedExecPaneNames()
+	if len(names) == 0 {
+		return ""
+	}
+	panes := make([]string, len(names))
+	for i, name := range names {
+		panes[i] = fmt.Sprintf("%s (%s)", name, m.namedExecPanes[name])
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nBesides the exec pane, commands can run in these named exec panes: " + strings.Join(panes, ", ") + ".\n")
+	b.WriteString(`To run a command in one of them, name it in the pane attribute: <ExecCommand pane="` + names[0] + `">ls -l</ExecCommand>. Without the attribute the command runs in the exec pane. Pick the pane of the service the command belongs to.`)
+	if m.toolCallingEnabled() {
+		b.WriteString(` With the exec_command tool, pass the name as "pane".`)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
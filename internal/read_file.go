@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// fileRead is the outcome of a ReadFile action
+type fileRead struct {
+	Path      string
+	Content   string
+	Size      int64
+	Truncated bool
+	Err       error
+}
+
+// readFilePrompt describes the ReadFile tag, or returns "" when reading
+// files is turned off
+func (m *Manager) readFilePrompt() string {
+	if !m.Config.Files.Read || system.TmuxIsRemote() {
+		return ""
+	}
+	return fmt.Sprintf("\n<ReadFile>: Use this to read a file instead of running cat in the exec pane, which keeps its history clean: <ReadFile>src/main.go</ReadFile>. Relative paths are resolved against the exec pane's working directory. The contents, up to %d bytes, are sent back to you in the next message. Only files under %s can be read.",
+		m.Config.Files.MaxReadBytes, strings.Join(m.fileRoots(), ", "))
+}
+
+// fileRoots returns the directories ReadFile, WriteFile and ApplyPatch may
+// touch files under: files.allowed_paths, or the exec pane's working directory
+func (m *Manager) fileRoots() []string {
+	var roots []string
+	for _, root := range m.Config.Files.AllowedPaths {
+		if root == "~" || strings.HasPrefix(root, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				root = filepath.Join(home, root[1:])
+			}
+		}
+		if root != "" {
+			roots = append(roots, filepath.Clean(root))
+		}
+	}
+	if len(roots) == 0 && m.ExecPane != nil && m.ExecPane.CurrentPath != "" {
+		roots = append(roots, filepath.Clean(m.ExecPane.CurrentPath))
+	}
+	return roots
+}
+
+// resolveFilePath returns the real path of a file the AI may touch, relative
+// paths taken from the exec pane's working directory, or an error when it's
+// outside every allowed directory, symlinks included. A file that doesn't
+// exist yet is resolved through its nearest existing parent directory.
+func (m *Manager) resolveFilePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		if m.ExecPane == nil || m.ExecPane.CurrentPath == "" {
+			return "", errors.New("the exec pane's working directory is unknown, use an absolute path")
+		}
+		path = filepath.Join(m.ExecPane.CurrentPath, path)
+	}
+	real, missing := filepath.Clean(path), ""
+	for {
+		resolved, err := filepath.EvalSymlinks(real)
+		if err == nil {
+			real = filepath.Join(resolved, missing)
+			break
+		}
+		if !os.IsNotExist(err) || filepath.Dir(real) == real {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(real), missing)
+		real = filepath.Dir(real)
+	}
+	for _, root := range m.fileRoots() {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		if rel, err := filepath.Rel(root, real); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("not under an allowed directory (%s)", strings.Join(m.fileRoots(), ", "))
+}
+
+// readFile carries out a ReadFile action, returning up to
+// files.max_read_bytes of a text file under the allowed directories
+func (m *Manager) readFile(path string) fileRead {
+	read := fileRead{Path: path}
+	if !m.Config.Files.Read {
+		read.Err = errors.New("reading files is turned off, files.read is false")
+		return read
+	}
+	if system.TmuxIsRemote() {
+		read.Err = errors.New("files can't be read on a remote tmux server, use ExecCommand")
+		return read
+	}
+
+	real, err := m.resolveFilePath(path)
+	if err != nil {
+		read.Err = err
+		return read
+	}
+	f, err := os.Open(real)
+	if err != nil {
+		read.Err = err
+		return read
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		read.Err = err
+		return read
+	}
+	if info.IsDir() {
+		read.Err = errors.New("is a directory")
+		return read
+	}
+
+	limit := int64(m.Config.Files.MaxReadBytes)
+	if limit <= 0 {
+		limit = info.Size()
+	}
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		read.Err = err
+		return read
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		read.Err = errors.New("binary file")
+		return read
+	}
+	read.Content = string(data)
+	read.Size = info.Size()
+	read.Truncated = info.Size() > int64(len(data))
+	return read
+}
+
+// readFilesObservation sends the files the model asked for back to it
+func readFilesObservation(reads []fileRead) string {
+	var b strings.Builder
+	b.WriteString("Files you asked to read:\n")
+	for _, r := range reads {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "<file path=\"%s\" error=\"%s\"/>\n", sanitizeXML(r.Path), sanitizeXML(r.Err.Error()))
+			continue
+		}
+		fmt.Fprintf(&b, "<file path=\"%s\" size=\"%d\"", sanitizeXML(r.Path), r.Size)
+		if r.Truncated {
+			fmt.Fprintf(&b, " truncated_to=\"%d\"", len(r.Content))
+		}
+		fmt.Fprintf(&b, ">\n%s\n</file>\n", sanitizeXML(r.Content))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
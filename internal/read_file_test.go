@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFileManager(dir string) *Manager {
+	return &Manager{
+		Config:   &config.Config{Files: config.FilesConfig{Read: true, MaxReadBytes: 16}},
+		ExecPane: &system.TmuxPaneDetails{Id: "%2", CurrentPath: dir},
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "long.txt"), []byte(strings.Repeat("a", 40)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "blob.bin"), []byte("a\x00b"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("key"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "link")))
+	manager := readFileManager(dir)
+
+	read := manager.readFile("main.go")
+	require.NoError(t, read.Err)
+	assert.Equal(t, "package main\n", read.Content)
+	assert.False(t, read.Truncated)
+
+	read = manager.readFile(filepath.Join(dir, "long.txt"))
+	require.NoError(t, read.Err)
+	assert.Len(t, read.Content, 16)
+	assert.Equal(t, int64(40), read.Size)
+	assert.True(t, read.Truncated)
+
+	assert.ErrorContains(t, manager.readFile("blob.bin").Err, "binary")
+	assert.ErrorContains(t, manager.readFile(".").Err, "directory")
+	assert.ErrorContains(t, manager.readFile(filepath.Join(outside, "secret")).Err, "not under an allowed directory")
+	assert.ErrorContains(t, manager.readFile("../"+filepath.Base(outside)+"/secret").Err, "not under an allowed directory")
+	assert.ErrorContains(t, manager.readFile("link").Err, "not under an allowed directory", "symlinks can't escape")
+
+	manager.Config.Files.AllowedPaths = []string{outside}
+	assert.NoError(t, manager.readFile(filepath.Join(outside, "secret")).Err)
+	assert.Error(t, manager.readFile("main.go").Err, "allowed_paths replaces the working directory")
+
+	manager.Config.Files.Read = false
+	assert.ErrorContains(t, manager.readFile(filepath.Join(outside, "secret")).Err, "turned off")
+	assert.Empty(t, manager.readFilePrompt())
+}
+
+func TestReadFilesObservation(t *testing.T) {
+	observation := readFilesObservation([]fileRead{
+		{Path: "main.go", Content: "a < b", Size: 5},
+		{Path: "long.txt", Content: "aaaa", Size: 40, Truncated: true},
+		{Path: "gone", Err: os.ErrNotExist},
+	})
+	assert.Contains(t, observation, "<file path=\"main.go\" size=\"5\">\na &lt; b\n</file>")
+	assert.Contains(t, observation, "<file path=\"long.txt\" size=\"40\" truncated_to=\"4\">")
+	assert.Contains(t, observation, "<file path=\"gone\" error=\"file does not exist\"/>")
+}
+
+func TestFollowUpMessageKeepsFileReads(t *testing.T) {
+	assert.Equal(t, "sending updated pane(s) content", followUpMessage(nil))
+
+	reads := readFilesObservation([]fileRead{{Path: "main.go", Content: "package main", Size: 12}})
+	followUp := followUpMessage([]string{abortedObservation([]CommandExecHistory{{Command: "make", Code: 2}}), reads})
+	assert.Contains(t, followUp, "make")
+	assert.True(t, strings.HasSuffix(followUp, "\n\n"+reads), "file contents sent alongside command results")
+}
+
+func TestParseReadFile(t *testing.T) {
+	manager := readFileManager(t.TempDir())
+	r, err := manager.parseAIResponse("Let me look.\n<ReadFile>go.mod</ReadFile>\n<ReadFile>main.go</ReadFile>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go.mod", "main.go"}, r.ReadFile)
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// redacted replaces secrets in context sent to the model and in debug bundles
+const redacted = "[REDACTED]"
+
+// secretShape is a kind of credential and the regex matching it. Matches are
+// replaced with Replace, which may keep groups such as an assignment's name;
+// "" replaces the whole match with redacted.
+type secretShape struct {
+	Name    string
+	Re      *regexp.Regexp
+	Replace string
+}
+
+// secretValue is the value of an assignment or header, not starting with
+// "[" so values redacted by an earlier shape aren't counted twice
+const secretValue = `[^\s"',;\[][^\s"',;]{3,}`
+
+// secretShapes match credentials that commonly show up in pane output, in
+// the order they're applied
+var secretShapes = []secretShape{
+	{Name: "private key", Re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{Name: "OpenAI/Anthropic key", Re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`)},
+	{Name: "AWS access key", Re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "GitHub token", Re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`)},
+	{Name: "Slack token", Re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{Name: "Google API key", Re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{Name: "JWT", Re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{Name: "URL password", Re: regexp.MustCompile(`(\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:)[^\s@/]+(@)`), Replace: "${1}" + redacted + "${2}"},
+	{Name: "bearer token", Re: regexp.MustCompile(`(?i)(\b(?:bearer|authorization:\s*\w+)\s+)` + secretValue), Replace: "${1}" + redacted},
+	// password=..., API_KEY: ... and .env lines like AWS_SECRET_ACCESS_KEY=...
+	{Name: "secret assignment", Re: regexp.MustCompile(`(?i)(\b[\w.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)(?:_\w*)?["']?\s*[=:]\s*["']?)` + secretValue), Replace: "${1}" + redacted},
+}
+
+// redactText replaces the given secrets, the secret shapes and extra in
+// text, adding how many of each it replaced to counts when it isn't nil
+func redactText(text string, secrets []string, extra []secretShape, counts map[string]int) string {
+	for _, s := range secrets {
+		if counts != nil {
+			if n := strings.Count(text, s); n > 0 {
+				counts["configured secret"] += n
+			}
+		}
+		text = strings.ReplaceAll(text, s, redacted)
+	}
+	for _, shape := range append(secretShapes, extra...) {
+		if counts != nil {
+			if n := len(shape.Re.FindAllStringIndex(text, -1)); n > 0 {
+				counts[shape.Name] += n
+			}
+		}
+		replace := shape.Replace
+		if replace == "" {
+			replace = redacted
+		}
+		text = shape.Re.ReplaceAllString(text, replace)
+	}
+	return text
+}
+
+// redactSecrets replaces the given secrets and anything that looks like a
+// credential in text
+func redactSecrets(text string, secrets []string) string {
+	return redactText(text, secrets, nil, nil)
+}
+
+// redactContext masks secrets in pane content and command output sent to
+// the model, with redaction.patterns on top of the built-in shapes, and
+// counts them for /info. It returns text as is when redaction is off.
+func (m *Manager) redactContext(text string) string {
+	if text == "" || !m.GetRedaction() {
+		return text
+	}
+	if m.redactionCounts == nil {
+		m.redactionCounts = make(map[string]int)
+	}
+	return redactText(text, nil, m.customSecretShapes(), m.redactionCounts)
+}
+
+// customSecretShapes compiles redaction.patterns, skipping invalid ones.
+// They're compiled again only when the config changes.
+func (m *Manager) customSecretShapes() []secretShape {
+	key := strings.Join(m.Config.Redaction.Patterns, "\n")
+	if m.redactionPatterns != nil && m.redactionPatterns.key == key {
+		return m.redactionPatterns.shapes
+	}
+	var shapes []secretShape
+	for _, pattern := range m.Config.Redaction.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Error("Invalid redaction pattern %q: %v", pattern, err)
+			continue
+		}
+		shapes = append(shapes, secretShape{Name: "redaction.patterns", Re: re})
+	}
+	m.redactionPatterns = &compiledShapes{key: key, shapes: shapes}
+	return shapes
+}
+
+// compiledShapes caches redaction.patterns compiled
+type compiledShapes struct {
+	key    string
+	shapes []secretShape
+}
+
+// redactionSummary describes what was redacted this session for /info
+func (m *Manager) redactionSummary() string {
+	if !m.GetRedaction() {
+		return "off"
+	}
+	total := 0
+	names := make([]string, 0, len(m.redactionCounts))
+	for name, n := range m.redactionCounts {
+		total += n
+		names = append(names, name)
+	}
+	if total == 0 {
+		return "nothing so far"
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, m.redactionCounts[name])
+	}
+	return fmt.Sprintf("%d secrets (%s)", total, strings.Join(parts, ", "))
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// retrySleep waits for d or until ctx is done. Tests replace it to avoid real delays.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRetryableStatus reports whether an HTTP status is worth retrying:
+// rate limits, transient server errors and Anthropic's 529 "overloaded".
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryBackoff returns the delay before the given retry (1-based), doubling
+// from the initial backoff up to the configured maximum. With jitter enabled
+// the delay is drawn uniformly from [delay/2, delay].
+func retryBackoff(policy config.RetryConfig, retry int) time.Duration {
+	delay := time.Duration(policy.InitialBackoffMs) * time.Millisecond
+	maxDelay := time.Duration(policy.MaxBackoffMs) * time.Millisecond
+	for i := 1; i < retry && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	if policy.Jitter && delay > 0 {
+		half := delay / 2
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return delay
+}
+
+// doWithRetry sends req, retrying network failures and retryable statuses
+// according to the configured retry policy. The request body is rewound
+// between attempts, so requests must be built with a replayable body.
+func (c *AiClient) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	policy := c.config.Retry
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		if ctx.Err() != nil || attempt >= attempts {
+			return resp, err
+		}
+
+		var delay time.Duration
+		if err != nil {
+			delay = retryBackoff(policy, attempt)
+			logger.Info("AI request failed: %v, retrying in %s (attempt %d/%d)", err, delay, attempt+1, attempts)
+			recordRetry(ctx, attempt+1, delay, err.Error())
+		} else if isRetryableStatus(resp.StatusCode) {
+			delay = retryBackoff(policy, attempt)
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = d
+				if maxDelay := time.Duration(policy.MaxBackoffMs) * time.Millisecond; maxDelay > 0 && delay > maxDelay {
+					delay = maxDelay
+				}
+			}
+			logger.Info("AI request returned status %d, retrying in %s (attempt %d/%d)", resp.StatusCode, delay, attempt+1, attempts)
+			recordRetry(ctx, attempt+1, delay, fmt.Sprintf("status %d", resp.StatusCode))
+			_ = resp.Body.Close()
+		} else {
+			return resp, nil
+		}
+
+		if err := retrySleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubRetrySleep(t *testing.T) *[]time.Duration {
+	var delays []time.Duration
+	orig := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = orig })
+	return &delays
+}
+
+func TestChatCompletionRetriesTransientErrors(t *testing.T) {
+	delays := stubRetrySleep(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":"rate limited"}`))
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		OpenRouter: config.OpenRouterConfig{APIKey: "k", BaseURL: server.URL},
+		Retry:      config.RetryConfig{MaxAttempts: 3, InitialBackoffMs: 100, MaxBackoffMs: 5000},
+	}
+	client := NewAiClient(cfg)
+
+	resp, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{2 * time.Second, 200 * time.Millisecond}, *delays)
+}
+
+func TestChatCompletionGivesUpAfterMaxAttempts(t *testing.T) {
+	stubRetrySleep(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`overloaded`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		OpenRouter: config.OpenRouterConfig{APIKey: "k", BaseURL: server.URL},
+		Retry:      config.RetryConfig{MaxAttempts: 2, InitialBackoffMs: 10},
+	}
+	_, err := NewAiClient(cfg).ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overloaded")
+	assert.Equal(t, 2, calls)
+}
+
+func TestChatCompletionDoesNotRetryClientErrors(t *testing.T) {
+	stubRetrySleep(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		OpenRouter: config.OpenRouterConfig{APIKey: "k", BaseURL: server.URL},
+		Retry:      config.RetryConfig{MaxAttempts: 5, InitialBackoffMs: 10},
+	}
+	_, err := NewAiClient(cfg).ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m")
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := config.RetryConfig{InitialBackoffMs: 500, MaxBackoffMs: 3000}
+	assert.Equal(t, 500*time.Millisecond, retryBackoff(policy, 1))
+	assert.Equal(t, 1000*time.Millisecond, retryBackoff(policy, 2))
+	assert.Equal(t, 2000*time.Millisecond, retryBackoff(policy, 3))
+	assert.Equal(t, 3000*time.Millisecond, retryBackoff(policy, 4))
+
+	policy.Jitter = true
+	for i := 0; i < 20; i++ {
+		d := retryBackoff(policy, 2)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, 1000*time.Millisecond)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, ok = retryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	_, ok = retryAfter("", now)
+	assert.False(t, ok)
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestGetResponseFallsBackToNextModel(t *testing.T) {
+	stubRetrySleep(t)
+
+	var primaryCalls, fallbackCalls int
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`overloaded`))
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalls++
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "backup-model", req.Model)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer up.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "primary",
+		Models: map[string]config.ModelConfig{
+			"primary": {Provider: "openrouter", Model: "primary-model", APIKey: "k", BaseURL: down.URL, FallbackModels: []string{"missing", "second", "backup"}},
+			"second":  {Provider: "openrouter", Model: "second-model", APIKey: "k", BaseURL: down.URL},
+			"backup":  {Provider: "openrouter", Model: "backup-model", APIKey: "k", BaseURL: up.URL},
+		},
+	}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+
+	messages := []ChatMessage{{Content: "hi", FromUser: true}}
+	resp, err := manager.AiClient.GetResponseFromChatMessages(context.Background(), messages, manager.GetModel())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, 2, primaryCalls, "primary and second both tried")
+	assert.Equal(t, 1, fallbackCalls)
+	assert.Equal(t, "primary", manager.GetModelsDefault(), "the session keeps its model")
+
+	t.Run("returns the last error when every model fails", func(t *testing.T) {
+		mc := cfg.Models["primary"]
+		mc.FallbackModels = []string{"second"}
+		cfg.Models["primary"] = mc
+		_, err := manager.AiClient.GetResponseFromChatMessages(context.Background(), messages, manager.GetModel())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "overloaded")
+	})
+}
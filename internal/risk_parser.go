@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Risk flags raised from the shell syntax tree rather than a regex
+const (
+	flagChaining     = "command chaining (;, &&, ||, newline)"
+	flagBackground   = "background job (&)"
+	flagRedirect     = "redirection"
+	flagSubstitution = "command substitution"
+	flagProcSubst    = "process substitution"
+	flagIFS          = "$IFS expansion"
+	flagDynamicName  = "non-literal command name"
+	flagCompound     = "compound command"
+)
+
+// parsedCommand is a shell command reduced to what the risk scorer needs:
+// syntax-level risk flags and the normalized text of every simple command
+type parsedCommand struct {
+	flags []string
+	calls []string
+}
+
+// shellInterpreters run their -c argument as a script, which is parsed in turn
+var shellInterpreters = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true}
+
+// parseShellCommand parses cmd as bash. Quoting and escapes are resolved in
+// the returned calls, so `r\m -rf` and `'rm' -rf` both read as `rm -rf` and an
+// operator inside quotes, like `echo "a;b"`, isn't mistaken for chaining.
+func parseShellCommand(cmd string) (*parsedCommand, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(cmd), "")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parsedCommand{}
+	if len(file.Stmts) > 1 {
+		p.flag(flagChaining)
+	}
+	for _, stmt := range file.Stmts {
+		p.stmt(stmt)
+	}
+
+	// Substitutions and $IFS can appear anywhere, including inside other words
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CmdSubst:
+			p.flag(flagSubstitution)
+		case *syntax.ProcSubst:
+			p.flag(flagProcSubst)
+		case *syntax.ParamExp:
+			if n.Param != nil && n.Param.Value == "IFS" {
+				p.flag(flagIFS)
+			}
+		}
+		return true
+	})
+	return p, nil
+}
+
+func (p *parsedCommand) flag(flag string) {
+	for _, f := range p.flags {
+		if f == flag {
+			return
+		}
+	}
+	p.flags = append(p.flags, flag)
+}
+
+func (p *parsedCommand) stmt(stmt *syntax.Stmt) {
+	if stmt.Background || stmt.Coprocess || stmt.Disown {
+		p.flag(flagBackground)
+	}
+	if len(stmt.Redirs) > 0 {
+		p.flag(flagRedirect)
+	}
+
+	switch cmd := stmt.Cmd.(type) {
+	case nil:
+	case *syntax.CallExpr:
+		p.call(cmd)
+	case *syntax.BinaryCmd:
+		if cmd.Op == syntax.AndStmt || cmd.Op == syntax.OrStmt {
+			p.flag(flagChaining)
+		}
+		p.stmt(cmd.X)
+		p.stmt(cmd.Y)
+	case *syntax.TimeClause:
+		if cmd.Stmt != nil {
+			p.stmt(cmd.Stmt)
+		}
+	case *syntax.DeclClause, *syntax.TestClause, *syntax.LetClause, *syntax.ArithmCmd:
+		// Builtins with their own syntax; no safe pattern matches them, so
+		// they're scored as unknown unless a deny pattern does
+		p.calls = append(p.calls, printNode(cmd))
+	default:
+		// Subshells, blocks, loops, conditionals and function declarations
+		p.flag(flagCompound)
+		p.calls = append(p.calls, printNode(cmd))
+	}
+}
+
+func (p *parsedCommand) call(call *syntax.CallExpr) {
+	if len(call.Args) == 0 {
+		// Assignment only, e.g. FOO=bar
+		p.calls = append(p.calls, printNode(call))
+		return
+	}
+
+	if !isLiteralWord(call.Args[0]) {
+		p.flag(flagDynamicName)
+	}
+
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = wordString(arg)
+	}
+	p.calls = append(p.calls, strings.Join(args, " "))
+
+	// sh -c 'script' hides the real command inside a string
+	if shellInterpreters[path.Base(args[0])] {
+		for i := 1; i+1 < len(args); i++ {
+			if args[i] != "-c" {
+				continue
+			}
+			if nested, err := parseShellCommand(args[i+1]); err == nil {
+				for _, flag := range nested.flags {
+					p.flag(flag)
+				}
+				p.calls = append(p.calls, nested.calls...)
+			}
+			break
+		}
+	}
+}
+
+// isLiteralWord reports whether a word expands to fixed text
+func isLiteralWord(word *syntax.Word) bool {
+	for _, part := range word.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit, *syntax.SglQuoted:
+		case *syntax.DblQuoted:
+			for _, inner := range part.Parts {
+				if _, ok := inner.(*syntax.Lit); !ok {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// wordString returns a word with quotes and escapes removed. $IFS reads as a
+// space, matching the word splitting it's typically used to smuggle in.
+// Other expansions keep their source form.
+func wordString(word *syntax.Word) string {
+	var b strings.Builder
+	for _, part := range word.Parts {
+		b.WriteString(wordPartString(part, false))
+	}
+	return b.String()
+}
+
+func wordPartString(part syntax.WordPart, quoted bool) string {
+	switch part := part.(type) {
+	case *syntax.Lit:
+		return unescape(part.Value, quoted)
+	case *syntax.SglQuoted:
+		return part.Value
+	case *syntax.DblQuoted:
+		var b strings.Builder
+		for _, inner := range part.Parts {
+			b.WriteString(wordPartString(inner, true))
+		}
+		return b.String()
+	case *syntax.ParamExp:
+		if part.Param != nil && part.Param.Value == "IFS" {
+			return " "
+		}
+	}
+	return printNode(part)
+}
+
+// unescape removes shell backslash escapes. Inside double quotes a backslash
+// only escapes $, `, ", \ and newline.
+func unescape(s string, quoted bool) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		next := s[i+1]
+		if quoted && !strings.ContainsRune("$`\"\\\n", rune(next)) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if next != '\n' {
+			b.WriteByte(next)
+		}
+	}
+	return b.String()
+}
+
+func printNode(node syntax.Node) string {
+	var b strings.Builder
+	if err := syntax.NewPrinter(syntax.SingleLine(true)).Print(&b, node); err != nil {
+		return ""
+	}
+	return b.String()
+}
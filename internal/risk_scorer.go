@@ -3,7 +3,10 @@ package internal
 
 import (
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
 )
 
 type RiskLevel string
@@ -102,41 +105,46 @@ var (
 		{regexp.MustCompile(`^lsof(\s|$)`)},
 	}
 
-	// Dangerous patterns - major risks that require user confirmation
-	dangerousPatterns = []Pattern{
+	// Shell syntax patterns - only used when a command can't be parsed;
+	// parsed commands get these checks from the syntax tree instead
+	shellSyntaxPatterns = []Pattern{
 		// Detect explicit chaining/substitution and scoped redirects (avoid overbroad [<>])
-		{regexp.MustCompile(`;`)},                             // Semicolon command chaining
-		{regexp.MustCompile(`(?m)\s&\s|&$`)},                  // Background job operator (standalone &)
-		{regexp.MustCompile(`\$\(`)},                          // Command substitution $()
-		{regexp.MustCompile("`")},                              // Command substitution (legacy) ``
-		{regexp.MustCompile(`\|\|`)},                         // Logical OR chaining
-		{regexp.MustCompile(`&&`)},                           // Logical AND chaining
+		{regexp.MustCompile(`;`)},            // Semicolon command chaining
+		{regexp.MustCompile(`(?m)\s&\s|&$`)}, // Background job operator (standalone &)
+		{regexp.MustCompile(`\$\(`)},         // Command substitution $()
+		{regexp.MustCompile("`")},            // Command substitution (legacy) ``
+		{regexp.MustCompile(`\|\|`)},         // Logical OR chaining
+		{regexp.MustCompile(`&&`)},           // Logical AND chaining
 		// Redirect operator detection (>, >>, <, and fd>), scoped to redirect tokens so we don't match stray angle brackets
 		{regexp.MustCompile(`(?:^|\s|[a-zA-Z0-9])(?:[0-9]*[<>]{1,2})\s*[^&|;]+`)},
+	}
+
+	// Dangerous patterns - major risks that require user confirmation
+	dangerousPatterns = []Pattern{
 		// Specific redirect to dangerous system paths (write redirects targeting system dirs)
 		{regexp.MustCompile(`[>\s]+/(?:etc|dev|proc|sys|boot|root)(?:/|$)`)},
-		
+
 		// NEW: Also add the other fixes
 		{regexp.MustCompile(`\bfind\b.*-exec\b`)}, // find with -exec (potentially dangerous execution)
 		{regexp.MustCompile(`\b(curl|wget)\b.*\s(-o|--output|-O)\b`)},
 		{regexp.MustCompile(`\bsed\b.*[\s;]e\b`)},
 
 		// chmod patterns - detect execute permission grants
-		{regexp.MustCompile(`\bchmod\s+.*(\+x|=[^,]*x)`)},      // chmod +x or symbolic grant of execute
+		{regexp.MustCompile(`\bchmod\s+.*(\+x|=[^,]*x)`)},        // chmod +x or symbolic grant of execute
 		{regexp.MustCompile(`\bchmod\s+[0-7]*[1357][0-7]{2}\b`)}, // chmod with execute bits (1,3,5,7)
 
 		// Destructive filesystem operations (most common/dangerous)
-		{regexp.MustCompile(`\brm\s+-[rR]f`)},        // rm -rf
-		{regexp.MustCompile(`\brm\s+.*-[rR].*f`)},    // rm with -r and -f in any order
-		{regexp.MustCompile(`\brm\s+(-[rR]\s+)?/`)},  // rm targeting root paths
-		{regexp.MustCompile(`\bfind\b.*-delete\b`)},  // find with -delete flag
-		{regexp.MustCompile(`\bxargs\s+rm\b`)},       // xargs with rm (mass deletion)
-		{regexp.MustCompile(`\bmkfs\b`)},             // Format filesystem
-		{regexp.MustCompile(`\bdd\s+.*of=/dev/`)},    // Write to device
-		{regexp.MustCompile(`\bfdisk\b`)},            // Partition management
-		{regexp.MustCompile(`\bparted\b`)},           // Partition editor
-		{regexp.MustCompile(`:\s*,\s*\$\s*d\b`)},     // dd in sed (delete all lines)
-		{regexp.MustCompile(`\btruncate\s+-s\s*0`)},  // Truncate files to zero size
+		{regexp.MustCompile(`\brm\s+-[rR]f`)},       // rm -rf
+		{regexp.MustCompile(`\brm\s+.*-[rR].*f`)},   // rm with -r and -f in any order
+		{regexp.MustCompile(`\brm\s+(-[rR]\s+)?/`)}, // rm targeting root paths
+		{regexp.MustCompile(`\bfind\b.*-delete\b`)}, // find with -delete flag
+		{regexp.MustCompile(`\bxargs\s+rm\b`)},      // xargs with rm (mass deletion)
+		{regexp.MustCompile(`\bmkfs\b`)},            // Format filesystem
+		{regexp.MustCompile(`\bdd\s+.*of=/dev/`)},   // Write to device
+		{regexp.MustCompile(`\bfdisk\b`)},           // Partition management
+		{regexp.MustCompile(`\bparted\b`)},          // Partition editor
+		{regexp.MustCompile(`:\s*,\s*\$\s*d\b`)},    // dd in sed (delete all lines)
+		{regexp.MustCompile(`\btruncate\s+-s\s*0`)}, // Truncate files to zero size
 
 		// Privilege escalation (very common)
 		{regexp.MustCompile(`\bsudo\b`)},
@@ -144,34 +152,34 @@ var (
 		{regexp.MustCompile(`\bdoas\b`)}, // OpenBSD sudo alternative
 
 		// Dangerous ownership changes
-		{regexp.MustCompile(`\bchown\s+.*root`)},             // chown to root
+		{regexp.MustCompile(`\bchown\s+.*root`)}, // chown to root
 
 		// Code execution risks
 		{regexp.MustCompile(`\|\s*(sh|bash|zsh|fish)\b`)}, // pipe to shell
-		{regexp.MustCompile(`\beval\s`)},                   // eval command
-		{regexp.MustCompile(`\bexec\s`)},                   // exec command
-		{regexp.MustCompile(`\bcurl\b.*\|\s*(sh|bash)`)},   // curl | sh
-		{regexp.MustCompile(`\bwget\b.*\|\s*(sh|bash)`)},   // wget | sh
-		{regexp.MustCompile(`\bsource\s+/dev/(tcp|udp)`)},  // network file execution
-		{regexp.MustCompile(`\.\s+/dev/(tcp|udp)`)},        // dot source network
-		{regexp.MustCompile(`\bperl\s+-e`)},                // perl one-liner execution
-		{regexp.MustCompile(`\bpython\s+-c`)},              // python one-liner execution
-		{regexp.MustCompile(`\bruby\s+-e`)},                // ruby one-liner execution
-		{regexp.MustCompile(`\bawk\s+.*system\(`)},         // awk with system() calls
-		{regexp.MustCompile(`\b:\(\)\s*\{.*:\|:`)},         // fork bomb pattern
+		{regexp.MustCompile(`\beval\s`)},                  // eval command
+		{regexp.MustCompile(`\bexec\s`)},                  // exec command
+		{regexp.MustCompile(`\bcurl\b.*\|\s*(sh|bash)`)},  // curl | sh
+		{regexp.MustCompile(`\bwget\b.*\|\s*(sh|bash)`)},  // wget | sh
+		{regexp.MustCompile(`\bsource\s+/dev/(tcp|udp)`)}, // network file execution
+		{regexp.MustCompile(`\.\s+/dev/(tcp|udp)`)},       // dot source network
+		{regexp.MustCompile(`\bperl\s+-e`)},               // perl one-liner execution
+		{regexp.MustCompile(`\bpython\s+-c`)},             // python one-liner execution
+		{regexp.MustCompile(`\bruby\s+-e`)},               // ruby one-liner execution
+		{regexp.MustCompile(`\bawk\s+.*system\(`)},        // awk with system() calls
+		{regexp.MustCompile(`\b:\(\)\s*\{.*:\|:`)},        // fork bomb pattern
 
 		// System critical modifications
 		// REMOVED: Redundant, covered by new `[<>]` rule
 		// {regexp.MustCompile(`>\s*/etc/`)},                                 // Writing to system config
 		{regexp.MustCompile(`\b(systemctl|service)\s+(stop|disable|mask)`)}, // Stop/disable services
-		{regexp.MustCompile(`\breboot\b`)},                                // Restart system
-		{regexp.MustCompile(`\bshutdown\b`)},                              // Shutdown system
-		{regexp.MustCompile(`\bhalt\b`)},                                  // Halt system
-		{regexp.MustCompile(`\bpoweroff\b`)},                              // Power off system
-		{regexp.MustCompile(`\bkillall\b`)},                               // Kill all processes by name
-		{regexp.MustCompile(`\bpkill\b`)},                                 // Kill processes by pattern
-		{regexp.MustCompile(`\bkill\s+-9`)},                               // Force kill signal
-		{regexp.MustCompile(`\binit\s+[016]`)},                            // Change runlevel
+		{regexp.MustCompile(`\breboot\b`)},                                  // Restart system
+		{regexp.MustCompile(`\bshutdown\b`)},                                // Shutdown system
+		{regexp.MustCompile(`\bhalt\b`)},                                    // Halt system
+		{regexp.MustCompile(`\bpoweroff\b`)},                                // Power off system
+		{regexp.MustCompile(`\bkillall\b`)},                                 // Kill all processes by name
+		{regexp.MustCompile(`\bpkill\b`)},                                   // Kill processes by pattern
+		{regexp.MustCompile(`\bkill\s+-9`)},                                 // Force kill signal
+		{regexp.MustCompile(`\binit\s+[016]`)},                              // Change runlevel
 
 		// Package management (can install/remove critical packages)
 		{regexp.MustCompile(`\bapt(-get)?\s+(remove|purge|autoremove)`)}, // apt remove
@@ -182,8 +190,8 @@ var (
 		{regexp.MustCompile(`\bnpm\s+(uninstall|remove)\s+-g`)},          // npm global uninstall
 
 		// Disk/filesystem operations
-		{regexp.MustCompile(`\bumount\s+/`)},     // Unmount root paths
-		{regexp.MustCompile(`\bfsck\b`)},         // Filesystem check (can modify)
+		{regexp.MustCompile(`\bumount\s+/`)},       // Unmount root paths
+		{regexp.MustCompile(`\bfsck\b`)},           // Filesystem check (can modify)
 		{regexp.MustCompile(`\bmount\s+.*-o.*rw`)}, // Remount with write
 
 		// Database operations
@@ -191,16 +199,16 @@ var (
 		{regexp.MustCompile(`\bDROP\s+(DATABASE|TABLE)\b`)},                   // SQL DROP
 
 		// Docker/Container dangerous ops
-		{regexp.MustCompile(`\bdocker\s+(rm|rmi)\s+.*-f`)},           // Force remove
-		{regexp.MustCompile(`\bdocker\s+system\s+prune\s+.*-a`)},     // Remove all unused
-		{regexp.MustCompile(`\bkubectl\s+delete`)},                   // Kubernetes delete
-		{regexp.MustCompile(`\bdocker\s+compose\s+down\s+.*-v`)},     // Remove volumes
+		{regexp.MustCompile(`\bdocker\s+(rm|rmi)\s+.*-f`)},       // Force remove
+		{regexp.MustCompile(`\bdocker\s+system\s+prune\s+.*-a`)}, // Remove all unused
+		{regexp.MustCompile(`\bkubectl\s+delete`)},               // Kubernetes delete
+		{regexp.MustCompile(`\bdocker\s+compose\s+down\s+.*-v`)}, // Remove volumes
 
 		// Git dangerous operations
-		{regexp.MustCompile(`\bgit\s+push\s+.*--force`)},        // Force push
-		{regexp.MustCompile(`\bgit\s+clean\s+.*-[fFdDxX]`)},     // Clean untracked files
-		{regexp.MustCompile(`\bgit\s+reset\s+.*--hard`)},        // Hard reset
-		{regexp.MustCompile(`\bgit\s+branch\s+.*-D`)},           // Force delete branch
+		{regexp.MustCompile(`\bgit\s+push\s+.*--force`)},    // Force push
+		{regexp.MustCompile(`\bgit\s+clean\s+.*-[fFdDxX]`)}, // Clean untracked files
+		{regexp.MustCompile(`\bgit\s+reset\s+.*--hard`)},    // Hard reset
+		{regexp.MustCompile(`\bgit\s+branch\s+.*-D`)},       // Force delete branch
 
 		// Cron/scheduled tasks
 		{regexp.MustCompile(`\bcrontab\s+-r`)}, // Remove all cron jobs
@@ -208,8 +216,34 @@ var (
 	}
 )
 
-
 func ScoreCommand(cmd string) RiskAssessment {
+	return scoreWithPatterns(cmd, nil, nil)
+}
+
+// ScoreCommandWithPatterns scores a command with user-supplied regexes added
+// to the built-in safe (allow) and dangerous (deny) patterns. Invalid
+// patterns are logged and skipped.
+func ScoreCommandWithPatterns(cmd string, allow, deny []string) RiskAssessment {
+	return scoreWithPatterns(cmd, compilePatterns(allow), compilePatterns(deny))
+}
+
+func compilePatterns(patterns []string) []Pattern {
+	compiled := make([]Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logger.Error("Invalid safety pattern '%s': %v", p, err)
+			continue
+		}
+		compiled = append(compiled, Pattern{re})
+	}
+	return compiled
+}
+
+func scoreWithPatterns(cmd string, extraSafe, extraDangerous []Pattern) RiskAssessment {
 	assessment := RiskAssessment{
 		Level: RiskUnknown, // Default to unknown
 		Flags: []string{},
@@ -222,27 +256,55 @@ func ScoreCommand(cmd string) RiskAssessment {
 		return assessment
 	}
 
-	// Check for dangerous patterns first (highest priority)
-	for _, pattern := range dangerousPatterns {
-		if pattern.Regex.MatchString(cmd) {
+	dangerous := append(extraDangerous, dangerousPatterns...)
+	safe := append(extraSafe, safePatterns...)
+
+	parsed, err := parseShellCommand(cmd)
+	if err != nil {
+		// Fall back to matching shell syntax on the raw string; a command
+		// that doesn't parse is never considered safe
+		assessment.Flags = matchPatterns(append(shellSyntaxPatterns, dangerous...), assessment.Flags, cmd)
+		if len(assessment.Flags) > 0 {
 			assessment.Level = RiskDanger
-			assessment.Flags = append(assessment.Flags, pattern.Regex.String())
 		}
+		return assessment
+	}
+
+	// Check for dangerous syntax and patterns first (highest priority). The
+	// raw string is matched too so quoting can't hide a dangerous word.
+	assessment.Flags = append(assessment.Flags, parsed.flags...)
+	assessment.Flags = matchPatterns(dangerous, assessment.Flags, cmd)
+	for _, call := range parsed.calls {
+		assessment.Flags = matchPatterns(dangerous, assessment.Flags, call)
 	}
 
 	// If dangerous patterns found, return immediately
-	if assessment.Level == RiskDanger {
+	if len(assessment.Flags) > 0 {
+		assessment.Level = RiskDanger
 		return assessment
 	}
 
-	// Check for safe patterns
-	for _, pattern := range safePatterns {
-		if pattern.Regex.MatchString(cmd) {
-			assessment.Level = RiskSafe
+	// Safe only when every simple command (e.g. each side of a pipe) is safe
+	if len(parsed.calls) == 0 {
+		return assessment
+	}
+	for _, call := range parsed.calls {
+		if len(matchPatterns(safe, nil, call)) == 0 {
+			// If no matches, it's unknown (requires user confirmation)
 			return assessment
 		}
 	}
-
-	// If no matches, it's unknown (requires user confirmation)
+	assessment.Level = RiskSafe
 	return assessment
 }
+
+// matchPatterns appends the patterns matching s to flags, skipping duplicates
+func matchPatterns(patterns []Pattern, flags []string, s string) []string {
+	for _, pattern := range patterns {
+		if !pattern.Regex.MatchString(s) || slices.Contains(flags, pattern.Regex.String()) {
+			continue
+		}
+		flags = append(flags, pattern.Regex.String())
+	}
+	return flags
+}
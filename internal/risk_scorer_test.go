@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -251,9 +252,76 @@ func TestScoreCommand_CommandChainingVulnerabilities(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			assessment := ScoreCommand(tt.cmd)
 			if assessment.Level != tt.expected {
-				t.Errorf("ScoreCommand(%q) = %v, want %v\nReason: %s", 
+				t.Errorf("ScoreCommand(%q) = %v, want %v\nReason: %s",
 					tt.cmd, assessment.Level, tt.expected, tt.reason)
 			}
 		})
 	}
 }
+
+func TestScoreCommandWithPatterns(t *testing.T) {
+	allow := []string{`^make\s+test$`}
+	deny := []string{`\bterraform\s+destroy\b`, `(`}
+
+	tests := []struct {
+		cmd  string
+		want RiskLevel
+	}{
+		{"make test", RiskSafe},
+		{"make deploy", RiskUnknown},
+		{"terraform destroy -auto-approve", RiskDanger},
+		{"ls -la", RiskSafe},
+		{"rm -rf build", RiskDanger},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			assessment := ScoreCommandWithPatterns(tt.cmd, allow, deny)
+			if assessment.Level != tt.want {
+				t.Errorf("ScoreCommandWithPatterns(%q) = %v, want %v", tt.cmd, assessment.Level, tt.want)
+			}
+		})
+	}
+
+	assessment := ScoreCommandWithPatterns("terraform destroy", allow, deny)
+	if len(assessment.Flags) != 1 || assessment.Flags[0] != deny[0] {
+		t.Errorf("expected user deny pattern in flags, got %v", assessment.Flags)
+	}
+}
+
+func TestScoreCommand_ShellParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		expected RiskLevel
+		flag     string
+	}{
+		{"IFS word splitting", "rm$IFS-rf /", RiskDanger, flagIFS},
+		{"braced IFS", "rm${IFS}-rf${IFS}/tmp", RiskDanger, flagIFS},
+		{"escaped command name", `r\m -rf /tmp/test`, RiskDanger, `\brm\s+-[rR]f`},
+		{"quoted command name", `'rm' "-rf" /tmp/test`, RiskDanger, `\brm\s+-[rR]f`},
+		{"hidden in sh -c", `sh -c 'r\m -r''f /tmp'`, RiskDanger, `\brm\s+-[rR]f`},
+		{"dynamic command name", `$cmd -la`, RiskDanger, flagDynamicName},
+		{"subshell", "(cd /tmp && ls)", RiskDanger, flagCompound},
+		{"process substitution", "cat <(ls)", RiskDanger, flagProcSubst},
+		{"heredoc", "cat <<EOF\nhi\nEOF", RiskDanger, flagRedirect},
+		{"operator in quotes", `echo "a;b"`, RiskSafe, ""},
+		{"redirect in quotes", `grep "a > b" notes.txt`, RiskSafe, ""},
+		{"quoted ampersand", `echo 'me & you'`, RiskSafe, ""},
+		{"assignment only", "FOO=bar", RiskUnknown, ""},
+		{"unparseable falls back to patterns", "echo 'unterminated; rm -rf /", RiskDanger, `;`},
+		{"unparseable is never safe", "ls 'unterminated", RiskUnknown, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := ScoreCommand(tt.cmd)
+			if assessment.Level != tt.expected {
+				t.Errorf("ScoreCommand(%q) = %v (%v), want %v", tt.cmd, assessment.Level, assessment.Flags, tt.expected)
+			}
+			if tt.flag != "" && !slices.Contains(assessment.Flags, tt.flag) {
+				t.Errorf("ScoreCommand(%q) flags = %v, want %q", tt.cmd, assessment.Flags, tt.flag)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// sandboxMount is where the sandbox workdir is mounted inside the container
+const sandboxMount = "/workspace"
+
+// runDocker runs docker with the given args and returns its combined output.
+// It's a variable so tests can stub it.
+var runDocker = func(ctx context.Context, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+}
+
+// sandboxWorkdir returns the absolute host directory mounted into the sandbox
+func (m *Manager) sandboxWorkdir() (string, error) {
+	dir := m.Config.Sandbox.Workdir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		dir = wd
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sandbox workdir: %w", err)
+	}
+	return abs, nil
+}
+
+// sandboxArgs builds the `docker run` arguments for a command
+func (m *Manager) sandboxArgs(command, workdir string) []string {
+	cfg := m.Config.Sandbox
+	args := []string{"run", "--rm", "-v", workdir + ":" + sandboxMount, "-w", sandboxMount}
+	if cfg.Network != "" {
+		args = append(args, "--network", cfg.Network)
+	}
+	args = append(args, cfg.DockerArgs...)
+	return append(args, cfg.Image, "sh", "-c", command)
+}
+
+// ExecInSandbox runs a command in a fresh container that is removed when the
+// command exits. Only changes under the mounted workdir outlive the command.
+func (m *Manager) ExecInSandbox(command string) (CommandExecHistory, error) {
+	history := CommandExecHistory{Command: command}
+
+	workdir, err := m.sandboxWorkdir()
+	if err != nil {
+		return history, err
+	}
+
+	timeout := time.Duration(m.Config.Sandbox.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info("sandbox: running in %s (workdir %s): %s", m.Config.Sandbox.Image, workdir, command)
+	output, err := runDocker(ctx, m.sandboxArgs(command, workdir)...)
+	history.Output = strings.TrimRight(string(output), "\n")
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return history, fmt.Errorf("sandbox command timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		history.Code = exitErr.ExitCode()
+	case err != nil:
+		return history, fmt.Errorf("failed to run docker: %w", err)
+	}
+	return history, nil
+}
+
+// sandboxObservation is the follow-up message for commands run in the sandbox,
+// whose output never reaches the exec pane
+func sandboxObservation(results []CommandExecHistory, maxLines int) string {
+	var b strings.Builder
+	b.WriteString("Sandbox mode is on: these commands ran in a throwaway Docker container, not in the exec pane. " +
+		"Only files under " + sandboxMount + " persist between commands.\n")
+	for _, r := range results {
+		output := r.Output
+		if lines := strings.Split(output, "\n"); maxLines > 0 && len(lines) > maxLines {
+			output = strings.Join(lines[len(lines)-maxLines:], "\n")
+		}
+		fmt.Fprintf(&b, "<sandbox_command exit_code=\"%d\">\n$ %s\n%s\n</sandbox_command>\n", r.Code, sanitizeXML(r.Command), sanitizeXML(output))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
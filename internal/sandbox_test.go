@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecInSandbox(t *testing.T) {
+	orig := runDocker
+	t.Cleanup(func() { runDocker = orig })
+
+	var gotArgs []string
+	runDocker = func(ctx context.Context, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("hello\n"), nil
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Sandbox.Workdir = "/tmp/project"
+	cfg.Sandbox.DockerArgs = []string{"--memory", "512m"}
+	manager := &Manager{Config: cfg, SessionOverrides: make(map[string]interface{})}
+
+	history, err := manager.ExecInSandbox("echo hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", history.Output)
+	assert.Equal(t, 0, history.Code)
+	assert.Equal(t, []string{
+		"run", "--rm", "-v", "/tmp/project:/workspace", "-w", "/workspace",
+		"--network", "none", "--memory", "512m",
+		"alpine:latest", "sh", "-c", "echo hello",
+	}, gotArgs)
+}
+
+func TestExecInSandboxExitCode(t *testing.T) {
+	orig := runDocker
+	t.Cleanup(func() { runDocker = orig })
+
+	runDocker = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "sh", "-c", "echo oops; exit 3").CombinedOutput()
+	}
+	manager := &Manager{Config: config.DefaultConfig(), SessionOverrides: make(map[string]interface{})}
+
+	history, err := manager.ExecInSandbox("false")
+	require.NoError(t, err, "a failing command isn't a sandbox error")
+	assert.Equal(t, 3, history.Code)
+	assert.Equal(t, "oops", history.Output)
+
+	runDocker = func(ctx context.Context, args ...string) ([]byte, error) {
+		return nil, errors.New("executable file not found")
+	}
+	_, err = manager.ExecInSandbox("true")
+	assert.ErrorContains(t, err, "failed to run docker")
+}
+
+func TestSandboxObservation(t *testing.T) {
+	obs := sandboxObservation([]CommandExecHistory{
+		{Command: "ls <dir>", Output: "a\nb\nc", Code: 1},
+	}, 2)
+	assert.Contains(t, obs, "<sandbox_command exit_code=\"1\">\n$ ls &lt;dir&gt;\nb\nc\n</sandbox_command>")
+}
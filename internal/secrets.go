@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// secretTimeout bounds an api_key_cmd or keychain lookup, long enough for
+// a password manager to ask for a fingerprint
+const secretTimeout = 60 * time.Second
+
+// keychainCommand returns the command that prints a secret stored under
+// service: security on macOS, secret-tool (libsecret) elsewhere
+var keychainCommand = func(ctx context.Context, service string) *exec.Cmd {
+	if runtime.GOOS == "darwin" {
+		return exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-w")
+	}
+	return exec.CommandContext(ctx, "secret-tool", "lookup", "service", service)
+}
+
+// secretSource names where a model's API key comes from when api_key is
+// empty, or "" when it needs no lookup
+func secretSource(mc config.ModelConfig) string {
+	switch {
+	case mc.APIKey != "":
+		return ""
+	case mc.APIKeyCmd != "":
+		return "api_key_cmd: " + mc.APIKeyCmd
+	case mc.APIKeyKeychain != "":
+		return "api_key_keychain: " + mc.APIKeyKeychain
+	}
+	return ""
+}
+
+// hasAPIKey reports whether a model has an API key or a way to read one,
+// without reading it
+func hasAPIKey(mc config.ModelConfig) bool {
+	return mc.APIKey != "" || secretSource(mc) != ""
+}
+
+// cachedAPIKey returns the API key of mc, filled in from a lookup done
+// earlier. It never runs the lookup itself.
+func (c *AiClient) cachedAPIKey(mc config.ModelConfig) string {
+	source := secretSource(mc)
+	if source == "" {
+		return mc.APIKey
+	}
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	return c.secrets[source]
+}
+
+// resolveAPIKey returns mc with its API key read from api_key_cmd or the
+// keychain. Keys are read once, when a request first needs them, and kept
+// for the rest of the session.
+func (c *AiClient) resolveAPIKey(ctx context.Context, mc config.ModelConfig) (config.ModelConfig, error) {
+	source := secretSource(mc)
+	if source == "" {
+		return mc, nil
+	}
+
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	if key, ok := c.secrets[source]; ok {
+		mc.APIKey = key
+		return mc, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, secretTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if mc.APIKeyCmd != "" {
+		cmd = exec.CommandContext(ctx, "sh", "-c", mc.APIKeyCmd)
+	} else {
+		cmd = keychainCommand(ctx, mc.APIKeyKeychain)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return mc, fmt.Errorf("failed to read the API key with %s: %w", source, err)
+	}
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return mc, fmt.Errorf("failed to read the API key with %s: no output", source)
+	}
+
+	logger.Info("Read the API key with %s", source)
+	if c.secrets == nil {
+		c.secrets = make(map[string]string)
+	}
+	c.secrets[source] = key
+	mc.APIKey = key
+	return mc, nil
+}
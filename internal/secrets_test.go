@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAPIKeyRunsCommandOnce(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	mc := config.ModelConfig{Provider: "openai", APIKeyCmd: "echo run >> " + runs + "; echo ' sk-from-cmd '"}
+	c := NewAiClient(config.DefaultConfig())
+
+	assert.Empty(t, c.cachedAPIKey(mc), "nothing is read before a request needs it")
+	for i := 0; i < 2; i++ {
+		resolved, err := c.resolveAPIKey(context.Background(), mc)
+		require.NoError(t, err)
+		assert.Equal(t, "sk-from-cmd", resolved.APIKey)
+	}
+	assert.Equal(t, "sk-from-cmd", c.cachedAPIKey(mc))
+
+	data, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(data))
+}
+
+func TestResolveAPIKeyErrors(t *testing.T) {
+	c := NewAiClient(config.DefaultConfig())
+
+	_, err := c.resolveAPIKey(context.Background(), config.ModelConfig{APIKeyCmd: "echo locked >&2; exit 1"})
+	assert.ErrorContains(t, err, "api_key_cmd: echo locked >&2; exit 1")
+	assert.ErrorContains(t, err, "locked")
+
+	_, err = c.resolveAPIKey(context.Background(), config.ModelConfig{APIKeyCmd: "true"})
+	assert.ErrorContains(t, err, "no output")
+
+	resolved, err := c.resolveAPIKey(context.Background(), config.ModelConfig{APIKey: "plain", APIKeyCmd: "exit 1"})
+	require.NoError(t, err, "a plaintext api_key wins")
+	assert.Equal(t, "plain", resolved.APIKey)
+}
+
+func TestResolveAPIKeyFromKeychain(t *testing.T) {
+	original := keychainCommand
+	t.Cleanup(func() { keychainCommand = original })
+	var service string
+	keychainCommand = func(ctx context.Context, s string) *exec.Cmd {
+		service = s
+		return exec.CommandContext(ctx, "echo", "sk-from-keychain")
+	}
+
+	c := NewAiClient(config.DefaultConfig())
+	resolved, err := c.resolveAPIKey(context.Background(), config.ModelConfig{APIKeyKeychain: "tmuxai-openrouter"})
+	require.NoError(t, err)
+	assert.Equal(t, "tmuxai-openrouter", service)
+	assert.Equal(t, "sk-from-keychain", resolved.APIKey)
+}
+
+func TestRequestUsesAPIKeyFromCommand(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "some/model", APIKeyCmd: "echo sk-secret", BaseURL: server.URL}}
+	m := newStandaloneManager(cfg)
+	assert.True(t, m.hasValidAIConfiguration())
+
+	messages := []ChatMessage{{Content: "hi", FromUser: true, Timestamp: time.Now()}}
+	_, err := m.AiClient.GetResponseFromChatMessages(context.Background(), messages, m.GetModel())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer sk-secret", auth)
+}
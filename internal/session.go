@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/session"
+)
+
+// initSessions opens the session store and starts a new session
+func (m *Manager) initSessions() {
+	if !m.Config.SaveSessions {
+		return
+	}
+	store, err := session.NewFileStore(config.GetConfigFilePath("sessions"))
+	if err != nil {
+		logger.Error("Failed to open session store: %v", err)
+		return
+	}
+	m.sessionStore = store
+	m.newSession()
+}
+
+// newSession starts saving to a fresh session, leaving the previous one on disk
+func (m *Manager) newSession() {
+	m.stateMu.Lock()
+	m.SessionID = session.NewID()
+	m.stateMu.Unlock()
+	logger.SetSession(m.SessionID)
+	m.sessionCreatedAt = time.Now()
+	m.sessionTitle = ""
+	m.sessionSummary = nil
+}
+
+// snapshotSession captures the manager state that a resume restores
+func (m *Manager) snapshotSession() *session.Session {
+	sess := &session.Session{
+		ID:               m.SessionID,
+		Title:            m.sessionTitle,
+		CreatedAt:        m.sessionCreatedAt,
+		SessionOverrides: m.SessionOverrides,
+		Summary:          m.sessionSummary,
+	}
+	for _, msg := range m.Messages {
+		sess.Messages = append(sess.Messages, session.Message{Content: msg.Content, Request: msg.Request, FromUser: msg.FromUser, Typed: msg.Typed, Timestamp: msg.Timestamp})
+	}
+	for _, h := range m.ExecHistory {
+		sess.ExecHistory = append(sess.ExecHistory, session.ExecRecord{Command: h.Command, Output: h.Output, Code: h.Code})
+	}
+	for name := range m.LoadedKBs {
+		sess.LoadedKBs = append(sess.LoadedKBs, name)
+	}
+	sort.Strings(sess.LoadedKBs)
+	return sess
+}
+
+// saveSession persists the current session; empty sessions aren't saved
+func (m *Manager) saveSession() {
+	if m.sessionStore == nil || m.SessionID == "" || len(m.Messages) == 0 {
+		return
+	}
+	m.summarizeSession()
+	if err := m.sessionStore.Save(m.snapshotSession()); err != nil {
+		logger.Error("Failed to save session %s: %v", m.SessionID, err)
+	}
+}
+
+// ResumeSession replaces the chat state with a saved session. id may be
+// "latest" for the most recently updated one.
+func (m *Manager) ResumeSession(id string) error {
+	if m.sessionStore == nil {
+		return fmt.Errorf("session saving is disabled (save_sessions: false)")
+	}
+
+	var sess *session.Session
+	var err error
+	if id == "latest" {
+		sess, err = m.sessionStore.Latest()
+	} else {
+		sess, err = m.sessionStore.Load(id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// keep what we have so far before switching
+	if sess.ID != m.SessionID {
+		m.saveSession()
+	}
+
+	messages := make([]ChatMessage, 0, len(sess.Messages))
+	for _, msg := range sess.Messages {
+		messages = append(messages, ChatMessage{Content: msg.Content, Request: msg.Request, FromUser: msg.FromUser, Typed: msg.Typed, Timestamp: msg.Timestamp})
+	}
+	// JSON decodes numbers as float64 while the getters expect int
+	overrides := make(map[string]interface{}, len(sess.SessionOverrides))
+	for key, value := range sess.SessionOverrides {
+		if f, ok := value.(float64); ok && f == float64(int(f)) {
+			value = int(f)
+		}
+		overrides[key] = value
+	}
+
+	execHistory := make([]CommandExecHistory, 0, len(sess.ExecHistory))
+	for _, h := range sess.ExecHistory {
+		execHistory = append(execHistory, CommandExecHistory{Command: h.Command, Output: h.Output, Code: h.Code})
+	}
+
+	m.stateMu.Lock()
+	m.SessionID = sess.ID
+	m.Messages = messages
+	m.ExecHistory = execHistory
+	m.SessionOverrides = overrides
+	m.stateMu.Unlock()
+	logger.SetSession(m.SessionID)
+	m.sessionCreatedAt = sess.CreatedAt
+	m.sessionTitle = sess.Title
+	m.sessionSummary = sess.Summary
+
+	// KB contents are re-read so edits made since the save are picked up
+	m.LoadedKBs = make(map[string]string, len(sess.LoadedKBs))
+	for _, name := range sess.LoadedKBs {
+		if err := m.loadKB(name); err != nil {
+			logger.Error("Failed to reload knowledge base %s for session %s: %v", name, sess.ID, err)
+		}
+	}
+
+	logger.Info("Resumed session %s with %d messages", sess.ID, len(m.Messages))
+	return nil
+}
+
+// showSessions lists saved sessions, most recent first
+func (m *Manager) showSessions() {
+	if m.sessionStore == nil {
+		m.Println("Session saving is disabled (save_sessions: false)")
+		return
+	}
+	sessions, err := m.sessionStore.List()
+	if err != nil {
+		m.Println(fmt.Sprintf("Error listing sessions: %v", err))
+		return
+	}
+	if len(sessions) == 0 {
+		m.Println("No saved sessions")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Saved sessions:\n")
+	for _, sess := range sessions {
+		current := ""
+		if sess.ID == m.SessionID {
+			current = " (current)"
+		}
+		fmt.Fprintf(&b, "  %s  %s  %d messages  %s%s\n",
+			sess.ID, sess.UpdatedAt.Local().Format("2006-01-02 15:04"), len(sess.Messages), sessionPreview(sess), current)
+		if sess.Summary != nil && sess.Summary.Summary != "" {
+			fmt.Fprintf(&b, "      %s\n", sess.Summary.Summary)
+		}
+	}
+	m.Println(strings.TrimRight(b.String(), "\n"))
+}
+
+// sessionPreview returns the session's generated name, or the start of its
+// first request
+func sessionPreview(sess *session.Session) string {
+	if sess.Summary != nil && sess.Summary.Name != "" {
+		return sess.Summary.Name
+	}
+	preview := strings.Join(strings.Fields(sess.Title), " ")
+	if len([]rune(preview)) > 60 {
+		preview = string([]rune(preview)[:57]) + "..."
+	}
+	return preview
+}
+
+// sessionSummaryInterval is how many new messages trigger a fresh summary
+const sessionSummaryInterval = 10
+
+// summarizeSession names and summarizes the session with the
+// session_summary_model (or the current model) once it has a first exchange,
+// refreshing it as the conversation grows. Failures only leave the old summary.
+func (m *Manager) summarizeSession() {
+	if !m.Config.SessionSummaries || m.AiClient == nil || len(m.Messages) < 2 {
+		return
+	}
+	if m.sessionSummary != nil && len(m.Messages)-m.sessionSummary.MessageCount < sessionSummaryInterval {
+		return
+	}
+
+	prompt := "Give this terminal assistant session a short name (at most 6 words) and a one-sentence summary of what was done. " +
+		"Reply with exactly two lines:\nName: <name>\nSummary: <summary>\n\n" + m.sessionTranscript(4000)
+	messages := []ChatMessage{{Content: prompt, FromUser: true, Timestamp: time.Now()}}
+
+	model := m.Config.SessionSummaryModel
+	if model == "" {
+		model = m.Config.Roles.Summarize
+	}
+	response, err := m.withModel(model, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return m.AiClient.GetResponseFromChatMessages(ctx, messages, m.GetModel())
+	})
+	if err != nil {
+		logger.Error("Failed to summarize session %s: %v", m.SessionID, err)
+		return
+	}
+
+	summary := parseSessionSummary(response)
+	if summary.Name == "" {
+		logger.Debug("Session summary response had no name: %s", response)
+		return
+	}
+	summary.MessageCount = len(m.Messages)
+	summary.GeneratedAt = time.Now()
+	m.sessionSummary = summary
+}
+
+// withModel runs fn with another model configuration selected, restoring the
+// session's model afterwards. An empty or unknown name keeps the current one.
+func (m *Manager) withModel(name string, fn func() (string, error)) (string, error) {
+	if name == "" {
+		return fn()
+	}
+	if _, exists := m.GetModelConfig(name); !exists {
+		logger.Error("Model configuration %q not found, using the current model", name)
+		return fn()
+	}
+
+	defer m.selectModel(name)()
+	return fn()
+}
+
+// selectModel selects another model configuration for the session and
+// returns the function that restores the previous one
+func (m *Manager) selectModel(name string) func() {
+	prev, hadOverride := m.SessionOverrides["default_model"]
+	m.setOverride("default_model", name)
+	return func() {
+		if hadOverride {
+			m.setOverride("default_model", prev)
+		} else {
+			m.deleteOverride("default_model")
+		}
+	}
+}
+
+// sessionTranscript renders typed requests and AI replies without pane
+// context, keeping the most recent maxChars
+func (m *Manager) sessionTranscript(maxChars int) string {
+	var b strings.Builder
+	for _, msg := range m.Messages {
+		if msg.FromUser {
+			if msg.Typed {
+				fmt.Fprintf(&b, "User: %s\n", msg.Request)
+			}
+			continue
+		}
+		text := msg.Content
+		if r, err := m.parseAIResponse(msg.Content); err == nil {
+			text = r.Message
+			for _, command := range r.ExecCommand {
+				text += "\n$ " + command
+			}
+		}
+		fmt.Fprintf(&b, "Assistant: %s\n", strings.TrimSpace(text))
+	}
+	transcript := b.String()
+	if len(transcript) > maxChars {
+		transcript = transcript[len(transcript)-maxChars:]
+	}
+	return transcript
+}
+
+// parseSessionSummary reads the "Name:" and "Summary:" lines of a response
+func parseSessionSummary(response string) *session.SessionSummary {
+	summary := &session.SessionSummary{}
+	for _, line := range strings.Split(response, "\n") {
+		// models like to bold the labels
+		line = strings.TrimSpace(strings.ReplaceAll(line, "**", ""))
+		if name, ok := cutPrefixFold(line, "name:"); ok {
+			summary.Name = strings.Trim(strings.TrimSpace(name), `"`)
+		} else if text, ok := cutPrefixFold(line, "summary:"); ok {
+			summary.Summary = strings.TrimSpace(text)
+		}
+	}
+	return summary
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
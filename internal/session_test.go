@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newManager := func() *Manager {
+		m := &Manager{
+			Config:           &config.Config{SaveSessions: true},
+			SessionOverrides: make(map[string]interface{}),
+			LoadedKBs:        make(map[string]string),
+		}
+		m.initSessions()
+		return m
+	}
+
+	saved := newManager()
+	saved.sessionTitle = "fix the build"
+	saved.Messages = []ChatMessage{{Content: "fix the build", FromUser: true}, {Content: "done"}}
+	saved.ExecHistory = []CommandExecHistory{{Command: "make", Output: "ok", Code: 2}}
+	saved.SessionOverrides["max_capture_lines"] = 50
+	saved.SessionOverrides["sandbox"] = true
+	saved.LoadedKBs["missing-kb"] = "content"
+	saved.saveSession()
+
+	fresh := newManager()
+	fresh.SessionID = "fresh"
+	require.NoError(t, fresh.ResumeSession(saved.SessionID))
+	assert.Equal(t, saved.SessionID, fresh.SessionID)
+	assert.Equal(t, saved.Messages[1].Content, fresh.Messages[1].Content)
+	assert.Equal(t, 2, fresh.ExecHistory[0].Code)
+	assert.Equal(t, 50, fresh.GetMaxCaptureLines(), "ints survive the JSON round trip")
+	assert.True(t, fresh.GetSandbox())
+	assert.Empty(t, fresh.LoadedKBs, "KBs that no longer exist are skipped")
+
+	other := newManager()
+	require.NoError(t, other.ResumeSession("latest"))
+	assert.Equal(t, saved.SessionID, other.SessionID)
+
+	assert.ErrorContains(t, other.ResumeSession("nope"), "not found")
+}
+
+func TestSaveSessionSkipsEmpty(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := &Manager{Config: &config.Config{SaveSessions: true}, SessionOverrides: map[string]interface{}{}}
+	m.initSessions()
+	m.saveSession()
+
+	sessions, err := m.sessionStore.List()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestParseSessionSummary(t *testing.T) {
+	summary := parseSessionSummary("**Name:** \"Fix nginx reload\"\nSummary: Found a typo in the nginx config and reloaded it.\n")
+	assert.Equal(t, "Fix nginx reload", summary.Name)
+	assert.Equal(t, "Found a typo in the nginx config and reloaded it.", summary.Summary)
+
+	assert.Empty(t, parseSessionSummary("I can't help with that").Name)
+}
+
+func TestWithModelRestoresOverride(t *testing.T) {
+	manager := &Manager{
+		Config: &config.Config{Models: map[string]config.ModelConfig{
+			"main":  {Provider: "openai", Model: "gpt-5"},
+			"cheap": {Provider: "openai", Model: "gpt-5-mini"},
+		}},
+		SessionOverrides: make(map[string]interface{}),
+	}
+
+	model, err := manager.withModel("cheap", func() (string, error) { return manager.GetModelsDefault(), nil })
+	require.NoError(t, err)
+	assert.Equal(t, "cheap", model)
+	_, exists := manager.SessionOverrides["default_model"]
+	assert.False(t, exists, "no override is left behind")
+
+	manager.SessionOverrides["default_model"] = "main"
+	model, _ = manager.withModel("missing", func() (string, error) { return manager.GetModelsDefault(), nil })
+	assert.Equal(t, "main", model, "unknown models fall back to the current one")
+	_, _ = manager.withModel("cheap", func() (string, error) { return "", nil })
+	assert.Equal(t, "main", manager.SessionOverrides["default_model"])
+}
+
+func TestSessionTranscriptSkipsPaneContext(t *testing.T) {
+	manager := &Manager{Config: &config.Config{}, Messages: []ChatMessage{
+		{Content: "<panes/>\n\ncheck disk", Request: "check disk", FromUser: true, Typed: true},
+		{Content: "Checking.\n<ExecCommand>df -h</ExecCommand>"},
+		{Content: "<panes/>\n\nsending updated pane(s) content", Request: "sending updated pane(s) content", FromUser: true},
+	}}
+	assert.Equal(t, "User: check disk\nAssistant: Checking.\n$ df -h\n", manager.sessionTranscript(1000))
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// maxHistoryCommandLen skips long one-off commands from the frequent list,
+// they are rarely worth repeating and cost tokens
+const maxHistoryCommandLen = 160
+
+// zshHistoryPrefix is the ": <start>:<elapsed>;" of zsh's extended history
+var zshHistoryPrefix = regexp.MustCompile(`^: \d+:\d+;`)
+
+// aliasLine matches alias definitions in bash and zsh rc files, and alias
+// and abbr definitions in fish config
+var aliasLine = regexp.MustCompile(`^\s*(?:alias|abbr)\s+\S`)
+
+// historyShell maps a shell name to the history and rc formats we read,
+// "" when it isn't bash, zsh or fish
+func historyShell(shell string) string {
+	name := strings.TrimPrefix(filepath.Base(shell), "-")
+	for _, s := range []string{"bash", "zsh", "fish"} {
+		if name == s {
+			return s
+		}
+	}
+	return ""
+}
+
+// historyFile returns where shell keeps its history: $HISTFILE for bash and
+// zsh when set, else the default location
+func historyFile(shell, home string) string {
+	switch shell {
+	case "fish":
+		dataDir := os.Getenv("XDG_DATA_HOME")
+		if dataDir == "" {
+			dataDir = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(dataDir, "fish", "fish_history")
+	case "zsh":
+		if f := os.Getenv("HISTFILE"); f != "" {
+			return f
+		}
+		return filepath.Join(home, ".zsh_history")
+	default:
+		if f := os.Getenv("HISTFILE"); f != "" {
+			return f
+		}
+		return filepath.Join(home, ".bash_history")
+	}
+}
+
+// aliasFiles returns the rc files shell aliases are usually defined in
+func aliasFiles(shell, home string) []string {
+	switch shell {
+	case "fish":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(home, ".config")
+		}
+		files := []string{filepath.Join(configDir, "fish", "config.fish")}
+		confd, _ := filepath.Glob(filepath.Join(configDir, "fish", "conf.d", "*.fish"))
+		return append(files, confd...)
+	case "zsh":
+		dir := os.Getenv("ZDOTDIR")
+		if dir == "" {
+			dir = home
+		}
+		return []string{filepath.Join(dir, ".zshrc"), filepath.Join(dir, ".zsh_aliases")}
+	default:
+		return []string{filepath.Join(home, ".bashrc"), filepath.Join(home, ".bash_aliases")}
+	}
+}
+
+// parseHistory returns the commands of a history file, oldest first
+func parseHistory(shell string, data []byte) []string {
+	var commands []string
+	lines := strings.Split(string(data), "\n")
+	switch shell {
+	case "fish":
+		for _, line := range lines {
+			if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+				commands = append(commands, strings.ReplaceAll(cmd, `\\`, `\`))
+			}
+		}
+	case "zsh":
+		data = unmetafyZsh(data)
+		lines = strings.Split(string(data), "\n")
+		for i := 0; i < len(lines); i++ {
+			cmd := zshHistoryPrefix.ReplaceAllString(lines[i], "")
+			// multi-line commands are stored with an escaped newline
+			for strings.HasSuffix(cmd, `\`) && i+1 < len(lines) {
+				i++
+				cmd = cmd[:len(cmd)-1] + "\n" + lines[i]
+			}
+			commands = append(commands, cmd)
+		}
+	default:
+		for _, line := range lines {
+			// HISTTIMEFORMAT writes a "#<timestamp>" line before each command
+			if len(line) > 1 && line[0] == '#' && strings.Trim(line[1:], "0123456789") == "" {
+				continue
+			}
+			commands = append(commands, line)
+		}
+	}
+
+	kept := commands[:0]
+	for _, cmd := range commands {
+		if cmd = strings.TrimSpace(cmd); cmd != "" {
+			kept = append(kept, cmd)
+		}
+	}
+	return kept
+}
+
+// unmetafyZsh undoes the escaping zsh applies to some bytes in its history
+// file (0x83 followed by the byte xor 32), which garbles non-ASCII commands
+func unmetafyZsh(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == 0x83 && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^32)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+// parseAliases returns the alias (and fish abbr) definitions in rc file data
+func parseAliases(data []byte) []string {
+	var aliases []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if aliasLine.MatchString(line) {
+			aliases = append(aliases, strings.TrimSpace(line))
+		}
+	}
+	return aliases
+}
+
+// commandProgram returns the program a command runs, skipping sudo and
+// leading variable assignments
+func commandProgram(cmd string) string {
+	for _, field := range strings.Fields(cmd) {
+		if field == "sudo" || field == "env" || field == "time" || strings.Contains(field, "=") {
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+// rankedCount is a string and how often it occurs
+type rankedCount struct {
+	Value string
+	Count int
+	Last  int // position of the last occurrence, to break ties by recency
+}
+
+// rankByCount counts the values and returns the limit most frequent ones,
+// the most recent first among equals
+func rankByCount(values []string, limit int) []rankedCount {
+	counts := map[string]*rankedCount{}
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		if c, ok := counts[v]; ok {
+			c.Count++
+			c.Last = i
+		} else {
+			counts[v] = &rankedCount{Value: v, Count: 1, Last: i}
+		}
+	}
+	ranked := make([]rankedCount, 0, len(counts))
+	for _, c := range counts {
+		ranked = append(ranked, *c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Last > ranked[j].Last
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// shellContextXml summarizes the history and aliases of shell: the aliases,
+// the most used programs and the most repeated commands, secrets redacted.
+// It returns "" when there's nothing to summarize.
+func (m *Manager) shellContextXml(shell, home string) string {
+	cfg := m.Config.ShellContext
+
+	var aliases []string
+	for _, path := range aliasFiles(shell, home) {
+		if data, err := os.ReadFile(path); err == nil {
+			aliases = append(aliases, parseAliases(data)...)
+		}
+	}
+
+	var commands []string
+	path := historyFile(shell, home)
+	if data, err := os.ReadFile(path); err == nil {
+		commands = parseHistory(shell, data)
+	} else if !os.IsNotExist(err) {
+		logger.Error("Failed to read shell history %s: %v", path, err)
+	}
+	if cfg.HistoryLines > 0 && len(commands) > cfg.HistoryLines {
+		commands = commands[len(commands)-cfg.HistoryLines:]
+	}
+
+	programs := make([]string, len(commands))
+	repeatable := make([]string, len(commands))
+	for i, cmd := range commands {
+		programs[i] = commandProgram(cmd)
+		if len(cmd) <= maxHistoryCommandLen && !strings.Contains(cmd, "\n") {
+			repeatable[i] = cmd
+		}
+	}
+	var frequent []rankedCount
+	for _, c := range rankByCount(repeatable, cfg.MaxCommands) {
+		// a command run once says little about habits
+		if c.Count > 1 {
+			frequent = append(frequent, c)
+		}
+	}
+
+	if len(aliases) == 0 && len(commands) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<user_shell_habits shell=\"%s\">\n", shell)
+	b.WriteString("Taken from the user's shell history and config. Prefer these tools, aliases, contexts and targets when suggesting commands.\n")
+	if len(aliases) > 0 {
+		if cfg.MaxCommands > 0 && len(aliases) > cfg.MaxCommands*2 {
+			aliases = aliases[:cfg.MaxCommands*2]
+		}
+		fmt.Fprintf(&b, "Aliases:\n%s\n", strings.Join(aliases, "\n"))
+	}
+	if ranked := rankByCount(programs, cfg.MaxCommands); len(ranked) > 0 {
+		used := make([]string, len(ranked))
+		for i, c := range ranked {
+			used[i] = fmt.Sprintf("%s (%d)", c.Value, c.Count)
+		}
+		fmt.Fprintf(&b, "Most used programs: %s\n", strings.Join(used, ", "))
+	}
+	if len(frequent) > 0 {
+		b.WriteString("Frequent commands:\n")
+		for _, c := range frequent {
+			b.WriteString(c.Value + "\n")
+		}
+	}
+	b.WriteString("</user_shell_habits>\n")
+	return redactSecrets(b.String(), nil)
+}
+
+// shellContextPrompt returns the shell habits block for the exec pane's
+// shell (or $SHELL) when shell_context is on. History and rc files are only
+// read once per shell and session; they're local, so nothing is sent when
+// tmux runs on a remote host.
+func (m *Manager) shellContextPrompt() string {
+	if !m.GetShellContext() || system.TmuxIsRemote() {
+		return ""
+	}
+	shell := ""
+	if m.ExecPane != nil {
+		shell = historyShell(m.ExecPane.Shell)
+	}
+	if shell == "" {
+		shell = historyShell(os.Getenv("SHELL"))
+	}
+	if shell == "" {
+		return ""
+	}
+	block, ok := m.shellContextCache[shell]
+	if !ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		block = m.shellContextXml(shell, home)
+		if m.shellContextCache == nil {
+			m.shellContextCache = map[string]string{}
+		}
+		m.shellContextCache[shell] = block
+	}
+	if block == "" {
+		return ""
+	}
+	return "\n" + block
+}
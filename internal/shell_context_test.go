@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHistory(t *testing.T) {
+	bash := "ls -la\n#1700000000\ngit status\n\n  make test  \n"
+	assert.Equal(t, []string{"ls -la", "git status", "make test"}, parseHistory("bash", []byte(bash)))
+
+	zsh := ": 1700000000:0;eza -l\n: 1700000001:3;for f in *; do\\\necho $f\\\ndone\n: 1700000002:0;echo \xc4\x83\xa3\n"
+	assert.Equal(t, []string{"eza -l", "for f in *; do\necho $f\ndone", "echo ă"}, parseHistory("zsh", []byte(zsh)))
+
+	fish := "- cmd: kubectl get pods\n  when: 1700000000\n- cmd: echo a\\\\b\n  when: 1700000001\n  paths:\n    - a\n"
+	assert.Equal(t, []string{"kubectl get pods", `echo a\b`}, parseHistory("fish", []byte(fish)))
+}
+
+func TestParseAliases(t *testing.T) {
+	rc := "export PATH=$PATH:~/bin\nalias ll='eza -l'\n  alias k=kubectl\n# alias old=x\nabbr -a gco git checkout\naliases=1\n"
+	assert.Equal(t, []string{"alias ll='eza -l'", "alias k=kubectl", "abbr -a gco git checkout"}, parseAliases([]byte(rc)))
+}
+
+func TestHistoryShellAndProgram(t *testing.T) {
+	assert.Equal(t, "zsh", historyShell("/usr/bin/zsh"))
+	assert.Equal(t, "bash", historyShell("-bash"))
+	assert.Equal(t, "", historyShell("pwsh"))
+
+	assert.Equal(t, "apt", commandProgram("sudo apt install jq"))
+	assert.Equal(t, "make", commandProgram("CGO_ENABLED=0 make build"))
+	assert.Equal(t, "", commandProgram("  "))
+}
+
+func TestRankByCount(t *testing.T) {
+	ranked := rankByCount([]string{"a", "b", "a", "c", "b", "", "d"}, 3)
+	require.Len(t, ranked, 3)
+	assert.Equal(t, "b", ranked[0].Value, "ties go to the most recent")
+	assert.Equal(t, 2, ranked[0].Count)
+	assert.Equal(t, "a", ranked[1].Value)
+	assert.Equal(t, "d", ranked[2].Value)
+}
+
+func TestShellContextPrompt(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HISTFILE", "")
+	t.Setenv("ZDOTDIR", "")
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zshrc"), []byte("alias ls=eza\n"), 0o644))
+	history := ": 1:0;kubectl config use-context prod\n: 2:0;make deploy\n: 3:0;make deploy\n: 4:0;export API_TOKEN=abcdef123456\n: 5:0;kubectl config use-context prod\n: 6:0;git push\n"
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zsh_history"), []byte(history), 0o600))
+
+	manager := &Manager{
+		Config:   &config.Config{ShellContext: config.ShellContextConfig{Enabled: true, MaxCommands: 5}},
+		ExecPane: &system.TmuxPaneDetails{Shell: "zsh"},
+	}
+	prompt := manager.shellContextPrompt()
+	assert.Contains(t, prompt, `<user_shell_habits shell="zsh">`)
+	assert.Contains(t, prompt, "Aliases:\nalias ls=eza\n")
+	assert.Contains(t, prompt, "Most used programs: kubectl (2), make (2), git (1), export (1)")
+	assert.Contains(t, prompt, "Frequent commands:\nkubectl config use-context prod\nmake deploy\n</user_shell_habits>")
+	assert.NotContains(t, prompt, "abcdef123456")
+
+	require.NoError(t, os.Remove(filepath.Join(home, ".zsh_history")))
+	assert.Equal(t, prompt, manager.shellContextPrompt(), "read once per session")
+
+	manager.SessionOverrides = map[string]interface{}{"shell_context.enabled": false}
+	assert.Empty(t, manager.shellContextPrompt())
+
+	manager.SessionOverrides = nil
+	manager.ExecPane.Shell = "bash"
+	assert.Empty(t, strings.TrimSpace(manager.shellContextPrompt()), "no bash history or aliases")
+}
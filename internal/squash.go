@@ -3,76 +3,186 @@ package internal
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alvinunreal/tmuxai/logger"
-	"github.com/alvinunreal/tmuxai/system"
 	"github.com/briandowns/spinner"
 )
 
-func (m *Manager) needSquash() bool {
+// chatSummaryPrefix marks the message that replaces squashed history
+const chatSummaryPrefix = "CHAT HISTORY SUMMARY:\n"
+
+// maxSquashCommands and maxSquashOutputLines bound the exec history sent for
+// summarization: the last commands, each with the end of its output
+const (
+	maxSquashCommands    = 30
+	maxSquashOutputLines = 5
+)
+
+// contextTokens estimates the tokens sent with the next request
+func (m *Manager) contextTokens() int {
 	totalTokens := 0
+	tokenizer := m.tokenizer()
 
 	isPrepared := m.ExecPane != nil && m.ExecPane.IsPrepared
-	totalTokens += system.EstimateTokenCount(m.chatAssistantPrompt(isPrepared).Content)
-	totalTokens += m.getTotalLoadedKBTokens()
+	totalTokens += tokenizer.Count(m.chatAssistantPrompt(isPrepared).Content)
+	if m.Config.KnowledgeBase.Search.Enabled {
+		totalTokens += tokenizer.Count(m.kbExcerptsSent)
+	} else {
+		totalTokens += m.kbPromptTokens()
+	}
 
 	// Count loaded skill content toward squash budget.
 	for _, content := range m.LoadedSkills {
-		totalTokens += system.EstimateTokenCount(content)
+		totalTokens += tokenizer.Count(content)
 	}
 
 	for _, msg := range m.Messages {
-		totalTokens += system.EstimateTokenCount(msg.Content)
+		totalTokens += tokenizer.Count(msg.Content)
 	}
+	return totalTokens
+}
 
-	threshold := int(float64(m.GetMaxContextSize()) * 0.8)
-	return totalTokens > threshold
+// squashBudget is the token count above which the history is squashed
+func (m *Manager) squashBudget() int {
+	threshold := m.Config.Compaction.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.8
+	}
+	return int(float64(m.GetMaxContextSize()) * threshold)
 }
 
-func (m *Manager) squashHistory() {
-	if len(m.Messages) < 2 {
+func (m *Manager) needSquash() bool {
+	return m.contextTokens() > m.squashBudget()
+}
+
+// squashHistory summarizes all but the most recent messages
+func (m *Manager) squashHistory() bool {
+	keep := m.Config.Compaction.KeepMessages
+	if keep < 0 {
+		keep = 0
+	}
+	return m.compactHistory(keep)
+}
+
+// squashCommand handles /squash [N]: it summarizes all but the last N
+// messages (compaction.keep_messages by default) and reports the tokens saved
+func (m *Manager) squashCommand(args []string) {
+	keep := max(m.Config.Compaction.KeepMessages, 0)
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			m.Println("Usage: /squash [messages to keep]")
+			return
+		}
+		keep = n
+	}
+
+	before, tokensBefore := len(m.Messages), m.contextTokens()
+	if !m.compactHistory(keep) {
+		m.Println(fmt.Sprintf("Nothing to squash: only the last %d messages are in the history", len(m.Messages)))
 		return
 	}
+	m.resetPaneSnapshots()
+	tokensAfter := m.contextTokens()
+	m.Println(fmt.Sprintf("✓ Squashed %d messages into a summary, kept %d: %d → %d tokens (%d saved)",
+		before-len(m.Messages)+1, len(m.Messages)-1, tokensBefore, tokensAfter, tokensBefore-tokensAfter))
+}
 
-	messagesToSummarize := m.Messages[:len(m.Messages)-1]
+// compactHistory replaces the oldest messages with a summary, keeping about
+// the last keep messages verbatim. It reports whether anything was replaced.
+func (m *Manager) compactHistory(keep int) bool {
+	split := compactionSplit(m.Messages, keep)
+	if split == 0 || (split == 1 && strings.HasPrefix(m.Messages[0].Content, chatSummaryPrefix)) {
+		return false
+	}
 
-	summarizedHistory, err := m.summarizeChatHistory(messagesToSummarize)
+	summarizedHistory, err := m.summarizeChatHistory(m.Messages[:split])
 	if err != nil {
 		logger.Error("Failed to summarize chat history: %v", err)
-		return
+		return false
 	}
 
-	m.Messages = []ChatMessage{
+	kept := m.Messages[split:]
+	m.setMessages(append([]ChatMessage{
 		{
 			Content:   summarizedHistory,
 			FromUser:  false,
 			Timestamp: time.Now(),
 		},
+	}, kept...))
+	logger.Debug("Context reduced through summarization: %d messages summarized, %d kept", split, len(kept))
+	return true
+}
+
+// compactionSplit returns how many leading messages to summarize so that
+// about keep messages remain. The kept part starts on a user message, so
+// roles still alternate after the summary.
+func compactionSplit(messages []ChatMessage, keep int) int {
+	split := len(messages) - keep
+	if split <= 0 {
+		return 0
+	}
+	for split < len(messages) && !messages[split].FromUser {
+		split++
 	}
-	logger.Debug("Context successfully reduced through summarization")
+	return split
+}
+
+// isContextLengthError reports whether a provider rejected a request for
+// being larger than the model's context window
+func isContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"context_length_exceeded",
+		"maximum context length",
+		"context window",
+		"prompt is too long",
+		"input is too long",
+		"too many tokens",
+		"request too large",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
 }
 
 // summarizeChatHistory asks the AI to summarize the chat history
 func (m *Manager) summarizeChatHistory(messages []ChatMessage) (string, error) {
 	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
 	s.Start()
+	defer s.Stop()
 
-	// Convert messages to a readable format for summarization
+	// Convert messages to a readable format for summarization. User requests
+	// are sent without the pane capture they were wrapped in, which is stale
+	// by now and would make the summary request nearly as large as the history.
 	var chatLog strings.Builder
 	for _, msg := range messages {
 		role := "Assistant"
+		content := msg.Content
 		if msg.FromUser {
 			role = "User"
+			if msg.Request != "" {
+				content = msg.Request
+			}
 		}
 
-		fmt.Fprintf(&chatLog, "[%s]: %s\n\n", role, msg.Content)
+		fmt.Fprintf(&chatLog, "[%s]: %s\n\n", role, content)
+	}
+	if commands := execHistoryLog(m.ExecHistory); commands != "" {
+		chatLog.WriteString(m.redactContext(commands))
 	}
 
 	// Create a summarization prompt
 	summarizationPrompt := fmt.Sprintf(
-		"Below is a chat history between a user and an assistant. Please provide a concise summary of the key points, decisions, and context from this conversation. Focus on the most important information that would be needed to continue the conversation effectively:\n\n%s",
+		"Below is a chat history between a user and an assistant, followed by the commands run in the exec pane. Please provide a concise summary of the key points, decisions, and context from this conversation, including which commands worked or failed. Focus on the most important information that would be needed to continue the conversation effectively:\n\n%s",
 		chatLog.String(),
 	)
 
@@ -88,7 +198,9 @@ func (m *Manager) summarizeChatHistory(messages []ChatMessage) (string, error) {
 	// Create a context for the summarization request (no timeout to support local LLMs with large contexts)
 	ctx := context.Background()
 
-	summary, err := m.AiClient.GetResponseFromChatMessages(ctx, summarizationMessage, m.GetModel())
+	summary, err := m.withModel(m.Config.Roles.Summarize, func() (string, error) {
+		return m.AiClient.GetResponseFromChatMessages(ctx, summarizationMessage, m.GetModel())
+	})
 	if err != nil {
 		return "", err
 	}
@@ -97,6 +209,27 @@ func (m *Manager) summarizeChatHistory(messages []ChatMessage) (string, error) {
 		debugChatMessages(summarizationMessage, summary)
 	}
 
-	s.Stop()
-	return fmt.Sprintf("CHAT HISTORY SUMMARY:\n%s", summary), nil
+	return chatSummaryPrefix + summary, nil
+}
+
+// execHistoryLog renders the last commands of the exec history with their
+// exit codes and the end of their output, for summarization
+func execHistoryLog(history []CommandExecHistory) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("[Exec pane commands]:\n")
+	for _, h := range history[max(len(history)-maxSquashCommands, 0):] {
+		fmt.Fprintf(&b, "$ %s", h.Command)
+		if h.Code >= 0 {
+			fmt.Fprintf(&b, " (exit code %d)", h.Code)
+		}
+		b.WriteString("\n")
+		if output := strings.TrimRight(h.Output, "\n"); output != "" {
+			lines := strings.Split(output, "\n")
+			b.WriteString(strings.Join(lines[max(len(lines)-maxSquashOutputLines, 0):], "\n") + "\n")
+		}
+	}
+	return b.String()
 }
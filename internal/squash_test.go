@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exchange(n int) []ChatMessage {
+	var messages []ChatMessage
+	for i := 0; i < n; i++ {
+		messages = append(messages,
+			ChatMessage{Content: "<pane>capture</pane>\n\nrequest", Request: "request", FromUser: true},
+			ChatMessage{Content: "reply"},
+		)
+	}
+	return messages
+}
+
+func TestCompactionSplit(t *testing.T) {
+	messages := exchange(5) // 10 messages, user messages at even indexes
+
+	assert.Equal(t, 4, compactionSplit(messages, 6))
+	assert.Equal(t, 6, compactionSplit(messages, 5), "kept part starts on a user message")
+	assert.Equal(t, 10, compactionSplit(messages, 0))
+	assert.Equal(t, 0, compactionSplit(messages, 10))
+	assert.Equal(t, 0, compactionSplit(messages, 20))
+}
+
+func TestIsContextLengthError(t *testing.T) {
+	assert.True(t, isContextLengthError(errors.New(`API returned error: {"error":{"code":"context_length_exceeded"}}`)))
+	assert.True(t, isContextLengthError(errors.New("This model's maximum context length is 8192 tokens")))
+	assert.True(t, isContextLengthError(errors.New("prompt is too long: 210000 tokens > 200000 maximum")))
+	assert.False(t, isContextLengthError(errors.New("API returned error: invalid api key")))
+	assert.False(t, isContextLengthError(nil))
+}
+
+func TestCompactHistoryKeepsRecentMessages(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"User listed files."}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "m",
+		Models:       map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}},
+		Compaction:   config.CompactionConfig{KeepMessages: 4},
+	}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.Messages = exchange(4)
+	manager.Messages[6].Request = "latest request"
+
+	require.True(t, manager.squashHistory())
+	require.Len(t, manager.Messages, 5)
+	assert.Equal(t, chatSummaryPrefix+"User listed files.", manager.Messages[0].Content)
+	assert.False(t, manager.Messages[0].FromUser)
+	assert.Equal(t, "latest request", manager.Messages[3].Request)
+	assert.NotContains(t, prompt, "<pane>", "pane captures aren't sent for summarization")
+
+	// the summary alone is never summarized again
+	manager.Messages = manager.Messages[:1]
+	assert.False(t, manager.compactHistory(0))
+	assert.True(t, strings.HasPrefix(manager.Messages[0].Content, chatSummaryPrefix))
+}
+
+func TestSquashUsesSummarizeRole(t *testing.T) {
+	var model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"User listed files."}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "main",
+		Roles:        config.RolesConfig{Summarize: "cheap"},
+		Models: map[string]config.ModelConfig{
+			"main":  {Provider: "openrouter", Model: "big-model", APIKey: "k", BaseURL: server.URL},
+			"cheap": {Provider: "openrouter", Model: "small-model", APIKey: "k", BaseURL: server.URL},
+		},
+		Compaction: config.CompactionConfig{KeepMessages: 2},
+	}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.Messages = exchange(3)
+
+	require.True(t, manager.squashHistory())
+	assert.Equal(t, "small-model", model)
+	assert.Equal(t, "main", manager.GetModelsDefault())
+}
+
+func TestSquashCommand(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Built the project, tests fail."}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "m",
+		Models:       map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL}},
+		Compaction:   config.CompactionConfig{KeepMessages: 6},
+	}
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	manager.AiClient = NewAiClient(cfg)
+	manager.AiClient.SetConfigManager(manager)
+	manager.Messages = exchange(4)
+	manager.ExecHistory = []CommandExecHistory{
+		{Command: "make", Output: "ok\n", Code: 0},
+		{Command: "make test", Output: "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nFAIL\n", Code: 2},
+	}
+
+	manager.squashCommand([]string{"x"})
+	assert.Len(t, manager.Messages, 8, "invalid count")
+
+	manager.squashCommand([]string{"2"})
+	require.Len(t, manager.Messages, 3)
+	assert.Equal(t, chatSummaryPrefix+"Built the project, tests fail.", manager.Messages[0].Content)
+	assert.Contains(t, prompt, "$ make test (exit code 2)\nline 3\nline 4\nline 5\nline 6\nFAIL\n")
+	assert.NotContains(t, prompt, "line 2", "only the end of each output is sent")
+
+	manager.squashCommand(nil)
+	assert.Len(t, manager.Messages, 3, "fewer messages than keep_messages")
+}
+
+func TestExecHistoryLog(t *testing.T) {
+	assert.Empty(t, execHistoryLog(nil))
+
+	var history []CommandExecHistory
+	for i := 0; i < maxSquashCommands+2; i++ {
+		history = append(history, CommandExecHistory{Command: "cmd" + strconv.Itoa(i), Code: -1})
+	}
+	log := execHistoryLog(history)
+	assert.NotContains(t, log, "$ cmd1\n", "only the last commands are sent")
+	assert.Contains(t, log, "$ cmd2\n", "unknown exit codes are left out")
+	assert.True(t, strings.HasSuffix(log, "$ cmd31\n"))
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// contextStats is the token breakdown of the next request
+type contextStats struct {
+	SystemPrompt int
+	Panes        int // pane XML the next request would capture now
+	KBs          int // KBs sent whole, or the excerpts last sent in search mode
+	Skills       int
+	History      int // chat messages, including the pane snapshots they carry
+	HistoryPanes int // pane snapshots and environment notes within History
+	Messages     int
+	Max          int
+}
+
+func (s contextStats) total() int {
+	return s.SystemPrompt + s.Panes + s.KBs + s.Skills + s.History
+}
+
+// contextBreakdown counts what the next request would carry, part by part
+func (m *Manager) contextBreakdown() contextStats {
+	tokenizer := m.tokenizer()
+	isPrepared := m.ExecPane != nil && m.ExecPane.IsPrepared
+	stats := contextStats{
+		SystemPrompt: tokenizer.Count(m.chatAssistantPrompt(isPrepared).Content),
+		Messages:     len(m.Messages),
+		Max:          m.GetMaxContextSize(),
+	}
+	if m.getTmuxPanesInXml != nil {
+		stats.Panes = tokenizer.Count(m.getTmuxPanesInXml(m.Config))
+	}
+	if m.Config.KnowledgeBase.Search.Enabled {
+		stats.KBs = tokenizer.Count(m.kbExcerptsSent)
+	} else {
+		stats.KBs = m.kbPromptTokens()
+	}
+	for _, content := range m.LoadedSkills {
+		stats.Skills += tokenizer.Count(content)
+	}
+	for _, msg := range m.Messages {
+		tokens := tokenizer.Count(msg.Content)
+		stats.History += tokens
+		// user messages carry the panes and environment ahead of the request
+		if msg.FromUser && msg.Request != "" && msg.Request != msg.Content {
+			stats.HistoryPanes += max(tokens-tokenizer.Count(msg.Request), 0)
+		}
+	}
+	return stats
+}
+
+// showStats prints what the next request is made of and how much of the
+// context is left
+func (m *Manager) showStats() {
+	stats := m.contextBreakdown()
+	tokenizer := m.tokenizer()
+	formatter := system.NewInfoFormatter()
+	approx := ""
+	if !tokenizer.Exact {
+		approx = "~"
+	}
+	percent := func(tokens int) string {
+		if total := stats.total(); total > 0 {
+			return fmt.Sprintf("%5.1f%%", float64(tokens)/float64(total)*100)
+		}
+		return "    -"
+	}
+
+	var b strings.Builder
+	b.WriteString(formatter.FormatSection("\nContext Breakdown"))
+	rows := []struct {
+		label  string
+		tokens int
+		note   string
+	}{
+		{"System prompt", stats.SystemPrompt, ""},
+		{"Panes", stats.Panes, "captured now, up to " + fmt.Sprint(m.GetMaxCaptureLines()) + " lines each"},
+		{"Knowledge bases", stats.KBs, fmt.Sprintf("%d loaded", len(m.LoadedKBs))},
+		{"Skills", stats.Skills, fmt.Sprintf("%d loaded", len(m.LoadedSkills))},
+		{"Chat history", stats.History, fmt.Sprintf("%d messages, %d tokens of them pane snapshots", stats.Messages, stats.HistoryPanes)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%s %9s %s", formatter.LabelColor.Sprintf("%-16s:", row.label), approx+fmt.Sprint(row.tokens), percent(row.tokens))
+		if row.note != "" {
+			b.WriteString("  (" + row.note + ")")
+		}
+		b.WriteString("\n")
+	}
+
+	total := stats.total()
+	usage := 0.0
+	if stats.Max > 0 {
+		usage = float64(total) / float64(stats.Max) * 100
+	}
+	b.WriteString(formatter.FormatKeyValue("Total", fmt.Sprintf("%s%d tokens of %d %s", approx, total, stats.Max, formatter.FormatProgressBar(usage, 10))))
+	b.WriteString(formatter.FormatKeyValue("Remaining", fmt.Sprintf("%s%d tokens", approx, stats.Max-total)))
+	b.WriteString(formatter.FormatKeyValue("Squash At", fmt.Sprintf("%d tokens", m.squashBudget())))
+	b.WriteString(formatter.FormatKeyValue("Model", m.getPromptModelName()))
+	b.WriteString(formatter.FormatKeyValue("Tokenizer", tokenizer.Description()))
+	fmt.Print(b.String())
+}
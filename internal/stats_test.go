@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextBreakdown(t *testing.T) {
+	panes := "<panes><pane id=\"%1\">make: *** [build] Error 2</pane></panes>"
+	manager := &Manager{
+		Config:           config.DefaultConfig(),
+		SessionOverrides: make(map[string]interface{}),
+		ExecPane:         &system.TmuxPaneDetails{},
+		LoadedKBs:        map[string]string{"docker": "Use docker compose up -d to start the stack."},
+		LoadedSkills:     map[string]string{"go": "Run go vet before go test."},
+		Messages: []ChatMessage{
+			{Content: panes + "\n\nwhy does the build fail?", Request: "why does the build fail?", FromUser: true},
+			{Content: "A missing header."},
+		},
+		getTmuxPanesInXml: func(*config.Config) string { return panes },
+	}
+
+	stats := manager.contextBreakdown()
+	tokenizer := manager.tokenizer()
+	assert.Equal(t, tokenizer.Count(manager.chatAssistantPrompt(false).Content), stats.SystemPrompt)
+	assert.Equal(t, tokenizer.Count(panes), stats.Panes)
+	assert.Equal(t, tokenizer.Count("Use docker compose up -d to start the stack."), stats.KBs)
+	assert.Equal(t, tokenizer.Count("Run go vet before go test."), stats.Skills)
+	assert.Equal(t, 2, stats.Messages)
+	assert.Equal(t, tokenizer.Count(manager.Messages[0].Content)+tokenizer.Count("A missing header."), stats.History)
+	assert.Positive(t, stats.HistoryPanes)
+	assert.Less(t, stats.HistoryPanes, tokenizer.Count(manager.Messages[0].Content))
+	assert.Equal(t, stats.SystemPrompt+stats.Panes+stats.KBs+stats.Skills+stats.History, stats.total())
+	assert.Equal(t, manager.Config.MaxContextSize, stats.Max)
+}
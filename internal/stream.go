@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamHandler receives response text deltas as they arrive from the provider
+type StreamHandler func(delta string)
+
+type streamHandlerKey struct{}
+
+func ctxWithStreamHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, handler)
+}
+
+func streamHandlerFromCtx(ctx context.Context) StreamHandler {
+	if v, ok := ctx.Value(streamHandlerKey{}).(StreamHandler); ok {
+		return v
+	}
+	return nil
+}
+
+// chatCompletionStreamChunk represents one SSE event of a streamed chat completion
+type chatCompletionStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *ChatCompletionUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// readChatCompletionStream reads an OpenAI-compatible SSE stream, forwarding
+// each content delta to onDelta, and returns the full response text along
+// with the usage reported in the final chunk, if any.
+func readChatCompletionStream(r io.Reader, onDelta StreamHandler) (string, TokenUsage, error) {
+	var sb strings.Builder
+	var usage TokenUsage
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Blank lines separate events, lines starting with ':' are keep-alive comments
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", usage, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", usage, fmt.Errorf("API returned error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = TokenUsage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			sb.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", usage, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return sb.String(), usage, nil
+}
+
+// streamPrinter renders the conversational part of a streamed response and
+// goes quiet once an action tag starts; the tags themselves are parsed and
+// acted on after the stream completes. Text that might be the beginning of a
+// tag is held back until it can be told apart from a plain '<'.
+type streamPrinter struct {
+	out     io.Writer
+	onFirst func()
+
+	pending string
+	started bool
+	stopped bool
+}
+
+func newStreamPrinter(out io.Writer, onFirst func()) *streamPrinter {
+	return &streamPrinter{out: out, onFirst: onFirst}
+}
+
+// Write implements StreamHandler
+func (p *streamPrinter) Write(delta string) {
+	if p.stopped {
+		return
+	}
+	p.pending += delta
+
+	for {
+		idx := strings.IndexByte(p.pending, '<')
+		if idx < 0 {
+			p.emit(p.pending)
+			p.pending = ""
+			return
+		}
+
+		p.emit(p.pending[:idx])
+		p.pending = p.pending[idx:]
+
+		switch streamTagState(p.pending) {
+		case streamTagMatched:
+			p.stopped = true
+			p.pending = ""
+			return
+		case streamTagPartial:
+			return
+		}
+
+		p.emit("<")
+		p.pending = p.pending[1:]
+	}
+}
+
+// Finish flushes held-back text and terminates the streamed line. It reports
+// whether anything was printed.
+func (p *streamPrinter) Finish() bool {
+	if !p.stopped {
+		p.emit(p.pending)
+		p.pending = ""
+	}
+	if p.started {
+		_, _ = fmt.Fprintln(p.out)
+	}
+	return p.started
+}
+
+func (p *streamPrinter) emit(text string) {
+	if text == "" {
+		return
+	}
+	if !p.started {
+		// Leading whitespace would leave a gap under the spinner line
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		p.started = true
+		if p.onFirst != nil {
+			p.onFirst()
+		}
+	}
+	_, _ = io.WriteString(p.out, text)
+}
+
+// streamStopTags are the tags that end the printable part of a response
+var streamStopTags = append(append([]string{}, tagNames...), "MCPToolCall")
+
+const (
+	streamTagNone = iota
+	streamTagPartial
+	streamTagMatched
+)
+
+// streamTagState classifies text starting with '<' as an action tag opening,
+// a possible prefix of one, or ordinary text.
+func streamTagState(text string) int {
+	for _, name := range streamStopTags {
+		opening := "<" + name
+		if strings.HasPrefix(text, opening) {
+			return streamTagMatched
+		}
+		if strings.HasPrefix(opening, text) {
+			return streamTagPartial
+		}
+	}
+	return streamTagNone
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadChatCompletionStream(t *testing.T) {
+	body := ": keep-alive\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	var deltas []string
+	got, usage, err := readChatCompletionStream(strings.NewReader(body), func(d string) { deltas = append(deltas, d) })
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", got)
+	assert.Equal(t, TokenUsage{}, usage)
+	assert.Equal(t, []string{"Hel", "lo"}, deltas)
+}
+
+func TestChatCompletionStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Errorf("expected stream to be requested")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok \"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"streamed\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "fast",
+		Models: map[string]config.ModelConfig{
+			"fast": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL, Stream: true},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	var seen strings.Builder
+	ctx := ctxWithStreamHandler(context.Background(), func(d string) { seen.WriteString(d) })
+	resp, err := client.ChatCompletion(ctx, []Message{{Role: "user", Content: "hi"}}, "m")
+	require.NoError(t, err)
+	assert.Equal(t, "ok streamed", resp)
+	assert.Equal(t, "ok streamed", seen.String())
+}
+
+func TestStreamPrinterStopsAtActionTag(t *testing.T) {
+	var out bytes.Buffer
+	firstCalls := 0
+	p := newStreamPrinter(&out, func() { firstCalls++ })
+
+	for _, chunk := range []string{"\nListing files", " where a < b", " now.\n<Ex", "ecCommand>ls -la</ExecCommand>"} {
+		p.Write(chunk)
+	}
+	assert.True(t, p.Finish())
+	assert.Equal(t, "Listing files where a < b now.\n\n", out.String())
+	assert.Equal(t, 1, firstCalls)
+}
+
+func TestStreamPrinterFlushesHeldText(t *testing.T) {
+	var out bytes.Buffer
+	p := newStreamPrinter(&out, nil)
+	p.Write("compare x <Re")
+	assert.Equal(t, "compare x ", out.String())
+	p.Finish()
+	assert.Equal(t, "compare x <Re\n", out.String())
+}
+
+func TestStreamPrinterNothingPrinted(t *testing.T) {
+	var out bytes.Buffer
+	p := newStreamPrinter(&out, nil)
+	p.Write("<RequestAccomplished>1</RequestAccomplished>")
+	assert.False(t, p.Finish())
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/internal/mcp"
+)
+
+const structuredOutputSchemaName = "tmuxai_response"
+
+// ChatResponseFormat represents the response_format field of the chat completion API
+type ChatResponseFormat struct {
+	Type       string              `json:"type"`
+	JSONSchema *ChatJSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// ChatJSONSchemaSpec names and describes a JSON schema for structured outputs
+type ChatJSONSchemaSpec struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// aiResponseSchema is the strict JSON schema generated from AIResponse's json tags
+var aiResponseSchema = schemaForStruct(reflect.TypeOf(AIResponse{}))
+
+// schemaForStruct builds a strict-mode JSON schema for a flat struct of
+// strings, bools and string slices. Strict mode requires every property to
+// be listed as required and forbids additional properties.
+func schemaForStruct(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		var prop map[string]interface{}
+		if field.Type.Kind() == reflect.Slice {
+			if items := schemaType(field.Type.Elem()); items != "" {
+				prop = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": items}}
+			}
+		} else if typ := schemaType(field.Type); typ != "" {
+			prop = map[string]interface{}{"type": typ}
+		}
+		if prop == nil {
+			continue
+		}
+		properties[name] = prop
+		required = append(required, name)
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// schemaType returns the JSON schema type of a scalar Go type, or "" if it
+// has none
+func schemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int:
+		return "integer"
+	}
+	return ""
+}
+
+func chatCompletionResponseFormat() *ChatResponseFormat {
+	return &ChatResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &ChatJSONSchemaSpec{
+			Name:   structuredOutputSchemaName,
+			Strict: true,
+			Schema: aiResponseSchema,
+		},
+	}
+}
+
+// responsesTextFormat returns the Responses API text.format setting for structured outputs
+func responsesTextFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"format": map[string]interface{}{
+			"type":   "json_schema",
+			"name":   structuredOutputSchemaName,
+			"strict": true,
+			"schema": aiResponseSchema,
+		},
+	}
+}
+
+var jsonFenceRe = regexp.MustCompile("(?s)^```(?:json)?\\s*(.*?)\\s*```$")
+
+// parseStructuredResponse decodes a JSON response matching AIResponse. It
+// reports false when the response isn't a single JSON object with only known
+// fields, in which case the caller falls back to XML tag parsing.
+func parseStructuredResponse(response string) (AIResponse, bool) {
+	text := strings.TrimSpace(response)
+	if m := jsonFenceRe.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	if !strings.HasPrefix(text, "{") {
+		return AIResponse{}, false
+	}
+
+	var r AIResponse
+	dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil || dec.More() {
+		return AIResponse{}, false
+	}
+
+	// MCP tool calls aren't part of the schema; models embed them in the message
+	calls, cleaned := mcp.ParseMCPToolCalls(r.Message)
+	r.MCPToolCalls = calls
+	r.Message = strings.TrimSpace(cleaned)
+
+	return r, true
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAIResponseSchema(t *testing.T) {
+	props := aiResponseSchema["properties"].(map[string]interface{})
+	assert.Len(t, props, 17)
+	assert.Equal(t, map[string]interface{}{"type": "boolean"}, props["request_accomplished"])
+	assert.Equal(t, "array", props["exec_command"].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, props["exec_command_timeouts"].(map[string]interface{})["items"])
+	assert.Equal(t, map[string]interface{}{"type": "boolean"}, props["exec_command_background"].(map[string]interface{})["items"])
+	assert.NotContains(t, props, "MCPToolCalls")
+	assert.Len(t, aiResponseSchema["required"], 17)
+	assert.Equal(t, false, aiResponseSchema["additionalProperties"])
+}
+
+func TestParseAIResponseStructured(t *testing.T) {
+	m := &Manager{}
+
+	r, err := m.parseAIResponse("```json\n{\"message\":\"Listing files.\",\"send_keys\":[],\"exec_command\":[\"ls\"],\"paste_multiline_content\":\"\",\"request_accomplished\":false,\"exec_pane_seems_busy\":false,\"waiting_for_user_response\":false,\"no_comment\":false}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "Listing files.", r.Message)
+	assert.Equal(t, []string{"ls"}, r.ExecCommand)
+
+	r, err = m.parseAIResponse(`{"message":"Checking <MCPToolCall>{\"name\":\"mcp__fs__read\",\"arguments\":{}}</MCPToolCall>"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Checking", r.Message)
+	assert.Len(t, r.MCPToolCalls, 1)
+}
+
+func TestParseAIResponseStructuredFallsBackToXML(t *testing.T) {
+	m := &Manager{}
+
+	r, err := m.parseAIResponse(`{"unexpected": true}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"unexpected": true}`, r.Message)
+
+	r, err = m.parseAIResponse("Done.\n<RequestAccomplished>1</RequestAccomplished>")
+	require.NoError(t, err)
+	assert.True(t, r.RequestAccomplished)
+	assert.Equal(t, "Done.", r.Message)
+}
+
+func TestChatCompletionStructuredOutputRequest(t *testing.T) {
+	var got ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"message\":\"hi\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "json",
+		Models: map[string]config.ModelConfig{
+			"json": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL, StructuredOutput: true},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	_, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m")
+	require.NoError(t, err)
+	require.NotNil(t, got.ResponseFormat)
+	assert.Equal(t, "json_schema", got.ResponseFormat.Type)
+	assert.True(t, got.ResponseFormat.JSONSchema.Strict)
+
+	assert.True(t, manager.structuredOutputEnabled())
+	assert.Contains(t, manager.chatAssistantPrompt(false).Content, "Respond with a single JSON object")
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// tmuxaiExecutable is the binary subagents run, the running one by default
+var tmuxaiExecutable = os.Executable
+
+// runningAgents counts the subagents that haven't finished
+func (m *Manager) runningAgents() int {
+	n := 0
+	for _, job := range m.backgroundJobs() {
+		if job.isAgent() && job.running() {
+			n++
+		}
+	}
+	return n
+}
+
+// subagentsPrompt describes the SpawnAgent tag, or returns "" when
+// subagents are turned off
+func (m *Manager) subagentsPrompt() string {
+	limit := m.GetMaxSubagents()
+	if limit <= 0 || system.TmuxIsRemote() {
+		return ""
+	}
+	return fmt.Sprintf("\n<SpawnAgent>: Use this to hand a self-contained task, like running the test suite or investigating a log, to a secondary agent with an exec pane of its own, while you continue: <SpawnAgent>run the test suite and report failures</SpawnAgent>. Describe the task fully, the agent doesn't see this conversation. Up to %d agents run at once; their status and results are listed under background jobs.", limit)
+}
+
+// startSubagent hands task to a second TmuxAI working in an exec pane of
+// its own. It runs `tmuxai -m` in another new pane, where its confirmation
+// prompts can be answered, and is polled like a background job; its result
+// is read when it exits.
+func (m *Manager) startSubagent(task string) (*backgroundJob, error) {
+	limit := m.GetMaxSubagents()
+	if limit <= 0 {
+		return nil, errors.New("subagents are turned off, max_subagents is 0")
+	}
+	if running := m.runningAgents(); running >= limit {
+		return nil, fmt.Errorf("%d agents are already running, the max_subagents limit", running)
+	}
+	if system.TmuxIsRemote() {
+		return nil, errors.New("subagents need a local tmux server")
+	}
+	exe, err := tmuxaiExecutable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the tmuxai binary: %w", err)
+	}
+
+	result, err := os.CreateTemp("", "tmuxai-agent-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the agent result file: %w", err)
+	}
+	_ = result.Close()
+
+	splitArgs := []string{"-d"}
+	if m.ExecPane.CurrentPath != "" {
+		splitArgs = append(splitArgs, "-c", m.ExecPane.CurrentPath)
+	}
+	execPaneID, err := system.TmuxCreateNewPane(m.ExecPane.Id, splitArgs)
+	if err != nil {
+		_ = os.Remove(result.Name())
+		return nil, fmt.Errorf("failed to create the agent's exec pane: %w", err)
+	}
+	paneID, err := system.TmuxCreateNewPane(execPaneID, append([]string{"-h"}, splitArgs...))
+	if err != nil {
+		_ = system.TmuxKillPane(execPaneID)
+		_ = os.Remove(result.Name())
+		return nil, fmt.Errorf("failed to create the agent pane: %w", err)
+	}
+
+	command := m.subagentCommand(exe, execPaneID, task, result.Name())
+	if err := system.TmuxSendCommandToPane(paneID, command, true); err != nil {
+		_ = system.TmuxKillPane(paneID)
+		_ = system.TmuxKillPane(execPaneID)
+		_ = os.Remove(result.Name())
+		return nil, fmt.Errorf("failed to start the agent in pane %s: %w", paneID, err)
+	}
+
+	job := &backgroundJob{
+		Command:    command,
+		PaneID:     paneID,
+		Started:    time.Now(),
+		Task:       task,
+		ExecPaneID: execPaneID,
+		resultPath: result.Name(),
+	}
+	m.addJob(job)
+	logger.Info("Started agent %d in pane %s with exec pane %s: %s", job.ID, paneID, execPaneID, task)
+	return job, nil
+}
+
+// subagentCommand is the tmuxai invocation of a subagent, with the current
+// model, config file and yolo and dry-run settings. Its chat output goes to
+// stderr, so it stays visible in its pane, and its JSON result to resultPath.
+func (m *Manager) subagentCommand(exe, execPaneID, task, resultPath string) string {
+	args := []string{exe, "--exec-pane", execPaneID, "--read-panes", execPaneID}
+	if name := m.GetModelsDefault(); name != "" {
+		if _, ok := m.Config.Models[name]; ok {
+			args = append(args, "--model", name)
+		}
+	}
+	if path := config.ConfigFileUsed(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			args = append(args, "--config", path)
+		}
+	}
+	if m.GetYolo() {
+		args = append(args, "--yolo")
+	}
+	if m.GetDryRun() {
+		args = append(args, "--dry-run")
+	}
+	args = append(args, "--json", "-m", task)
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = system.ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ") + " > " + system.ShellQuote(resultPath)
+}
+
+// readAgentResult reads and removes the result a subagent wrote, or returns
+// nil when it exited without one
+func readAgentResult(path string) *OnceResult {
+	defer func() { _ = os.Remove(path) }()
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var result OnceResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		logger.Error("Failed to read agent result %s: %v", path, err)
+		return nil
+	}
+	return &result
+}
+
+// agentOutcome sums up how a finished subagent did
+func (j *backgroundJob) agentOutcome() string {
+	switch {
+	case j.Result == nil:
+		return "exited without a result"
+	case j.Result.Accomplished:
+		return "accomplished"
+	}
+	return "not accomplished"
+}
+
+// agentPrompt describes a subagent, with its result once it finished, for
+// the model
+func (j *backgroundJob) agentPrompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<agent id=\"%d\" pane=\"%s\" exec_pane=\"%s\" status=\"%s\">\nTask: %s\n", j.ID, j.PaneID, j.ExecPaneID, j.status(), sanitizeXML(j.Task))
+	if !j.running() {
+		fmt.Fprintf(&b, "Outcome: %s\n", j.agentOutcome())
+	}
+	if j.Result != nil {
+		if j.Result.Message != "" {
+			fmt.Fprintf(&b, "Last message: %s\n", sanitizeXML(j.Result.Message))
+		}
+		for _, c := range j.Result.ExecutedCommands {
+			line := "$ " + c.Command + " (" + c.Decision
+			if c.ExitCode != nil {
+				line += fmt.Sprintf(", exit code %d", *c.ExitCode)
+			}
+			b.WriteString(sanitizeXML(line) + ")\n")
+		}
+	}
+	b.WriteString("</agent>\n")
+	return b.String()
+}
+
+// spawnFailuresObservation tells the model which agents couldn't be started
+func spawnFailuresObservation(failures []string) string {
+	return "Agents not started:\n" + strings.Join(failures, "\n")
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockAgentPanes(t *testing.T) (*Manager, map[string]string, map[string]string) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DefaultModel = "fast"
+	cfg.Models = map[string]config.ModelConfig{"fast": {Provider: "openrouter", Model: "google/gemini-2.5-flash"}}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: map[string]interface{}{},
+		ExecPane:         &system.TmuxPaneDetails{Id: "%2", CurrentPath: "/src/app"},
+		// keeps pollJobs from starting, the tests poll themselves
+		jobsPolling: true,
+	}
+
+	originalCreate, originalSend := system.TmuxCreateNewPane, system.TmuxSendCommandToPane
+	originalDetails, originalCapture, originalKill := system.TmuxPanesDetails, system.TmuxCapturePane, system.TmuxKillPane
+	originalExe := tmuxaiExecutable
+	t.Cleanup(func() {
+		system.TmuxCreateNewPane, system.TmuxSendCommandToPane = originalCreate, originalSend
+		system.TmuxPanesDetails, system.TmuxCapturePane, system.TmuxKillPane = originalDetails, originalCapture, originalKill
+		tmuxaiExecutable = originalExe
+	})
+	tmuxaiExecutable = func() (string, error) { return "/usr/local/bin/tmuxai", nil }
+
+	// pane ID -> its foreground command, and the command typed into it
+	panes := map[string]string{}
+	typed := map[string]string{}
+	system.TmuxCreateNewPane = func(target string, splitArgs []string) (string, error) {
+		id := "%" + string(rune('5'+len(panes)))
+		panes[id] = "zsh"
+		return id, nil
+	}
+	system.TmuxSendCommandToPane = func(paneId string, command string, autoenter bool) error {
+		typed[paneId] = command
+		panes[paneId] = "tmuxai"
+		return nil
+	}
+	system.TmuxPanesDetails = func(target string) ([]system.TmuxPaneDetails, error) {
+		command, ok := panes[target]
+		if !ok {
+			return nil, assert.AnError
+		}
+		return []system.TmuxPaneDetails{{Id: target, CurrentCommand: command}}, nil
+	}
+	system.TmuxCapturePane = func(paneId string, maxLines int) (string, error) { return "", nil }
+	system.TmuxKillPane = func(paneId string) error {
+		delete(panes, paneId)
+		return nil
+	}
+	return manager, panes, typed
+}
+
+func TestStartSubagent(t *testing.T) {
+	manager, panes, typed := mockAgentPanes(t)
+	manager.Config.MaxSubagents = 1
+
+	job, err := manager.startSubagent("run the test suite")
+	require.NoError(t, err)
+	assert.Equal(t, "%5", job.ExecPaneID)
+	assert.Equal(t, "%6", job.PaneID)
+	assert.True(t, strings.HasPrefix(typed["%6"], "'/usr/local/bin/tmuxai' '--exec-pane' '%5' '--read-panes' '%5' '--model' 'fast' "), typed["%6"])
+	assert.Contains(t, typed["%6"], "'--json' '-m' 'run the test suite' > '"+job.resultPath+"'")
+
+	_, err = manager.startSubagent("lint")
+	assert.ErrorContains(t, err, "max_subagents")
+
+	// the agent writes its result and exits back to the shell
+	require.NoError(t, os.WriteFile(job.resultPath, []byte(`{"message":"3 tests failed","request_accomplished":true,"executed_commands":[{"command":"go test ./...","pane_id":"%5","decision":"approved","exit_code":1}]}`), 0o644))
+	panes["%6"] = "zsh"
+	assert.False(t, manager.pollJobsOnce())
+
+	agents := manager.backgroundJobs()
+	require.Len(t, agents, 1)
+	require.NotNil(t, agents[0].Result)
+	assert.Equal(t, "3 tests failed", agents[0].Result.Message)
+	_, err = os.Stat(job.resultPath)
+	assert.True(t, os.IsNotExist(err), "the result file is removed once read")
+
+	prompt := manager.backgroundJobsPrompt()
+	assert.Contains(t, prompt, "<agent id=\"1\" pane=\"%6\" exec_pane=\"%5\"")
+	assert.Contains(t, prompt, "Outcome: accomplished\nLast message: 3 tests failed\n$ go test ./... (approved, exit code 1)\n</agent>")
+
+	require.NoError(t, manager.stopBackgroundJob(1))
+	assert.Empty(t, panes, "both panes of the agent are closed")
+}
+
+func TestFollowUpMessageKeepsStartedAgents(t *testing.T) {
+	manager, _, _ := mockAgentPanes(t)
+	manager.Config.MaxSubagents = 1
+
+	job, err := manager.startSubagent("run the test suite")
+	require.NoError(t, err)
+	_, err = manager.startSubagent("lint")
+	require.Error(t, err)
+
+	followUp := followUpMessage([]string{spawnFailuresObservation([]string{"lint: " + err.Error()}), jobsObservation([]*backgroundJob{job})})
+	assert.Contains(t, followUp, "Agents not started:\nlint: ")
+	assert.Contains(t, followUp, "Started agent 1 in pane %6, with exec pane %5: run the test suite")
+}
+
+func TestSubagentsTurnedOff(t *testing.T) {
+	manager, _, _ := mockAgentPanes(t)
+	manager.SessionOverrides["max_subagents"] = 0
+
+	_, err := manager.startSubagent("run the test suite")
+	assert.ErrorContains(t, err, "turned off")
+	assert.Empty(t, manager.subagentsPrompt())
+}
+
+func TestParseSpawnAgent(t *testing.T) {
+	m := &Manager{}
+	r, err := m.parseAIResponse("I'll let an agent run the tests.\n<SpawnAgent>run go test ./... and summarize failures</SpawnAgent>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"run go test ./... and summarize failures"}, r.SpawnAgent)
+	assert.Equal(t, "I'll let an agent run the tests.", r.Message)
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// systemTemplateFile is where a custom system prompt template is read from,
+// relative to the config directory
+const systemTemplateFile = "prompts/system.tmpl"
+
+// systemPromptData is what prompts/system.tmpl is rendered with. Panes and
+// KBs are methods, so the panes are only captured when the template uses them.
+type systemPromptData struct {
+	OS      string // the exec pane's OS
+	Shell   string // the exec pane's shell
+	Model   string // the current model
+	Default string // the built-in system prompt, or prompts.base_system
+	m       *Manager
+}
+
+// Panes returns the current tmux window state, as sent with each request
+func (d systemPromptData) Panes() string {
+	return d.m.getTmuxPanesInXml(d.m.Config)
+}
+
+// KBs returns the names of the loaded knowledge bases, sorted
+func (d systemPromptData) KBs() []string {
+	return sortedKeys(d.m.LoadedKBs)
+}
+
+// systemTemplatePath returns the path of prompts/system.tmpl
+func systemTemplatePath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, systemTemplateFile), nil
+}
+
+// loadSystemTemplate parses prompts/system.tmpl into m.systemTemplate and
+// renders it once to catch errors early. Without the file the built-in
+// system prompt is used; returns whether a template is in use.
+func (m *Manager) loadSystemTemplate() (bool, error) {
+	m.systemTemplate = nil
+	path, err := systemTemplatePath()
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	tmpl, err := template.New("system").Funcs(template.FuncMap{"join": strings.Join}).Parse(string(data))
+	if err != nil {
+		return false, err
+	}
+	if _, err := m.renderSystemTemplate(tmpl); err != nil {
+		return false, err
+	}
+	m.systemTemplate = tmpl
+	return true, nil
+}
+
+// renderSystemTemplate executes tmpl with the current session's data
+func (m *Manager) renderSystemTemplate(tmpl *template.Template) (string, error) {
+	data := systemPromptData{OS: m.OS, Model: m.GetModel(), Default: m.defaultSystemPrompt(), m: m}
+	if m.ExecPane != nil {
+		data.Shell = m.ExecPane.Shell
+		data.OS = m.paneOS(*m.ExecPane)
+	}
+	if data.OS == "" {
+		data.OS = runtime.GOOS
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// templatedSystemPrompt renders the system prompt template, falling back to
+// the built-in prompt if it fails
+func (m *Manager) templatedSystemPrompt() string {
+	prompt, err := m.renderSystemTemplate(m.systemTemplate)
+	if err != nil {
+		logger.Error("Failed to render %s, using the built-in system prompt: %v", systemTemplateFile, err)
+		return m.defaultSystemPrompt()
+	}
+	return prompt
+}
+
+// reloadSystemTemplate handles /prompt reload
+func (m *Manager) reloadSystemTemplate() {
+	path, _ := systemTemplatePath()
+	loaded, err := m.loadSystemTemplate()
+	switch {
+	case err != nil:
+		m.Println(fmt.Sprintf("Failed to load %s, using the built-in system prompt: %v", path, err))
+	case !loaded:
+		m.Println(fmt.Sprintf("No %s, using the built-in system prompt", path))
+	default:
+		m.Println(fmt.Sprintf("✓ Reloaded %s", path))
+	}
+}
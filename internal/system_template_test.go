@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, err := systemTemplatePath()
+	require.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	cfg.Prompts.BaseSystem = "Built-in prompt."
+	panesCaptured := 0
+	manager := &Manager{
+		Config:    cfg,
+		OS:        "linux",
+		ExecPane:  &system.TmuxPaneDetails{Shell: "fish", OS: "linux"},
+		LoadedKBs: map[string]string{"k8s": "...", "docker": "..."},
+		getTmuxPanesInXml: func(*config.Config) string {
+			panesCaptured++
+			return "<panes/>"
+		},
+	}
+
+	loaded, err := manager.loadSystemTemplate()
+	require.NoError(t, err)
+	assert.False(t, loaded, "no template file")
+	assert.Equal(t, "Built-in prompt.", manager.baseSystemPrompt())
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("{{.Default}} Shell: {{.Shell}} on {{.OS}}. KBs: {{join .KBs \", \"}}."), 0o644))
+	loaded, err = manager.loadSystemTemplate()
+	require.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Equal(t, "Built-in prompt. Shell: fish on linux. KBs: docker, k8s.", manager.baseSystemPrompt())
+	assert.Zero(t, panesCaptured, "panes are only captured when the template uses them")
+
+	require.NoError(t, os.WriteFile(path, []byte("{{.Panes}}"), 0o644))
+	manager.reloadSystemTemplate()
+	assert.Equal(t, "<panes/>", manager.baseSystemPrompt())
+
+	require.NoError(t, os.WriteFile(path, []byte("{{.Missing}}"), 0o644))
+	_, err = manager.loadSystemTemplate()
+	assert.Error(t, err, "rendered once when loaded")
+	assert.Nil(t, manager.systemTemplate)
+	assert.Equal(t, "Built-in prompt.", manager.baseSystemPrompt())
+
+	require.NoError(t, os.WriteFile(path, []byte("{{if}}"), 0o644))
+	_, err = manager.loadSystemTemplate()
+	assert.Error(t, err)
+}
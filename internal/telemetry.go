@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer and meter TmuxAI reports with
+const instrumentationName = "github.com/alvinunreal/tmuxai"
+
+// telemetryShutdownTimeout bounds the final export when TmuxAI exits
+const telemetryShutdownTimeout = 5 * time.Second
+
+// tracer and meter are no-ops until startTelemetry installs exporting
+// providers; the otel globals forward to them once it does
+var (
+	tracer = otel.Tracer(instrumentationName)
+	meter  = otel.Meter(instrumentationName)
+
+	aiRequestDuration, _ = meter.Float64Histogram("tmuxai.ai.request.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of AI requests, including retries"))
+	aiTokens, _ = meter.Int64Counter("tmuxai.ai.tokens",
+		metric.WithUnit("{token}"), metric.WithDescription("Tokens reported by the provider, by type"))
+	aiRetries, _ = meter.Int64Counter("tmuxai.ai.retries",
+		metric.WithDescription("AI requests retried after rate limits, server or network errors"))
+	execDuration, _ = meter.Float64Histogram("tmuxai.exec.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of commands run in the exec pane"))
+)
+
+// startTelemetry installs OTLP/HTTP trace and metric exporters when
+// telemetry.enabled is set. The returned function flushes and stops them.
+func startTelemetry(cfg config.TelemetryConfig) func() {
+	if !cfg.Enabled {
+		return func() {}
+	}
+
+	traceOpts := []otlptracehttp.Option{}
+	metricOpts := []otlpmetrichttp.Option{}
+	if cfg.Endpoint != "" {
+		base := strings.TrimSuffix(cfg.Endpoint, "/")
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(base+"/v1/traces"))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(base+"/v1/metrics"))
+	}
+	if len(cfg.Headers) > 0 {
+		traceOpts = append(traceOpts, otlptracehttp.WithHeaders(cfg.Headers))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+
+	ctx := context.Background()
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		logger.Error("Telemetry disabled: failed to create trace exporter: %v", err)
+		return func() {}
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		logger.Error("Telemetry disabled: failed to create metric exporter: %v", err)
+		_ = traceExporter.Shutdown(ctx)
+		return func() {}
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "tmuxai"),
+		attribute.String("service.version", Version),
+	)
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)), sdkmetric.WithResource(res))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	// export failures belong in the log, not on the chat's terminal
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Error("Telemetry: %v", err)
+	}))
+	logger.Info("Telemetry enabled, exporting over OTLP/HTTP")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("Failed to flush traces: %v", err)
+		}
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			logger.Error("Failed to flush metrics: %v", err)
+		}
+	}
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordTokens adds provider-reported token usage to the token counter and
+// the current AI request span
+func recordTokens(ctx context.Context, usage TokenUsage) {
+	aiTokens.Add(ctx, int64(usage.PromptTokens), metric.WithAttributes(attribute.String("type", "input")))
+	aiTokens.Add(ctx, int64(usage.CompletionTokens), metric.WithAttributes(attribute.String("type", "output")))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+	)
+}
+
+// recordRetry counts a retried AI request and marks it on the request span
+func recordRetry(ctx context.Context, attempt int, delay time.Duration, reason string) {
+	aiRetries.Add(ctx, 1)
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attribute.Int("attempt", attempt),
+		attribute.String("delay", delay.String()),
+		attribute.String("reason", reason),
+	))
+}
+
+// tracedExecWaitCapture runs ExecWaitCaptureTimeout in an exec span and
+// records its duration
+func (m *Manager) tracedExecWaitCapture(ctx context.Context, command string, timeout time.Duration) (CommandExecHistory, error) {
+	_, span := tracer.Start(ctx, "tmuxai.exec", trace.WithAttributes(
+		attribute.String("tmuxai.pane", m.ExecPane.Id),
+		attribute.String("tmuxai.command", command),
+	))
+	start := time.Now()
+	history, err := m.ExecWaitCaptureTimeout(command, timeout)
+
+	span.SetAttributes(
+		attribute.Int("tmuxai.exit_code", history.Code),
+		attribute.Bool("tmuxai.timed_out", history.TimedOut),
+	)
+	spanErr := err
+	if spanErr == nil && history.AbortReason != "" {
+		spanErr = fmt.Errorf("aborted: %s", history.AbortReason)
+	}
+	endSpan(span, spanErr)
+	execDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.Bool("tmuxai.timed_out", history.TimedOut)))
+	return history, err
+}
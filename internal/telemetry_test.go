@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	telemetryOnce   sync.Once
+	testSpans       *tracetest.SpanRecorder
+	testMetricsRead *sdkmetric.ManualReader
+)
+
+// recordTelemetry installs in-memory trace and metric providers. The otel
+// globals only forward to the first providers set, so they are shared.
+func recordTelemetry() (*tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	telemetryOnce.Do(func() {
+		testSpans = tracetest.NewSpanRecorder()
+		testMetricsRead = sdkmetric.NewManualReader()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(testSpans)))
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(testMetricsRead)))
+	})
+	return testSpans, testMetricsRead
+}
+
+// sumMetric returns the total of an int64 counter
+func sumMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) int64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	var total int64
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, point := range sum.DataPoints {
+					total += point.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestAIRequestSpanRecordsRetriesAndTokens(t *testing.T) {
+	spans, reader := recordTelemetry()
+	stubRetrySleep(t)
+	retriesBefore := sumMetric(t, reader, "tmuxai.ai.retries")
+	tokensBefore := sumMetric(t, reader, "tmuxai.ai.tokens")
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":120,"completion_tokens":8}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Retry.MaxAttempts = 2
+	cfg.DefaultModel = "m"
+	cfg.Models = map[string]config.ModelConfig{"m": {Provider: "openrouter", Model: "some/model", APIKey: "k", BaseURL: server.URL}}
+	m := newStandaloneManager(cfg)
+
+	messages := []ChatMessage{{Content: "hi", FromUser: true, Timestamp: time.Now()}}
+	_, err := m.AiClient.GetResponseFromChatMessages(context.Background(), messages, m.GetModel())
+	require.NoError(t, err)
+
+	var request sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		if span.Name() == "tmuxai.ai_request" {
+			request = span
+		}
+	}
+	require.NotNil(t, request)
+	assert.Contains(t, request.Attributes(), attribute.String("gen_ai.request.model", "some/model"))
+	assert.Contains(t, request.Attributes(), attribute.Int("gen_ai.usage.input_tokens", 120))
+	require.Len(t, request.Events(), 1)
+	assert.Equal(t, "retry", request.Events()[0].Name)
+
+	assert.Equal(t, int64(1), sumMetric(t, reader, "tmuxai.ai.retries")-retriesBefore)
+	assert.Equal(t, int64(128), sumMetric(t, reader, "tmuxai.ai.tokens")-tokensBefore)
+}
+
+func TestStartTelemetryDisabled(t *testing.T) {
+	stop := startTelemetry(config.TelemetryConfig{})
+	assert.NotNil(t, stop)
+	stop()
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// agentTool describes a response action exposed as a native function tool.
+// Each tool mirrors one XML tag; apply maps a call's arguments straight into
+// the AIResponse fields the tag would set.
+type agentTool struct {
+	Name        string
+	Description string
+	Param       string     // string argument name, empty for boolean flag tools
+	Attrs       []toolAttr // optional arguments
+	apply       func(r *AIResponse, value string, args map[string]interface{})
+}
+
+// toolAttr is an optional tool argument and its JSON schema type
+type toolAttr struct {
+	Name string
+	Type string
+}
+
+var agentTools = []agentTool{
+	{"exec_command", "Execute a shell command in the tmux exec pane, or in the named exec pane given as pane. timeout is how many seconds the command may run before it is reported as timed out. background starts a long-running command as a background job in a pane of its own.", "command", []toolAttr{{"pane", "string"}, {"timeout", "integer"}, {"background", "boolean"}}, func(r *AIResponse, v string, args map[string]interface{}) {
+		pane, _ := args["pane"].(string)
+		timeout, _ := args["timeout"].(float64)
+		background, _ := args["background"].(bool)
+		n := len(r.ExecCommand)
+		r.ExecCommand = append(r.ExecCommand, v)
+		r.ExecCommandPanes = appendAligned(r.ExecCommandPanes, n, strings.ToLower(strings.TrimSpace(pane)))
+		r.ExecCommandTimeouts = appendAligned(r.ExecCommandTimeouts, n, max(int(timeout), 0))
+		r.ExecCommandBackground = appendAligned(r.ExecCommandBackground, n, background)
+	}},
+	{"send_keys", "Send keystrokes to the tmux exec pane. Supports tmux key names (Enter, Escape, C-c, M-a, Up, F1, ...). Call once per key sequence.", "keys", nil, func(r *AIResponse, v string, _ map[string]interface{}) { r.SendKeys = append(r.SendKeys, v) }},
+	{"paste_multiline_content", "Paste multiline text into the exec pane, e.g. into an editor. Never use this to run shell commands.", "content", nil, func(r *AIResponse, v string, _ map[string]interface{}) { r.PasteMultilineContent = v }},
+	{"write_file", "Create or overwrite the file at path with content, instead of pasting a heredoc into the exec pane. The user sees the diff and may decline it.", "content", []toolAttr{{"path", "string"}}, func(r *AIResponse, v string, args map[string]interface{}) {
+		path, _ := args["path"].(string)
+		r.WriteFile = append(r.WriteFile, v)
+		r.WriteFilePaths = append(r.WriteFilePaths, strings.TrimSpace(path))
+	}},
+	{"apply_patch", "Change existing files with a unified diff (--- a/path, +++ b/path, @@ hunks with context). The user sees the diff and may decline it.", "patch", nil, func(r *AIResponse, v string, _ map[string]interface{}) { r.ApplyPatch = append(r.ApplyPatch, v) }},
+	{"spawn_agent", "Hand a self-contained task, like running the test suite or investigating a log, to a secondary agent with an exec pane of its own while you continue. Describe the task fully, the agent doesn't see this conversation.", "task", nil, func(r *AIResponse, v string, _ map[string]interface{}) { r.SpawnAgent = append(r.SpawnAgent, v) }},
+	{"read_file", "Read a text file, relative to the exec pane's working directory, instead of running cat in the exec pane. Its contents are sent back in the next message.", "path", nil, func(r *AIResponse, v string, _ map[string]interface{}) { r.ReadFile = append(r.ReadFile, v) }},
+	{"request_accomplished", "Signal that the user's request has been completed and verified.", "", nil, func(r *AIResponse, _ string, _ map[string]interface{}) { r.RequestAccomplished = true }},
+	{"waiting_for_user_response", "Signal that you need input or clarification from the user.", "", nil, func(r *AIResponse, _ string, _ map[string]interface{}) { r.WaitingForUserResponse = true }},
+	{"exec_pane_seems_busy", "Signal that the exec pane is busy and you need to wait before proceeding.", "", nil, func(r *AIResponse, _ string, _ map[string]interface{}) { r.ExecPaneSeemsBusy = true }},
+	{"no_comment", "Signal that there is nothing new worth commenting on (watch mode).", "", nil, func(r *AIResponse, _ string, _ map[string]interface{}) { r.NoComment = true }},
+	{"watch_goal_met", "Signal that the goal of /watch until has been met, which stops watch mode.", "", nil, func(r *AIResponse, _ string, _ map[string]interface{}) { r.WatchGoalMet = true }},
+}
+
+// appendAligned appends the value for item n of a per-item attribute
+// slice, which stays nil until an item sets a non-zero value
+func appendAligned[T comparable](values []T, n int, v T) []T {
+	var zero T
+	if values == nil && v == zero {
+		return nil
+	}
+	for len(values) < n {
+		values = append(values, zero)
+	}
+	return append(values, v)
+}
+
+// toolParameters returns the JSON schema for a tool's arguments
+func (t agentTool) toolParameters() map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	if t.Param != "" {
+		properties[t.Param] = map[string]interface{}{"type": "string"}
+		required = append(required, t.Param)
+	}
+	for _, attr := range t.Attrs {
+		properties[attr.Name] = map[string]interface{}{"type": attr.Type}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// ChatTool represents a function tool in the chat completion API
+type ChatTool struct {
+	Type     string           `json:"type"`
+	Function ChatToolFunction `json:"function"`
+}
+
+// ChatToolFunction represents a function declaration in the chat completion API
+type ChatToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ChatToolCall represents a tool call returned by the chat completion API
+type ChatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// AnthropicTool represents a tool declaration in the Anthropic Messages API
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+func chatCompletionTools() []ChatTool {
+	tools := make([]ChatTool, 0, len(agentTools))
+	for _, t := range agentTools {
+		tools = append(tools, ChatTool{
+			Type:     "function",
+			Function: ChatToolFunction{Name: t.Name, Description: t.Description, Parameters: t.toolParameters()},
+		})
+	}
+	return tools
+}
+
+func anthropicTools() []AnthropicTool {
+	tools := make([]AnthropicTool, 0, len(agentTools))
+	for _, t := range agentTools {
+		tools = append(tools, AnthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.toolParameters()})
+	}
+	return tools
+}
+
+// responsesTools returns the tool declarations in the OpenAI Responses API format
+func responsesTools() []interface{} {
+	tools := make([]interface{}, 0, len(agentTools))
+	for _, t := range agentTools {
+		tools = append(tools, map[string]interface{}{
+			"type":        "function",
+			"name":        t.Name,
+			"description": t.Description,
+			"parameters":  t.toolParameters(),
+		})
+	}
+	return tools
+}
+
+// toolCall is a function call returned by the provider in tool calling mode
+type toolCall struct {
+	Name      string
+	Arguments json.RawMessage
+}
+
+// applyToolCall sets the AIResponse fields of a tool call. Unknown tools and
+// calls without their argument are skipped so a hallucinated call can't
+// break the response.
+func applyToolCall(r *AIResponse, call toolCall) bool {
+	for _, t := range agentTools {
+		if t.Name != call.Name {
+			continue
+		}
+		var args map[string]interface{}
+		if len(call.Arguments) > 0 {
+			if err := json.Unmarshal(call.Arguments, &args); err != nil {
+				logger.Error("Failed to parse arguments for tool %s: %v", call.Name, err)
+				return false
+			}
+		}
+		value, _ := args[t.Param].(string)
+		if t.Param != "" && strings.TrimSpace(value) == "" {
+			logger.Error("Tool %s called without %s", call.Name, t.Param)
+			return false
+		}
+		t.apply(r, strings.TrimSpace(value), args)
+		return true
+	}
+
+	logger.Error("Model called unknown tool: %s", call.Name)
+	return false
+}
+
+// toolCallResponse combines the text and tool calls of a response into the
+// JSON form structured output mode answers with, which parseAIResponse
+// decodes as is. Text without usable tool calls is returned unchanged, so
+// XML tags written instead of calling tools still work.
+func toolCallResponse(text string, calls []toolCall) string {
+	r := AIResponse{Message: strings.TrimSpace(text)}
+	applied := false
+	for _, call := range calls {
+		applied = applyToolCall(&r, call) || applied
+	}
+	if !applied {
+		return text
+	}
+
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		logger.Error("Failed to encode tool calls: %v", err)
+		return text
+	}
+	// unset fields are left out to keep the history short
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		logger.Error("Failed to encode tool calls: %v", err)
+		return text
+	}
+	for key, value := range fields {
+		if value == nil || value == false || (value == "" && key != "message") {
+			delete(fields, key)
+		}
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fields); err != nil {
+		logger.Error("Failed to encode tool calls: %v", err)
+		return text
+	}
+	return strings.TrimSpace(b.String())
+}
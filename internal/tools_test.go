@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyToolCall(t *testing.T) {
+	var r AIResponse
+	assert.True(t, applyToolCall(&r, toolCall{"exec_command", json.RawMessage(`{"command":"ls -la"}`)}))
+	assert.True(t, applyToolCall(&r, toolCall{"exec_command", json.RawMessage(`{"command":"psql","pane":"DB"}`)}))
+	assert.True(t, applyToolCall(&r, toolCall{"exec_command", json.RawMessage(`{"command":"make test","timeout":30}`)}))
+	assert.True(t, applyToolCall(&r, toolCall{"exec_command", json.RawMessage(`{"command":"npm run dev","background":true,"timeout":0}`)}))
+	assert.Equal(t, []string{"ls -la", "psql", "make test", "npm run dev"}, r.ExecCommand)
+	assert.Equal(t, []string{"", "db", "", ""}, r.ExecCommandPanes)
+	assert.Equal(t, []int{0, 0, 30, 0}, r.ExecCommandTimeouts)
+	assert.Equal(t, []bool{false, false, false, true}, r.ExecCommandBackground)
+
+	assert.True(t, applyToolCall(&r, toolCall{"send_keys", json.RawMessage(`{"keys":"C-c"}`)}))
+	assert.True(t, applyToolCall(&r, toolCall{"spawn_agent", json.RawMessage(`{"task":"run the test suite"}`)}))
+	assert.True(t, applyToolCall(&r, toolCall{"request_accomplished", nil}))
+	assert.Equal(t, []string{"C-c"}, r.SendKeys)
+	assert.Equal(t, []string{"run the test suite"}, r.SpawnAgent)
+	assert.True(t, r.RequestAccomplished)
+
+	assert.False(t, applyToolCall(&r, toolCall{"exec_command", json.RawMessage(`{}`)}))
+	assert.False(t, applyToolCall(&r, toolCall{"rm_rf", json.RawMessage(`{}`)}))
+	assert.Len(t, r.ExecCommand, 4)
+}
+
+func TestToolCallResponse(t *testing.T) {
+	command := `grep -c '</ExecCommand>' a.html && echo "&lt; &amp;copy=1" <in.txt`
+	content := "if a < b && c > d {\n\tfmt.Println(\"&amp; &copy=\")\n}\n// </WriteFile>"
+	args, err := json.Marshal(map[string]string{"command": command})
+	require.NoError(t, err)
+	fileArgs, err := json.Marshal(map[string]string{"content": content, "path": "a&b.go"})
+	require.NoError(t, err)
+
+	manager := &Manager{Config: &config.Config{}, SessionOverrides: map[string]interface{}{}}
+	r, err := manager.parseAIResponse(toolCallResponse("Checking.", []toolCall{{"exec_command", args}, {"write_file", fileArgs}}))
+	require.NoError(t, err)
+	assert.Equal(t, "Checking.", r.Message)
+	assert.Equal(t, []string{command}, r.ExecCommand, "arguments reach the response as given")
+	assert.Nil(t, r.ExecCommandPanes)
+	assert.Equal(t, []string{content}, r.WriteFile)
+	assert.Equal(t, []string{"a&b.go"}, r.WriteFilePaths)
+
+	text := "Running it.\n<ExecCommand>ls</ExecCommand>"
+	assert.Equal(t, text, toolCallResponse(text, nil), "tags written instead of tool calls still parse")
+	assert.Equal(t, text, toolCallResponse(text, []toolCall{{"rm_rf", nil}}))
+}
+
+func toolCallingManager(provider, baseURL string) (*Manager, *AiClient) {
+	cfg := &config.Config{
+		DefaultModel: "tools",
+		Models: map[string]config.ModelConfig{
+			"tools": {Provider: provider, Model: "m", APIKey: "k", BaseURL: baseURL, ToolCalling: true, Stream: true},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+	manager.AiClient = client
+	return manager, client
+}
+
+func TestChatCompletionToolCalls(t *testing.T) {
+	var got ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Listing files.","tool_calls":[{"id":"c1","type":"function","function":{"name":"exec_command","arguments":"{\"command\":\"ls\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	manager, client := toolCallingManager("openrouter", server.URL)
+	resp, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "list"}}, "m")
+	require.NoError(t, err)
+	assert.Equal(t, `{"exec_command":["ls"],"message":"Listing files."}`, resp)
+	assert.Len(t, got.Tools, len(agentTools))
+	assert.False(t, got.Stream, "streaming is disabled in tool calling mode")
+
+	r, err := manager.parseAIResponse(resp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls"}, r.ExecCommand)
+	assert.Equal(t, "Listing files.", r.Message)
+}
+
+func TestAnthropicToolUse(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Done."},{"type":"tool_use","id":"t1","name":"request_accomplished","input":{}}],"stop_reason":"tool_use"}`))
+	}))
+	defer server.Close()
+
+	_, client := toolCallingManager("anthropic", server.URL)
+	resp, err := client.AnthropicMessages(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m", client.providerModelConfig("anthropic"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"Done.","request_accomplished":true}`, resp)
+	assert.Len(t, got["tools"], len(agentTools))
+}
+
+func TestResponsesFunctionCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"output":[{"type":"function_call","name":"send_keys","arguments":"{\"keys\":\"q\"}"}]}`))
+	}))
+	defer server.Close()
+
+	_, client := toolCallingManager("openai", server.URL)
+	resp, err := client.Response(context.Background(), []Message{{Role: "user", Content: "quit"}}, "m")
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"","send_keys":["q"]}`, resp)
+}
+
+func TestToolCallingPromptHint(t *testing.T) {
+	manager, _ := toolCallingManager("openrouter", "")
+	assert.Contains(t, manager.chatAssistantPrompt(false).Content, "Call these tools instead of writing the XML tags")
+	assert.Contains(t, manager.chatAssistantPrompt(false).Content, "spawn_agent")
+
+	manager.Config.Models["tools"] = config.ModelConfig{Provider: "openrouter", Model: "m", APIKey: "k"}
+	assert.NotContains(t, manager.chatAssistantPrompt(false).Content, "Call these tools instead")
+}
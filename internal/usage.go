@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// TokenUsage holds the token counts a provider reported for a single request
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// ModelUsage accumulates usage for one model configuration over a session
+type ModelUsage struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	Priced           bool // false when the model has no pricing configured
+}
+
+// recordUsage adds a request's token usage to the named model configuration,
+// pricing it from the current model config when costs are configured.
+func (m *Manager) recordUsage(usage TokenUsage) {
+	mc, _ := m.GetCurrentModelConfig()
+	name := m.GetModelsDefault()
+	if name == "" {
+		name = mc.Model
+	}
+
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+
+	if m.usage == nil {
+		m.usage = make(map[string]*ModelUsage)
+	}
+	entry, ok := m.usage[name]
+	if !ok {
+		entry = &ModelUsage{}
+		m.usage[name] = entry
+	}
+
+	entry.Requests++
+	entry.PromptTokens += usage.PromptTokens
+	entry.CompletionTokens += usage.CompletionTokens
+	if mc.InputCostPerMillion > 0 || mc.OutputCostPerMillion > 0 {
+		entry.Cost += float64(usage.PromptTokens)*mc.InputCostPerMillion/1e6 +
+			float64(usage.CompletionTokens)*mc.OutputCostPerMillion/1e6
+		entry.Priced = true
+	}
+}
+
+// GetUsage returns a copy of the per-model usage recorded this session
+func (m *Manager) GetUsage() map[string]ModelUsage {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+
+	result := make(map[string]ModelUsage, len(m.usage))
+	for name, entry := range m.usage {
+		result[name] = *entry
+	}
+	return result
+}
+
+// showUsage prints the per-model token and cost breakdown for the session
+func (m *Manager) showUsage() {
+	usage := m.GetUsage()
+	if len(usage) == 0 {
+		m.Println("No AI requests recorded in this session yet.")
+		return
+	}
+
+	names := make([]string, 0, len(usage))
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatter := system.NewInfoFormatter()
+	fmt.Println(formatter.FormatSection("\nSession Usage"))
+	fmt.Printf("%-20s %8s %12s %12s %10s\n", "Model", "Requests", "Prompt", "Completion", "Cost")
+
+	var total ModelUsage
+	for _, name := range names {
+		u := usage[name]
+		fmt.Printf("%-20s %8d %12d %12d %10s\n", name, u.Requests, u.PromptTokens, u.CompletionTokens, formatCost(u))
+		total.Requests += u.Requests
+		total.PromptTokens += u.PromptTokens
+		total.CompletionTokens += u.CompletionTokens
+		total.Cost += u.Cost
+		total.Priced = total.Priced || u.Priced
+	}
+
+	if len(names) > 1 {
+		fmt.Printf("%-20s %8d %12d %12d %10s\n", "Total", total.Requests, total.PromptTokens, total.CompletionTokens, formatCost(total))
+	}
+}
+
+func formatCost(u ModelUsage) string {
+	if !u.Priced {
+		return "-"
+	}
+	return fmt.Sprintf("$%.4f", u.Cost)
+}
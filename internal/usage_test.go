@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordUsageAccumulatesPerModel(t *testing.T) {
+	manager := &Manager{
+		Config: &config.Config{
+			DefaultModel: "fast",
+			Models: map[string]config.ModelConfig{
+				"fast":  {Provider: "openrouter", Model: "a", InputCostPerMillion: 1, OutputCostPerMillion: 4},
+				"local": {Provider: "ollama", Model: "b"},
+			},
+		},
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+
+	manager.recordUsage(TokenUsage{PromptTokens: 1000, CompletionTokens: 500})
+	manager.recordUsage(TokenUsage{PromptTokens: 3000, CompletionTokens: 500})
+	manager.SetModelsDefault("local")
+	manager.recordUsage(TokenUsage{PromptTokens: 10, CompletionTokens: 20})
+
+	usage := manager.GetUsage()
+	require.Len(t, usage, 2)
+
+	fast := usage["fast"]
+	assert.Equal(t, 2, fast.Requests)
+	assert.Equal(t, 4000, fast.PromptTokens)
+	assert.Equal(t, 1000, fast.CompletionTokens)
+	assert.True(t, fast.Priced)
+	assert.InDelta(t, 0.008, fast.Cost, 1e-9)
+	assert.Equal(t, "$0.0080", formatCost(fast))
+
+	local := usage["local"]
+	assert.Equal(t, 1, local.Requests)
+	assert.False(t, local.Priced)
+	assert.Equal(t, "-", formatCost(local))
+}
+
+func TestChatCompletionRecordsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultModel: "fast",
+		Models: map[string]config.ModelConfig{
+			"fast": {Provider: "openrouter", Model: "m", APIKey: "k", BaseURL: server.URL},
+		},
+	}
+	manager := &Manager{
+		Config:           cfg,
+		SessionOverrides: make(map[string]interface{}),
+		LoadedKBs:        make(map[string]string),
+	}
+	client := NewAiClient(cfg)
+	client.SetConfigManager(manager)
+
+	_, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, "m")
+	require.NoError(t, err)
+
+	usage := manager.GetUsage()["fast"]
+	assert.Equal(t, 1, usage.Requests)
+	assert.Equal(t, 12, usage.PromptTokens)
+	assert.Equal(t, 3, usage.CompletionTokens)
+}
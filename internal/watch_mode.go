@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/alvinunreal/tmuxai/system"
+)
+
+// watchTriggered captures the panes and reports whether this watch mode
+// check is worth an AI call under watch.only_on_change and watch.triggers
+func (m *Manager) watchTriggered() bool {
+	if m.Status == "" {
+		return false
+	}
+
+	// in control mode, panes that printed nothing aren't captured again
+	triggers := m.watchTriggers()
+	if m.watchChanges == nil {
+		m.watchChanges = m.watchPaneChanges()
+	} else if !m.watchChanges() && (m.GetWatchOnlyOnChange() || len(triggers) > 0) {
+		logger.Debug("Watch mode: no pane output since the last check, skipping AI call")
+		m.watchTrigger = ""
+		return false
+	}
+
+	capture := m.getTmuxPanesInXml(m.Config)
+	previous := m.watchLastCapture
+	m.watchLastCapture = capture
+	m.watchTrigger = ""
+
+	if m.GetWatchOnlyOnChange() && capture == previous {
+		logger.Debug("Watch mode: panes unchanged, skipping AI call")
+		return false
+	}
+
+	if len(triggers) == 0 {
+		return true
+	}
+	for _, line := range newLines(previous, capture) {
+		for _, re := range triggers {
+			if re.MatchString(line) {
+				logger.Debug("Watch mode: %q matched trigger %s", line, re)
+				m.watchTrigger = strings.TrimSpace(line)
+				return true
+			}
+		}
+	}
+	logger.Debug("Watch mode: no new lines matched the triggers, skipping AI call")
+	return false
+}
+
+// watchTriggers compiles watch.triggers, skipping invalid expressions
+func (m *Manager) watchTriggers() []*regexp.Regexp {
+	var triggers []*regexp.Regexp
+	for _, pattern := range m.Config.Watch.Triggers {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Error("Ignoring invalid watch trigger %q: %v", pattern, err)
+			continue
+		}
+		triggers = append(triggers, re)
+	}
+	return triggers
+}
+
+// newLines returns the lines of current that previous doesn't have, counting
+// repeated lines so a line printed again is new
+func newLines(previous, current string) []string {
+	seen := make(map[string]int)
+	for _, line := range strings.Split(previous, "\n") {
+		seen[line]++
+	}
+	var lines []string
+	for _, line := range strings.Split(current, "\n") {
+		if seen[line] > 0 {
+			seen[line]--
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseWatchArgs splits /watch arguments into the leading pane IDs and the
+// watch description, which may be quoted. A description starting with
+// "until" is a stop condition, reported by until.
+func parseWatchArgs(args []string) (panes []string, desc string, until bool) {
+	for len(args) > 0 && isPaneID(args[0]) {
+		if !slices.Contains(panes, args[0]) {
+			panes = append(panes, args[0])
+		}
+		args = args[1:]
+	}
+	if len(args) > 1 && strings.EqualFold(args[0], "until") {
+		until = true
+		args = args[1:]
+	}
+	desc = strings.TrimSpace(strings.Join(args, " "))
+	if len(desc) >= 2 && (desc[0] == '"' || desc[0] == '\'') && desc[len(desc)-1] == desc[0] {
+		desc = strings.TrimSpace(desc[1 : len(desc)-1])
+	}
+	return panes, desc, until
+}
+
+// isPaneID reports whether s is a tmux pane ID such as %3
+func isPaneID(s string) bool {
+	if len(s) < 2 || s[0] != '%' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// watchingPanes reports whether watch mode is limited to the panes given to /watch
+func (m *Manager) watchingPanes() bool {
+	return m.WatchMode && len(m.watchPanes) > 0
+}
+
+// watchedPanes looks up the panes given to /watch, wherever they are.
+// Panes closed since are skipped.
+func (m *Manager) watchedPanes() []system.TmuxPaneDetails {
+	var panes []system.TmuxPaneDetails
+	for _, id := range m.watchPanes {
+		pane, found := m.findPane(id)
+		if !found {
+			logger.Debug("Watch mode: pane %s no longer exists", id)
+			continue
+		}
+		pane.IsTmuxAiExecPane = pane.Id == m.ExecPane.Id
+		pane.IsPrepared = pane.IsTmuxAiExecPane
+		panes = append(panes, pane)
+	}
+	return panes
+}
+
+// watchNotifyMaxChars caps the text of a desktop notification
+const watchNotifyMaxChars = 200
+
+// watchAttributionRe finds the pane a comment is about, as asked for in the
+// watch prompt
+var watchAttributionRe = regexp.MustCompile(`^\[(%\d+)\]`)
+
+// watchObservation is what a watch mode check reports to desktop
+// notifications and the webhook
+type watchObservation struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Goal      string    `json:"goal,omitempty"` // set for watch_goal_met events
+	PaneID    string    `json:"pane_id,omitempty"`
+	Matched   string    `json:"matched,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Text      string    `json:"text"` // summary for Slack-compatible webhooks
+}
+
+// watchObservation describes a met /watch until goal, a watch mode comment,
+// or the trigger match that prompted the check when the AI had nothing to
+// say. It reports false when there is none of these.
+func (m *Manager) watchObservation(r AIResponse) (watchObservation, bool) {
+	obs := watchObservation{Event: "watch_observation", Timestamp: time.Now().UTC(), Matched: m.watchTrigger}
+	if !r.NoComment {
+		obs.Comment = strings.TrimSpace(r.Message)
+	}
+	switch {
+	case r.WatchGoalMet && m.watchGoal != "":
+		obs.Event = "watch_goal_met"
+		obs.Goal = m.watchGoal
+		obs.Text = "Watch goal met: " + m.watchGoal
+	case obs.Comment != "":
+		obs.Text = obs.Comment
+	case obs.Matched != "":
+		obs.Text = "Matched: " + obs.Matched
+	default:
+		return obs, false
+	}
+
+	if match := watchAttributionRe.FindStringSubmatch(obs.Comment); match != nil {
+		obs.PaneID = match[1]
+	} else if len(m.watchPanes) == 1 {
+		obs.PaneID = m.watchPanes[0]
+	}
+	return obs, true
+}
+
+// reportWatch sends the observation of a watch mode check as a desktop
+// notification and to the webhook, when configured. A met goal is always
+// notified, since the user is waiting for it.
+func (m *Manager) reportWatch(r AIResponse) {
+	obs, ok := m.watchObservation(r)
+	m.watchTrigger = ""
+	if !ok {
+		return
+	}
+
+	if m.GetWatchNotify() || obs.Event == "watch_goal_met" {
+		message := obs.Text
+		if runes := []rune(message); len(runes) > watchNotifyMaxChars {
+			message = string(runes[:watchNotifyMaxChars-3]) + "..."
+		}
+		notify := m.notify
+		if notify == nil {
+			notify = system.Notify
+		}
+		notify("TmuxAI watch", message)
+	}
+
+	if m.Config.Watch.Webhook != "" {
+		// a slow endpoint mustn't hold up the next check
+		go func() {
+			if err := m.postWatchWebhook(obs); err != nil {
+				logger.Error("Watch webhook failed: %v", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLines(t *testing.T) {
+	assert.Equal(t, []string{"c", "a"}, newLines("a\nb", "a\nb\nc\na"))
+	assert.Empty(t, newLines("a\nb", "b\na"))
+}
+
+func TestWatchTriggered(t *testing.T) {
+	cfg := config.DefaultConfig()
+	capture := "$ make test\nok"
+	manager := &Manager{Config: cfg, Status: "running", SessionOverrides: map[string]interface{}{}}
+	manager.getTmuxPanesInXml = func(*config.Config) string { return capture }
+
+	assert.True(t, manager.watchTriggered())
+	assert.True(t, manager.watchTriggered(), "every check calls the AI by default")
+
+	cfg.Watch.OnlyOnChange = true
+	assert.False(t, manager.watchTriggered(), "unchanged panes are skipped")
+	capture += "\nok again"
+	assert.True(t, manager.watchTriggered())
+
+	cfg.Watch.OnlyOnChange = false
+	cfg.Watch.Triggers = []string{"ERROR|panic:", "("}
+	capture += "\nstill fine"
+	assert.False(t, manager.watchTriggered(), "new lines don't match")
+	capture += "\npanic: nil map"
+	assert.True(t, manager.watchTriggered())
+	assert.False(t, manager.watchTriggered(), "only new lines are matched")
+
+	manager.Status = ""
+	capture += "\nERROR again"
+	assert.False(t, manager.watchTriggered(), "stopped watch mode never triggers")
+}
+
+func TestGetWatchInterval(t *testing.T) {
+	cfg := config.DefaultConfig()
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	assert.Equal(t, cfg.WaitInterval, manager.GetWatchInterval())
+
+	cfg.Watch.Interval = 30
+	assert.Equal(t, 30, manager.GetWatchInterval())
+
+	manager.SessionOverrides["watch.interval"] = 60
+	assert.Equal(t, 60, manager.GetWatchInterval())
+}
+
+func TestParseWatchArgs(t *testing.T) {
+	panes, desc, until := parseWatchArgs([]string{"%1", "%3", `"alert`, "on", `errors"`})
+	assert.Equal(t, []string{"%1", "%3"}, panes)
+	assert.Equal(t, "alert on errors", desc)
+	assert.False(t, until)
+
+	panes, desc, _ = parseWatchArgs([]string{"watch", "for", "100%", "cpu"})
+	assert.Empty(t, panes)
+	assert.Equal(t, "watch for 100% cpu", desc)
+
+	panes, desc, _ = parseWatchArgs([]string{"%2", "%2"})
+	assert.Equal(t, []string{"%2"}, panes)
+	assert.Empty(t, desc)
+
+	panes, desc, until = parseWatchArgs([]string{"%2", "until", `"tests`, `pass"`})
+	assert.Equal(t, []string{"%2"}, panes)
+	assert.Equal(t, "tests pass", desc)
+	assert.True(t, until)
+
+	_, desc, until = parseWatchArgs([]string{"until"})
+	assert.Equal(t, "until", desc, "a lone until is the description")
+	assert.False(t, until)
+}
+
+func TestWatchPanesLimitContext(t *testing.T) {
+	manager := &Manager{
+		Config:            config.DefaultConfig(),
+		ExecPane:          &system.TmuxPaneDetails{Id: "%0"},
+		ForcedReadPaneIDs: map[string]bool{"%5": true},
+		watchPanes:        []string{"%1", "%3"},
+	}
+	assert.True(t, manager.shouldIncludeReadPane(system.TmuxPaneDetails{Id: "%5"}), "outside watch mode the panes are ignored")
+
+	manager.WatchMode = true
+	assert.True(t, manager.shouldIncludeReadPane(system.TmuxPaneDetails{Id: "%3"}))
+	assert.False(t, manager.shouldIncludeReadPane(system.TmuxPaneDetails{Id: "%5"}))
+	assert.False(t, manager.shouldIncludeReadPane(system.TmuxPaneDetails{Id: "%0", IsTmuxAiExecPane: true}))
+	assert.Contains(t, manager.watchPrompt().Content, "watching the panes with Id %1, %3")
+}
+
+func TestReportWatchNotifies(t *testing.T) {
+	cfg := config.DefaultConfig()
+	var sent []string
+	manager := &Manager{Config: cfg, SessionOverrides: map[string]interface{}{}}
+	manager.notify = func(title, message string) { sent = append(sent, message) }
+
+	manager.reportWatch(AIResponse{Message: "disk almost full"})
+	assert.Empty(t, sent, "notifications are off by default")
+
+	cfg.Watch.Notify = true
+	manager.reportWatch(AIResponse{Message: "disk almost full"})
+	manager.reportWatch(AIResponse{NoComment: true})
+	manager.watchTrigger = "panic: nil map"
+	manager.reportWatch(AIResponse{NoComment: true})
+	manager.reportWatch(AIResponse{NoComment: true})
+	assert.Equal(t, []string{"disk almost full", "Matched: panic: nil map"}, sent)
+}
+
+func TestWatchObservation(t *testing.T) {
+	manager := &Manager{Config: config.DefaultConfig(), watchPanes: []string{"%1", "%3"}}
+
+	obs, ok := manager.watchObservation(AIResponse{Message: "[%3] too many connections"})
+	assert.True(t, ok)
+	assert.Equal(t, "%3", obs.PaneID, "the pane comes from the comment's attribution")
+	assert.Equal(t, "[%3] too many connections", obs.Text)
+
+	manager.watchTrigger = "ERROR: disk full"
+	obs, ok = manager.watchObservation(AIResponse{NoComment: true, Message: "ignored"})
+	assert.True(t, ok)
+	assert.Empty(t, obs.Comment)
+	assert.Equal(t, "Matched: ERROR: disk full", obs.Text)
+
+	manager.watchTrigger = ""
+	_, ok = manager.watchObservation(AIResponse{NoComment: true})
+	assert.False(t, ok)
+}
+
+func TestWatchGoalMet(t *testing.T) {
+	var sent []string
+	manager := &Manager{Config: config.DefaultConfig(), SessionOverrides: map[string]interface{}{}, watchGoal: "tests pass"}
+	manager.notify = func(title, message string) { sent = append(sent, message) }
+
+	r, err := manager.parseAIResponse("All 42 tests passed.\n<WatchGoalMet>1</WatchGoalMet>")
+	assert.NoError(t, err)
+	assert.True(t, r.WatchGoalMet)
+
+	obs, ok := manager.watchObservation(r)
+	assert.True(t, ok)
+	assert.Equal(t, "watch_goal_met", obs.Event)
+	assert.Equal(t, "tests pass", obs.Goal)
+
+	manager.reportWatch(r)
+	assert.Equal(t, []string{"Watch goal met: tests pass"}, sent, "a met goal is notified even with watch.notify off")
+	assert.Contains(t, manager.watchPrompt().Content, "<WatchGoalMet>1</WatchGoalMet>")
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// watchWebhookClient posts watch mode observations. Like remote KBs it may
+// reach private addresses, since the URL comes from the user's own config.
+var watchWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postWatchWebhook POSTs an observation as JSON to watch.webhook
+func (m *Manager) postWatchWebhook(obs watchObservation) error {
+	body, err := json.Marshal(obs)
+	if err != nil {
+		return fmt.Errorf("failed to encode observation: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchWebhookClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Config.Watch.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	for key, value := range m.Config.Watch.WebhookHeaders {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+
+	resp, err := watchWebhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting to %s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostWatchWebhook(t *testing.T) {
+	t.Setenv("ALERTS_TOKEN", "secret")
+	var got map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Watch.Webhook = server.URL
+	cfg.Watch.WebhookHeaders = map[string]string{"Authorization": "Bearer ${ALERTS_TOKEN}"}
+	manager := &Manager{Config: cfg}
+
+	obs := watchObservation{Event: "watch_observation", Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		PaneID: "%3", Matched: "ERROR: disk full", Comment: "[%3] disk is full", Text: "[%3] disk is full"}
+	require.NoError(t, manager.postWatchWebhook(obs))
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, map[string]any{
+		"event": "watch_observation", "timestamp": "2026-01-02T03:04:05Z", "pane_id": "%3",
+		"matched": "ERROR: disk full", "comment": "[%3] disk is full", "text": "[%3] disk is full",
+	}, got)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	assert.ErrorContains(t, manager.postWatchWebhook(obs), "403")
+}
@@ -590,7 +590,7 @@ func FetchWithFallbacks(ctx context.Context, rawURL string, maxChars, timeoutSec
 	wbResult := WebFetch(ctx2, wbURL, maxChars, timeoutSeconds, false)
 	if wbResult.Error == nil && !needsFallback(wbResult.Content) {
 		infoMsg := "  -> Direct fetch failed, using fallback: wayback"
-		logger.Info(infoMsg)
+		logger.Info("%s", infoMsg)
 		fmt.Fprintln(os.Stderr, infoMsg)
 		return FetchResult{
 			Content: wbResult.Content,
@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// backupsDir is the directory in the config directory that keeps the
+// previous version of files changed by WriteFile and ApplyPatch
+const backupsDir = "backups"
+
+// fileChange is a change WriteFile or ApplyPatch proposes for one file
+type fileChange struct {
+	Path   string // as the model gave it
+	Real   string // resolved path that gets written
+	Old    string
+	New    string
+	Exists bool
+}
+
+// fileWrite is the outcome of a proposed fileChange
+type fileWrite struct {
+	Path   string
+	Status string // written, unchanged, dry_run or failed
+	Err    error
+}
+
+// writeFilePrompt describes the WriteFile and ApplyPatch tags, or returns ""
+// when writing files is turned off
+func (m *Manager) writeFilePrompt() string {
+	if !m.Config.Files.Write || system.TmuxIsRemote() {
+		return ""
+	}
+	return fmt.Sprintf("\n<WriteFile>: Use this to create or overwrite a file instead of pasting a heredoc into the exec pane: <WriteFile path=\"src/main.go\">full file contents</WriteFile>. The path attribute is required."+
+		"\n<ApplyPatch>: Use this to change part of an existing file with a unified diff (--- a/path, +++ b/path, @@ hunks with 3 lines of context), which may cover several files: <ApplyPatch>--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@ ...</ApplyPatch>. Prefer it over WriteFile for small edits of large files."+
+		"\nRelative paths are resolved against the exec pane's working directory, and only files under %s can be written. The user sees the diff of each change and may decline it.",
+		strings.Join(m.fileRoots(), ", "))
+}
+
+// checkWritable reports why files can't be written, nil if they can
+func (m *Manager) checkWritable() error {
+	if !m.Config.Files.Write {
+		return errors.New("writing files is turned off, files.write is false")
+	}
+	if system.TmuxIsRemote() {
+		return errors.New("files can't be written on a remote tmux server, use PasteMultilineContent")
+	}
+	return nil
+}
+
+// proposeWrite returns the change a WriteFile action makes to path
+func (m *Manager) proposeWrite(path, content string) (fileChange, error) {
+	if err := m.checkWritable(); err != nil {
+		return fileChange{}, err
+	}
+	if path == "" {
+		return fileChange{}, errors.New("WriteFile needs a path attribute")
+	}
+	change, err := m.currentFile(path)
+	if err != nil {
+		return fileChange{}, err
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	change.New = content
+	return change, nil
+}
+
+// proposePatch returns the changes an ApplyPatch action makes, one per file
+// in the unified diff
+func (m *Manager) proposePatch(patch string) ([]fileChange, error) {
+	if err := m.checkWritable(); err != nil {
+		return nil, err
+	}
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]fileChange, 0, len(files))
+	for _, f := range files {
+		change, err := m.currentFile(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if change.New, err = applyHunks(change.Old, f.Hunks); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// currentFile resolves path and reads what it holds now, if it exists
+func (m *Manager) currentFile(path string) (fileChange, error) {
+	real, err := m.resolveFilePath(path)
+	if err != nil {
+		return fileChange{}, err
+	}
+	change := fileChange{Path: path, Real: real}
+	data, err := os.ReadFile(real)
+	switch {
+	case err == nil:
+		change.Old, change.Exists = string(data), true
+	case !os.IsNotExist(err):
+		return fileChange{}, err
+	}
+	return change, nil
+}
+
+// diff renders the change as a unified diff
+func (c fileChange) diff() string {
+	from := "a/" + c.Path
+	if !c.Exists {
+		from = "/dev/null"
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(c.Old),
+		B:        difflib.SplitLines(c.New),
+		FromFile: from,
+		ToFile:   "b/" + c.Path,
+		Context:  3,
+	})
+	return diff
+}
+
+// applyFileChange writes a change atomically, through a temporary file
+// renamed over the original, after backing the original up with
+// files.backup. It returns the backup's path, "" if none was made.
+func (m *Manager) applyFileChange(c fileChange) (string, error) {
+	mode := os.FileMode(0o644)
+	backup := ""
+	if c.Exists {
+		if info, err := os.Stat(c.Real); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if m.Config.Files.Backup {
+			var err error
+			if backup, err = backupFile(c.Real, c.Old, mode); err != nil {
+				return "", fmt.Errorf("failed to back up: %w", err)
+			}
+		}
+	}
+
+	dir := filepath.Dir(c.Real)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return backup, err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(c.Real)+".tmuxai-*")
+	if err != nil {
+		return backup, err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(c.New); err != nil {
+		_ = tmp.Close()
+		return backup, err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return backup, err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return backup, err
+	}
+	if err := tmp.Close(); err != nil {
+		return backup, err
+	}
+	return backup, os.Rename(tmp.Name(), c.Real)
+}
+
+// backupFile saves content to the backups directory, named after the time
+// and the file it came from
+func backupFile(path, content string, mode os.FileMode) (string, error) {
+	dir := config.GetConfigFilePath(backupsDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	name := time.Now().Format("20060102-150405.000") + "-" + strings.ReplaceAll(strings.TrimPrefix(path, string(filepath.Separator)), string(filepath.Separator), "_")
+	backup := filepath.Join(dir, name)
+	return backup, os.WriteFile(backup, []byte(content), mode)
+}
+
+// writeFiles shows the diff of each change, asks to confirm it with
+// files.write_confirm, and writes it. It reports false when a change was
+// declined, which ends the task like a declined command.
+func (m *Manager) writeFiles(changes []fileChange) ([]fileWrite, bool) {
+	var writes []fileWrite
+	for _, c := range changes {
+		diff := c.diff()
+		if diff == "" {
+			m.Println(fmt.Sprintf("No changes to %s", c.Path))
+			writes = append(writes, fileWrite{Path: c.Path, Status: "unchanged"})
+			continue
+		}
+		code, _ := system.HighlightCode("diff", diff)
+		m.Println(code)
+
+		if m.GetDryRun() {
+			m.skipDryRunAction("write_file", c.Path)
+			writes = append(writes, fileWrite{Path: c.Path, Status: "dry_run"})
+			continue
+		}
+		decision := auditAuto
+		if m.GetWriteFileConfirm() {
+			if !m.confirmedWrite(fmt.Sprintf("Write %s?", c.Path)) {
+				m.audit("write_file", "", c.Path, auditDeclined, nil)
+				return writes, false
+			}
+			decision = auditApproved
+		}
+
+		backup, err := m.applyFileChange(c)
+		m.audit("write_file", "", c.Path, decision, nil)
+		write := fileWrite{Path: c.Path, Status: "written", Err: err}
+		if err != nil {
+			write.Status = "failed"
+			m.Println(fmt.Sprintf("Failed to write %s: %v", c.Path, err))
+		} else if backup != "" {
+			m.Println(fmt.Sprintf("Wrote %s (backup: %s)", c.Path, backup))
+		} else {
+			m.Println(fmt.Sprintf("Wrote %s", c.Path))
+		}
+		writes = append(writes, write)
+	}
+	return writes, true
+}
+
+// writeFilesObservation tells the model which file changes were written
+func writeFilesObservation(writes []fileWrite) string {
+	var b strings.Builder
+	b.WriteString("File changes:\n")
+	for _, w := range writes {
+		fmt.Fprintf(&b, "<file path=\"%s\" status=\"%s\"", sanitizeXML(w.Path), w.Status)
+		if w.Err != nil {
+			fmt.Fprintf(&b, " error=\"%s\"", sanitizeXML(w.Err.Error()))
+		}
+		b.WriteString("/>\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// filePatch is the part of a unified diff that changes one file
+type filePatch struct {
+	Path  string
+	Hunks []patchHunk
+}
+
+// patchHunk is one @@ section of a unified diff: the lines it expects at
+// OldStart (1-based) and the lines that replace them
+type patchHunk struct {
+	OldStart int
+	Old, New []string
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// parseUnifiedDiff splits a unified diff into the changes it makes to each
+// file. Lines outside of file sections, like "diff --git", are ignored.
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	var files []filePatch
+	var file *filePatch
+	var hunk *patchHunk
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			path := diffPath(lines[i+1][4:])
+			if path == "/dev/null" {
+				return nil, fmt.Errorf("deleting %s isn't supported, use ExecCommand", diffPath(line[4:]))
+			}
+			files = append(files, filePatch{Path: path})
+			file, hunk = &files[len(files)-1], nil
+			i++
+		case file != nil && strings.HasPrefix(line, "@@"):
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			file.Hunks = append(file.Hunks, patchHunk{OldStart: start})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		case hunk == nil:
+			continue
+		case strings.HasPrefix(line, "+"):
+			hunk.New = append(hunk.New, line[1:])
+		case strings.HasPrefix(line, "-"):
+			hunk.Old = append(hunk.Old, line[1:])
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			// models often drop the space of empty context lines
+			line = strings.TrimPrefix(line, " ")
+			hunk.Old = append(hunk.Old, line)
+			hunk.New = append(hunk.New, line)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no --- and +++ file headers found in the patch")
+	}
+	for _, f := range files {
+		if len(f.Hunks) == 0 {
+			return nil, fmt.Errorf("no @@ hunks for %s", f.Path)
+		}
+		// blank lines after a hunk read as empty context lines; dropping
+		// them from both sides changes nothing but where the hunk matches
+		for i := range f.Hunks {
+			h := &f.Hunks[i]
+			for len(h.Old) > 0 && len(h.New) > 0 && h.Old[len(h.Old)-1] == "" && h.New[len(h.New)-1] == "" {
+				h.Old, h.New = h.Old[:len(h.Old)-1], h.New[:len(h.New)-1]
+			}
+		}
+	}
+	return files, nil
+}
+
+// diffPath strips the a/ or b/ prefix and any timestamp from a diff header path
+func diffPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// applyHunks applies hunks to content. A hunk whose lines moved is applied
+// where they are found closest to its stated position; trailing whitespace
+// is ignored when matching.
+func applyHunks(content string, hunks []patchHunk) (string, error) {
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	offset := 0
+	for n, h := range hunks {
+		want := max(h.OldStart-1, 0) + offset
+		pos := findLines(lines, h.Old, want)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d doesn't match the file, read it again and send a new patch", n+1)
+		}
+		replaced := append(append(append([]string{}, lines[:pos]...), h.New...), lines[pos+len(h.Old):]...)
+		lines = replaced
+		offset = pos - max(h.OldStart-1, 0) + len(h.New) - len(h.Old)
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	result := strings.Join(lines, "\n")
+	if content == "" || strings.HasSuffix(content, "\n") {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines returns the index of want in lines closest to near, or -1
+func findLines(lines, want []string, near int) int {
+	near = min(max(near, 0), len(lines))
+	if len(want) == 0 {
+		return near
+	}
+	matches := func(pos int) bool {
+		if pos < 0 || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if strings.TrimRight(lines[pos+i], " \t") != strings.TrimRight(line, " \t") {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= len(lines); d++ {
+		if matches(near - d) {
+			return near - d
+		}
+		if matches(near + d) {
+			return near + d
+		}
+	}
+	return -1
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alvinunreal/tmuxai/config"
+	"github.com/alvinunreal/tmuxai/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFileManager(dir string) *Manager {
+	manager := &Manager{
+		Config:   &config.Config{Files: config.FilesConfig{Write: true, WriteConfirm: true, Backup: true}},
+		ExecPane: &system.TmuxPaneDetails{Id: "%2", CurrentPath: dir},
+	}
+	manager.confirmedWrite = func(prompt string) bool { return true }
+	return manager
+}
+
+func TestApplyHunks(t *testing.T) {
+	content := "a\nb\nc\nd\ne\nf\n"
+
+	patched, err := applyHunks(content, []patchHunk{
+		{OldStart: 2, Old: []string{"b", "c"}, New: []string{"b", "C"}},
+		{OldStart: 5, Old: []string{"e"}, New: []string{"e", "e2"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nC\nd\ne\ne2\nf\n", patched)
+
+	patched, err = applyHunks(content, []patchHunk{{OldStart: 1, Old: []string{"d  "}, New: []string{"D"}}})
+	require.NoError(t, err, "a hunk that moved is found, trailing whitespace ignored")
+	assert.Equal(t, "a\nb\nc\nD\ne\nf\n", patched)
+
+	_, err = applyHunks(content, []patchHunk{{OldStart: 1, Old: []string{"x"}, New: []string{"y"}}})
+	assert.ErrorContains(t, err, "hunk 1 doesn't match")
+
+	patched, err = applyHunks("", []patchHunk{{OldStart: 0, New: []string{"new"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", patched)
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files, err := parseUnifiedDiff("diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n keep\n-old\n+new\n\n--- /dev/null\n+++ b/y\t2026-01-01\n@@ -0,0 +1 @@\n+y\n")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "x", files[0].Path)
+	assert.Equal(t, []patchHunk{{OldStart: 1, Old: []string{"keep", "old"}, New: []string{"keep", "new"}}}, files[0].Hunks)
+	assert.Equal(t, "y", files[1].Path)
+
+	_, err = parseUnifiedDiff("--- a/x\n+++ /dev/null\n@@ -1 +0,0 @@\n-x")
+	assert.ErrorContains(t, err, "deleting x")
+	_, err = parseUnifiedDiff("just text")
+	assert.Error(t, err)
+}
+
+func TestWriteFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o600))
+	manager := writeFileManager(dir)
+
+	changes, err := manager.proposePatch("--- a/main.go\n+++ b/main.go\n@@ -3 +3 @@\n-func main() {}\n+func main() { run() }")
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Contains(t, changes[0].diff(), "+func main() { run() }")
+	change, err := manager.proposeWrite("cmd/new.txt", "hello")
+	require.NoError(t, err)
+	assert.Contains(t, change.diff(), "--- /dev/null")
+
+	writes, ok := manager.writeFiles(append(changes, change))
+	require.True(t, ok)
+	assert.Equal(t, []fileWrite{{Path: "main.go", Status: "written"}, {Path: "cmd/new.txt", Status: "written"}}, writes)
+
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() { run() }\n", string(data))
+	info, err := os.Stat(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the mode is kept")
+	data, err = os.ReadFile(filepath.Join(dir, "cmd", "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	backups, err := os.ReadDir(config.GetConfigFilePath(backupsDir))
+	require.NoError(t, err)
+	require.Len(t, backups, 1, "new files have nothing to back up")
+	data, err = os.ReadFile(filepath.Join(config.GetConfigFilePath(backupsDir), backups[0].Name()))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", string(data))
+
+	manager.confirmedWrite = func(prompt string) bool { return false }
+	change, err = manager.proposeWrite("main.go", "declined")
+	require.NoError(t, err)
+	_, ok = manager.writeFiles([]fileChange{change})
+	assert.False(t, ok)
+	data, _ = os.ReadFile(filepath.Join(dir, "main.go"))
+	assert.Equal(t, "package main\n\nfunc main() { run() }\n", string(data))
+}
+
+func TestProposeWriteRejected(t *testing.T) {
+	manager := writeFileManager(t.TempDir())
+
+	_, err := manager.proposeWrite(filepath.Join(t.TempDir(), "x"), "x")
+	assert.ErrorContains(t, err, "not under an allowed directory")
+	_, err = manager.proposeWrite("", "x")
+	assert.ErrorContains(t, err, "path attribute")
+
+	manager.Config.Files.Write = false
+	_, err = manager.proposeWrite("x", "x")
+	assert.ErrorContains(t, err, "turned off")
+	assert.Empty(t, manager.writeFilePrompt())
+}
+
+func TestParseWriteFile(t *testing.T) {
+	manager := writeFileManager(t.TempDir())
+	r, err := manager.parseAIResponse("Creating it.\n<WriteFile path=\"a.txt\">one\ntwo</WriteFile>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one\ntwo"}, r.WriteFile)
+	assert.Equal(t, []string{"a.txt"}, r.WriteFilePaths)
+	assert.Equal(t, "Creating it.", r.Message)
+}
+
+func TestFollowUpMessageKeepsFileWrites(t *testing.T) {
+	writes := writeFilesObservation([]fileWrite{{Path: "a.txt", Status: "written"}})
+	reads := readFilesObservation([]fileRead{{Path: "a.txt", Content: "one", Size: 3}})
+	assert.Equal(t, writes+"\n\n"+reads, followUpMessage([]string{writes, reads}))
+}
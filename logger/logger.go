@@ -1,11 +1,16 @@
 package logger
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -13,13 +18,42 @@ var (
 	once     sync.Once
 )
 
+// levels orders the log levels; messages below the configured one are dropped
+var levels = map[string]int{"debug": 0, "info": 1, "error": 2}
+
+// Options configures the log format ("text" or "json"), the lowest level
+// written ("debug", "info" or "error") and rotation: the file is rotated
+// past MaxSizeMB (0 = never), keeping MaxFiles rotated files, none older
+// than MaxAgeDays (0 = no age limit).
+type Options struct {
+	Format     string
+	Level      string
+	MaxSizeMB  int
+	MaxFiles   int
+	MaxAgeDays int
+}
+
+// DefaultOptions are used until Configure is called
+var DefaultOptions = Options{Format: "text", Level: "debug", MaxSizeMB: 10, MaxFiles: 3}
+
 // Logger represents a custom logger for TmuxAI
 type Logger struct {
-	logFile *os.File
+	logFile *rotatingFile
 	logger  *log.Logger
+	json    bool
+	level   int
+	session string
 	mu      sync.Mutex
 }
 
+// record is a JSON log line
+type record struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Session string `json:"session,omitempty"`
+	Msg     string `json:"msg"`
+}
+
 // Init initializes the logger
 func Init() error {
 	var err error
@@ -41,19 +75,34 @@ func newLogger() (*Logger, error) {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	logPath := filepath.Join(logDir, "tmuxai.log")
-	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	return newFileLogger(filepath.Join(logDir, "tmuxai.log"))
+}
+
+// newFileLogger creates a logger writing to path with DefaultOptions
+func newFileLogger(path string) (*Logger, error) {
+	logFile, err := openRotatingFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
 
-	logger := log.New(logFile, "", log.LstdFlags)
-
-	return &Logger{
+	l := &Logger{
 		logFile: logFile,
-		logger:  logger,
+		logger:  log.New(logFile, "", log.LstdFlags),
+		session: newSessionID(),
 		mu:      sync.Mutex{},
-	}, nil
+	}
+	if err := l.Configure(DefaultOptions); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// newSessionID returns a random ID that tells the lines of concurrent
+// TmuxAI processes apart until SetSession names the chat session
+func newSessionID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // GetInstance returns the singleton logger instance
@@ -64,6 +113,34 @@ func GetInstance() (*Logger, error) {
 	return instance, nil
 }
 
+// Configure applies format, level and rotation options
+func (l *Logger) Configure(opts Options) error {
+	level, ok := levels[strings.ToLower(opts.Level)]
+	if !ok {
+		return fmt.Errorf("unknown log level %q, use debug, info or error", opts.Level)
+	}
+	format := strings.ToLower(opts.Format)
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown log format %q, use text or json", opts.Format)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.json = format == "json"
+	l.level = level
+	l.logFile.maxSize = int64(opts.MaxSizeMB) * 1024 * 1024
+	l.logFile.maxFiles = opts.MaxFiles
+	l.logFile.maxAge = time.Duration(opts.MaxAgeDays) * 24 * time.Hour
+	return nil
+}
+
+// SetSession sets the session ID JSON lines are tagged with
+func (l *Logger) SetSession(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.session = id
+}
+
 // Close closes the logger
 func (l *Logger) Close() error {
 	l.mu.Lock()
@@ -71,25 +148,53 @@ func (l *Logger) Close() error {
 	return l.logFile.Close()
 }
 
-// Info logs an info message
-func (l *Logger) Info(format string, v ...interface{}) {
+// write logs a message at level, as JSON or as "[LEVEL] message" text
+func (l *Logger) write(level, format string, v ...interface{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.logger.Printf("[INFO] "+format, v...)
+	if levels[level] < l.level {
+		return
+	}
+	msg := fmt.Sprintf(format, v...)
+	if !l.json {
+		l.logger.Printf("[%s] %s", strings.ToUpper(level), msg)
+		return
+	}
+	line, err := json.Marshal(record{Time: time.Now().Format(time.RFC3339Nano), Level: level, Session: l.session, Msg: msg})
+	if err != nil {
+		return
+	}
+	_, _ = l.logFile.Write(append(line, '\n'))
+}
+
+// Info logs an info message
+func (l *Logger) Info(format string, v ...interface{}) {
+	l.write("info", format, v...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.logger.Printf("[ERROR] "+format, v...)
+	l.write("error", format, v...)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.logger.Printf("[DEBUG] "+format, v...)
+	l.write("debug", format, v...)
+}
+
+// Configure applies options to the singleton instance
+func Configure(opts Options) error {
+	if instance == nil {
+		return nil
+	}
+	return instance.Configure(opts)
+}
+
+// SetSession tags the singleton instance's JSON lines with a session ID
+func SetSession(id string) {
+	if instance != nil {
+		instance.SetSession(id)
+	}
 }
 
 // Info logs an info message using the singleton instance
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmuxai.log")
+	l, err := newFileLogger(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	require.NoError(t, l.Configure(Options{Format: "json", Level: "info"}))
+	l.SetSession("1792056612345")
+	l.Debug("dropped below the level")
+	l.Info("captured %d lines", 42)
+	l.Error("failed: %v", "timeout")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var rec record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "info", rec.Level)
+	assert.Equal(t, "1792056612345", rec.Session)
+	assert.Equal(t, "captured 42 lines", rec.Msg)
+	_, err = time.Parse(time.RFC3339Nano, rec.Time)
+	assert.NoError(t, err)
+
+	assert.Error(t, l.Configure(Options{Format: "xml", Level: "info"}))
+	assert.Error(t, l.Configure(Options{Format: "json", Level: "trace"}))
+}
+
+func TestTextLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmuxai.log")
+	l, err := newFileLogger(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	l.Debug("100%% %s", "done")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[DEBUG] 100% done\n")
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmuxai.log")
+	r, err := openRotatingFile(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	r.maxSize, r.maxFiles = 10, 2
+
+	for _, chunk := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := r.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	read := func(p string) string {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "dddddddd\n", read(path))
+	assert.Equal(t, "cccccccc\n", read(path+".1"))
+	assert.Equal(t, "bbbbbbbb\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3", "only max_files rotated files are kept")
+}
+
+func TestRotationRemovesOldFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmuxai.log")
+	require.NoError(t, os.WriteFile(path+".1", []byte("old\n"), 0o644))
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(path+".1", old, old))
+
+	r, err := openRotatingFile(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	r.maxSize, r.maxFiles, r.maxAge = 10, 3, 24*time.Hour
+
+	_, err = r.Write([]byte("aaaaaaaa\n"))
+	require.NoError(t, err)
+	_, err = r.Write([]byte("bbbbbbbb\n"))
+	require.NoError(t, err)
+
+	assert.FileExists(t, path+".1")
+	assert.NoFileExists(t, path+".2", "the day-old file is removed")
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// rotatingFile is an append-only log file that is renamed to path.1 once it
+// grows past maxSize, shifting older files to path.2 and so on. Only
+// maxFiles rotated files are kept, and with maxAge none older than that.
+type rotatingFile struct {
+	path     string
+	file     *os.File
+	size     int64
+	maxSize  int64
+	maxFiles int
+	maxAge   time.Duration
+}
+
+// openRotatingFile opens path for appending, creating it if needed
+func openRotatingFile(path string) (*rotatingFile, error) {
+	r := &rotatingFile{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first when p would take the file past maxSize
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file to path.1 and starts a new one
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	if r.maxFiles <= 0 {
+		_ = os.Remove(r.path)
+	} else {
+		_ = os.Remove(r.rotatedPath(r.maxFiles))
+		for i := r.maxFiles - 1; i >= 1; i-- {
+			_ = os.Rename(r.rotatedPath(i), r.rotatedPath(i+1))
+		}
+		_ = os.Rename(r.path, r.rotatedPath(1))
+	}
+
+	if r.maxAge > 0 {
+		for i := 1; i <= r.maxFiles; i++ {
+			info, err := os.Stat(r.rotatedPath(i))
+			if err == nil && time.Since(info.ModTime()) > r.maxAge {
+				_ = os.Remove(r.rotatedPath(i))
+			}
+		}
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) rotatedPath(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
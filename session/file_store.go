@@ -0,0 +1,157 @@
+// Package session persists TmuxAI chat sessions so they can be resumed.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a saved chat message
+type Message struct {
+	Content   string    `json:"content"`
+	Request   string    `json:"request,omitempty"`
+	FromUser  bool      `json:"from_user"`
+	Typed     bool      `json:"typed,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ExecRecord is a saved command from a prepared exec pane
+type ExecRecord struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	Code    int    `json:"code"`
+}
+
+// Session is everything needed to rehydrate a manager
+type Session struct {
+	ID               string          `json:"id"`
+	Title            string          `json:"title,omitempty"` // first user request
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Messages         []Message       `json:"messages"`
+	ExecHistory      []ExecRecord    `json:"exec_history,omitempty"`
+	LoadedKBs        []string        `json:"loaded_kbs,omitempty"`
+	SessionOverrides map[string]any  `json:"session_overrides,omitempty"`
+	Summary          *SessionSummary `json:"summary,omitempty"`
+}
+
+// SessionSummary is a generated name and description for a session
+type SessionSummary struct {
+	Name         string    `json:"name"`
+	Summary      string    `json:"summary"`
+	MessageCount int       `json:"message_count"` // messages in the session when generated
+	GeneratedAt  time.Time `json:"generated_at"`
+}
+
+// NewID returns an ID for a session started now: a Unix timestamp in
+// milliseconds, so instances started in the same second don't collide
+func NewID() string {
+	return strconv.FormatInt(time.Now().UnixMilli(), 10)
+}
+
+// FileStore keeps one JSON file per session in a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes a session, replacing any earlier save with the same ID
+func (s *FileStore) Save(sess *Session) error {
+	if sess.ID == "" || strings.ContainsAny(sess.ID, `/\`) {
+		return fmt.Errorf("invalid session id %q", sess.ID)
+	}
+	sess.UpdatedAt = time.Now()
+	if sess.CreatedAt.IsZero() {
+		sess.CreatedAt = sess.UpdatedAt
+	}
+
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// write to a temp file first so a crash never leaves a truncated session
+	tmp := s.path(sess.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(sess.ID)); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// Load reads a saved session by ID
+func (s *FileStore) Load(id string) (*Session, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid session id %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return &sess, nil
+}
+
+// List returns all saved sessions, most recently updated first
+func (s *FileStore) List() ([]*Session, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var sessions []*Session
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		sess, err := s.Load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// Latest returns the most recently updated session
+func (s *FileStore) Latest() (*Session, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("no saved sessions")
+	}
+	return sessions[0], nil
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreSaveLoad(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+	require.NoError(t, err)
+
+	sess := &Session{
+		ID:               "1700000000",
+		Messages:         []Message{{Content: "hi", FromUser: true, Timestamp: time.Unix(1700000000, 0)}},
+		ExecHistory:      []ExecRecord{{Command: "ls", Output: "a", Code: 0}},
+		LoadedKBs:        []string{"docker"},
+		SessionOverrides: map[string]any{"max_capture_lines": 50},
+	}
+	require.NoError(t, store.Save(sess))
+	assert.False(t, sess.CreatedAt.IsZero())
+
+	loaded, err := store.Load("1700000000")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", loaded.Messages[0].Content)
+	assert.Equal(t, []string{"docker"}, loaded.LoadedKBs)
+	assert.Equal(t, float64(50), loaded.SessionOverrides["max_capture_lines"])
+
+	_, err = store.Load("missing")
+	assert.ErrorContains(t, err, "not found")
+	_, err = store.Load("../config")
+	assert.ErrorContains(t, err, "invalid session id")
+}
+
+func TestFileStoreListNewestFirst(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	_, err = store.Latest()
+	assert.Error(t, err)
+
+	require.NoError(t, store.Save(&Session{ID: "1"}))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, store.Save(&Session{ID: "2"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600))
+
+	sessions, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2, "unreadable files are skipped")
+	assert.Equal(t, "2", sessions[0].ID)
+
+	latest, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, "2", latest.ID)
+}
@@ -0,0 +1,75 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// clipboardGOOS, clipboardLookPath and clipboardRun are replaced in tests
+var (
+	clipboardGOOS     = runtime.GOOS
+	clipboardLookPath = exec.LookPath
+	clipboardRun      = func(input string, name string, args ...string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(input)
+		return cmd.Run()
+	}
+	// clipboardTmux loads text into a tmux buffer and, with -w, sends it to
+	// the terminal's clipboard as OSC 52
+	clipboardTmux = func(input string) error {
+		cmd := tmuxCommand("load-buffer", "-w", "-")
+		cmd.Stdin = strings.NewReader(input)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("tmux load-buffer failed: %w, stderr: %s", err, stderr.String())
+		}
+		return nil
+	}
+)
+
+// clipboardCommands are the clipboard tools tried on each OS, in order
+func clipboardCommands(goos string) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+	return [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		// WSL
+		{"clip.exe"},
+	}
+}
+
+// CopyToClipboard puts text on the system clipboard with the first clipboard
+// tool that works. Without one, as over SSH, the text goes to a tmux buffer
+// and on to the terminal's clipboard through OSC 52. It returns what the
+// text was copied with.
+func CopyToClipboard(text string) (string, error) {
+	var errs []error
+	for _, command := range clipboardCommands(clipboardGOOS) {
+		if _, err := clipboardLookPath(command[0]); err != nil {
+			continue
+		}
+		if err := clipboardRun(text, command[0], command[1:]...); err != nil {
+			logger.Debug("Copying with %s failed: %v", command[0], err)
+			errs = append(errs, fmt.Errorf("%s: %w", command[0], err))
+			continue
+		}
+		return command[0], nil
+	}
+	if err := clipboardTmux(text); err != nil {
+		errs = append(errs, err)
+		return "", fmt.Errorf("failed to copy to the clipboard: %w", errors.Join(errs...))
+	}
+	return "OSC 52", nil
+}
@@ -0,0 +1,57 @@
+package system
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	goos, lookPath, run, tmux := clipboardGOOS, clipboardLookPath, clipboardRun, clipboardTmux
+	defer func() { clipboardGOOS, clipboardLookPath, clipboardRun, clipboardTmux = goos, lookPath, run, tmux }()
+
+	installed := map[string]bool{"xclip": true, "xsel": true}
+	clipboardLookPath = func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	var ran []string
+	var copied string
+	clipboardRun = func(input string, name string, args ...string) error {
+		if name == "xclip" {
+			return errors.New("no display")
+		}
+		ran, copied = append([]string{name}, args...), input
+		return nil
+	}
+	var buffered string
+	clipboardTmux = func(input string) error {
+		buffered = input
+		return nil
+	}
+
+	clipboardGOOS = "linux"
+	method, err := CopyToClipboard("make test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"xsel", "--clipboard", "--input"}; method != "xsel" || !reflect.DeepEqual(ran, want) || copied != "make test" {
+		t.Errorf("copied %q with %q (%s), want xsel after xclip failed", copied, ran, method)
+	}
+
+	installed = map[string]bool{}
+	method, err = CopyToClipboard("over ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != "OSC 52" || buffered != "over ssh" {
+		t.Errorf("copied %q with %s, want the tmux buffer without a clipboard tool", buffered, method)
+	}
+
+	clipboardTmux = func(string) error { return errors.New("no server") }
+	if _, err := CopyToClipboard("lost"); err == nil {
+		t.Error("expected an error when nothing can copy")
+	}
+}
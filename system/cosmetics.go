@@ -51,11 +51,7 @@ func Cosmetics(message string) string {
 
 // processInlineCode finds inline code (single backticks) and applies ANSI formatting.
 func processInlineCode(text string, inlineCodeRe *regexp.Regexp) string {
-	const (
-		bgDarkBlue = "\033[48;5;235m"
-		fgCyan     = "\033[38;5;51m"
-		reset      = "\033[0m"
-	)
+	codeColor := CurrentTheme().InlineCode
 	result := ""
 	lastIndex := 0
 	matches := inlineCodeRe.FindAllStringSubmatchIndex(text, -1)
@@ -66,7 +62,7 @@ func processInlineCode(text string, inlineCodeRe *regexp.Regexp) string {
 		result += text[lastIndex:start]
 		// Add formatted inline code
 		code := text[codeStart:codeEnd]
-		result += bgDarkBlue + fgCyan + code + reset
+		result += codeColor.Sprint(code)
 		lastIndex = end
 	}
 	// Add any remaining text
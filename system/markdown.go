@@ -0,0 +1,69 @@
+package system
+
+import (
+	"os"
+	"strings"
+
+	"github.com/alvinunreal/tmuxai/logger"
+	"github.com/charmbracelet/glamour"
+	"golang.org/x/term"
+)
+
+// markdownWidth is the wrap width when the terminal's width can't be read
+const markdownWidth = 80
+
+// PlainTerminal reports whether stdout can't show colors or styling: it is
+// not a terminal, TERM is dumb or NO_COLOR is set
+func PlainTerminal() bool {
+	if os.Getenv("TERM") == "dumb" || os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	return !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// RenderMarkdown renders a message's headings, lists, tables and code
+// blocks for the terminal, wrapped to width (0 for the terminal's width).
+// The style comes from GLAMOUR_STYLE, or else the theme.
+func RenderMarkdown(message string, width int) (string, error) {
+	if width <= 0 {
+		width = markdownWidth
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+	style := os.Getenv("GLAMOUR_STYLE")
+	if style == "" {
+		style = CurrentTheme().MarkdownStyle
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStylePath(style),
+		glamour.WithWordWrap(width),
+		glamour.WithEmoji(),
+	)
+	if err != nil {
+		return "", err
+	}
+	out, err := renderer.Render(message)
+	if err != nil {
+		return "", err
+	}
+	// glamour pads the output with blank lines
+	return strings.Trim(out, "\n"), nil
+}
+
+// FormatMessage prepares an AI message for printing: rendered as Markdown
+// when asked and the terminal can show it, highlighted with Cosmetics if
+// rendering fails or is off, and left as-is on a plain terminal
+func FormatMessage(message string, markdown bool) string {
+	if PlainTerminal() {
+		return message
+	}
+	if markdown {
+		rendered, err := RenderMarkdown(message, 0)
+		if err == nil {
+			return rendered
+		}
+		logger.Debug("Failed to render Markdown, falling back to plain output: %v", err)
+	}
+	return Cosmetics(message)
+}
@@ -0,0 +1,30 @@
+package system
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Setenv("GLAMOUR_STYLE", "notty")
+	out, err := RenderMarkdown("# Fix\n\n- run `make`\n- check the log\n\n| a | b |\n|---|---|\n| 1 | 2 |\n", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Fix", "• run make", "check the log", "| b"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered %q, want it to contain %q", out, want)
+		}
+	}
+	if strings.HasPrefix(out, "\n") || strings.HasSuffix(out, "\n") {
+		t.Errorf("rendered %q, want no padding lines", out)
+	}
+}
+
+func TestFormatMessagePlainTerminal(t *testing.T) {
+	t.Setenv("TERM", "dumb")
+	msg := "# Fix\n\n```sh\nmake\n```"
+	if out := FormatMessage(msg, true); out != msg {
+		t.Errorf("formatted %q for a dumb terminal, want the message as-is", out)
+	}
+}
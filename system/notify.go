@@ -0,0 +1,51 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// notifyGOOS, notifyLookPath and notifyRun are replaced in tests
+var (
+	notifyGOOS     = runtime.GOOS
+	notifyLookPath = exec.LookPath
+	notifyRun      = func(name string, args ...string) error { return exec.Command(name, args...).Run() }
+)
+
+// Notify shows a desktop notification with notify-send on Linux or
+// osascript on macOS, ringing the terminal bell when neither works so tmux
+// can still flag the window
+func Notify(title, message string) {
+	if err := desktopNotify(title, message); err != nil {
+		logger.Debug("Desktop notification failed, ringing the bell: %v", err)
+		fmt.Fprint(os.Stdout, "\a")
+	}
+}
+
+func desktopNotify(title, message string) error {
+	var name string
+	var args []string
+	switch notifyGOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		name, args = "notify-send", []string{"--app-name=TmuxAI", title, message}
+	case "darwin":
+		// passed as arguments so quotes in the message can't break the script
+		name, args = "osascript", []string{
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message,
+		}
+	default:
+		return errors.New("no desktop notifier on " + notifyGOOS)
+	}
+	if _, err := notifyLookPath(name); err != nil {
+		return err
+	}
+	return notifyRun(name, args...)
+}
@@ -0,0 +1,43 @@
+package system
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDesktopNotify(t *testing.T) {
+	goos, lookPath, run := notifyGOOS, notifyLookPath, notifyRun
+	defer func() { notifyGOOS, notifyLookPath, notifyRun = goos, lookPath, run }()
+	var ran []string
+	notifyLookPath = func(name string) (string, error) { return "/usr/bin/" + name, nil }
+	notifyRun = func(name string, args ...string) error {
+		ran = append([]string{name}, args...)
+		return nil
+	}
+
+	notifyGOOS = "linux"
+	if err := desktopNotify("TmuxAI", "disk full"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"notify-send", "--app-name=TmuxAI", "TmuxAI", "disk full"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+
+	notifyGOOS = "darwin"
+	if err := desktopNotify("TmuxAI", `say "hi"`); err != nil {
+		t.Fatal(err)
+	}
+	if ran[0] != "osascript" || ran[len(ran)-1] != `say "hi"` {
+		t.Errorf("ran %q, want osascript with the message as an argument", ran)
+	}
+
+	notifyLookPath = func(name string) (string, error) { return "", errors.New("not found") }
+	if err := desktopNotify("TmuxAI", "disk full"); err == nil {
+		t.Error("expected an error when the notifier isn't installed")
+	}
+	notifyGOOS = "windows"
+	if err := desktopNotify("TmuxAI", "disk full"); err == nil {
+		t.Error("expected an error without a notifier")
+	}
+}
@@ -0,0 +1,226 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Theme holds the colors of the CLI: the prompt, status symbols, risk
+// badges and code
+type Theme struct {
+	Prompt      *color.Color // the TmuxAI name in the prompt
+	Arrow       *color.Color // the » after the prompt
+	State       *color.Color // status symbols like [▶] and [∞]
+	Model       *color.Color // the model badge
+	Confirm     *color.Color // confirmation questions
+	RiskSafe    *color.Color
+	RiskUnknown *color.Color
+	RiskDanger  *color.Color
+	InlineCode  *color.Color
+	// chroma style of code blocks, "" leaves them uncolored
+	CodeStyle string
+	// glamour style of rendered Markdown
+	MarkdownStyle string
+}
+
+// themePresets are the named themes; theme.colors overrides single colors
+var themePresets = map[string]map[string]string{
+	"default": {
+		"prompt":         "green bold",
+		"arrow":          "yellow bold",
+		"state":          "magenta bold",
+		"model":          "cyan bold",
+		"confirm":        "cyan bold",
+		"risk_safe":      "green bold",
+		"risk_unknown":   "yellow bold",
+		"risk_danger":    "red bold",
+		"inline_code":    "51 on-235",
+		"code_style":     "monokai",
+		"markdown_style": "dark",
+	},
+	// for terminals with a light background
+	"light": {
+		"prompt":         "green bold",
+		"arrow":          "blue bold",
+		"state":          "magenta bold",
+		"model":          "blue bold",
+		"confirm":        "blue bold",
+		"risk_safe":      "green bold",
+		"risk_unknown":   "130 bold",
+		"risk_danger":    "red bold",
+		"inline_code":    "24 on-254",
+		"code_style":     "github",
+		"markdown_style": "light",
+	},
+	"none": {
+		"prompt":         "none",
+		"arrow":          "none",
+		"state":          "none",
+		"model":          "none",
+		"confirm":        "none",
+		"risk_safe":      "none",
+		"risk_unknown":   "none",
+		"risk_danger":    "none",
+		"inline_code":    "none",
+		"code_style":     "none",
+		"markdown_style": "notty",
+	},
+}
+
+var currentTheme, _ = NewTheme("default", nil)
+
+// CurrentTheme returns the theme set with SetTheme, the default one
+// until then
+func CurrentTheme() *Theme {
+	return currentTheme
+}
+
+// SetTheme switches the CLI to a theme
+func SetTheme(preset string, colors map[string]string) error {
+	theme, err := NewTheme(preset, colors)
+	if err != nil {
+		return err
+	}
+	currentTheme = theme
+	return nil
+}
+
+// ThemePresets lists the named themes
+func ThemePresets() []string {
+	names := make([]string, 0, len(themePresets))
+	for name := range themePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTheme builds a preset theme ("" for the default one), with colors
+// overriding single entries of it, e.g. {"prompt": "#ff8800 bold"}
+func NewTheme(preset string, colors map[string]string) (*Theme, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	base, ok := themePresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme '%s', use one of %s", preset, strings.Join(ThemePresets(), ", "))
+	}
+	specs := make(map[string]string, len(base))
+	for key, spec := range base {
+		specs[key] = spec
+	}
+	for key, spec := range colors {
+		if _, ok := specs[key]; !ok {
+			return nil, fmt.Errorf("unknown theme color '%s'", key)
+		}
+		specs[key] = spec
+	}
+
+	theme := &Theme{CodeStyle: specs["code_style"], MarkdownStyle: specs["markdown_style"]}
+	if theme.CodeStyle == "none" {
+		theme.CodeStyle = ""
+	}
+	for key, target := range map[string]**color.Color{
+		"prompt":       &theme.Prompt,
+		"arrow":        &theme.Arrow,
+		"state":        &theme.State,
+		"model":        &theme.Model,
+		"confirm":      &theme.Confirm,
+		"risk_safe":    &theme.RiskSafe,
+		"risk_unknown": &theme.RiskUnknown,
+		"risk_danger":  &theme.RiskDanger,
+		"inline_code":  &theme.InlineCode,
+	} {
+		c, err := ParseColor(specs[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid theme color %s: %w", key, err)
+		}
+		*target = c
+	}
+	return theme, nil
+}
+
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+var colorStyles = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+}
+
+// ParseColor reads a color like "green bold", "hi-cyan", "#ff8800",
+// "208 on-235" (256-color codes) or "none". A color prefixed with on- is
+// the background.
+func ParseColor(spec string) (*color.Color, error) {
+	c := color.New()
+	words := strings.Fields(strings.ToLower(spec))
+	if len(words) == 1 && words[0] == "none" {
+		c.DisableColor()
+		return c, nil
+	}
+	for _, word := range words {
+		if attr, ok := colorStyles[word]; ok {
+			c.Add(attr)
+			continue
+		}
+		name, background := strings.CutPrefix(word, "on-")
+		if err := addColor(c, name, background); err != nil {
+			return nil, fmt.Errorf("'%s': %w", spec, err)
+		}
+	}
+	return c, nil
+}
+
+func addColor(c *color.Color, name string, background bool) error {
+	if hex, ok := strings.CutPrefix(name, "#"); ok {
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return fmt.Errorf("invalid hex color #%s", hex)
+		}
+		r, g, b := int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)
+		if background {
+			c.AddBgRGB(r, g, b)
+		} else {
+			c.AddRGB(r, g, b)
+		}
+		return nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 0 || n > 255 {
+			return fmt.Errorf("256-color code %d out of range", n)
+		}
+		if background {
+			c.Add(48, 5, color.Attribute(n))
+		} else {
+			c.Add(38, 5, color.Attribute(n))
+		}
+		return nil
+	}
+	base, bright := strings.CutPrefix(name, "hi-")
+	attr, ok := colorNames[base]
+	if !ok {
+		return fmt.Errorf("unknown color %s", name)
+	}
+	if bright {
+		attr += color.FgHiBlack - color.FgBlack
+	}
+	if background {
+		attr += color.BgBlack - color.FgBlack
+	}
+	c.Add(attr)
+	return nil
+}
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseColor(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+	color.NoColor = false
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"green bold", "\x1b[32;1mx"},
+		{"hi-cyan", "\x1b[96mx"},
+		{"on-red", "\x1b[41mx"},
+		{"#ff8800", "\x1b[38;2;255;136;0mx"},
+		{"208 on-235", "\x1b[38;5;208;48;5;235mx"},
+		{"none", "x"},
+	}
+	for _, tt := range tests {
+		c, err := ParseColor(tt.spec)
+		if err != nil {
+			t.Errorf("ParseColor(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := c.Sprint("x"); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("ParseColor(%q) printed %q, want it to start with %q", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"purple", "#ff88", "300"} {
+		if _, err := ParseColor(spec); err == nil {
+			t.Errorf("ParseColor(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer func() { currentTheme, _ = NewTheme("default", nil) }()
+
+	if err := SetTheme("light", map[string]string{"prompt": "blue", "code_style": "none"}); err != nil {
+		t.Fatal(err)
+	}
+	theme := CurrentTheme()
+	if theme.CodeStyle != "" || theme.MarkdownStyle != "light" {
+		t.Errorf("got code style %q and markdown style %q, want none and light", theme.CodeStyle, theme.MarkdownStyle)
+	}
+	if code, err := HighlightCode("sh", "make test"); err != nil || code != "make test" {
+		t.Errorf("highlighted %q (%v), want code left uncolored", code, err)
+	}
+
+	if err := SetTheme("solarized", nil); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+	if err := SetTheme("", map[string]string{"prompt_color": "red"}); err == nil {
+		t.Error("expected an error for an unknown color")
+	}
+	if CurrentTheme() != theme {
+		t.Error("a failed SetTheme must keep the current theme")
+	}
+}
@@ -2,23 +2,25 @@ package system
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alvinunreal/tmuxai/logger"
 )
 
 // TmuxCreateNewPane creates a new split pane in the specified window and returns its ID.
-func TmuxCreateNewPane(target string, splitArgs []string) (string, error) {
+var TmuxCreateNewPane = func(target string, splitArgs []string) (string, error) {
 	args, err := buildSplitWindowArgs(target, splitArgs)
 	if err != nil {
 		return "", err
 	}
 
-	cmd := exec.Command("tmux", args...)
+	cmd := tmuxCommand(args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -78,7 +80,8 @@ func buildSplitWindowArgs(target string, splitArgs []string) ([]string, error) {
 
 // TmuxPanesDetails gets details for all panes in a target window
 var TmuxPanesDetails = func(target string) ([]TmuxPaneDetails, error) {
-	cmd := exec.Command("tmux", "list-panes", "-t", target, "-F", "#{pane_id},#{pane_active},#{pane_pid},#{pane_current_command},#{history_size},#{history_limit}")
+	// pane_current_path comes last since it may contain commas
+	cmd := tmuxCommand("list-panes", "-t", target, "-F", "#{pane_id},#{pane_active},#{pane_pid},#{pane_current_command},#{history_size},#{history_limit},#{pane_current_path}")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -103,8 +106,8 @@ var TmuxPanesDetails = func(target string) ([]TmuxPaneDetails, error) {
 			continue
 		}
 
-		parts := strings.SplitN(line, ",", 6)
-		if len(parts) < 5 {
+		parts := strings.SplitN(line, ",", 7)
+		if len(parts) < 6 {
 			logger.Error("Invalid pane details format for line: %s", line)
 			continue
 		}
@@ -120,8 +123,16 @@ var TmuxPanesDetails = func(target string) ([]TmuxPaneDetails, error) {
 		pid, _ := strconv.Atoi(parts[2])
 		historySize, _ := strconv.Atoi(parts[4])
 		historyLimit, _ := strconv.Atoi(parts[5])
-		currentCommandArgs := GetProcessArgs(pid)
+		// Remote pids can't be inspected locally
+		currentCommandArgs := ""
+		if !TmuxIsRemote() {
+			currentCommandArgs = GetProcessArgs(pid)
+		}
 		isSubShell := IsSubShell(parts[3])
+		currentPath := ""
+		if len(parts) == 7 {
+			currentPath = parts[6]
+		}
 
 		paneDetail := TmuxPaneDetails{
 			Id:                 id,
@@ -132,6 +143,7 @@ var TmuxPanesDetails = func(target string) ([]TmuxPaneDetails, error) {
 			HistorySize:        historySize,
 			HistoryLimit:       historyLimit,
 			IsSubShell:         isSubShell,
+			CurrentPath:        currentPath,
 		}
 
 		paneDetails = append(paneDetails, paneDetail)
@@ -140,9 +152,26 @@ var TmuxPanesDetails = func(target string) ([]TmuxPaneDetails, error) {
 	return paneDetails, nil
 }
 
+// CapturePaneTimeout bounds a single capture-pane, so a hung SSH connection
+// or a busy tmux server can't stall an agent iteration
+var CapturePaneTimeout = 5 * time.Second
+
 // TmuxCapturePane gets the content of a specific pane by ID
 var TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-t", paneId, "-S", fmt.Sprintf("-%d", maxLines))
+	return tmuxCapturePane(paneId, maxLines)
+}
+
+// TmuxCapturePaneEscapes captures a pane like TmuxCapturePane, keeping the
+// escape sequences for colors and text attributes (capture-pane -e)
+var TmuxCapturePaneEscapes = func(paneId string, maxLines int) (string, error) {
+	return tmuxCapturePane(paneId, maxLines, "-e")
+}
+
+func tmuxCapturePane(paneId string, maxLines int, flags ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), CapturePaneTimeout)
+	defer cancel()
+	args := append([]string{"capture-pane", "-p"}, flags...)
+	cmd := tmuxCommandContext(ctx, append(args, "-t", paneId, "-S", fmt.Sprintf("-%d", maxLines))...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -159,6 +188,10 @@ var TmuxCapturePane = func(paneId string, maxLines int) (string, error) {
 
 // Return current tmux window target with session id and window id
 var TmuxCurrentWindowTarget = func() (string, error) {
+	if TmuxIsRemote() {
+		return tmuxRemoteWindowTarget()
+	}
+
 	paneId, err := TmuxCurrentPaneId()
 	if err != nil {
 		return "", err
@@ -182,6 +215,90 @@ var TmuxCurrentWindowTarget = func() (string, error) {
 	return target, nil
 }
 
+// TmuxSessionWindows lists all windows of the session containing the current pane
+var TmuxSessionWindows = func() ([]TmuxWindowDetails, error) {
+	target, err := TmuxCurrentPaneId()
+	if TmuxIsRemote() {
+		target, err = TmuxCurrentWindowTarget()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := tmuxCommand("list-windows", "-t", target, "-F", "#{session_id}:#{window_index},#{window_index},#{window_active},#{window_name}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session windows: %w", err)
+	}
+
+	var windows []TmuxWindowDetails
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ",", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		index, _ := strconv.Atoi(parts[1])
+		windows = append(windows, TmuxWindowDetails{
+			Target:   parts[0],
+			Index:    index,
+			Name:     parts[3],
+			IsActive: parts[2] == "1",
+		})
+	}
+	return windows, nil
+}
+
+// TmuxResolvePaneId resolves any tmux target (e.g. "mysession:1.2") to its pane ID
+var TmuxResolvePaneId = func(target string) (string, error) {
+	cmd := tmuxCommand("display-message", "-p", "-t", target, "#{pane_id}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to resolve tmux target %s: %s", target, strings.TrimSpace(stderr.String()))
+	}
+
+	paneId := strings.TrimSpace(stdout.String())
+	if paneId == "" {
+		return "", fmt.Errorf("no pane found for tmux target %s", target)
+	}
+	return paneId, nil
+}
+
+// TmuxVersion returns the version of the observed tmux server's tmux
+// binary, e.g. "3.3a"
+var TmuxVersion = func() (string, error) {
+	cmd := tmuxCommand("-V")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(stdout.String()), "tmux "), nil
+}
+
+// TmuxActivePaneId returns the active pane of the current tmux client, the
+// pane a display-popup was opened over
+var TmuxActivePaneId = func() (string, error) {
+	cmd := exec.Command("tmux", "display-message", "-p", "#{pane_id}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to get the active pane: %s", strings.TrimSpace(stderr.String()))
+	}
+	paneId := strings.TrimSpace(stdout.String())
+	if paneId == "" {
+		return "", fmt.Errorf("no active pane found")
+	}
+	return paneId, nil
+}
+
 var TmuxCurrentPaneId = func() (string, error) {
 	tmuxPane := os.Getenv("TMUX_PANE")
 	if tmuxPane == "" {
@@ -218,15 +335,18 @@ func TmuxAttachSession(paneId string) error {
 	return nil
 }
 
+// TmuxClearPane clears a pane on the local tmux server
 func TmuxClearPane(paneId string) error {
-	paneDetails, err := TmuxPanesDetails(paneId)
-	if err != nil {
-		logger.Error("Failed to get pane details for %s: %v", paneId, err)
-		return err
-	}
+	if !TmuxIsRemote() {
+		paneDetails, err := TmuxPanesDetails(paneId)
+		if err != nil {
+			logger.Error("Failed to get pane details for %s: %v", paneId, err)
+			return err
+		}
 
-	if len(paneDetails) == 0 {
-		return fmt.Errorf("no pane details found for pane %s", paneId)
+		if len(paneDetails) == 0 {
+			return fmt.Errorf("no pane details found for pane %s", paneId)
+		}
 	}
 
 	cmd := exec.Command("tmux", "split-window", "-vp", "100", "-t", paneId)
@@ -250,3 +370,98 @@ func TmuxClearPane(paneId string) error {
 	logger.Debug("Successfully cleared pane %s", paneId)
 	return nil
 }
+
+// TmuxKillPane closes a pane and ends whatever runs in it
+var TmuxKillPane = func(paneId string) error {
+	cmd := tmuxCommand("kill-pane", "-t", paneId)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to kill pane %s: %s", paneId, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// TmuxPipePane appends everything a pane prints, escape sequences included,
+// to the file at path, replacing any pipe already open. An empty path closes
+// the pipe.
+var TmuxPipePane = func(paneId string, path string) error {
+	args := []string{"pipe-pane", "-t", paneId}
+	if path != "" {
+		args = append(args, "cat >> "+ShellQuote(path))
+	}
+	cmd := tmuxCommand(args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to pipe pane %s: %s", paneId, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// TmuxPaneOption returns a pane option such as a @user option, or "" if unset
+var TmuxPaneOption = func(paneId string, name string) (string, error) {
+	cmd := tmuxCommand("show-options", "-p", "-q", "-v", "-t", paneId, name)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to read option %s of pane %s: %s", name, paneId, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// TmuxCursor is where a pane's cursor is. Line counts from the top of the
+// pane's history, so it stays comparable while output scrolls.
+type TmuxCursor struct {
+	Line  int
+	X     int
+	Width int
+}
+
+// TmuxCursorPosition returns the cursor position of a pane
+var TmuxCursorPosition = func(paneId string) (TmuxCursor, error) {
+	cmd := tmuxCommand("display-message", "-p", "-t", paneId, "#{history_size} #{cursor_y} #{cursor_x} #{pane_width}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return TmuxCursor{}, fmt.Errorf("failed to get cursor of pane %s: %s", paneId, strings.TrimSpace(stderr.String()))
+	}
+	var historySize, cursorY int
+	var cursor TmuxCursor
+	if _, err := fmt.Sscan(stdout.String(), &historySize, &cursorY, &cursor.X, &cursor.Width); err != nil {
+		return TmuxCursor{}, fmt.Errorf("failed to parse cursor of pane %s: %w", paneId, err)
+	}
+	cursor.Line = historySize + cursorY
+	return cursor, nil
+}
+
+// TmuxCaptureLines captures the lines from..to of a pane, counted from the
+// top of its history like TmuxCursor.Line
+var TmuxCaptureLines = func(paneId string, from, to int) (string, error) {
+	if to < from {
+		return "", nil
+	}
+	cmd := tmuxCommand("display-message", "-p", "-t", paneId, "#{history_size}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to get history size of pane %s: %s", paneId, strings.TrimSpace(stderr.String()))
+	}
+	historySize, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse history size of pane %s: %w", paneId, err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	cmd = tmuxCommand("capture-pane", "-p", "-t", paneId, "-S", strconv.Itoa(from-historySize), "-E", strconv.Itoa(to-historySize))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to capture pane %s: %s", paneId, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
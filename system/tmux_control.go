@@ -0,0 +1,244 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alvinunreal/tmuxai/logger"
+)
+
+// tmuxControlAttachTimeout is how long the control client may take to
+// answer its attach-session
+const tmuxControlAttachTimeout = 3 * time.Second
+
+// TmuxControl is a read-only tmux control mode client (tmux -C) attached to
+// one session. Its %output notifications tell when a pane printed, so callers
+// can wait for output instead of polling capture-pane.
+type TmuxControl struct {
+	SessionID string
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	mu      sync.Mutex
+	changed map[string]chan struct{} // closed on the pane's next output, "" for any pane
+	covers  map[string]bool          // whether a pane is in the session
+	done    chan struct{}            // closed once the client exits
+}
+
+// TmuxStartControl attaches a control mode client to the session of target.
+// It needs tmux 3.2 for the ignore-size and read-only client flags.
+var TmuxStartControl = func(target string) (*TmuxControl, error) {
+	sessionID, err := tmuxPaneSession(target)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := tmuxCommand("-C", "attach-session", "-f", "ignore-size,read-only", "-t", sessionID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tmux control client: %w", err)
+	}
+
+	c := &TmuxControl{
+		SessionID: sessionID,
+		cmd:       cmd,
+		stdin:     stdin,
+		changed:   make(map[string]chan struct{}),
+		covers:    make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+	attached := make(chan error, 1)
+	go c.read(stdout, attached)
+
+	select {
+	case err = <-attached:
+	case <-time.After(tmuxControlAttachTimeout):
+		err = fmt.Errorf("no answer to attach-session")
+	}
+	if err != nil {
+		c.Close()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("tmux control client failed to attach to %s: %w", sessionID, err)
+	}
+	logger.Info("tmux control client attached to session %s", sessionID)
+	return c, nil
+}
+
+// read dispatches the client's notifications until it exits. The reply to
+// attach-session, the first %begin block, is reported to attached.
+func (c *TmuxControl) read(stdout io.Reader, attached chan<- error) {
+	defer c.exit()
+
+	reader := bufio.NewReader(stdout)
+	inBlock, answered := false, false
+	var reply []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			event, paneID := parseControlLine(line)
+			switch {
+			case event == "begin":
+				inBlock = true
+			case event == "end" || event == "error":
+				inBlock = false
+				if !answered {
+					answered = true
+					if event == "error" {
+						attached <- fmt.Errorf("%s", strings.Join(reply, "; "))
+					} else {
+						attached <- nil
+					}
+				}
+			case inBlock:
+				if !answered {
+					reply = append(reply, line)
+				}
+			case event == "output":
+				c.signal(paneID)
+			case event == "exit":
+				logger.Info("tmux control client for session %s exited", c.SessionID)
+			}
+		}
+		if err != nil {
+			if !answered {
+				attached <- fmt.Errorf("client exited")
+			}
+			return
+		}
+	}
+}
+
+// parseControlLine returns the notification a control mode line carries,
+// without its leading %, and the pane it's about for %output
+func parseControlLine(line string) (event, paneID string) {
+	if !strings.HasPrefix(line, "%") {
+		return "", ""
+	}
+	fields := strings.SplitN(line, " ", 3)
+	event = strings.TrimPrefix(fields[0], "%")
+	if event == "extended-output" {
+		event = "output"
+	}
+	if event == "output" && len(fields) > 1 {
+		paneID = fields[1]
+	}
+	return event, paneID
+}
+
+// signal wakes whoever waits for paneID's output or any pane's
+func (c *TmuxControl) signal(paneID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []string{paneID, ""} {
+		if ch, ok := c.changed[key]; ok {
+			close(ch)
+			delete(c.changed, key)
+		}
+	}
+}
+
+// exit marks the client gone and wakes every waiter
+func (c *TmuxControl) exit() {
+	_ = c.cmd.Wait()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, ch := range c.changed {
+		close(ch)
+		delete(c.changed, key)
+	}
+	close(c.done)
+}
+
+// Changed returns a channel closed the next time paneID prints, or any pane
+// of the session when paneID is "". It's closed right away once the client
+// has exited, so callers fall back to polling.
+func (c *TmuxControl) Changed(paneID string) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Exited() {
+		return c.done
+	}
+	ch, ok := c.changed[paneID]
+	if !ok {
+		ch = make(chan struct{})
+		c.changed[paneID] = ch
+	}
+	return ch
+}
+
+// Exited reports whether the client is gone, e.g. after its session closed
+func (c *TmuxControl) Exited() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Covers reports whether paneID is in the client's session, the only panes
+// tmux sends it output for
+func (c *TmuxControl) Covers(paneID string) bool {
+	c.mu.Lock()
+	covered, ok := c.covers[paneID]
+	c.mu.Unlock()
+	if ok {
+		return covered
+	}
+
+	sessionID, err := tmuxPaneSession(paneID)
+	if err != nil {
+		logger.Error("Failed to get session of pane %s: %v", paneID, err)
+		return false
+	}
+	covered = sessionID == c.SessionID
+	c.mu.Lock()
+	c.covers[paneID] = covered
+	c.mu.Unlock()
+	return covered
+}
+
+// Close detaches the client, which exits when its stdin closes
+func (c *TmuxControl) Close() {
+	_ = c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		_ = c.cmd.Process.Kill()
+		<-c.done
+	}
+}
+
+// tmuxPaneSession returns the ID of the session a tmux target is in
+func tmuxPaneSession(target string) (string, error) {
+	cmd := tmuxCommand("display-message", "-p", "-t", target, "#{session_id}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to get session of %s: %s", target, strings.TrimSpace(stderr.String()))
+	}
+	sessionID := strings.TrimSpace(stdout.String())
+	if sessionID == "" {
+		return "", fmt.Errorf("no session found for tmux target %s", target)
+	}
+	return sessionID, nil
+}
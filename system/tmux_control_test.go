@@ -0,0 +1,29 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseControlLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		event  string
+		paneID string
+	}{
+		{`%output %3 hi\015\012`, "output", "%3"},
+		{`%extended-output %12 40 : done`, "output", "%12"},
+		{"%begin 1792025150 263 0", "begin", ""},
+		{"%end 1792025150 263 0", "end", ""},
+		{"%error 1792025152 271 0", "error", ""},
+		{"%exit", "exit", ""},
+		{"%session-changed $0 work", "session-changed", ""},
+		{"can't find session: nosuch", "", ""},
+	}
+	for _, tt := range tests {
+		event, paneID := parseControlLine(tt.line)
+		assert.Equal(t, tt.event, event, tt.line)
+		assert.Equal(t, tt.paneID, paneID, tt.line)
+	}
+}
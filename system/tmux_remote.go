@@ -0,0 +1,83 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// tmuxRemote holds the SSH destination when the observed tmux server runs on
+// another host. The TmuxAI chat pane always stays on the local tmux server;
+// pane listing, capture, send-keys and splits go to the remote one.
+var tmuxRemote struct {
+	Host    string
+	SSHArgs []string
+	Target  string
+}
+
+// SetTmuxRemote routes pane operations through `ssh host tmux ...`. target
+// optionally selects the remote session or window to observe.
+func SetTmuxRemote(host string, sshArgs []string, target string) {
+	tmuxRemote.Host = host
+	tmuxRemote.SSHArgs = sshArgs
+	tmuxRemote.Target = target
+}
+
+// TmuxIsRemote reports whether pane operations run on a remote tmux server
+func TmuxIsRemote() bool {
+	return tmuxRemote.Host != ""
+}
+
+// TmuxRemoteHost returns the configured SSH host, empty when running locally
+func TmuxRemoteHost() string {
+	return tmuxRemote.Host
+}
+
+// tmuxCommand builds a tmux command for the observed tmux server
+func tmuxCommand(args ...string) *exec.Cmd {
+	return tmuxCommandContext(context.Background(), args...)
+}
+
+// tmuxCommandContext is tmuxCommand, killed when ctx is done
+func tmuxCommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	if !TmuxIsRemote() {
+		return exec.CommandContext(ctx, "tmux", args...)
+	}
+
+	// ssh joins its arguments into a single remote shell command line
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, "tmux")
+	for _, arg := range args {
+		quoted = append(quoted, ShellQuote(arg))
+	}
+
+	sshArgs := append([]string{}, tmuxRemote.SSHArgs...)
+	sshArgs = append(sshArgs, tmuxRemote.Host, strings.Join(quoted, " "))
+	return exec.CommandContext(ctx, "ssh", sshArgs...)
+}
+
+// tmuxRemoteWindowTarget resolves the configured remote target, or the
+// remote server's current window, to session_id:window_index
+func tmuxRemoteWindowTarget() (string, error) {
+	args := []string{"display-message", "-p"}
+	if tmuxRemote.Target != "" {
+		args = append(args, "-t", tmuxRemote.Target)
+	}
+	args = append(args, "#{session_id}:#{window_index}")
+
+	output, err := tmuxCommand(args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote window target on %s: %w", tmuxRemote.Host, err)
+	}
+	target := strings.TrimSpace(string(output))
+	if target == "" {
+		return "", fmt.Errorf("empty window target returned from %s", tmuxRemote.Host)
+	}
+	return target, nil
+}
+
+// ShellQuote quotes s as a single word for POSIX shells
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
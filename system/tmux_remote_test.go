@@ -0,0 +1,24 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmuxCommandLocal(t *testing.T) {
+	cmd := tmuxCommand("capture-pane", "-p", "-t", "%1")
+	assert.Equal(t, []string{"tmux", "capture-pane", "-p", "-t", "%1"}, cmd.Args)
+}
+
+func TestTmuxCommandRemote(t *testing.T) {
+	SetTmuxRemote("devbox", []string{"-o", "BatchMode=yes"}, "work")
+	defer SetTmuxRemote("", nil, "")
+
+	assert.True(t, TmuxIsRemote())
+	cmd := tmuxCommand("send-keys", "-t", "%3", "-l", "echo 'hi'; ls")
+	assert.Equal(t, []string{
+		"ssh", "-o", "BatchMode=yes", "devbox",
+		`tmux 'send-keys' '-t' '%3' '-l' 'echo '\''hi'\''; ls'`,
+	}, cmd.Args)
+}
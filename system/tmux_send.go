@@ -3,8 +3,10 @@ package system
 import (
 	"bytes"
 	"fmt"
-	"os/exec"
+	"math/rand"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/alvinunreal/tmuxai/logger"
 )
@@ -19,7 +21,7 @@ var TmuxSendCommandToPane = func(paneId string, command string, autoenter bool)
 				if strings.HasSuffix(line, ";") {
 					line = line[:len(line)-1] + "\\;"
 				}
-				cmd := exec.Command("tmux", "send-keys", "-t", paneId, "-l", line)
+				cmd := tmuxCommand("send-keys", "-t", paneId, "-l", line)
 				var stderr bytes.Buffer
 				cmd.Stderr = &stderr
 				err := cmd.Run()
@@ -32,7 +34,7 @@ var TmuxSendCommandToPane = func(paneId string, command string, autoenter bool)
 				args := []string{"send-keys", "-t", paneId}
 				processed := processLineWithSpecialKeys(line)
 				args = append(args, processed...)
-				cmd := exec.Command("tmux", args...)
+				cmd := tmuxCommand(args...)
 				var stderr bytes.Buffer
 				cmd.Stderr = &stderr
 				err := cmd.Run()
@@ -46,7 +48,7 @@ var TmuxSendCommandToPane = func(paneId string, command string, autoenter bool)
 		// Send Enter key after each line except for empty lines at the end
 		if autoenter {
 			if i < len(lines)-1 || (i == len(lines)-1 && line != "") {
-				enterCmd := exec.Command("tmux", "send-keys", "-t", paneId, "Enter")
+				enterCmd := tmuxCommand("send-keys", "-t", paneId, "Enter")
 				err := enterCmd.Run()
 				if err != nil {
 					logger.Error("Failed to send Enter key to pane %s: %v", paneId, err)
@@ -58,6 +60,136 @@ var TmuxSendCommandToPane = func(paneId string, command string, autoenter bool)
 	return nil
 }
 
+// TypingDelays slow down keys sent to a pane, for programs that drop keys
+// sent all at once
+type TypingDelays struct {
+	Key   time.Duration // between keys
+	Line  time.Duration // after each Enter
+	Human bool          // vary Key like a person typing
+}
+
+// humanKeyDelay is the average delay between keys in human mode when no key
+// delay is set
+const humanKeyDelay = 80 * time.Millisecond
+
+// typingRand is replaced in tests
+var typingRand = rand.Float64
+
+// TmuxTypeToPane sends keys like TmuxSendCommandToPane without autoenter,
+// but one key at a time with the given delays in between. Without delays the
+// keys are sent at once.
+var TmuxTypeToPane = func(paneId string, keys string, delays TypingDelays) error {
+	if delays.Key <= 0 && delays.Line <= 0 && !delays.Human {
+		return TmuxSendCommandToPane(paneId, keys, false)
+	}
+	steps := typingSteps(keys)
+	for i, step := range steps {
+		args := []string{"send-keys", "-t", paneId, step.keys}
+		if step.literal {
+			args = []string{"send-keys", "-t", paneId, "-l", step.keys}
+		}
+		cmd := tmuxCommand(args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			logger.Error("Failed to type into pane %s: %v, stderr: %s", paneId, err, stderr.String())
+			return fmt.Errorf("failed to type into pane: %w", err)
+		}
+		if i < len(steps)-1 {
+			time.Sleep(delays.after(step))
+		}
+	}
+	return nil
+}
+
+// typingStep is a single key: a literal character, or a tmux key name like
+// Enter or C-c
+type typingStep struct {
+	keys    string
+	literal bool
+}
+
+// typingSteps splits keys into the single keys TmuxSendCommandToPane would
+// send, lines joined without Enter as it does without autoenter
+func typingSteps(keys string) []typingStep {
+	var steps []typingStep
+	for _, line := range strings.Split(keys, "\n") {
+		parts := []string{line}
+		if containsSpecialKey(line) {
+			parts = processLineWithSpecialKeys(line)
+		}
+		for _, part := range parts {
+			if strings.HasPrefix(part, "C-") || strings.HasPrefix(part, "M-") || getSpecialKeys()[part] {
+				steps = append(steps, typingStep{keys: part})
+				continue
+			}
+			for _, r := range part {
+				key := string(r)
+				if key == ";" {
+					// a lone ; separates tmux commands
+					key = "\\;"
+				}
+				steps = append(steps, typingStep{keys: key, literal: true})
+			}
+		}
+	}
+	return steps
+}
+
+// after returns how long to wait after a key: the line delay after Enter,
+// the key delay otherwise. Human mode varies the key delay between half and
+// one and a half times, and pauses twice as long after a space.
+func (d TypingDelays) after(step typingStep) time.Duration {
+	if !step.literal && (step.keys == "Enter" || step.keys == "C-m") && d.Line > 0 {
+		return d.Line
+	}
+	if !d.Human {
+		return d.Key
+	}
+	delay := d.Key
+	if delay <= 0 {
+		delay = humanKeyDelay
+	}
+	delay = delay/2 + time.Duration(typingRand()*float64(delay))
+	if step.keys == " " {
+		delay *= 2
+	}
+	return delay
+}
+
+// pasteBuffer is the tmux buffer pastes go through, named so a paste never
+// replaces the user's own most recent buffer
+var pasteBuffer = fmt.Sprintf("tmuxai-paste-%d", os.Getpid())
+
+// TmuxPasteToPane pastes content into a pane in one go through a tmux
+// buffer, as a bracketed paste when the program in the pane asks for one,
+// so heredocs and indented REPL input arrive intact. Enter is pressed after
+// the last line, as TmuxSendCommandToPane does with autoenter.
+var TmuxPasteToPane = func(paneId string, content string) error {
+	load := tmuxCommand("load-buffer", "-b", pasteBuffer, "-")
+	load.Stdin = strings.NewReader(strings.TrimSuffix(content, "\n"))
+	var stderr bytes.Buffer
+	load.Stderr = &stderr
+	if err := load.Run(); err != nil {
+		logger.Error("Failed to load paste buffer: %v, stderr: %s", err, stderr.String())
+		return fmt.Errorf("failed to load paste buffer: %w", err)
+	}
+
+	stderr.Reset()
+	paste := tmuxCommand("paste-buffer", "-p", "-d", "-b", pasteBuffer, "-t", paneId)
+	paste.Stderr = &stderr
+	if err := paste.Run(); err != nil {
+		logger.Error("Failed to paste to pane %s: %v, stderr: %s", paneId, err, stderr.String())
+		return fmt.Errorf("failed to paste to pane: %w", err)
+	}
+
+	if err := tmuxCommand("send-keys", "-t", paneId, "Enter").Run(); err != nil {
+		logger.Error("Failed to send Enter key to pane %s: %v", paneId, err)
+		return fmt.Errorf("failed to send Enter key to pane: %w", err)
+	}
+	return nil
+}
+
 // containsSpecialKey checks if a string contains any tmux special key notation
 func containsSpecialKey(line string) bool {
 	// Check for control or meta key combinations
@@ -0,0 +1,38 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypingSteps(t *testing.T) {
+	assert.Equal(t, []typingStep{
+		{keys: ":", literal: true},
+		{keys: "w", literal: true},
+		{keys: `\;`, literal: true},
+		{keys: "Enter"},
+		{keys: "C-c"},
+	}, typingSteps(":w; Enter\nC-c"))
+
+	assert.Equal(t, []typingStep{
+		{keys: "a", literal: true},
+		{keys: "b", literal: true},
+	}, typingSteps("a\nb"), "lines are joined without Enter")
+}
+
+func TestTypingDelaysAfter(t *testing.T) {
+	original := typingRand
+	t.Cleanup(func() { typingRand = original })
+	typingRand = func() float64 { return 0.5 }
+
+	delays := TypingDelays{Key: 20 * time.Millisecond, Line: 300 * time.Millisecond}
+	assert.Equal(t, 20*time.Millisecond, delays.after(typingStep{keys: "x", literal: true}))
+	assert.Equal(t, 300*time.Millisecond, delays.after(typingStep{keys: "Enter"}))
+
+	human := TypingDelays{Human: true}
+	assert.Equal(t, humanKeyDelay, human.after(typingStep{keys: "x", literal: true}))
+	assert.Equal(t, 2*humanKeyDelay, human.after(typingStep{keys: " ", literal: true}), "pauses between words")
+	assert.Equal(t, humanKeyDelay, human.after(typingStep{keys: "Enter"}), "without a line delay Enter is a key like any other")
+}
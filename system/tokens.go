@@ -0,0 +1,100 @@
+package system
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// use the BPE files embedded in the binary instead of downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// Tokenizer counts tokens the way a model family does. Exact is false when
+// the provider's tokenizer isn't public and a close BPE encoding, scaled by
+// Scale, stands in for it.
+type Tokenizer struct {
+	Encoding string
+	Exact    bool
+	Scale    float64
+}
+
+// encodings caches loaded BPE encodings, which take a moment to build
+var (
+	encodings   = map[string]*tiktoken.Tiktoken{}
+	encodingsMu sync.Mutex
+)
+
+func loadEncoding(name string) *tiktoken.Tiktoken {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	// a failed load is cached as nil so it isn't retried on every count
+	enc, _ := tiktoken.GetEncoding(name)
+	encodings[name] = enc
+	return enc
+}
+
+// TokenizerFor picks the tokenizer for a provider and model name. Model names
+// may carry a router prefix such as "openai/" or "anthropic/".
+func TokenizerFor(provider, model string) Tokenizer {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "gpt-4o"), strings.HasPrefix(name, "gpt-4.1"), strings.HasPrefix(name, "gpt-4.5"),
+		strings.HasPrefix(name, "gpt-5"), strings.HasPrefix(name, "chatgpt-4o"), strings.HasPrefix(name, "gpt-oss"),
+		strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "o3"), strings.HasPrefix(name, "o4"):
+		return Tokenizer{Encoding: "o200k_base", Exact: true, Scale: 1}
+	case strings.HasPrefix(name, "gpt-4"), strings.HasPrefix(name, "gpt-3.5"):
+		return Tokenizer{Encoding: "cl100k_base", Exact: true, Scale: 1}
+	case strings.Contains(name, "claude") || provider == "anthropic":
+		// Claude's tokenizer produces roughly 10% more tokens than cl100k
+		return Tokenizer{Encoding: "cl100k_base", Scale: 1.1}
+	case strings.Contains(name, "gemini") || strings.Contains(name, "gemma") || provider == "gemini":
+		// Gemini's large SentencePiece vocabulary tokenizes close to o200k
+		return Tokenizer{Encoding: "o200k_base", Scale: 1}
+	}
+	return Tokenizer{Encoding: "cl100k_base", Scale: 1}
+}
+
+// Count returns the number of tokens in text, falling back to
+// EstimateTokenCount if the encoding can't be loaded
+func (t Tokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	enc := loadEncoding(t.Encoding)
+	if enc == nil {
+		return EstimateTokenCount(text)
+	}
+	count := len(enc.EncodeOrdinary(text))
+	if t.Scale > 0 && t.Scale != 1 {
+		count = int(float64(count)*t.Scale + 0.5)
+	}
+	return count
+}
+
+// Description names the tokenizer for display, e.g. "o200k_base" or
+// "cl100k_base ×1.1, approximate"
+func (t Tokenizer) Description() string {
+	if loadEncoding(t.Encoding) == nil {
+		return "word-based estimate"
+	}
+	desc := t.Encoding
+	if t.Scale > 0 && t.Scale != 1 {
+		desc += " ×" + strconv.FormatFloat(t.Scale, 'f', -1, 64)
+	}
+	if !t.Exact {
+		desc += ", approximate"
+	}
+	return desc
+}
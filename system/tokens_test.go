@@ -0,0 +1,62 @@
+package system
+
+import "testing"
+
+func TestTokenizerFor(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		encoding        string
+		exact           bool
+	}{
+		{"openai", "gpt-4o-mini", "o200k_base", true},
+		{"openrouter", "openai/gpt-5", "o200k_base", true},
+		{"openai", "o3-mini", "o200k_base", true},
+		{"azure", "gpt-4", "cl100k_base", true},
+		{"anthropic", "claude-sonnet-4", "cl100k_base", false},
+		{"openrouter", "anthropic/claude-3.5-sonnet", "cl100k_base", false},
+		{"gemini", "gemini-2.5-flash", "o200k_base", false},
+		{"ollama", "llama3.1:8b", "cl100k_base", false},
+	}
+	for _, tt := range tests {
+		tok := TokenizerFor(tt.provider, tt.model)
+		if tok.Encoding != tt.encoding || tok.Exact != tt.exact {
+			t.Errorf("TokenizerFor(%q, %q) = %+v, want %s exact=%v", tt.provider, tt.model, tok, tt.encoding, tt.exact)
+		}
+	}
+}
+
+func TestTokenizerCount(t *testing.T) {
+	o200k := TokenizerFor("openai", "gpt-4o")
+	// "hello world" is two tokens in both cl100k and o200k
+	if got := o200k.Count("hello world"); got != 2 {
+		t.Errorf("o200k Count(hello world) = %d, want 2", got)
+	}
+	if got := o200k.Count(""); got != 0 {
+		t.Errorf("Count of empty text = %d, want 0", got)
+	}
+	// special tokens in pane content are counted as text, not rejected
+	if got := o200k.Count("<|endoftext|>"); got <= 1 {
+		t.Errorf("Count(<|endoftext|>) = %d, want it tokenized as ordinary text", got)
+	}
+
+	claude := TokenizerFor("anthropic", "claude-sonnet-4")
+	text := "The quick brown fox jumps over the lazy dog. "
+	base := Tokenizer{Encoding: "cl100k_base", Scale: 1}.Count(text)
+	if got := claude.Count(text); got <= base {
+		t.Errorf("claude Count = %d, want it scaled above cl100k count %d", got, base)
+	}
+	if got := claude.Description(); got != "cl100k_base ×1.1, approximate" {
+		t.Errorf("Description() = %q", got)
+	}
+}
+
+func TestTokenizerCountUnknownEncodingFallsBack(t *testing.T) {
+	tok := Tokenizer{Encoding: "no_such_encoding", Scale: 1}
+	text := "ls -la /tmp"
+	if got, want := tok.Count(text), EstimateTokenCount(text); got != want {
+		t.Errorf("Count = %d, want estimate %d", got, want)
+	}
+	if got := tok.Description(); got != "word-based estimate" {
+		t.Errorf("Description() = %q", got)
+	}
+}
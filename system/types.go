@@ -5,6 +5,14 @@ import (
 	"strings"
 )
 
+// TmuxWindowDetails describes a window in the current tmux session
+type TmuxWindowDetails struct {
+	Target   string // session_id:window_index, usable as a list-panes target
+	Index    int
+	Name     string
+	IsActive bool
+}
+
 type TmuxPaneDetails struct {
 	Id                 string
 	CurrentPid         int
@@ -21,6 +29,7 @@ type TmuxPaneDetails struct {
 	IsSubShell         bool
 	HistorySize        int
 	HistoryLimit       int
+	CurrentPath        string // working directory of the pane's foreground process
 }
 
 func (p *TmuxPaneDetails) String() string {
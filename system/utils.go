@@ -76,8 +76,12 @@ var HighlightCode = func(language string, code string) (string, error) {
 		}
 	}
 
-	// Choose a style (theme) - using monokai for good terminal visibility
-	style := styles.Get("monokai")
+	// the theme's style, monokai by default for good terminal visibility
+	styleName := CurrentTheme().CodeStyle
+	if styleName == "" {
+		return code, nil
+	}
+	style := styles.Get(styleName)
 	if style == nil {
 		style = styles.Fallback
 	}
@@ -108,6 +112,9 @@ var HighlightCode = func(language string, code string) (string, error) {
 func IsShellCommand(command string) bool {
 	shellCommands := []string{
 		"bash", "zsh", "fish", "sh", "dash", "ksh", "csh", "tcsh",
+		"pwsh", "powershell", "nu",
+		// Windows shells run from WSL
+		"pwsh.exe", "powershell.exe", "cmd.exe",
 	}
 	return slices.Contains(shellCommands, command)
 }